@@ -0,0 +1,63 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package accounts defines the signer abstraction used by the eth_sign,
+// eth_signTransaction and eth_sendTransaction rpc methods. Implementations
+// live in the keystore (local encrypted JSON keys) and external (clef
+// compatible remote signer) sub-packages.
+package accounts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+
+	"github.com/erigontech/erigon/core/types"
+)
+
+// ErrUnknownAccount is returned for any requested operation for which no
+// backend provides the specified account.
+var ErrUnknownAccount = errors.New("unknown account")
+
+// Signer holds a set of accounts and signs transactions and messages on
+// their behalf.
+type Signer interface {
+	// Accounts returns the addresses the signer can sign for.
+	Accounts(ctx context.Context) ([]libcommon.Address, error)
+
+	// SignTx signs the given transaction with the key of account, replay-protected
+	// with chainID. The returned transaction carries the signature.
+	SignTx(ctx context.Context, account libcommon.Address, txn types.Transaction, chainID *big.Int) (types.Transaction, error)
+
+	// SignText signs the EIP-191 personal message hash of text (see TextHash).
+	// The recovery id of the returned 65-byte signature is 27 or 28.
+	SignText(ctx context.Context, account libcommon.Address, text []byte) ([]byte, error)
+
+	// Close releases any resources (connections, decrypted keys) held by the signer.
+	Close() error
+}
+
+// TextHash computes the hash signed by eth_sign:
+//
+//	keccak256("\x19Ethereum Signed Message:\n"${message length}${message})
+func TextHash(data []byte) []byte {
+	msg := fmt.Sprintf("\x19Ethereum Signed Message:\n%d%s", len(data), data)
+	return crypto.Keccak256([]byte(msg))
+}
@@ -0,0 +1,154 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements accounts.Signer by delegating to an external
+// signer (such as clef) over its JSON-RPC API.
+package external
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/accounts"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/rpc"
+)
+
+// Signer talks to an external signer. Every request is subject to the
+// approval rules of the external signer, so calls may block until a user
+// confirms them there.
+type Signer struct {
+	endpoint string
+	client   *rpc.Client
+}
+
+var _ accounts.Signer = (*Signer)(nil)
+
+// Dial connects to the external signer at endpoint, which is either an
+// http(s)/ws(s) URL or the path of an IPC socket.
+func Dial(ctx context.Context, endpoint string, logger log.Logger) (*Signer, error) {
+	client, err := rpc.DialContext(ctx, endpoint, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Signer{endpoint: endpoint, client: client}, nil
+}
+
+// SendTxArgs is the transaction object accepted by account_signTransaction.
+type SendTxArgs struct {
+	From                 libcommon.Address  `json:"from"`
+	To                   *libcommon.Address `json:"to"`
+	Gas                  hexutil.Uint64     `json:"gas"`
+	GasPrice             *hexutil.Big       `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big       `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big       `json:"maxPriorityFeePerGas,omitempty"`
+	Value                hexutil.Big        `json:"value"`
+	Nonce                hexutil.Uint64     `json:"nonce"`
+	Data                 *hexutility.Bytes  `json:"data"`
+	AccessList           *types.AccessList  `json:"accessList,omitempty"`
+	ChainID              *hexutil.Big       `json:"chainId,omitempty"`
+}
+
+// signTransactionResult is the reply of account_signTransaction.
+type signTransactionResult struct {
+	Raw hexutility.Bytes `json:"raw"`
+}
+
+func (s *Signer) String() string {
+	return "external signer " + s.endpoint
+}
+
+// Accounts implements account_list.
+func (s *Signer) Accounts(ctx context.Context) ([]libcommon.Address, error) {
+	var res []libcommon.Address
+	if err := s.client.CallContext(ctx, &res, "account_list"); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SignTx implements account_signTransaction. The signer receives the
+// transaction fields rather than the signing hash, so it can show the user
+// what is being approved. The reply is checked to be signed by account.
+func (s *Signer) SignTx(ctx context.Context, account libcommon.Address, txn types.Transaction, chainID *big.Int) (types.Transaction, error) {
+	data := hexutility.Bytes(txn.GetData())
+	args := SendTxArgs{
+		From:    account,
+		To:      txn.GetTo(),
+		Gas:     hexutil.Uint64(txn.GetGas()),
+		Value:   hexutil.Big(*txn.GetValue().ToBig()),
+		Nonce:   hexutil.Uint64(txn.GetNonce()),
+		Data:    &data,
+		ChainID: (*hexutil.Big)(chainID),
+	}
+	switch txn.Type() {
+	case types.LegacyTxType:
+		args.GasPrice = (*hexutil.Big)(txn.GetPrice().ToBig())
+	case types.AccessListTxType:
+		accessList := txn.GetAccessList()
+		args.GasPrice = (*hexutil.Big)(txn.GetPrice().ToBig())
+		args.AccessList = &accessList
+	case types.DynamicFeeTxType:
+		accessList := txn.GetAccessList()
+		args.MaxFeePerGas = (*hexutil.Big)(txn.GetFeeCap().ToBig())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(txn.GetTip().ToBig())
+		args.AccessList = &accessList
+	default:
+		return nil, fmt.Errorf("%s: unsupported transaction type %d", s, txn.Type())
+	}
+
+	var res signTransactionResult
+	if err := s.client.CallContext(ctx, &res, "account_signTransaction", &args); err != nil {
+		return nil, err
+	}
+	signed, err := types.DecodeTransaction(res.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: decoding signed transaction: %w", s, err)
+	}
+	sender, err := signed.Sender(*types.LatestSignerForChainID(chainID))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s, err)
+	}
+	if sender != account {
+		return nil, fmt.Errorf("%s: transaction signed by %x, expected %x", s, sender, account)
+	}
+	return signed, nil
+}
+
+// SignText implements account_signData with the text/plain content type,
+// for which the external signer computes the EIP-191 personal message hash.
+func (s *Signer) SignText(ctx context.Context, account libcommon.Address, text []byte) ([]byte, error) {
+	var res hexutility.Bytes
+	if err := s.client.CallContext(ctx, &res, "account_signData", "text/plain", account, hexutility.Bytes(text)); err != nil {
+		return nil, err
+	}
+	if len(res) != 65 {
+		return nil, fmt.Errorf("%s: invalid signature length %d", s, len(res))
+	}
+	return res, nil
+}
+
+// Close closes the connection to the external signer.
+func (s *Signer) Close() error {
+	s.client.Close()
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/accounts"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/rpc"
+)
+
+// fakeClef serves the subset of the clef "account" namespace used by Signer.
+type fakeClef struct {
+	key *ecdsa.PrivateKey
+}
+
+func (c *fakeClef) List() []libcommon.Address {
+	return []libcommon.Address{crypto.PubkeyToAddress(c.key.PublicKey)}
+}
+
+func (c *fakeClef) SignTransaction(args SendTxArgs) (map[string]interface{}, error) {
+	if args.From != crypto.PubkeyToAddress(c.key.PublicKey) {
+		return nil, errors.New("request denied")
+	}
+	value, _ := uint256.FromBig(args.Value.ToInt())
+	var txn types.Transaction
+	if args.MaxFeePerGas != nil {
+		chainID, _ := uint256.FromBig(args.ChainID.ToInt())
+		tip, _ := uint256.FromBig(args.MaxPriorityFeePerGas.ToInt())
+		feeCap, _ := uint256.FromBig(args.MaxFeePerGas.ToInt())
+		txn = &types.DynamicFeeTransaction{
+			CommonTx: types.CommonTx{Nonce: uint64(args.Nonce), Gas: uint64(args.Gas), To: args.To, Value: value, Data: *args.Data},
+			ChainID:  chainID,
+			Tip:      tip,
+			FeeCap:   feeCap,
+		}
+	} else {
+		gasPrice, _ := uint256.FromBig(args.GasPrice.ToInt())
+		txn = types.NewTransaction(uint64(args.Nonce), *args.To, value, uint64(args.Gas), gasPrice, *args.Data)
+	}
+	signed, err := types.SignTx(txn, *types.LatestSignerForChainID(args.ChainID.ToInt()), c.key)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := signed.MarshalBinary(&buf); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"raw": hexutility.Bytes(buf.Bytes()), "tx": signed}, nil
+}
+
+func (c *fakeClef) SignData(contentType string, addr libcommon.Address, data hexutility.Bytes) (hexutility.Bytes, error) {
+	if contentType != "text/plain" {
+		return nil, errors.New("unsupported content type")
+	}
+	sig, err := crypto.Sign(accounts.TextHash(data), c.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+func TestExternalSignerIPC(t *testing.T) {
+	logger := log.New()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	srv := rpc.NewServer(50, false /* traceRequests */, false /* debugSingleRequests */, true, logger, 100)
+	require.NoError(t, srv.RegisterName("account", &fakeClef{key: key}))
+	endpoint := filepath.Join(t.TempDir(), "clef.ipc")
+	l, err := net.Listen("unix", endpoint)
+	require.NoError(t, err)
+	go srv.ServeListener(l) //nolint:errcheck
+	defer srv.Stop()
+	defer l.Close()
+
+	ctx := context.Background()
+	signer, err := Dial(ctx, endpoint, logger)
+	require.NoError(t, err)
+	defer signer.Close()
+
+	addrs, err := signer.Accounts(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []libcommon.Address{addr}, addrs)
+
+	chainID := big.NewInt(1337)
+	to := libcommon.Address{1}
+	for _, txn := range []types.Transaction{
+		types.NewTransaction(3, to, uint256.NewInt(5), 21000, uint256.NewInt(7), nil),
+		&types.DynamicFeeTransaction{
+			CommonTx: types.CommonTx{Nonce: 4, Gas: 30000, To: &to, Value: uint256.NewInt(5), Data: []byte{0xca, 0xfe}},
+			ChainID:  uint256.MustFromBig(chainID),
+			Tip:      uint256.NewInt(1),
+			FeeCap:   uint256.NewInt(9),
+		},
+	} {
+		signed, err := signer.SignTx(ctx, addr, txn, chainID)
+		require.NoError(t, err)
+		require.Equal(t, txn.Type(), signed.Type())
+		require.Equal(t, txn.SigningHash(chainID), signed.SigningHash(chainID))
+	}
+
+	_, err = signer.SignTx(ctx, libcommon.Address{2}, types.NewTransaction(0, to, uint256.NewInt(0), 21000, uint256.NewInt(1), nil), chainID)
+	require.ErrorContains(t, err, "request denied")
+
+	sig, err := signer.SignText(ctx, addr, []byte("hello"))
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig)
+	require.NoError(t, err)
+	require.Equal(t, addr, crypto.PubkeyToAddress(*pub))
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package keystore implements accounts.Signer on top of a directory of
+// Web3 Secret Storage v3 encrypted key files.
+package keystore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+
+	"github.com/erigontech/erigon/accounts"
+	"github.com/erigontech/erigon/core/types"
+)
+
+// KeyStore holds the decrypted keys of a keystore directory in memory.
+// Keys are unlocked once, when the keystore is opened.
+type KeyStore struct {
+	mu   sync.RWMutex
+	keys map[libcommon.Address]*ecdsa.PrivateKey
+}
+
+var _ accounts.Signer = (*KeyStore)(nil)
+
+// Open decrypts every key file in dir. Each key is unlocked with the first of
+// passwords that decrypts it; a key that none of them unlocks is an error.
+// Hidden files, sub-directories and editor backups are skipped.
+func Open(dir string, passwords []string) (*KeyStore, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	ks := &KeyStore{keys: map[libcommon.Address]*ecdsa.PrivateKey{}}
+	for _, e := range entries {
+		if e.IsDir() || skipKeyFile(e.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		keyjson, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := decryptWithAny(keyjson, passwords)
+		if err != nil {
+			return nil, fmt.Errorf("keystore: %s: %w", path, err)
+		}
+		ks.keys[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+	return ks, nil
+}
+
+func decryptWithAny(keyjson []byte, passwords []string) (*ecdsa.PrivateKey, error) {
+	if len(passwords) == 0 {
+		passwords = []string{""}
+	}
+	for _, password := range passwords {
+		key, err := DecryptKey(keyjson, password)
+		if err == nil {
+			return key, nil
+		}
+		if !errors.Is(err, ErrDecrypt) {
+			return nil, err
+		}
+	}
+	return nil, ErrDecrypt
+}
+
+func skipKeyFile(name string) bool {
+	return strings.HasPrefix(name, ".") || strings.HasSuffix(name, "~")
+}
+
+// ReadPasswordFile returns the non-empty lines of a password file, one
+// password per line.
+func ReadPasswordFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var passwords []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			passwords = append(passwords, line)
+		}
+	}
+	return passwords, scanner.Err()
+}
+
+// StoreKey encrypts key with password and writes it into dir using the
+// UTC--<created at>--<address> file naming convention. It returns the file path.
+func StoreKey(dir string, key *ecdsa.PrivateKey, password string, scryptN, scryptP int) (string, error) {
+	keyjson, err := EncryptKey(key, password, scryptN, scryptP)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	ts := time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z")
+	path := filepath.Join(dir, fmt.Sprintf("UTC--%s--%x", ts, addr[:]))
+	if err := os.WriteFile(path, keyjson, 0600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Accounts returns the addresses of all unlocked keys, sorted.
+func (ks *KeyStore) Accounts(_ context.Context) ([]libcommon.Address, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	addrs := make([]libcommon.Address, 0, len(ks.keys))
+	for addr := range ks.keys {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	return addrs, nil
+}
+
+// SignTx signs txn with the key of account using the latest signer for chainID.
+func (ks *KeyStore) SignTx(_ context.Context, account libcommon.Address, txn types.Transaction, chainID *big.Int) (types.Transaction, error) {
+	key, err := ks.key(account)
+	if err != nil {
+		return nil, err
+	}
+	return types.SignTx(txn, *types.LatestSignerForChainID(chainID), key)
+}
+
+// SignText signs the EIP-191 hash of text with the key of account.
+func (ks *KeyStore) SignText(_ context.Context, account libcommon.Address, text []byte) ([]byte, error) {
+	key, err := ks.key(account)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(accounts.TextHash(text), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// Close drops the decrypted keys.
+func (ks *KeyStore) Close() error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = map[libcommon.Address]*ecdsa.PrivateKey{}
+	return nil
+}
+
+func (ks *KeyStore) key(account libcommon.Address) (*ecdsa.PrivateKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	key, ok := ks.keys[account]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return key, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+
+	"github.com/erigontech/erigon/accounts"
+	"github.com/erigontech/erigon/core/types"
+)
+
+// Test vectors from the Web3 Secret Storage definition.
+const (
+	web3TestPassword = "testpassword"
+	web3TestKey      = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+
+	web3PBKDF2Vector = `{
+  "crypto": {
+    "cipher": "aes-128-ctr",
+    "cipherparams": {"iv": "6087dab2f9fdbbfaddc31a909735c1e6"},
+    "ciphertext": "5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46",
+    "kdf": "pbkdf2",
+    "kdfparams": {
+      "c": 262144,
+      "dklen": 32,
+      "prf": "hmac-sha256",
+      "salt": "ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"
+    },
+    "mac": "517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"
+  },
+  "id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+  "version": 3
+}`
+
+	web3ScryptVector = `{
+  "crypto": {
+    "cipher": "aes-128-ctr",
+    "cipherparams": {"iv": "83dbcc02d8ccb40e466191a123791e0e"},
+    "ciphertext": "d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c",
+    "kdf": "scrypt",
+    "kdfparams": {
+      "dklen": 32,
+      "n": 262144,
+      "p": 8,
+      "r": 1,
+      "salt": "ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"
+    },
+    "mac": "2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"
+  },
+  "id": "3198bc9c-6672-5ab3-d995-4942343ae5b6",
+  "version": 3
+}`
+)
+
+func TestDecryptKeyVectors(t *testing.T) {
+	for name, vector := range map[string]string{"pbkdf2": web3PBKDF2Vector, "scrypt": web3ScryptVector} {
+		t.Run(name, func(t *testing.T) {
+			key, err := DecryptKey([]byte(vector), web3TestPassword)
+			require.NoError(t, err)
+			require.Equal(t, web3TestKey, hex.EncodeToString(crypto.FromECDSA(key)))
+
+			_, err = DecryptKey([]byte(vector), "wrong")
+			require.ErrorIs(t, err, ErrDecrypt)
+		})
+	}
+}
+
+func TestDecryptKeyShortIV(t *testing.T) {
+	// the MAC doesn't cover the IV, a valid key file with a truncated one must not crash the decryption
+	vector := strings.Replace(web3PBKDF2Vector, "6087dab2f9fdbbfaddc31a909735c1e6", "6087dab2", 1)
+	_, err := DecryptKey([]byte(vector), web3TestPassword)
+	require.ErrorContains(t, err, "invalid IV length")
+}
+
+func TestEncryptDecryptRoundTrip(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	keyjson, err := EncryptKey(key, "foo", LightScryptN, LightScryptP)
+	require.NoError(t, err)
+
+	decrypted, err := DecryptKey(keyjson, "foo")
+	require.NoError(t, err)
+	require.Equal(t, crypto.FromECDSA(key), crypto.FromECDSA(decrypted))
+
+	_, err = DecryptKey(keyjson, "bar")
+	require.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestKeyStoreSign(t *testing.T) {
+	dir := t.TempDir()
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	_, err := StoreKey(dir, key1, "one", LightScryptN, LightScryptP)
+	require.NoError(t, err)
+	_, err = StoreKey(dir, key2, "two", LightScryptN, LightScryptP)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "passwords"), []byte("one\ntwo\n"), 0600))
+
+	passwords, err := ReadPasswordFile(filepath.Join(dir, "passwords"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"one", "two"}, passwords)
+	require.NoError(t, os.Remove(filepath.Join(dir, "passwords")))
+
+	_, err = Open(dir, []string{"one"})
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	ks, err := Open(dir, passwords)
+	require.NoError(t, err)
+	defer ks.Close()
+
+	ctx := context.Background()
+	addr1, addr2 := crypto.PubkeyToAddress(key1.PublicKey), crypto.PubkeyToAddress(key2.PublicKey)
+	addrs, err := ks.Accounts(ctx)
+	require.NoError(t, err)
+	require.ElementsMatch(t, []libcommon.Address{addr1, addr2}, addrs)
+
+	chainID := big.NewInt(1337)
+	txn := types.NewTransaction(0, libcommon.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil)
+	signed, err := ks.SignTx(ctx, addr2, txn, chainID)
+	require.NoError(t, err)
+	sender, err := types.LatestSignerForChainID(chainID).Sender(signed)
+	require.NoError(t, err)
+	require.Equal(t, addr2, sender)
+
+	sig, err := ks.SignText(ctx, addr1, []byte("hello"))
+	require.NoError(t, err)
+	require.Len(t, sig, 65)
+	require.Contains(t, []byte{27, 28}, sig[crypto.RecoveryIDOffset])
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig)
+	require.NoError(t, err)
+	require.Equal(t, addr1, crypto.PubkeyToAddress(*pub))
+
+	_, err = ks.SignText(ctx, libcommon.Address{0xde, 0xad}, []byte("hello"))
+	require.ErrorIs(t, err, accounts.ErrUnknownAccount)
+}
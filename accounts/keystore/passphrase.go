@@ -0,0 +1,250 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+)
+
+const (
+	version = 3
+
+	keyHeaderKDF = "scrypt"
+
+	// StandardScryptN is the N parameter of Scrypt encryption algorithm, using 256MB
+	// memory and taking approximately 1s CPU time on a modern processor.
+	StandardScryptN = 1 << 18
+
+	// StandardScryptP is the P parameter of Scrypt encryption algorithm, using 256MB
+	// memory and taking approximately 1s CPU time on a modern processor.
+	StandardScryptP = 1
+
+	// LightScryptN is the N parameter of Scrypt encryption algorithm, using 4MB
+	// memory and taking approximately 100ms CPU time on a modern processor.
+	LightScryptN = 1 << 12
+
+	// LightScryptP is the P parameter of Scrypt encryption algorithm, using 4MB
+	// memory and taking approximately 100ms CPU time on a modern processor.
+	LightScryptP = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+)
+
+var (
+	// ErrDecrypt is returned when the password does not match the key file.
+	ErrDecrypt = errors.New("could not decrypt key with given password")
+)
+
+// encryptedKeyJSONV3 is the Web3 Secret Storage v3 key file layout.
+type encryptedKeyJSONV3 struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	Id      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherparamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherparamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// EncryptKey encrypts key with password into a Web3 Secret Storage v3 JSON
+// document, using scrypt with the given cost parameters as the KDF.
+func EncryptKey(key *ecdsa.PrivateKey, password string, scryptN, scryptP int) ([]byte, error) {
+	salt := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	encryptKey := derivedKey[:16]
+	keyBytes := libcommon.LeftPadBytes(key.D.Bytes(), 32)
+
+	iv := make([]byte, aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, err
+	}
+	cipherText, err := aesCTRXOR(encryptKey, keyBytes, iv)
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	return json.Marshal(encryptedKeyJSONV3{
+		Address: hex.EncodeToString(addr[:]),
+		Crypto: cryptoJSON{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherparamsJSON{IV: hex.EncodeToString(iv)},
+			KDF:          keyHeaderKDF,
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		Id:      id,
+		Version: version,
+	})
+}
+
+// DecryptKey decrypts a Web3 Secret Storage v3 JSON document with password.
+// Both the scrypt and pbkdf2 (hmac-sha256) key derivation functions are supported.
+func DecryptKey(keyjson []byte, password string) (*ecdsa.PrivateKey, error) {
+	var k encryptedKeyJSONV3
+	if err := json.Unmarshal(keyjson, &k); err != nil {
+		return nil, err
+	}
+	if k.Version != version {
+		return nil, fmt.Errorf("unsupported key version: %d", k.Version)
+	}
+	if k.Crypto.Cipher != "aes-128-ctr" {
+		return nil, fmt.Errorf("unsupported cipher: %s", k.Crypto.Cipher)
+	}
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := deriveKey(k.Crypto, password)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(crypto.Keccak256(derivedKey[16:32], cipherText), mac) {
+		return nil, ErrDecrypt
+	}
+	plainText, err := aesCTRXOR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	key, err := crypto.ToECDSA(plainText)
+	if err != nil {
+		return nil, err
+	}
+	if k.Address != "" {
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		if libcommon.HexToAddress(k.Address) != addr {
+			return nil, fmt.Errorf("key content mismatch: have account %x, want %s", addr, k.Address)
+		}
+	}
+	return key, nil
+}
+
+func deriveKey(cj cryptoJSON, password string) ([]byte, error) {
+	salt, err := hex.DecodeString(paramString(cj.KDFParams, "salt"))
+	if err != nil {
+		return nil, err
+	}
+	dkLen := paramInt(cj.KDFParams, "dklen")
+	if dkLen < 32 {
+		return nil, fmt.Errorf("invalid dklen: %d", dkLen)
+	}
+	switch cj.KDF {
+	case keyHeaderKDF:
+		n := paramInt(cj.KDFParams, "n")
+		r := paramInt(cj.KDFParams, "r")
+		p := paramInt(cj.KDFParams, "p")
+		return scrypt.Key([]byte(password), salt, n, r, p, dkLen)
+	case "pbkdf2":
+		if prf := paramString(cj.KDFParams, "prf"); prf != "hmac-sha256" {
+			return nil, fmt.Errorf("unsupported PBKDF2 PRF: %s", prf)
+		}
+		c := paramInt(cj.KDFParams, "c")
+		return pbkdf2.Key([]byte(password), salt, c, dkLen, sha256.New), nil
+	default:
+		return nil, fmt.Errorf("unsupported KDF: %s", cj.KDF)
+	}
+}
+
+// paramInt reads a numeric KDF parameter; encoding/json decodes numbers into float64.
+func paramInt(params map[string]interface{}, name string) int {
+	f, _ := params[name].(float64)
+	return int(f)
+}
+
+func paramString(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return s
+}
+
+func aesCTRXOR(key, inText, iv []byte) ([]byte, error) {
+	aesBlock, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	// cipher.NewCTR panics on an IV of the wrong size, which comes from the key file
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid IV length %d, expected %d", len(iv), aes.BlockSize)
+	}
+	stream := cipher.NewCTR(aesBlock, iv)
+	outText := make([]byte, len(inText))
+	stream.XORKeyStream(outText, inText)
+	return outText, nil
+}
+
+// newUUID returns a random (version 4) UUID in its canonical textual form.
+func newUUID() (string, error) {
+	var u [16]byte
+	if _, err := io.ReadFull(rand.Reader, u[:]); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
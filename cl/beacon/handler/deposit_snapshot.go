@@ -0,0 +1,206 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon/cl/beacon/beaconhttp"
+	"github.com/erigontech/erigon/cl/cltypes/solid"
+	"github.com/erigontech/erigon/cl/merkle_tree"
+	state_accessors "github.com/erigontech/erigon/cl/persistence/state"
+	"github.com/erigontech/erigon/cl/utils"
+)
+
+// depositSnapshotResponse is the EIP-4881 deposit tree snapshot.
+type depositSnapshotResponse struct {
+	Finalized            []libcommon.Hash `json:"finalized"`
+	DepositRoot          libcommon.Hash   `json:"deposit_root"`
+	DepositCount         uint64           `json:"deposit_count,string"`
+	ExecutionBlockHash   libcommon.Hash   `json:"execution_block_hash"`
+	ExecutionBlockHeight uint64           `json:"execution_block_height,string"`
+}
+
+// GetEthV1BeaconDepositSnapshot rebuilds the finalized deposit tree from the merkle proof of the last deposit included
+// in the finalized chain, so no eth1 deposit cache is needed. The snapshot is only served once every deposit of the
+// finalized eth1 data has been included, as otherwise it would not match any execution block.
+func (a *ApiHandler) GetEthV1BeaconDepositSnapshot(w http.ResponseWriter, r *http.Request) (*beaconhttp.BeaconResponse, error) {
+	ctx := r.Context()
+
+	tx, err := a.indiciesDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	snRoTx := a.caplinStateSnapshots.View()
+	defer snRoTx.Close()
+	stateGetter := state_accessors.GetValFnTxAndSnapshot(tx, snRoTx)
+
+	// take the snapshot at the latest finalized slot the states archive knows about.
+	progress, err := state_accessors.GetStateProcessingProgress(tx)
+	if err != nil {
+		return nil, err
+	}
+	if a.caplinStateSnapshots != nil {
+		progress = max(progress, a.caplinStateSnapshots.BlocksAvailable())
+	}
+	snapshotSlot := min(a.forkchoiceStore.FinalizedSlot(), progress)
+
+	_, slotData, err := a.readSlotDataAtOrBefore(stateGetter, snapshotSlot)
+	if err != nil {
+		return nil, err
+	}
+	if slotData == nil {
+		return nil, beaconhttp.NewEndpointError(http.StatusNotFound, errors.New("could not read finalized deposit data, node may not be archive or it still processing historical states"))
+	}
+	eth1Data := slotData.Eth1Data
+	depositCount := slotData.Eth1DepositIndex
+	if depositCount != eth1Data.DepositCount {
+		return nil, beaconhttp.NewEndpointError(http.StatusNotFound, fmt.Errorf("finalized deposits are still being included: %d/%d", depositCount, eth1Data.DepositCount))
+	}
+
+	finalized, depositRoot, err := a.finalizedDepositTree(ctx, tx, stateGetter, snapshotSlot, depositCount)
+	if err != nil {
+		return nil, err
+	}
+	if depositRoot != eth1Data.Root {
+		return nil, fmt.Errorf("reconstructed deposit root %x does not match eth1 data root %x", depositRoot, eth1Data.Root)
+	}
+
+	if a.engine == nil {
+		return nil, beaconhttp.NewEndpointError(http.StatusServiceUnavailable, errors.New("execution client is not available"))
+	}
+	header, err := a.engine.GetHeaderByHash(ctx, eth1Data.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, beaconhttp.NewEndpointError(http.StatusNotFound, fmt.Errorf("execution block not found: %x", eth1Data.BlockHash))
+	}
+
+	return newBeaconResponse(&depositSnapshotResponse{
+		Finalized:            finalized,
+		DepositRoot:          depositRoot,
+		DepositCount:         depositCount,
+		ExecutionBlockHash:   eth1Data.BlockHash,
+		ExecutionBlockHeight: header.Number.Uint64(),
+	}).WithFinalized(true), nil
+}
+
+// finalizedDepositTree locates the block which included deposit depositCount-1 and derives the finalized subtrees of
+// the deposit tree from its proof.
+func (a *ApiHandler) finalizedDepositTree(ctx context.Context, tx kv.Tx, stateGetter state_accessors.GetValFn, toSlot, depositCount uint64) ([]libcommon.Hash, libcommon.Hash, error) {
+	depth := a.beaconChainCfg.DepositContractTreeDepth
+	if depositCount == 0 {
+		finalized, root := depositSnapshotFromProof(libcommon.Hash{}, nil, 0, depth)
+		return finalized, root, nil
+	}
+	// the deposit index only grows, so binary search the first slot in which it reached depositCount.
+	lo, hi := uint64(0), toSlot
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		_, sd, err := a.readSlotDataAtOrBefore(stateGetter, mid)
+		if err != nil {
+			return nil, libcommon.Hash{}, err
+		}
+		if sd == nil || sd.Eth1DepositIndex < depositCount {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	sd, err := state_accessors.ReadSlotData(stateGetter, lo, a.beaconChainCfg)
+	if err != nil {
+		return nil, libcommon.Hash{}, err
+	}
+	block, err := a.blockReader.ReadBlockBySlot(ctx, tx, lo)
+	if err != nil {
+		return nil, libcommon.Hash{}, err
+	}
+	if sd == nil || block == nil {
+		return nil, libcommon.Hash{}, beaconhttp.NewEndpointError(http.StatusNotFound, fmt.Errorf("could not find the block including deposit %d", depositCount-1))
+	}
+	deposits := block.Block.Body.Deposits
+	firstIndex := sd.Eth1DepositIndex - uint64(deposits.Len())
+	if sd.Eth1DepositIndex < uint64(deposits.Len()) || depositCount <= firstIndex || depositCount > sd.Eth1DepositIndex {
+		// the deposit is part of the genesis state.
+		return nil, libcommon.Hash{}, beaconhttp.NewEndpointError(http.StatusNotFound, fmt.Errorf("could not find the block including deposit %d", depositCount-1))
+	}
+	deposit := deposits.Get(int(depositCount - 1 - firstIndex))
+	leaf, err := deposit.Data.HashSSZ()
+	if err != nil {
+		return nil, libcommon.Hash{}, err
+	}
+	finalized, root := depositSnapshotFromProof(leaf, deposit.Proof, depositCount, depth)
+	return finalized, root, nil
+}
+
+// readSlotDataAtOrBefore returns the slot data of the closest slot with a block not after slot.
+func (a *ApiHandler) readSlotDataAtOrBefore(stateGetter state_accessors.GetValFn, slot uint64) (uint64, *state_accessors.SlotData, error) {
+	for lookback := uint64(0); lookback <= slot && lookback < a.beaconChainCfg.SlotsPerHistoricalRoot; lookback++ {
+		sd, err := state_accessors.ReadSlotData(stateGetter, slot-lookback, a.beaconChainCfg)
+		if err != nil {
+			return 0, nil, err
+		}
+		if sd != nil {
+			return slot - lookback, sd, nil
+		}
+	}
+	return 0, nil, nil
+}
+
+// depositSnapshotFromProof computes the EIP-4881 finalized hashes and the deposit root of a tree holding depositCount
+// leaves, given the last leaf and its proof. Every complete left subtree along the path of the last leaf is finalized:
+// the lowest one is hashed up from the leaf itself, the others are left siblings in the proof.
+func depositSnapshotFromProof(leaf libcommon.Hash, proof solid.HashVectorSSZ, depositCount, depth uint64) ([]libcommon.Hash, libcommon.Hash) {
+	finalized := []libcommon.Hash{}
+	node := merkle_tree.ZeroHashes[depth]
+	if depositCount > 0 {
+		lastIndex := depositCount - 1
+		node = leaf
+		height := uint64(0)
+		// hash up the complete subtree which contains the last leaf.
+		for ; lastIndex>>height&1 == 1; height++ {
+			node = utils.Sha256(proof.Get(int(height)).Bytes(), node[:])
+		}
+		finalized = append(finalized, node)
+		// the right side of the tree is still empty, the left siblings are all complete subtrees.
+		for ; height < depth; height++ {
+			if lastIndex>>height&1 == 1 {
+				sibling := proof.Get(int(height))
+				finalized = append(finalized, sibling)
+				node = utils.Sha256(sibling[:], node[:])
+			} else {
+				node = utils.Sha256(node[:], merkle_tree.ZeroHashes[height][:])
+			}
+		}
+		// the finalized list is ordered from the largest subtree to the smallest one.
+		for i, j := 0, len(finalized)-1; i < j; i, j = i+1, j-1 {
+			finalized[i], finalized[j] = finalized[j], finalized[i]
+		}
+	}
+	var count [32]byte
+	binary.LittleEndian.PutUint64(count[:], depositCount)
+	return finalized, utils.Sha256(node[:], count[:])
+}
@@ -0,0 +1,56 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/cltypes/solid"
+	"github.com/erigontech/erigon/cl/merkle_tree"
+	"github.com/erigontech/erigon/cl/utils"
+)
+
+func TestDepositSnapshotFromProof(t *testing.T) {
+	// the deposit contract root before any deposit.
+	finalized, root := depositSnapshotFromProof(libcommon.Hash{}, nil, 0, 32)
+	require.Empty(t, finalized)
+	require.Equal(t, libcommon.HexToHash("0xd70a234731285c6804c2a4f56711ddb8c82c99740f207854891028af34e27e5e"), root)
+
+	// three leaves: the first two form a finalized subtree, the third is finalized on its own.
+	leaves := []libcommon.Hash{{1}, {2}, {3}}
+	left := libcommon.Hash(utils.Sha256(leaves[0][:], leaves[1][:]))
+	proof := solid.NewHashVector(33)
+	proof.Set(0, merkle_tree.ZeroHashes[0])
+	proof.Set(1, left)
+	for i := 2; i < 32; i++ {
+		proof.Set(i, merkle_tree.ZeroHashes[i])
+	}
+	finalized, root = depositSnapshotFromProof(leaves[2], proof, 3, 32)
+	require.Equal(t, []libcommon.Hash{left, leaves[2]}, finalized)
+
+	right := utils.Sha256(leaves[2][:], merkle_tree.ZeroHashes[0][:])
+	node := utils.Sha256(left[:], right[:])
+	for i := 2; i < 32; i++ {
+		node = utils.Sha256(node[:], merkle_tree.ZeroHashes[i][:])
+	}
+	var count [32]byte
+	count[0] = 3
+	require.Equal(t, libcommon.Hash(utils.Sha256(node[:], count[:])), root)
+}
@@ -289,6 +289,7 @@ func (a *ApiHandler) init() {
 						r.Get("/updates", a.GetEthV1BeaconLightClientUpdates)
 					})
 					r.Get("/blob_sidecars/{block_id}", beaconhttp.HandleEndpointFunc(a.GetEthV1BeaconBlobSidecars))
+					r.Get("/deposit_snapshot", beaconhttp.HandleEndpointFunc(a.GetEthV1BeaconDepositSnapshot))
 					r.Route("/states", func(r chi.Router) {
 						r.Route("/{state_id}", func(r chi.Router) {
 							r.Get("/randao", beaconhttp.HandleEndpointFunc(a.getRandao))
@@ -302,6 +303,9 @@ func (a *ApiHandler) init() {
 							r.Get("/validator_balances", a.GetEthV1BeaconValidatorsBalances)
 							r.Post("/validator_balances", a.PostEthV1BeaconValidatorsBalances)
 							r.Get("/validators/{validator_id}", beaconhttp.HandleEndpointFunc(a.GetEthV1BeaconStatesValidator))
+							r.Get("/pending_deposits", beaconhttp.HandleEndpointFunc(a.GetEthV1BeaconStatesPendingDeposits))
+							r.Get("/pending_partial_withdrawals", beaconhttp.HandleEndpointFunc(a.GetEthV1BeaconStatesPendingPartialWithdrawals))
+							r.Get("/pending_consolidations", beaconhttp.HandleEndpointFunc(a.GetEthV1BeaconStatesPendingConsolidations))
 						})
 					})
 				})
@@ -323,6 +327,8 @@ func (a *ApiHandler) init() {
 					r.Post("/contribution_and_proofs", a.PostEthV1ValidatorContributionsAndProofs)
 					r.Post("/prepare_beacon_proposer", a.PostEthV1ValidatorPrepareBeaconProposal)
 					r.Post("/liveness/{epoch}", beaconhttp.HandleEndpointFunc(a.liveness))
+					r.Post("/beacon_committee_selections", beaconhttp.HandleEndpointFunc(a.PostEthV1ValidatorBeaconCommitteeSelections))
+					r.Post("/sync_committee_selections", beaconhttp.HandleEndpointFunc(a.PostEthV1ValidatorSyncCommitteeSelections))
 					if a.routerCfg.Builder {
 						r.Post("/register_validator", beaconhttp.HandleEndpointFunc(a.PostEthV1BuilderRegisterValidator))
 					}
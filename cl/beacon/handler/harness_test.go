@@ -68,6 +68,18 @@ func TestHarnessCapella(t *testing.T) {
 		append(
 			defaultHarnessOpts(harnessConfig{t: t, v: clparams.CapellaVersion, finalized: true}),
 			beacontest.WithTestFromFs(Harnesses, "expected_withdrawals"),
+			beacontest.WithTestFromFs(Harnesses, "pending_queues"),
+			beacontest.WithTestFromFs(Harnesses, "deposit_snapshot"),
+			beacontest.WithTestFromFs(Harnesses, "selections"),
+		)...,
+	)
+}
+
+func TestHarnessElectra(t *testing.T) {
+	beacontest.Execute(
+		append(
+			defaultHarnessOpts(harnessConfig{t: t, v: clparams.ElectraVersion, finalized: true}),
+			beacontest.WithTestFromFs(Harnesses, "pending_queues_electra"),
 		)...,
 	)
 }
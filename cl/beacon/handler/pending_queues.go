@@ -0,0 +1,152 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/erigontech/erigon/cl/beacon/beaconhttp"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/cltypes/solid"
+	"github.com/erigontech/erigon/cl/persistence/beacon_indicies"
+	state_accessors "github.com/erigontech/erigon/cl/persistence/state"
+	"github.com/erigontech/erigon/cl/phase1/core/state"
+)
+
+var errNotElectraState = beaconhttp.NewEndpointError(http.StatusBadRequest, errors.New("the specified state is not an electra state"))
+
+// pendingQueues holds the Electra churn queues of a single beacon state.
+type pendingQueues struct {
+	deposits           *solid.ListSSZ[*solid.PendingDeposit]
+	partialWithdrawals *solid.ListSSZ[*solid.PendingPartialWithdrawal]
+	consolidations     *solid.ListSSZ[*solid.PendingConsolidation]
+}
+
+func (a *ApiHandler) GetEthV1BeaconStatesPendingDeposits(w http.ResponseWriter, r *http.Request) (*beaconhttp.BeaconResponse, error) {
+	return a.getPendingQueue(r, func(q *pendingQueues) any { return q.deposits })
+}
+
+func (a *ApiHandler) GetEthV1BeaconStatesPendingPartialWithdrawals(w http.ResponseWriter, r *http.Request) (*beaconhttp.BeaconResponse, error) {
+	return a.getPendingQueue(r, func(q *pendingQueues) any { return q.partialWithdrawals })
+}
+
+func (a *ApiHandler) GetEthV1BeaconStatesPendingConsolidations(w http.ResponseWriter, r *http.Request) (*beaconhttp.BeaconResponse, error) {
+	return a.getPendingQueue(r, func(q *pendingQueues) any { return q.consolidations })
+}
+
+// getPendingQueue serves one of the pending queues of the requested state. The head state is read in place, older
+// canonical states come from the historical slot data and anything else is looked up in fork choice. The version
+// is taken from whichever state was read, since the queues only exist from Electra on.
+func (a *ApiHandler) getPendingQueue(r *http.Request, pick func(q *pendingQueues) any) (*beaconhttp.BeaconResponse, error) {
+	ctx := r.Context()
+
+	tx, err := a.indiciesDB.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockId, err := beaconhttp.StateIdFromRequest(r)
+	if err != nil {
+		return nil, beaconhttp.NewEndpointError(http.StatusBadRequest, err)
+	}
+	blockRoot, httpStatus, err := a.blockRootFromStateId(ctx, tx, blockId)
+	if err != nil {
+		return nil, beaconhttp.NewEndpointError(httpStatus, err)
+	}
+	isOptimistic := a.forkchoiceStore.IsRootOptimistic(blockRoot)
+	slot, err := beacon_indicies.ReadBlockSlotByBlockRoot(tx, blockRoot)
+	if err != nil {
+		return nil, err
+	}
+	if slot == nil {
+		return nil, beaconhttp.NewEndpointError(http.StatusNotFound, fmt.Errorf("could not read block slot: %x", blockRoot))
+	}
+	canonicalRoot, err := beacon_indicies.ReadCanonicalBlockRoot(tx, *slot)
+	if err != nil {
+		return nil, err
+	}
+	isFinalized := canonicalRoot == blockRoot && *slot <= a.forkchoiceStore.FinalizedSlot()
+
+	var (
+		queues  *pendingQueues
+		version clparams.StateVersion
+	)
+	if blockRoot == a.syncedData.HeadRoot() {
+		if err := a.syncedData.ViewHeadState(func(headState *state.CachingBeaconState) error {
+			version = headState.Version()
+			if version < clparams.ElectraVersion {
+				return errNotElectraState
+			}
+			queues = &pendingQueues{
+				deposits:           headState.GetPendingDeposits().ShallowCopy(),
+				partialWithdrawals: headState.GetPendingPartialWithdrawals().ShallowCopy(),
+				consolidations:     headState.GetPendingConsolidations().ShallowCopy(),
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if queues == nil && canonicalRoot == blockRoot {
+		snRoTx := a.caplinStateSnapshots.View()
+		defer snRoTx.Close()
+
+		slotData, err := state_accessors.ReadSlotData(state_accessors.GetValFnTxAndSnapshot(tx, snRoTx), *slot, a.beaconChainCfg)
+		if err != nil {
+			return nil, err
+		}
+		if slotData != nil {
+			if slotData.Version < clparams.ElectraVersion {
+				return nil, errNotElectraState
+			}
+			version = slotData.Version
+			queues = &pendingQueues{
+				deposits:           slotData.PendingDeposits,
+				partialWithdrawals: slotData.PendingPartialWithdrawals,
+				consolidations:     slotData.PendingConsolidations,
+			}
+		}
+	}
+
+	if queues == nil {
+		s, err := a.forkchoiceStore.GetStateAtBlockRoot(blockRoot, true)
+		if err != nil {
+			return nil, beaconhttp.NewEndpointError(http.StatusBadRequest, err)
+		}
+		if s == nil {
+			return nil, beaconhttp.NewEndpointError(http.StatusNotFound, errors.New("could not read pending queues, node may not be archive or it still processing historical states"))
+		}
+		version = s.Version()
+		if version < clparams.ElectraVersion {
+			return nil, errNotElectraState
+		}
+		queues = &pendingQueues{
+			deposits:           s.GetPendingDeposits(),
+			partialWithdrawals: s.GetPendingPartialWithdrawals(),
+			consolidations:     s.GetPendingConsolidations(),
+		}
+	}
+
+	return newBeaconResponse(pick(queues)).
+		WithFinalized(isFinalized).
+		WithVersion(version).
+		WithOptimistic(isOptimistic), nil
+}
@@ -0,0 +1,37 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/erigontech/erigon/cl/beacon/beaconhttp"
+)
+
+// errSelectionsNotSupported is returned by the distributed validator selection endpoints. They exist for DV middleware
+// to combine the partial selection proofs of its threshold key shares, which a beacon node serving whole validator
+// keys cannot do, so the beacon API answers 501 for non-DVT beacon nodes.
+var errSelectionsNotSupported = beaconhttp.NewEndpointError(http.StatusNotImplemented, errors.New("selection proof combination is only supported by distributed validator middleware"))
+
+func (a *ApiHandler) PostEthV1ValidatorBeaconCommitteeSelections(w http.ResponseWriter, r *http.Request) (*beaconhttp.BeaconResponse, error) {
+	return nil, errSelectionsNotSupported
+}
+
+func (a *ApiHandler) PostEthV1ValidatorSyncCommitteeSelections(w http.ResponseWriter, r *http.Request) (*beaconhttp.BeaconResponse, error) {
+	return nil, errSelectionsNotSupported
+}
@@ -19,6 +19,7 @@ package handler
 import (
 	"context"
 	"math"
+	"math/big"
 	"testing"
 
 	"github.com/spf13/afero"
@@ -44,11 +45,13 @@ import (
 	state_accessors "github.com/erigontech/erigon/cl/persistence/state"
 	"github.com/erigontech/erigon/cl/persistence/state/historical_states_reader"
 	"github.com/erigontech/erigon/cl/phase1/core/state"
+	"github.com/erigontech/erigon/cl/phase1/execution_client"
 	mock_services2 "github.com/erigontech/erigon/cl/phase1/forkchoice/mock_services"
 	"github.com/erigontech/erigon/cl/phase1/network/services/mock_services"
 	"github.com/erigontech/erigon/cl/pool"
 	"github.com/erigontech/erigon/cl/utils/eth_clock"
 	"github.com/erigontech/erigon/cl/validator/validator_params"
+	"github.com/erigontech/erigon/core/types"
 )
 
 func setupTestingHandler(t *testing.T, v clparams.StateVersion, logger log.Logger, useRealSyncDataMgr bool) (db kv.RwDB, blocks []*cltypes.SignedBeaconBlock, f afero.Fs, preState, postState *state.CachingBeaconState, h *ApiHandler, opPool pool.OperationsPool, syncedData synced_data.SyncedData, fcu *mock_services2.ForkChoiceStorageMock, vp *validator_params.ValidatorParams) {
@@ -65,6 +68,13 @@ func setupTestingHandler(t *testing.T, v clparams.StateVersion, logger log.Logge
 		bcfg.BellatrixForkEpoch = 1
 		bcfg.CapellaForkEpoch = 1
 		blocks, preState, postState = tests.GetCapellaRandom()
+	} else if v == clparams.ElectraVersion {
+		bcfg.AltairForkEpoch = 1
+		bcfg.BellatrixForkEpoch = 1
+		bcfg.CapellaForkEpoch = 1
+		bcfg.DenebForkEpoch = 1
+		bcfg.ElectraForkEpoch = 1
+		blocks, preState, postState = tests.GetElectraRandom()
 	}
 	fcu = mock_services2.NewForkChoiceStorageMock(t)
 	db = memdb.NewTestDB(t, kv.ChainDB)
@@ -143,6 +153,8 @@ func setupTestingHandler(t *testing.T, v clparams.StateVersion, logger log.Logge
 		return nil
 	}).AnyTimes()
 	mockValidatorMonitor.EXPECT().ObserveValidator(gomock.Any()).AnyTimes()
+	engine := execution_client.NewMockExecutionEngine(ctrl)
+	engine.EXPECT().GetHeaderByHash(gomock.Any(), gomock.Any()).Return(&types.Header{Number: big.NewInt(1234)}, nil).AnyTimes()
 
 	vp = validator_params.NewValidatorParams()
 	h = NewApiHandler(
@@ -166,7 +178,7 @@ func setupTestingHandler(t *testing.T, v clparams.StateVersion, logger log.Logge
 			Events:     true,
 			Validator:  true,
 			Lighthouse: true,
-		}, nil, blobStorage, nil, vp, nil, nil, fcu.SyncContributionPool, nil, nil,
+		}, nil, blobStorage, nil, vp, nil, engine, fcu.SyncContributionPool, nil, nil,
 		syncCommitteeMessagesService,
 		syncContributionService,
 		aggregateAndProofsService,
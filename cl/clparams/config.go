@@ -54,6 +54,22 @@ type CaplinConfig struct {
 	MevRelayUrl string
 	// EnableValidatorMonitor is used to enable the validator monitor metrics and corresponding logs
 	EnableValidatorMonitor bool
+	// EraDir is optional and is a directory of .era files to backfill the history from instead of the peers
+	EraDir string
+
+	// Embedded validator client, it needs the beacon and validator endpoints of the beacon API
+	EnableValidatorClient bool
+	// ValidatorKeystoresDir and ValidatorSecretsPath are EIP-2335 keystores loaded at startup and their passwords
+	ValidatorKeystoresDir string
+	ValidatorSecretsPath  string
+	// ValidatorRemoteSignerUrl is a web3signer compatible signer whose keys are all used
+	ValidatorRemoteSignerUrl string
+	ValidatorFeeRecipient    libcommon.Address
+	ValidatorGraffiti        string
+	// Keymanager API of the validator client, authenticated with the token of KeymanagerTokenFile
+	KeymanagerAddr      string
+	KeymanagerPort      uint64
+	KeymanagerTokenFile string
 
 	// Devnets config
 	CustomConfigPath       string
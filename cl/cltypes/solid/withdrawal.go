@@ -53,9 +53,9 @@ func (p *WithdrawalRequest) Static() bool {
 }
 
 type PendingPartialWithdrawal struct {
-	Index             uint64 // validator index
-	Amount            uint64 // Gwei
-	WithdrawableEpoch uint64
+	Index             uint64 `json:"validator_index,string"` // validator index
+	Amount            uint64 `json:"amount,string"`          // Gwei
+	WithdrawableEpoch uint64 `json:"withdrawable_epoch,string"`
 }
 
 func (p *PendingPartialWithdrawal) EncodingSizeSSZ() int {
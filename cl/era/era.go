@@ -0,0 +1,488 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era reads and writes the .era archives of the beacon chain history. The era N file holds the
+// blocks of the slots [(N-1)*SLOTS_PER_HISTORICAL_ROOT, N*SLOTS_PER_HISTORICAL_ROOT) and the state at the
+// slot N*SLOTS_PER_HISTORICAL_ROOT; the era 0 file holds the genesis state only:
+//
+//	era := Version | CompressedSignedBeaconBlock* | CompressedBeaconState | SlotIndex(block)? | SlotIndex(state)
+//
+// Blocks and states are snappy-framed SSZ, the slot indices hold the offsets of the entries of each slot,
+// 0 for the slots without a block.
+//
+// See https://github.com/eth-clients/e2store-format-specs/blob/main/formats/era.md
+package era
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/phase1/core/state"
+	"github.com/erigontech/erigon/cl/utils"
+	"github.com/erigontech/erigon/turbo/snapshotsync/e2store"
+)
+
+const (
+	TypeCompressedSignedBeaconBlock uint16 = 0x01
+	TypeCompressedBeaconState       uint16 = 0x02
+	TypeSlotIndex                   uint16 = 0x3269 // "i2"
+
+	Extension = ".era"
+)
+
+// Filename returns the standard name of the era file: <config>-<era>-<era count>-<short historical root>.era
+func Filename(configName string, era uint64, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%05d-%s%s", configName, era, 1, hex.EncodeToString(root[:4]), Extension)
+}
+
+// ParseFilename is the reverse of Filename, files of several eras are not supported
+func ParseFilename(name string) (configName string, era uint64, shortRoot []byte, err error) {
+	base := strings.TrimSuffix(filepath.Base(name), Extension)
+	parts := strings.Split(base, "-")
+	if len(parts) < 4 || base == filepath.Base(name) {
+		return "", 0, nil, fmt.Errorf("malformed era file name %q", name)
+	}
+	configName = strings.Join(parts[:len(parts)-3], "-")
+	if era, err = strconv.ParseUint(parts[len(parts)-3], 10, 64); err != nil {
+		return "", 0, nil, fmt.Errorf("malformed era file name %q: %w", name, err)
+	}
+	if count, err := strconv.ParseUint(parts[len(parts)-2], 10, 64); err != nil || count != 1 {
+		return "", 0, nil, fmt.Errorf("era file %q: only single era files are supported", name)
+	}
+	if shortRoot, err = hex.DecodeString(parts[len(parts)-1]); err != nil || len(shortRoot) != 4 {
+		return "", 0, nil, fmt.Errorf("malformed era file name %q: bad historical root", name)
+	}
+	return configName, era, shortRoot, nil
+}
+
+// Files returns the era files of the config in the dir, by era number
+func Files(dir, configName string) (map[uint64]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := map[uint64]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), Extension) {
+			continue
+		}
+		name, era, _, err := ParseFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if name != configName {
+			continue
+		}
+		if other, ok := files[era]; ok {
+			return nil, fmt.Errorf("two files of era %d: %s, %s", era, other, entry.Name())
+		}
+		files[era] = filepath.Join(dir, entry.Name())
+	}
+	return files, nil
+}
+
+// SortedEras returns the era numbers of the files in ascending order
+func SortedEras(files map[uint64]string) []uint64 {
+	eras := make([]uint64, 0, len(files))
+	for era := range files {
+		eras = append(eras, era)
+	}
+	sort.Slice(eras, func(i, j int) bool { return eras[i] < eras[j] })
+	return eras
+}
+
+// HistoricalRoot returns the root that names the era of the state at its first slot: the genesis validators
+// root for the era 0, otherwise the last historical summary or, before Capella, the last historical root.
+func HistoricalRoot(s *state.CachingBeaconState, era uint64) (common.Hash, error) {
+	if era == 0 {
+		return s.GenesisValidatorsRoot(), nil
+	}
+	// at the Capella boundary the last root may still have gone to the historical roots
+	if s.Version() >= clparams.CapellaVersion && s.HistoricalSummariesLength() > 0 {
+		root, err := s.HistoricalSummary(int(s.HistoricalSummariesLength() - 1)).HashSSZ()
+		return root, err
+	}
+	if s.HistoricalRootsLength() == 0 {
+		return common.Hash{}, fmt.Errorf("era %d: the state has no historical roots", era)
+	}
+	return s.HistoricalRoot(int(s.HistoricalRootsLength() - 1)), nil
+}
+
+// Builder writes an era file: the blocks of the era in slot order, then the state
+type Builder struct {
+	w       *e2store.Writer
+	written int64
+
+	cfg       *clparams.BeaconChainConfig
+	era       uint64
+	startSlot uint64
+	offsets   []int64 // absolute offsets of the blocks by slot, 0 for empty slots
+	lastSlot  int64
+
+	buf    bytes.Buffer
+	snappy *snappy.Writer
+}
+
+func NewBuilder(w io.Writer, beaconCfg *clparams.BeaconChainConfig, era uint64) *Builder {
+	b := &Builder{w: e2store.NewWriter(w), cfg: beaconCfg, era: era, lastSlot: -1}
+	if era > 0 {
+		b.startSlot = (era - 1) * beaconCfg.SlotsPerHistoricalRoot
+		b.offsets = make([]int64, beaconCfg.SlotsPerHistoricalRoot)
+	}
+	return b
+}
+
+// AddBlock appends the block, blocks are added in slot order
+func (b *Builder) AddBlock(block *cltypes.SignedBeaconBlock) error {
+	slot := block.Block.Slot
+	if b.era == 0 || slot < b.startSlot || slot >= b.startSlot+uint64(len(b.offsets)) {
+		return fmt.Errorf("era %d: block of slot %d out of the era", b.era, slot)
+	}
+	if int64(slot) <= b.lastSlot {
+		return fmt.Errorf("era %d: block of slot %d after slot %d", b.era, slot, b.lastSlot)
+	}
+	encoded, err := block.EncodeSSZ(nil)
+	if err != nil {
+		return err
+	}
+	if err := b.writeVersion(); err != nil {
+		return err
+	}
+	b.offsets[slot-b.startSlot] = b.written
+	b.lastSlot = int64(slot)
+	return b.writeCompressed(TypeCompressedSignedBeaconBlock, encoded)
+}
+
+// Finalize writes the state of the first slot of the next era and the slot indices, and returns the
+// historical root that names the file
+func (b *Builder) Finalize(s *state.CachingBeaconState) (common.Hash, error) {
+	stateSlot := b.era * b.cfg.SlotsPerHistoricalRoot
+	if s.Slot() != stateSlot {
+		return common.Hash{}, fmt.Errorf("era %d: state of slot %d, expected %d", b.era, s.Slot(), stateSlot)
+	}
+	root, err := HistoricalRoot(s, b.era)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	encoded, err := s.EncodeSSZ(nil)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.writeVersion(); err != nil {
+		return common.Hash{}, err
+	}
+	stateOffset := b.written
+	if err := b.writeCompressed(TypeCompressedBeaconState, encoded); err != nil {
+		return common.Hash{}, err
+	}
+	if b.era > 0 {
+		if err := b.writeIndex(b.startSlot, b.offsets); err != nil {
+			return common.Hash{}, err
+		}
+	}
+	if err := b.writeIndex(stateSlot, []int64{stateOffset}); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+func (b *Builder) writeVersion() error {
+	if b.written > 0 {
+		return nil
+	}
+	return b.write(e2store.TypeVersion, nil)
+}
+
+// writeIndex writes the slot index, the offsets are relative to the start of the index
+func (b *Builder) writeIndex(startSlot uint64, offsets []int64) error {
+	base := b.written
+	index := make([]byte, 16+8*len(offsets))
+	binary.LittleEndian.PutUint64(index, startSlot)
+	for i, offset := range offsets {
+		if offset != 0 {
+			binary.LittleEndian.PutUint64(index[8+8*i:], uint64(offset-base))
+		}
+	}
+	binary.LittleEndian.PutUint64(index[8+8*len(offsets):], uint64(len(offsets)))
+	return b.write(TypeSlotIndex, index)
+}
+
+func (b *Builder) write(typ uint16, value []byte) error {
+	n, err := b.w.Write(typ, value)
+	b.written += int64(n)
+	return err
+}
+
+func (b *Builder) writeCompressed(typ uint16, data []byte) error {
+	b.buf.Reset()
+	if b.snappy == nil {
+		b.snappy = snappy.NewBufferedWriter(&b.buf)
+	} else {
+		b.snappy.Reset(&b.buf)
+	}
+	if _, err := b.snappy.Write(data); err != nil {
+		return err
+	}
+	if err := b.snappy.Close(); err != nil {
+		return err
+	}
+	return b.write(typ, b.buf.Bytes())
+}
+
+// Era is an open era file
+type Era struct {
+	f   *os.File
+	r   *e2store.Reader
+	cfg *clparams.BeaconChainConfig
+
+	era          uint64
+	startSlot    uint64
+	blockOffsets []int64 // absolute offsets of the blocks by slot, 0 for empty slots
+	stateOffset  int64
+}
+
+// Open opens the era file and reads its slot indices
+func Open(path string, beaconCfg *clparams.BeaconChainConfig) (*Era, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newEra(f, beaconCfg)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return e, nil
+}
+
+func newEra(f *os.File, beaconCfg *clparams.BeaconChainConfig) (*Era, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	e := &Era{f: f, r: e2store.NewReader(f), cfg: beaconCfg}
+	version, _, err := e.r.ReadAt(0)
+	if err != nil {
+		return nil, err
+	}
+	if version.Type != e2store.TypeVersion || len(version.Value) != 0 {
+		return nil, errors.New("era: no version entry")
+	}
+
+	// the state index is the last entry, the block index is right before it
+	stateIndexAt := info.Size() - e2store.HeaderSize - 24
+	stateSlot, offsets, err := e.readIndex(stateIndexAt, 1)
+	if err != nil {
+		return nil, fmt.Errorf("state index: %w", err)
+	}
+	if stateSlot%beaconCfg.SlotsPerHistoricalRoot != 0 {
+		return nil, fmt.Errorf("era: state of slot %d is not at an era boundary", stateSlot)
+	}
+	e.era = stateSlot / beaconCfg.SlotsPerHistoricalRoot
+	e.stateOffset = offsets[0]
+	if e.era == 0 {
+		return e, nil
+	}
+	count := int64(beaconCfg.SlotsPerHistoricalRoot)
+	blockIndexAt := stateIndexAt - e2store.HeaderSize - 16 - 8*count
+	if e.startSlot, e.blockOffsets, err = e.readIndex(blockIndexAt, count); err != nil {
+		return nil, fmt.Errorf("block index: %w", err)
+	}
+	if e.startSlot != stateSlot-beaconCfg.SlotsPerHistoricalRoot {
+		return nil, fmt.Errorf("era: blocks from slot %d with the state of slot %d", e.startSlot, stateSlot)
+	}
+	return e, nil
+}
+
+// readIndex reads the slot index at off, holding count offsets before off
+func (e *Era) readIndex(off int64, count int64) (uint64, []int64, error) {
+	if off < e2store.HeaderSize {
+		return 0, nil, errors.New("era: file too short")
+	}
+	index, err := e.r.ReadValueAt(off, TypeSlotIndex)
+	if err != nil {
+		return 0, nil, err
+	}
+	if int64(len(index)) != 16+8*count || binary.LittleEndian.Uint64(index[len(index)-8:]) != uint64(count) {
+		return 0, nil, errors.New("era: malformed slot index")
+	}
+	startSlot := binary.LittleEndian.Uint64(index)
+	offsets := make([]int64, count)
+	for i := range offsets {
+		rel := int64(binary.LittleEndian.Uint64(index[8+8*i:]))
+		if rel == 0 {
+			continue
+		}
+		offsets[i] = off + rel
+		if offsets[i] < e2store.HeaderSize || offsets[i] >= off {
+			return 0, nil, fmt.Errorf("era: slot %d offset out of the file", startSlot+uint64(i))
+		}
+	}
+	return startSlot, offsets, nil
+}
+
+func (e *Era) Close() error { return e.f.Close() }
+
+// Era is the era number of the file
+func (e *Era) Era() uint64 { return e.era }
+
+// StartSlot is the first slot of the blocks of the file
+func (e *Era) StartSlot() uint64 { return e.startSlot }
+
+// EndSlot is the slot after the last block of the file, which is the slot of the state
+func (e *Era) EndSlot() uint64 { return e.era * e.cfg.SlotsPerHistoricalRoot }
+
+// Block reads the block of the slot, nil if the slot is empty
+func (e *Era) Block(slot uint64) (*cltypes.SignedBeaconBlock, error) {
+	if slot < e.startSlot || slot >= e.EndSlot() {
+		return nil, fmt.Errorf("era: slot %d is out of the range [%d, %d)", slot, e.startSlot, e.EndSlot())
+	}
+	off := e.blockOffsets[slot-e.startSlot]
+	if off == 0 {
+		return nil, nil
+	}
+	data, err := e.readCompressed(off, TypeCompressedSignedBeaconBlock)
+	if err != nil {
+		return nil, fmt.Errorf("slot %d: %w", slot, err)
+	}
+	version := e.cfg.GetCurrentStateVersion(slot / e.cfg.SlotsPerEpoch)
+	block := cltypes.NewSignedBeaconBlock(e.cfg, version)
+	if err := block.DecodeSSZ(data, int(version)); err != nil {
+		return nil, fmt.Errorf("slot %d: %w", slot, err)
+	}
+	if block.Block.Slot != slot {
+		return nil, fmt.Errorf("slot %d: the block is of slot %d", slot, block.Block.Slot)
+	}
+	return block, nil
+}
+
+// State reads the state of the file
+func (e *Era) State() (*state.CachingBeaconState, error) {
+	data, err := e.readCompressed(e.stateOffset, TypeCompressedBeaconState)
+	if err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+	slot, err := utils.ExtractSlotFromSerializedBeaconState(data)
+	if err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+	if slot != e.EndSlot() {
+		return nil, fmt.Errorf("state: of slot %d, expected %d", slot, e.EndSlot())
+	}
+	s := state.New(e.cfg)
+	if err := s.DecodeSSZ(data, int(e.cfg.GetCurrentStateVersion(slot/e.cfg.SlotsPerEpoch))); err != nil {
+		return nil, fmt.Errorf("state: %w", err)
+	}
+	return s, nil
+}
+
+func (e *Era) readCompressed(off int64, typ uint16) ([]byte, error) {
+	value, err := e.r.ReadValueAt(off, typ)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(snappy.NewReader(bytes.NewReader(value)))
+}
+
+// Verify reads the state and all the blocks of the file and checks them against each other: the state must
+// be the one named by the historical root, its block roots and state roots must hash to that root, and each
+// block must have the block root and the state root of its slot and the previous block root as parent.
+// f is called with the blocks in slot order. Returns the state.
+func (e *Era) Verify(shortRoot []byte, f func(*cltypes.SignedBeaconBlock) error) (*state.CachingBeaconState, error) {
+	s, err := e.State()
+	if err != nil {
+		return nil, err
+	}
+	root, err := HistoricalRoot(s, e.era)
+	if err != nil {
+		return nil, err
+	}
+	if shortRoot != nil && !bytes.Equal(shortRoot, root[:len(shortRoot)]) {
+		return nil, fmt.Errorf("era %d: historical root %x, the file is named %x", e.era, root, shortRoot)
+	}
+	if e.era == 0 {
+		return s, nil
+	}
+	blockRoots, stateRoots := s.BlockRoots(), s.StateRoots()
+	blockSummaryRoot, err := blockRoots.HashSSZ()
+	if err != nil {
+		return nil, err
+	}
+	stateSummaryRoot, err := stateRoots.HashSSZ()
+	if err != nil {
+		return nil, err
+	}
+	summaryRoot, err := (&cltypes.HistoricalSummary{BlockSummaryRoot: blockSummaryRoot, StateSummaryRoot: stateSummaryRoot}).HashSSZ()
+	if err != nil {
+		return nil, err
+	}
+	if common.Hash(summaryRoot) != root {
+		return nil, fmt.Errorf("era %d: block and state roots hash to %x, historical root %x", e.era, common.Hash(summaryRoot), root)
+	}
+
+	sphr := e.cfg.SlotsPerHistoricalRoot
+	for slot := e.startSlot; slot < e.EndSlot(); slot++ {
+		expected := blockRoots.Get(int(slot % sphr))
+		var previous common.Hash
+		if slot > e.startSlot {
+			previous = blockRoots.Get(int((slot - 1) % sphr))
+		}
+		block, err := e.Block(slot)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			// an empty slot repeats the root of the last block
+			if slot > e.startSlot && expected != previous {
+				return nil, fmt.Errorf("slot %d: no block, expected block %x", slot, expected)
+			}
+			continue
+		}
+		blockRoot, err := block.Block.HashSSZ()
+		if err != nil {
+			return nil, err
+		}
+		if blockRoot != expected {
+			return nil, fmt.Errorf("slot %d: block root %x, expected %x", slot, common.Hash(blockRoot), expected)
+		}
+		if stateRoot := stateRoots.Get(int(slot % sphr)); block.Block.StateRoot != stateRoot {
+			return nil, fmt.Errorf("slot %d: state root %x, expected %x", slot, block.Block.StateRoot, stateRoot)
+		}
+		if slot > e.startSlot && block.Block.ParentRoot != previous {
+			return nil, fmt.Errorf("slot %d: parent root %x, expected %x", slot, block.Block.ParentRoot, previous)
+		}
+		if f != nil {
+			if err := f(block); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cl/antiquary/tests"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/phase1/core/state"
+)
+
+// testEra makes the era of the bellatrix test blocks, with a state at the era boundary holding their roots
+func testEra(t *testing.T) (*clparams.BeaconChainConfig, uint64, []*cltypes.SignedBeaconBlock, *state.CachingBeaconState) {
+	cfg := clparams.MainnetBeaconConfig
+	cfg.AltairForkEpoch, cfg.BellatrixForkEpoch = 0, 0
+	blocks, _, s := tests.GetBellatrixRandom()
+	sphr := cfg.SlotsPerHistoricalRoot
+	era := blocks[0].Block.Slot/sphr + 1
+
+	// every slot of the era has the root of the last block before it
+	root := blocks[0].Block.ParentRoot
+	next := 0
+	for slot := (era - 1) * sphr; slot < era*sphr; slot++ {
+		stateRoot := common.Hash{byte(slot), byte(slot >> 8)}
+		if next < len(blocks) && blocks[next].Block.Slot == slot {
+			blockRoot, err := blocks[next].Block.HashSSZ()
+			require.NoError(t, err)
+			root, stateRoot = blockRoot, blocks[next].Block.StateRoot
+			next++
+		}
+		s.SetBlockRootAt(int(slot%sphr), root)
+		s.SetStateRootAt(int(slot%sphr), stateRoot)
+	}
+	s.SetSlot(era * sphr)
+	blockSummaryRoot, err := s.BlockRoots().HashSSZ()
+	require.NoError(t, err)
+	stateSummaryRoot, err := s.StateRoots().HashSSZ()
+	require.NoError(t, err)
+	historicalRoot, err := (&cltypes.HistoricalSummary{BlockSummaryRoot: blockSummaryRoot, StateSummaryRoot: stateSummaryRoot}).HashSSZ()
+	require.NoError(t, err)
+	s.AddHistoricalRoot(historicalRoot)
+	return &cfg, era, blocks, s
+}
+
+func writeEra(t *testing.T, dir string, cfg *clparams.BeaconChainConfig, era uint64, blocks []*cltypes.SignedBeaconBlock, s *state.CachingBeaconState) string {
+	tmp := filepath.Join(dir, "building")
+	f, err := os.Create(tmp)
+	require.NoError(t, err)
+	b := NewBuilder(f, cfg, era)
+	for _, block := range blocks {
+		require.NoError(t, b.AddBlock(block))
+	}
+	root, err := b.Finalize(s)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	path := filepath.Join(dir, Filename(cfg.ConfigName, era, root))
+	require.NoError(t, os.Rename(tmp, path))
+	return path
+}
+
+func TestReadWrite(t *testing.T) {
+	cfg, era, blocks, s := testEra(t)
+	dir := t.TempDir()
+	path := writeEra(t, dir, cfg, era, blocks, s)
+
+	e, err := Open(path, cfg)
+	require.NoError(t, err)
+	defer e.Close()
+	require.Equal(t, era, e.Era())
+	require.Equal(t, (era-1)*cfg.SlotsPerHistoricalRoot, e.StartSlot())
+	require.Equal(t, era*cfg.SlotsPerHistoricalRoot, e.EndSlot())
+
+	block, err := e.Block(blocks[3].Block.Slot)
+	require.NoError(t, err)
+	have, err := block.Block.HashSSZ()
+	require.NoError(t, err)
+	want, err := blocks[3].Block.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, want, have)
+	block, err = e.Block(e.StartSlot())
+	require.NoError(t, err)
+	require.Nil(t, block)
+	_, err = e.Block(e.EndSlot())
+	require.Error(t, err)
+
+	st, err := e.State()
+	require.NoError(t, err)
+	have, err = st.HashSSZ()
+	require.NoError(t, err)
+	want, err = s.HashSSZ()
+	require.NoError(t, err)
+	require.Equal(t, want, have)
+
+	configName, fileEra, shortRoot, err := ParseFilename(path)
+	require.NoError(t, err)
+	require.Equal(t, "mainnet", configName)
+	require.Equal(t, era, fileEra)
+	var visited int
+	_, err = e.Verify(shortRoot, func(*cltypes.SignedBeaconBlock) error {
+		visited++
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, len(blocks), visited)
+	_, err = e.Verify([]byte{1, 2, 3, 4}, nil)
+	require.ErrorContains(t, err, "the file is named")
+
+	files, err := Files(dir, "mainnet")
+	require.NoError(t, err)
+	require.Equal(t, map[uint64]string{era: path}, files)
+	files, err = Files(dir, "sepolia")
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestVerifyDetectsCorruption(t *testing.T) {
+	cfg, era, blocks, s := testEra(t)
+	sphr := cfg.SlotsPerHistoricalRoot
+
+	// a missing block
+	path := writeEra(t, t.TempDir(), cfg, era, append(append([]*cltypes.SignedBeaconBlock{}, blocks[:5]...), blocks[6:]...), s)
+	e, err := Open(path, cfg)
+	require.NoError(t, err)
+	_, err = e.Verify(nil, nil)
+	require.ErrorContains(t, err, "no block")
+	require.NoError(t, e.Close())
+
+	// a block root out of the state, the historical root no longer matches
+	blockRoot := s.BlockRoots().Get(int(blocks[5].Block.Slot % sphr))
+	s.SetBlockRootAt(int(blocks[5].Block.Slot%sphr), common.Hash{1})
+	path = writeEra(t, t.TempDir(), cfg, era, blocks, s)
+	e, err = Open(path, cfg)
+	require.NoError(t, err)
+	_, err = e.Verify(nil, nil)
+	require.ErrorContains(t, err, "block and state roots hash to")
+	require.NoError(t, e.Close())
+	s.SetBlockRootAt(int(blocks[5].Block.Slot%sphr), blockRoot)
+
+	// a block out of order
+	b := NewBuilder(&discard{}, cfg, era)
+	require.NoError(t, b.AddBlock(blocks[1]))
+	require.ErrorContains(t, b.AddBlock(blocks[0]), "after slot")
+	require.ErrorContains(t, NewBuilder(&discard{}, cfg, era+1).AddBlock(blocks[0]), "out of the era")
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestStore(t *testing.T) {
+	cfg, era, blocks, s := testEra(t)
+	dir := t.TempDir()
+	writeEra(t, dir, cfg, era, blocks, s)
+	store, err := NewStore(dir, cfg, log.New())
+	require.NoError(t, err)
+	defer store.Close()
+
+	served, ok, err := store.Blocks(blocks[2].Block.Slot, blocks[5].Block.Slot+1)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Len(t, served, 4)
+	require.Equal(t, blocks[2].Block.Slot, served[0].Block.Slot)
+	require.Equal(t, blocks[5].Block.Slot, served[3].Block.Slot)
+
+	// the next era is not in the dir
+	_, ok, err = store.Blocks(era*cfg.SlotsPerHistoricalRoot-1, era*cfg.SlotsPerHistoricalRoot+1)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// a file which fails the verification is dropped
+	corruptDir := t.TempDir()
+	writeEra(t, corruptDir, cfg, era, blocks[1:], s)
+	store, err = NewStore(corruptDir, cfg, log.New())
+	require.NoError(t, err)
+	defer store.Close()
+	_, ok, err = store.Blocks(blocks[2].Block.Slot, blocks[5].Block.Slot+1)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
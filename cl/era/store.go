@@ -0,0 +1,127 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/cltypes"
+)
+
+// Store serves the blocks of a directory of era files. Each file is verified when it is first opened, the
+// files which fail the verification are dropped.
+type Store struct {
+	cfg    *clparams.BeaconChainConfig
+	logger log.Logger
+
+	mu    sync.Mutex
+	files map[uint64]string
+	open  map[uint64]*Era
+}
+
+// NewStore opens the era files of the chain in the dir
+func NewStore(dir string, beaconCfg *clparams.BeaconChainConfig, logger log.Logger) (*Store, error) {
+	files, err := Files(dir, beaconCfg.ConfigName)
+	if err != nil {
+		return nil, err
+	}
+	if eras := SortedEras(files); len(eras) > 0 {
+		logger.Info("[Era] found era files", "dir", dir, "files", len(eras), "from", eras[0], "to", eras[len(eras)-1])
+	}
+	return &Store{cfg: beaconCfg, logger: logger, files: files, open: map[uint64]*Era{}}, nil
+}
+
+// Blocks returns the blocks of the slots [from, to) in slot order. ok is false if a part of the range is in
+// no valid era file.
+func (s *Store) Blocks(from, to uint64) (blocks []*cltypes.SignedBeaconBlock, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if from >= to {
+		return nil, false, nil
+	}
+	sphr := s.cfg.SlotsPerHistoricalRoot
+	firstEra, lastEra := from/sphr+1, (to-1)/sphr+1
+	// the history is read backwards, the later eras are done with
+	for era, e := range s.open {
+		if era > lastEra {
+			e.Close()
+			delete(s.open, era)
+		}
+	}
+	for era := firstEra; era <= lastEra; era++ {
+		if _, err := s.openEra(era); err != nil {
+			s.logger.Warn("[Era] dropping era file", "era", era, "err", err)
+			delete(s.files, era)
+		}
+		if s.open[era] == nil {
+			return nil, false, nil
+		}
+	}
+	for slot := from; slot < to; slot++ {
+		block, err := s.open[slot/sphr+1].Block(slot)
+		if err != nil {
+			return nil, false, err
+		}
+		if block != nil {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, true, nil
+}
+
+// openEra opens and verifies the file of the era, nil if there is none
+func (s *Store) openEra(era uint64) (*Era, error) {
+	if e, ok := s.open[era]; ok {
+		return e, nil
+	}
+	path, ok := s.files[era]
+	if !ok {
+		return nil, nil
+	}
+	_, _, shortRoot, err := ParseFilename(path)
+	if err != nil {
+		return nil, err
+	}
+	e, err := Open(path, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+	if e.Era() != era {
+		e.Close()
+		return nil, fmt.Errorf("%s: holds the era %d", path, e.Era())
+	}
+	if _, err := e.Verify(shortRoot, nil); err != nil {
+		e.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.open[era] = e
+	return e, nil
+}
+
+// Close closes the open era files
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for era, e := range s.open {
+		e.Close()
+		delete(s.open, era)
+	}
+	return nil
+}
@@ -134,6 +134,10 @@ func (cc *ExecutionClientDirect) CurrentHeader(ctx context.Context) (*types.Head
 	return cc.chainRW.CurrentHeader(ctx), nil
 }
 
+func (cc *ExecutionClientDirect) GetHeaderByHash(ctx context.Context, hash libcommon.Hash) (*types.Header, error) {
+	return cc.chainRW.GetHeaderByHash(ctx, hash), nil
+}
+
 func (cc *ExecutionClientDirect) IsCanonicalHash(ctx context.Context, hash libcommon.Hash) (bool, error) {
 	return cc.chainRW.IsCanonicalHash(ctx, hash)
 }
@@ -217,6 +217,15 @@ func (cc *ExecutionClientRpc) CurrentHeader(ctx context.Context) (*types.Header,
 	panic("unimplemented")
 }
 
+// GetHeaderByHash reads the header through eth_getBlockByHash, which the authenticated engine endpoint also serves.
+func (cc *ExecutionClientRpc) GetHeaderByHash(ctx context.Context, hash libcommon.Hash) (*types.Header, error) {
+	var header *types.Header
+	if err := cc.client.CallContext(ctx, &header, rpc_helper.GetBlockByHash, hash, false); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
 func (cc *ExecutionClientRpc) IsCanonicalHash(ctx context.Context, hash libcommon.Hash) (bool, error) {
 	panic("unimplemented")
 }
@@ -41,6 +41,7 @@ type ExecutionEngine interface {
 	InsertBlocks(ctx context.Context, blocks []*types.Block, wait bool) error
 	InsertBlock(ctx context.Context, block *types.Block) error
 	CurrentHeader(ctx context.Context) (*types.Header, error)
+	GetHeaderByHash(ctx context.Context, hash libcommon.Hash) (*types.Header, error)
 	IsCanonicalHash(ctx context.Context, hash libcommon.Hash) (bool, error)
 	Ready(ctx context.Context) (bool, error)
 	// Range methods
@@ -30,6 +30,7 @@ import (
 	"github.com/erigontech/erigon-lib/kv"
 
 	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/era"
 	"github.com/erigontech/erigon/cl/persistence/base_encoding"
 	"github.com/erigontech/erigon/cl/persistence/beacon_indicies"
 	"github.com/erigontech/erigon/cl/phase1/execution_client"
@@ -52,6 +53,7 @@ type BackwardBeaconDownloader struct {
 	db             kv.RwDB
 	sn             *freezeblocks.CaplinSnapshots
 	neverSkip      bool
+	eraStore       *era.Store
 
 	mu sync.Mutex
 }
@@ -94,6 +96,13 @@ func (b *BackwardBeaconDownloader) SetNeverSkip(neverSkip bool) {
 	b.neverSkip = neverSkip
 }
 
+// SetEraStore sets the era files to read the blocks from before asking the peers.
+func (b *BackwardBeaconDownloader) SetEraStore(eraStore *era.Store) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.eraStore = eraStore
+}
+
 // SetShouldStopAtFn sets the stop condition.
 func (b *BackwardBeaconDownloader) SetOnNewBlock(onNewBlock OnNewBlock) {
 	b.mu.Lock()
@@ -124,6 +133,7 @@ func (b *BackwardBeaconDownloader) Peers() (uint64, error) {
 // It then processes the response by iterating over the blocks in reverse order and calling a provided callback function onNewBlock on each block.
 // If the callback returns an error or signals that the download should be finished, the function will exit.
 // If the block's root hash does not match the expected root hash, it will be rejected and the function will continue to the next block.
+// If the era files are set and hold the range, the blocks are read from them instead of the peers.
 func (b *BackwardBeaconDownloader) RequestMore(ctx context.Context) error {
 	count := uint64(64)
 	start := b.slotToDownload.Load() - count + 1
@@ -134,6 +144,15 @@ func (b *BackwardBeaconDownloader) RequestMore(ctx context.Context) error {
 	var atomicResp atomic.Value
 	atomicResp.Store([]*cltypes.SignedBeaconBlock{})
 
+	if fromEra, served := b.eraBlocks(start); served {
+		if len(fromEra) == 0 {
+			// the era files have no blocks in the range
+			b.slotToDownload.Store(start - 1)
+			return nil
+		}
+		atomicResp.Store(fromEra)
+	}
+
 Loop:
 	for {
 		select {
@@ -290,3 +309,35 @@ Loop:
 
 	return tx.Commit()
 }
+
+// eraBlocks reads the blocks from start to the slot to download from the era files. served is false if the era
+// files do not have the range or the expected block is not in it, then the blocks are asked to the peers.
+func (b *BackwardBeaconDownloader) eraBlocks(start uint64) (blocks []*cltypes.SignedBeaconBlock, served bool) {
+	if b.eraStore == nil {
+		return nil, false
+	}
+	blocks, ok, err := b.eraStore.Blocks(start, b.slotToDownload.Load()+1)
+	if err != nil {
+		log.Warn("Could not read blocks from the era files", "from", start, "err", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	// the era files are verified on their own, they must also be on the chain we are walking back
+	for _, block := range blocks {
+		blockRoot, err := block.Block.HashSSZ()
+		if err != nil {
+			return nil, false
+		}
+		if blockRoot == b.expectedRoot {
+			return blocks, true
+		}
+	}
+	// an empty range is fine as long as the expected block is before it, the genesis block is never in the era files
+	if len(blocks) == 0 && start > 0 {
+		return nil, true
+	}
+	log.Debug("Expected root is not in the era files", "from", start, "expected", b.expectedRoot)
+	return nil, false
+}
@@ -0,0 +1,326 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package keystore reads and writes EIP-2335 BLS keystores.
+package keystore
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Giulio2002/bls"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/text/unicode/norm"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+)
+
+const (
+	Version = 4
+
+	kdfScrypt     = "scrypt"
+	kdfPBKDF2     = "pbkdf2"
+	checksumSHA   = "sha256"
+	cipherAES     = "aes-128-ctr"
+	prfHMACSHA256 = "hmac-sha256"
+)
+
+var ErrInvalidPassword = errors.New("invalid keystore password")
+
+type Module struct {
+	Function string                 `json:"function"`
+	Params   map[string]interface{} `json:"params"`
+	Message  hexBytes               `json:"message"`
+}
+
+type Crypto struct {
+	KDF      Module `json:"kdf"`
+	Checksum Module `json:"checksum"`
+	Cipher   Module `json:"cipher"`
+}
+
+// Keystore is an EIP-2335 keystore, the secret key in it is encrypted with a password
+type Keystore struct {
+	Crypto      Crypto   `json:"crypto"`
+	Description string   `json:"description"`
+	Pubkey      hexBytes `json:"pubkey"`
+	Path        string   `json:"path"`
+	UUID        string   `json:"uuid"`
+	Version     int      `json:"version"`
+}
+
+// hexBytes is hex encoded without the 0x prefix, as in the EIP, but a prefix is tolerated
+type hexBytes []byte
+
+func (h hexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *hexBytes) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x"))
+	if err != nil {
+		return err
+	}
+	*h = b
+	return nil
+}
+
+// Parse decodes a keystore from its JSON
+func Parse(data []byte) (*Keystore, error) {
+	k := &Keystore{}
+	if err := json.Unmarshal(data, k); err != nil {
+		return nil, err
+	}
+	if k.Version != Version {
+		return nil, fmt.Errorf("unsupported keystore version %d", k.Version)
+	}
+	if len(k.Pubkey) != 0 && len(k.Pubkey) != 48 {
+		return nil, fmt.Errorf("invalid keystore pubkey length %d", len(k.Pubkey))
+	}
+	return k, nil
+}
+
+// PublicKey is the public key the keystore claims to hold
+func (k *Keystore) PublicKey() libcommon.Bytes48 {
+	var pk libcommon.Bytes48
+	copy(pk[:], k.Pubkey)
+	return pk
+}
+
+// Decrypt returns the secret key of the keystore
+func (k *Keystore) Decrypt(password string) (*bls.PrivateKey, error) {
+	key, err := k.decryptionKey(password)
+	if err != nil {
+		return nil, err
+	}
+	if k.Crypto.Checksum.Function != checksumSHA {
+		return nil, fmt.Errorf("unsupported checksum function %q", k.Crypto.Checksum.Function)
+	}
+	checksum := sha256.Sum256(append(append([]byte{}, key[16:32]...), k.Crypto.Cipher.Message...))
+	if !bytes.Equal(checksum[:], k.Crypto.Checksum.Message) {
+		return nil, ErrInvalidPassword
+	}
+	if k.Crypto.Cipher.Function != cipherAES {
+		return nil, fmt.Errorf("unsupported cipher function %q", k.Crypto.Cipher.Function)
+	}
+	iv, err := hexParam(k.Crypto.Cipher.Params, "iv")
+	if err != nil {
+		return nil, err
+	}
+	secret, err := aes128CTR(key[:16], iv, k.Crypto.Cipher.Message)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := bls.NewPrivateKeyFromBytes(secret)
+	if err != nil {
+		return nil, err
+	}
+	if len(k.Pubkey) > 0 && !bytes.Equal(bls.CompressPublicKey(sk.PublicKey()), k.Pubkey) {
+		return nil, errors.New("keystore secret does not match its pubkey")
+	}
+	return sk, nil
+}
+
+func (k *Keystore) decryptionKey(password string) ([]byte, error) {
+	params := k.Crypto.KDF.Params
+	salt, err := hexParam(params, "salt")
+	if err != nil {
+		return nil, err
+	}
+	dklen, err := intParam(params, "dklen")
+	if err != nil {
+		return nil, err
+	}
+	if dklen < 32 {
+		return nil, fmt.Errorf("kdf dklen %d is too short", dklen)
+	}
+	pass := normalizePassword(password)
+	switch k.Crypto.KDF.Function {
+	case kdfScrypt:
+		n, err := intParam(params, "n")
+		if err != nil {
+			return nil, err
+		}
+		r, err := intParam(params, "r")
+		if err != nil {
+			return nil, err
+		}
+		p, err := intParam(params, "p")
+		if err != nil {
+			return nil, err
+		}
+		return scrypt.Key(pass, salt, n, r, p, dklen)
+	case kdfPBKDF2:
+		c, err := intParam(params, "c")
+		if err != nil {
+			return nil, err
+		}
+		if prf, _ := params["prf"].(string); prf != prfHMACSHA256 {
+			return nil, fmt.Errorf("unsupported pbkdf2 prf %q", prf)
+		}
+		return pbkdf2.Key(pass, salt, c, dklen, sha256.New), nil
+	}
+	return nil, fmt.Errorf("unsupported kdf function %q", k.Crypto.KDF.Function)
+}
+
+// Encrypt makes a keystore of the secret key, the key is derived with pbkdf2 of the given number of iterations
+func Encrypt(sk *bls.PrivateKey, password string, iterations int) (*Keystore, error) {
+	salt := make([]byte, 32)
+	iv := make([]byte, 16)
+	id := make([]byte, 16)
+	for _, b := range [][]byte{salt, iv, id} {
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+	}
+	key := pbkdf2.Key(normalizePassword(password), salt, iterations, 32, sha256.New)
+	message, err := aes128CTR(key[:16], iv, sk.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	checksum := sha256.Sum256(append(append([]byte{}, key[16:32]...), message...))
+	// uuid v4
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return &Keystore{
+		Crypto: Crypto{
+			KDF: Module{
+				Function: kdfPBKDF2,
+				Params:   map[string]interface{}{"dklen": 32, "c": iterations, "prf": prfHMACSHA256, "salt": hex.EncodeToString(salt)},
+				Message:  hexBytes{},
+			},
+			Checksum: Module{Function: checksumSHA, Params: map[string]interface{}{}, Message: checksum[:]},
+			Cipher:   Module{Function: cipherAES, Params: map[string]interface{}{"iv": hex.EncodeToString(iv)}, Message: message},
+		},
+		Pubkey:  bls.CompressPublicKey(sk.PublicKey()),
+		UUID:    fmt.Sprintf("%x-%x-%x-%x-%x", id[:4], id[4:6], id[6:8], id[8:10], id[10:]),
+		Version: Version,
+	}, nil
+}
+
+// normalizePassword applies the NFKD normalization and strips the control codes, as required by the EIP
+func normalizePassword(password string) []byte {
+	return []byte(strings.Map(func(r rune) rune {
+		if r < 0x20 || (r >= 0x7f && r <= 0x9f) {
+			return -1
+		}
+		return r
+	}, norm.NFKD.String(password)))
+}
+
+func aes128CTR(key, iv, in []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() {
+		return nil, fmt.Errorf("invalid cipher iv length %d", len(iv))
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+func hexParam(params map[string]interface{}, name string) ([]byte, error) {
+	s, ok := params[name].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing %s param", name)
+	}
+	return hex.DecodeString(strings.TrimPrefix(s, "0x"))
+}
+
+func intParam(params map[string]interface{}, name string) (int, error) {
+	switch v := params[name].(type) {
+	case float64:
+		if v < 0 || v != float64(int(v)) {
+			return 0, fmt.Errorf("invalid %s param %v", name, v)
+		}
+		return int(v), nil
+	case int:
+		return v, nil
+	}
+	return 0, fmt.Errorf("missing %s param", name)
+}
+
+// Key is a decrypted keystore
+type Key struct {
+	Keystore  *Keystore
+	SecretKey *bls.PrivateKey
+}
+
+// LoadDir decrypts the keystores of the dir. The password of a keystore is in the file of the secrets dir
+// named after its pubkey, or in a single password file for all of them.
+func LoadDir(keystoresDir, secretsPath string) ([]Key, error) {
+	entries, err := os.ReadDir(keystoresDir)
+	if err != nil {
+		return nil, err
+	}
+	var sharedPassword *string
+	if info, err := os.Stat(secretsPath); err == nil && !info.IsDir() {
+		password, err := readPassword(secretsPath)
+		if err != nil {
+			return nil, err
+		}
+		sharedPassword = &password
+	}
+	var keys []Key
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(keystoresDir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		k, err := Parse(data)
+		if err != nil {
+			// deposit data and the like live next to the keystores
+			continue
+		}
+		var password string
+		if sharedPassword != nil {
+			password = *sharedPassword
+		} else if password, err = readPassword(filepath.Join(secretsPath, "0x"+hex.EncodeToString(k.Pubkey))); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		sk, err := k.Decrypt(password)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keys = append(keys, Key{Keystore: k, SecretKey: sk})
+	}
+	return keys, nil
+}
+
+func readPassword(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
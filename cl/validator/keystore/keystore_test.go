@@ -0,0 +1,117 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Giulio2002/bls"
+	"github.com/stretchr/testify/require"
+)
+
+// the pbkdf2 test vector of the EIP
+const pbkdf2Keystore = `{
+	"crypto": {
+		"kdf": {
+			"function": "pbkdf2",
+			"params": {
+				"dklen": 32,
+				"c": 262144,
+				"prf": "hmac-sha256",
+				"salt": "d4e56740f876aef8c010b86a40d5f56745a118d0906a34e69aec8c0db1cb8fa3"
+			},
+			"message": ""
+		},
+		"checksum": {
+			"function": "sha256",
+			"params": {},
+			"message": "8a9f5d9912ed7e75ea794bc5a89bca5f193721d30868ade6f73043c6ea6febf1"
+		},
+		"cipher": {
+			"function": "aes-128-ctr",
+			"params": {
+				"iv": "264daa3f303d7259501c93d997d84fe6"
+			},
+			"message": "cee03fde2af33149775b7223e7845e4fb2c8ae1792e5f99fe9ecf474cc8c16ad"
+		}
+	},
+	"description": "This is a test keystore that uses PBKDF2 to secure the secret.",
+	"pubkey": "9612d7a727c9d0a22e185a1c768478dfe919cada9266988cb32359c11f2b7b27f4ae4040902382ae2910c15e2b420d07",
+	"path": "m/12381/60/0/0",
+	"uuid": "64625def-3331-4eea-ab6f-782f3ed16a83",
+	"version": 4
+}`
+
+const testPassword = "\U0001d531\U0001d522\U0001d530\U0001d531\U0001d52d\U0001d51e\U0001d530\U0001d530\U0001d534\U0001d52c\U0001d52f\U0001d521\U0001f511"
+
+func TestDecryptTestVector(t *testing.T) {
+	k, err := Parse([]byte(pbkdf2Keystore))
+	require.NoError(t, err)
+	sk, err := k.Decrypt(testPassword)
+	require.NoError(t, err)
+	require.Equal(t, "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f", hex.EncodeToString(sk.Bytes()))
+
+	_, err = k.Decrypt("testpassword")
+	require.ErrorIs(t, err, ErrInvalidPassword)
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	sk, err := bls.GenerateKey()
+	require.NoError(t, err)
+	k, err := Encrypt(sk, "pass\u0007word", 1024)
+	require.NoError(t, err)
+	data, err := json.Marshal(k)
+	require.NoError(t, err)
+
+	k, err = Parse(data)
+	require.NoError(t, err)
+	// the control codes are stripped from the password
+	decrypted, err := k.Decrypt("password")
+	require.NoError(t, err)
+	require.Equal(t, sk.Bytes(), decrypted.Bytes())
+	pk := k.PublicKey()
+	require.Equal(t, bls.CompressPublicKey(sk.PublicKey()), pk[:])
+}
+
+func TestLoadDir(t *testing.T) {
+	keystores, secrets := t.TempDir(), t.TempDir()
+	var want [][]byte
+	for i := 0; i < 2; i++ {
+		sk, err := bls.GenerateKey()
+		require.NoError(t, err)
+		k, err := Encrypt(sk, "secret", 1024)
+		require.NoError(t, err)
+		data, err := json.Marshal(k)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(keystores, k.UUID+".json"), data, 0600))
+		require.NoError(t, os.WriteFile(filepath.Join(secrets, "0x"+hex.EncodeToString(k.Pubkey)), []byte("secret\n"), 0600))
+		want = append(want, sk.Bytes())
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(keystores, "deposit_data.json"), []byte("[]"), 0600))
+
+	keys, err := LoadDir(keystores, secrets)
+	require.NoError(t, err)
+	var have [][]byte
+	for _, key := range keys {
+		have = append(have, key.SecretKey.Bytes())
+	}
+	require.ElementsMatch(t, want, have)
+}
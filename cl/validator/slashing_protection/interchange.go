@@ -0,0 +1,197 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package slashing_protection
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+)
+
+// InterchangeFormatVersion is the version of the EIP-3076 interchange format
+const InterchangeFormatVersion = "5"
+
+type Interchange struct {
+	Metadata InterchangeMetadata `json:"metadata"`
+	Data     []InterchangeData   `json:"data"`
+}
+
+type InterchangeMetadata struct {
+	InterchangeFormatVersion string         `json:"interchange_format_version"`
+	GenesisValidatorsRoot    libcommon.Hash `json:"genesis_validators_root"`
+}
+
+type InterchangeData struct {
+	Pubkey             libcommon.Bytes48   `json:"pubkey"`
+	SignedBlocks       []SignedBlock       `json:"signed_blocks"`
+	SignedAttestations []SignedAttestation `json:"signed_attestations"`
+}
+
+type SignedBlock struct {
+	Slot        uint64          `json:"slot,string"`
+	SigningRoot *libcommon.Hash `json:"signing_root,omitempty"`
+}
+
+type SignedAttestation struct {
+	SourceEpoch uint64          `json:"source_epoch,string"`
+	TargetEpoch uint64          `json:"target_epoch,string"`
+	SigningRoot *libcommon.Hash `json:"signing_root,omitempty"`
+}
+
+// Import adds the records of the interchange data. The watermarks are raised to the latest imported records,
+// so nothing at or before them is signed afterwards.
+func (p *SlashingProtection) Import(ctx context.Context, interchange *Interchange) error {
+	if interchange.Metadata.InterchangeFormatVersion != InterchangeFormatVersion {
+		return fmt.Errorf("unsupported interchange format version %q", interchange.Metadata.InterchangeFormatVersion)
+	}
+	if interchange.Metadata.GenesisValidatorsRoot != p.genesisValidatorsRoot {
+		return fmt.Errorf("%w: genesis validators root %x, expected %x", ErrGenesisMismatch, interchange.Metadata.GenesisValidatorsRoot, p.genesisValidatorsRoot)
+	}
+	return p.db.Update(ctx, func(tx kv.RwTx) error {
+		for _, data := range interchange.Data {
+			if len(data.SignedBlocks) == 0 && len(data.SignedAttestations) == 0 {
+				continue
+			}
+			wm, err := readWatermark(tx, data.Pubkey)
+			if err != nil {
+				return err
+			}
+			wm.set = true
+			for _, block := range data.SignedBlocks {
+				key := recordKey(data.Pubkey, block.Slot)
+				if ok, err := tx.Has(SignedBlocks, key); err != nil {
+					return err
+				} else if !ok {
+					var root libcommon.Hash
+					if block.SigningRoot != nil {
+						root = *block.SigningRoot
+					}
+					if err := tx.Put(SignedBlocks, key, root[:]); err != nil {
+						return err
+					}
+				}
+				wm.slot = max(wm.slot, block.Slot)
+			}
+			for _, att := range data.SignedAttestations {
+				if att.SourceEpoch > att.TargetEpoch {
+					return fmt.Errorf("%w: %x source %d, target %d", ErrInvalidVote, data.Pubkey, att.SourceEpoch, att.TargetEpoch)
+				}
+				key := recordKey(data.Pubkey, att.TargetEpoch)
+				if ok, err := tx.Has(SignedAttestations, key); err != nil {
+					return err
+				} else if !ok {
+					var root libcommon.Hash
+					if att.SigningRoot != nil {
+						root = *att.SigningRoot
+					}
+					if err := tx.Put(SignedAttestations, key, attestationValue(att.SourceEpoch, root)); err != nil {
+						return err
+					}
+				}
+				wm.source, wm.target = max(wm.source, att.SourceEpoch), max(wm.target, att.TargetEpoch)
+			}
+			if err := writeWatermark(tx, data.Pubkey, wm); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Export returns the records of the keys, of all of them if pubkeys is nil. The watermarks are exported as
+// records without signing roots.
+func (p *SlashingProtection) Export(ctx context.Context, pubkeys []libcommon.Bytes48) (*Interchange, error) {
+	interchange := &Interchange{
+		Metadata: InterchangeMetadata{
+			InterchangeFormatVersion: InterchangeFormatVersion,
+			GenesisValidatorsRoot:    p.genesisValidatorsRoot,
+		},
+		Data: []InterchangeData{},
+	}
+	if err := p.db.View(ctx, func(tx kv.Tx) error {
+		if pubkeys == nil {
+			var err error
+			if pubkeys, err = allPubkeys(tx); err != nil {
+				return err
+			}
+		}
+		for _, pubkey := range pubkeys {
+			data, err := export(tx, pubkey)
+			if err != nil {
+				return err
+			}
+			interchange.Data = append(interchange.Data, data)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return interchange, nil
+}
+
+func export(tx kv.Tx, pubkey libcommon.Bytes48) (InterchangeData, error) {
+	data := InterchangeData{Pubkey: pubkey, SignedBlocks: []SignedBlock{}, SignedAttestations: []SignedAttestation{}}
+	wm, err := readWatermark(tx, pubkey)
+	if err != nil {
+		return data, err
+	}
+	slots, err := records(tx, SignedBlocks, pubkey)
+	if err != nil {
+		return data, err
+	}
+	for _, slot := range slots {
+		root, err := tx.GetOne(SignedBlocks, recordKey(pubkey, slot))
+		if err != nil {
+			return data, err
+		}
+		data.SignedBlocks = append(data.SignedBlocks, SignedBlock{Slot: slot, SigningRoot: signingRoot(root)})
+	}
+	if wm.slot > 0 && (len(slots) == 0 || slots[len(slots)-1] < wm.slot) {
+		data.SignedBlocks = append(data.SignedBlocks, SignedBlock{Slot: wm.slot})
+	}
+	targets, err := records(tx, SignedAttestations, pubkey)
+	if err != nil {
+		return data, err
+	}
+	for _, target := range targets {
+		v, err := tx.GetOne(SignedAttestations, recordKey(pubkey, target))
+		if err != nil {
+			return data, err
+		}
+		data.SignedAttestations = append(data.SignedAttestations, SignedAttestation{
+			SourceEpoch: binary.BigEndian.Uint64(v[:8]),
+			TargetEpoch: target,
+			SigningRoot: signingRoot(v[8:]),
+		})
+	}
+	if wm.target > 0 && (len(targets) == 0 || targets[len(targets)-1] < wm.target) {
+		data.SignedAttestations = append(data.SignedAttestations, SignedAttestation{SourceEpoch: wm.source, TargetEpoch: wm.target})
+	}
+	return data, nil
+}
+
+// signingRoot is nil for the records imported without a signing root
+func signingRoot(b []byte) *libcommon.Hash {
+	root := libcommon.BytesToHash(b)
+	if root == (libcommon.Hash{}) {
+		return nil
+	}
+	return &root
+}
@@ -0,0 +1,344 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package slashing_protection keeps the record of what the validators signed and refuses to sign anything
+// slashable, it imports and exports the records in the EIP-3076 interchange format.
+package slashing_protection
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/mdbx"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cl/clparams"
+)
+
+const (
+	// SignedBlocks is pubkey + slot -> signing root
+	SignedBlocks = "SignedBlocks"
+	// SignedAttestations is pubkey + target epoch -> source epoch + signing root
+	SignedAttestations = "SignedAttestations"
+	// Watermarks is pubkey -> block slot + attestation source epoch + attestation target epoch. Nothing at
+	// or below them is signed, they are raised by the imports and the pruning.
+	Watermarks = "Watermarks"
+	// Metadata holds the genesis validators root the records are for
+	Metadata = "Metadata"
+)
+
+var tablesCfg = kv.TableCfg{
+	SignedBlocks:       {},
+	SignedAttestations: {},
+	Watermarks:         {},
+	Metadata:           {},
+}
+
+var genesisValidatorsRootKey = []byte("genesis_validators_root")
+
+var (
+	ErrDoubleProposal  = errors.New("double proposal")
+	ErrDoubleVote      = errors.New("double vote")
+	ErrSurroundVote    = errors.New("surround vote")
+	ErrBelowWatermark  = errors.New("below the slashing protection watermark")
+	ErrInvalidVote     = errors.New("attestation source is after its target")
+	ErrGenesisMismatch = errors.New("slashing protection data is for another chain")
+)
+
+// SlashingProtection is the slashing protection database of the validator client
+type SlashingProtection struct {
+	db                    kv.RwDB
+	genesisValidatorsRoot libcommon.Hash
+	slotsPerEpoch         uint64
+	pruningEpochs         uint64
+}
+
+// Open opens the database in the dir, it refuses a database of another chain
+func Open(ctx context.Context, dir string, beaconCfg *clparams.BeaconChainConfig, genesisValidatorsRoot libcommon.Hash, logger log.Logger) (*SlashingProtection, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	db, err := mdbx.New(kv.CaplinDB, logger).Path(dir).
+		WithTableCfg(func(kv.TableCfg) kv.TableCfg { return tablesCfg }).
+		Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(ctx, func(tx kv.RwTx) error {
+		root, err := tx.GetOne(Metadata, genesisValidatorsRootKey)
+		if err != nil {
+			return err
+		}
+		if root == nil {
+			return tx.Put(Metadata, genesisValidatorsRootKey, genesisValidatorsRoot[:])
+		}
+		if !bytes.Equal(root, genesisValidatorsRoot[:]) {
+			return fmt.Errorf("%w: genesis validators root %x, expected %x", ErrGenesisMismatch, root, genesisValidatorsRoot)
+		}
+		return nil
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SlashingProtection{
+		db:                    db,
+		genesisValidatorsRoot: genesisValidatorsRoot,
+		slotsPerEpoch:         beaconCfg.SlotsPerEpoch,
+		pruningEpochs:         beaconCfg.SlashingProtectionPruningEpochs,
+	}, nil
+}
+
+func (p *SlashingProtection) Close() {
+	p.db.Close()
+}
+
+// CheckAndInsertBlock records the proposal of the block with the signing root, or returns an error if signing
+// it could be slashed. Signing the same block again is allowed.
+func (p *SlashingProtection) CheckAndInsertBlock(ctx context.Context, pubkey libcommon.Bytes48, slot uint64, signingRoot libcommon.Hash) error {
+	return p.db.Update(ctx, func(tx kv.RwTx) error {
+		return checkAndInsertBlock(tx, pubkey, slot, signingRoot)
+	})
+}
+
+func checkAndInsertBlock(tx kv.RwTx, pubkey libcommon.Bytes48, slot uint64, signingRoot libcommon.Hash) error {
+	key := recordKey(pubkey, slot)
+	root, err := tx.GetOne(SignedBlocks, key)
+	if err != nil {
+		return err
+	}
+	if root != nil {
+		if signingRoot != (libcommon.Hash{}) && bytes.Equal(root, signingRoot[:]) {
+			return nil
+		}
+		return fmt.Errorf("%w: slot %d is already signed", ErrDoubleProposal, slot)
+	}
+	wm, err := readWatermark(tx, pubkey)
+	if err != nil {
+		return err
+	}
+	if wm.set && slot <= wm.slot {
+		return fmt.Errorf("%w: slot %d, watermark %d", ErrBelowWatermark, slot, wm.slot)
+	}
+	return tx.Put(SignedBlocks, key, signingRoot[:])
+}
+
+// CheckAndInsertAttestation records the vote, or returns an error if signing it could be slashed. Signing the
+// same attestation again is allowed.
+func (p *SlashingProtection) CheckAndInsertAttestation(ctx context.Context, pubkey libcommon.Bytes48, source, target uint64, signingRoot libcommon.Hash) error {
+	return p.db.Update(ctx, func(tx kv.RwTx) error {
+		return checkAndInsertAttestation(tx, pubkey, source, target, signingRoot)
+	})
+}
+
+func checkAndInsertAttestation(tx kv.RwTx, pubkey libcommon.Bytes48, source, target uint64, signingRoot libcommon.Hash) error {
+	if source > target {
+		return fmt.Errorf("%w: source %d, target %d", ErrInvalidVote, source, target)
+	}
+	key := recordKey(pubkey, target)
+	v, err := tx.GetOne(SignedAttestations, key)
+	if err != nil {
+		return err
+	}
+	if v != nil {
+		if signingRoot != (libcommon.Hash{}) && bytes.Equal(v[8:], signingRoot[:]) {
+			return nil
+		}
+		return fmt.Errorf("%w: target %d is already signed", ErrDoubleVote, target)
+	}
+	wm, err := readWatermark(tx, pubkey)
+	if err != nil {
+		return err
+	}
+	if wm.set && (source < wm.source || target <= wm.target) {
+		return fmt.Errorf("%w: source %d, target %d, watermark source %d, target %d", ErrBelowWatermark, source, target, wm.source, wm.target)
+	}
+	c, err := tx.Cursor(SignedAttestations)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, v, err := c.Seek(pubkey[:]); k != nil; k, v, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, pubkey[:]) {
+			break
+		}
+		prevSource, prevTarget := binary.BigEndian.Uint64(v[:8]), binary.BigEndian.Uint64(k[48:])
+		if source < prevSource && prevTarget < target {
+			return fmt.Errorf("%w: source %d, target %d surrounds source %d, target %d", ErrSurroundVote, source, target, prevSource, prevTarget)
+		}
+		if prevSource < source && target < prevTarget {
+			return fmt.Errorf("%w: source %d, target %d is surrounded by source %d, target %d", ErrSurroundVote, source, target, prevSource, prevTarget)
+		}
+	}
+	return tx.Put(SignedAttestations, key, attestationValue(source, signingRoot))
+}
+
+// Prune drops the records older than the pruning distance behind the latest ones of each key, the watermarks
+// are raised so that nothing conflicting with the dropped records can be signed.
+func (p *SlashingProtection) Prune(ctx context.Context) error {
+	return p.db.Update(ctx, func(tx kv.RwTx) error {
+		pubkeys, err := allPubkeys(tx)
+		if err != nil {
+			return err
+		}
+		for _, pubkey := range pubkeys {
+			if err := p.prune(tx, pubkey); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (p *SlashingProtection) prune(tx kv.RwTx, pubkey libcommon.Bytes48) error {
+	wm, err := readWatermark(tx, pubkey)
+	if err != nil {
+		return err
+	}
+	raised := false
+	blocks, err := records(tx, SignedBlocks, pubkey)
+	if err != nil {
+		return err
+	}
+	if len(blocks) > 0 && blocks[len(blocks)-1] > p.pruningEpochs*p.slotsPerEpoch {
+		below := blocks[len(blocks)-1] - p.pruningEpochs*p.slotsPerEpoch
+		for _, slot := range blocks {
+			if slot >= below {
+				break
+			}
+			if err := tx.Delete(SignedBlocks, recordKey(pubkey, slot)); err != nil {
+				return err
+			}
+			wm.slot, raised = max(wm.slot, slot), true
+		}
+	}
+	targets, err := records(tx, SignedAttestations, pubkey)
+	if err != nil {
+		return err
+	}
+	if len(targets) > 0 && targets[len(targets)-1] > p.pruningEpochs {
+		below := targets[len(targets)-1] - p.pruningEpochs
+		for _, target := range targets {
+			if target >= below {
+				break
+			}
+			key := recordKey(pubkey, target)
+			v, err := tx.GetOne(SignedAttestations, key)
+			if err != nil {
+				return err
+			}
+			if err := tx.Delete(SignedAttestations, key); err != nil {
+				return err
+			}
+			wm.source, wm.target, raised = max(wm.source, binary.BigEndian.Uint64(v[:8])), max(wm.target, target), true
+		}
+	}
+	if !raised {
+		return nil
+	}
+	wm.set = true
+	return writeWatermark(tx, pubkey, wm)
+}
+
+type watermark struct {
+	set                  bool
+	slot, source, target uint64
+}
+
+func readWatermark(tx kv.Getter, pubkey libcommon.Bytes48) (watermark, error) {
+	v, err := tx.GetOne(Watermarks, pubkey[:])
+	if err != nil || v == nil {
+		return watermark{}, err
+	}
+	if len(v) != 24 {
+		return watermark{}, fmt.Errorf("invalid watermark of %x", pubkey)
+	}
+	return watermark{
+		set:    true,
+		slot:   binary.BigEndian.Uint64(v),
+		source: binary.BigEndian.Uint64(v[8:]),
+		target: binary.BigEndian.Uint64(v[16:]),
+	}, nil
+}
+
+func writeWatermark(tx kv.RwTx, pubkey libcommon.Bytes48, wm watermark) error {
+	v := make([]byte, 24)
+	binary.BigEndian.PutUint64(v, wm.slot)
+	binary.BigEndian.PutUint64(v[8:], wm.source)
+	binary.BigEndian.PutUint64(v[16:], wm.target)
+	return tx.Put(Watermarks, pubkey[:], v)
+}
+
+func recordKey(pubkey libcommon.Bytes48, n uint64) []byte {
+	k := make([]byte, 56)
+	copy(k, pubkey[:])
+	binary.BigEndian.PutUint64(k[48:], n)
+	return k
+}
+
+func attestationValue(source uint64, signingRoot libcommon.Hash) []byte {
+	v := make([]byte, 40)
+	binary.BigEndian.PutUint64(v, source)
+	copy(v[8:], signingRoot[:])
+	return v
+}
+
+// records returns the slots or target epochs of the records of the key, in order
+func records(tx kv.Tx, table string, pubkey libcommon.Bytes48) ([]uint64, error) {
+	c, err := tx.Cursor(table)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	var ns []uint64
+	for k, _, err := c.Seek(pubkey[:]); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(k, pubkey[:]) {
+			break
+		}
+		ns = append(ns, binary.BigEndian.Uint64(k[48:]))
+	}
+	return ns, nil
+}
+
+// allPubkeys returns the keys with any records or watermark
+func allPubkeys(tx kv.Tx) ([]libcommon.Bytes48, error) {
+	seen := map[libcommon.Bytes48]struct{}{}
+	var pubkeys []libcommon.Bytes48
+	for _, table := range []string{SignedBlocks, SignedAttestations, Watermarks} {
+		if err := tx.ForEach(table, nil, func(k, _ []byte) error {
+			var pubkey libcommon.Bytes48
+			copy(pubkey[:], k)
+			if _, ok := seen[pubkey]; !ok {
+				seen[pubkey] = struct{}{}
+				pubkeys = append(pubkeys, pubkey)
+			}
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return pubkeys, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package slashing_protection
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cl/clparams"
+)
+
+var (
+	testGenesisValidatorsRoot = libcommon.HexToHash("0x04700007fabc8282644aed6d1c7c9e21d38a03a0c4ba193f3afe428824b3a673")
+	testPubkey                = libcommon.Bytes48{0xb8, 0x45}
+)
+
+func openTest(t *testing.T) *SlashingProtection {
+	cfg := clparams.MainnetBeaconConfig
+	cfg.SlashingProtectionPruningEpochs = 10
+	p, err := Open(context.Background(), t.TempDir(), &cfg, testGenesisValidatorsRoot, log.New())
+	require.NoError(t, err)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestBlocks(t *testing.T) {
+	ctx := context.Background()
+	p := openTest(t)
+
+	require.NoError(t, p.CheckAndInsertBlock(ctx, testPubkey, 10, libcommon.Hash{1}))
+	// the same block again
+	require.NoError(t, p.CheckAndInsertBlock(ctx, testPubkey, 10, libcommon.Hash{1}))
+	require.ErrorIs(t, p.CheckAndInsertBlock(ctx, testPubkey, 10, libcommon.Hash{2}), ErrDoubleProposal)
+	require.NoError(t, p.CheckAndInsertBlock(ctx, testPubkey, 11, libcommon.Hash{2}))
+	// another key
+	require.NoError(t, p.CheckAndInsertBlock(ctx, libcommon.Bytes48{1}, 10, libcommon.Hash{2}))
+}
+
+func TestAttestations(t *testing.T) {
+	ctx := context.Background()
+	p := openTest(t)
+
+	require.NoError(t, p.CheckAndInsertAttestation(ctx, testPubkey, 2, 3, libcommon.Hash{1}))
+	require.NoError(t, p.CheckAndInsertAttestation(ctx, testPubkey, 2, 3, libcommon.Hash{1}))
+	require.ErrorIs(t, p.CheckAndInsertAttestation(ctx, testPubkey, 2, 3, libcommon.Hash{2}), ErrDoubleVote)
+	require.ErrorIs(t, p.CheckAndInsertAttestation(ctx, testPubkey, 4, 3, libcommon.Hash{2}), ErrInvalidVote)
+
+	require.NoError(t, p.CheckAndInsertAttestation(ctx, testPubkey, 5, 8, libcommon.Hash{3}))
+	// surrounds 5 -> 8
+	require.ErrorIs(t, p.CheckAndInsertAttestation(ctx, testPubkey, 4, 9, libcommon.Hash{4}), ErrSurroundVote)
+	// surrounded by 5 -> 8
+	require.ErrorIs(t, p.CheckAndInsertAttestation(ctx, testPubkey, 6, 7, libcommon.Hash{4}), ErrSurroundVote)
+	require.NoError(t, p.CheckAndInsertAttestation(ctx, testPubkey, 8, 9, libcommon.Hash{4}))
+	// an older vote which conflicts with nothing is still fine
+	require.NoError(t, p.CheckAndInsertAttestation(ctx, testPubkey, 3, 4, libcommon.Hash{5}))
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	p := openTest(t)
+	slotsPerEpoch := clparams.MainnetBeaconConfig.SlotsPerEpoch
+
+	for epoch := uint64(1); epoch <= 30; epoch++ {
+		require.NoError(t, p.CheckAndInsertAttestation(ctx, testPubkey, epoch-1, epoch, libcommon.Hash{byte(epoch)}))
+		require.NoError(t, p.CheckAndInsertBlock(ctx, testPubkey, epoch*slotsPerEpoch, libcommon.Hash{byte(epoch)}))
+	}
+	require.NoError(t, p.Prune(ctx))
+
+	interchange, err := p.Export(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, interchange.Data, 1)
+	require.Len(t, interchange.Data[0].SignedAttestations, 11)
+	require.Equal(t, uint64(20), interchange.Data[0].SignedAttestations[0].TargetEpoch)
+	require.Len(t, interchange.Data[0].SignedBlocks, 11)
+
+	// the pruned records are still protected by the watermarks
+	require.ErrorIs(t, p.CheckAndInsertAttestation(ctx, testPubkey, 3, 5, libcommon.Hash{1}), ErrBelowWatermark)
+	require.ErrorIs(t, p.CheckAndInsertBlock(ctx, testPubkey, 5*slotsPerEpoch, libcommon.Hash{1}), ErrBelowWatermark)
+	require.NoError(t, p.CheckAndInsertAttestation(ctx, testPubkey, 30, 31, libcommon.Hash{1}))
+}
+
+// the complete example of the EIP
+const testInterchange = `{
+  "metadata": {
+    "interchange_format_version": "5",
+    "genesis_validators_root": "0x04700007fabc8282644aed6d1c7c9e21d38a03a0c4ba193f3afe428824b3a673"
+  },
+  "data": [
+    {
+      "pubkey": "0xb845089a1457f811bfc000588fbb4e713669be8ce060ea6be3c6ece09afc3794106c91ca73acda5e5457122d58723bed",
+      "signed_blocks": [
+        {
+          "slot": "81952",
+          "signing_root": "0x4ff6f743a43f3b4f95350831aeaf0a122a1a392922c45d804280284a69eb850b"
+        },
+        {
+          "slot": "81951"
+        }
+      ],
+      "signed_attestations": [
+        {
+          "source_epoch": "2290",
+          "target_epoch": "3007",
+          "signing_root": "0x587d6a4f59a58fe24f406e0502413e77fe1babddee641fda30034ed37ecc884d"
+        },
+        {
+          "source_epoch": "2290",
+          "target_epoch": "3008"
+        }
+      ]
+    }
+  ]
+}`
+
+func TestImportExport(t *testing.T) {
+	ctx := context.Background()
+	p := openTest(t)
+	interchange := &Interchange{}
+	require.NoError(t, json.Unmarshal([]byte(testInterchange), interchange))
+	require.NoError(t, p.Import(ctx, interchange))
+	pubkey := interchange.Data[0].Pubkey
+
+	// nothing at or below the imported records
+	require.ErrorIs(t, p.CheckAndInsertBlock(ctx, pubkey, 81952, libcommon.Hash{1}), ErrDoubleProposal)
+	require.ErrorIs(t, p.CheckAndInsertBlock(ctx, pubkey, 81900, libcommon.Hash{1}), ErrBelowWatermark)
+	require.ErrorIs(t, p.CheckAndInsertAttestation(ctx, pubkey, 2289, 3009, libcommon.Hash{1}), ErrBelowWatermark)
+	require.ErrorIs(t, p.CheckAndInsertAttestation(ctx, pubkey, 2290, 3008, libcommon.Hash{1}), ErrDoubleVote)
+	require.NoError(t, p.CheckAndInsertBlock(ctx, pubkey, 81953, libcommon.Hash{1}))
+	require.NoError(t, p.CheckAndInsertAttestation(ctx, pubkey, 2290, 3009, libcommon.Hash{1}))
+
+	exported, err := p.Export(ctx, []libcommon.Bytes48{pubkey})
+	require.NoError(t, err)
+	require.Equal(t, interchange.Metadata, exported.Metadata)
+	require.Len(t, exported.Data, 1)
+	require.Equal(t, []SignedBlock{
+		{Slot: 81951},
+		{Slot: 81952, SigningRoot: interchange.Data[0].SignedBlocks[0].SigningRoot},
+		{Slot: 81953, SigningRoot: &libcommon.Hash{1}},
+	}, exported.Data[0].SignedBlocks)
+	require.Len(t, exported.Data[0].SignedAttestations, 3)
+
+	// the export imports into another database
+	other := openTest(t)
+	require.NoError(t, other.Import(ctx, exported))
+	require.ErrorIs(t, other.CheckAndInsertBlock(ctx, pubkey, 81953, libcommon.Hash{2}), ErrDoubleProposal)
+
+	interchange.Metadata.GenesisValidatorsRoot = libcommon.Hash{1}
+	require.ErrorIs(t, p.Import(ctx, interchange), ErrGenesisMismatch)
+}
+
+func TestGenesisMismatch(t *testing.T) {
+	dir := t.TempDir()
+	cfg := clparams.MainnetBeaconConfig
+	p, err := Open(context.Background(), dir, &cfg, testGenesisValidatorsRoot, log.New())
+	require.NoError(t, err)
+	p.Close()
+	_, err = Open(context.Background(), dir, &cfg, libcommon.Hash{1}, log.New())
+	require.ErrorIs(t, err, ErrGenesisMismatch)
+}
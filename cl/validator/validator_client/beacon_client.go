@@ -0,0 +1,329 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package validator_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/beacon/building"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/cltypes/solid"
+)
+
+// handlerTransport serves the requests with the beacon API handler of the node, without going through a socket
+type handlerTransport struct {
+	handler http.Handler
+}
+
+type bufferedResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (b *bufferedResponse) Header() http.Header { return b.header }
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	if b.code == 0 {
+		b.code = http.StatusOK
+	}
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponse) WriteHeader(code int) {
+	if b.code == 0 {
+		b.code = code
+	}
+}
+
+func (t handlerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	w := &bufferedResponse{header: http.Header{}}
+	t.handler.ServeHTTP(w, r)
+	if w.code == 0 {
+		w.code = http.StatusOK
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(w.code) + " " + http.StatusText(w.code),
+		StatusCode:    w.code,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        w.header,
+		Body:          io.NopCloser(&w.body),
+		ContentLength: int64(w.body.Len()),
+		Request:       r,
+	}, nil
+}
+
+// beaconClient is the client of the validator-facing beacon API
+type beaconClient struct {
+	baseURL   string
+	client    *http.Client
+	beaconCfg *clparams.BeaconChainConfig
+}
+
+func newBeaconClient(handler http.Handler, beaconCfg *clparams.BeaconChainConfig) *beaconClient {
+	return &beaconClient{
+		baseURL:   "http://caplin",
+		client:    &http.Client{Transport: handlerTransport{handler: handler}},
+		beaconCfg: beaconCfg,
+	}
+}
+
+// do sends the request with the body encoded in json, and decodes the data field of the response into out
+func (c *beaconClient) do(ctx context.Context, method, path string, query url.Values, headers map[string]string, body any, out any) error {
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(&struct {
+		Data any `json:"data"`
+	}{Data: out})
+}
+
+type validatorInfo struct {
+	Index     uint64 `json:"index,string"`
+	Status    string `json:"status"`
+	Validator struct {
+		Pubkey libcommon.Bytes48 `json:"pubkey"`
+	} `json:"validator"`
+}
+
+func (c *beaconClient) validators(ctx context.Context, pubkeys []libcommon.Bytes48) ([]validatorInfo, error) {
+	ids := make([]string, 0, len(pubkeys))
+	for _, pubkey := range pubkeys {
+		ids = append(ids, pubkey.Hex())
+	}
+	var out []validatorInfo
+	err := c.do(ctx, http.MethodPost, "/eth/v1/beacon/states/head/validators", nil, nil, map[string][]string{"ids": ids}, &out)
+	return out, err
+}
+
+type attesterDuty struct {
+	Pubkey                  libcommon.Bytes48 `json:"pubkey"`
+	ValidatorIndex          uint64            `json:"validator_index,string"`
+	CommitteeIndex          uint64            `json:"committee_index,string"`
+	CommitteeLength         uint64            `json:"committee_length,string"`
+	ValidatorCommitteeIndex uint64            `json:"validator_committee_index,string"`
+	CommitteesAtSlot        uint64            `json:"committees_at_slot,string"`
+	Slot                    uint64            `json:"slot,string"`
+}
+
+func (c *beaconClient) attesterDuties(ctx context.Context, epoch uint64, indices []uint64) ([]attesterDuty, error) {
+	var out []attesterDuty
+	err := c.do(ctx, http.MethodPost, "/eth/v1/validator/duties/attester/"+strconv.FormatUint(epoch, 10), nil, nil, indicesStrings(indices), &out)
+	return out, err
+}
+
+type proposerDuty struct {
+	Pubkey         libcommon.Bytes48 `json:"pubkey"`
+	ValidatorIndex uint64            `json:"validator_index,string"`
+	Slot           uint64            `json:"slot,string"`
+}
+
+func (c *beaconClient) proposerDuties(ctx context.Context, epoch uint64) ([]proposerDuty, error) {
+	var out []proposerDuty
+	err := c.do(ctx, http.MethodGet, "/eth/v1/validator/duties/proposer/"+strconv.FormatUint(epoch, 10), nil, nil, nil, &out)
+	return out, err
+}
+
+type syncDuty struct {
+	Pubkey                        libcommon.Bytes48 `json:"pubkey"`
+	ValidatorIndex                uint64            `json:"validator_index,string"`
+	ValidatorSyncCommitteeIndices []string          `json:"validator_sync_committee_indices"`
+}
+
+func (c *beaconClient) syncDuties(ctx context.Context, epoch uint64, indices []uint64) ([]syncDuty, error) {
+	var out []syncDuty
+	err := c.do(ctx, http.MethodPost, "/eth/v1/validator/duties/sync/"+strconv.FormatUint(epoch, 10), nil, nil, indicesStrings(indices), &out)
+	return out, err
+}
+
+func (c *beaconClient) headBlockRoot(ctx context.Context) (libcommon.Hash, error) {
+	var out struct {
+		Root libcommon.Hash `json:"root"`
+	}
+	err := c.do(ctx, http.MethodGet, "/eth/v1/beacon/blocks/head/root", nil, nil, nil, &out)
+	return out.Root, err
+}
+
+func (c *beaconClient) attestationData(ctx context.Context, slot, committeeIndex uint64) (*solid.AttestationData, error) {
+	out := &solid.AttestationData{}
+	err := c.do(ctx, http.MethodGet, "/eth/v1/validator/attestation_data", url.Values{
+		"slot":            {strconv.FormatUint(slot, 10)},
+		"committee_index": {strconv.FormatUint(committeeIndex, 10)},
+	}, nil, nil, out)
+	return out, err
+}
+
+func (c *beaconClient) submitAttestations(ctx context.Context, attestations []*solid.Attestation) error {
+	return c.do(ctx, http.MethodPost, "/eth/v1/beacon/pool/attestations", nil, nil, attestations, nil)
+}
+
+func (c *beaconClient) aggregateAttestation(ctx context.Context, slot uint64, attestationDataRoot libcommon.Hash) (*solid.Attestation, error) {
+	out := &solid.Attestation{}
+	err := c.do(ctx, http.MethodGet, "/eth/v1/validator/aggregate_attestation", url.Values{
+		"slot":                  {strconv.FormatUint(slot, 10)},
+		"attestation_data_root": {attestationDataRoot.Hex()},
+	}, nil, nil, out)
+	return out, err
+}
+
+func (c *beaconClient) submitAggregates(ctx context.Context, aggregates []*cltypes.SignedAggregateAndProof) error {
+	return c.do(ctx, http.MethodPost, "/eth/v1/validator/aggregate_and_proofs", nil, nil, aggregates, nil)
+}
+
+func (c *beaconClient) submitSyncCommitteeMessages(ctx context.Context, msgs []*cltypes.SyncCommitteeMessage) error {
+	return c.do(ctx, http.MethodPost, "/eth/v1/beacon/pool/sync_committees", nil, nil, msgs, nil)
+}
+
+func (c *beaconClient) syncCommitteeContribution(ctx context.Context, slot, subcommitteeIndex uint64, blockRoot libcommon.Hash) (*cltypes.Contribution, error) {
+	out := &cltypes.Contribution{}
+	err := c.do(ctx, http.MethodGet, "/eth/v1/validator/sync_committee_contribution", url.Values{
+		"slot":               {strconv.FormatUint(slot, 10)},
+		"subcommittee_index": {strconv.FormatUint(subcommitteeIndex, 10)},
+		"beacon_block_root":  {blockRoot.Hex()},
+	}, nil, nil, out)
+	return out, err
+}
+
+func (c *beaconClient) submitContributions(ctx context.Context, contributions []*cltypes.SignedContributionAndProof) error {
+	return c.do(ctx, http.MethodPost, "/eth/v1/validator/contribution_and_proofs", nil, nil, contributions, nil)
+}
+
+func (c *beaconClient) subscribeToBeaconCommittees(ctx context.Context, subs []*cltypes.BeaconCommitteeSubscription) error {
+	return c.do(ctx, http.MethodPost, "/eth/v1/validator/beacon_committee_subscriptions", nil, nil, subs, nil)
+}
+
+func (c *beaconClient) subscribeToSyncCommittees(ctx context.Context, subs []building.SyncCommitteeSubscription) error {
+	return c.do(ctx, http.MethodPost, "/eth/v1/validator/sync_committee_subscriptions", nil, nil, subs, nil)
+}
+
+func (c *beaconClient) prepareBeaconProposer(ctx context.Context, preparations []building.PrepareBeaconProposer) error {
+	return c.do(ctx, http.MethodPost, "/eth/v1/validator/prepare_beacon_proposer", nil, nil, preparations, nil)
+}
+
+// producedBlock is a block to propose, either full or blinded
+type producedBlock struct {
+	version clparams.StateVersion
+	block   *cltypes.DenebBeaconBlock
+	blinded *cltypes.BlindedBeaconBlock
+}
+
+func (c *beaconClient) produceBlock(ctx context.Context, slot uint64, randaoReveal libcommon.Bytes96, graffiti libcommon.Hash) (*producedBlock, error) {
+	u := c.baseURL + "/eth/v3/validator/blocks/" + strconv.FormatUint(slot, 10) + "?" + url.Values{
+		"randao_reveal": {randaoReveal.Hex()},
+		"graffiti":      {graffiti.Hex()},
+	}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("produce block: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var envelope struct {
+		Version string          `json:"version"`
+		Blinded bool            `json:"execution_payload_blinded"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, err
+	}
+	version, err := clparams.StringToClVersion(envelope.Version)
+	if err != nil {
+		return nil, err
+	}
+	produced := &producedBlock{version: version}
+	if envelope.Blinded {
+		produced.blinded = cltypes.NewBlindedBeaconBlock(c.beaconCfg, version)
+		if err := json.Unmarshal(envelope.Data, produced.blinded); err != nil {
+			return nil, err
+		}
+		return produced, nil
+	}
+	produced.block = cltypes.NewDenebBeaconBlock(c.beaconCfg, version)
+	if err := json.Unmarshal(envelope.Data, produced.block); err != nil {
+		return nil, err
+	}
+	produced.block.Block.SetVersion(version)
+	return produced, nil
+}
+
+func (c *beaconClient) publishBlock(ctx context.Context, version clparams.StateVersion, block any, blinded bool) error {
+	path := "/eth/v2/beacon/blocks"
+	if blinded {
+		path = "/eth/v2/beacon/blinded_blocks"
+	}
+	return c.do(ctx, http.MethodPost, path, nil, map[string]string{"Eth-Consensus-Version": version.String()}, block, nil)
+}
+
+func indicesStrings(indices []uint64) []string {
+	out := make([]string, 0, len(indices))
+	for _, idx := range indices {
+		out = append(out, strconv.FormatUint(idx, 10))
+	}
+	return out
+}
@@ -0,0 +1,669 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package validator_client
+
+import (
+	"context"
+	"encoding/binary"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/beacon/beaconhttp"
+	"github.com/erigontech/erigon/cl/beacon/building"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/cltypes/solid"
+	"github.com/erigontech/erigon/cl/merkle_tree"
+	"github.com/erigontech/erigon/cl/phase1/core/state"
+	"github.com/erigontech/erigon/cl/utils"
+)
+
+const (
+	maxValidatorsPerCommittee = 2048
+	maxCommitteesPerSlot      = 64
+)
+
+// dutiesCache holds the indices of the keys and their duties of the current and next epochs
+type dutiesCache struct {
+	mu       sync.Mutex
+	indices  map[libcommon.Bytes48]uint64
+	attester map[uint64][]attesterDuty
+	proposer map[uint64][]proposerDuty
+	sync     map[uint64][]syncDuty
+	// attested is the attestation data root of each committee of the slot we attested for
+	attested map[uint64]map[uint64]libcommon.Hash
+}
+
+func newDutiesCache() *dutiesCache {
+	d := &dutiesCache{}
+	d.reset()
+	return d
+}
+
+func (d *dutiesCache) reset() {
+	d.indices = map[libcommon.Bytes48]uint64{}
+	d.attester = map[uint64][]attesterDuty{}
+	d.proposer = map[uint64][]proposerDuty{}
+	d.sync = map[uint64][]syncDuty{}
+	d.attested = map[uint64]map[uint64]libcommon.Hash{}
+}
+
+// prune drops the duties of the epochs before the given one
+func (d *dutiesCache) prune(epoch, slotsPerEpoch uint64) {
+	for e := range d.attester {
+		if e < epoch {
+			delete(d.attester, e)
+		}
+	}
+	for e := range d.proposer {
+		if e < epoch {
+			delete(d.proposer, e)
+		}
+	}
+	for e := range d.sync {
+		if e < epoch {
+			delete(d.sync, e)
+		}
+	}
+	for slot := range d.attested {
+		if slot/slotsPerEpoch < epoch {
+			delete(d.attested, slot)
+		}
+	}
+}
+
+// Run performs the duties of the keys until the context is done
+func (v *ValidatorClient) Run(ctx context.Context) {
+	v.logger.Info("[Validator] starting validator client", "keys", len(v.pubkeys()))
+	next := v.ethClock.GetCurrentSlot() + 1
+	for {
+		if err := sleepUntil(ctx, v.ethClock.GetSlotTime(next)); err != nil {
+			return
+		}
+		go v.onSlot(ctx, next)
+		next++
+		// catch up after the node was stalled
+		if current := v.ethClock.GetCurrentSlot(); current >= next {
+			next = current + 1
+		}
+	}
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (v *ValidatorClient) onSlot(ctx context.Context, slot uint64) {
+	epoch := slot / v.beaconCfg.SlotsPerEpoch
+	v.mu.Lock()
+	keysChanged := v.keysChanged
+	v.keysChanged = false
+	v.mu.Unlock()
+	if keysChanged || slot%v.beaconCfg.SlotsPerEpoch == 0 {
+		if err := v.onEpoch(ctx, epoch); err != nil {
+			v.logger.Warn("[Validator] failed to update the duties", "epoch", epoch, "err", err)
+			// retry on the next slot
+			v.mu.Lock()
+			v.keysChanged = true
+			v.mu.Unlock()
+		}
+	}
+
+	slotTime := v.ethClock.GetSlotTime(slot)
+	slotDuration := time.Duration(v.beaconCfg.SecondsPerSlot) * time.Second
+	v.propose(ctx, slot)
+	if sleepUntil(ctx, slotTime.Add(slotDuration/3)) != nil {
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		v.attest(ctx, slot)
+	}()
+	go func() {
+		defer wg.Done()
+		v.signSyncCommitteeMessages(ctx, slot)
+	}()
+	wg.Wait()
+	if sleepUntil(ctx, slotTime.Add(2*slotDuration/3)) != nil {
+		return
+	}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		v.aggregate(ctx, slot)
+	}()
+	go func() {
+		defer wg.Done()
+		v.contribute(ctx, slot)
+	}()
+	wg.Wait()
+}
+
+// onEpoch refreshes the indices of the keys, they change as keys are imported, deleted or activated, and
+// fetches the duties of the epoch and of the next one
+func (v *ValidatorClient) onEpoch(ctx context.Context, epoch uint64) error {
+	d := v.duties
+	pubkeys := v.pubkeys()
+	if len(pubkeys) == 0 {
+		d.mu.Lock()
+		d.reset()
+		d.mu.Unlock()
+		return nil
+	}
+	validators, err := v.beacon.validators(ctx, pubkeys)
+	if err != nil {
+		return err
+	}
+	indices := map[libcommon.Bytes48]uint64{}
+	for _, validator := range validators {
+		if v.key(validator.Validator.Pubkey) != nil {
+			indices[validator.Validator.Pubkey] = validator.Index
+		}
+	}
+	d.mu.Lock()
+	if !maps.Equal(indices, d.indices) {
+		d.reset()
+		d.indices = indices
+	}
+	d.mu.Unlock()
+	if len(indices) == 0 {
+		return nil
+	}
+
+	indexList := make([]uint64, 0, len(indices))
+	for _, idx := range indices {
+		indexList = append(indexList, idx)
+	}
+
+	var preparations []building.PrepareBeaconProposer
+	if v.cfg.FeeRecipient != (libcommon.Address{}) {
+		for _, idx := range indexList {
+			preparations = append(preparations, building.PrepareBeaconProposer{ValidatorIndex: int(idx), FeeRecipient: v.cfg.FeeRecipient})
+		}
+		if err := v.beacon.prepareBeaconProposer(ctx, preparations); err != nil {
+			v.logger.Warn("[Validator] failed to prepare the proposers", "err", err)
+		}
+	}
+
+	// the proposers are only known for the current epoch
+	d.mu.Lock()
+	_, ok := d.proposer[epoch]
+	d.mu.Unlock()
+	if !ok {
+		proposer, err := v.beacon.proposerDuties(ctx, epoch)
+		if err != nil {
+			return err
+		}
+		d.mu.Lock()
+		d.proposer[epoch] = proposer
+		d.mu.Unlock()
+	}
+
+	var subscriptions []*cltypes.BeaconCommitteeSubscription
+	for _, e := range []uint64{epoch, epoch + 1} {
+		d.mu.Lock()
+		_, ok := d.attester[e]
+		d.mu.Unlock()
+		if ok {
+			continue
+		}
+		attester, err := v.beacon.attesterDuties(ctx, e, indexList)
+		if err != nil {
+			return err
+		}
+		syncDuties, err := v.beacon.syncDuties(ctx, e, indexList)
+		if err != nil {
+			return err
+		}
+		d.mu.Lock()
+		d.attester[e], d.sync[e] = attester, syncDuties
+		d.mu.Unlock()
+		for _, duty := range attester {
+			proof, err := v.selectionProof(ctx, duty.Pubkey, duty.Slot)
+			if err != nil {
+				v.logger.Warn("[Validator] failed to sign the selection proof", "pubkey", duty.Pubkey.Hex(), "slot", duty.Slot, "err", err)
+				continue
+			}
+			subscriptions = append(subscriptions, &cltypes.BeaconCommitteeSubscription{
+				ValidatorIndex:   duty.ValidatorIndex,
+				CommitteeIndex:   duty.CommitteeIndex,
+				CommitteesAtSlot: duty.CommitteesAtSlot,
+				Slot:             duty.Slot,
+				IsAggregator:     state.IsAggregator(v.beaconCfg, duty.CommitteeLength, duty.CommitteeIndex, proof),
+			})
+		}
+		if len(syncDuties) > 0 {
+			untilEpoch := (e/v.beaconCfg.EpochsPerSyncCommitteePeriod + 1) * v.beaconCfg.EpochsPerSyncCommitteePeriod
+			var syncSubscriptions []building.SyncCommitteeSubscription
+			for _, duty := range syncDuties {
+				sub := building.SyncCommitteeSubscription{ValidatorIndex: int(duty.ValidatorIndex), UntilEpoch: int(untilEpoch)}
+				for _, idx := range duty.ValidatorSyncCommitteeIndices {
+					n, err := strconv.Atoi(idx)
+					if err != nil {
+						return err
+					}
+					sub.SyncCommitteeIndices = append(sub.SyncCommitteeIndices, beaconhttp.IntStr(n))
+				}
+				syncSubscriptions = append(syncSubscriptions, sub)
+			}
+			if err := v.beacon.subscribeToSyncCommittees(ctx, syncSubscriptions); err != nil {
+				v.logger.Warn("[Validator] failed to subscribe to the sync committees", "err", err)
+			}
+		}
+	}
+	if len(subscriptions) > 0 {
+		if err := v.beacon.subscribeToBeaconCommittees(ctx, subscriptions); err != nil {
+			v.logger.Warn("[Validator] failed to subscribe to the beacon committees", "err", err)
+		}
+	}
+
+	d.mu.Lock()
+	d.prune(epoch, v.beaconCfg.SlotsPerEpoch)
+	d.mu.Unlock()
+	if err := v.protection.Prune(ctx); err != nil {
+		v.logger.Warn("[Validator] failed to prune the slashing protection database", "err", err)
+	}
+	return nil
+}
+
+func (v *ValidatorClient) forkInfo(epoch uint64) ForkInfo {
+	return forkInfoAtEpoch(v.beaconCfg, v.ethClock.GenesisValidatorsRoot(), epoch)
+}
+
+// sign signs the request with the key of the pubkey
+func (v *ValidatorClient) sign(ctx context.Context, pubkey libcommon.Bytes48, req *SignRequest) (libcommon.Bytes96, error) {
+	key := v.key(pubkey)
+	if key == nil {
+		return libcommon.Bytes96{}, ErrKeyNotFound
+	}
+	return key.signer.Sign(ctx, pubkey, req)
+}
+
+func (v *ValidatorClient) selectionProof(ctx context.Context, pubkey libcommon.Bytes48, slot uint64) (libcommon.Bytes96, error) {
+	epoch := slot / v.beaconCfg.SlotsPerEpoch
+	req, err := newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainSelectionProof, epoch, merkle_tree.Uint64Root(slot),
+		SignAggregationSlot, "aggregation_slot", map[string]string{"slot": strconv.FormatUint(slot, 10)})
+	if err != nil {
+		return libcommon.Bytes96{}, err
+	}
+	return v.sign(ctx, pubkey, req)
+}
+
+func (v *ValidatorClient) propose(ctx context.Context, slot uint64) {
+	epoch := slot / v.beaconCfg.SlotsPerEpoch
+	var duty *proposerDuty
+	v.duties.mu.Lock()
+	for _, d := range v.duties.proposer[epoch] {
+		if d.Slot == slot {
+			d := d
+			duty = &d
+		}
+	}
+	v.duties.mu.Unlock()
+	if duty == nil || v.key(duty.Pubkey) == nil {
+		return
+	}
+	logger := v.logger.New("slot", slot, "pubkey", duty.Pubkey.Hex())
+
+	randaoReq, err := newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainRandao, epoch, merkle_tree.Uint64Root(epoch),
+		SignRandaoReveal, "randao_reveal", map[string]string{"epoch": strconv.FormatUint(epoch, 10)})
+	if err != nil {
+		logger.Warn("[Validator] failed to propose", "err", err)
+		return
+	}
+	randaoReveal, err := v.sign(ctx, duty.Pubkey, randaoReq)
+	if err != nil {
+		logger.Warn("[Validator] failed to sign the randao reveal", "err", err)
+		return
+	}
+	var graffiti libcommon.Hash
+	copy(graffiti[:], v.cfg.Graffiti)
+	produced, err := v.beacon.produceBlock(ctx, slot, randaoReveal, graffiti)
+	if err != nil {
+		logger.Warn("[Validator] failed to produce the block", "err", err)
+		return
+	}
+
+	header := &cltypes.BeaconBlockHeader{}
+	var blockRoot libcommon.Hash
+	if produced.blinded != nil {
+		b := produced.blinded
+		header.Slot, header.ProposerIndex, header.ParentRoot, header.Root = b.Slot, b.ProposerIndex, b.ParentRoot, b.StateRoot
+		if header.BodyRoot, err = b.Body.HashSSZ(); err == nil {
+			blockRoot, err = b.HashSSZ()
+		}
+	} else {
+		b := produced.block.Block
+		header.Slot, header.ProposerIndex, header.ParentRoot, header.Root = b.Slot, b.ProposerIndex, b.ParentRoot, b.StateRoot
+		if header.BodyRoot, err = b.Body.HashSSZ(); err == nil {
+			blockRoot, err = b.HashSSZ()
+		}
+	}
+	if err != nil {
+		logger.Warn("[Validator] failed to hash the block", "err", err)
+		return
+	}
+	if header.Slot != slot || header.ProposerIndex != duty.ValidatorIndex {
+		logger.Warn("[Validator] the produced block is not ours", "block_slot", header.Slot, "proposer", header.ProposerIndex)
+		return
+	}
+	req, err := newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainBeaconProposer, epoch, blockRoot, SignBlock, "beacon_block", map[string]any{
+		"version":      strings.ToUpper(produced.version.String()),
+		"block_header": header,
+	})
+	if err != nil {
+		logger.Warn("[Validator] failed to propose", "err", err)
+		return
+	}
+	if err := v.protection.CheckAndInsertBlock(ctx, duty.Pubkey, slot, req.SigningRoot); err != nil {
+		logger.Error("[Validator] refusing to sign the block", "err", err)
+		return
+	}
+	signature, err := v.sign(ctx, duty.Pubkey, req)
+	if err != nil {
+		logger.Warn("[Validator] failed to sign the block", "err", err)
+		return
+	}
+	if produced.blinded != nil {
+		err = v.beacon.publishBlock(ctx, produced.version, &cltypes.SignedBlindedBeaconBlock{Block: produced.blinded, Signature: signature}, true)
+	} else {
+		err = v.beacon.publishBlock(ctx, produced.version, &cltypes.DenebSignedBeaconBlock{
+			SignedBlock: &cltypes.SignedBeaconBlock{Block: produced.block.Block, Signature: signature},
+			KZGProofs:   produced.block.KZGProofs,
+			Blobs:       produced.block.Blobs,
+		}, false)
+	}
+	if err != nil {
+		logger.Warn("[Validator] failed to publish the block", "err", err)
+		return
+	}
+	logger.Info("[Validator] proposed block", "root", blockRoot, "blinded", produced.blinded != nil)
+}
+
+// aggregationBits has the bit of the validator in its committee set
+func aggregationBits(committeeLength, validatorCommitteeIndex uint64, capacity int) *solid.BitList {
+	bits := make([]byte, committeeLength/8+1)
+	bits[validatorCommitteeIndex/8] |= 1 << (validatorCommitteeIndex % 8)
+	// the length bit
+	bits[committeeLength/8] |= 1 << (committeeLength % 8)
+	return solid.BitlistFromBytes(bits, capacity)
+}
+
+func (v *ValidatorClient) attest(ctx context.Context, slot uint64) {
+	epoch := slot / v.beaconCfg.SlotsPerEpoch
+	version := v.beaconCfg.GetCurrentStateVersion(epoch)
+	v.duties.mu.Lock()
+	var duties []attesterDuty
+	for _, duty := range v.duties.attester[epoch] {
+		if duty.Slot == slot {
+			duties = append(duties, duty)
+		}
+	}
+	v.duties.mu.Unlock()
+
+	attestationData := map[uint64]*solid.AttestationData{}
+	dataRoots := map[uint64]libcommon.Hash{}
+	var attestations []*solid.Attestation
+	for _, duty := range duties {
+		logger := v.logger.New("slot", slot, "pubkey", duty.Pubkey.Hex())
+		data, ok := attestationData[duty.CommitteeIndex]
+		if !ok {
+			var err error
+			if data, err = v.beacon.attestationData(ctx, slot, duty.CommitteeIndex); err != nil {
+				logger.Warn("[Validator] failed to get the attestation data", "err", err)
+				continue
+			}
+			if version.AfterOrEqual(clparams.ElectraVersion) {
+				data.CommitteeIndex = 0
+			}
+			root, err := data.HashSSZ()
+			if err != nil {
+				logger.Warn("[Validator] failed to hash the attestation data", "err", err)
+				continue
+			}
+			attestationData[duty.CommitteeIndex], dataRoots[duty.CommitteeIndex] = data, root
+		}
+		req, err := newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainBeaconAttester, data.Target.Epoch, dataRoots[duty.CommitteeIndex],
+			SignAttestation, "attestation", data)
+		if err != nil {
+			logger.Warn("[Validator] failed to attest", "err", err)
+			continue
+		}
+		if err := v.protection.CheckAndInsertAttestation(ctx, duty.Pubkey, data.Source.Epoch, data.Target.Epoch, req.SigningRoot); err != nil {
+			logger.Error("[Validator] refusing to sign the attestation", "err", err)
+			continue
+		}
+		signature, err := v.sign(ctx, duty.Pubkey, req)
+		if err != nil {
+			logger.Warn("[Validator] failed to sign the attestation", "err", err)
+			continue
+		}
+		attestation := &solid.Attestation{Data: data, Signature: signature}
+		if version.AfterOrEqual(clparams.ElectraVersion) {
+			attestation.AggregationBits = aggregationBits(duty.CommitteeLength, duty.ValidatorCommitteeIndex, maxValidatorsPerCommittee*maxCommitteesPerSlot)
+			attestation.CommitteeBits = solid.NewBitVector(maxCommitteesPerSlot)
+			if err := attestation.CommitteeBits.SetBitAt(int(duty.CommitteeIndex), true); err != nil {
+				logger.Warn("[Validator] failed to attest", "err", err)
+				continue
+			}
+		} else {
+			attestation.AggregationBits = aggregationBits(duty.CommitteeLength, duty.ValidatorCommitteeIndex, maxValidatorsPerCommittee)
+		}
+		attestations = append(attestations, attestation)
+	}
+	v.duties.mu.Lock()
+	v.duties.attested[slot] = dataRoots
+	v.duties.mu.Unlock()
+	if len(attestations) == 0 {
+		return
+	}
+	if err := v.beacon.submitAttestations(ctx, attestations); err != nil {
+		v.logger.Warn("[Validator] failed to submit the attestations", "slot", slot, "err", err)
+		return
+	}
+	v.logger.Debug("[Validator] attested", "slot", slot, "count", len(attestations))
+}
+
+func (v *ValidatorClient) aggregate(ctx context.Context, slot uint64) {
+	epoch := slot / v.beaconCfg.SlotsPerEpoch
+	v.duties.mu.Lock()
+	var duties []attesterDuty
+	for _, duty := range v.duties.attester[epoch] {
+		if duty.Slot == slot {
+			duties = append(duties, duty)
+		}
+	}
+	dataRoots := v.duties.attested[slot]
+	v.duties.mu.Unlock()
+
+	var aggregates []*cltypes.SignedAggregateAndProof
+	for _, duty := range duties {
+		dataRoot, ok := dataRoots[duty.CommitteeIndex]
+		if !ok {
+			continue
+		}
+		logger := v.logger.New("slot", slot, "pubkey", duty.Pubkey.Hex())
+		proof, err := v.selectionProof(ctx, duty.Pubkey, slot)
+		if err != nil {
+			logger.Warn("[Validator] failed to sign the selection proof", "err", err)
+			continue
+		}
+		if !state.IsAggregator(v.beaconCfg, duty.CommitteeLength, duty.CommitteeIndex, proof) {
+			continue
+		}
+		aggregate, err := v.beacon.aggregateAttestation(ctx, slot, dataRoot)
+		if err != nil {
+			logger.Warn("[Validator] failed to get the aggregate", "err", err)
+			continue
+		}
+		msg := &cltypes.AggregateAndProof{AggregatorIndex: duty.ValidatorIndex, Aggregate: aggregate, SelectionProof: proof}
+		root, err := msg.HashSSZ()
+		if err != nil {
+			logger.Warn("[Validator] failed to hash the aggregate", "err", err)
+			continue
+		}
+		req, err := newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainAggregateAndProof, epoch, root, SignAggregateAndProof, "aggregate_and_proof", msg)
+		if err != nil {
+			logger.Warn("[Validator] failed to aggregate", "err", err)
+			continue
+		}
+		signature, err := v.sign(ctx, duty.Pubkey, req)
+		if err != nil {
+			logger.Warn("[Validator] failed to sign the aggregate", "err", err)
+			continue
+		}
+		aggregates = append(aggregates, &cltypes.SignedAggregateAndProof{Message: msg, Signature: signature})
+	}
+	if len(aggregates) == 0 {
+		return
+	}
+	if err := v.beacon.submitAggregates(ctx, aggregates); err != nil {
+		v.logger.Warn("[Validator] failed to submit the aggregates", "slot", slot, "err", err)
+	}
+}
+
+func (v *ValidatorClient) syncDutiesAt(epoch uint64) []syncDuty {
+	v.duties.mu.Lock()
+	defer v.duties.mu.Unlock()
+	return v.duties.sync[epoch]
+}
+
+func (v *ValidatorClient) signSyncCommitteeMessages(ctx context.Context, slot uint64) {
+	epoch := slot / v.beaconCfg.SlotsPerEpoch
+	duties := v.syncDutiesAt(epoch)
+	if len(duties) == 0 || v.beaconCfg.GetCurrentStateVersion(epoch) < clparams.AltairVersion {
+		return
+	}
+	blockRoot, err := v.beacon.headBlockRoot(ctx)
+	if err != nil {
+		v.logger.Warn("[Validator] failed to get the head block root", "slot", slot, "err", err)
+		return
+	}
+	var msgs []*cltypes.SyncCommitteeMessage
+	for _, duty := range duties {
+		req, err := newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainSyncCommittee, epoch, blockRoot, SignSyncCommitteeMessage, "sync_committee_message",
+			map[string]string{"beacon_block_root": blockRoot.Hex(), "slot": strconv.FormatUint(slot, 10)})
+		if err != nil {
+			v.logger.Warn("[Validator] failed to sign the sync committee message", "err", err)
+			continue
+		}
+		signature, err := v.sign(ctx, duty.Pubkey, req)
+		if err != nil {
+			v.logger.Warn("[Validator] failed to sign the sync committee message", "pubkey", duty.Pubkey.Hex(), "err", err)
+			continue
+		}
+		msgs = append(msgs, &cltypes.SyncCommitteeMessage{Slot: slot, BeaconBlockRoot: blockRoot, ValidatorIndex: duty.ValidatorIndex, Signature: signature})
+	}
+	if len(msgs) == 0 {
+		return
+	}
+	if err := v.beacon.submitSyncCommitteeMessages(ctx, msgs); err != nil {
+		v.logger.Warn("[Validator] failed to submit the sync committee messages", "slot", slot, "err", err)
+	}
+}
+
+func (v *ValidatorClient) isSyncCommitteeAggregator(proof libcommon.Bytes96) bool {
+	modulo := max(1, v.beaconCfg.SyncCommitteeSize/v.beaconCfg.SyncCommitteeSubnetCount/v.beaconCfg.TargetAggregatorsPerSyncSubcommittee)
+	hash := utils.Sha256(proof[:])
+	return binary.LittleEndian.Uint64(hash[:8])%modulo == 0
+}
+
+func (v *ValidatorClient) contribute(ctx context.Context, slot uint64) {
+	epoch := slot / v.beaconCfg.SlotsPerEpoch
+	duties := v.syncDutiesAt(epoch)
+	if len(duties) == 0 || v.beaconCfg.GetCurrentStateVersion(epoch) < clparams.AltairVersion {
+		return
+	}
+	blockRoot, err := v.beacon.headBlockRoot(ctx)
+	if err != nil {
+		v.logger.Warn("[Validator] failed to get the head block root", "slot", slot, "err", err)
+		return
+	}
+	subcommitteeSize := v.beaconCfg.SyncCommitteeSize / v.beaconCfg.SyncCommitteeSubnetCount
+	var contributions []*cltypes.SignedContributionAndProof
+	for _, duty := range duties {
+		logger := v.logger.New("slot", slot, "pubkey", duty.Pubkey.Hex())
+		subcommittees := map[uint64]struct{}{}
+		for _, idx := range duty.ValidatorSyncCommitteeIndices {
+			n, err := strconv.ParseUint(idx, 10, 64)
+			if err != nil {
+				continue
+			}
+			subcommittees[n/subcommitteeSize] = struct{}{}
+		}
+		for subcommittee := range subcommittees {
+			selection := &cltypes.SyncAggregatorSelectionData{Slot: slot, SubcommitteeIndex: subcommittee}
+			root, err := selection.HashSSZ()
+			if err != nil {
+				continue
+			}
+			req, err := newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainSyncCommitteeSelectionProof, epoch, root,
+				SignSyncCommitteeSelection, "sync_aggregator_selection_data", selection)
+			if err != nil {
+				continue
+			}
+			proof, err := v.sign(ctx, duty.Pubkey, req)
+			if err != nil {
+				logger.Warn("[Validator] failed to sign the sync committee selection proof", "err", err)
+				continue
+			}
+			if !v.isSyncCommitteeAggregator(proof) {
+				continue
+			}
+			contribution, err := v.beacon.syncCommitteeContribution(ctx, slot, subcommittee, blockRoot)
+			if err != nil {
+				logger.Warn("[Validator] failed to get the sync committee contribution", "err", err)
+				continue
+			}
+			msg := &cltypes.ContributionAndProof{AggregatorIndex: duty.ValidatorIndex, Contribution: contribution, SelectionProof: proof}
+			msgRoot, err := msg.HashSSZ()
+			if err != nil {
+				continue
+			}
+			req, err = newSignRequest(v.forkInfo(epoch), v.beaconCfg.DomainContributionAndProof, epoch, msgRoot,
+				SignContributionAndProof, "contribution_and_proof", msg)
+			if err != nil {
+				continue
+			}
+			signature, err := v.sign(ctx, duty.Pubkey, req)
+			if err != nil {
+				logger.Warn("[Validator] failed to sign the contribution", "err", err)
+				continue
+			}
+			contributions = append(contributions, &cltypes.SignedContributionAndProof{Message: msg, Signature: signature})
+		}
+	}
+	if len(contributions) == 0 {
+		return
+	}
+	if err := v.beacon.submitContributions(ctx, contributions); err != nil {
+		v.logger.Warn("[Validator] failed to submit the sync committee contributions", "slot", slot, "err", err)
+	}
+}
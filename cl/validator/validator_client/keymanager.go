@@ -0,0 +1,273 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package validator_client
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/validator/keystore"
+	"github.com/erigontech/erigon/cl/validator/slashing_protection"
+)
+
+// Statuses of the keymanager API
+const (
+	statusImported  = "imported"
+	statusDuplicate = "duplicate"
+	statusDeleted   = "deleted"
+	statusNotActive = "not_active"
+	statusNotFound  = "not_found"
+	statusError     = "error"
+)
+
+// LoadOrCreateToken reads the bearer token of the keymanager API, a random one is written if the file does not exist
+func LoadOrCreateToken(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err == nil {
+		token := strings.TrimSpace(string(b))
+		if token == "" {
+			return "", errors.New("keymanager token file is empty")
+		}
+		return token, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	token := "api-token-0x" + hex.EncodeToString(secret)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(token), 0600); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+type keymanagerStatus struct {
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
+
+func statusOf(err error, ok string) keymanagerStatus {
+	switch {
+	case err == nil:
+		return keymanagerStatus{Status: ok}
+	case errors.Is(err, ErrDuplicateKey):
+		return keymanagerStatus{Status: statusDuplicate}
+	case errors.Is(err, ErrKeyNotFound):
+		return keymanagerStatus{Status: statusNotFound}
+	}
+	return keymanagerStatus{Status: statusError, Message: err.Error()}
+}
+
+// KeymanagerHandler serves the keymanager API of the validator client, authenticated with a bearer token
+func (v *ValidatorClient) KeymanagerHandler(token string) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || auth == "" {
+				writeKeymanagerError(w, http.StatusUnauthorized, "missing bearer token")
+				return
+			}
+			if subtle.ConstantTimeCompare([]byte(auth), []byte(token)) != 1 {
+				writeKeymanagerError(w, http.StatusForbidden, "invalid bearer token")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	})
+	r.Route("/eth/v1", func(r chi.Router) {
+		r.Get("/keystores", v.listKeystores)
+		r.Post("/keystores", v.importKeystores)
+		r.Delete("/keystores", v.deleteKeystores)
+		r.Get("/remotekeys", v.listRemoteKeys)
+		r.Post("/remotekeys", v.importRemoteKeys)
+		r.Delete("/remotekeys", v.deleteRemoteKeys)
+	})
+	return r
+}
+
+// ListenAndServeKeymanager serves the keymanager API on the address until the listener fails
+func (v *ValidatorClient) ListenAndServeKeymanager(addr, token string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	v.logger.Info("[Validator] keymanager API listening", "addr", addr)
+	server := &http.Server{Handler: v.KeymanagerHandler(token)}
+	return server.Serve(listener)
+}
+
+func writeKeymanagerError(w http.ResponseWriter, code int, msg string) {
+	writeKeymanagerJSON(w, code, map[string]string{"message": msg})
+}
+
+func writeKeymanagerJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (v *ValidatorClient) listKeystores(w http.ResponseWriter, r *http.Request) {
+	type keystoreInfo struct {
+		ValidatingPubkey libcommon.Bytes48 `json:"validating_pubkey"`
+		DerivationPath   string            `json:"derivation_path,omitempty"`
+		Readonly         bool              `json:"readonly"`
+	}
+	data := []keystoreInfo{}
+	for _, key := range v.sortedKeys(false) {
+		data = append(data, keystoreInfo{ValidatingPubkey: key.pubkey, DerivationPath: key.derivationPath, Readonly: key.readonly})
+	}
+	writeKeymanagerJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func (v *ValidatorClient) importKeystores(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Keystores          []string `json:"keystores"`
+		Passwords          []string `json:"passwords"`
+		SlashingProtection string   `json:"slashing_protection"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeKeymanagerError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Keystores) != len(req.Passwords) {
+		writeKeymanagerError(w, http.StatusBadRequest, "keystores and passwords have different lengths")
+		return
+	}
+	// the history must be in place before any key can sign
+	if req.SlashingProtection != "" {
+		var interchange slashing_protection.Interchange
+		if err := json.Unmarshal([]byte(req.SlashingProtection), &interchange); err != nil {
+			writeKeymanagerError(w, http.StatusBadRequest, "invalid slashing protection: "+err.Error())
+			return
+		}
+		if err := v.protection.Import(r.Context(), &interchange); err != nil {
+			writeKeymanagerError(w, http.StatusBadRequest, "invalid slashing protection: "+err.Error())
+			return
+		}
+	}
+	data := make([]keymanagerStatus, len(req.Keystores))
+	for i, raw := range req.Keystores {
+		k, err := keystore.Parse([]byte(raw))
+		if err == nil && len(k.Pubkey) == 0 {
+			err = errors.New("keystore has no pubkey")
+		}
+		if err == nil {
+			err = v.ImportKeystore(k, req.Passwords[i])
+		}
+		data[i] = statusOf(err, statusImported)
+	}
+	writeKeymanagerJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func (v *ValidatorClient) deleteKeystores(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Pubkeys []libcommon.Bytes48 `json:"pubkeys"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeKeymanagerError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data := make([]keymanagerStatus, len(req.Pubkeys))
+	for i, pubkey := range req.Pubkeys {
+		data[i] = statusOf(v.DeleteKey(pubkey, false), statusDeleted)
+	}
+	// the history is exported after the keys stopped signing
+	interchange, err := v.protection.Export(r.Context(), req.Pubkeys)
+	if err != nil {
+		writeKeymanagerError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for i, history := range interchange.Data {
+		if data[i].Status == statusNotFound && (len(history.SignedBlocks) > 0 || len(history.SignedAttestations) > 0) {
+			data[i].Status = statusNotActive
+		}
+	}
+	slashingProtection, err := json.Marshal(interchange)
+	if err != nil {
+		writeKeymanagerError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeKeymanagerJSON(w, http.StatusOK, map[string]any{"data": data, "slashing_protection": string(slashingProtection)})
+}
+
+func (v *ValidatorClient) listRemoteKeys(w http.ResponseWriter, r *http.Request) {
+	type remoteKeyInfo struct {
+		Pubkey   libcommon.Bytes48 `json:"pubkey"`
+		URL      string            `json:"url"`
+		Readonly bool              `json:"readonly"`
+	}
+	data := []remoteKeyInfo{}
+	for _, key := range v.sortedKeys(true) {
+		data = append(data, remoteKeyInfo{Pubkey: key.pubkey, URL: key.remoteURL, Readonly: key.readonly})
+	}
+	writeKeymanagerJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func (v *ValidatorClient) importRemoteKeys(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RemoteKeys []RemoteKey `json:"remote_keys"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeKeymanagerError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data := make([]keymanagerStatus, len(req.RemoteKeys))
+	for i, key := range req.RemoteKeys {
+		var err error
+		if key.URL == "" {
+			err = errors.New("missing remote signer url")
+		} else {
+			err = v.ImportRemoteKey(key)
+		}
+		data[i] = statusOf(err, statusImported)
+	}
+	writeKeymanagerJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+func (v *ValidatorClient) deleteRemoteKeys(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Pubkeys []libcommon.Bytes48 `json:"pubkeys"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeKeymanagerError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	data := make([]keymanagerStatus, len(req.Pubkeys))
+	for i, pubkey := range req.Pubkeys {
+		data[i] = statusOf(v.DeleteKey(pubkey, true), statusDeleted)
+	}
+	writeKeymanagerJSON(w, http.StatusOK, map[string]any{"data": data})
+}
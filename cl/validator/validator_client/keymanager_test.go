@@ -0,0 +1,193 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package validator_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/Giulio2002/bls"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/validator/keystore"
+	"github.com/erigontech/erigon/cl/validator/slashing_protection"
+)
+
+const testToken = "api-token-0x01"
+
+func newTestValidatorClient(t *testing.T) *ValidatorClient {
+	cfg := clparams.MainnetBeaconConfig
+	dataDir := t.TempDir()
+	protection, err := slashing_protection.Open(context.Background(), filepath.Join(dataDir, "slashing_protection"), &cfg, libcommon.Hash{1}, log.New())
+	require.NoError(t, err)
+	t.Cleanup(protection.Close)
+	return &ValidatorClient{
+		cfg:        Config{DataDir: dataDir},
+		beaconCfg:  &cfg,
+		protection: protection,
+		logger:     log.New(),
+		keys:       map[libcommon.Bytes48]*validatorKey{},
+		duties:     newDutiesCache(),
+	}
+}
+
+func keymanagerRequest(t *testing.T, handler http.Handler, method, path, token string, body any, out any) int {
+	var reqBody bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&reqBody).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &reqBody)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if out != nil {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+	}
+	return rec.Code
+}
+
+type statusesResponse struct {
+	Data               []keymanagerStatus `json:"data"`
+	SlashingProtection string             `json:"slashing_protection"`
+}
+
+func TestKeymanagerAuth(t *testing.T) {
+	handler := newTestValidatorClient(t).KeymanagerHandler(testToken)
+	require.Equal(t, http.StatusUnauthorized, keymanagerRequest(t, handler, http.MethodGet, "/eth/v1/keystores", "", nil, nil))
+	require.Equal(t, http.StatusForbidden, keymanagerRequest(t, handler, http.MethodGet, "/eth/v1/keystores", "wrong", nil, nil))
+	require.Equal(t, http.StatusOK, keymanagerRequest(t, handler, http.MethodGet, "/eth/v1/keystores", testToken, nil, nil))
+}
+
+func TestKeymanagerKeystores(t *testing.T) {
+	v := newTestValidatorClient(t)
+	handler := v.KeymanagerHandler(testToken)
+
+	sk, err := bls.GenerateKey()
+	require.NoError(t, err)
+	k, err := keystore.Encrypt(sk, "password", 1024)
+	require.NoError(t, err)
+	raw, err := json.Marshal(k)
+	require.NoError(t, err)
+	pubkey := k.PublicKey()
+
+	protection := &slashing_protection.Interchange{
+		Metadata: slashing_protection.InterchangeMetadata{
+			InterchangeFormatVersion: slashing_protection.InterchangeFormatVersion,
+			GenesisValidatorsRoot:    libcommon.Hash{1},
+		},
+		Data: []slashing_protection.InterchangeData{{
+			Pubkey:             pubkey,
+			SignedBlocks:       []slashing_protection.SignedBlock{{Slot: 100}},
+			SignedAttestations: []slashing_protection.SignedAttestation{{SourceEpoch: 2, TargetEpoch: 3}},
+		}},
+	}
+	protectionJSON, err := json.Marshal(protection)
+	require.NoError(t, err)
+
+	var res statusesResponse
+	require.Equal(t, http.StatusOK, keymanagerRequest(t, handler, http.MethodPost, "/eth/v1/keystores", testToken, map[string]any{
+		"keystores":           []string{string(raw), string(raw), "{}"},
+		"passwords":           []string{"password", "password", "password"},
+		"slashing_protection": string(protectionJSON),
+	}, &res))
+	require.Equal(t, []string{statusImported, statusDuplicate, statusError}, statuses(res.Data))
+	// the imported history protects the key
+	require.ErrorIs(t, v.protection.CheckAndInsertBlock(context.Background(), pubkey, 99, libcommon.Hash{2}), slashing_protection.ErrBelowWatermark)
+
+	var list struct {
+		Data []struct {
+			ValidatingPubkey libcommon.Bytes48 `json:"validating_pubkey"`
+			Readonly         bool              `json:"readonly"`
+		} `json:"data"`
+	}
+	require.Equal(t, http.StatusOK, keymanagerRequest(t, handler, http.MethodGet, "/eth/v1/keystores", testToken, nil, &list))
+	require.Len(t, list.Data, 1)
+	require.Equal(t, pubkey, list.Data[0].ValidatingPubkey)
+	require.False(t, list.Data[0].Readonly)
+
+	res = statusesResponse{}
+	require.Equal(t, http.StatusOK, keymanagerRequest(t, handler, http.MethodDelete, "/eth/v1/keystores", testToken, map[string]any{
+		"pubkeys": []libcommon.Bytes48{pubkey, {2}},
+	}, &res))
+	require.Equal(t, []string{statusDeleted, statusNotFound}, statuses(res.Data))
+	var exported slashing_protection.Interchange
+	require.NoError(t, json.Unmarshal([]byte(res.SlashingProtection), &exported))
+	require.Len(t, exported.Data, 2)
+	require.Equal(t, uint64(100), exported.Data[0].SignedBlocks[0].Slot)
+	require.Nil(t, v.key(pubkey))
+
+	// the history outlives the key
+	res = statusesResponse{}
+	require.Equal(t, http.StatusOK, keymanagerRequest(t, handler, http.MethodDelete, "/eth/v1/keystores", testToken, map[string]any{
+		"pubkeys": []libcommon.Bytes48{pubkey},
+	}, &res))
+	require.Equal(t, []string{statusNotActive}, statuses(res.Data))
+}
+
+func TestKeymanagerRemoteKeys(t *testing.T) {
+	v := newTestValidatorClient(t)
+	handler := v.KeymanagerHandler(testToken)
+	v.keys[libcommon.Bytes48{1}] = &validatorKey{pubkey: libcommon.Bytes48{1}, readonly: true, remoteURL: "http://flags"}
+
+	var res statusesResponse
+	require.Equal(t, http.StatusOK, keymanagerRequest(t, handler, http.MethodPost, "/eth/v1/remotekeys", testToken, map[string]any{
+		"remote_keys": []RemoteKey{{Pubkey: libcommon.Bytes48{2}, URL: "http://signer"}, {Pubkey: libcommon.Bytes48{1}, URL: "http://signer"}},
+	}, &res))
+	require.Equal(t, []string{statusImported, statusDuplicate}, statuses(res.Data))
+
+	stored, err := v.readRemoteKeys()
+	require.NoError(t, err)
+	require.Equal(t, []RemoteKey{{Pubkey: libcommon.Bytes48{2}, URL: "http://signer"}}, stored)
+
+	res = statusesResponse{}
+	require.Equal(t, http.StatusOK, keymanagerRequest(t, handler, http.MethodDelete, "/eth/v1/remotekeys", testToken, map[string]any{
+		"pubkeys": []libcommon.Bytes48{{2}, {1}, {3}},
+	}, &res))
+	require.Equal(t, []string{statusDeleted, statusError, statusNotFound}, statuses(res.Data))
+
+	stored, err = v.readRemoteKeys()
+	require.NoError(t, err)
+	require.Empty(t, stored)
+}
+
+func TestLoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	token, err := LoadOrCreateToken(path)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+	again, err := LoadOrCreateToken(path)
+	require.NoError(t, err)
+	require.Equal(t, token, again)
+}
+
+func statuses(data []keymanagerStatus) []string {
+	var s []string
+	for _, d := range data {
+		s = append(s, d.Status)
+	}
+	return s
+}
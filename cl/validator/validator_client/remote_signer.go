@@ -0,0 +1,124 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package validator_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+)
+
+const remoteSignerTimeout = 10 * time.Second
+
+// RemoteSigner signs with the keys of a web3signer compatible remote signer
+type RemoteSigner struct {
+	url    string
+	client *http.Client
+}
+
+func NewRemoteSigner(url string) *RemoteSigner {
+	return &RemoteSigner{
+		url:    strings.TrimRight(url, "/"),
+		client: &http.Client{Timeout: remoteSignerTimeout},
+	}
+}
+
+func (r *RemoteSigner) URL() string {
+	return r.url
+}
+
+// PublicKeys returns the keys the remote signer holds
+func (r *RemoteSigner) PublicKeys(ctx context.Context) ([]libcommon.Bytes48, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+"/api/v1/eth2/publicKeys", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, remoteSignerError(resp)
+	}
+	var pubkeys []libcommon.Bytes48
+	if err := json.NewDecoder(resp.Body).Decode(&pubkeys); err != nil {
+		return nil, err
+	}
+	return pubkeys, nil
+}
+
+func (r *RemoteSigner) Sign(ctx context.Context, pubkey libcommon.Bytes48, signReq *SignRequest) (libcommon.Bytes96, error) {
+	var sig libcommon.Bytes96
+	body := map[string]any{
+		"type":        signReq.Type,
+		"fork_info":   signReq.ForkInfo,
+		"signingRoot": signReq.SigningRoot,
+	}
+	if signReq.PayloadName != "" {
+		body[signReq.PayloadName] = signReq.Payload
+	}
+	b, err := json.Marshal(body)
+	if err != nil {
+		return sig, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url+"/api/v1/eth2/sign/"+pubkey.Hex(), bytes.NewReader(b))
+	if err != nil {
+		return sig, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return sig, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return sig, remoteSignerError(resp)
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return sig, err
+	}
+	// the signature is either in a json object or the plain text of the body
+	signature := strings.TrimSpace(string(respBody))
+	if strings.HasPrefix(signature, "{") {
+		var res struct {
+			Signature string `json:"signature"`
+		}
+		if err := json.Unmarshal(respBody, &res); err != nil {
+			return sig, err
+		}
+		signature = res.Signature
+	}
+	if err := sig.UnmarshalText([]byte(signature)); err != nil {
+		return sig, fmt.Errorf("invalid remote signature: %w", err)
+	}
+	return sig, nil
+}
+
+func remoteSignerError(resp *http.Response) error {
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("remote signer: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+}
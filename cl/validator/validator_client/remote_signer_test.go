@@ -0,0 +1,69 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package validator_client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/clparams"
+)
+
+func TestRemoteSigner(t *testing.T) {
+	pubkey := libcommon.Bytes48{0xaa}
+	signature := libcommon.Bytes96{0xbb}
+	var signed map[string]json.RawMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/eth2/publicKeys":
+			_ = json.NewEncoder(w).Encode([]libcommon.Bytes48{pubkey})
+		case "/api/v1/eth2/sign/" + pubkey.Hex():
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&signed))
+			_ = json.NewEncoder(w).Encode(map[string]any{"signature": signature})
+		default:
+			http.Error(w, "unknown key", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	signer := NewRemoteSigner(server.URL + "/")
+	pubkeys, err := signer.PublicKeys(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []libcommon.Bytes48{pubkey}, pubkeys)
+
+	cfg := clparams.MainnetBeaconConfig
+	req, err := newSignRequest(forkInfoAtEpoch(&cfg, libcommon.Hash{1}, 0), cfg.DomainRandao, 0, libcommon.Hash{2},
+		SignRandaoReveal, "randao_reveal", map[string]string{"epoch": "0"})
+	require.NoError(t, err)
+	sig, err := signer.Sign(ctx, pubkey, req)
+	require.NoError(t, err)
+	require.Equal(t, signature, sig)
+	require.JSONEq(t, `"RANDAO_REVEAL"`, string(signed["type"]))
+	require.JSONEq(t, `{"epoch":"0"}`, string(signed["randao_reveal"]))
+	require.Contains(t, signed, "fork_info")
+	require.Contains(t, signed, "signingRoot")
+
+	_, err = signer.Sign(ctx, libcommon.Bytes48{0xcc}, req)
+	require.Error(t, err)
+}
@@ -0,0 +1,105 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package validator_client
+
+import (
+	"context"
+
+	"github.com/Giulio2002/bls"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/fork"
+	"github.com/erigontech/erigon/cl/utils"
+)
+
+// The types of the signing requests, as named by the web3signer API
+const (
+	SignBlock                  = "BLOCK_V2"
+	SignAttestation            = "ATTESTATION"
+	SignAggregationSlot        = "AGGREGATION_SLOT"
+	SignAggregateAndProof      = "AGGREGATE_AND_PROOF"
+	SignRandaoReveal           = "RANDAO_REVEAL"
+	SignSyncCommitteeMessage   = "SYNC_COMMITTEE_MESSAGE"
+	SignSyncCommitteeSelection = "SYNC_COMMITTEE_SELECTION_PROOF"
+	SignContributionAndProof   = "SYNC_COMMITTEE_CONTRIBUTION_AND_PROOF"
+)
+
+type ForkInfo struct {
+	Fork                  cltypes.Fork   `json:"fork"`
+	GenesisValidatorsRoot libcommon.Hash `json:"genesis_validators_root"`
+}
+
+// forkInfoAtEpoch is the fork of the chain at the epoch
+func forkInfoAtEpoch(beaconCfg *clparams.BeaconChainConfig, genesisValidatorsRoot libcommon.Hash, epoch uint64) ForkInfo {
+	version := beaconCfg.GetCurrentStateVersion(epoch)
+	current := utils.Uint32ToBytes4(beaconCfg.GetForkVersionByVersion(version))
+	previous := current
+	if version > clparams.Phase0Version {
+		previous = utils.Uint32ToBytes4(beaconCfg.GetForkVersionByVersion(version - 1))
+	}
+	return ForkInfo{
+		Fork: cltypes.Fork{
+			PreviousVersion: previous,
+			CurrentVersion:  current,
+			Epoch:           beaconCfg.GetForkEpochByVersion(version),
+		},
+		GenesisValidatorsRoot: genesisValidatorsRoot,
+	}
+}
+
+// SignRequest is a message to sign. The local keys only need the signing root, the remote signers get the
+// object too, under the PayloadName field of the request.
+type SignRequest struct {
+	Type        string
+	ForkInfo    ForkInfo
+	SigningRoot libcommon.Hash
+	PayloadName string
+	Payload     any
+}
+
+// newSignRequest computes the signing root of the object in the domain at the epoch
+func newSignRequest(forkInfo ForkInfo, domainType libcommon.Bytes4, epoch uint64, root libcommon.Hash, signType, payloadName string, payload any) (*SignRequest, error) {
+	domain, err := fork.Domain(&forkInfo.Fork, epoch, domainType, forkInfo.GenesisValidatorsRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &SignRequest{
+		Type:        signType,
+		ForkInfo:    forkInfo,
+		SigningRoot: utils.Sha256(root[:], domain),
+		PayloadName: payloadName,
+		Payload:     payload,
+	}, nil
+}
+
+// Signer signs for one or more validator keys
+type Signer interface {
+	Sign(ctx context.Context, pubkey libcommon.Bytes48, req *SignRequest) (libcommon.Bytes96, error)
+}
+
+// localSigner signs with a secret key of a keystore
+type localSigner struct {
+	sk *bls.PrivateKey
+}
+
+func (l *localSigner) Sign(_ context.Context, _ libcommon.Bytes48, req *SignRequest) (libcommon.Bytes96, error) {
+	var sig libcommon.Bytes96
+	copy(sig[:], l.sk.Sign(req.SigningRoot[:]).Bytes())
+	return sig, nil
+}
@@ -0,0 +1,340 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package validator_client is the validator client embedded in Caplin. It performs the duties of its keys
+// through the beacon API of the node, signing with keystores or a web3signer compatible remote signer,
+// and refuses to sign anything slashable.
+package validator_client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cl/clparams"
+	"github.com/erigontech/erigon/cl/utils/eth_clock"
+	"github.com/erigontech/erigon/cl/validator/keystore"
+	"github.com/erigontech/erigon/cl/validator/slashing_protection"
+)
+
+// keystoreIterations is the pbkdf2 iterations of the keystores written by the client
+const keystoreIterations = 262144
+
+var (
+	ErrDuplicateKey = errors.New("key is already active")
+	ErrReadonlyKey  = errors.New("key is loaded from the command line flags, remove it there")
+	ErrKeyNotFound  = errors.New("key not found")
+)
+
+type Config struct {
+	// DataDir holds the slashing protection database and the keys imported through the keymanager API
+	DataDir string
+	// KeystoresDir and SecretsPath are the keystores to load at startup and their passwords, either a dir
+	// of files named after the pubkeys or a single file
+	KeystoresDir string
+	SecretsPath  string
+	// RemoteSignerURL is a web3signer compatible signer whose keys are all used
+	RemoteSignerURL string
+	FeeRecipient    libcommon.Address
+	Graffiti        string
+}
+
+// validatorKey is a key of the client
+type validatorKey struct {
+	pubkey libcommon.Bytes48
+	signer Signer
+	// readonly keys come from the flags, they are not managed through the keymanager API
+	readonly       bool
+	remoteURL      string
+	derivationPath string
+}
+
+func (k *validatorKey) remote() bool {
+	return k.remoteURL != ""
+}
+
+type ValidatorClient struct {
+	cfg        Config
+	beaconCfg  *clparams.BeaconChainConfig
+	ethClock   eth_clock.EthereumClock
+	beacon     *beaconClient
+	protection *slashing_protection.SlashingProtection
+	logger     log.Logger
+
+	mu   sync.RWMutex
+	keys map[libcommon.Bytes48]*validatorKey
+	// keysChanged makes the next slot refresh the indices and the duties
+	keysChanged bool
+
+	duties *dutiesCache
+}
+
+// New loads the keys and opens the slashing protection database. The beacon API handler must serve the
+// beacon and validator endpoints.
+func New(ctx context.Context, cfg Config, beaconCfg *clparams.BeaconChainConfig, ethClock eth_clock.EthereumClock, beaconAPI http.Handler, logger log.Logger) (*ValidatorClient, error) {
+	if err := os.MkdirAll(cfg.DataDir, 0700); err != nil {
+		return nil, err
+	}
+	protection, err := slashing_protection.Open(ctx, filepath.Join(cfg.DataDir, "slashing_protection"), beaconCfg, ethClock.GenesisValidatorsRoot(), logger)
+	if err != nil {
+		return nil, err
+	}
+	v := &ValidatorClient{
+		cfg:         cfg,
+		beaconCfg:   beaconCfg,
+		ethClock:    ethClock,
+		beacon:      newBeaconClient(beaconAPI, beaconCfg),
+		protection:  protection,
+		logger:      logger,
+		keys:        map[libcommon.Bytes48]*validatorKey{},
+		keysChanged: true,
+		duties:      newDutiesCache(),
+	}
+	if err := v.loadKeys(ctx); err != nil {
+		protection.Close()
+		return nil, err
+	}
+	return v, nil
+}
+
+func (v *ValidatorClient) Close() {
+	v.protection.Close()
+}
+
+func (v *ValidatorClient) keystoresDir() string { return filepath.Join(v.cfg.DataDir, "keystores") }
+func (v *ValidatorClient) secretsDir() string   { return filepath.Join(v.cfg.DataDir, "secrets") }
+func (v *ValidatorClient) remoteKeysFile() string {
+	return filepath.Join(v.cfg.DataDir, "remote_keys.json")
+}
+
+func (v *ValidatorClient) loadKeys(ctx context.Context) error {
+	if v.cfg.KeystoresDir != "" {
+		keys, err := keystore.LoadDir(v.cfg.KeystoresDir, v.cfg.SecretsPath)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			v.addLocalKey(key, true)
+		}
+	}
+	if _, err := os.Stat(v.keystoresDir()); err == nil {
+		keys, err := keystore.LoadDir(v.keystoresDir(), v.secretsDir())
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			v.addLocalKey(key, false)
+		}
+	}
+	if v.cfg.RemoteSignerURL != "" {
+		signer := NewRemoteSigner(v.cfg.RemoteSignerURL)
+		pubkeys, err := signer.PublicKeys(ctx)
+		if err != nil {
+			return fmt.Errorf("remote signer %s: %w", v.cfg.RemoteSignerURL, err)
+		}
+		for _, pubkey := range pubkeys {
+			v.keys[pubkey] = &validatorKey{pubkey: pubkey, signer: signer, readonly: true, remoteURL: signer.URL()}
+		}
+	}
+	remoteKeys, err := v.readRemoteKeys()
+	if err != nil {
+		return err
+	}
+	for _, key := range remoteKeys {
+		if _, ok := v.keys[key.Pubkey]; !ok {
+			v.keys[key.Pubkey] = &validatorKey{pubkey: key.Pubkey, signer: NewRemoteSigner(key.URL), remoteURL: key.URL}
+		}
+	}
+	v.logger.Info("[Validator] loaded keys", "count", len(v.keys))
+	return nil
+}
+
+func (v *ValidatorClient) addLocalKey(key keystore.Key, readonly bool) {
+	pubkey := key.Keystore.PublicKey()
+	if _, ok := v.keys[pubkey]; ok {
+		return
+	}
+	v.keys[pubkey] = &validatorKey{
+		pubkey:         pubkey,
+		signer:         &localSigner{sk: key.SecretKey},
+		readonly:       readonly,
+		derivationPath: key.Keystore.Path,
+	}
+}
+
+// key returns the key of the pubkey, nil if the client does not have it
+func (v *ValidatorClient) key(pubkey libcommon.Bytes48) *validatorKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.keys[pubkey]
+}
+
+func (v *ValidatorClient) pubkeys() []libcommon.Bytes48 {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	pubkeys := make([]libcommon.Bytes48, 0, len(v.keys))
+	for pubkey := range v.keys {
+		pubkeys = append(pubkeys, pubkey)
+	}
+	sort.Slice(pubkeys, func(i, j int) bool { return pubkeys[i].Hex() < pubkeys[j].Hex() })
+	return pubkeys
+}
+
+// sortedKeys returns the local or the remote keys
+func (v *ValidatorClient) sortedKeys(remote bool) []*validatorKey {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	var keys []*validatorKey
+	for _, key := range v.keys {
+		if key.remote() == remote {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].pubkey.Hex() < keys[j].pubkey.Hex() })
+	return keys
+}
+
+// ImportKeystore decrypts the keystore, stores it with its password in the data dir and starts using it
+func (v *ValidatorClient) ImportKeystore(k *keystore.Keystore, password string) error {
+	pubkey := k.PublicKey()
+	if v.key(pubkey) != nil {
+		return ErrDuplicateKey
+	}
+	sk, err := k.Decrypt(password)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(k)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(v.keystoresDir(), 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(v.secretsDir(), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(v.secretsDir(), pubkey.Hex()), []byte(password), 0600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(v.keystoresDir(), pubkey.Hex()+".json"), data, 0600); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.keys[pubkey]; ok {
+		return ErrDuplicateKey
+	}
+	v.addLocalKey(keystore.Key{Keystore: k, SecretKey: sk}, false)
+	v.keysChanged = true
+	v.logger.Info("[Validator] imported keystore", "pubkey", pubkey.Hex())
+	return nil
+}
+
+// DeleteKey stops using the key and removes it from the data dir
+func (v *ValidatorClient) DeleteKey(pubkey libcommon.Bytes48, remote bool) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key, ok := v.keys[pubkey]
+	if !ok || key.remote() != remote {
+		return ErrKeyNotFound
+	}
+	if key.readonly {
+		return ErrReadonlyKey
+	}
+	delete(v.keys, pubkey)
+	v.keysChanged = true
+	if remote {
+		if err := v.writeRemoteKeys(); err != nil {
+			return err
+		}
+	} else {
+		for _, path := range []string{filepath.Join(v.keystoresDir(), pubkey.Hex()+".json"), filepath.Join(v.secretsDir(), pubkey.Hex())} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	v.logger.Info("[Validator] deleted key", "pubkey", pubkey.Hex(), "remote", remote)
+	return nil
+}
+
+// RemoteKey is a key held by a remote signer
+type RemoteKey struct {
+	Pubkey libcommon.Bytes48 `json:"pubkey"`
+	URL    string            `json:"url"`
+}
+
+// ImportRemoteKey starts using the key of the remote signer
+func (v *ValidatorClient) ImportRemoteKey(key RemoteKey) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if _, ok := v.keys[key.Pubkey]; ok {
+		return ErrDuplicateKey
+	}
+	signer := NewRemoteSigner(key.URL)
+	v.keys[key.Pubkey] = &validatorKey{pubkey: key.Pubkey, signer: signer, remoteURL: signer.URL()}
+	if err := v.writeRemoteKeys(); err != nil {
+		delete(v.keys, key.Pubkey)
+		return err
+	}
+	v.keysChanged = true
+	v.logger.Info("[Validator] imported remote key", "pubkey", key.Pubkey.Hex(), "url", key.URL)
+	return nil
+}
+
+func (v *ValidatorClient) readRemoteKeys() ([]RemoteKey, error) {
+	data, err := os.ReadFile(v.remoteKeysFile())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var keys []RemoteKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("%s: %w", v.remoteKeysFile(), err)
+	}
+	return keys, nil
+}
+
+// writeRemoteKeys stores the remote keys which are not from the flags, the lock must be held
+func (v *ValidatorClient) writeRemoteKeys() error {
+	keys := []RemoteKey{}
+	for _, key := range v.keys {
+		if key.remote() && !key.readonly {
+			keys = append(keys, RemoteKey{Pubkey: key.pubkey, URL: key.remoteURL})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Pubkey.Hex() < keys[j].Pubkey.Hex() })
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := v.remoteKeysFile() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, v.remoteKeysFile())
+}
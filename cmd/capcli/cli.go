@@ -27,6 +27,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"strings"
@@ -49,6 +50,7 @@ import (
 	"github.com/erigontech/erigon/cl/clparams"
 	"github.com/erigontech/erigon/cl/clparams/initial_state"
 	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/era"
 	"github.com/erigontech/erigon/cl/persistence/beacon_indicies"
 	"github.com/erigontech/erigon/cl/persistence/format/snapshot_format"
 	"github.com/erigontech/erigon/cl/persistence/format/snapshot_format/getters"
@@ -83,6 +85,7 @@ var CLI struct {
 	CheckBlobsSnapshotsCount  CheckBlobsSnapshotsCount  `cmd:"" help:"check blobs snapshots count"`
 	DumpBlobsSnapshotsToStore DumpBlobsSnapshotsToStore `cmd:"" help:"dump blobs snapshots to store"`
 	DumpStateSnapshots        DumpStateSnapshots        `cmd:"" help:"dump state snapshots"`
+	ExportEra                 ExportEra                 `cmd:"" help:"export the history to era files"`
 }
 
 type chainCfg struct {
@@ -1269,3 +1272,142 @@ func (c *DumpStateSnapshots) Run(ctx *Context) error {
 
 	return nil
 }
+
+type ExportEra struct {
+	chainCfg
+	outputFolder
+	From uint64 `name:"from" help:"first era to export" default:"0"`
+	To   uint64 `name:"to" help:"last era to export, 0 for the last one with a historical state"`
+	Out  string `name:"out" help:"directory of the era files" type:"existingdir" required:""`
+}
+
+func (c *ExportEra) Run(ctx *Context) error {
+	vt := state_accessors.NewStaticValidatorTable()
+	_, beaconConfig, t, err := clparams.GetConfigsByNetworkName(c.Chain)
+	if err != nil {
+		return err
+	}
+	log.Root().SetHandler(log.LvlFilterHandler(log.LvlInfo, log.StderrHandler))
+	dirs := datadir.New(c.Datadir)
+	db, _, err := caplin1.OpenCaplinDatabase(ctx, beaconConfig, nil, dirs.CaplinIndexing, dirs.CaplinBlobs, nil, false, 0)
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	freezingCfg := ethconfig.Defaults.Snapshot
+	freezingCfg.ChainName = c.Chain
+	allSnapshots := freezeblocks.NewRoSnapshots(freezingCfg, dirs.Snap, 0, log.Root())
+	if err := allSnapshots.OpenFolder(); err != nil {
+		return err
+	}
+	if err := state_accessors.ReadValidatorsTable(tx, vt); err != nil {
+		return err
+	}
+	blockReader := freezeblocks.NewBlockReader(allSnapshots, nil, nil, nil)
+	eth1Getter := getters.NewExecutionSnapshotReader(ctx, blockReader, db)
+	eth1Getter.SetBeaconChainConfig(beaconConfig)
+	csn := freezeblocks.NewCaplinSnapshots(freezingCfg, beaconConfig, dirs, log.Root())
+	if err := csn.OpenFolder(); err != nil {
+		return err
+	}
+	snr := freezeblocks.NewBeaconSnapshotReader(csn, eth1Getter, beaconConfig)
+	gSpot, err := initial_state.GetGenesisState(t)
+	if err != nil {
+		return err
+	}
+	snTypes := snapshotsync.MakeCaplinStateSnapshotsTypes(db)
+	stateSn := snapshotsync.NewCaplinStateSnapshots(freezingCfg, beaconConfig, dirs, snTypes, log.Root())
+	if err := stateSn.OpenFolder(); err != nil {
+		return err
+	}
+	if _, err := antiquary.FillStaticValidatorsTableIfNeeded(ctx, log.Root(), stateSn, vt); err != nil {
+		return err
+	}
+	// the historical roots of the node are the ones of its latest state, no need to go to the network
+	genesisCopy, err := gSpot.Copy()
+	if err != nil {
+		return err
+	}
+	bs, err := checkpoint_sync.NewLocalCheckpointSyncer(genesisCopy, afero.NewBasePathFs(afero.NewOsFs(), dirs.CaplinLatest)).GetLatestBeaconState(ctx)
+	if err != nil {
+		return err
+	}
+	sn := synced_data.NewSyncedDataManager(beaconConfig, true)
+	sn.OnHeadState(bs)
+	hr := historical_states_reader.NewHistoricalStatesReader(beaconConfig, snr, vt, gSpot, stateSn, sn)
+
+	sphr := beaconConfig.SlotsPerHistoricalRoot
+	to := c.To
+	if to == 0 {
+		progress, err := state_accessors.GetStateProcessingProgress(tx)
+		if err != nil {
+			return err
+		}
+		to = max(progress, stateSn.BlocksAvailable()) / sphr
+	}
+	for i := c.From; i <= to; i++ {
+		path, err := exportEra(ctx, tx, beaconConfig, snr, hr, gSpot, i, c.Out)
+		if err != nil {
+			return fmt.Errorf("era %d: %w", i, err)
+		}
+		log.Info("Exported era", "era", i, "file", path)
+	}
+	return nil
+}
+
+// exportEra writes the era file of the blocks and the historical state, and checks it
+func exportEra(ctx context.Context, tx kv.Tx, beaconConfig *clparams.BeaconChainConfig, snr freezeblocks.BeaconSnapshotReader, hr *historical_states_reader.HistoricalStatesReader, genesis *state.CachingBeaconState, eraNum uint64, dir string) (string, error) {
+	sphr := beaconConfig.SlotsPerHistoricalRoot
+	tmp, err := os.CreateTemp(dir, "era-*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	b := era.NewBuilder(tmp, beaconConfig, eraNum)
+	st := genesis
+	if eraNum > 0 {
+		// the genesis block is not in the era files
+		for slot := max((eraNum-1)*sphr, 1); slot < eraNum*sphr; slot++ {
+			block, err := snr.ReadBlockBySlot(ctx, tx, slot)
+			if err != nil {
+				return "", err
+			}
+			if block == nil {
+				continue
+			}
+			if err := b.AddBlock(block); err != nil {
+				return "", err
+			}
+		}
+		if st, err = hr.ReadHistoricalState(ctx, tx, eraNum*sphr); err != nil {
+			return "", err
+		}
+		if st == nil {
+			return "", fmt.Errorf("no historical state at slot %d", eraNum*sphr)
+		}
+	}
+	root, err := b.Finalize(st)
+	if err != nil {
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	e, err := era.Open(tmp.Name(), beaconConfig)
+	if err != nil {
+		return "", err
+	}
+	_, err = e.Verify(root[:4], nil)
+	e.Close()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, era.Filename(beaconConfig.ConfigName, eraNum, root))
+	return path, os.Rename(tmp.Name(), path)
+}
@@ -40,6 +40,7 @@ import (
 	"github.com/erigontech/erigon/cl/beacon/synced_data"
 	"github.com/erigontech/erigon/cl/clparams/initial_state"
 	"github.com/erigontech/erigon/cl/cltypes"
+	"github.com/erigontech/erigon/cl/era"
 	"github.com/erigontech/erigon/cl/monitor"
 	"github.com/erigontech/erigon/cl/rpc"
 	"github.com/erigontech/erigon/cl/sentinel"
@@ -48,6 +49,7 @@ import (
 	"github.com/erigontech/erigon/cl/validator/attestation_producer"
 	"github.com/erigontech/erigon/cl/validator/committee_subscription"
 	"github.com/erigontech/erigon/cl/validator/sync_contribution_pool"
+	"github.com/erigontech/erigon/cl/validator/validator_client"
 	"github.com/erigontech/erigon/cl/validator/validator_params"
 	"github.com/erigontech/erigon/eth/ethconfig"
 	"github.com/erigontech/erigon/params"
@@ -401,6 +403,13 @@ func RunCaplinService(ctx context.Context, engine execution_client.ExecutionEngi
 		return err
 	}
 	antiq := antiquary.NewAntiquary(ctx, blobStorage, genesisState, vTables, beaconConfig, dirs, snDownloader, indexDB, stateSnapshots, csn, rcsn, syncedDataManager, logger, states, backfilling, blobBackfilling, config.SnapshotGenerationEnabled, snBuildSema)
+	var eraStore *era.Store
+	if config.EraDir != "" {
+		if eraStore, err = era.NewStore(config.EraDir, beaconConfig, logger); err != nil {
+			return err
+		}
+		defer eraStore.Close()
+	}
 	// Create the antiquary
 	go func() {
 		if err := antiq.Loop(); err != nil {
@@ -412,6 +421,10 @@ func RunCaplinService(ctx context.Context, engine execution_client.ExecutionEngi
 		return err
 	}
 
+	if config.EnableValidatorClient && (!config.BeaconAPIRouter.Beacon || !config.BeaconAPIRouter.Validator) {
+		return errors.New("the validator client needs the beacon and validator endpoints of the beacon API, check --beacon.api flag")
+	}
+
 	statesReader := historical_states_reader.NewHistoricalStatesReader(beaconConfig, rcsn, vTables, genesisState, stateSnapshots, syncedDataManager)
 	validatorParameters := validator_params.NewValidatorParams()
 	if config.BeaconAPIRouter.Active {
@@ -454,6 +467,35 @@ func RunCaplinService(ctx context.Context, engine execution_client.ExecutionEngi
 			ArchiveApi: apiHandler,
 		}, config.BeaconAPIRouter)
 		log.Info("Beacon API started", "addr", config.BeaconAPIRouter.Address)
+
+		if config.EnableValidatorClient {
+			validatorClient, err := validator_client.New(ctx, validator_client.Config{
+				DataDir:         dirs.CaplinValidator,
+				KeystoresDir:    config.ValidatorKeystoresDir,
+				SecretsPath:     config.ValidatorSecretsPath,
+				RemoteSignerURL: config.ValidatorRemoteSignerUrl,
+				FeeRecipient:    config.ValidatorFeeRecipient,
+				Graffiti:        config.ValidatorGraffiti,
+			}, beaconConfig, ethClock, apiHandler, logger)
+			if err != nil {
+				return fmt.Errorf("validator client: %w", err)
+			}
+			defer validatorClient.Close()
+			tokenFile := config.KeymanagerTokenFile
+			if tokenFile == "" {
+				tokenFile = path.Join(dirs.CaplinValidator, "api-token.txt")
+			}
+			token, err := validator_client.LoadOrCreateToken(tokenFile)
+			if err != nil {
+				return fmt.Errorf("keymanager token: %w", err)
+			}
+			go func() {
+				if err := validatorClient.ListenAndServeKeymanager(fmt.Sprintf("%s:%d", config.KeymanagerAddr, config.KeymanagerPort), token); err != nil {
+					logger.Error("[Validator] keymanager API failed", "err", err)
+				}
+			}()
+			go validatorClient.Run(ctx)
+		}
 	}
 
 	stageCfg := stages.ClStagesCfg(
@@ -472,6 +514,7 @@ func RunCaplinService(ctx context.Context, engine execution_client.ExecutionEngi
 		config.LoopBlockLimit,
 		backfilling,
 		blobBackfilling,
+		eraStore,
 		syncedDataManager,
 		emitters,
 		blobStorage,
@@ -125,6 +125,7 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().BoolVar(&polygonSync, "polygon.sync", false, "Enable if Erigon has been synced using the new polygon sync component")
 
 	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, utils.RpcAccessListFlag.Name, "", "Specify granular (method-by-method) API allowlist")
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcRateLimitFilePath, utils.RpcRateLimitFlag.Name, "", utils.RpcRateLimitFlag.Usage)
 	rootCmd.PersistentFlags().UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.RpcStreamingDisable, utils.RpcStreamingDisableFlag.Name, false, utils.RpcStreamingDisableFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.DebugSingleRequest, utils.HTTPDebugSingleFlag.Name, false, utils.HTTPDebugSingleFlag.Usage)
@@ -180,6 +181,10 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().IntVar(&cfg.ReturnDataLimit, utils.RpcReturnDataLimit.Name, utils.RpcReturnDataLimit.Value, utils.RpcReturnDataLimit.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.AllowUnprotectedTxs, utils.AllowUnprotectedTxs.Name, utils.AllowUnprotectedTxs.Value, utils.AllowUnprotectedTxs.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.MaxGetProofRewindBlockCount, utils.RpcMaxGetProofRewindBlockCount.Name, utils.RpcMaxGetProofRewindBlockCount.Value, utils.RpcMaxGetProofRewindBlockCount.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.KeystoreDir, utils.RpcKeystoreFlag.Name, utils.RpcKeystoreFlag.Value, utils.RpcKeystoreFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.KeystorePasswordFile, utils.RpcKeystorePasswordFileFlag.Name, utils.RpcKeystorePasswordFileFlag.Value, utils.RpcKeystorePasswordFileFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.ExternalSigner, utils.RpcExternalSignerFlag.Name, utils.RpcExternalSignerFlag.Value, utils.RpcExternalSignerFlag.Usage)
+	rootCmd.PersistentFlags().BoolVar(&cfg.AllowInsecureUnlock, utils.RpcAllowInsecureUnlockFlag.Name, utils.RpcAllowInsecureUnlockFlag.Value, utils.RpcAllowInsecureUnlockFlag.Usage)
 	rootCmd.PersistentFlags().Uint64Var(&cfg.OtsMaxPageSize, utils.OtsSearchMaxCapFlag.Name, utils.OtsSearchMaxCapFlag.Value, utils.OtsSearchMaxCapFlag.Usage)
 	rootCmd.PersistentFlags().DurationVar(&cfg.RPCSlowLogThreshold, utils.RPCSlowFlag.Name, utils.RPCSlowFlag.Value, utils.RPCSlowFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.WebsocketSubscribeLogsChannelSize, utils.WSSubscribeLogsChannelSize.Name, utils.WSSubscribeLogsChannelSize.Value, utils.WSSubscribeLogsChannelSize.Usage)
@@ -187,6 +192,9 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	if err := rootCmd.MarkPersistentFlagFilename("rpc.accessList", "json"); err != nil {
 		panic(err)
 	}
+	if err := rootCmd.MarkPersistentFlagFilename(utils.RpcRateLimitFlag.Name, "json"); err != nil {
+		panic(err)
+	}
 	if err := rootCmd.MarkPersistentFlagDirname("datadir"); err != nil {
 		panic(err)
 	}
@@ -672,6 +680,12 @@ func startRegularRpcServer(ctx context.Context, cfg *httpcfg.HttpCfg, rpcAPI []r
 	}
 	srv.SetAllowList(allowListForRPC)
 
+	rateLimiter, err := parseRateLimitForRPC(cfg.RpcRateLimitFilePath)
+	if err != nil {
+		return err
+	}
+	srv.SetRateLimiter(rateLimiter)
+
 	srv.SetBatchLimit(cfg.BatchLimit)
 
 	defer srv.Stop()
@@ -67,6 +67,7 @@ type HttpCfg struct {
 	WebsocketCompression              bool
 	WebsocketSubscribeLogsChannelSize int
 	RpcAllowListFilePath              string
+	RpcRateLimitFilePath              string
 	RpcBatchConcurrency               uint
 	RpcStreamingDisable               bool
 	RpcFiltersConfig                  rpchelper.FiltersConfig
@@ -103,6 +104,12 @@ type HttpCfg struct {
 	ReturnDataLimit             int  // Maximum number of bytes returned from calls (like eth_call)
 	AllowUnprotectedTxs         bool // Whether to allow non EIP-155 protected transactions  txs over RPC
 	MaxGetProofRewindBlockCount int  //Max GetProof rewind block count
+
+	// Account signer backing eth_accounts, eth_sign, eth_signTransaction and eth_sendTransaction, disabled when both are empty
+	KeystoreDir          string // Directory of Web3 Secret Storage v3 key files
+	KeystorePasswordFile string // File with the keystore passwords, one per line
+	ExternalSigner       string // Endpoint (IPC path or http/ws URL) of a clef compatible external signer
+	AllowInsecureUnlock  bool   // Whether to unlock the keystore while the http server listens on a non-loopback address
 	// Ots API
 	OtsMaxPageSize uint64
 
@@ -33,6 +33,7 @@ import (
 	"github.com/erigontech/erigon/cmd/snapshots/cmp"
 	"github.com/erigontech/erigon/cmd/snapshots/copy"
 	"github.com/erigontech/erigon/cmd/snapshots/manifest"
+	"github.com/erigontech/erigon/cmd/snapshots/scrub"
 	"github.com/erigontech/erigon/cmd/snapshots/sync"
 	"github.com/erigontech/erigon/cmd/snapshots/torrents"
 	"github.com/erigontech/erigon/cmd/snapshots/verify"
@@ -54,6 +55,7 @@ func main() {
 		&cmp.Command,
 		&copy.Command,
 		&verify.Command,
+		&scrub.Command,
 		&torrents.Command,
 		&manifest.Command,
 	}
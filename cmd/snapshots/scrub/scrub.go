@@ -0,0 +1,196 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package scrub
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/urfave/cli/v2"
+
+	"github.com/erigontech/erigon-lib/chain/snapcfg"
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/common/dbg"
+	"github.com/erigontech/erigon-lib/downloader"
+	"github.com/erigontech/erigon-lib/downloader/downloadercfg"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cmd/downloader/downloadernat"
+	"github.com/erigontech/erigon/cmd/snapshots/sync"
+	"github.com/erigontech/erigon/cmd/utils"
+	"github.com/erigontech/erigon/p2p/nat"
+	"github.com/erigontech/erigon/params"
+)
+
+var (
+	RateFlag = cli.StringFlag{
+		Name:  "rate",
+		Usage: `Limit the disk read rate of the scrub, e.g. 100mb. 0 means unlimited`,
+		Value: "0",
+	}
+	QuarantineFlag = cli.BoolFlag{
+		Name:  "quarantine",
+		Usage: `Move the corrupted files which have a .torrent to <datadir>/snapshots/` + downloader.QuarantineDirName,
+	}
+	RepairFlag = cli.BoolFlag{
+		Name:  "repair",
+		Usage: `Quarantine the corrupted files which have a .torrent, and download them again from webseeds or peers`,
+	}
+)
+
+var Command = cli.Command{
+	Action:    scrub,
+	Name:      "scrub",
+	Usage:     "check every snapshot file against its torrent piece hashes and read it whole",
+	ArgsUsage: "[file name, prefix or suffix...]",
+	Flags: []cli.Flag{
+		&utils.DataDirFlag,
+		&utils.ChainFlag,
+		&RateFlag,
+		&QuarantineFlag,
+		&RepairFlag,
+		&utils.WebSeedsFlag,
+		&utils.NATFlag,
+		&utils.DisableIPV6,
+		&utils.DisableIPV4,
+		&utils.TorrentDownloadRateFlag,
+		&utils.TorrentUploadRateFlag,
+		&utils.TorrentVerbosityFlag,
+		&utils.TorrentPortFlag,
+		&utils.TorrentConnsPerFileFlag,
+	},
+	Description: `Reports the corrupted .seg/.kv/.v/.ef files and their accessors. Exits with an error if any was found and not repaired.`,
+}
+
+func scrub(cliCtx *cli.Context) error {
+	logger := sync.Logger(cliCtx.Context)
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+
+	var readRate datasize.ByteSize
+	if err := readRate.UnmarshalText([]byte(cliCtx.String(RateFlag.Name))); err != nil {
+		return fmt.Errorf("invalid --%s: %w", RateFlag.Name, err)
+	}
+
+	if cliCtx.Bool(QuarantineFlag.Name) || cliCtx.Bool(RepairFlag.Name) {
+		// corrupted files can't be moved away while a node has them open
+		_, l, err := dirs.MustFlock()
+		if err != nil {
+			return err
+		}
+		defer l.Unlock()
+	}
+
+	var repair downloader.RepairFunc
+	var d *downloader.Downloader
+	if cliCtx.Bool(RepairFlag.Name) {
+		var err error
+		if d, err = newDownloader(cliCtx, dirs, logger); err != nil {
+			return err
+		}
+		defer d.Close()
+		repair = d.Redownload
+	}
+
+	cfg := downloader.ScrubCfg{
+		Dirs:       dirs,
+		ReadRate:   readRate,
+		Quarantine: cliCtx.Bool(QuarantineFlag.Name) || repair != nil,
+	}
+	bad, err := downloader.NewScrubber(cfg, repair, logger).Scrub(cliCtx.Context, cliCtx.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	var notRepaired int
+	for _, res := range bad {
+		fmt.Printf("%s: %s (quarantined=%t, repaired=%t)\n", res.Name, res.Err, res.Quarantined, res.Repaired)
+		if !res.Repaired {
+			notRepaired++
+		}
+	}
+
+	if d != nil && len(bad) > notRepaired {
+		logger.Info("[snapshots] Waiting for the repaired files to be downloaded")
+		logEvery := time.NewTicker(20 * time.Second)
+		defer logEvery.Stop()
+		for !d.Completed() {
+			select {
+			case <-cliCtx.Context.Done():
+				return cliCtx.Context.Err()
+			case <-logEvery.C:
+				stats := d.Stats()
+				logger.Info("[snapshots] Repair", "progress", fmt.Sprintf("%.2f%%", stats.Progress))
+			case <-time.After(time.Second):
+			}
+		}
+	}
+
+	if notRepaired > 0 {
+		return fmt.Errorf("found %d corrupted files", notRepaired)
+	}
+	return nil
+}
+
+// newDownloader starts a downloader which only downloads the files it's asked to repair
+func newDownloader(cliCtx *cli.Context, dirs datadir.Dirs, logger log.Logger) (*downloader.Downloader, error) {
+	chain := cliCtx.String(utils.ChainFlag.Name)
+	if chain == "" {
+		return nil, errors.New("--chain is required to repair files")
+	}
+	var downloadRate, uploadRate datasize.ByteSize
+	if err := downloadRate.UnmarshalText([]byte(cliCtx.String(utils.TorrentDownloadRateFlag.Name))); err != nil {
+		return nil, err
+	}
+	if err := uploadRate.UnmarshalText([]byte(cliCtx.String(utils.TorrentUploadRateFlag.Name))); err != nil {
+		return nil, err
+	}
+	torrentLogLevel, _, err := downloadercfg.Int2LogLevel(cliCtx.Int(utils.TorrentVerbosityFlag.Name))
+	if err != nil {
+		return nil, err
+	}
+
+	webseedsList := common.CliString2Array(cliCtx.String(utils.WebSeedsFlag.Name))
+	if known, ok := snapcfg.KnownWebseeds[chain]; ok {
+		webseedsList = append(webseedsList, known...)
+	}
+
+	version := "erigon: " + params.VersionWithCommit(params.GitCommit)
+	cfg, err := downloadercfg.New(cliCtx.Context, dirs, version, torrentLogLevel, downloadRate, uploadRate,
+		cliCtx.Int(utils.TorrentPortFlag.Name), cliCtx.Int(utils.TorrentConnsPerFileFlag.Name), 0, nil, webseedsList, chain, false, false)
+	if err != nil {
+		return nil, err
+	}
+	cfg.ClientConfig.PieceHashersPerTorrent = dbg.EnvInt("DL_HASHERS", 32)
+	cfg.ClientConfig.DisableIPv6 = cliCtx.Bool(utils.DisableIPV6.Name)
+	cfg.ClientConfig.DisableIPv4 = cliCtx.Bool(utils.DisableIPV4.Name)
+	natif, err := nat.Parse(cliCtx.String(utils.NATFlag.Name))
+	if err != nil {
+		return nil, fmt.Errorf("invalid nat option %s: %w", cliCtx.String(utils.NATFlag.Name), err)
+	}
+	downloadernat.DoNat(natif, cfg.ClientConfig, logger)
+	// only the files being repaired are added
+	cfg.AddTorrentsFromDisk = false
+
+	d, err := downloader.New(cliCtx.Context, cfg, logger, log.LvlInfo, false)
+	if err != nil {
+		return nil, err
+	}
+	d.MainLoopInBackground(false)
+	return d, nil
+}
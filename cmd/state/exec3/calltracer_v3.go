@@ -26,6 +26,10 @@ import (
 type CallTracer struct {
 	froms map[libcommon.Address]struct{}
 	tos   map[libcommon.Address]struct{}
+
+	selectors map[[4]byte]struct{}
+	failed    map[libcommon.Address]struct{}
+	callees   []libcommon.Address // callees of the open frames
 }
 
 func NewCallTracer() *CallTracer {
@@ -33,31 +37,60 @@ func NewCallTracer() *CallTracer {
 }
 func (ct *CallTracer) Reset() {
 	ct.froms, ct.tos = nil, nil
+	ct.selectors, ct.failed, ct.callees = nil, nil, ct.callees[:0]
 }
 func (ct *CallTracer) Froms() map[libcommon.Address]struct{} { return ct.froms }
 func (ct *CallTracer) Tos() map[libcommon.Address]struct{}   { return ct.tos }
 
+// Selectors returns the function selectors of the call frames, calls to precompiles excluded
+func (ct *CallTracer) Selectors() map[[4]byte]struct{} { return ct.selectors }
+
+// Failed returns the callees of the failed (reverted or errored) call frames
+func (ct *CallTracer) Failed() map[libcommon.Address]struct{} { return ct.failed }
+
 func (ct *CallTracer) CaptureTxStart(gasLimit uint64) {}
 func (ct *CallTracer) CaptureTxEnd(restGas uint64)    {}
 func (ct *CallTracer) CaptureStart(env *vm.EVM, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
-	if ct.froms == nil {
-		ct.froms = map[libcommon.Address]struct{}{}
-		ct.tos = map[libcommon.Address]struct{}{}
-	}
-	ct.froms[from], ct.tos[to] = struct{}{}, struct{}{}
+	ct.enter(from, to, precompile, create, input)
 }
 func (ct *CallTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	ct.enter(from, to, precompile, create, input)
+}
+func (ct *CallTracer) enter(from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte) {
 	if ct.froms == nil {
 		ct.froms = map[libcommon.Address]struct{}{}
 		ct.tos = map[libcommon.Address]struct{}{}
 	}
 	ct.froms[from], ct.tos[to] = struct{}{}, struct{}{}
+	if !create && !precompile && len(input) >= 4 {
+		if ct.selectors == nil {
+			ct.selectors = map[[4]byte]struct{}{}
+		}
+		ct.selectors[[4]byte(input[:4])] = struct{}{}
+	}
+	ct.callees = append(ct.callees, to)
 }
 func (ct *CallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
 }
 func (ct *CallTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
 }
 func (ct *CallTracer) CaptureEnd(output []byte, usedGas uint64, err error) {
+	ct.exit(err)
 }
 func (ct *CallTracer) CaptureExit(output []byte, usedGas uint64, err error) {
+	ct.exit(err)
+}
+func (ct *CallTracer) exit(err error) {
+	if len(ct.callees) == 0 {
+		return
+	}
+	callee := ct.callees[len(ct.callees)-1]
+	ct.callees = ct.callees[:len(ct.callees)-1]
+	if err == nil {
+		return
+	}
+	if ct.failed == nil {
+		ct.failed = map[libcommon.Address]struct{}{}
+	}
+	ct.failed[callee] = struct{}{}
 }
@@ -76,7 +76,7 @@ type HistoricalTraceWorker struct {
 }
 
 type TraceConsumer struct {
-	NewTracer func() GenericTracer
+	NewTracer func() vm.EVMLogger
 	//Reduce receiving results of execution. They are sorted and have no gaps.
 	Reduce func(task *state.TxTask, tx kv.Tx) error
 }
@@ -212,6 +212,10 @@ func (rw *HistoricalTraceWorker) RunTxTask(txTask *state.TxTask) {
 			// Update the state with pending changes
 			ibs.SoftFinalise()
 			txTask.Logs = ibs.GetRawLogs(txTask.TxIndex)
+			if ct, ok := rw.vmConfig.Tracer.(*CallTracer); ok {
+				txTask.TraceFroms, txTask.TraceTos = ct.Froms(), ct.Tos()
+				txTask.TraceSelectors, txTask.TraceFailed = ct.Selectors(), ct.Failed()
+			}
 		}
 	}
 }
@@ -282,6 +282,8 @@ func (rw *Worker) RunTxTaskNoLock(txTask *state.TxTask, isMining bool) {
 			txTask.Logs = ibs.GetLogs(txTask.TxIndex, txTask.Tx.Hash(), txTask.BlockNum, txTask.BlockHash)
 			txTask.TraceFroms = rw.callTracer.Froms()
 			txTask.TraceTos = rw.callTracer.Tos()
+			txTask.TraceSelectors = rw.callTracer.Selectors()
+			txTask.TraceFailed = rw.callTracer.Failed()
 		}
 
 	}
@@ -0,0 +1,124 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// stateless re-executes a block out of its execution witness (as returned by debug_executionWitness),
+// without any state database, and checks the resulting state root against the block header.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/rlp"
+
+	"github.com/erigontech/erigon/core/stateless"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/ethconsensusconfig"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/rpc"
+)
+
+var (
+	chainName   = flag.String("chain", "mainnet", "name of the chain the block belongs to")
+	blockFile   = flag.String("block", "", "file with the hex encoded RLP of the block (as returned by debug_getRawBlock)")
+	witnessFile = flag.String("witness", "", "file with the JSON witness of the block (as returned by debug_executionWitness)")
+	rpcURL      = flag.String("rpc", "", "fetch the block and the witness from this RPC endpoint instead of files")
+	number      = flag.Uint64("number", 0, "number of the block to fetch when using -rpc")
+	verbosity   = flag.Int("verbosity", int(log.LvlInfo), "log verbosity (0-5)")
+)
+
+func init() {
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage:", os.Args[0], "[-chain <name>] (-block <file> -witness <file> | -rpc <url> -number <n>)")
+		flag.PrintDefaults()
+		fmt.Fprintln(os.Stderr, `
+Executes a block statelessly using its execution witness and verifies the post-state root.`)
+	}
+}
+
+func main() {
+	flag.Parse()
+	logger := log.New()
+	logger.SetHandler(log.LvlFilterHandler(log.Lvl(*verbosity), log.StderrHandler))
+	ctx := context.Background()
+
+	chainConfig := params.ChainConfigByChainName(*chainName)
+	if chainConfig == nil {
+		die(fmt.Errorf("unknown chain %q", *chainName))
+	}
+
+	var rawBlock hexutility.Bytes
+	var witness stateless.ExecutionWitness
+	switch {
+	case *rpcURL != "":
+		client, err := rpc.DialContext(ctx, *rpcURL, logger)
+		if err != nil {
+			die(err)
+		}
+		defer client.Close()
+		blockNr := hexutil.Uint64(*number)
+		if err := client.CallContext(ctx, &rawBlock, "debug_getRawBlock", blockNr); err != nil {
+			die(fmt.Errorf("fetching block: %w", err))
+		}
+		if err := client.CallContext(ctx, &witness, "debug_executionWitness", blockNr); err != nil {
+			die(fmt.Errorf("fetching witness: %w", err))
+		}
+
+	case *blockFile != "" && *witnessFile != "":
+		blockHex, err := os.ReadFile(*blockFile)
+		if err != nil {
+			die(err)
+		}
+		if rawBlock, err = hexutil.Decode(strings.TrimSpace(string(blockHex))); err != nil {
+			die(fmt.Errorf("decoding block file: %w", err))
+		}
+		witnessJson, err := os.ReadFile(*witnessFile)
+		if err != nil {
+			die(err)
+		}
+		if err := json.Unmarshal(witnessJson, &witness); err != nil {
+			die(fmt.Errorf("decoding witness file: %w", err))
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(rawBlock, block); err != nil {
+		die(fmt.Errorf("decoding block: %w", err))
+	}
+
+	engine := ethconsensusconfig.CreateConsensusEngineBareBones(ctx, chainConfig, logger)
+	root, err := stateless.VerifyBlock(chainConfig, engine, block, &witness, logger)
+	if err != nil {
+		die(fmt.Errorf("block %d: %w", block.NumberU64(), err))
+	}
+	fmt.Printf("block %d (%x) verified, state root %x\n", block.NumberU64(), block.Hash(), root)
+}
+
+func die(args ...interface{}) {
+	fmt.Fprintln(os.Stderr, args...)
+	os.Exit(1)
+}
@@ -71,6 +71,8 @@ var (
 	totalBlobPoolLimit uint64
 	priceBump          uint64
 	blobPriceBump      uint64
+	privateTxnLifetime uint64
+	blobStoreSize      string
 
 	noTxGossip bool
 
@@ -102,6 +104,8 @@ func init() {
 	rootCmd.PersistentFlags().Uint64Var(&priceBump, "txpool.pricebump", txpoolcfg.DefaultConfig.PriceBump, "Price bump percentage to replace an already existing transaction")
 	rootCmd.PersistentFlags().Uint64Var(&blobPriceBump, "txpool.blobpricebump", txpoolcfg.DefaultConfig.BlobPriceBump, "Price bump percentage to replace an existing blob (type-3) transaction")
 	rootCmd.PersistentFlags().DurationVar(&commitEvery, utils.TxPoolCommitEveryFlag.Name, utils.TxPoolCommitEveryFlag.Value, utils.TxPoolCommitEveryFlag.Usage)
+	rootCmd.PersistentFlags().Uint64Var(&privateTxnLifetime, utils.TxPoolPrivateTxnLifetimeFlag.Name, utils.TxPoolPrivateTxnLifetimeFlag.Value, utils.TxPoolPrivateTxnLifetimeFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&blobStoreSize, utils.TxPoolBlobStoreSizeFlag.Name, utils.TxPoolBlobStoreSizeFlag.Value, utils.TxPoolBlobStoreSizeFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&noTxGossip, utils.TxPoolGossipDisableFlag.Name, utils.TxPoolGossipDisableFlag.Value, utils.TxPoolGossipDisableFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&mdbxWriteMap, utils.DbWriteMapFlag.Name, utils.DbWriteMapFlag.Value, utils.DbWriteMapFlag.Usage)
 	rootCmd.Flags().StringSliceVar(&traceSenders, utils.TxPoolTraceSendersFlag.Name, []string{}, utils.TxPoolTraceSendersFlag.Usage)
@@ -171,6 +175,10 @@ func doTxpool(ctx context.Context, logger log.Logger) error {
 	cfg.TotalBlobPoolLimit = totalBlobPoolLimit
 	cfg.PriceBump = priceBump
 	cfg.BlobPriceBump = blobPriceBump
+	cfg.PrivateTxnLifetime = privateTxnLifetime
+	if err := cfg.BlobStoreSize.UnmarshalText([]byte(blobStoreSize)); err != nil {
+		return fmt.Errorf("invalid --%s: %w", utils.TxPoolBlobStoreSizeFlag.Name, err)
+	}
 	cfg.NoGossip = noTxGossip
 	cfg.MdbxWriteMap = mdbxWriteMap
 
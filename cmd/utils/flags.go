@@ -63,6 +63,7 @@ import (
 	"github.com/erigontech/erigon/polygon/heimdall"
 	"github.com/erigontech/erigon/rpc/rpccfg"
 	"github.com/erigontech/erigon/turbo/logging"
+	"github.com/erigontech/erigon/txnprovider"
 	"github.com/erigontech/erigon/txnprovider/txpool/txpoolcfg"
 )
 
@@ -216,6 +217,16 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: ethconfig.Defaults.DeprecatedTxPool.Lifetime,
 	}
+	TxPoolPrivateTxnLifetimeFlag = cli.Uint64Flag{
+		Name:  "txpool.private.lifetime",
+		Usage: "Number of blocks private transactions (eth_sendPrivateRawTransaction) are kept for local block building before they expire",
+		Value: txpoolcfg.DefaultConfig.PrivateTxnLifetime,
+	}
+	TxPoolBlobStoreSizeFlag = cli.StringFlag{
+		Name:  "txpool.blobstore.size",
+		Usage: "Disk space budget of the blob transactions store. When it's full, the blob transactions paying the least are evicted",
+		Value: txpoolcfg.DefaultConfig.BlobStoreSize.String(),
+	}
 	TxPoolTraceSendersFlag = cli.StringFlag{
 		Name:  "txpool.trace.senders",
 		Usage: "Comma separated list of addresses, whose transactions will traced in transaction pool with debug printing",
@@ -272,6 +283,15 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerTxnOrderingFlag = cli.StringFlag{
+		Name:  "miner.txnordering",
+		Usage: "Ordering policy of the transactions in built blocks: " + strings.Join(txnprovider.OrderingPolicyNames, ", "),
+		Value: txnprovider.DefaultOrderingPolicyName,
+	}
+	MinerPrioritySendersFlag = cli.StringFlag{
+		Name:  "miner.prioritysenders",
+		Usage: "Comma separated list of sender addresses whose transactions go first with --miner.txnordering=priority-lanes",
+	}
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
@@ -296,6 +316,11 @@ var (
 		Usage: "Reporting URL of a ethstats service (nodename:secret@host:port)",
 		Value: "",
 	}
+	LogExporterConfigFlag = cli.StringFlag{
+		Name:  "logexporter.config",
+		Usage: "Path of the JSON config of the exporters pushing the logs, receipts and headers of the new blocks, with revert events on reorgs, to webhooks, NDJSON files or Unix sockets",
+		Value: "",
+	}
 	FakePoWFlag = cli.BoolFlag{
 		Name:  "fakepow",
 		Usage: "Disables proof-of-work verification",
@@ -415,6 +440,10 @@ var (
 		Name:  "rpc.accessList",
 		Usage: "Specify granular (method-by-method) API allowlist",
 	}
+	RpcRateLimitFlag = cli.StringFlag{
+		Name:  "rpc.ratelimit",
+		Usage: "Path to a JSON file with the per-method costs and the per-client rate limits of the http/ws/ipc RPC servers",
+	}
 
 	RpcGasCapFlag = cli.UintFlag{
 		Name:  "rpc.gascap",
@@ -507,6 +536,25 @@ var (
 		Name:  "rpc.allow-unprotected-txs",
 		Usage: "Allow for unprotected (non-EIP155 signed) transactions to be submitted via RPC",
 	}
+	RpcKeystoreFlag = cli.StringFlag{
+		Name:  "rpc.keystore",
+		Usage: "Directory of encrypted (Web3 Secret Storage) key files used by eth_accounts, eth_sign, eth_signTransaction and eth_sendTransaction",
+		Value: "",
+	}
+	RpcKeystorePasswordFileFlag = cli.StringFlag{
+		Name:  "rpc.keystore.passwordfile",
+		Usage: "Password file to unlock the --rpc.keystore keys, one password per line",
+		Value: "",
+	}
+	RpcAllowInsecureUnlockFlag = cli.BoolFlag{
+		Name:  "rpc.allow-insecure-unlock",
+		Usage: "Allow to unlock the --rpc.keystore keys while the http server listens on a non-loopback address, exposing eth_sign, eth_signTransaction and eth_sendTransaction to remote clients",
+	}
+	RpcExternalSignerFlag = cli.StringFlag{
+		Name:  "rpc.signer",
+		Usage: "External signer (clef) endpoint, an IPC socket path or http/ws URL, used by eth_accounts, eth_sign, eth_signTransaction and eth_sendTransaction",
+		Value: "",
+	}
 	// Careful! Because we must rewind the hash state
 	// and re-compute the state trie, the further back in time the request, the more
 	// computationally intensive the operation becomes.
@@ -692,6 +740,16 @@ var (
 		Name:  ethconfig.FlagSnapStateStop,
 		Usage: "Workaround to stop producing new state files, if you meet some state-related critical bug. It will stop aggregate DB history in a state files. DB will grow and may slightly slow-down - and removing this flag in future will not fix this effect (db size will not greatly reduce).",
 	}
+	SnapScrubIntervalFlag = cli.DurationFlag{
+		Name:  "snap.scrub.interval",
+		Usage: "Check all snapshot files against their torrent piece hashes and read them whole every interval, in background. Corrupted files are only reported, use `snapshots scrub --repair` with the node stopped to repair them. 0 - disabled",
+		Value: 0,
+	}
+	SnapScrubRateFlag = cli.StringFlag{
+		Name:  "snap.scrub.rate",
+		Usage: "Disk read rate limit of the background snapshots scrub",
+		Value: "16mb",
+	}
 	TorrentVerbosityFlag = cli.IntFlag{
 		Name:  "torrent.verbosity",
 		Value: 2,
@@ -1027,6 +1085,56 @@ var (
 		Usage: "sets whether backfilling is enabled for caplin",
 		Value: false,
 	}
+	CaplinEraDirFlag = cli.StringFlag{
+		Name:  "caplin.era-dir",
+		Usage: "directory of .era files to backfill caplin's history from instead of the peers",
+		Value: "",
+	}
+	CaplinValidatorFlag = cli.BoolFlag{
+		Name:  "caplin.validator",
+		Usage: "enables the validator client embedded in caplin, it requires the beacon and validator endpoints of the beacon API",
+		Value: false,
+	}
+	CaplinValidatorKeystoresFlag = cli.StringFlag{
+		Name:  "caplin.validator.keystores",
+		Usage: "directory of EIP-2335 keystores for the embedded validator client",
+		Value: "",
+	}
+	CaplinValidatorSecretsFlag = cli.StringFlag{
+		Name:  "caplin.validator.secrets",
+		Usage: "password file of the keystores, or a directory of password files named after their pubkeys",
+		Value: "",
+	}
+	CaplinValidatorFeeRecipientFlag = cli.StringFlag{
+		Name:  "caplin.validator.fee-recipient",
+		Usage: "fee recipient of the blocks proposed by the embedded validator client",
+		Value: "",
+	}
+	CaplinValidatorGraffitiFlag = cli.StringFlag{
+		Name:  "caplin.validator.graffiti",
+		Usage: "graffiti of the blocks proposed by the embedded validator client",
+		Value: "",
+	}
+	CaplinValidatorRemoteSignerFlag = cli.StringFlag{
+		Name:  "caplin.validator.remote-signer",
+		Usage: "URL of a web3signer compatible remote signer, all of its keys are used",
+		Value: "",
+	}
+	CaplinKeymanagerAddrFlag = cli.StringFlag{
+		Name:  "caplin.keymanager.addr",
+		Usage: "sets the host to listen for the keymanager API of the embedded validator client",
+		Value: "localhost",
+	}
+	CaplinKeymanagerPortFlag = cli.Uint64Flag{
+		Name:  "caplin.keymanager.port",
+		Usage: "sets the port to listen for the keymanager API of the embedded validator client",
+		Value: 7500,
+	}
+	CaplinKeymanagerTokenFileFlag = cli.StringFlag{
+		Name:  "caplin.keymanager.token-file",
+		Usage: "bearer token file of the keymanager API, a random token is written there if it does not exist (default: <datadir>/caplin/validator/api-token.txt)",
+		Value: "",
+	}
 	CaplinDisableBlobPruningFlag = cli.BoolFlag{
 		Name:  "caplin.backfilling.blob.no-pruning",
 		Usage: "disable blob pruning in caplin",
@@ -1576,6 +1684,14 @@ func setTxPool(ctx *cli.Context, fullCfg *ethconfig.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolPrivateTxnLifetimeFlag.Name) {
+		fullCfg.TxPool.PrivateTxnLifetime = ctx.Uint64(TxPoolPrivateTxnLifetimeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolBlobStoreSizeFlag.Name) {
+		if err := fullCfg.TxPool.BlobStoreSize.UnmarshalText([]byte(ctx.String(TxPoolBlobStoreSizeFlag.Name))); err != nil {
+			panic(fmt.Errorf("invalid --%s: %w", TxPoolBlobStoreSizeFlag.Name, err))
+		}
+	}
 	if ctx.IsSet(TxPoolTraceSendersFlag.Name) {
 		// Parse the command separated flag
 		senderHexes := libcommon.CliString2Array(ctx.String(TxPoolTraceSendersFlag.Name))
@@ -1712,6 +1828,18 @@ func setMiner(ctx *cli.Context, cfg *params.MiningConfig) {
 	if ctx.IsSet(MinerNoVerfiyFlag.Name) {
 		cfg.Noverify = ctx.Bool(MinerNoVerfiyFlag.Name)
 	}
+	if ctx.IsSet(MinerPrioritySendersFlag.Name) {
+		for _, addr := range libcommon.CliString2Array(ctx.String(MinerPrioritySendersFlag.Name)) {
+			if !libcommon.IsHexAddress(addr) {
+				Fatalf("Invalid priority sender address %q", addr)
+			}
+			cfg.PrioritySenders = append(cfg.PrioritySenders, libcommon.HexToAddress(addr))
+		}
+	}
+	cfg.TxnOrdering = ctx.String(MinerTxnOrderingFlag.Name)
+	if _, err := txnprovider.NewOrderingPolicy(cfg.TxnOrdering, cfg.PrioritySenders); err != nil {
+		Fatalf("Option %s: %v", MinerTxnOrderingFlag.Name, err)
+	}
 }
 
 func setWhitelist(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1765,6 +1893,21 @@ func setCaplin(ctx *cli.Context, cfg *ethconfig.Config) {
 	cfg.CaplinConfig.Archive = ctx.Bool(CaplinArchiveFlag.Name)
 	cfg.CaplinConfig.MevRelayUrl = ctx.String(CaplinMevRelayUrl.Name)
 	cfg.CaplinConfig.EnableValidatorMonitor = ctx.Bool(CaplinValidatorMonitorFlag.Name)
+	cfg.CaplinConfig.EraDir = ctx.String(CaplinEraDirFlag.Name)
+	cfg.CaplinConfig.EnableValidatorClient = ctx.Bool(CaplinValidatorFlag.Name)
+	cfg.CaplinConfig.ValidatorKeystoresDir = ctx.String(CaplinValidatorKeystoresFlag.Name)
+	cfg.CaplinConfig.ValidatorSecretsPath = ctx.String(CaplinValidatorSecretsFlag.Name)
+	if feeRecipient := ctx.String(CaplinValidatorFeeRecipientFlag.Name); feeRecipient != "" {
+		if !libcommon.IsHexAddress(feeRecipient) {
+			Fatalf("Invalid --%s: %s", CaplinValidatorFeeRecipientFlag.Name, feeRecipient)
+		}
+		cfg.CaplinConfig.ValidatorFeeRecipient = libcommon.HexToAddress(feeRecipient)
+	}
+	cfg.CaplinConfig.ValidatorGraffiti = ctx.String(CaplinValidatorGraffitiFlag.Name)
+	cfg.CaplinConfig.ValidatorRemoteSignerUrl = ctx.String(CaplinValidatorRemoteSignerFlag.Name)
+	cfg.CaplinConfig.KeymanagerAddr = ctx.String(CaplinKeymanagerAddrFlag.Name)
+	cfg.CaplinConfig.KeymanagerPort = ctx.Uint64(CaplinKeymanagerPortFlag.Name)
+	cfg.CaplinConfig.KeymanagerTokenFile = ctx.String(CaplinKeymanagerTokenFileFlag.Name)
 	if checkpointUrls := ctx.StringSlice(CaplinCheckpointSyncUrlFlag.Name); len(checkpointUrls) > 0 {
 		clparams.ConfigurableCheckpointsURLs = checkpointUrls
 	}
@@ -1876,6 +2019,10 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	cfg.Snapshot.Verify = ctx.Bool(DownloaderVerifyFlag.Name)
 	cfg.Snapshot.DownloaderAddr = strings.TrimSpace(ctx.String(DownloaderAddrFlag.Name))
 	cfg.Snapshot.ChainName = chain
+	cfg.Snapshot.ScrubInterval = ctx.Duration(SnapScrubIntervalFlag.Name)
+	if err := cfg.Snapshot.ScrubRate.UnmarshalText([]byte(ctx.String(SnapScrubRateFlag.Name))); err != nil {
+		panic(fmt.Errorf("invalid --%s: %w", SnapScrubRateFlag.Name, err))
+	}
 	if cfg.Snapshot.DownloaderAddr == "" {
 		downloadRateStr := ctx.String(TorrentDownloadRateFlag.Name)
 		uploadRateStr := ctx.String(TorrentUploadRateFlag.Name)
@@ -1932,6 +2079,7 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	setCaplin(ctx, cfg)
 
 	cfg.Ethstats = ctx.String(EthStatsURLFlag.Name)
+	cfg.LogExporter = ctx.String(LogExporterConfigFlag.Name)
 
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
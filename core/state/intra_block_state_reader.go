@@ -0,0 +1,95 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"github.com/holiman/uint256"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/types/accounts"
+)
+
+// IntraBlockStateReader reads the state as left by the transactions already applied to an IntraBlockState.
+// It lets a throwaway IntraBlockState be layered on top of another one, e.g. to simulate transactions
+// without touching the state they would later be committed to. The transactions of the underlying state
+// must have been finalized.
+type IntraBlockStateReader struct {
+	ibs *IntraBlockState
+}
+
+func NewIntraBlockStateReader(ibs *IntraBlockState) *IntraBlockStateReader {
+	return &IntraBlockStateReader{ibs: ibs}
+}
+
+func (r *IntraBlockStateReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	so, err := r.ibs.getStateObject(address)
+	if err != nil {
+		return nil, err
+	}
+	if so == nil || so.deleted || so.selfdestructed {
+		return nil, nil
+	}
+	account := so.data
+	return &account, nil
+}
+
+func (r *IntraBlockStateReader) ReadAccountDataForDebug(address common.Address) (*accounts.Account, error) {
+	return r.ReadAccountData(address)
+}
+
+func (r *IntraBlockStateReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	so, err := r.ibs.getStateObject(address)
+	if err != nil {
+		return nil, err
+	}
+	if so == nil || so.deleted || so.selfdestructed || so.data.Incarnation != incarnation {
+		return nil, nil
+	}
+	var value uint256.Int
+	so.GetState(key, &value)
+	if value.IsZero() {
+		return nil, nil
+	}
+	return value.Bytes(), nil
+}
+
+func (r *IntraBlockStateReader) ReadAccountCode(address common.Address, incarnation uint64) ([]byte, error) {
+	so, err := r.ibs.getStateObject(address)
+	if err != nil {
+		return nil, err
+	}
+	if so == nil || so.deleted || so.selfdestructed {
+		return nil, nil
+	}
+	return so.Code(), nil
+}
+
+func (r *IntraBlockStateReader) ReadAccountCodeSize(address common.Address, incarnation uint64) (int, error) {
+	code, err := r.ReadAccountCode(address, incarnation)
+	return len(code), err
+}
+
+func (r *IntraBlockStateReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	so, err := r.ibs.getStateObject(address)
+	if err != nil {
+		return 0, err
+	}
+	if so == nil {
+		return 0, nil
+	}
+	return so.data.Incarnation, nil
+}
@@ -243,6 +243,31 @@ func (rs *StateV3) ApplyLogsAndTraces4(txTask *TxTask, domains *libstate.SharedD
 			}
 		}
 	}
+
+	if shouldPruneNonEssentials {
+		return nil
+	}
+	return ApplyTraceIndices(txTask, domains)
+}
+
+// ApplyTraceIndices writes the function selectors and the failed callees of the txn call frames
+func ApplyTraceIndices(txTask *TxTask, domains *libstate.SharedDomains) error {
+	for selector := range txTask.TraceSelectors {
+		if err := domains.IndexAdd(kv.TblTracesSelectorIdx, selector[:]); err != nil {
+			return err
+		}
+	}
+	if len(txTask.TraceFailed) == 0 {
+		return nil
+	}
+	if err := domains.IndexAdd(kv.TblTracesFailedIdx, kv.TracesFailedAnyKey); err != nil {
+		return err
+	}
+	for addr := range txTask.TraceFailed {
+		if err := domains.IndexAdd(kv.TblTracesFailedIdx, addr[:]); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
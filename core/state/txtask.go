@@ -73,6 +73,8 @@ type TxTask struct {
 	Logs               []*types.Log
 	TraceFroms         map[libcommon.Address]struct{}
 	TraceTos           map[libcommon.Address]struct{}
+	TraceSelectors     map[[4]byte]struct{}
+	TraceFailed        map[libcommon.Address]struct{} // callees of the failed call frames
 
 	UsedGas uint64
 
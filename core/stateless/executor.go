@@ -0,0 +1,72 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/chain"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/trie"
+
+	"github.com/erigontech/erigon/consensus"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/core/vm"
+)
+
+// ExecuteBlock re-executes the block on top of the parent state provided by the witness, without any database.
+// It returns the execution result, whose StateRoot is the post-state root computed from the witness trie.
+// Receipts, gas used and bloom are checked against the block header during execution.
+func ExecuteBlock(chainConfig *chain.Config, engine consensus.Engine, block *types.Block, witness *ExecutionWitness, logger log.Logger) (*core.EphemeralExecResult, error) {
+	if err := witness.validateHeaders(block); err != nil {
+		return nil, err
+	}
+	parent := witness.ParentHeader()
+
+	t, err := trie.BuildTrieFromNodes(parent.Root, toBytes(witness.State), toBytes(witness.Codes))
+	if err != nil {
+		return nil, fmt.Errorf("building state trie from witness: %w", err)
+	}
+	statelessState := state.NewStatelessFromTrie(t, parent.Number.Uint64(), false /* trace */)
+
+	chainReader := newWitnessChainReader(chainConfig, witness)
+	execResult, err := core.ExecuteBlockEphemerally(chainConfig, &vm.Config{}, witness.getHashFn(), engine, block, statelessState, statelessState, chainReader, nil, logger)
+	if err != nil {
+		return nil, err
+	}
+	execResult.StateRoot = statelessState.Finalize()
+	return execResult, nil
+}
+
+// VerifyBlock executes the block statelessly and checks that the resulting state root and the transactions root
+// match the ones in the block header.
+func VerifyBlock(chainConfig *chain.Config, engine consensus.Engine, block *types.Block, witness *ExecutionWitness, logger log.Logger) (libcommon.Hash, error) {
+	execResult, err := ExecuteBlock(chainConfig, engine, block, witness, logger)
+	if err != nil {
+		return libcommon.Hash{}, err
+	}
+	if execResult.TxRoot != block.TxHash() {
+		return execResult.StateRoot, fmt.Errorf("mismatch in block TxRoot actual(%x) != expected(%x)", execResult.TxRoot, block.TxHash())
+	}
+	if execResult.StateRoot != block.Root() {
+		return execResult.StateRoot, fmt.Errorf("state root mismatch after stateless execution actual(%x) != expected(%x)", execResult.StateRoot, block.Root())
+	}
+	return execResult.StateRoot, nil
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/erigontech/erigon-lib/chain"
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/rlp"
+
+	"github.com/erigontech/erigon/consensus"
+	"github.com/erigontech/erigon/core/types"
+)
+
+// ExecutionWitness is everything needed to execute a block without access to the state database,
+// in the format of debug_executionWitness (compatible with geth):
+//   - Headers: the ancestors of the block needed for BLOCKHASH, in reverse order. The first one is the parent and must be present.
+//   - Codes: the bytecodes of the contracts touched by the block.
+//   - State: the RLP encoded MPT nodes (account and storage tries) on the paths to the touched accounts and slots,
+//     at the state of the parent block.
+//   - Keys: the preimages of the touched trie keys, i.e. account addresses and storage slots.
+type ExecutionWitness struct {
+	Headers []*types.Header    `json:"headers"`
+	Codes   []hexutility.Bytes `json:"codes"`
+	State   []hexutility.Bytes `json:"state"`
+	Keys    []hexutility.Bytes `json:"keys"`
+}
+
+// ParentHeader returns the header of the parent of the witnessed block
+func (w *ExecutionWitness) ParentHeader() *types.Header {
+	if len(w.Headers) == 0 {
+		return nil
+	}
+	return w.Headers[0]
+}
+
+// validateHeaders checks that the headers of the witness form a chain ending at the parent of the given block
+func (w *ExecutionWitness) validateHeaders(block *types.Block) error {
+	if len(w.Headers) == 0 {
+		return errors.New("witness has no parent header")
+	}
+	expected := block.ParentHash()
+	for i, h := range w.Headers {
+		if h == nil {
+			return fmt.Errorf("witness header %d is nil", i)
+		}
+		if h.Hash() != expected {
+			return fmt.Errorf("witness header %d: hash %x does not match the expected %x", i, h.Hash(), expected)
+		}
+		expected = h.ParentHash
+	}
+	return nil
+}
+
+// getHashFn resolves BLOCKHASH from the witness headers. Block hashes which are not covered by the witness are
+// returned as empty, which is going to show up as a state root mismatch.
+func (w *ExecutionWitness) getHashFn() func(n uint64) libcommon.Hash {
+	hashes := make(map[uint64]libcommon.Hash, len(w.Headers))
+	for _, h := range w.Headers {
+		hashes[h.Number.Uint64()] = h.Hash()
+	}
+	return func(n uint64) libcommon.Hash {
+		return hashes[n]
+	}
+}
+
+func toBytes(in []hexutility.Bytes) [][]byte {
+	out := make([][]byte, len(in))
+	for i := range in {
+		out[i] = in[i]
+	}
+	return out
+}
+
+// witnessChainReader serves the headers of the witness to the consensus engine
+type witnessChainReader struct {
+	config  *chain.Config
+	headers map[libcommon.Hash]*types.Header
+	parent  *types.Header
+}
+
+var _ consensus.ChainReader = (*witnessChainReader)(nil)
+
+func newWitnessChainReader(config *chain.Config, w *ExecutionWitness) *witnessChainReader {
+	cr := &witnessChainReader{config: config, headers: make(map[libcommon.Hash]*types.Header, len(w.Headers)), parent: w.ParentHeader()}
+	for _, h := range w.Headers {
+		cr.headers[h.Hash()] = h
+	}
+	return cr
+}
+
+func (cr *witnessChainReader) Config() *chain.Config                 { return cr.config }
+func (cr *witnessChainReader) CurrentHeader() *types.Header          { return cr.parent }
+func (cr *witnessChainReader) CurrentFinalizedHeader() *types.Header { return nil }
+func (cr *witnessChainReader) CurrentSafeHeader() *types.Header      { return nil }
+func (cr *witnessChainReader) GetHeader(hash libcommon.Hash, number uint64) *types.Header {
+	if h, ok := cr.headers[hash]; ok && h.Number.Uint64() == number {
+		return h
+	}
+	return nil
+}
+func (cr *witnessChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	for _, h := range cr.headers {
+		if h.Number.Uint64() == number {
+			return h
+		}
+	}
+	return nil
+}
+func (cr *witnessChainReader) GetHeaderByHash(hash libcommon.Hash) *types.Header {
+	return cr.headers[hash]
+}
+func (cr *witnessChainReader) GetTd(hash libcommon.Hash, number uint64) *big.Int         { return nil }
+func (cr *witnessChainReader) FrozenBlocks() uint64                                      { return 0 }
+func (cr *witnessChainReader) FrozenBorBlocks() uint64                                   { return 0 }
+func (cr *witnessChainReader) GetBlock(hash libcommon.Hash, number uint64) *types.Block  { return nil }
+func (cr *witnessChainReader) HasBlock(hash libcommon.Hash, number uint64) bool          { return false }
+func (cr *witnessChainReader) BorStartEventId(hash libcommon.Hash, number uint64) uint64 { return 0 }
+func (cr *witnessChainReader) BorEventsByBlock(hash libcommon.Hash, number uint64) []rlp.RawValue {
+	return nil
+}
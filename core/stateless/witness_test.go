@@ -0,0 +1,83 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stateless
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+
+	"github.com/erigontech/erigon/core/types"
+)
+
+func makeHeaders(n int) []*types.Header {
+	headers := make([]*types.Header, n)
+	parentHash := libcommon.Hash{}
+	for i := 0; i < n; i++ {
+		headers[i] = &types.Header{ParentHash: parentHash, Number: big.NewInt(int64(i)), Difficulty: big.NewInt(1)}
+		parentHash = headers[i].Hash()
+	}
+	return headers
+}
+
+func TestWitnessHeaders(t *testing.T) {
+	chain := makeHeaders(10)
+	block := types.NewBlockWithHeader(&types.Header{ParentHash: chain[9].Hash(), Number: big.NewInt(10), Difficulty: big.NewInt(1)})
+
+	w := &ExecutionWitness{Headers: []*types.Header{chain[9], chain[8], chain[7]}}
+	require.NoError(t, w.validateHeaders(block))
+	require.Equal(t, chain[9], w.ParentHeader())
+
+	getHash := w.getHashFn()
+	require.Equal(t, chain[8].Hash(), getHash(8))
+	require.Equal(t, libcommon.Hash{}, getHash(3))
+
+	// gap in the chain of headers
+	w = &ExecutionWitness{Headers: []*types.Header{chain[9], chain[7]}}
+	require.Error(t, w.validateHeaders(block))
+
+	// parent is missing
+	w = &ExecutionWitness{Headers: []*types.Header{chain[8]}}
+	require.Error(t, w.validateHeaders(block))
+
+	require.Error(t, (&ExecutionWitness{}).validateHeaders(block))
+}
+
+func TestWitnessJSON(t *testing.T) {
+	chain := makeHeaders(2)
+	w := &ExecutionWitness{
+		Headers: []*types.Header{chain[1], chain[0]},
+		Codes:   []hexutility.Bytes{{0x60, 0x00}},
+		State:   []hexutility.Bytes{{0xc0}},
+		Keys:    []hexutility.Bytes{libcommon.Address{1}.Bytes()},
+	}
+
+	enc, err := json.Marshal(w)
+	require.NoError(t, err)
+	var dec ExecutionWitness
+	require.NoError(t, json.Unmarshal(enc, &dec))
+	require.Equal(t, w.Codes, dec.Codes)
+	require.Equal(t, w.State, dec.State)
+	require.Equal(t, w.Keys, dec.Keys)
+	require.Len(t, dec.Headers, 2)
+	require.Equal(t, chain[1].Hash(), dec.Headers[0].Hash())
+}
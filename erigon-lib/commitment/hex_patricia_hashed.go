@@ -774,7 +774,9 @@ func (hph *HexPatriciaHashed) computeCellHashWithStorage(cell *cell, depth int,
 					return nil, storageRootHashIsSet, nil, err
 				}
 				cell.setFromUpdate(update)
-				fmt.Printf("Storage %x was not loaded\n", cell.storageAddr[:cell.storageAddrLen])
+				if hph.trace {
+					fmt.Printf("Storage %x was not loaded\n", cell.storageAddr[:cell.storageAddrLen])
+				}
 			}
 			if singleton {
 				if hph.trace {
@@ -1155,7 +1157,11 @@ func (hph *HexPatriciaHashed) PrintGrid() {
 
 // this function is only related to the witness
 func (hph *HexPatriciaHashed) createAccountNode(c *cell, row int, hashedKey []byte, codeReads map[libcommon.Hash]witnesstypes.CodeWithHash) (*trie.AccountNode, error) {
-	_, storageIsSet, storageRootHash, err := hph.computeCellHashWithStorage(c, hph.depths[row], nil)
+	// the memoized hash of an account with a single storage slot is the hash of the whole leaf, not the storage root:
+	// hash a copy of the cell without it
+	cp := *c
+	cp.stateHashLen = 0
+	_, storageIsSet, storageRootHash, err := hph.computeCellHashWithStorage(&cp, hph.depths[row], nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1198,6 +1204,35 @@ func (hph *HexPatriciaHashed) createAccountNode(c *cell, row int, hashedKey []by
 	return accountNode, nil
 }
 
+// singleStorageAccountLeaf returns the leaf of an account with a single storage slot. The cell holds it together with
+// the leaf of its storage trie, under the rest of the account key followed by the storage key.
+func (hph *HexPatriciaHashed) singleStorageAccountLeaf(c *cell, row int, hashedKey []byte, codeReads map[libcommon.Hash]witnesstypes.CodeWithHash) (trie.Node, error) {
+	accNode, err := hph.createAccountNode(c, row, hashedKey, codeReads)
+	if err != nil {
+		return nil, err
+	}
+	if len(hashedKey) > 64 {
+		storageUpdate, err := hph.ctx.Storage(c.storageAddr[:c.storageAddrLen])
+		if err != nil {
+			return nil, err
+		}
+		accNode.Storage = &trie.ShortNode{
+			Key: leafNibbles(ecrypto.Keccak256(c.storageAddr[c.accountAddrLen:c.storageAddrLen]), 0),
+			Val: trie.ValueNode(storageUpdate.Storage[:storageUpdate.StorageLen]),
+		}
+	}
+	return &trie.ShortNode{Key: leafNibbles(ecrypto.Keccak256(c.accountAddr[:c.accountAddrLen]), hph.depths[row]), Val: accNode}, nil
+}
+
+// leafNibbles returns the nibbles of hashedKey from depth on, with the terminator of a leaf key
+func leafNibbles(hashedKey []byte, depth int) []byte {
+	nibbles := make([]byte, 0, 2*len(hashedKey)-depth+1)
+	for i := depth; i < 2*len(hashedKey); i++ {
+		nibbles = append(nibbles, (hashedKey[i/2]>>(4*(1-i%2)))&0xf)
+	}
+	return append(nibbles, 16)
+}
+
 func (hph *HexPatriciaHashed) nCellsInRow(row int) int { //nolint:unused
 	count := 0
 	for col := 0; col < 16; col++ {
@@ -1221,7 +1256,21 @@ func (hph *HexPatriciaHashed) ToTrie(hashedKey []byte, codeReads map[libcommon.H
 		// need to check node type along the key path
 		cellToExpand := &hph.grid[row][currentNibble]
 		// determine the next node
-		if cellToExpand.hashedExtLen > 0 { // extension cell
+		lastNode := false
+		if cellToExpand.accountAddrLen > 0 && cellToExpand.storageAddrLen > 0 && hph.depths[row] <= 64 {
+			// an account with a single storage slot, its leaf ends the path
+			leaf, err := hph.singleStorageAccountLeaf(cellToExpand, row, hashedKey, codeReads)
+			if err != nil {
+				return nil, err
+			}
+			nextNode = leaf
+			lastNode = true
+		} else if hashedExtKey := cellToExpand.hashedExtension[:cellToExpand.hashedExtLen]; cellToExpand.hashedExtLen > 0 && cellToExpand.hashLen > 0 &&
+			cellToExpand.accountAddrLen == 0 && cellToExpand.storageAddrLen == 0 && !bytes.HasPrefix(hashedKey[min(keyPos+1, len(hashedKey)):], hashedExtKey) {
+			// the key diverges from the extension, which ends the path
+			nextNode = &trie.ShortNode{Key: common.Copy(hashedExtKey), Val: trie.NewHashNode(cellToExpand.hash[:cellToExpand.hashLen])}
+			lastNode = true
+		} else if cellToExpand.hashedExtLen > 0 { // extension cell
 			keyPos += cellToExpand.hashedExtLen // jump ahead
 			hashedExtKey := cellToExpand.hashedExtension[:cellToExpand.hashedExtLen]
 			extKeyLength := len(hashedExtKey)
@@ -1250,7 +1299,10 @@ func (hph *HexPatriciaHashed) ToTrie(hashedKey []byte, codeReads map[libcommon.H
 					nextNode = &trie.ShortNode{Key: extensionKey, Val: accNode}
 					extNodeSubTrie := trie.NewInMemoryTrie(nextNode)
 					subTrieRoot := extNodeSubTrie.Root()
-					cellHash, _, _, _ := hph.computeCellHashWithStorage(cellToExpand, hph.depths[row], nil)
+					cellHash, err := hph.witnessCellHash(cellToExpand, hph.depths[row])
+					if err != nil {
+						return nil, err
+					}
 					if !bytes.Equal(subTrieRoot, cellHash[1:]) {
 						return nil, fmt.Errorf("subTrieRoot(%x) != cellHash(%x)", subTrieRoot, cellHash[1:])
 					}
@@ -1291,7 +1343,7 @@ func (hph *HexPatriciaHashed) ToTrie(hashedKey []byte, codeReads map[libcommon.H
 					fullNode.Children[col] = nil
 					continue
 				}
-				cellHash, _, _, err := hph.computeCellHashWithStorage(currentCell, hph.depths[row], nil)
+				cellHash, err := hph.witnessCellHash(currentCell, hph.depths[row])
 				if err != nil {
 					return nil, err
 				}
@@ -1315,9 +1367,12 @@ func (hph *HexPatriciaHashed) ToTrie(hashedKey []byte, codeReads map[libcommon.H
 		} else {
 			break // break if currentNode is nil
 		}
+		if lastNode {
+			break
+		}
 		// we need to check if we are dealing with the next node being an account node and we have a storage key,
-		// in that case start a new tree for the storage
-		if nextAccNode, ok := nextNode.(*trie.AccountNode); ok && len(hashedKey) > 64 {
+		// in that case start a new tree for the storage, unless the account has no storage at all
+		if nextAccNode, ok := nextNode.(*trie.AccountNode); ok && len(hashedKey) > 64 && nextAccNode.Storage != nil {
 			nextNode = &trie.FullNode{}
 			nextAccNode.Storage = nextNode
 		}
@@ -1327,6 +1382,14 @@ func (hph *HexPatriciaHashed) ToTrie(hashedKey []byte, codeReads map[libcommon.H
 	return tr, nil
 }
 
+// witnessCellHash hashes a copy of the cell: hashing writes the hashed keys of the leaves, with their terminators, into
+// the hashed extension of the cell, which the grid still needs to unfold the next keys of the witness
+func (hph *HexPatriciaHashed) witnessCellHash(c *cell, depth int) ([]byte, error) {
+	cp := *c
+	cellHash, _, _, err := hph.computeCellHashWithStorage(&cp, depth, nil)
+	return cellHash, err
+}
+
 // unfoldBranchNode returns true if unfolding has been done
 func (hph *HexPatriciaHashed) unfoldBranchNode(row, depth int, deleted bool) (bool, error) {
 	key := hexToCompact(hph.currentKey[:hph.currentKeyLen])
@@ -1907,13 +1970,16 @@ func (hph *HexPatriciaHashed) GenerateWitness(ctx context.Context, updates *Upda
 		var tr *trie.Trie
 		var computedRootHash []byte
 
-		fmt.Printf("\n%d/%d) plainKey [%x] hashedKey [%x] currentKey [%x]\n", ki+1, updatesCount, plainKey, hashedKey, hph.currentKey[:hph.currentKeyLen])
+		if hph.trace {
+			fmt.Printf("\n%d/%d) plainKey [%x] hashedKey [%x] currentKey [%x]\n", ki+1, updatesCount, plainKey, hashedKey, hph.currentKey[:hph.currentKeyLen])
+		}
 
 		if len(plainKey) == 20 { // account
 			account, err := hph.ctx.Account(plainKey)
 			if err != nil {
 				return fmt.Errorf("account with plainkey=%x not found: %w", plainKey, err)
-			} else {
+			}
+			if hph.trace {
 				addrHash := ecrypto.Keccak256(plainKey)
 				fmt.Printf("account with plainKey=%x, addrHash=%x FOUND = %v\n", plainKey, addrHash, account)
 			}
@@ -1922,7 +1988,9 @@ func (hph *HexPatriciaHashed) GenerateWitness(ctx context.Context, updates *Upda
 			if err != nil {
 				return fmt.Errorf("storage with plainkey=%x not found: %w", plainKey, err)
 			}
-			fmt.Printf("storage found = %v\n", storage.Storage)
+			if hph.trace {
+				fmt.Printf("storage found = %v\n", storage.Storage)
+			}
 		}
 
 		// Keep folding until the currentKey is the prefix of the key we modify
@@ -1937,7 +2005,9 @@ func (hph *HexPatriciaHashed) GenerateWitness(ctx context.Context, updates *Upda
 				return fmt.Errorf("unfold: %w", err)
 			}
 		}
-		hph.PrintGrid()
+		if hph.trace {
+			hph.PrintGrid()
+		}
 
 		// convert grid to trie.Trie
 		tr, err = hph.ToTrie(hashedKey, codeReads) // build witness trie for this key, based on the current state of the grid
@@ -1945,7 +2015,9 @@ func (hph *HexPatriciaHashed) GenerateWitness(ctx context.Context, updates *Upda
 			return err
 		}
 		computedRootHash = tr.Root()
-		fmt.Printf("computedRootHash = %x\n", computedRootHash)
+		if hph.trace {
+			fmt.Printf("computedRootHash = %x\n", computedRootHash)
+		}
 
 		if !bytes.Equal(computedRootHash, expectedRootHash) {
 			err = fmt.Errorf("root hash mismatch computedRootHash(%x)!=expectedRootHash(%x)", computedRootHash, expectedRootHash)
@@ -1984,7 +2056,9 @@ func (hph *HexPatriciaHashed) GenerateWitness(ctx context.Context, updates *Upda
 
 	witnessTrieRootHash := witnessTrie.Root()
 
-	fmt.Printf("mergedTrieRootHash = %x\n", witnessTrieRootHash)
+	if hph.trace {
+		fmt.Printf("mergedTrieRootHash = %x\n", witnessTrieRootHash)
+	}
 
 	if !bytes.Equal(witnessTrieRootHash, expectedRootHash) {
 		return nil, nil, fmt.Errorf("root hash mismatch witnessTrieRootHash(%x)!=expectedRootHash(%x)", witnessTrieRootHash, expectedRootHash)
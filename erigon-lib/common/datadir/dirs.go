@@ -50,6 +50,7 @@ type Dirs struct {
 	CaplinIndexing  string
 	CaplinLatest    string
 	CaplinGenesis   string
+	CaplinValidator string
 }
 
 func New(datadir string) Dirs {
@@ -81,6 +82,7 @@ func New(datadir string) Dirs {
 		CaplinIndexing:  filepath.Join(datadir, "caplin", "indexing"),
 		CaplinLatest:    filepath.Join(datadir, "caplin", "latest"),
 		CaplinGenesis:   filepath.Join(datadir, "caplin", "genesis"),
+		CaplinValidator: filepath.Join(datadir, "caplin", "validator"),
 	}
 
 	dir.MustExist(dirs.Chaindata, dirs.Tmp,
@@ -17,6 +17,7 @@
 package metrics
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/erigontech/erigon-lib/log/v3"
@@ -78,3 +79,10 @@ func UpdateBlockProducerProductionDelay(parentBlockTime uint64, producedBlockNum
 		log.Info("[producer-delay] Production", "blockNumber", producedBlockNum, "delay", time.Since(t))
 	}
 }
+
+func UpdateBlockProducerTxnOrdering(policy string, start time.Time, candidates int, yielded int, droppedBundles int) {
+	metrics.GetOrCreateSummary(fmt.Sprintf(`block_producer_txn_ordering{policy="%s"}`, policy)).ObserveDuration(start)
+	metrics.GetOrCreateCounter(fmt.Sprintf(`block_producer_txn_candidates{policy="%s"}`, policy)).AddInt(candidates)
+	metrics.GetOrCreateCounter(fmt.Sprintf(`block_producer_txns_yielded{policy="%s"}`, policy)).AddInt(yielded)
+	metrics.GetOrCreateCounter(fmt.Sprintf(`block_producer_bundles_dropped{policy="%s"}`, policy)).AddInt(droppedBundles)
+}
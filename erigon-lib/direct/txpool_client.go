@@ -119,3 +119,75 @@ func (s *TxPoolClient) Status(ctx context.Context, in *txpool_proto.StatusReques
 func (s *TxPoolClient) Nonce(ctx context.Context, in *txpool_proto.NonceRequest, opts ...grpc.CallOption) (*txpool_proto.NonceReply, error) {
 	return s.server.Nonce(ctx, in)
 }
+
+func (s *TxPoolClient) GetBlobs(ctx context.Context, in *txpool_proto.GetBlobsRequest, opts ...grpc.CallOption) (*txpool_proto.GetBlobsReply, error) {
+	return s.server.GetBlobs(ctx, in)
+}
+
+func (s *TxPoolClient) SendBundle(ctx context.Context, in *txpool_proto.SendBundleRequest, opts ...grpc.CallOption) (*txpool_proto.SendBundleReply, error) {
+	return s.server.SendBundle(ctx, in)
+}
+
+func (s *TxPoolClient) CancelBundle(ctx context.Context, in *txpool_proto.CancelBundleRequest, opts ...grpc.CallOption) (*txpool_proto.CancelBundleReply, error) {
+	return s.server.CancelBundle(ctx, in)
+}
+
+func (s *TxPoolClient) AddConditional(ctx context.Context, in *txpool_proto.AddConditionalRequest, opts ...grpc.CallOption) (*txpool_proto.AddReply, error) {
+	return s.server.AddConditional(ctx, in)
+}
+
+// -- start OnTxnEvent
+
+func (s *TxPoolClient) OnTxnEvent(ctx context.Context, in *txpool_proto.OnTxnEventRequest, opts ...grpc.CallOption) (txpool_proto.Txpool_OnTxnEventClient, error) {
+	ch := make(chan *onTxnEventReply, 16384)
+	streamServer := &TxPoolOnTxnEventS{ch: ch, ctx: ctx}
+	go func() {
+		defer close(ch)
+		streamServer.Err(s.server.OnTxnEvent(in, streamServer))
+	}()
+	return &TxPoolOnTxnEventC{ch: ch, ctx: ctx}, nil
+}
+
+type onTxnEventReply struct {
+	r   *txpool_proto.TxnEvent
+	err error
+}
+
+type TxPoolOnTxnEventS struct {
+	ch  chan *onTxnEventReply
+	ctx context.Context
+	grpc.ServerStream
+}
+
+func (s *TxPoolOnTxnEventS) Send(m *txpool_proto.TxnEvent) error {
+	s.ch <- &onTxnEventReply{r: m}
+	return nil
+}
+func (s *TxPoolOnTxnEventS) Context() context.Context { return s.ctx }
+func (s *TxPoolOnTxnEventS) Err(err error) {
+	if err == nil {
+		return
+	}
+	s.ch <- &onTxnEventReply{err: err}
+}
+
+type TxPoolOnTxnEventC struct {
+	ch  chan *onTxnEventReply
+	ctx context.Context
+	grpc.ClientStream
+}
+
+func (c *TxPoolOnTxnEventC) Recv() (*txpool_proto.TxnEvent, error) {
+	m, ok := <-c.ch
+	if !ok || m == nil {
+		return nil, io.EOF
+	}
+	return m.r, m.err
+}
+func (c *TxPoolOnTxnEventC) Context() context.Context { return c.ctx }
+
+// -- end OnTxnEvent
+
+func (s *TxPoolClient) TransactionStatus(ctx context.Context, in *txpool_proto.TransactionStatusRequest, opts ...grpc.CallOption) (*txpool_proto.TransactionStatusReply, error) {
+	return s.server.TransactionStatus(ctx, in)
+}
@@ -2504,6 +2504,44 @@ func (d *Downloader) VerifyData(ctx context.Context, whiteList []string, failFas
 	return nil
 }
 
+// Redownload fetches again a file whose local copy was removed, e.g. because it was found corrupted. The file must
+// have its .torrent on disk: the torrent is dropped, its completion is reset and it's added back, so the main loop
+// downloads it again from webseeds or peers.
+func (d *Downloader) Redownload(ctx context.Context, name string) error {
+	ts, err := d.torrentFS.LoadByName(name)
+	if err != nil {
+		return fmt.Errorf("redownload: %w", err)
+	}
+	for _, t := range d.torrentClient.Torrents() {
+		if t.InfoHash() == ts.InfoHash {
+			t.Drop()
+			break
+		}
+	}
+	if err := d.db.Update(ctx, torrentInfoReset(name, ts.InfoHash.Bytes(), 0)); err != nil {
+		return fmt.Errorf("redownload: %s: reset failed: %w", name, err)
+	}
+	t, _, err := addTorrentFile(ctx, ts, d.torrentClient, d.db, d.webseeds)
+	if err != nil {
+		return fmt.Errorf("redownload: %w", err)
+	}
+
+	// the piece completion db still has the pieces of the removed file as complete
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.GotInfo():
+		}
+		for i := 0; i < t.NumPieces(); i++ {
+			t.Piece(i).VerifyData()
+		}
+	}()
+	return nil
+}
+
 // AddNewSeedableFile decides what we do depending on whether we have the .seg file or the .torrent file
 // have .torrent no .seg => get .seg file from .torrent
 // have .seg no .torrent => get .torrent from .seg
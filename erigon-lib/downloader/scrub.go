@@ -0,0 +1,335 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	"github.com/c2h5oh/datasize"
+	"golang.org/x/time/rate"
+
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/common/dir"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/recsplit"
+	"github.com/erigontech/erigon-lib/seg"
+)
+
+// QuarantineDirName is the sub-directory of the snapshots dir where corrupted files are moved to
+const QuarantineDirName = "quarantine"
+
+var (
+	// scrubDataExtensions are the files made of compressed words, readable by seg.Decompressor
+	scrubDataExtensions = []string{".seg", ".kv", ".v", ".ef"}
+	// scrubRecSplitExtensions are the accessors which are recsplit indices
+	scrubRecSplitExtensions = []string{".idx", ".kvi", ".vi", ".efi"}
+	// scrubOpaqueExtensions are the accessors only checked against their torrent piece hashes
+	scrubOpaqueExtensions = []string{".bt", ".kvei"}
+)
+
+var ErrScrubPieceMismatch = errors.New("piece hash mismatch")
+
+// RepairFunc fetches again a file which was quarantined, e.g. Downloader.Redownload
+type RepairFunc func(ctx context.Context, name string) error
+
+type ScrubCfg struct {
+	Dirs datadir.Dirs
+	// ReadRate limits the disk read throughput of the scrubber, 0 means unlimited
+	ReadRate datasize.ByteSize
+	// Quarantine moves the corrupted files which have a .torrent out of the way, and repairs them if a RepairFunc
+	// is set. Files without .torrent can't be fetched again and are only reported.
+	Quarantine bool
+}
+
+// ScrubResult is the outcome of scrubbing one file, Err is nil when the file is healthy
+type ScrubResult struct {
+	Name        string
+	Err         error
+	Quarantined bool
+	Repaired    bool
+}
+
+// Scrubber walks the snapshot files, checks them against their torrent piece hashes and reads them whole
+// (decompressing every word of segments, opening indices), to catch bit-rot before it crashes the node
+type Scrubber struct {
+	cfg     ScrubCfg
+	repair  RepairFunc
+	limiter *rate.Limiter
+	logger  log.Logger
+}
+
+const scrubReadChunk = 1 * datasize.MB
+
+func NewScrubber(cfg ScrubCfg, repair RepairFunc, logger log.Logger) *Scrubber {
+	s := &Scrubber{cfg: cfg, repair: repair, logger: logger}
+	if cfg.ReadRate > 0 {
+		s.limiter = rate.NewLimiter(rate.Limit(cfg.ReadRate.Bytes()), int(scrubReadChunk.Bytes()))
+	}
+	return s
+}
+
+// Files lists the files the scrubber checks, relative to the snapshots dir
+func (s *Scrubber) Files() ([]string, error) {
+	extensions := slices.Concat(scrubDataExtensions, scrubRecSplitExtensions, scrubOpaqueExtensions)
+	var res []string
+	for _, d := range []string{s.cfg.Dirs.Snap, s.cfg.Dirs.SnapCaplin, s.cfg.Dirs.SnapDomain, s.cfg.Dirs.SnapHistory, s.cfg.Dirs.SnapIdx, s.cfg.Dirs.SnapAccessors} {
+		exists, err := dir.Exist(d)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			continue
+		}
+		paths, err := dir.ListFiles(d, extensions...)
+		if err != nil {
+			return nil, err
+		}
+		for _, fPath := range paths {
+			name, err := filepath.Rel(s.cfg.Dirs.Snap, fPath)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, name)
+		}
+	}
+	return res, nil
+}
+
+// Scrub checks all the files (or those matching the white list, by exact name, prefix or suffix) once, and
+// returns the results of the corrupted ones
+func (s *Scrubber) Scrub(ctx context.Context, whiteList []string) ([]ScrubResult, error) {
+	files, err := s.Files()
+	if err != nil {
+		return nil, err
+	}
+	if len(whiteList) > 0 {
+		files = slices.DeleteFunc(files, func(name string) bool {
+			return !slices.ContainsFunc(whiteList, func(w string) bool {
+				return name == w || strings.HasPrefix(name, w) || strings.HasSuffix(name, w)
+			})
+		})
+	}
+
+	logEvery := time.NewTicker(20 * time.Second)
+	defer logEvery.Stop()
+	s.logger.Info("[snapshots] Scrub start", "files", len(files))
+
+	var bad []ScrubResult
+	for i, name := range files {
+		select {
+		case <-ctx.Done():
+			return bad, ctx.Err()
+		case <-logEvery.C:
+			s.logger.Info("[snapshots] Scrub", "files", fmt.Sprintf("%d/%d", i, len(files)), "corrupted", len(bad))
+		default:
+		}
+
+		res := ScrubResult{Name: name, Err: s.ScrubFile(ctx, name)}
+		if res.Err == nil {
+			continue
+		}
+		if errors.Is(res.Err, context.Canceled) || errors.Is(res.Err, context.DeadlineExceeded) {
+			return bad, res.Err
+		}
+		s.logger.Warn("[snapshots] Scrub: corrupted file", "file", name, "err", res.Err)
+		if s.cfg.Quarantine {
+			s.quarantine(ctx, &res)
+		}
+		bad = append(bad, res)
+	}
+	s.logger.Info("[snapshots] Scrub done", "files", len(files), "corrupted", len(bad))
+	return bad, nil
+}
+
+// Run scrubs all the files every interval, until the context is cancelled
+func (s *Scrubber) Run(ctx context.Context, interval time.Duration) {
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		bad, err := s.Scrub(ctx, nil)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Warn("[snapshots] Scrub", "err", err)
+		}
+		if len(bad) > 0 && !s.cfg.Quarantine {
+			s.logger.Warn("[snapshots] Scrub: corrupted files are only reported, stop the node and run `snapshots scrub --repair` to fetch them again", "corrupted", len(bad))
+		}
+		timer.Reset(interval)
+	}
+}
+
+// ScrubFile checks one file, given by its path relative to the snapshots dir
+func (s *Scrubber) ScrubFile(ctx context.Context, name string) (err error) {
+	fPath := filepath.Join(s.cfg.Dirs.Snap, name)
+	if err := s.verifyPieces(ctx, fPath); err != nil {
+		return err
+	}
+
+	// corrupted files may make the readers go out of bounds
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	ext := filepath.Ext(name)
+	switch {
+	case slices.Contains(scrubDataExtensions, ext):
+		return s.readWords(ctx, fPath)
+	case slices.Contains(scrubRecSplitExtensions, ext):
+		idx, err := recsplit.OpenIndex(fPath)
+		if err != nil {
+			return err
+		}
+		idx.Close()
+	}
+	return nil
+}
+
+// verifyPieces checks the file against the piece hashes of its .torrent, if it has one
+func (s *Scrubber) verifyPieces(ctx context.Context, fPath string) error {
+	exists, err := dir.FileExist(fPath + ".torrent")
+	if err != nil || !exists {
+		return err
+	}
+	mi, err := metainfo.LoadFromFile(fPath + ".torrent")
+	if err != nil {
+		return fmt.Errorf("load torrent: %w", err)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return fmt.Errorf("unmarshal torrent info: %w", err)
+	}
+
+	f, err := os.Open(fPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if st.Size() != info.TotalLength() {
+		return fmt.Errorf("%w: size %d, expected %d", ErrScrubPieceMismatch, st.Size(), info.TotalLength())
+	}
+
+	hasher := sha1.New()
+	for i := 0; i < info.NumPieces(); i++ {
+		p := info.Piece(i)
+		if err := s.throttle(ctx, p.Length()); err != nil {
+			return err
+		}
+		hasher.Reset()
+		if _, err := io.Copy(hasher, io.NewSectionReader(f, p.Offset(), p.Length())); err != nil {
+			return err
+		}
+		if !bytes.Equal(hasher.Sum(nil), p.Hash().Bytes()) {
+			return fmt.Errorf("%w: piece %d", ErrScrubPieceMismatch, i)
+		}
+	}
+	return nil
+}
+
+// readWords decompresses every word of the file
+func (s *Scrubber) readWords(ctx context.Context, fPath string) error {
+	d, err := seg.NewDecompressor(fPath)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	var words int
+	var buf []byte
+	var offset, throttled uint64
+	g := d.MakeGetter()
+	for g.HasNext() {
+		buf, offset = g.Next(buf[:0])
+		words++
+		if offset-throttled >= scrubReadChunk.Bytes() {
+			if err := s.throttle(ctx, int64(offset-throttled)); err != nil {
+				return err
+			}
+			throttled = offset
+		}
+	}
+	if words != d.Count() {
+		return fmt.Errorf("read %d words, expected %d", words, d.Count())
+	}
+	return nil
+}
+
+func (s *Scrubber) throttle(ctx context.Context, n int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if s.limiter == nil {
+		return nil
+	}
+	for n > 0 {
+		chunk := min(n, int64(s.limiter.Burst()))
+		if err := s.limiter.WaitN(ctx, int(chunk)); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// quarantine moves a corrupted file to the quarantine dir, and fetches it again
+func (s *Scrubber) quarantine(ctx context.Context, res *ScrubResult) {
+	fPath := filepath.Join(s.cfg.Dirs.Snap, res.Name)
+	if exists, _ := dir.FileExist(fPath + ".torrent"); !exists {
+		s.logger.Warn("[snapshots] Scrub: no .torrent, leaving corrupted file in place", "file", res.Name)
+		return
+	}
+	qPath := filepath.Join(s.cfg.Dirs.Snap, QuarantineDirName, res.Name)
+	if err := os.MkdirAll(filepath.Dir(qPath), 0755); err != nil {
+		s.logger.Warn("[snapshots] Scrub: quarantine", "file", res.Name, "err", err)
+		return
+	}
+	if err := os.Rename(fPath, qPath); err != nil {
+		s.logger.Warn("[snapshots] Scrub: quarantine", "file", res.Name, "err", err)
+		return
+	}
+	res.Quarantined = true
+	s.logger.Info("[snapshots] Scrub: quarantined", "file", res.Name, "to", qPath)
+
+	if s.repair == nil {
+		return
+	}
+	if err := s.repair(ctx, res.Name); err != nil {
+		s.logger.Warn("[snapshots] Scrub: repair", "file", res.Name, "err", err)
+		return
+	}
+	res.Repaired = true
+	s.logger.Info("[snapshots] Scrub: scheduled download", "file", res.Name)
+}
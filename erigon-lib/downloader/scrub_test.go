@@ -0,0 +1,106 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/seg"
+)
+
+func createTestSegment(t *testing.T, dirs datadir.Dirs, name string) string {
+	t.Helper()
+	fPath := filepath.Join(dirs.Snap, name)
+	c, err := seg.NewCompressor(context.Background(), t.Name(), fPath, dirs.Tmp, seg.DefaultCfg, log.LvlDebug, log.New())
+	require.NoError(t, err)
+	defer c.Close()
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, c.AddWord([]byte(fmt.Sprintf("word-%d", i))))
+	}
+	require.NoError(t, c.Compress())
+	return fPath
+}
+
+func corruptFile(t *testing.T, fPath string, offset int64) {
+	t.Helper()
+	data, err := os.ReadFile(fPath)
+	require.NoError(t, err)
+	data[offset] ^= 0xff
+	require.NoError(t, os.WriteFile(fPath, data, 0644))
+}
+
+func TestScrubPieces(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	dirs := datadir.New(t.TempDir())
+
+	fPath := createTestSegment(t, dirs, "v1-000000-000500-headers.seg")
+	_, err := BuildTorrentIfNeed(ctx, "v1-000000-000500-headers.seg", dirs.Snap, NewAtomicTorrentFS(dirs.Snap))
+	require.NoError(err)
+
+	var repaired []string
+	repair := func(_ context.Context, name string) error {
+		repaired = append(repaired, name)
+		return nil
+	}
+	s := NewScrubber(ScrubCfg{Dirs: dirs, Quarantine: true}, repair, log.New())
+	bad, err := s.Scrub(ctx, nil)
+	require.NoError(err)
+	require.Empty(bad)
+
+	st, err := os.Stat(fPath)
+	require.NoError(err)
+	corruptFile(t, fPath, st.Size()-1)
+	bad, err = s.Scrub(ctx, nil)
+	require.NoError(err)
+	require.Len(bad, 1)
+	require.ErrorIs(bad[0].Err, ErrScrubPieceMismatch)
+	require.True(bad[0].Quarantined)
+	require.True(bad[0].Repaired)
+	require.Equal([]string{"v1-000000-000500-headers.seg"}, repaired)
+	require.FileExists(filepath.Join(dirs.Snap, QuarantineDirName, "v1-000000-000500-headers.seg"))
+	require.NoFileExists(fPath)
+}
+
+func TestScrubWords(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	dirs := datadir.New(t.TempDir())
+
+	fPath := createTestSegment(t, dirs, "v1-000000-000500-bodies.seg")
+	s := NewScrubber(ScrubCfg{Dirs: dirs, Quarantine: true, ReadRate: 1024 * 1024 * 1024}, nil, log.New())
+	require.NoError(s.ScrubFile(ctx, "v1-000000-000500-bodies.seg"))
+
+	// without .torrent the file can only be checked by reading it, and is left in place
+	st, err := os.Stat(fPath)
+	require.NoError(err)
+	require.NoError(os.Truncate(fPath, st.Size()-10))
+	bad, err := s.Scrub(ctx, []string{"bodies.seg"})
+	require.NoError(err)
+	require.Len(bad, 1)
+	require.Error(bad[0].Err)
+	require.False(bad[0].Quarantined)
+	require.FileExists(fPath)
+}
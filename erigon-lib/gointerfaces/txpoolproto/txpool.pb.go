@@ -80,6 +80,64 @@ func (ImportResult) EnumDescriptor() ([]byte, []int) {
 	return file_txpool_txpool_proto_rawDescGZIP(), []int{0}
 }
 
+type TxnEventType int32
+
+const (
+	TxnEventType_ADDED     TxnEventType = 0
+	TxnEventType_PROMOTED  TxnEventType = 1
+	TxnEventType_DEMOTED   TxnEventType = 2
+	TxnEventType_REPLACED  TxnEventType = 3
+	TxnEventType_DISCARDED TxnEventType = 4
+	TxnEventType_MINED     TxnEventType = 5
+)
+
+// Enum value maps for TxnEventType.
+var (
+	TxnEventType_name = map[int32]string{
+		0: "ADDED",
+		1: "PROMOTED",
+		2: "DEMOTED",
+		3: "REPLACED",
+		4: "DISCARDED",
+		5: "MINED",
+	}
+	TxnEventType_value = map[string]int32{
+		"ADDED":     0,
+		"PROMOTED":  1,
+		"DEMOTED":   2,
+		"REPLACED":  3,
+		"DISCARDED": 4,
+		"MINED":     5,
+	}
+)
+
+func (x TxnEventType) Enum() *TxnEventType {
+	p := new(TxnEventType)
+	*p = x
+	return p
+}
+
+func (x TxnEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TxnEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_txpool_txpool_proto_enumTypes[1].Descriptor()
+}
+
+func (TxnEventType) Type() protoreflect.EnumType {
+	return &file_txpool_txpool_proto_enumTypes[1]
+}
+
+func (x TxnEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TxnEventType.Descriptor instead.
+func (TxnEventType) EnumDescriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{1}
+}
+
 type AllReply_TxnType int32
 
 const (
@@ -113,11 +171,11 @@ func (x AllReply_TxnType) String() string {
 }
 
 func (AllReply_TxnType) Descriptor() protoreflect.EnumDescriptor {
-	return file_txpool_txpool_proto_enumTypes[1].Descriptor()
+	return file_txpool_txpool_proto_enumTypes[2].Descriptor()
 }
 
 func (AllReply_TxnType) Type() protoreflect.EnumType {
-	return &file_txpool_txpool_proto_enumTypes[1]
+	return &file_txpool_txpool_proto_enumTypes[2]
 }
 
 func (x AllReply_TxnType) Number() protoreflect.EnumNumber {
@@ -181,7 +239,10 @@ type AddRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	RlpTxs [][]byte `protobuf:"bytes,1,rep,name=rlp_txs,json=rlpTxs,proto3" json:"rlp_txs,omitempty"`
+	RlpTxs                [][]byte `protobuf:"bytes,1,rep,name=rlp_txs,json=rlpTxs,proto3" json:"rlp_txs,omitempty"`
+	Private               bool     `protobuf:"varint,2,opt,name=private,proto3" json:"private,omitempty"`                                                              // keep the txns for local block building only, never gossip them
+	PrivateMaxBlockNumber uint64   `protobuf:"varint,3,opt,name=private_max_block_number,json=privateMaxBlockNumber,proto3" json:"private_max_block_number,omitempty"` // private txns expire after this block, 0 - after the pool's private txn lifetime
+	PrivateFallback       bool     `protobuf:"varint,4,opt,name=private_fallback,json=privateFallback,proto3" json:"private_fallback,omitempty"`                       // gossip expired private txns instead of dropping them
 }
 
 func (x *AddRequest) Reset() {
@@ -223,6 +284,27 @@ func (x *AddRequest) GetRlpTxs() [][]byte {
 	return nil
 }
 
+func (x *AddRequest) GetPrivate() bool {
+	if x != nil {
+		return x.Private
+	}
+	return false
+}
+
+func (x *AddRequest) GetPrivateMaxBlockNumber() uint64 {
+	if x != nil {
+		return x.PrivateMaxBlockNumber
+	}
+	return 0
+}
+
+func (x *AddRequest) GetPrivateFallback() bool {
+	if x != nil {
+		return x.PrivateFallback
+	}
+	return false
+}
+
 type AddReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -792,18 +874,16 @@ func (x *NonceReply) GetNonce() uint64 {
 	return 0
 }
 
-type AllReply_Tx struct {
+type GetBlobsRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	TxnType AllReply_TxnType `protobuf:"varint,1,opt,name=txn_type,json=txnType,proto3,enum=txpool.AllReply_TxnType" json:"txn_type,omitempty"`
-	Sender  *typesproto.H160 `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
-	RlpTx   []byte           `protobuf:"bytes,3,opt,name=rlp_tx,json=rlpTx,proto3" json:"rlp_tx,omitempty"`
+	BlobHashes []*typesproto.H256 `protobuf:"bytes,1,rep,name=blob_hashes,json=blobHashes,proto3" json:"blob_hashes,omitempty"`
 }
 
-func (x *AllReply_Tx) Reset() {
-	*x = AllReply_Tx{}
+func (x *GetBlobsRequest) Reset() {
+	*x = GetBlobsRequest{}
 	if protoimpl.UnsafeEnabled {
 		mi := &file_txpool_txpool_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -811,13 +891,13 @@ func (x *AllReply_Tx) Reset() {
 	}
 }
 
-func (x *AllReply_Tx) String() string {
+func (x *GetBlobsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AllReply_Tx) ProtoMessage() {}
+func (*GetBlobsRequest) ProtoMessage() {}
 
-func (x *AllReply_Tx) ProtoReflect() protoreflect.Message {
+func (x *GetBlobsRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_txpool_txpool_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -829,59 +909,98 @@ func (x *AllReply_Tx) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AllReply_Tx.ProtoReflect.Descriptor instead.
-func (*AllReply_Tx) Descriptor() ([]byte, []int) {
-	return file_txpool_txpool_proto_rawDescGZIP(), []int{8, 0}
+// Deprecated: Use GetBlobsRequest.ProtoReflect.Descriptor instead.
+func (*GetBlobsRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{14}
 }
 
-func (x *AllReply_Tx) GetTxnType() AllReply_TxnType {
+func (x *GetBlobsRequest) GetBlobHashes() []*typesproto.H256 {
 	if x != nil {
-		return x.TxnType
+		return x.BlobHashes
 	}
-	return AllReply_PENDING
+	return nil
 }
 
-func (x *AllReply_Tx) GetSender() *typesproto.H160 {
+type BlobAndProofV1 struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Blob  []byte `protobuf:"bytes,1,opt,name=blob,proto3" json:"blob,omitempty"`
+	Proof []byte `protobuf:"bytes,2,opt,name=proof,proto3" json:"proof,omitempty"`
+}
+
+func (x *BlobAndProofV1) Reset() {
+	*x = BlobAndProofV1{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlobAndProofV1) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlobAndProofV1) ProtoMessage() {}
+
+func (x *BlobAndProofV1) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlobAndProofV1.ProtoReflect.Descriptor instead.
+func (*BlobAndProofV1) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *BlobAndProofV1) GetBlob() []byte {
 	if x != nil {
-		return x.Sender
+		return x.Blob
 	}
 	return nil
 }
 
-func (x *AllReply_Tx) GetRlpTx() []byte {
+func (x *BlobAndProofV1) GetProof() []byte {
 	if x != nil {
-		return x.RlpTx
+		return x.Proof
 	}
 	return nil
 }
 
-type PendingReply_Tx struct {
+type GetBlobsReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Sender  *typesproto.H160 `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
-	RlpTx   []byte           `protobuf:"bytes,2,opt,name=rlp_tx,json=rlpTx,proto3" json:"rlp_tx,omitempty"`
-	IsLocal bool             `protobuf:"varint,3,opt,name=is_local,json=isLocal,proto3" json:"is_local,omitempty"`
+	BlobsAndProofs []*BlobAndProofV1 `protobuf:"bytes,1,rep,name=blobs_and_proofs,json=blobsAndProofs,proto3" json:"blobs_and_proofs,omitempty"`
 }
 
-func (x *PendingReply_Tx) Reset() {
-	*x = PendingReply_Tx{}
+func (x *GetBlobsReply) Reset() {
+	*x = GetBlobsReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_txpool_txpool_proto_msgTypes[15]
+		mi := &file_txpool_txpool_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
 }
 
-func (x *PendingReply_Tx) String() string {
+func (x *GetBlobsReply) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*PendingReply_Tx) ProtoMessage() {}
+func (*GetBlobsReply) ProtoMessage() {}
 
-func (x *PendingReply_Tx) ProtoReflect() protoreflect.Message {
-	mi := &file_txpool_txpool_proto_msgTypes[15]
+func (x *GetBlobsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -892,262 +1011,1303 @@ func (x *PendingReply_Tx) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use PendingReply_Tx.ProtoReflect.Descriptor instead.
-func (*PendingReply_Tx) Descriptor() ([]byte, []int) {
-	return file_txpool_txpool_proto_rawDescGZIP(), []int{9, 0}
+// Deprecated: Use GetBlobsReply.ProtoReflect.Descriptor instead.
+func (*GetBlobsReply) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{16}
 }
 
-func (x *PendingReply_Tx) GetSender() *typesproto.H160 {
+func (x *GetBlobsReply) GetBlobsAndProofs() []*BlobAndProofV1 {
 	if x != nil {
-		return x.Sender
+		return x.BlobsAndProofs
 	}
 	return nil
 }
 
-func (x *PendingReply_Tx) GetRlpTx() []byte {
+type SendBundleRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RlpTxs            [][]byte           `protobuf:"bytes,1,rep,name=rlp_txs,json=rlpTxs,proto3" json:"rlp_txs,omitempty"`
+	BlockNumber       uint64             `protobuf:"varint,2,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	MinTimestamp      uint64             `protobuf:"varint,3,opt,name=min_timestamp,json=minTimestamp,proto3" json:"min_timestamp,omitempty"`
+	MaxTimestamp      uint64             `protobuf:"varint,4,opt,name=max_timestamp,json=maxTimestamp,proto3" json:"max_timestamp,omitempty"`
+	RevertingTxHashes []*typesproto.H256 `protobuf:"bytes,5,rep,name=reverting_tx_hashes,json=revertingTxHashes,proto3" json:"reverting_tx_hashes,omitempty"`
+	ReplacementUuid   string             `protobuf:"bytes,6,opt,name=replacement_uuid,json=replacementUuid,proto3" json:"replacement_uuid,omitempty"`
+}
+
+func (x *SendBundleRequest) Reset() {
+	*x = SendBundleRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[17]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SendBundleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendBundleRequest) ProtoMessage() {}
+
+func (x *SendBundleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[17]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendBundleRequest.ProtoReflect.Descriptor instead.
+func (*SendBundleRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SendBundleRequest) GetRlpTxs() [][]byte {
 	if x != nil {
-		return x.RlpTx
+		return x.RlpTxs
 	}
 	return nil
 }
 
-func (x *PendingReply_Tx) GetIsLocal() bool {
+func (x *SendBundleRequest) GetBlockNumber() uint64 {
 	if x != nil {
-		return x.IsLocal
+		return x.BlockNumber
 	}
-	return false
+	return 0
 }
 
-var File_txpool_txpool_proto protoreflect.FileDescriptor
-
-var file_txpool_txpool_proto_rawDesc = []byte{
-	0x0a, 0x13, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2f, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x1a, 0x1b, 0x67,
-	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65,
-	0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x11, 0x74, 0x79, 0x70, 0x65,
-	0x73, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x2f, 0x0a,
-	0x08, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x06, 0x68, 0x61, 0x73,
-	0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65,
-	0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x25,
-	0x0a, 0x0a, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07,
-	0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x72,
-	0x6c, 0x70, 0x54, 0x78, 0x73, 0x22, 0x54, 0x0a, 0x08, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x12, 0x30, 0x0a, 0x08, 0x69, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20,
-	0x03, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x49, 0x6d, 0x70,
-	0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x08, 0x69, 0x6d, 0x70, 0x6f, 0x72,
-	0x74, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x02, 0x20,
-	0x03, 0x28, 0x09, 0x52, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x22, 0x3a, 0x0a, 0x13, 0x54,
-	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x12, 0x23, 0x0a, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52,
-	0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x2c, 0x0a, 0x11, 0x54, 0x72, 0x61, 0x6e, 0x73,
-	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x17, 0x0a, 0x07,
-	0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x72,
-	0x6c, 0x70, 0x54, 0x78, 0x73, 0x22, 0x0e, 0x0a, 0x0c, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x25, 0x0a, 0x0a, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x70, 0x6c, 0x5f, 0x74, 0x78, 0x73, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x70, 0x6c, 0x54, 0x78, 0x73, 0x22, 0x0c, 0x0a, 0x0a,
-	0x41, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xda, 0x01, 0x0a, 0x08, 0x41,
-	0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x25, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x01,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c,
-	0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x52, 0x03, 0x74, 0x78, 0x73, 0x1a, 0x75,
-	0x0a, 0x02, 0x54, 0x78, 0x12, 0x33, 0x0a, 0x08, 0x74, 0x78, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
-	0x41, 0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x6e, 0x54, 0x79, 0x70, 0x65,
-	0x52, 0x07, 0x74, 0x78, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12, 0x23, 0x0a, 0x06, 0x73, 0x65, 0x6e,
-	0x64, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65,
-	0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x15,
-	0x0a, 0x06, 0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05,
-	0x72, 0x6c, 0x70, 0x54, 0x78, 0x22, 0x30, 0x0a, 0x07, 0x54, 0x78, 0x6e, 0x54, 0x79, 0x70, 0x65,
-	0x12, 0x0b, 0x0a, 0x07, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0a, 0x0a,
-	0x06, 0x51, 0x55, 0x45, 0x55, 0x45, 0x44, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x42, 0x41, 0x53,
-	0x45, 0x5f, 0x46, 0x45, 0x45, 0x10, 0x02, 0x22, 0x96, 0x01, 0x0a, 0x0c, 0x50, 0x65, 0x6e, 0x64,
-	0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x29, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x50,
-	0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x52, 0x03,
-	0x74, 0x78, 0x73, 0x1a, 0x5b, 0x0a, 0x02, 0x54, 0x78, 0x12, 0x23, 0x0a, 0x06, 0x73, 0x65, 0x6e,
-	0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65,
-	0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x15,
-	0x0a, 0x06, 0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05,
-	0x72, 0x6c, 0x70, 0x54, 0x78, 0x12, 0x19, 0x0a, 0x08, 0x69, 0x73, 0x5f, 0x6c, 0x6f, 0x63, 0x61,
-	0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x69, 0x73, 0x4c, 0x6f, 0x63, 0x61, 0x6c,
-	0x22, 0x0f, 0x0a, 0x0d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x22, 0x7b, 0x0a, 0x0b, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79,
-	0x12, 0x23, 0x0a, 0x0d, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x5f, 0x63, 0x6f, 0x75, 0x6e,
-	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67,
-	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x71, 0x75, 0x65, 0x75, 0x65, 0x64, 0x5f,
-	0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x71, 0x75, 0x65,
-	0x75, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x24, 0x0a, 0x0e, 0x62, 0x61, 0x73, 0x65,
-	0x5f, 0x66, 0x65, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d,
-	0x52, 0x0c, 0x62, 0x61, 0x73, 0x65, 0x46, 0x65, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x35,
-	0x0a, 0x0c, 0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x25,
-	0x0a, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x07, 0x61, 0x64,
-	0x64, 0x72, 0x65, 0x73, 0x73, 0x22, 0x38, 0x0a, 0x0a, 0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x08, 0x52, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e,
-	0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x2a,
-	0x6c, 0x0a, 0x0c, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
-	0x0b, 0x0a, 0x07, 0x53, 0x55, 0x43, 0x43, 0x45, 0x53, 0x53, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e,
-	0x41, 0x4c, 0x52, 0x45, 0x41, 0x44, 0x59, 0x5f, 0x45, 0x58, 0x49, 0x53, 0x54, 0x53, 0x10, 0x01,
-	0x12, 0x0f, 0x0a, 0x0b, 0x46, 0x45, 0x45, 0x5f, 0x54, 0x4f, 0x4f, 0x5f, 0x4c, 0x4f, 0x57, 0x10,
-	0x02, 0x12, 0x09, 0x0a, 0x05, 0x53, 0x54, 0x41, 0x4c, 0x45, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07,
-	0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10, 0x04, 0x12, 0x12, 0x0a, 0x0e, 0x49, 0x4e, 0x54,
-	0x45, 0x52, 0x4e, 0x41, 0x4c, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x05, 0x32, 0xec, 0x03,
-	0x0a, 0x06, 0x54, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x36, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73,
-	0x69, 0x6f, 0x6e, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f,
-	0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x13, 0x2e, 0x74, 0x79,
-	0x70, 0x65, 0x73, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79,
-	0x12, 0x31, 0x0a, 0x0b, 0x46, 0x69, 0x6e, 0x64, 0x55, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x12,
-	0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x65,
-	0x73, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x48, 0x61, 0x73,
-	0x68, 0x65, 0x73, 0x12, 0x2b, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x12, 0x12, 0x2e, 0x74, 0x78, 0x70,
-	0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10,
-	0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c, 0x79,
-	0x12, 0x46, 0x0a, 0x0c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73,
-	0x12, 0x1b, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
-	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e,
-	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2b, 0x0a, 0x03, 0x41, 0x6c, 0x6c, 0x12,
-	0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c,
-	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x37, 0x0a, 0x07, 0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67,
-	0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
-	0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f,
-	0x6c, 0x2e, 0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x33,
-	0x0a, 0x05, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x12, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c,
-	0x2e, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e,
-	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x30, 0x01, 0x12, 0x34, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x15, 0x2e,
-	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x13, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x53, 0x74,
-	0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x31, 0x0a, 0x05, 0x4e, 0x6f, 0x6e,
-	0x63, 0x65, 0x12, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4e, 0x6f, 0x6e, 0x63,
-	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f,
-	0x6c, 0x2e, 0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x16, 0x5a, 0x14,
-	0x2e, 0x2f, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x3b, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+func (x *SendBundleRequest) GetMinTimestamp() uint64 {
+	if x != nil {
+		return x.MinTimestamp
+	}
+	return 0
 }
 
-var (
-	file_txpool_txpool_proto_rawDescOnce sync.Once
-	file_txpool_txpool_proto_rawDescData = file_txpool_txpool_proto_rawDesc
-)
+func (x *SendBundleRequest) GetMaxTimestamp() uint64 {
+	if x != nil {
+		return x.MaxTimestamp
+	}
+	return 0
+}
 
-func file_txpool_txpool_proto_rawDescGZIP() []byte {
-	file_txpool_txpool_proto_rawDescOnce.Do(func() {
-		file_txpool_txpool_proto_rawDescData = protoimpl.X.CompressGZIP(file_txpool_txpool_proto_rawDescData)
-	})
-	return file_txpool_txpool_proto_rawDescData
+func (x *SendBundleRequest) GetRevertingTxHashes() []*typesproto.H256 {
+	if x != nil {
+		return x.RevertingTxHashes
+	}
+	return nil
 }
 
-var file_txpool_txpool_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_txpool_txpool_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
-var file_txpool_txpool_proto_goTypes = []any{
-	(ImportResult)(0),               // 0: txpool.ImportResult
-	(AllReply_TxnType)(0),           // 1: txpool.AllReply.TxnType
-	(*TxHashes)(nil),                // 2: txpool.TxHashes
-	(*AddRequest)(nil),              // 3: txpool.AddRequest
-	(*AddReply)(nil),                // 4: txpool.AddReply
-	(*TransactionsRequest)(nil),     // 5: txpool.TransactionsRequest
-	(*TransactionsReply)(nil),       // 6: txpool.TransactionsReply
-	(*OnAddRequest)(nil),            // 7: txpool.OnAddRequest
-	(*OnAddReply)(nil),              // 8: txpool.OnAddReply
-	(*AllRequest)(nil),              // 9: txpool.AllRequest
-	(*AllReply)(nil),                // 10: txpool.AllReply
-	(*PendingReply)(nil),            // 11: txpool.PendingReply
-	(*StatusRequest)(nil),           // 12: txpool.StatusRequest
-	(*StatusReply)(nil),             // 13: txpool.StatusReply
-	(*NonceRequest)(nil),            // 14: txpool.NonceRequest
-	(*NonceReply)(nil),              // 15: txpool.NonceReply
-	(*AllReply_Tx)(nil),             // 16: txpool.AllReply.Tx
-	(*PendingReply_Tx)(nil),         // 17: txpool.PendingReply.Tx
-	(*typesproto.H256)(nil),         // 18: types.H256
-	(*typesproto.H160)(nil),         // 19: types.H160
-	(*emptypb.Empty)(nil),           // 20: google.protobuf.Empty
-	(*typesproto.VersionReply)(nil), // 21: types.VersionReply
+func (x *SendBundleRequest) GetReplacementUuid() string {
+	if x != nil {
+		return x.ReplacementUuid
+	}
+	return ""
 }
-var file_txpool_txpool_proto_depIdxs = []int32{
-	18, // 0: txpool.TxHashes.hashes:type_name -> types.H256
-	0,  // 1: txpool.AddReply.imported:type_name -> txpool.ImportResult
-	18, // 2: txpool.TransactionsRequest.hashes:type_name -> types.H256
-	16, // 3: txpool.AllReply.txs:type_name -> txpool.AllReply.Tx
-	17, // 4: txpool.PendingReply.txs:type_name -> txpool.PendingReply.Tx
-	19, // 5: txpool.NonceRequest.address:type_name -> types.H160
-	1,  // 6: txpool.AllReply.Tx.txn_type:type_name -> txpool.AllReply.TxnType
-	19, // 7: txpool.AllReply.Tx.sender:type_name -> types.H160
-	19, // 8: txpool.PendingReply.Tx.sender:type_name -> types.H160
-	20, // 9: txpool.Txpool.Version:input_type -> google.protobuf.Empty
-	2,  // 10: txpool.Txpool.FindUnknown:input_type -> txpool.TxHashes
-	3,  // 11: txpool.Txpool.Add:input_type -> txpool.AddRequest
-	5,  // 12: txpool.Txpool.Transactions:input_type -> txpool.TransactionsRequest
-	9,  // 13: txpool.Txpool.All:input_type -> txpool.AllRequest
-	20, // 14: txpool.Txpool.Pending:input_type -> google.protobuf.Empty
-	7,  // 15: txpool.Txpool.OnAdd:input_type -> txpool.OnAddRequest
-	12, // 16: txpool.Txpool.Status:input_type -> txpool.StatusRequest
-	14, // 17: txpool.Txpool.Nonce:input_type -> txpool.NonceRequest
-	21, // 18: txpool.Txpool.Version:output_type -> types.VersionReply
-	2,  // 19: txpool.Txpool.FindUnknown:output_type -> txpool.TxHashes
-	4,  // 20: txpool.Txpool.Add:output_type -> txpool.AddReply
-	6,  // 21: txpool.Txpool.Transactions:output_type -> txpool.TransactionsReply
-	10, // 22: txpool.Txpool.All:output_type -> txpool.AllReply
-	11, // 23: txpool.Txpool.Pending:output_type -> txpool.PendingReply
-	8,  // 24: txpool.Txpool.OnAdd:output_type -> txpool.OnAddReply
-	13, // 25: txpool.Txpool.Status:output_type -> txpool.StatusReply
-	15, // 26: txpool.Txpool.Nonce:output_type -> txpool.NonceReply
-	18, // [18:27] is the sub-list for method output_type
-	9,  // [9:18] is the sub-list for method input_type
-	9,  // [9:9] is the sub-list for extension type_name
-	9,  // [9:9] is the sub-list for extension extendee
-	0,  // [0:9] is the sub-list for field type_name
+
+type SendBundleReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BundleHash *typesproto.H256 `protobuf:"bytes,1,opt,name=bundle_hash,json=bundleHash,proto3" json:"bundle_hash,omitempty"`
 }
 
-func init() { file_txpool_txpool_proto_init() }
-func file_txpool_txpool_proto_init() {
-	if File_txpool_txpool_proto != nil {
-		return
+func (x *SendBundleReply) Reset() {
+	*x = SendBundleReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[18]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_txpool_txpool_proto_msgTypes[0].Exporter = func(v any, i int) any {
-			switch v := v.(*TxHashes); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_txpool_txpool_proto_msgTypes[1].Exporter = func(v any, i int) any {
-			switch v := v.(*AddRequest); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_txpool_txpool_proto_msgTypes[2].Exporter = func(v any, i int) any {
-			switch v := v.(*AddReply); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
+}
+
+func (x *SendBundleReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendBundleReply) ProtoMessage() {}
+
+func (x *SendBundleReply) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[18]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
 		}
-		file_txpool_txpool_proto_msgTypes[3].Exporter = func(v any, i int) any {
-			switch v := v.(*TransactionsRequest); i {
-			case 0:
-				return &v.state
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendBundleReply.ProtoReflect.Descriptor instead.
+func (*SendBundleReply) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SendBundleReply) GetBundleHash() *typesproto.H256 {
+	if x != nil {
+		return x.BundleHash
+	}
+	return nil
+}
+
+type CancelBundleRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	ReplacementUuid string `protobuf:"bytes,1,opt,name=replacement_uuid,json=replacementUuid,proto3" json:"replacement_uuid,omitempty"`
+}
+
+func (x *CancelBundleRequest) Reset() {
+	*x = CancelBundleRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[19]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelBundleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelBundleRequest) ProtoMessage() {}
+
+func (x *CancelBundleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[19]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelBundleRequest.ProtoReflect.Descriptor instead.
+func (*CancelBundleRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *CancelBundleRequest) GetReplacementUuid() string {
+	if x != nil {
+		return x.ReplacementUuid
+	}
+	return ""
+}
+
+type CancelBundleReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cancelled bool `protobuf:"varint,1,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+}
+
+func (x *CancelBundleReply) Reset() {
+	*x = CancelBundleReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[20]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancelBundleReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancelBundleReply) ProtoMessage() {}
+
+func (x *CancelBundleReply) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[20]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancelBundleReply.ProtoReflect.Descriptor instead.
+func (*CancelBundleReply) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CancelBundleReply) GetCancelled() bool {
+	if x != nil {
+		return x.Cancelled
+	}
+	return false
+}
+
+type StorageSlot struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Key   *typesproto.H256 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value *typesproto.H256 `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (x *StorageSlot) Reset() {
+	*x = StorageSlot{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[21]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StorageSlot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StorageSlot) ProtoMessage() {}
+
+func (x *StorageSlot) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[21]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StorageSlot.ProtoReflect.Descriptor instead.
+func (*StorageSlot) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *StorageSlot) GetKey() *typesproto.H256 {
+	if x != nil {
+		return x.Key
+	}
+	return nil
+}
+
+func (x *StorageSlot) GetValue() *typesproto.H256 {
+	if x != nil {
+		return x.Value
+	}
+	return nil
+}
+
+type KnownAccount struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Address     *typesproto.H160 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Slots       []*StorageSlot   `protobuf:"bytes,2,rep,name=slots,proto3" json:"slots,omitempty"`
+	StorageRoot *typesproto.H256 `protobuf:"bytes,3,opt,name=storage_root,json=storageRoot,proto3" json:"storage_root,omitempty"`
+}
+
+func (x *KnownAccount) Reset() {
+	*x = KnownAccount{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *KnownAccount) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KnownAccount) ProtoMessage() {}
+
+func (x *KnownAccount) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KnownAccount.ProtoReflect.Descriptor instead.
+func (*KnownAccount) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *KnownAccount) GetAddress() *typesproto.H160 {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *KnownAccount) GetSlots() []*StorageSlot {
+	if x != nil {
+		return x.Slots
+	}
+	return nil
+}
+
+func (x *KnownAccount) GetStorageRoot() *typesproto.H256 {
+	if x != nil {
+		return x.StorageRoot
+	}
+	return nil
+}
+
+// zero block number and timestamp bounds are unset
+type TransactionConditions struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	KnownAccounts  []*KnownAccount `protobuf:"bytes,1,rep,name=known_accounts,json=knownAccounts,proto3" json:"known_accounts,omitempty"`
+	BlockNumberMin uint64          `protobuf:"varint,2,opt,name=block_number_min,json=blockNumberMin,proto3" json:"block_number_min,omitempty"`
+	BlockNumberMax uint64          `protobuf:"varint,3,opt,name=block_number_max,json=blockNumberMax,proto3" json:"block_number_max,omitempty"`
+	TimestampMin   uint64          `protobuf:"varint,4,opt,name=timestamp_min,json=timestampMin,proto3" json:"timestamp_min,omitempty"`
+	TimestampMax   uint64          `protobuf:"varint,5,opt,name=timestamp_max,json=timestampMax,proto3" json:"timestamp_max,omitempty"`
+	// block the storage roots of known_accounts were checked against
+	StorageRootsBlock uint64 `protobuf:"varint,6,opt,name=storage_roots_block,json=storageRootsBlock,proto3" json:"storage_roots_block,omitempty"`
+}
+
+func (x *TransactionConditions) Reset() {
+	*x = TransactionConditions{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransactionConditions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionConditions) ProtoMessage() {}
+
+func (x *TransactionConditions) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionConditions.ProtoReflect.Descriptor instead.
+func (*TransactionConditions) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *TransactionConditions) GetKnownAccounts() []*KnownAccount {
+	if x != nil {
+		return x.KnownAccounts
+	}
+	return nil
+}
+
+func (x *TransactionConditions) GetBlockNumberMin() uint64 {
+	if x != nil {
+		return x.BlockNumberMin
+	}
+	return 0
+}
+
+func (x *TransactionConditions) GetBlockNumberMax() uint64 {
+	if x != nil {
+		return x.BlockNumberMax
+	}
+	return 0
+}
+
+func (x *TransactionConditions) GetTimestampMin() uint64 {
+	if x != nil {
+		return x.TimestampMin
+	}
+	return 0
+}
+
+func (x *TransactionConditions) GetTimestampMax() uint64 {
+	if x != nil {
+		return x.TimestampMax
+	}
+	return 0
+}
+
+func (x *TransactionConditions) GetStorageRootsBlock() uint64 {
+	if x != nil {
+		return x.StorageRootsBlock
+	}
+	return 0
+}
+
+type AddConditionalRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RlpTx      []byte                 `protobuf:"bytes,1,opt,name=rlp_tx,json=rlpTx,proto3" json:"rlp_tx,omitempty"`
+	Conditions *TransactionConditions `protobuf:"bytes,2,opt,name=conditions,proto3" json:"conditions,omitempty"`
+}
+
+func (x *AddConditionalRequest) Reset() {
+	*x = AddConditionalRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AddConditionalRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddConditionalRequest) ProtoMessage() {}
+
+func (x *AddConditionalRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddConditionalRequest.ProtoReflect.Descriptor instead.
+func (*AddConditionalRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *AddConditionalRequest) GetRlpTx() []byte {
+	if x != nil {
+		return x.RlpTx
+	}
+	return nil
+}
+
+func (x *AddConditionalRequest) GetConditions() *TransactionConditions {
+	if x != nil {
+		return x.Conditions
+	}
+	return nil
+}
+
+type TxnEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type          TxnEventType     `protobuf:"varint,1,opt,name=type,proto3,enum=txpool.TxnEventType" json:"type,omitempty"`
+	Hash          *typesproto.H256 `protobuf:"bytes,2,opt,name=hash,proto3" json:"hash,omitempty"`
+	SubPool       AllReply_TxnType `protobuf:"varint,3,opt,name=sub_pool,json=subPool,proto3,enum=txpool.AllReply_TxnType" json:"sub_pool,omitempty"`
+	ReplacedBy    *typesproto.H256 `protobuf:"bytes,4,opt,name=replaced_by,json=replacedBy,proto3" json:"replaced_by,omitempty"`
+	DiscardReason uint32           `protobuf:"varint,5,opt,name=discard_reason,json=discardReason,proto3" json:"discard_reason,omitempty"`
+	BlockNumber   uint64           `protobuf:"varint,6,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	Timestamp     uint64           `protobuf:"varint,7,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+}
+
+func (x *TxnEvent) Reset() {
+	*x = TxnEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TxnEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxnEvent) ProtoMessage() {}
+
+func (x *TxnEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxnEvent.ProtoReflect.Descriptor instead.
+func (*TxnEvent) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *TxnEvent) GetType() TxnEventType {
+	if x != nil {
+		return x.Type
+	}
+	return TxnEventType_ADDED
+}
+
+func (x *TxnEvent) GetHash() *typesproto.H256 {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *TxnEvent) GetSubPool() AllReply_TxnType {
+	if x != nil {
+		return x.SubPool
+	}
+	return AllReply_PENDING
+}
+
+func (x *TxnEvent) GetReplacedBy() *typesproto.H256 {
+	if x != nil {
+		return x.ReplacedBy
+	}
+	return nil
+}
+
+func (x *TxnEvent) GetDiscardReason() uint32 {
+	if x != nil {
+		return x.DiscardReason
+	}
+	return 0
+}
+
+func (x *TxnEvent) GetBlockNumber() uint64 {
+	if x != nil {
+		return x.BlockNumber
+	}
+	return 0
+}
+
+func (x *TxnEvent) GetTimestamp() uint64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+type OnTxnEventRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+}
+
+func (x *OnTxnEventRequest) Reset() {
+	*x = OnTxnEventRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OnTxnEventRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OnTxnEventRequest) ProtoMessage() {}
+
+func (x *OnTxnEventRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OnTxnEventRequest.ProtoReflect.Descriptor instead.
+func (*OnTxnEventRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{26}
+}
+
+type TransactionStatusRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash *typesproto.H256 `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+}
+
+func (x *TransactionStatusRequest) Reset() {
+	*x = TransactionStatusRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransactionStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionStatusRequest) ProtoMessage() {}
+
+func (x *TransactionStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionStatusRequest.ProtoReflect.Descriptor instead.
+func (*TransactionStatusRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *TransactionStatusRequest) GetHash() *typesproto.H256 {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+type TransactionStatusReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Known  bool        `protobuf:"varint,1,opt,name=known,proto3" json:"known,omitempty"`
+	Events []*TxnEvent `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+}
+
+func (x *TransactionStatusReply) Reset() {
+	*x = TransactionStatusReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TransactionStatusReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionStatusReply) ProtoMessage() {}
+
+func (x *TransactionStatusReply) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionStatusReply.ProtoReflect.Descriptor instead.
+func (*TransactionStatusReply) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *TransactionStatusReply) GetKnown() bool {
+	if x != nil {
+		return x.Known
+	}
+	return false
+}
+
+func (x *TransactionStatusReply) GetEvents() []*TxnEvent {
+	if x != nil {
+		return x.Events
+	}
+	return nil
+}
+
+type AllReply_Tx struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	TxnType   AllReply_TxnType `protobuf:"varint,1,opt,name=txn_type,json=txnType,proto3,enum=txpool.AllReply_TxnType" json:"txn_type,omitempty"`
+	Sender    *typesproto.H160 `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
+	RlpTx     []byte           `protobuf:"bytes,3,opt,name=rlp_tx,json=rlpTx,proto3" json:"rlp_tx,omitempty"`
+	IsPrivate bool             `protobuf:"varint,4,opt,name=is_private,json=isPrivate,proto3" json:"is_private,omitempty"`
+}
+
+func (x *AllReply_Tx) Reset() {
+	*x = AllReply_Tx{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AllReply_Tx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllReply_Tx) ProtoMessage() {}
+
+func (x *AllReply_Tx) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllReply_Tx.ProtoReflect.Descriptor instead.
+func (*AllReply_Tx) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{8, 0}
+}
+
+func (x *AllReply_Tx) GetTxnType() AllReply_TxnType {
+	if x != nil {
+		return x.TxnType
+	}
+	return AllReply_PENDING
+}
+
+func (x *AllReply_Tx) GetSender() *typesproto.H160 {
+	if x != nil {
+		return x.Sender
+	}
+	return nil
+}
+
+func (x *AllReply_Tx) GetRlpTx() []byte {
+	if x != nil {
+		return x.RlpTx
+	}
+	return nil
+}
+
+func (x *AllReply_Tx) GetIsPrivate() bool {
+	if x != nil {
+		return x.IsPrivate
+	}
+	return false
+}
+
+type PendingReply_Tx struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Sender  *typesproto.H160 `protobuf:"bytes,1,opt,name=sender,proto3" json:"sender,omitempty"`
+	RlpTx   []byte           `protobuf:"bytes,2,opt,name=rlp_tx,json=rlpTx,proto3" json:"rlp_tx,omitempty"`
+	IsLocal bool             `protobuf:"varint,3,opt,name=is_local,json=isLocal,proto3" json:"is_local,omitempty"`
+}
+
+func (x *PendingReply_Tx) Reset() {
+	*x = PendingReply_Tx{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PendingReply_Tx) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PendingReply_Tx) ProtoMessage() {}
+
+func (x *PendingReply_Tx) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PendingReply_Tx.ProtoReflect.Descriptor instead.
+func (*PendingReply_Tx) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{9, 0}
+}
+
+func (x *PendingReply_Tx) GetSender() *typesproto.H160 {
+	if x != nil {
+		return x.Sender
+	}
+	return nil
+}
+
+func (x *PendingReply_Tx) GetRlpTx() []byte {
+	if x != nil {
+		return x.RlpTx
+	}
+	return nil
+}
+
+func (x *PendingReply_Tx) GetIsLocal() bool {
+	if x != nil {
+		return x.IsLocal
+	}
+	return false
+}
+
+var File_txpool_txpool_proto protoreflect.FileDescriptor
+
+var file_txpool_txpool_proto_rawDesc = []byte{
+	0x0a, 0x13, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2f, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x06, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x1a, 0x1b, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65,
+	0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x11, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x2f, 0x0a,
+	0x08, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x23, 0x0a, 0x06, 0x68, 0x61, 0x73,
+	0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0xa3,
+	0x01, 0x0a, 0x0a, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a,
+	0x07, 0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06,
+	0x72, 0x6c, 0x70, 0x54, 0x78, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65,
+	0x12, 0x37, 0x0a, 0x18, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x5f, 0x6d, 0x61, 0x78, 0x5f,
+	0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x15, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x4d, 0x61, 0x78, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x29, 0x0a, 0x10, 0x70, 0x72, 0x69,
+	0x76, 0x61, 0x74, 0x65, 0x5f, 0x66, 0x61, 0x6c, 0x6c, 0x62, 0x61, 0x63, 0x6b, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x0f, 0x70, 0x72, 0x69, 0x76, 0x61, 0x74, 0x65, 0x46, 0x61, 0x6c, 0x6c,
+	0x62, 0x61, 0x63, 0x6b, 0x22, 0x54, 0x0a, 0x08, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c, 0x79,
+	0x12, 0x30, 0x0a, 0x08, 0x69, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x65, 0x64, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0e, 0x32, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x49, 0x6d, 0x70, 0x6f,
+	0x72, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x08, 0x69, 0x6d, 0x70, 0x6f, 0x72, 0x74,
+	0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x06, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x22, 0x3a, 0x0a, 0x13, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x23, 0x0a, 0x06, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x06,
+	0x68, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22, 0x2c, 0x0a, 0x11, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x17, 0x0a, 0x07, 0x72,
+	0x6c, 0x70, 0x5f, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x6c,
+	0x70, 0x54, 0x78, 0x73, 0x22, 0x0e, 0x0a, 0x0c, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x22, 0x25, 0x0a, 0x0a, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x70, 0x6c, 0x5f, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x70, 0x6c, 0x54, 0x78, 0x73, 0x22, 0x0c, 0x0a, 0x0a, 0x41,
+	0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xfa, 0x01, 0x0a, 0x08, 0x41, 0x6c,
+	0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x25, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x52, 0x03, 0x74, 0x78, 0x73, 0x1a, 0x94, 0x01,
+	0x0a, 0x02, 0x54, 0x78, 0x12, 0x33, 0x0a, 0x08, 0x74, 0x78, 0x6e, 0x5f, 0x74, 0x79, 0x70, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
+	0x41, 0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x6e, 0x54, 0x79, 0x70, 0x65,
+	0x52, 0x07, 0x74, 0x78, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12, 0x23, 0x0a, 0x06, 0x73, 0x65, 0x6e,
+	0x64, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x15,
+	0x0a, 0x06, 0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05,
+	0x72, 0x6c, 0x70, 0x54, 0x78, 0x12, 0x1d, 0x0a, 0x0a, 0x69, 0x73, 0x5f, 0x70, 0x72, 0x69, 0x76,
+	0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x69, 0x73, 0x50, 0x72, 0x69,
+	0x76, 0x61, 0x74, 0x65, 0x22, 0x30, 0x0a, 0x07, 0x54, 0x78, 0x6e, 0x54, 0x79, 0x70, 0x65, 0x12,
+	0x0b, 0x0a, 0x07, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06,
+	0x51, 0x55, 0x45, 0x55, 0x45, 0x44, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x42, 0x41, 0x53, 0x45,
+	0x5f, 0x46, 0x45, 0x45, 0x10, 0x02, 0x22, 0x96, 0x01, 0x0a, 0x0c, 0x50, 0x65, 0x6e, 0x64, 0x69,
+	0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x29, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x01,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x50, 0x65,
+	0x6e, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x52, 0x03, 0x74,
+	0x78, 0x73, 0x1a, 0x5b, 0x0a, 0x02, 0x54, 0x78, 0x12, 0x23, 0x0a, 0x06, 0x73, 0x65, 0x6e, 0x64,
+	0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73,
+	0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x15, 0x0a,
+	0x06, 0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x72,
+	0x6c, 0x70, 0x54, 0x78, 0x12, 0x19, 0x0a, 0x08, 0x69, 0x73, 0x5f, 0x6c, 0x6f, 0x63, 0x61, 0x6c,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x69, 0x73, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x22,
+	0x0f, 0x0a, 0x0d, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x22, 0x7b, 0x0a, 0x0b, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x23, 0x0a, 0x0d, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0c, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x43,
+	0x6f, 0x75, 0x6e, 0x74, 0x12, 0x21, 0x0a, 0x0c, 0x71, 0x75, 0x65, 0x75, 0x65, 0x64, 0x5f, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0b, 0x71, 0x75, 0x65, 0x75,
+	0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x24, 0x0a, 0x0e, 0x62, 0x61, 0x73, 0x65, 0x5f,
+	0x66, 0x65, 0x65, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0c, 0x62, 0x61, 0x73, 0x65, 0x46, 0x65, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x35, 0x0a,
+	0x0c, 0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x25, 0x0a,
+	0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b,
+	0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x07, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x22, 0x38, 0x0a, 0x0a, 0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x05, 0x66, 0x6f, 0x75, 0x6e, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22, 0x3f,
+	0x0a, 0x0f, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x62, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x2c, 0x0a, 0x0b, 0x62, 0x6c, 0x6f, 0x62, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48,
+	0x32, 0x35, 0x36, 0x52, 0x0a, 0x62, 0x6c, 0x6f, 0x62, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x22,
+	0x3a, 0x0a, 0x0e, 0x42, 0x6c, 0x6f, 0x62, 0x41, 0x6e, 0x64, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x56,
+	0x31, 0x12, 0x12, 0x0a, 0x04, 0x62, 0x6c, 0x6f, 0x62, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x04, 0x62, 0x6c, 0x6f, 0x62, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0x51, 0x0a, 0x0d, 0x47,
+	0x65, 0x74, 0x42, 0x6c, 0x6f, 0x62, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x40, 0x0a, 0x10,
+	0x62, 0x6c, 0x6f, 0x62, 0x73, 0x5f, 0x61, 0x6e, 0x64, 0x5f, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
+	0x42, 0x6c, 0x6f, 0x62, 0x41, 0x6e, 0x64, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x56, 0x31, 0x52, 0x0e,
+	0x62, 0x6c, 0x6f, 0x62, 0x73, 0x41, 0x6e, 0x64, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x73, 0x22, 0x81,
+	0x02, 0x0a, 0x11, 0x53, 0x65, 0x6e, 0x64, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x72, 0x6c, 0x70, 0x5f, 0x74, 0x78, 0x73, 0x18,
+	0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x06, 0x72, 0x6c, 0x70, 0x54, 0x78, 0x73, 0x12, 0x21, 0x0a,
+	0x0c, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x0b, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72,
+	0x12, 0x23, 0x0a, 0x0d, 0x6d, 0x69, 0x6e, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d,
+	0x70, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x6d, 0x69, 0x6e, 0x54, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x23, 0x0a, 0x0d, 0x6d, 0x61, 0x78, 0x5f, 0x74, 0x69, 0x6d,
+	0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x6d, 0x61,
+	0x78, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x12, 0x3b, 0x0a, 0x13, 0x72, 0x65,
+	0x76, 0x65, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x5f, 0x74, 0x78, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x65,
+	0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e,
+	0x48, 0x32, 0x35, 0x36, 0x52, 0x11, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x54,
+	0x78, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x29, 0x0a, 0x10, 0x72, 0x65, 0x70, 0x6c, 0x61,
+	0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x75, 0x75, 0x69, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0f, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x55, 0x75,
+	0x69, 0x64, 0x22, 0x3f, 0x0a, 0x0f, 0x53, 0x65, 0x6e, 0x64, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2c, 0x0a, 0x0b, 0x62, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x5f,
+	0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70,
+	0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x0a, 0x62, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x48,
+	0x61, 0x73, 0x68, 0x22, 0x40, 0x0a, 0x13, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x42, 0x75, 0x6e,
+	0x64, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x29, 0x0a, 0x10, 0x72, 0x65,
+	0x70, 0x6c, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x5f, 0x75, 0x75, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x6d, 0x65, 0x6e,
+	0x74, 0x55, 0x75, 0x69, 0x64, 0x22, 0x31, 0x0a, 0x11, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x42,
+	0x75, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x61,
+	0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x65, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x63,
+	0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x65, 0x64, 0x22, 0x4f, 0x0a, 0x0b, 0x53, 0x74, 0x6f, 0x72,
+	0x61, 0x67, 0x65, 0x53, 0x6c, 0x6f, 0x74, 0x12, 0x1d, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35,
+	0x36, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x21, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32,
+	0x35, 0x36, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x22, 0x90, 0x01, 0x0a, 0x0c, 0x4b, 0x6e,
+	0x6f, 0x77, 0x6e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x25, 0x0a, 0x07, 0x61, 0x64,
+	0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79,
+	0x70, 0x65, 0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73,
+	0x73, 0x12, 0x29, 0x0a, 0x05, 0x73, 0x6c, 0x6f, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x13, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67,
+	0x65, 0x53, 0x6c, 0x6f, 0x74, 0x52, 0x05, 0x73, 0x6c, 0x6f, 0x74, 0x73, 0x12, 0x2e, 0x0a, 0x0c,
+	0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x72, 0x6f, 0x6f, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52,
+	0x0b, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x52, 0x6f, 0x6f, 0x74, 0x22, 0xa2, 0x02, 0x0a,
+	0x15, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x64,
+	0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x3b, 0x0a, 0x0e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x5f,
+	0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4b, 0x6e, 0x6f, 0x77, 0x6e, 0x41, 0x63, 0x63,
+	0x6f, 0x75, 0x6e, 0x74, 0x52, 0x0d, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x41, 0x63, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x73, 0x12, 0x28, 0x0a, 0x10, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d,
+	0x62, 0x65, 0x72, 0x5f, 0x6d, 0x69, 0x6e, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x4e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x4d, 0x69, 0x6e, 0x12, 0x28, 0x0a,
+	0x10, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x5f, 0x6d, 0x61,
+	0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x4e, 0x75,
+	0x6d, 0x62, 0x65, 0x72, 0x4d, 0x61, 0x78, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x69, 0x6d, 0x65, 0x73,
+	0x74, 0x61, 0x6d, 0x70, 0x5f, 0x6d, 0x69, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c,
+	0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x4d, 0x69, 0x6e, 0x12, 0x23, 0x0a, 0x0d,
+	0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x5f, 0x6d, 0x61, 0x78, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x0c, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x4d, 0x61,
+	0x78, 0x12, 0x2e, 0x0a, 0x13, 0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x5f, 0x72, 0x6f, 0x6f,
+	0x74, 0x73, 0x5f, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11,
+	0x73, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x52, 0x6f, 0x6f, 0x74, 0x73, 0x42, 0x6c, 0x6f, 0x63,
+	0x6b, 0x22, 0x6d, 0x0a, 0x15, 0x41, 0x64, 0x64, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f,
+	0x6e, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x15, 0x0a, 0x06, 0x72, 0x6c,
+	0x70, 0x5f, 0x74, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x72, 0x6c, 0x70, 0x54,
+	0x78, 0x12, 0x3d, 0x0a, 0x0a, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x52, 0x0a, 0x63, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x73,
+	0x22, 0xa0, 0x02, 0x0a, 0x08, 0x54, 0x78, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x28, 0x0a,
+	0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14, 0x2e, 0x74, 0x78,
+	0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70,
+	0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1f, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32,
+	0x35, 0x36, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x12, 0x33, 0x0a, 0x08, 0x73, 0x75, 0x62, 0x5f,
+	0x70, 0x6f, 0x6f, 0x6c, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x18, 0x2e, 0x74, 0x78, 0x70,
+	0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x6e,
+	0x54, 0x79, 0x70, 0x65, 0x52, 0x07, 0x73, 0x75, 0x62, 0x50, 0x6f, 0x6f, 0x6c, 0x12, 0x2c, 0x0a,
+	0x0b, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x64, 0x5f, 0x62, 0x79, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52,
+	0x0a, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x64, 0x42, 0x79, 0x12, 0x25, 0x0a, 0x0e, 0x64,
+	0x69, 0x73, 0x63, 0x61, 0x72, 0x64, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x0d, 0x64, 0x69, 0x73, 0x63, 0x61, 0x72, 0x64, 0x52, 0x65, 0x61, 0x73,
+	0x6f, 0x6e, 0x12, 0x21, 0x0a, 0x0c, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x6e, 0x75, 0x6d, 0x62,
+	0x65, 0x72, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x4e,
+	0x75, 0x6d, 0x62, 0x65, 0x72, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61,
+	0x6d, 0x70, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x73, 0x74,
+	0x61, 0x6d, 0x70, 0x22, 0x13, 0x0a, 0x11, 0x4f, 0x6e, 0x54, 0x78, 0x6e, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0x3b, 0x0a, 0x18, 0x54, 0x72, 0x61, 0x6e,
+	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x1f, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52,
+	0x04, 0x68, 0x61, 0x73, 0x68, 0x22, 0x58, 0x0a, 0x16, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x14, 0x0a, 0x05, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05,
+	0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x12, 0x28, 0x0a, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54,
+	0x78, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x06, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x2a,
+	0x6c, 0x0a, 0x0c, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x0b, 0x0a, 0x07, 0x53, 0x55, 0x43, 0x43, 0x45, 0x53, 0x53, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e,
+	0x41, 0x4c, 0x52, 0x45, 0x41, 0x44, 0x59, 0x5f, 0x45, 0x58, 0x49, 0x53, 0x54, 0x53, 0x10, 0x01,
+	0x12, 0x0f, 0x0a, 0x0b, 0x46, 0x45, 0x45, 0x5f, 0x54, 0x4f, 0x4f, 0x5f, 0x4c, 0x4f, 0x57, 0x10,
+	0x02, 0x12, 0x09, 0x0a, 0x05, 0x53, 0x54, 0x41, 0x4c, 0x45, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07,
+	0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10, 0x04, 0x12, 0x12, 0x0a, 0x0e, 0x49, 0x4e, 0x54,
+	0x45, 0x52, 0x4e, 0x41, 0x4c, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x05, 0x2a, 0x5c, 0x0a,
+	0x0c, 0x54, 0x78, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x09, 0x0a,
+	0x05, 0x41, 0x44, 0x44, 0x45, 0x44, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x50, 0x52, 0x4f, 0x4d,
+	0x4f, 0x54, 0x45, 0x44, 0x10, 0x01, 0x12, 0x0b, 0x0a, 0x07, 0x44, 0x45, 0x4d, 0x4f, 0x54, 0x45,
+	0x44, 0x10, 0x02, 0x12, 0x0c, 0x0a, 0x08, 0x52, 0x45, 0x50, 0x4c, 0x41, 0x43, 0x45, 0x44, 0x10,
+	0x03, 0x12, 0x0d, 0x0a, 0x09, 0x44, 0x49, 0x53, 0x43, 0x41, 0x52, 0x44, 0x45, 0x44, 0x10, 0x04,
+	0x12, 0x09, 0x0a, 0x05, 0x4d, 0x49, 0x4e, 0x45, 0x44, 0x10, 0x05, 0x32, 0x89, 0x07, 0x0a, 0x06,
+	0x54, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x36, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x13, 0x2e, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x31,
+	0x0a, 0x0b, 0x46, 0x69, 0x6e, 0x64, 0x55, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x12, 0x10, 0x2e,
+	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x1a,
+	0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x65,
+	0x73, 0x12, 0x2b, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x12, 0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f,
+	0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x74,
+	0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x46,
+	0x0a, 0x0c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1b,
+	0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x74, 0x78,
+	0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2b, 0x0a, 0x03, 0x41, 0x6c, 0x6c, 0x12, 0x12, 0x2e,
+	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x12, 0x37, 0x0a, 0x07, 0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x12, 0x16,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
+	0x50, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x33, 0x0a, 0x05,
+	0x4f, 0x6e, 0x41, 0x64, 0x64, 0x12, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4f,
+	0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x74, 0x78,
+	0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x30,
+	0x01, 0x12, 0x34, 0x0a, 0x06, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x15, 0x2e, 0x74, 0x78,
+	0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x13, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x31, 0x0a, 0x05, 0x4e, 0x6f, 0x6e, 0x63, 0x65,
+	0x12, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
+	0x4e, 0x6f, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x3a, 0x0a, 0x08, 0x47, 0x65,
+	0x74, 0x42, 0x6c, 0x6f, 0x62, 0x73, 0x12, 0x17, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
+	0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x62, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x15, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x47, 0x65, 0x74, 0x42, 0x6c, 0x6f, 0x62,
+	0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x40, 0x0a, 0x0a, 0x53, 0x65, 0x6e, 0x64, 0x42, 0x75,
+	0x6e, 0x64, 0x6c, 0x65, 0x12, 0x19, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x53, 0x65,
+	0x6e, 0x64, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x17, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x53, 0x65, 0x6e, 0x64, 0x42, 0x75, 0x6e,
+	0x64, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x46, 0x0a, 0x0c, 0x43, 0x61, 0x6e, 0x63,
+	0x65, 0x6c, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x12, 0x1b, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f,
+	0x6c, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x43,
+	0x61, 0x6e, 0x63, 0x65, 0x6c, 0x42, 0x75, 0x6e, 0x64, 0x6c, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79,
+	0x12, 0x41, 0x0a, 0x0e, 0x41, 0x64, 0x64, 0x43, 0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e,
+	0x61, 0x6c, 0x12, 0x1d, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x43,
+	0x6f, 0x6e, 0x64, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x12, 0x3b, 0x0a, 0x0a, 0x4f, 0x6e, 0x54, 0x78, 0x6e, 0x45, 0x76, 0x65, 0x6e,
+	0x74, 0x12, 0x19, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4f, 0x6e, 0x54, 0x78, 0x6e,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x74,
+	0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x6e, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x30, 0x01,
+	0x12, 0x55, 0x0a, 0x11, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x20, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c,
+	0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x16, 0x5a, 0x14, 0x2e, 0x2f, 0x74, 0x78, 0x70,
+	0x6f, 0x6f, 0x6c, 0x3b, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_txpool_txpool_proto_rawDescOnce sync.Once
+	file_txpool_txpool_proto_rawDescData = file_txpool_txpool_proto_rawDesc
+)
+
+func file_txpool_txpool_proto_rawDescGZIP() []byte {
+	file_txpool_txpool_proto_rawDescOnce.Do(func() {
+		file_txpool_txpool_proto_rawDescData = protoimpl.X.CompressGZIP(file_txpool_txpool_proto_rawDescData)
+	})
+	return file_txpool_txpool_proto_rawDescData
+}
+
+var file_txpool_txpool_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_txpool_txpool_proto_msgTypes = make([]protoimpl.MessageInfo, 31)
+var file_txpool_txpool_proto_goTypes = []any{
+	(ImportResult)(0),                // 0: txpool.ImportResult
+	(TxnEventType)(0),                // 1: txpool.TxnEventType
+	(AllReply_TxnType)(0),            // 2: txpool.AllReply.TxnType
+	(*TxHashes)(nil),                 // 3: txpool.TxHashes
+	(*AddRequest)(nil),               // 4: txpool.AddRequest
+	(*AddReply)(nil),                 // 5: txpool.AddReply
+	(*TransactionsRequest)(nil),      // 6: txpool.TransactionsRequest
+	(*TransactionsReply)(nil),        // 7: txpool.TransactionsReply
+	(*OnAddRequest)(nil),             // 8: txpool.OnAddRequest
+	(*OnAddReply)(nil),               // 9: txpool.OnAddReply
+	(*AllRequest)(nil),               // 10: txpool.AllRequest
+	(*AllReply)(nil),                 // 11: txpool.AllReply
+	(*PendingReply)(nil),             // 12: txpool.PendingReply
+	(*StatusRequest)(nil),            // 13: txpool.StatusRequest
+	(*StatusReply)(nil),              // 14: txpool.StatusReply
+	(*NonceRequest)(nil),             // 15: txpool.NonceRequest
+	(*NonceReply)(nil),               // 16: txpool.NonceReply
+	(*GetBlobsRequest)(nil),          // 17: txpool.GetBlobsRequest
+	(*BlobAndProofV1)(nil),           // 18: txpool.BlobAndProofV1
+	(*GetBlobsReply)(nil),            // 19: txpool.GetBlobsReply
+	(*SendBundleRequest)(nil),        // 20: txpool.SendBundleRequest
+	(*SendBundleReply)(nil),          // 21: txpool.SendBundleReply
+	(*CancelBundleRequest)(nil),      // 22: txpool.CancelBundleRequest
+	(*CancelBundleReply)(nil),        // 23: txpool.CancelBundleReply
+	(*StorageSlot)(nil),              // 24: txpool.StorageSlot
+	(*KnownAccount)(nil),             // 25: txpool.KnownAccount
+	(*TransactionConditions)(nil),    // 26: txpool.TransactionConditions
+	(*AddConditionalRequest)(nil),    // 27: txpool.AddConditionalRequest
+	(*TxnEvent)(nil),                 // 28: txpool.TxnEvent
+	(*OnTxnEventRequest)(nil),        // 29: txpool.OnTxnEventRequest
+	(*TransactionStatusRequest)(nil), // 30: txpool.TransactionStatusRequest
+	(*TransactionStatusReply)(nil),   // 31: txpool.TransactionStatusReply
+	(*AllReply_Tx)(nil),              // 32: txpool.AllReply.Tx
+	(*PendingReply_Tx)(nil),          // 33: txpool.PendingReply.Tx
+	(*typesproto.H256)(nil),          // 34: types.H256
+	(*typesproto.H160)(nil),          // 35: types.H160
+	(*emptypb.Empty)(nil),            // 36: google.protobuf.Empty
+	(*typesproto.VersionReply)(nil),  // 37: types.VersionReply
+}
+var file_txpool_txpool_proto_depIdxs = []int32{
+	34, // 0: txpool.TxHashes.hashes:type_name -> types.H256
+	0,  // 1: txpool.AddReply.imported:type_name -> txpool.ImportResult
+	34, // 2: txpool.TransactionsRequest.hashes:type_name -> types.H256
+	32, // 3: txpool.AllReply.txs:type_name -> txpool.AllReply.Tx
+	33, // 4: txpool.PendingReply.txs:type_name -> txpool.PendingReply.Tx
+	35, // 5: txpool.NonceRequest.address:type_name -> types.H160
+	34, // 6: txpool.GetBlobsRequest.blob_hashes:type_name -> types.H256
+	18, // 7: txpool.GetBlobsReply.blobs_and_proofs:type_name -> txpool.BlobAndProofV1
+	34, // 8: txpool.SendBundleRequest.reverting_tx_hashes:type_name -> types.H256
+	34, // 9: txpool.SendBundleReply.bundle_hash:type_name -> types.H256
+	34, // 10: txpool.StorageSlot.key:type_name -> types.H256
+	34, // 11: txpool.StorageSlot.value:type_name -> types.H256
+	35, // 12: txpool.KnownAccount.address:type_name -> types.H160
+	24, // 13: txpool.KnownAccount.slots:type_name -> txpool.StorageSlot
+	34, // 14: txpool.KnownAccount.storage_root:type_name -> types.H256
+	25, // 15: txpool.TransactionConditions.known_accounts:type_name -> txpool.KnownAccount
+	26, // 16: txpool.AddConditionalRequest.conditions:type_name -> txpool.TransactionConditions
+	1,  // 17: txpool.TxnEvent.type:type_name -> txpool.TxnEventType
+	34, // 18: txpool.TxnEvent.hash:type_name -> types.H256
+	2,  // 19: txpool.TxnEvent.sub_pool:type_name -> txpool.AllReply.TxnType
+	34, // 20: txpool.TxnEvent.replaced_by:type_name -> types.H256
+	34, // 21: txpool.TransactionStatusRequest.hash:type_name -> types.H256
+	28, // 22: txpool.TransactionStatusReply.events:type_name -> txpool.TxnEvent
+	2,  // 23: txpool.AllReply.Tx.txn_type:type_name -> txpool.AllReply.TxnType
+	35, // 24: txpool.AllReply.Tx.sender:type_name -> types.H160
+	35, // 25: txpool.PendingReply.Tx.sender:type_name -> types.H160
+	36, // 26: txpool.Txpool.Version:input_type -> google.protobuf.Empty
+	3,  // 27: txpool.Txpool.FindUnknown:input_type -> txpool.TxHashes
+	4,  // 28: txpool.Txpool.Add:input_type -> txpool.AddRequest
+	6,  // 29: txpool.Txpool.Transactions:input_type -> txpool.TransactionsRequest
+	10, // 30: txpool.Txpool.All:input_type -> txpool.AllRequest
+	36, // 31: txpool.Txpool.Pending:input_type -> google.protobuf.Empty
+	8,  // 32: txpool.Txpool.OnAdd:input_type -> txpool.OnAddRequest
+	13, // 33: txpool.Txpool.Status:input_type -> txpool.StatusRequest
+	15, // 34: txpool.Txpool.Nonce:input_type -> txpool.NonceRequest
+	17, // 35: txpool.Txpool.GetBlobs:input_type -> txpool.GetBlobsRequest
+	20, // 36: txpool.Txpool.SendBundle:input_type -> txpool.SendBundleRequest
+	22, // 37: txpool.Txpool.CancelBundle:input_type -> txpool.CancelBundleRequest
+	27, // 38: txpool.Txpool.AddConditional:input_type -> txpool.AddConditionalRequest
+	29, // 39: txpool.Txpool.OnTxnEvent:input_type -> txpool.OnTxnEventRequest
+	30, // 40: txpool.Txpool.TransactionStatus:input_type -> txpool.TransactionStatusRequest
+	37, // 41: txpool.Txpool.Version:output_type -> types.VersionReply
+	3,  // 42: txpool.Txpool.FindUnknown:output_type -> txpool.TxHashes
+	5,  // 43: txpool.Txpool.Add:output_type -> txpool.AddReply
+	7,  // 44: txpool.Txpool.Transactions:output_type -> txpool.TransactionsReply
+	11, // 45: txpool.Txpool.All:output_type -> txpool.AllReply
+	12, // 46: txpool.Txpool.Pending:output_type -> txpool.PendingReply
+	9,  // 47: txpool.Txpool.OnAdd:output_type -> txpool.OnAddReply
+	14, // 48: txpool.Txpool.Status:output_type -> txpool.StatusReply
+	16, // 49: txpool.Txpool.Nonce:output_type -> txpool.NonceReply
+	19, // 50: txpool.Txpool.GetBlobs:output_type -> txpool.GetBlobsReply
+	21, // 51: txpool.Txpool.SendBundle:output_type -> txpool.SendBundleReply
+	23, // 52: txpool.Txpool.CancelBundle:output_type -> txpool.CancelBundleReply
+	5,  // 53: txpool.Txpool.AddConditional:output_type -> txpool.AddReply
+	28, // 54: txpool.Txpool.OnTxnEvent:output_type -> txpool.TxnEvent
+	31, // 55: txpool.Txpool.TransactionStatus:output_type -> txpool.TransactionStatusReply
+	41, // [41:56] is the sub-list for method output_type
+	26, // [26:41] is the sub-list for method input_type
+	26, // [26:26] is the sub-list for extension type_name
+	26, // [26:26] is the sub-list for extension extendee
+	0,  // [0:26] is the sub-list for field type_name
+}
+
+func init() { file_txpool_txpool_proto_init() }
+func file_txpool_txpool_proto_init() {
+	if File_txpool_txpool_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_txpool_txpool_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*TxHashes); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*AddRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*AddReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*TransactionsRequest); i {
+			case 0:
+				return &v.state
 			case 1:
 				return &v.sizeCache
 			case 2:
@@ -1277,7 +2437,7 @@ func file_txpool_txpool_proto_init() {
 			}
 		}
 		file_txpool_txpool_proto_msgTypes[14].Exporter = func(v any, i int) any {
-			switch v := v.(*AllReply_Tx); i {
+			switch v := v.(*GetBlobsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1289,6 +2449,186 @@ func file_txpool_txpool_proto_init() {
 			}
 		}
 		file_txpool_txpool_proto_msgTypes[15].Exporter = func(v any, i int) any {
+			switch v := v.(*BlobAndProofV1); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[16].Exporter = func(v any, i int) any {
+			switch v := v.(*GetBlobsReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[17].Exporter = func(v any, i int) any {
+			switch v := v.(*SendBundleRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[18].Exporter = func(v any, i int) any {
+			switch v := v.(*SendBundleReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[19].Exporter = func(v any, i int) any {
+			switch v := v.(*CancelBundleRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[20].Exporter = func(v any, i int) any {
+			switch v := v.(*CancelBundleReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[21].Exporter = func(v any, i int) any {
+			switch v := v.(*StorageSlot); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[22].Exporter = func(v any, i int) any {
+			switch v := v.(*KnownAccount); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[23].Exporter = func(v any, i int) any {
+			switch v := v.(*TransactionConditions); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[24].Exporter = func(v any, i int) any {
+			switch v := v.(*AddConditionalRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[25].Exporter = func(v any, i int) any {
+			switch v := v.(*TxnEvent); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[26].Exporter = func(v any, i int) any {
+			switch v := v.(*OnTxnEventRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[27].Exporter = func(v any, i int) any {
+			switch v := v.(*TransactionStatusRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[28].Exporter = func(v any, i int) any {
+			switch v := v.(*TransactionStatusReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[29].Exporter = func(v any, i int) any {
+			switch v := v.(*AllReply_Tx); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_proto_msgTypes[30].Exporter = func(v any, i int) any {
 			switch v := v.(*PendingReply_Tx); i {
 			case 0:
 				return &v.state
@@ -1306,8 +2646,8 @@ func file_txpool_txpool_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_txpool_txpool_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   16,
+			NumEnums:      3,
+			NumMessages:   31,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -21,15 +21,21 @@ import (
 const _ = grpc.SupportPackageIsVersion8
 
 const (
-	Txpool_Version_FullMethodName      = "/txpool.Txpool/Version"
-	Txpool_FindUnknown_FullMethodName  = "/txpool.Txpool/FindUnknown"
-	Txpool_Add_FullMethodName          = "/txpool.Txpool/Add"
-	Txpool_Transactions_FullMethodName = "/txpool.Txpool/Transactions"
-	Txpool_All_FullMethodName          = "/txpool.Txpool/All"
-	Txpool_Pending_FullMethodName      = "/txpool.Txpool/Pending"
-	Txpool_OnAdd_FullMethodName        = "/txpool.Txpool/OnAdd"
-	Txpool_Status_FullMethodName       = "/txpool.Txpool/Status"
-	Txpool_Nonce_FullMethodName        = "/txpool.Txpool/Nonce"
+	Txpool_Version_FullMethodName           = "/txpool.Txpool/Version"
+	Txpool_FindUnknown_FullMethodName       = "/txpool.Txpool/FindUnknown"
+	Txpool_Add_FullMethodName               = "/txpool.Txpool/Add"
+	Txpool_Transactions_FullMethodName      = "/txpool.Txpool/Transactions"
+	Txpool_All_FullMethodName               = "/txpool.Txpool/All"
+	Txpool_Pending_FullMethodName           = "/txpool.Txpool/Pending"
+	Txpool_OnAdd_FullMethodName             = "/txpool.Txpool/OnAdd"
+	Txpool_Status_FullMethodName            = "/txpool.Txpool/Status"
+	Txpool_Nonce_FullMethodName             = "/txpool.Txpool/Nonce"
+	Txpool_GetBlobs_FullMethodName          = "/txpool.Txpool/GetBlobs"
+	Txpool_SendBundle_FullMethodName        = "/txpool.Txpool/SendBundle"
+	Txpool_CancelBundle_FullMethodName      = "/txpool.Txpool/CancelBundle"
+	Txpool_AddConditional_FullMethodName    = "/txpool.Txpool/AddConditional"
+	Txpool_OnTxnEvent_FullMethodName        = "/txpool.Txpool/OnTxnEvent"
+	Txpool_TransactionStatus_FullMethodName = "/txpool.Txpool/TransactionStatus"
 )
 
 // TxpoolClient is the client API for Txpool service.
@@ -55,6 +61,19 @@ type TxpoolClient interface {
 	Status(ctx context.Context, in *StatusRequest, opts ...grpc.CallOption) (*StatusReply, error)
 	// returns nonce for given account
 	Nonce(ctx context.Context, in *NonceRequest, opts ...grpc.CallOption) (*NonceReply, error)
+	// returns the blobs and proofs for the given versioned hashes, unknown hashes are returned as empty entries
+	GetBlobs(ctx context.Context, in *GetBlobsRequest, opts ...grpc.CallOption) (*GetBlobsReply, error)
+	// submits a bundle of signed transactions to be included atomically in a locally built block
+	SendBundle(ctx context.Context, in *SendBundleRequest, opts ...grpc.CallOption) (*SendBundleReply, error)
+	// cancels the bundle submitted with the given replacement uuid
+	CancelBundle(ctx context.Context, in *CancelBundleRequest, opts ...grpc.CallOption) (*CancelBundleReply, error)
+	// Expecting a signed transaction. Adds it as local, to be included only while the given conditions hold.
+	// Conditional transactions are not propagated to peers
+	AddConditional(ctx context.Context, in *AddConditionalRequest, opts ...grpc.CallOption) (*AddReply, error)
+	// subscribe to transaction lifecycle events: added, promoted, demoted, replaced, discarded and mined
+	OnTxnEvent(ctx context.Context, in *OnTxnEventRequest, opts ...grpc.CallOption) (Txpool_OnTxnEventClient, error)
+	// returns the recorded lifecycle events of the given transaction
+	TransactionStatus(ctx context.Context, in *TransactionStatusRequest, opts ...grpc.CallOption) (*TransactionStatusReply, error)
 }
 
 type txpoolClient struct {
@@ -178,6 +197,89 @@ func (c *txpoolClient) Nonce(ctx context.Context, in *NonceRequest, opts ...grpc
 	return out, nil
 }
 
+func (c *txpoolClient) GetBlobs(ctx context.Context, in *GetBlobsRequest, opts ...grpc.CallOption) (*GetBlobsReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetBlobsReply)
+	err := c.cc.Invoke(ctx, Txpool_GetBlobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txpoolClient) SendBundle(ctx context.Context, in *SendBundleRequest, opts ...grpc.CallOption) (*SendBundleReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendBundleReply)
+	err := c.cc.Invoke(ctx, Txpool_SendBundle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txpoolClient) CancelBundle(ctx context.Context, in *CancelBundleRequest, opts ...grpc.CallOption) (*CancelBundleReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelBundleReply)
+	err := c.cc.Invoke(ctx, Txpool_CancelBundle_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txpoolClient) AddConditional(ctx context.Context, in *AddConditionalRequest, opts ...grpc.CallOption) (*AddReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddReply)
+	err := c.cc.Invoke(ctx, Txpool_AddConditional_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *txpoolClient) OnTxnEvent(ctx context.Context, in *OnTxnEventRequest, opts ...grpc.CallOption) (Txpool_OnTxnEventClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Txpool_ServiceDesc.Streams[1], Txpool_OnTxnEvent_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &txpoolOnTxnEventClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Txpool_OnTxnEventClient interface {
+	Recv() (*TxnEvent, error)
+	grpc.ClientStream
+}
+
+type txpoolOnTxnEventClient struct {
+	grpc.ClientStream
+}
+
+func (x *txpoolOnTxnEventClient) Recv() (*TxnEvent, error) {
+	m := new(TxnEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *txpoolClient) TransactionStatus(ctx context.Context, in *TransactionStatusRequest, opts ...grpc.CallOption) (*TransactionStatusReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TransactionStatusReply)
+	err := c.cc.Invoke(ctx, Txpool_TransactionStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // TxpoolServer is the server API for Txpool service.
 // All implementations must embed UnimplementedTxpoolServer
 // for forward compatibility
@@ -201,6 +303,19 @@ type TxpoolServer interface {
 	Status(context.Context, *StatusRequest) (*StatusReply, error)
 	// returns nonce for given account
 	Nonce(context.Context, *NonceRequest) (*NonceReply, error)
+	// returns the blobs and proofs for the given versioned hashes, unknown hashes are returned as empty entries
+	GetBlobs(context.Context, *GetBlobsRequest) (*GetBlobsReply, error)
+	// submits a bundle of signed transactions to be included atomically in a locally built block
+	SendBundle(context.Context, *SendBundleRequest) (*SendBundleReply, error)
+	// cancels the bundle submitted with the given replacement uuid
+	CancelBundle(context.Context, *CancelBundleRequest) (*CancelBundleReply, error)
+	// Expecting a signed transaction. Adds it as local, to be included only while the given conditions hold.
+	// Conditional transactions are not propagated to peers
+	AddConditional(context.Context, *AddConditionalRequest) (*AddReply, error)
+	// subscribe to transaction lifecycle events: added, promoted, demoted, replaced, discarded and mined
+	OnTxnEvent(*OnTxnEventRequest, Txpool_OnTxnEventServer) error
+	// returns the recorded lifecycle events of the given transaction
+	TransactionStatus(context.Context, *TransactionStatusRequest) (*TransactionStatusReply, error)
 	mustEmbedUnimplementedTxpoolServer()
 }
 
@@ -235,6 +350,24 @@ func (UnimplementedTxpoolServer) Status(context.Context, *StatusRequest) (*Statu
 func (UnimplementedTxpoolServer) Nonce(context.Context, *NonceRequest) (*NonceReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Nonce not implemented")
 }
+func (UnimplementedTxpoolServer) GetBlobs(context.Context, *GetBlobsRequest) (*GetBlobsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlobs not implemented")
+}
+func (UnimplementedTxpoolServer) SendBundle(context.Context, *SendBundleRequest) (*SendBundleReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendBundle not implemented")
+}
+func (UnimplementedTxpoolServer) CancelBundle(context.Context, *CancelBundleRequest) (*CancelBundleReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelBundle not implemented")
+}
+func (UnimplementedTxpoolServer) AddConditional(context.Context, *AddConditionalRequest) (*AddReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddConditional not implemented")
+}
+func (UnimplementedTxpoolServer) OnTxnEvent(*OnTxnEventRequest, Txpool_OnTxnEventServer) error {
+	return status.Errorf(codes.Unimplemented, "method OnTxnEvent not implemented")
+}
+func (UnimplementedTxpoolServer) TransactionStatus(context.Context, *TransactionStatusRequest) (*TransactionStatusReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TransactionStatus not implemented")
+}
 func (UnimplementedTxpoolServer) mustEmbedUnimplementedTxpoolServer() {}
 
 // UnsafeTxpoolServer may be embedded to opt out of forward compatibility for this service.
@@ -413,6 +546,117 @@ func _Txpool_Nonce_Handler(srv interface{}, ctx context.Context, dec func(interf
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Txpool_GetBlobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBlobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxpoolServer).GetBlobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Txpool_GetBlobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxpoolServer).GetBlobs(ctx, req.(*GetBlobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Txpool_SendBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxpoolServer).SendBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Txpool_SendBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxpoolServer).SendBundle(ctx, req.(*SendBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Txpool_CancelBundle_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelBundleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxpoolServer).CancelBundle(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Txpool_CancelBundle_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxpoolServer).CancelBundle(ctx, req.(*CancelBundleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Txpool_AddConditional_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddConditionalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxpoolServer).AddConditional(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Txpool_AddConditional_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxpoolServer).AddConditional(ctx, req.(*AddConditionalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Txpool_OnTxnEvent_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(OnTxnEventRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TxpoolServer).OnTxnEvent(m, &txpoolOnTxnEventServer{ServerStream: stream})
+}
+
+type Txpool_OnTxnEventServer interface {
+	Send(*TxnEvent) error
+	grpc.ServerStream
+}
+
+type txpoolOnTxnEventServer struct {
+	grpc.ServerStream
+}
+
+func (x *txpoolOnTxnEventServer) Send(m *TxnEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Txpool_TransactionStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TransactionStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TxpoolServer).TransactionStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Txpool_TransactionStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TxpoolServer).TransactionStatus(ctx, req.(*TransactionStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Txpool_ServiceDesc is the grpc.ServiceDesc for Txpool service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -452,6 +696,26 @@ var Txpool_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Nonce",
 			Handler:    _Txpool_Nonce_Handler,
 		},
+		{
+			MethodName: "GetBlobs",
+			Handler:    _Txpool_GetBlobs_Handler,
+		},
+		{
+			MethodName: "SendBundle",
+			Handler:    _Txpool_SendBundle_Handler,
+		},
+		{
+			MethodName: "CancelBundle",
+			Handler:    _Txpool_CancelBundle_Handler,
+		},
+		{
+			MethodName: "AddConditional",
+			Handler:    _Txpool_AddConditional_Handler,
+		},
+		{
+			MethodName: "TransactionStatus",
+			Handler:    _Txpool_TransactionStatus_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -459,6 +723,11 @@ var Txpool_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Txpool_OnAdd_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "OnTxnEvent",
+			Handler:       _Txpool_OnTxnEvent_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "txpool/txpool.proto",
 }
@@ -44,4 +44,7 @@ const (
 	FileLogTopicsIdx  = "logtopics"
 	FileTracesFromIdx = "tracesfrom"
 	FileTracesToIdx   = "tracesto"
+
+	FileTracesSelectorIdx = "tracesselector"
+	FileTracesFailedIdx   = "tracesfailed"
 )
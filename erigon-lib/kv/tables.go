@@ -163,6 +163,9 @@ const (
 
 	Sequence = "Sequence" // tbl_name -> seq_u64
 
+	// LogExporterCursor - journal of the recently delivered blocks of every log exporter, the last one is its cursor
+	LogExporterCursor = "LogExporterCursor" // exporter_name + 0x00 + block_num_u64 -> block_hash
+
 	Epoch        = "DevEpoch"        // block_num_u64+block_hash->transition_proof
 	PendingEpoch = "DevPendingEpoch" // block_num_u64+block_hash->transition_proof
 
@@ -222,6 +225,11 @@ const (
 	TblTracesToKeys   = "TracesToKeys"
 	TblTracesToIdx    = "TracesToIdx"
 
+	TblTracesSelectorKeys = "TracesSelectorKeys"
+	TblTracesSelectorIdx  = "TracesSelectorIdx"
+	TblTracesFailedKeys   = "TracesFailedKeys"
+	TblTracesFailedIdx    = "TracesFailedIdx"
+
 	// Prune progress of execution: tableName -> [8bytes of invStep]latest pruned key
 	// Could use table constants `Tbl{Account,Storage,Code,Commitment}Keys` for domains
 	// corresponding history tables `Tbl{Account,Storage,Code,Commitment}HistoryKeys` for history
@@ -355,6 +363,7 @@ var ChaindataTables = []string{
 	CallToIndex,
 	Log,
 	Sequence,
+	LogExporterCursor,
 	EthTx,
 	TrieOfAccounts,
 	TrieOfStorage,
@@ -410,6 +419,11 @@ var ChaindataTables = []string{
 	TblTracesToKeys,
 	TblTracesToIdx,
 
+	TblTracesSelectorKeys,
+	TblTracesSelectorIdx,
+	TblTracesFailedKeys,
+	TblTracesFailedIdx,
+
 	TblPruningProgress,
 
 	MaxTxNum,
@@ -463,12 +477,18 @@ const (
 	RecentLocalTransaction = "RecentLocalTransaction" // sequence_u64 -> tx_hash
 	PoolTransaction        = "PoolTransaction"        // txHash -> sender+tx_rlp
 	PoolInfo               = "PoolInfo"               // option_key -> option_value
+	PoolTxnConditions      = "PoolTxnConditions"      // txHash -> conditions of txns added with eth_sendRawTransactionConditional
+	PoolPrivateTransaction = "PoolPrivateTransaction" // txHash -> expiry_block_u64 + fallback_to_public, txns added with eth_sendPrivateRawTransaction
+	PoolTxnStatus          = "PoolTxnStatus"          // txHash -> lifecycle events of recently seen txns, for txpool_getTransactionStatus
 )
 
 var TxPoolTables = []string{
 	RecentLocalTransaction,
 	PoolTransaction,
 	PoolInfo,
+	PoolTxnConditions,
+	PoolPrivateTransaction,
+	PoolTxnStatus,
 }
 var SentryTables = []string{
 	Inodes,
@@ -579,6 +599,10 @@ var ChaindataTablesCfg = TableCfg{
 	TblTracesFromIdx:         {Flags: DupSort},
 	TblTracesToKeys:          {Flags: DupSort},
 	TblTracesToIdx:           {Flags: DupSort},
+	TblTracesSelectorKeys:    {Flags: DupSort},
+	TblTracesSelectorIdx:     {Flags: DupSort},
+	TblTracesFailedKeys:      {Flags: DupSort},
+	TblTracesFailedIdx:       {Flags: DupSort},
 }
 
 var BorTablesCfg = TableCfg{
@@ -738,14 +762,23 @@ const (
 	LogAddrIdx    InvertedIdx = "LogAddrIdx"
 	TracesFromIdx InvertedIdx = "TracesFromIdx"
 	TracesToIdx   InvertedIdx = "TracesToIdx"
-
-	LogAddrIdxPos    InvertedIdxPos = 0
-	LogTopicIdxPos   InvertedIdxPos = 1
-	TracesFromIdxPos InvertedIdxPos = 2
-	TracesToIdxPos   InvertedIdxPos = 3
-	StandaloneIdxLen InvertedIdxPos = 4
+	// TracesSelectorIdx - 4-byte function selectors of all the call frames of a txn
+	TracesSelectorIdx InvertedIdx = "TracesSelectorIdx"
+	// TracesFailedIdx - callees of the failed call frames of a txn, and TracesFailedAnyKey for any failed frame
+	TracesFailedIdx InvertedIdx = "TracesFailedIdx"
+
+	LogAddrIdxPos        InvertedIdxPos = 0
+	LogTopicIdxPos       InvertedIdxPos = 1
+	TracesFromIdxPos     InvertedIdxPos = 2
+	TracesToIdxPos       InvertedIdxPos = 3
+	TracesSelectorIdxPos InvertedIdxPos = 4
+	TracesFailedIdxPos   InvertedIdxPos = 5
+	StandaloneIdxLen     InvertedIdxPos = 6
 )
 
+// TracesFailedAnyKey is the TracesFailedIdx key of the txns having at least one failed call frame
+var TracesFailedAnyKey = []byte{0}
+
 const (
 	ReceiptsAppendable Appendable = 0
 	AppendableLen      Appendable = 0
@@ -761,6 +794,10 @@ func (iip InvertedIdxPos) String() string {
 		return "traceFrom"
 	case TracesToIdxPos:
 		return "traceTo"
+	case TracesSelectorIdxPos:
+		return "traceSelector"
+	case TracesFailedIdxPos:
+		return "traceFailed"
 	default:
 		return "unknown inverted index"
 	}
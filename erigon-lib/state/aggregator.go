@@ -266,6 +266,12 @@ func NewAggregator(ctx context.Context, dirs datadir.Dirs, aggregationStep uint6
 	if err := a.registerII(kv.TracesToIdxPos, salt, dirs, db, aggregationStep, kv.FileTracesToIdx, kv.TblTracesToKeys, kv.TblTracesToIdx, logger); err != nil {
 		return nil, err
 	}
+	if err := a.registerII(kv.TracesSelectorIdxPos, salt, dirs, db, aggregationStep, kv.FileTracesSelectorIdx, kv.TblTracesSelectorKeys, kv.TblTracesSelectorIdx, logger); err != nil {
+		return nil, err
+	}
+	if err := a.registerII(kv.TracesFailedIdxPos, salt, dirs, db, aggregationStep, kv.FileTracesFailedIdx, kv.TblTracesFailedKeys, kv.TblTracesFailedIdx, logger); err != nil {
+		return nil, err
+	}
 	a.KeepRecentTxnsOfHistoriesWithDisabledSnapshots(100_000) // ~1k blocks of history
 	a.recalcVisibleFiles(a.DirtyFilesEndTxNumMinimax())
 
@@ -711,6 +717,10 @@ func (a *Aggregator) buildFiles(ctx context.Context, step uint64) error {
 				static.ivfs[kv.TracesFromIdxPos] = sf
 			case kv.TblTracesToKeys:
 				static.ivfs[kv.TracesToIdxPos] = sf
+			case kv.TblTracesSelectorKeys:
+				static.ivfs[kv.TracesSelectorIdxPos] = sf
+			case kv.TblTracesFailedKeys:
+				static.ivfs[kv.TracesFailedIdxPos] = sf
 			default:
 				panic("unknown index " + ii.keysTable)
 			}
@@ -886,6 +896,19 @@ func (ac *AggregatorRoTx) minimaxTxNumInDomainFiles() uint64 {
 	return m
 }
 
+// IIFirstCompleteTxNum returns the txNum from which the inverted index has the entries of all the txns. The txns of
+// the domain files a node was synced from are not indexed by an index missing from those files, until it is backfilled.
+func (ac *AggregatorRoTx) IIFirstCompleteTxNum(idx kv.InvertedIdxPos) uint64 {
+	files := ac.iis[idx].files
+	if domainsEnd := ac.minimaxTxNumInDomainFiles(); files.EndTxNum() < domainsEnd {
+		return domainsEnd
+	}
+	if len(files) == 0 {
+		return 0
+	}
+	return files[0].startTxNum
+}
+
 func (ac *AggregatorRoTx) CanPrune(tx kv.Tx, untilTx uint64) bool {
 	if dbg.NoPrune() {
 		return false
@@ -1751,6 +1774,10 @@ func (ac *AggregatorRoTx) IndexRange(name kv.InvertedIdx, k []byte, fromTs, toTs
 		return ac.iis[kv.TracesFromIdxPos].IdxRange(k, fromTs, toTs, asc, limit, tx)
 	case kv.TracesToIdx:
 		return ac.iis[kv.TracesToIdxPos].IdxRange(k, fromTs, toTs, asc, limit, tx)
+	case kv.TracesSelectorIdx:
+		return ac.iis[kv.TracesSelectorIdxPos].IdxRange(k, fromTs, toTs, asc, limit, tx)
+	case kv.TracesFailedIdx:
+		return ac.iis[kv.TracesFailedIdxPos].IdxRange(k, fromTs, toTs, asc, limit, tx)
 	default:
 		return nil, fmt.Errorf("unexpected history name: %s", name)
 	}
@@ -1914,6 +1941,16 @@ func (ac *AggregatorRoTx) DebugEFAllValuesAreInRange(ctx context.Context, name k
 		if err != nil {
 			return err
 		}
+	case kv.TracesSelectorIdx:
+		err := ac.iis[kv.TracesSelectorIdxPos].DebugEFAllValuesAreInRange(ctx, failFast, fromStep)
+		if err != nil {
+			return err
+		}
+	case kv.TracesFailedIdx:
+		err := ac.iis[kv.TracesFailedIdxPos].DebugEFAllValuesAreInRange(ctx, failFast, fromStep)
+		if err != nil {
+			return err
+		}
 	case kv.LogAddrIdx:
 		err := ac.iis[kv.LogAddrIdxPos].DebugEFAllValuesAreInRange(ctx, failFast, fromStep)
 		if err != nil {
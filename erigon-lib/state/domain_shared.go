@@ -47,6 +47,7 @@ import (
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/order"
 	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/trie"
 	"github.com/erigontech/erigon-lib/types"
 )
 
@@ -670,6 +671,10 @@ func (sd *SharedDomains) IndexAdd(table kv.InvertedIdx, key []byte) (err error)
 		err = sd.iiWriters[kv.TracesToIdxPos].Add(key)
 	case kv.TblTracesFromIdx:
 		err = sd.iiWriters[kv.TracesFromIdxPos].Add(key)
+	case kv.TblTracesSelectorIdx:
+		err = sd.iiWriters[kv.TracesSelectorIdxPos].Add(key)
+	case kv.TblTracesFailedIdx:
+		err = sd.iiWriters[kv.TracesFailedIdxPos].Add(key)
 	default:
 		panic(fmt.Errorf("unknown shared index %s", table))
 	}
@@ -719,6 +724,10 @@ func (sd *SharedDomains) SetBlockNum(blockNum uint64) {
 	sd.blockNum.Store(blockNum)
 }
 
+func (sd *SharedDomains) GetCommitmentContext() *SharedDomainsCommitmentContext {
+	return sd.sdCtx
+}
+
 func (sd *SharedDomains) SetTrace(b bool) {
 	sd.trace = b
 }
@@ -1105,12 +1114,36 @@ type SharedDomainsCommitmentContext struct {
 	justRestored  atomic.Bool
 
 	limitReadAsOfTxNum uint64
+
+	historyRead       bool
+	historyReadAsOfTx uint64
 }
 
 func (sdc *SharedDomainsCommitmentContext) SetLimitReadAsOfTxNum(txNum uint64) {
 	sdc.limitReadAsOfTxNum = txNum
 }
 
+// SetHistoryStateReader makes the context read accounts, storage and code as of txNum (before txNum changed them)
+// from the domains history, instead of the latest state. The commitment domain keeps no history: to get the trie
+// of a past state, the keys changed since txNum have to be touched and the commitment computed again.
+// The values written to the shared domains since still take precedence, to compute the commitment of changes made
+// on top of the past state.
+func (sdc *SharedDomainsCommitmentContext) SetHistoryStateReader(txNum uint64) {
+	sdc.historyRead, sdc.historyReadAsOfTx = true, txNum
+}
+
+// readAsOf reads the historical value of a key, see SetHistoryStateReader
+func (sdc *SharedDomainsCommitmentContext) readAsOf(domain kv.Domain, plainKey []byte) ([]byte, error) {
+	if v, _, ok := sdc.sharedDomains.get(domain, plainKey); ok {
+		return v, nil
+	}
+	v, _, err := sdc.sharedDomains.aggTx.GetAsOf(sdc.sharedDomains.roTx, domain, plainKey, sdc.historyReadAsOfTx)
+	if err != nil {
+		return nil, fmt.Errorf("%s as of txn %d: %w", domain, sdc.historyReadAsOfTx, err)
+	}
+	return v, nil
+}
+
 func NewSharedDomainsCommitmentContext(sd *SharedDomains, mode commitment.Mode, trieVariant commitment.TrieVariant) *SharedDomainsCommitmentContext {
 	ctx := &SharedDomainsCommitmentContext{
 		sharedDomains: sd,
@@ -1174,6 +1207,9 @@ func (sdc *SharedDomainsCommitmentContext) PutBranch(prefix []byte, data []byte,
 }
 
 func (sdc *SharedDomainsCommitmentContext) readAccount(plainKey []byte) (encAccount []byte, err error) {
+	if sdc.historyRead {
+		return sdc.readAsOf(kv.AccountsDomain, plainKey)
+	}
 	if sdc.limitReadAsOfTxNum > 0 {
 		encAccount, _, err = sdc.sharedDomains.getAsOfFile(kv.AccountsDomain, plainKey, nil, sdc.limitReadAsOfTxNum)
 		if err != nil {
@@ -1189,6 +1225,9 @@ func (sdc *SharedDomainsCommitmentContext) readAccount(plainKey []byte) (encAcco
 }
 
 func (sdc *SharedDomainsCommitmentContext) readCode(plainKey []byte) (code []byte, err error) {
+	if sdc.historyRead {
+		return sdc.readAsOf(kv.CodeDomain, plainKey)
+	}
 	if sdc.limitReadAsOfTxNum > 0 {
 		code, _, err = sdc.sharedDomains.getAsOfFile(kv.CodeDomain, plainKey, nil, sdc.limitReadAsOfTxNum)
 		if err != nil {
@@ -1203,6 +1242,9 @@ func (sdc *SharedDomainsCommitmentContext) readCode(plainKey []byte) (code []byt
 	return code, nil
 }
 func (sdc *SharedDomainsCommitmentContext) readStorage(plainKey []byte) (enc []byte, err error) {
+	if sdc.historyRead {
+		return sdc.readAsOf(kv.StorageDomain, plainKey)
+	}
 	if sdc.limitReadAsOfTxNum > 0 {
 		enc, _, err = sdc.sharedDomains.getAsOfFile(kv.StorageDomain, plainKey, nil, sdc.limitReadAsOfTxNum)
 		if err != nil {
@@ -1298,6 +1340,17 @@ func (sdc *SharedDomainsCommitmentContext) Trie() commitment.Trie {
 	return sdc.patriciaTrie
 }
 
+// Witness returns the trie holding the merkle paths to the touched keys, e.g. to build proofs of them
+func (sdc *SharedDomainsCommitmentContext) Witness(ctx context.Context, expectedRoot []byte, logPrefix string) (proofTrie *trie.Trie, rootHash []byte, err error) {
+	hph, ok := sdc.patriciaTrie.(*commitment.HexPatriciaHashed)
+	if !ok {
+		return nil, nil, errors.New("witness is only supported by hex patricia trie")
+	}
+	sdc.ResetBranchCache()
+	defer sdc.ResetBranchCache()
+	return hph.GenerateWitness(ctx, sdc.updates, nil, expectedRoot, logPrefix)
+}
+
 // TouchPlainKey marks plainKey as updated and applies different fn for different key types
 // (different behaviour for Code, Account and Storage key modifications).
 func (sdc *SharedDomainsCommitmentContext) TouchKey(d kv.Domain, key string, val []byte) {
@@ -21,7 +21,6 @@ package trie
 
 import (
 	"errors"
-	"fmt"
 	"hash"
 	"sync"
 
@@ -292,7 +291,6 @@ func (h *hasher) valueNodeToBuffer(vn ValueNode, buffer []byte, pos int) (int, e
 		val = rlphacks.RlpSerializableBytes(vn)
 	}
 
-	fmt.Printf("valueNodeToBuffer()>>>>>>> val=%x\n", val)
 	if err := val.ToDoubleRLP(h.bw, h.prefixBuf[:]); err != nil {
 		return 0, err
 	}
@@ -0,0 +1,188 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"fmt"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/rlp"
+	"github.com/erigontech/erigon-lib/types/accounts"
+)
+
+// EncodedNodes returns the RLP encodings of all the resolved nodes of the trie (i.e. everything except hash nodes),
+// including the nodes of the storage sub-tries. This is the "state" part of a geth-style execution witness:
+// a flat list of MPT nodes which can be looked up by their keccak hash.
+func (t *Trie) EncodedNodes() ([][]byte, error) {
+	h := newHasher(t.valueNodesRLPEncoded)
+	defer returnHasherToPool(h)
+
+	var nodes [][]byte
+	var walk func(n Node) error
+	walk = func(n Node) error {
+		switch n := n.(type) {
+		case nil, HashNode, *HashNode, ValueNode, CodeNode:
+			return nil
+		case *AccountNode:
+			return walk(n.Storage)
+		case *ShortNode, *DuoNode, *FullNode:
+			enc, err := h.hashChildren(n, 0)
+			if err != nil {
+				return err
+			}
+			nodes = append(nodes, libcommon.CopyBytes(enc))
+		default:
+			return fmt.Errorf("%T: invalid node: %v", n, n)
+		}
+
+		switch n := n.(type) {
+		case *ShortNode:
+			return walk(n.Val)
+		case *DuoNode:
+			if err := walk(n.child1); err != nil {
+				return err
+			}
+			return walk(n.child2)
+		case *FullNode:
+			for _, child := range n.Children {
+				if err := walk(child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(t.RootNode); err != nil {
+		return nil, err
+	}
+	return nodes, nil
+}
+
+// BuildTrieFromNodes re-creates a (possibly partial) state trie with the given root out of a set of RLP encoded
+// MPT nodes, i.e. the inverse of EncodedNodes. Nodes that are not present in the set are left as hash nodes.
+// Account leaves are turned into account nodes, with their storage sub-tries and bytecodes (looked up by code
+// hash) attached.
+func BuildTrieFromNodes(root libcommon.Hash, nodes [][]byte, codes [][]byte) (*Trie, error) {
+	if root == EmptyRoot {
+		return New(EmptyRoot), nil
+	}
+	b := &nodesTrieBuilder{
+		nodes: make(map[libcommon.Hash][]byte, len(nodes)),
+		codes: make(map[libcommon.Hash][]byte, len(codes)),
+	}
+	for _, enc := range nodes {
+		b.nodes[crypto.Keccak256Hash(enc)] = enc
+	}
+	for _, code := range codes {
+		b.codes[crypto.Keccak256Hash(code)] = code
+	}
+	if _, ok := b.nodes[root]; !ok {
+		return nil, fmt.Errorf("root node %x is missing", root)
+	}
+	rootNode, err := b.resolve(HashNode{hash: root[:]}, false)
+	if err != nil {
+		return nil, err
+	}
+	t := New(root)
+	t.RootNode = rootNode
+	return t, nil
+}
+
+type nodesTrieBuilder struct {
+	nodes map[libcommon.Hash][]byte
+	codes map[libcommon.Hash][]byte
+}
+
+// resolve replaces hash references by the decoded nodes, recursively. storage tells whether the node belongs to a
+// storage sub-trie, in which case leaves are plain values rather than accounts.
+func (b *nodesTrieBuilder) resolve(n Node, storage bool) (Node, error) {
+	switch n := n.(type) {
+	case nil:
+		return nil, nil
+	case HashNode:
+		enc, ok := b.nodes[libcommon.BytesToHash(n.hash)]
+		if !ok {
+			return NewHashNode(n.hash), nil
+		}
+		decoded, err := decodeNode(enc)
+		if err != nil {
+			return nil, fmt.Errorf("decoding node %x: %w", n.hash, err)
+		}
+		return b.resolve(decoded, storage)
+	case *ShortNode:
+		if v, ok := n.Val.(ValueNode); ok {
+			leaf, err := b.leaf(v, storage)
+			if err != nil {
+				return nil, err
+			}
+			n.Val = leaf
+			return n, nil
+		}
+		child, err := b.resolve(n.Val, storage)
+		if err != nil {
+			return nil, err
+		}
+		n.Val = child
+		return n, nil
+	case *FullNode:
+		for i := 0; i < 16; i++ {
+			child, err := b.resolve(n.Children[i], storage)
+			if err != nil {
+				return nil, err
+			}
+			n.Children[i] = child
+		}
+		if n.Children[16] != nil {
+			return nil, fmt.Errorf("unexpected value in branch node")
+		}
+		return n, nil
+	default:
+		return nil, fmt.Errorf("%T: invalid node: %v", n, n)
+	}
+}
+
+func (b *nodesTrieBuilder) leaf(v ValueNode, storage bool) (Node, error) {
+	if storage {
+		// storage values are RLP encoded in the MPT, while the trie keeps them as plain bytes
+		val, _, err := rlp.SplitString(v)
+		if err != nil {
+			return nil, err
+		}
+		return ValueNode(val), nil
+	}
+
+	var acc accounts.Account
+	if err := acc.DecodeForHashing(v); err != nil {
+		return nil, err
+	}
+	accNode := &AccountNode{Account: acc, CodeSize: codeSizeUncached}
+	if acc.Root != EmptyRoot {
+		storageRoot, err := b.resolve(HashNode{hash: libcommon.CopyBytes(acc.Root[:])}, true)
+		if err != nil {
+			return nil, err
+		}
+		accNode.Storage = storageRoot
+	}
+	if !acc.IsEmptyCodeHash() {
+		if code, ok := b.codes[acc.CodeHash]; ok {
+			accNode.Code = code
+			accNode.CodeSize = len(code)
+		}
+	}
+	return accNode, nil
+}
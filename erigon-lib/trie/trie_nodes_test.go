@@ -0,0 +1,102 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package trie
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv/dbutils"
+	"github.com/erigontech/erigon-lib/types/accounts"
+)
+
+func TestBuildTrieFromEncodedNodes(t *testing.T) {
+	code := []byte{0x60, 0x00, 0x60, 0x00, 0xf3}
+	tr := newEmpty()
+	var addrHashes []libcommon.Hash
+	for i := 0; i < 20; i++ {
+		addrHash := crypto.Keccak256Hash([]byte{byte(i)})
+		addrHashes = append(addrHashes, addrHash)
+		acc := &accounts.Account{
+			Nonce:    uint64(i),
+			Balance:  *uint256.NewInt(uint64(i) * 1000),
+			Root:     EmptyRoot,
+			CodeHash: EmptyCodeHash,
+		}
+		if i == 3 {
+			acc.CodeHash = crypto.Keccak256Hash(code)
+		}
+		tr.UpdateAccount(addrHash[:], acc)
+	}
+	require.NoError(t, tr.UpdateAccountCode(addrHashes[3][:], code))
+	for i := 0; i < 10; i++ {
+		keyHash := crypto.Keccak256Hash([]byte{0xff, byte(i)})
+		tr.Update(dbutils.GenerateCompositeTrieKey(addrHashes[3], keyHash), []byte{byte(i + 1)})
+	}
+	root := tr.Hash()
+
+	nodes, err := tr.EncodedNodes()
+	require.NoError(t, err)
+	rebuilt, err := BuildTrieFromNodes(root, nodes, [][]byte{code})
+	require.NoError(t, err)
+	require.Equal(t, root, rebuilt.Hash())
+
+	acc, ok := rebuilt.GetAccount(addrHashes[5][:])
+	require.True(t, ok)
+	require.Equal(t, uint64(5), acc.Nonce)
+	require.Equal(t, uint64(5000), acc.Balance.Uint64())
+
+	gotCode, ok := rebuilt.GetAccountCode(addrHashes[3][:])
+	require.True(t, ok)
+	require.Equal(t, code, gotCode)
+
+	val, ok := rebuilt.Get(dbutils.GenerateCompositeTrieKey(addrHashes[3], crypto.Keccak256Hash([]byte{0xff, 7})))
+	require.True(t, ok)
+	require.Equal(t, []byte{8}, val)
+
+	// the rebuilt trie stays usable for updates
+	rebuilt.Update(dbutils.GenerateCompositeTrieKey(addrHashes[3], crypto.Keccak256Hash([]byte{0xff, 7})), []byte{0x42})
+	tr.Update(dbutils.GenerateCompositeTrieKey(addrHashes[3], crypto.Keccak256Hash([]byte{0xff, 7})), []byte{0x42})
+	require.Equal(t, tr.Hash(), rebuilt.Hash())
+}
+
+func TestBuildTrieFromPartialNodes(t *testing.T) {
+	tr := newEmpty()
+	var addrHashes []libcommon.Hash
+	for i := 0; i < 50; i++ {
+		addrHash := crypto.Keccak256Hash([]byte{byte(i)})
+		addrHashes = append(addrHashes, addrHash)
+		tr.UpdateAccount(addrHash[:], &accounts.Account{Nonce: uint64(i), Root: EmptyRoot, CodeHash: EmptyCodeHash})
+	}
+	root := tr.Hash()
+
+	// only keep the root node: every child is left as a hash node, but the root hash must still match
+	nodes, err := tr.EncodedNodes()
+	require.NoError(t, err)
+	rebuilt, err := BuildTrieFromNodes(root, nodes[:1], nil)
+	require.NoError(t, err)
+	require.Equal(t, root, rebuilt.Hash())
+	_, ok := rebuilt.GetAccount(addrHashes[1][:])
+	require.False(t, ok)
+
+	_, err = BuildTrieFromNodes(libcommon.Hash{1}, nodes, nil)
+	require.Error(t, err)
+}
@@ -124,6 +124,7 @@ import (
 	"github.com/erigontech/erigon/turbo/execution/eth1"
 	"github.com/erigontech/erigon/turbo/execution/eth1/eth1_chain_reader.go"
 	"github.com/erigontech/erigon/turbo/jsonrpc"
+	"github.com/erigontech/erigon/turbo/logexporter"
 	"github.com/erigontech/erigon/turbo/services"
 	"github.com/erigontech/erigon/turbo/shards"
 	"github.com/erigontech/erigon/turbo/silkworm"
@@ -187,7 +188,8 @@ type Ethereum struct {
 
 	notifications *shards.Notifications
 
-	unsubscribeEthstat func()
+	unsubscribeEthstat     func()
+	unsubscribeLogExporter func()
 
 	waitForStageLoopStop chan struct{}
 	waitForMiningStop    chan struct{}
@@ -242,6 +244,9 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 		logger.Warn("Sanitizing invalid miner gas price", "provided", config.Miner.GasPrice, "updated", ethconfig.Defaults.Miner.GasPrice)
 		config.Miner.GasPrice = new(big.Int).Set(ethconfig.Defaults.Miner.GasPrice)
 	}
+	if _, err := txnprovider.NewOrderingPolicy(config.Miner.TxnOrdering, config.Miner.PrioritySenders); err != nil {
+		return nil, fmt.Errorf("miner: %w", err)
+	}
 	dirs := stack.Config().Dirs
 
 	tmpdir := dirs.Tmp
@@ -662,6 +667,10 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 		}
 
 		backend.txnProvider = txnprovider.NewOrderedTxnPoolProvider(backend.txPool)
+		if casted, ok := backend.txPoolGrpcServer.(*txpool.GrpcServer); ok {
+			// bundles submitted via eth_sendBundle go ahead of the txpool transactions
+			backend.txnProvider = txnprovider.NewCompositeTxnProvider(txnprovider.NewBundleTxnProvider(casted.Bundles), backend.txnProvider)
+		}
 	}
 
 	backend.notifyMiningAboutNewTxs = make(chan struct{}, 1)
@@ -792,6 +801,30 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 
 	miningRPC = privateapi.NewMiningServer(ctx, backend, ethashApi, logger)
 
+	// the mining exec stage publishes the pending block and its logs once, as events: the mining api streams
+	// are fed from them
+	pendingBlockCh, pendingBlockClean := backend.notifications.Events.AddPendingBlockSubscription()
+	pendingLogsCh, pendingLogsClean := backend.notifications.Events.AddPendingLogsSubscription()
+	go func() {
+		defer debug.LogPanic()
+		defer pendingBlockClean()
+		defer pendingLogsClean()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case b := <-pendingBlockCh:
+				if err := miningRPC.(*privateapi.MiningServer).BroadcastPendingBlock(b); err != nil {
+					logger.Error("txpool rpc pending block broadcast", "err", err)
+				}
+			case logs := <-pendingLogsCh:
+				if err := miningRPC.(*privateapi.MiningServer).BroadcastPendingLogs(logs); err != nil {
+					logger.Error("txpool rpc pending logs broadcast", "err", err)
+				}
+			}
+		}
+	}()
+
 	var creds credentials.TransportCredentials
 	if stack.Config().PrivateApiAddr != "" {
 		if stack.Config().TLSConnection {
@@ -869,10 +902,8 @@ func New(ctx context.Context, stack *node.Node, config *ethconfig.Config, logger
 					},
 				})
 
-			case b := <-backend.pendingBlocks:
-				if err := miningRPC.(*privateapi.MiningServer).BroadcastPendingBlock(b); err != nil {
-					logger.Error("txpool rpc pending block broadcast", "err", err)
-				}
+			case <-backend.pendingBlocks:
+				// the mining exec stage already announced the pending block and its logs, see the pending block events
 			case <-backend.sentriesClient.Hd.QuitPoWMining:
 				return
 			}
@@ -1091,8 +1122,23 @@ func (s *Ethereum) Init(stack *node.Node, config *ethconfig.Config, chainConfig
 			return err
 		}
 	}
+	if config.LogExporter != "" {
+		exporterCfg, err := logexporter.ReadConfig(config.LogExporter)
+		if err != nil {
+			return err
+		}
+		exporter, err := logexporter.New(ctx, exporterCfg, chainKv, logexporter.NewChain(blockReader, chainConfig, s.engine), s.logger)
+		if err != nil {
+			return err
+		}
+		var headCh chan [][]byte
+		headCh, s.unsubscribeLogExporter = s.notifications.Events.AddHeaderSubscription()
+		go exporter.Run(ctx, headCh)
+	}
 
-	s.apiList = jsonrpc.APIList(chainKv, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, blockReader, &httpRpcCfg, s.engine, s.logger, s.polygonBridge, s.heimdallService)
+	if s.apiList, err = jsonrpc.APIList(chainKv, ethRpcClient, txPoolRpcClient, miningRpcClient, ff, stateCache, blockReader, &httpRpcCfg, s.engine, s.logger, s.polygonBridge, s.heimdallService); err != nil {
+		return err
+	}
 
 	if config.SilkwormRpcDaemon && httpRpcCfg.Enabled {
 		interface_log_settings := silkworm.RpcInterfaceLogSettings{
@@ -1457,6 +1503,16 @@ func (s *Ethereum) setUpSnapDownloader(ctx context.Context, downloaderCfg *downl
 		s.downloaderClient = direct.NewDownloaderClient(bittorrentServer)
 	}
 
+	if s.config.Snapshot.ScrubInterval > 0 {
+		// report only: the files are open (and mmapped) by the node, moving them away under its feet is not safe,
+		// the repair is left to the `snapshots scrub --repair` command, with the node stopped
+		scrubber := downloader.NewScrubber(downloader.ScrubCfg{
+			Dirs:     s.config.Dirs,
+			ReadRate: s.config.Snapshot.ScrubRate,
+		}, nil, s.logger)
+		go scrubber.Run(ctx, s.config.Snapshot.ScrubInterval)
+	}
+
 	s.agg.OnFreeze(func(frozenFileNames []string) {
 		events := s.notifications.Events
 		events.OnNewSnapshot()
@@ -1643,6 +1699,9 @@ func (s *Ethereum) Stop() error {
 	if s.unsubscribeEthstat != nil {
 		s.unsubscribeEthstat()
 	}
+	if s.unsubscribeLogExporter != nil {
+		s.unsubscribeLogExporter()
+	}
 	if s.downloader != nil {
 		s.downloader.Close()
 	}
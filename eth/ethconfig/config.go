@@ -142,6 +142,8 @@ type BlocksFreezing struct {
 	Verify         bool // verify snapshots on startup
 	DownloaderAddr string
 	ChainName      string
+	ScrubInterval  time.Duration     // scrub snapshot files in background every interval and report the corrupted ones, 0 - disabled
+	ScrubRate      datasize.ByteSize // disk read rate limit of the background scrub
 }
 
 func (s BlocksFreezing) String() string {
@@ -239,6 +241,8 @@ type Config struct {
 
 	// Ethstats service
 	Ethstats string
+	// Path of the log exporter config, see logexporter.Config
+	LogExporter string
 	// Consensus layer
 	InternalCL bool
 
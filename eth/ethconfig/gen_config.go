@@ -52,6 +52,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		PolygonSync                    bool
 		PolygonSyncStage               bool
 		Ethstats                       string
+		LogExporter                    string
 		InternalCL                     bool
 		OverridePragueTime             *big.Int `toml:",omitempty"`
 		SilkwormExecution              bool
@@ -99,6 +100,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.PolygonSync = c.PolygonSync
 	enc.PolygonSyncStage = c.PolygonSyncStage
 	enc.Ethstats = c.Ethstats
+	enc.LogExporter = c.LogExporter
 	enc.InternalCL = c.InternalCL
 	enc.OverridePragueTime = c.OverridePragueTime
 	enc.SilkwormExecution = c.SilkwormExecution
@@ -150,6 +152,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		PolygonSync                    *bool
 		PolygonSyncStage               *bool
 		Ethstats                       *string
+		LogExporter                    *string
 		InternalCL                     *bool
 		OverridePragueTime             *big.Int `toml:",omitempty"`
 		SilkwormExecution              *bool
@@ -260,6 +263,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Ethstats != nil {
 		c.Ethstats = *dec.Ethstats
 	}
+	if dec.LogExporter != nil {
+		c.LogExporter = *dec.LogExporter
+	}
 	if dec.InternalCL != nil {
 		c.InternalCL = *dec.InternalCL
 	}
@@ -35,6 +35,7 @@ import (
 	"github.com/erigontech/erigon/core/rawdb/rawtemporaldb"
 	"github.com/erigontech/erigon/core/state"
 	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/core/vm"
 	"github.com/erigontech/erigon/eth/ethconfig"
 	"github.com/erigontech/erigon/ethdb/prune"
 	"github.com/erigontech/erigon/turbo/services"
@@ -67,13 +68,23 @@ func StageCustomTraceCfg(db kv.TemporalRwDB, prune prune.Mode, dirs datadir.Dirs
 	}
 }
 
+// customTraceProgress returns the first txNums not in files of the receipts and of the trace indices (selectors
+// and failed calls), which are produced by this stage
+func customTraceProgress(tx kv.Tx, stepSize uint64) (receiptsTxNum, tracesTxNum uint64) {
+	ac := tx.(state2.HasAggTx).AggTx().(*state2.AggregatorRoTx)
+	receiptsTxNum = ac.DbgDomain(kv.ReceiptDomain).FirstStepNotInFiles() * stepSize
+	tracesTxNum = min(ac.DbgII(kv.TracesSelectorIdxPos).FirstStepNotInFiles(), ac.DbgII(kv.TracesFailedIdxPos).FirstStepNotInFiles()) * stepSize
+	return receiptsTxNum, tracesTxNum
+}
+
 func SpawnCustomTrace(cfg CustomTraceCfg, ctx context.Context, logger log.Logger) error {
 	var startBlock, endBlock uint64
 	if err := cfg.db.View(ctx, func(tx kv.Tx) (err error) {
 		txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, cfg.execArgs.BlockReader))
 
 		ac := tx.(state2.HasAggTx).AggTx().(*state2.AggregatorRoTx)
-		txNum := ac.DbgDomain(kv.AccountsDomain).FirstStepNotInFiles() * cfg.db.(state2.HasAgg).Agg().(*state2.Aggregator).StepSize()
+		stepSize := cfg.db.(state2.HasAgg).Agg().(*state2.Aggregator).StepSize()
+		txNum := ac.DbgDomain(kv.AccountsDomain).FirstStepNotInFiles() * stepSize
 		var ok bool
 		ok, endBlock, err = txNumsReader.FindBlockNum(tx, txNum)
 		if err != nil {
@@ -83,8 +94,8 @@ func SpawnCustomTrace(cfg CustomTraceCfg, ctx context.Context, logger log.Logger
 			panic(ok)
 		}
 
-		txNum = ac.DbgDomain(kv.ReceiptDomain).FirstStepNotInFiles() * cfg.db.(state2.HasAgg).Agg().(*state2.Aggregator).StepSize()
-		ok, startBlock, err = txNumsReader.FindBlockNum(tx, txNum)
+		receiptsTxNum, tracesTxNum := customTraceProgress(tx, stepSize)
+		ok, startBlock, err = txNumsReader.FindBlockNum(tx, min(receiptsTxNum, tracesTxNum))
 		if err != nil {
 			return fmt.Errorf("getting last executed block: %w", err)
 		}
@@ -107,6 +118,7 @@ func SpawnCustomTrace(cfg CustomTraceCfg, ctx context.Context, logger log.Logger
 }
 
 func customTraceBatchProduce(ctx context.Context, cfg *exec3.ExecArgs, db kv.RwDB, fromBlock, toBlock uint64, logPrefix string, logger log.Logger) error {
+	agg := db.(state2.HasAgg).Agg().(*state2.Aggregator)
 	var lastTxNum uint64
 	if err := db.Update(ctx, func(tx kv.RwTx) error {
 		ttx := tx.(kv.TemporalRwTx)
@@ -116,7 +128,9 @@ func customTraceBatchProduce(ctx context.Context, cfg *exec3.ExecArgs, db kv.RwD
 		}
 		defer doms.Close()
 
-		if err := customTraceBatch(ctx, cfg, ttx, doms, fromBlock, toBlock, logPrefix, logger); err != nil {
+		// only write what is not in files yet
+		receiptsTxNum, tracesTxNum := customTraceProgress(tx, agg.StepSize())
+		if err := customTraceBatch(ctx, cfg, ttx, doms, fromBlock, toBlock, receiptsTxNum, tracesTxNum, logPrefix, logger); err != nil {
 			return err
 		}
 		doms.SetTx(tx)
@@ -131,14 +145,13 @@ func customTraceBatchProduce(ctx context.Context, cfg *exec3.ExecArgs, db kv.RwD
 	}); err != nil {
 		return err
 	}
-	agg := db.(state2.HasAgg).Agg().(*state2.Aggregator)
 	var fromStep, toStep uint64
 	if lastTxNum/agg.StepSize() > 0 {
 		toStep = lastTxNum / agg.StepSize()
 	}
 	if err := db.View(ctx, func(tx kv.Tx) error {
-		ac := tx.(state2.HasAggTx).AggTx().(*state2.AggregatorRoTx)
-		fromStep = ac.DbgDomain(kv.ReceiptDomain).FirstStepNotInFiles()
+		receiptsTxNum, tracesTxNum := customTraceProgress(tx, agg.StepSize())
+		fromStep = min(receiptsTxNum, tracesTxNum) / agg.StepSize()
 		return nil
 	}); err != nil {
 		return err
@@ -159,7 +172,7 @@ func customTraceBatchProduce(ctx context.Context, cfg *exec3.ExecArgs, db kv.RwD
 	return nil
 }
 
-func customTraceBatch(ctx context.Context, cfg *exec3.ExecArgs, tx kv.TemporalRwTx, doms *state2.SharedDomains, fromBlock, toBlock uint64, logPrefix string, logger log.Logger) error {
+func customTraceBatch(ctx context.Context, cfg *exec3.ExecArgs, tx kv.TemporalRwTx, doms *state2.SharedDomains, fromBlock, toBlock, receiptsFromTxNum, tracesFromTxNum uint64, logPrefix string, logger log.Logger) error {
 	const logPeriod = 5 * time.Second
 	logEvery := time.NewTicker(logPeriod)
 	defer logEvery.Stop()
@@ -172,7 +185,7 @@ func customTraceBatch(ctx context.Context, cfg *exec3.ExecArgs, tx kv.TemporalRw
 	var prevTxNumLog = fromBlock
 	var m runtime.MemStats
 	if err := exec3.CustomTraceMapReduce(fromBlock, toBlock, exec3.TraceConsumer{
-		NewTracer: func() exec3.GenericTracer { return nil },
+		NewTracer: func() vm.EVMLogger { return exec3.NewCallTracer() },
 		Reduce: func(txTask *state.TxTask, tx kv.Tx) error {
 			if txTask.Error != nil {
 				return txTask.Error
@@ -194,7 +207,7 @@ func customTraceBatch(ctx context.Context, cfg *exec3.ExecArgs, tx kv.TemporalRw
 
 			doms.SetTx(tx)
 			doms.SetTxNum(txTask.TxNum)
-			if !txTask.Final {
+			if !txTask.Final && txTask.TxNum >= receiptsFromTxNum {
 				var receipt *types.Receipt
 				if txTask.TxIndex >= 0 && !txTask.Final {
 					receipt = txTask.BlockReceipts[txTask.TxIndex]
@@ -203,6 +216,11 @@ func customTraceBatch(ctx context.Context, cfg *exec3.ExecArgs, tx kv.TemporalRw
 					return err
 				}
 			}
+			if txTask.TxNum >= tracesFromTxNum {
+				if err := state.ApplyTraceIndices(txTask, doms); err != nil {
+					return err
+				}
+			}
 
 			if txTask.Final { // block changed
 				cumulativeBlobGasUsedInBlock = 0
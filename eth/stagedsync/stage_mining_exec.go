@@ -45,6 +45,7 @@ import (
 	"github.com/erigontech/erigon/params"
 	"github.com/erigontech/erigon/turbo/services"
 	"github.com/erigontech/erigon/txnprovider"
+	"github.com/erigontech/erigon/txnprovider/bundle"
 )
 
 type MiningExecCfg struct {
@@ -59,6 +60,8 @@ type MiningExecCfg struct {
 	interrupt   *int32
 	payloadId   uint64
 	txnProvider txnprovider.TxnProvider
+	// orderingPolicy of the yielded transactions, nil to keep the order of txnProvider
+	orderingPolicy txnprovider.OrderingPolicy
 }
 
 func StageMiningExecCfg(
@@ -74,6 +77,10 @@ func StageMiningExecCfg(
 	txnProvider txnprovider.TxnProvider,
 	blockReader services.FullBlockReader,
 ) MiningExecCfg {
+	orderingPolicy, err := txnprovider.NewOrderingPolicy(miningState.MiningConfig.TxnOrdering, miningState.MiningConfig.PrioritySenders)
+	if err != nil {
+		panic(err) // rejected at config time, see eth.New
+	}
 	return MiningExecCfg{
 		db:          db,
 		miningState: miningState,
@@ -86,6 +93,8 @@ func StageMiningExecCfg(
 		interrupt:   interrupt,
 		payloadId:   payloadId,
 		txnProvider: txnProvider,
+
+		orderingPolicy: orderingPolicy,
 	}
 }
 
@@ -124,11 +133,10 @@ func SpawnMiningExecStage(s *StageState, txc wrap.TxContainer, cfg MiningExecCfg
 	if noempty {
 
 		if len(preparedTxns) > 0 {
-			logs, _, err := addTransactionsToMiningBlock(ctx, logPrefix, current, cfg.chainConfig, cfg.vmConfig, getHeader, cfg.engine, preparedTxns, cfg.miningState.MiningConfig.Etherbase, ibs, cfg.interrupt, cfg.payloadId, logger)
+			_, _, err := addTransactionsToMiningBlock(ctx, logPrefix, current, cfg.chainConfig, cfg.vmConfig, getHeader, cfg.engine, preparedTxns, nil /* bundles */, cfg.miningState.MiningConfig.Etherbase, ibs, cfg.interrupt, cfg.payloadId, logger)
 			if err != nil {
 				return err
 			}
-			NotifyPendingLogs(logPrefix, cfg.notifier, logs, logger)
 		} else {
 
 			yielded := mapset.NewSet[[32]byte]()
@@ -158,11 +166,11 @@ func SpawnMiningExecStage(s *StageState, txc wrap.TxContainer, cfg MiningExecCfg
 				}
 
 				if len(txns) > 0 {
-					logs, stop, err := addTransactionsToMiningBlock(ctx, logPrefix, current, cfg.chainConfig, cfg.vmConfig, getHeader, cfg.engine, txns, cfg.miningState.MiningConfig.Etherbase, ibs, cfg.interrupt, cfg.payloadId, logger)
+					bundles, _ := cfg.txnProvider.(txnprovider.BundleProvider)
+					_, stop, err := addTransactionsToMiningBlock(ctx, logPrefix, current, cfg.chainConfig, cfg.vmConfig, getHeader, cfg.engine, txns, bundles, cfg.miningState.MiningConfig.Etherbase, ibs, cfg.interrupt, cfg.payloadId, logger)
 					if err != nil {
 						return err
 					}
-					NotifyPendingLogs(logPrefix, cfg.notifier, logs, logger)
 					if stop {
 						break
 					}
@@ -245,6 +253,7 @@ func SpawnMiningExecStage(s *StageState, txc wrap.TxContainer, cfg MiningExecCfg
 		return fmt.Errorf("StateV3.Apply: %w", err)
 	}
 	current.Header.Root = libcommon.BytesToHash(rh)
+	NotifyPendingBlock(logPrefix, cfg.notifier, block.WithSeal(current.Header), current.Receipts, logger)
 
 	logger.Info("FinalizeBlockExecution", "block", current.Header.Number, "txn", current.Txns.Len(), "gas", current.Header.GasUsed, "receipt", current.Receipts.Len(), "payload", cfg.payloadId)
 
@@ -275,9 +284,19 @@ func getNextTransactions(
 		txnprovider.WithGasTarget(remainingGas),
 		txnprovider.WithBlobGasTarget(remainingBlobGas),
 		txnprovider.WithTxnIdsFilter(alreadyYielded),
+		txnprovider.WithBlockTime(header.Time),
+	}
+	if header.BaseFee != nil {
+		yieldOpts = append(yieldOpts, txnprovider.WithBaseFee(uint256.MustFromBig(header.BaseFee)))
 	}
 
-	txns, err := cfg.txnProvider.Yield(ctx, yieldOpts...)
+	var txns []types.Transaction
+	var err error
+	if cfg.orderingPolicy != nil {
+		txns, err = txnprovider.YieldWithPolicy(ctx, cfg.txnProvider, cfg.orderingPolicy, yieldOpts...)
+	} else {
+		txns, err = cfg.txnProvider.Yield(ctx, yieldOpts...)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -438,6 +457,7 @@ func addTransactionsToMiningBlock(
 	getHeader func(hash libcommon.Hash, number uint64) *types.Header,
 	engine consensus.Engine,
 	txns types.Transactions,
+	bundles txnprovider.BundleProvider,
 	coinbase libcommon.Address,
 	ibs *state.IntraBlockState,
 	interrupt *int32,
@@ -472,6 +492,53 @@ func addTransactionsToMiningBlock(
 		return receipt.Logs, nil
 	}
 
+	// simulateBundle executes the transactions of a bundle on a throwaway state layered on top of ibs, leaving ibs,
+	// the gas pool and the header untouched. It fails if one of them fails, or reverts without being allowed to.
+	var simulateBundle = func(b *bundle.Bundle, bundleTxns []types.Transaction) error {
+		simIbs := state.New(state.NewIntraBlockStateReader(ibs))
+		simGasPool := new(core.GasPool).AddGas(gasPool.Gas()).AddBlobGas(gasPool.BlobGas())
+		simGasUsed := header.GasUsed
+		var simBlobGasUsed *uint64
+		if header.BlobGasUsed != nil {
+			blobGasUsed := *header.BlobGasUsed
+			simBlobGasUsed = &blobGasUsed
+		}
+		simVmConfig := *vmConfig
+		simVmConfig.Debug, simVmConfig.Tracer = false, nil
+		for i, txn := range bundleTxns {
+			simIbs.SetTxContext(txnIdx + i)
+			receipt, _, err := core.ApplyTransaction(&chainConfig, core.GetHashFn(header, getHeader), engine, &coinbase, simGasPool, simIbs, noop, header, txn, &simGasUsed, simBlobGasUsed, simVmConfig)
+			if err != nil {
+				return fmt.Errorf("transaction %x: %w", txn.Hash(), err)
+			}
+			if receipt.Status == types.ReceiptStatusFailed && !b.CanRevert(txn.Hash()) {
+				return fmt.Errorf("transaction %x reverted", txn.Hash())
+			}
+		}
+		return nil
+	}
+
+	// miningCommitBundle includes the transactions of a bundle atomically: the bundle is simulated first, and only
+	// committed to ibs if none of its transactions fails, or reverts without being allowed to. Reverting ibs once
+	// a transaction is finalized is not possible, hence the simulation.
+	var miningCommitBundle = func(b *bundle.Bundle, bundleTxns []types.Transaction) ([]*types.Log, error) {
+		if err := simulateBundle(b, bundleTxns); err != nil {
+			return nil, err
+		}
+		var logs []*types.Log
+		for _, txn := range bundleTxns {
+			txnLogs, err := miningCommitTx(txn, coinbase, vmConfig, chainConfig, ibs, current)
+			if err != nil {
+				// the simulation ran against the very same state, so this is not expected: the transactions
+				// committed so far stay in the block, which remains valid
+				return logs, fmt.Errorf("transaction %x failed after a successful bundle simulation: %w", txn.Hash(), err)
+			}
+			logs = append(logs, txnLogs...)
+			txnIdx++
+		}
+		return logs, nil
+	}
+
 	var stopped *time.Ticker
 	defer func() {
 		if stopped != nil {
@@ -482,7 +549,8 @@ func addTransactionsToMiningBlock(
 	done := false
 
 LOOP:
-	for _, txn := range txns {
+	for i := 0; i < len(txns); i++ {
+		txn := txns[i]
 		// see if we need to stop now
 		if stopped != nil {
 			select {
@@ -509,6 +577,34 @@ LOOP:
 			break
 		}
 
+		if bundles != nil {
+			if b, ok := bundles.BundleOf(txn.Hash()); ok {
+				// the transactions of a bundle are yielded next to each other
+				end := i + 1
+				for end < len(txns) {
+					if next, ok := bundles.BundleOf(txns[end].Hash()); !ok || next != b {
+						break
+					}
+					end++
+				}
+				bundleTxns := txns[i:end]
+				i = end - 1
+				if len(bundleTxns) != len(b.Txns) {
+					logger.Debug(fmt.Sprintf("[%s] Skipping incomplete bundle", logPrefix), "hash", b.Hash(), "have", len(bundleTxns), "want", len(b.Txns))
+					continue
+				}
+				logs, err := miningCommitBundle(b, bundleTxns)
+				coalescedLogs = append(coalescedLogs, logs...)
+				if err != nil {
+					logger.Debug(fmt.Sprintf("[%s] Dropping bundle", logPrefix), "hash", b.Hash(), "err", err)
+					bundles.DropBundle(b.Hash(), err)
+					continue
+				}
+				logger.Trace(fmt.Sprintf("[%s] Added bundle", logPrefix), "hash", b.Hash(), "txns", len(bundleTxns), "payload", payloadId)
+				continue
+			}
+		}
+
 		// We use the eip155 signer regardless of the env hf.
 		from, err := txn.Sender(*signer)
 		if err != nil {
@@ -557,14 +653,20 @@ LOOP:
 
 }
 
-func NotifyPendingLogs(logPrefix string, notifier ChainEventNotifier, logs types.Logs, logger log.Logger) {
-	if len(logs) == 0 {
-		return
-	}
-
+// NotifyPendingBlock publishes the pending block and all its logs. It is called every time the pending block is
+// rebuilt, and the logs replace the ones of the previous rebuild: they are sent even when empty, for the
+// subscribers to drop the logs which are no longer pending.
+func NotifyPendingBlock(logPrefix string, notifier ChainEventNotifier, block *types.Block, receipts types.Receipts, logger log.Logger) {
 	if notifier == nil {
-		logger.Debug(fmt.Sprintf("[%s] rpc notifier is not set, rpc daemon won't be updated about pending logs", logPrefix))
+		logger.Debug(fmt.Sprintf("[%s] rpc notifier is not set, rpc daemon won't be updated about pending block", logPrefix))
 		return
 	}
+	logs := types.Logs{}
+	for _, receipt := range receipts {
+		if receipt != nil {
+			logs = append(logs, receipt.Logs...)
+		}
+	}
+	notifier.OnNewPendingBlock(block)
 	notifier.OnNewPendingLogs(logs)
 }
@@ -0,0 +1,152 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package stagedsync
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/types/accounts"
+
+	"github.com/erigontech/erigon/consensus/ethash"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/txnprovider"
+	"github.com/erigontech/erigon/txnprovider/bundle"
+)
+
+// mapStateReader serves accounts and code from memory
+type mapStateReader struct {
+	accounts map[libcommon.Address]*accounts.Account
+	code     map[libcommon.Address][]byte
+}
+
+func (r *mapStateReader) ReadAccountData(address libcommon.Address) (*accounts.Account, error) {
+	if a, ok := r.accounts[address]; ok {
+		account := *a
+		return &account, nil
+	}
+	return nil, nil
+}
+
+func (r *mapStateReader) ReadAccountDataForDebug(address libcommon.Address) (*accounts.Account, error) {
+	return r.ReadAccountData(address)
+}
+
+func (r *mapStateReader) ReadAccountStorage(libcommon.Address, uint64, *libcommon.Hash) ([]byte, error) {
+	return nil, nil
+}
+
+func (r *mapStateReader) ReadAccountCode(address libcommon.Address, _ uint64) ([]byte, error) {
+	return r.code[address], nil
+}
+
+func (r *mapStateReader) ReadAccountCodeSize(address libcommon.Address, _ uint64) (int, error) {
+	return len(r.code[address]), nil
+}
+
+func (r *mapStateReader) ReadAccountIncarnation(libcommon.Address) (uint64, error) {
+	return 0, nil
+}
+
+func TestMiningBundleWithRevertingTxn(t *testing.T) {
+	t.Parallel()
+	chainConfig := *params.TestChainConfig
+	signer := types.MakeSigner(&chainConfig, 1, 0)
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	otherKey, _ := crypto.GenerateKey()
+	other := crypto.PubkeyToAddress(otherKey.PublicKey)
+	recipient := libcommon.HexToAddress("0xbeef")
+	// PUSH1 0 PUSH1 0 REVERT
+	reverter := libcommon.HexToAddress("0xdead")
+	reverterCode := []byte{0x60, 0x00, 0x60, 0x00, 0xfd}
+
+	newState := func() *state.IntraBlockState {
+		funded := uint256.NewInt(params.Ether)
+		return state.New(&mapStateReader{
+			accounts: map[libcommon.Address]*accounts.Account{
+				sender:   {Initialised: true, Balance: *funded},
+				other:    {Initialised: true, Balance: *funded},
+				reverter: {Initialised: true, CodeHash: crypto.Keccak256Hash(reverterCode)},
+			},
+			code: map[libcommon.Address][]byte{reverter: reverterCode},
+		})
+	}
+	sign := func(t *testing.T, prv *ecdsa.PrivateKey, nonce uint64, to libcommon.Address, value uint64) types.Transaction {
+		txn, err := types.SignTx(types.NewTransaction(nonce, to, uint256.NewInt(value), 100_000, uint256.NewInt(1), nil), *signer, prv)
+		require.NoError(t, err)
+		_, err = txn.Sender(*signer)
+		require.NoError(t, err)
+		return txn
+	}
+
+	for _, canRevert := range []bool{false, true} {
+		canRevert := canRevert
+		name := "reverting bundle is dropped"
+		if canRevert {
+			name = "allowed revert is included"
+		}
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			transfer := sign(t, key, 0, recipient, 1)
+			revert := sign(t, key, 1, reverter, 0)
+			unrelated := sign(t, otherKey, 0, recipient, 1)
+			b := &bundle.Bundle{Txns: []types.Transaction{transfer, revert}, BlockNumber: 1}
+			if canRevert {
+				b.RevertingTxnHashes = map[libcommon.Hash]struct{}{revert.Hash(): {}}
+			}
+			bundlePool := bundle.NewPool(log.New())
+			_, err := bundlePool.Add(b)
+			require.NoError(t, err)
+
+			ibs := newState()
+			current := &MiningBlock{Header: &types.Header{Number: big.NewInt(1), GasLimit: 30_000_000, Difficulty: big.NewInt(1)}}
+			_, _, err = addTransactionsToMiningBlock(context.Background(), "test", current, chainConfig, &vm.Config{}, func(libcommon.Hash, uint64) *types.Header { return nil },
+				ethash.NewFaker(), types.Transactions{transfer, revert, unrelated}, txnprovider.NewBundleTxnProvider(bundlePool), libcommon.Address{}, ibs, nil, 0, log.New())
+			require.NoError(t, err)
+
+			nonce, err := ibs.GetNonce(sender)
+			require.NoError(t, err)
+			balance, err := ibs.GetBalance(recipient)
+			require.NoError(t, err)
+			if canRevert {
+				require.Equal(t, types.Transactions{transfer, revert, unrelated}, current.Txns)
+				require.Equal(t, types.ReceiptStatusFailed, current.Receipts[1].Status)
+				require.Equal(t, uint64(2), nonce)
+				require.Equal(t, uint64(2), balance.Uint64())
+				require.Equal(t, 1, bundlePool.Len())
+			} else {
+				// the bundle leaves no trace, and block building goes on
+				require.Equal(t, types.Transactions{unrelated}, current.Txns)
+				require.Equal(t, uint64(0), nonce)
+				require.Equal(t, uint64(1), balance.Uint64())
+				require.Equal(t, 0, bundlePool.Len())
+			}
+		})
+	}
+}
@@ -31,6 +31,7 @@ import (
 type ChainEventNotifier interface {
 	OnNewHeader(newHeadersRlp [][]byte)
 	OnNewPendingLogs(types.Logs)
+	OnNewPendingBlock(*types.Block)
 	OnLogs([]*remote.SubscribeLogsReply)
 	HasLogSubsriptions() bool
 }
@@ -0,0 +1,131 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/dir"
+	"github.com/erigontech/erigon/consensus"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/core/vm/evmtypes"
+	"github.com/erigontech/erigon/eth/tracers"
+	_ "github.com/erigontech/erigon/eth/tracers/native"
+	"github.com/erigontech/erigon/tests"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+// rawTracerTest defines a single test to check a tracer producing
+// an arbitrary json result against.
+type rawTracerTest struct {
+	Genesis      *types.Genesis  `json:"genesis"`
+	Context      *callContext    `json:"context"`
+	Input        string          `json:"input"`
+	TracerConfig json.RawMessage `json:"tracerConfig"`
+	Result       json.RawMessage `json:"result"`
+}
+
+func TestFlatCallTracerNative(t *testing.T) {
+	testRawTracer("flatCallTracer", "call_tracer_flat", t)
+}
+
+func TestErc7562TracerNative(t *testing.T) {
+	testRawTracer("erc7562Tracer", "erc7562_tracer", t)
+}
+
+func testRawTracer(tracerName string, dirPath string, t *testing.T) {
+	files, err := dir.ReadDir(filepath.Join("testdata", dirPath))
+	if err != nil {
+		t.Fatalf("failed to retrieve tracer test suite: %v", err)
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(camel(strings.TrimSuffix(file.Name(), ".json")), func(t *testing.T) {
+			t.Parallel()
+
+			test := new(rawTracerTest)
+			if blob, err := os.ReadFile(filepath.Join("testdata", dirPath, file.Name())); err != nil {
+				t.Fatalf("failed to read testcase: %v", err)
+			} else if err := json.Unmarshal(blob, test); err != nil {
+				t.Fatalf("failed to parse testcase: %v", err)
+			}
+			res := runRawTracer(t, tracerName, test)
+			require.JSONEq(t, string(test.Result), string(res))
+		})
+	}
+}
+
+func runRawTracer(t *testing.T, tracerName string, test *rawTracerTest) json.RawMessage {
+	tx, err := types.UnmarshalTransactionFromBinary(libcommon.FromHex(test.Input), false /* blobTxnsAreWrappedWithBlobs */)
+	if err != nil {
+		t.Fatalf("failed to parse testcase input: %v", err)
+	}
+	signer := types.MakeSigner(test.Genesis.Config, uint64(test.Context.Number), uint64(test.Context.Time))
+	context := evmtypes.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    consensus.Transfer,
+		Coinbase:    test.Context.Miner,
+		BlockNumber: uint64(test.Context.Number),
+		Time:        uint64(test.Context.Time),
+		Difficulty:  (*big.Int)(test.Context.Difficulty),
+		GasLimit:    uint64(test.Context.GasLimit),
+	}
+	if test.Context.BaseFee != nil {
+		context.BaseFee, _ = uint256.FromBig((*big.Int)(test.Context.BaseFee))
+	}
+	rules := test.Genesis.Config.Rules(context.BlockNumber, context.Time)
+
+	m := mock.Mock(t)
+	dbTx, err := m.DB.BeginRw(m.Ctx)
+	require.NoError(t, err)
+	defer dbTx.Rollback()
+	statedb, err := tests.MakePreState(rules, dbTx, test.Genesis.Alloc, uint64(test.Context.Number))
+	require.NoError(t, err)
+	tracer, err := tracers.New(tracerName, &tracers.Context{TxHash: tx.Hash()}, test.TracerConfig)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", tracerName, err)
+	}
+	msg, err := tx.AsMessage(*signer, (*big.Int)(test.Context.BaseFee), rules)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction for tracing: %v", err)
+	}
+	txContext := core.NewEVMTxContext(msg)
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+	_, err = core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.GetGas()).AddBlobGas(tx.GetBlobGas()), true /* refunds */, false /* gasBailout */)
+	if err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	return res
+}
@@ -0,0 +1,343 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/holiman/uint256"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/eth/tracers"
+)
+
+func init() {
+	register("flatCallTracer", newFlatCallTracer)
+}
+
+var parityErrorMapping = map[string]string{
+	"contract creation code storage out of gas": "Out of gas",
+	"out of gas":                      "Out of gas",
+	"gas uint64 overflow":             "Out of gas",
+	"max code size exceeded":          "Out of gas",
+	"invalid jump destination":        "Bad jump destination",
+	"execution reverted":              "Reverted",
+	"return data out of bounds":       "Out of bounds",
+	"stack limit reached 1024 (1023)": "Out of stack",
+	"precompiled failed":              "Built-in failed",
+	"invalid input length":            "Built-in failed",
+}
+
+var parityErrorMappingStartingWith = map[string]string{
+	"invalid opcode:": "Bad instruction",
+	"stack underflow": "Stack underflow",
+}
+
+// flatCallFrame is a standalone callframe in the Parity (OpenEthereum) trace format.
+type flatCallFrame struct {
+	Action              flatCallAction  `json:"action"`
+	BlockHash           *libcommon.Hash `json:"blockHash"`
+	BlockNumber         uint64          `json:"blockNumber"`
+	Error               string          `json:"error,omitempty"`
+	Result              *flatCallResult `json:"result,omitempty"`
+	Subtraces           int             `json:"subtraces"`
+	TraceAddress        []int           `json:"traceAddress"`
+	TransactionHash     *libcommon.Hash `json:"transactionHash"`
+	TransactionPosition uint64          `json:"transactionPosition"`
+	Type                string          `json:"type"`
+}
+
+type flatCallAction struct {
+	SelfDestructed *libcommon.Address `json:"address,omitempty"`
+	Balance        *hexutil.Big       `json:"balance,omitempty"`
+	CallType       string             `json:"callType,omitempty"`
+	From           *libcommon.Address `json:"from,omitempty"`
+	Gas            *hexutil.Uint64    `json:"gas,omitempty"`
+	Init           *hexutility.Bytes  `json:"init,omitempty"`
+	Input          *hexutility.Bytes  `json:"input,omitempty"`
+	RefundAddress  *libcommon.Address `json:"refundAddress,omitempty"`
+	To             *libcommon.Address `json:"to,omitempty"`
+	Value          *hexutil.Big       `json:"value,omitempty"`
+}
+
+type flatCallResult struct {
+	Address *libcommon.Address `json:"address,omitempty"`
+	Code    *hexutility.Bytes  `json:"code,omitempty"`
+	GasUsed *hexutil.Uint64    `json:"gasUsed,omitempty"`
+	Output  *hexutility.Bytes  `json:"output,omitempty"`
+}
+
+// flatCallTracer reports call frames of a transaction in the flat Parity format.
+// It is built on top of callTracer: the nested call frames are collected by the
+// wrapped tracer and flattened once the transaction is finished.
+type flatCallTracer struct {
+	tracer      *callTracer
+	config      flatCallTracerConfig
+	ctx         *tracers.Context // Holds tracer context data
+	blockNumber uint64
+	gas         uint64 // gas available to the top call, intrinsic gas excluded
+	gasUsed     uint64 // gas used by the top call, intrinsic gas and refunds excluded
+	reason      error  // Textual reason for the interruption
+}
+
+type flatCallTracerConfig struct {
+	ConvertParityErrors bool `json:"convertParityErrors"` // If true, call tracer converts errors to parity format
+	IncludePrecompiles  bool `json:"includePrecompiles"`  // If true, call tracer includes calls to precompiled contracts
+}
+
+// newFlatCallTracer returns a new flatCallTracer.
+func newFlatCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config flatCallTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parity traces don't include calls to precompiles, so they are skipped
+	// by the wrapped call tracer unless explicitly requested.
+	tracer := &callTracer{
+		callstack: make([]callFrame, 1),
+		config:    callTracerConfig{IncludePrecompiles: config.IncludePrecompiles},
+	}
+	return &flatCallTracer{tracer: tracer, ctx: ctx, config: config}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *flatCallTracer) CaptureStart(env *vm.EVM, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.blockNumber = env.Context.BlockNumber
+	t.gas = gas
+	// The top call is always reported, even if it targets a precompile
+	t.tracer.CaptureStart(env, from, to, false /* precompile */, create, input, gas, value, code)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *flatCallTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.gasUsed = gasUsed
+	t.tracer.CaptureEnd(output, gasUsed, err)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *flatCallTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+// CaptureFault implements the EVMLogger interface to trace an execution fault.
+func (t *flatCallTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *flatCallTracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	// Child calls must have a value, even if it's zero.
+	// Practically speaking, only STATICCALL has nil value. Set it to zero.
+	if value == nil {
+		value = new(uint256.Int)
+	}
+	t.tracer.CaptureEnter(typ, from, to, precompile, create, input, gas, value, code)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *flatCallTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.tracer.CaptureExit(output, gasUsed, err)
+}
+
+func (t *flatCallTracer) CaptureTxStart(gasLimit uint64) {
+	t.tracer.CaptureTxStart(gasLimit)
+}
+
+func (t *flatCallTracer) CaptureTxEnd(restGas uint64) {
+	t.tracer.CaptureTxEnd(restGas)
+	if len(t.tracer.callstack) > 0 {
+		// Parity reports the gas of the top call without the intrinsic gas
+		t.tracer.callstack[0].Gas = t.gas
+		t.tracer.callstack[0].GasUsed = t.gasUsed
+	}
+}
+
+// GetResult returns the json-encoded list of flat call frames, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *flatCallTracer) GetResult() (json.RawMessage, error) {
+	if len(t.tracer.callstack) < 1 {
+		return nil, errors.New("invalid number of calls")
+	}
+
+	flat, err := t.flatFromNested(&t.tracer.callstack[0], []int{})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := json.Marshal(flat)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *flatCallTracer) Stop(err error) {
+	t.tracer.Stop(err)
+	t.reason = err
+}
+
+func (t *flatCallTracer) flatFromNested(input *callFrame, traceAddress []int) (output []flatCallFrame, err error) {
+	var frame *flatCallFrame
+	switch input.Type {
+	case vm.CREATE, vm.CREATE2:
+		frame = newFlatCreate(input)
+	case vm.SELFDESTRUCT:
+		frame = newFlatSelfdestruct(input)
+	case vm.CALL, vm.STATICCALL, vm.CALLCODE, vm.DELEGATECALL:
+		frame = newFlatCall(input)
+	default:
+		return nil, fmt.Errorf("unrecognized call frame type: %s", input.Type)
+	}
+
+	frame.Error = input.Error
+	if t.config.ConvertParityErrors {
+		frame.Error = convertErrorToParity(frame.Error)
+	}
+
+	// Revert output contains useful information (revert reason).
+	// Otherwise discard result.
+	if input.Error != "" && input.Error != vm.ErrExecutionReverted.Error() {
+		frame.Result = nil
+	}
+
+	t.fillCallFrameFromContext(frame)
+	frame.TraceAddress = traceAddress
+	frame.Subtraces = len(input.Calls)
+
+	output = append(output, *frame)
+	for i := range input.Calls {
+		childAddr := childTraceAddress(traceAddress, i)
+		flat, err := t.flatFromNested(&input.Calls[i], childAddr)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, flat...)
+	}
+	return output, nil
+}
+
+func (t *flatCallTracer) fillCallFrameFromContext(frame *flatCallFrame) {
+	frame.BlockNumber = t.blockNumber
+	if t.ctx == nil {
+		return
+	}
+	if t.ctx.BlockHash != (libcommon.Hash{}) {
+		frame.BlockHash = &t.ctx.BlockHash
+	}
+	if t.ctx.TxHash != (libcommon.Hash{}) {
+		frame.TransactionHash = &t.ctx.TxHash
+	}
+	frame.TransactionPosition = uint64(t.ctx.TxIndex)
+}
+
+func newFlatCreate(input *callFrame) *flatCallFrame {
+	var (
+		from       = input.From
+		to         = input.To
+		actionInit = hexutility.Bytes(input.Input)
+		resultCode = hexutility.Bytes(input.Output)
+		gas        = hexutil.Uint64(input.Gas)
+		gasUsed    = hexutil.Uint64(input.GasUsed)
+	)
+	return &flatCallFrame{
+		Type: strings.ToLower(vm.CREATE.String()),
+		Action: flatCallAction{
+			From:  &from,
+			Gas:   &gas,
+			Value: (*hexutil.Big)(input.Value),
+			Init:  &actionInit,
+		},
+		Result: &flatCallResult{
+			GasUsed: &gasUsed,
+			Address: &to,
+			Code:    &resultCode,
+		},
+	}
+}
+
+func newFlatCall(input *callFrame) *flatCallFrame {
+	var (
+		from         = input.From
+		to           = input.To
+		actionInput  = hexutility.Bytes(input.Input)
+		resultOutput = hexutility.Bytes(input.Output)
+		gas          = hexutil.Uint64(input.Gas)
+		gasUsed      = hexutil.Uint64(input.GasUsed)
+	)
+	return &flatCallFrame{
+		Type: strings.ToLower(vm.CALL.String()),
+		Action: flatCallAction{
+			From:     &from,
+			To:       &to,
+			Gas:      &gas,
+			Value:    (*hexutil.Big)(input.Value),
+			CallType: strings.ToLower(input.Type.String()),
+			Input:    &actionInput,
+		},
+		Result: &flatCallResult{
+			GasUsed: &gasUsed,
+			Output:  &resultOutput,
+		},
+	}
+}
+
+func newFlatSelfdestruct(input *callFrame) *flatCallFrame {
+	var (
+		from = input.From
+		to   = input.To
+	)
+	return &flatCallFrame{
+		Type: "suicide",
+		Action: flatCallAction{
+			SelfDestructed: &from,
+			Balance:        (*hexutil.Big)(input.Value),
+			RefundAddress:  &to,
+		},
+	}
+}
+
+func convertErrorToParity(err string) string {
+	if err == "" {
+		return ""
+	}
+	if openEthereumErr, ok := parityErrorMapping[err]; ok {
+		return openEthereumErr
+	}
+	for gethErrPrefix, openEthereumErr := range parityErrorMappingStartingWith {
+		if strings.HasPrefix(err, gethErrPrefix) {
+			return openEthereumErr
+		}
+	}
+	return err
+}
+
+func childTraceAddress(a []int, i int) []int {
+	child := make([]int, 0, len(a)+1)
+	child = append(child, a...)
+	child = append(child, i)
+	return child
+}
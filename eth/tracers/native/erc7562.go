@@ -0,0 +1,412 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync/atomic"
+
+	"github.com/holiman/uint256"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/eth/tracers"
+)
+
+func init() {
+	register("erc7562Tracer", newErc7562Tracer)
+}
+
+// maxKeccakPreimageSize caps the size of a single recorded KECCAK256 preimage
+const maxKeccakPreimageSize = 1 << 16
+
+type contractSizeWithOpcode struct {
+	ContractSize int       `json:"contractSize"`
+	Opcode       vm.OpCode `json:"opcode"`
+}
+
+// accessedSlots holds the storage slots accessed by a call frame. Reads hold the value
+// of a slot at the time of its first access, unless it was written before being read.
+type accessedSlots struct {
+	Reads           map[string][]string `json:"reads"`
+	Writes          map[string]uint64   `json:"writes"`
+	TransientReads  map[string]uint64   `json:"transientReads"`
+	TransientWrites map[string]uint64   `json:"transientWrites"`
+}
+
+// callFrameWithOpcodes is a call frame extended with the data required
+// to check the ERC-7562 validation rules of account abstraction.
+type callFrameWithOpcodes struct {
+	Type         vm.OpCode                                     `json:"-"`
+	From         libcommon.Address                             `json:"from"`
+	Gas          hexutil.Uint64                                `json:"gas"`
+	GasUsed      hexutil.Uint64                                `json:"gasUsed"`
+	To           *libcommon.Address                            `json:"to,omitempty"`
+	Input        hexutility.Bytes                              `json:"input"`
+	Output       hexutility.Bytes                              `json:"output,omitempty"`
+	Error        string                                        `json:"error,omitempty"`
+	RevertReason string                                        `json:"revertReason,omitempty"`
+	Logs         []callLog                                     `json:"logs,omitempty"`
+	Value        *hexutil.Big                                  `json:"value,omitempty"`
+	TypeString   string                                        `json:"type"`
+	Accessed     accessedSlots                                 `json:"accessedSlots"`
+	ExtCodeInfo  []libcommon.Address                           `json:"extCodeAccessInfo"`
+	UsedOpcodes  map[hexutil.Uint64]uint64                     `json:"usedOpcodes"`
+	ContractSize map[libcommon.Address]*contractSizeWithOpcode `json:"contractSize"`
+	OutOfGas     bool                                          `json:"outOfGas"`
+	Keccak       []hexutility.Bytes                            `json:"keccak,omitempty"`
+	Calls        []callFrameWithOpcodes                        `json:"calls,omitempty"`
+}
+
+func newCallFrameWithOpcodes(typ vm.OpCode, from, to libcommon.Address, input []byte, gas uint64, value *uint256.Int) callFrameWithOpcodes {
+	f := callFrameWithOpcodes{
+		Type:  typ,
+		From:  from,
+		To:    &to,
+		Input: libcommon.CopyBytes(input),
+		Gas:   hexutil.Uint64(gas),
+		Accessed: accessedSlots{
+			Reads:           map[string][]string{},
+			Writes:          map[string]uint64{},
+			TransientReads:  map[string]uint64{},
+			TransientWrites: map[string]uint64{},
+		},
+		ExtCodeInfo:  []libcommon.Address{},
+		UsedOpcodes:  map[hexutil.Uint64]uint64{},
+		ContractSize: map[libcommon.Address]*contractSizeWithOpcode{},
+	}
+	if value != nil {
+		f.Value = (*hexutil.Big)(value.ToBig())
+	}
+	return f
+}
+
+func (f *callFrameWithOpcodes) processOutput(output []byte, err error) {
+	output = libcommon.CopyBytes(output)
+	f.TypeString = f.Type.String()
+	if err == nil {
+		f.Output = output
+		return
+	}
+	f.Error = err.Error()
+	if errors.Is(err, vm.ErrOutOfGas) || errors.Is(err, vm.ErrCodeStoreOutOfGas) {
+		f.OutOfGas = true
+	}
+	if f.Type == vm.CREATE || f.Type == vm.CREATE2 {
+		f.To = nil
+	}
+	if !errors.Is(err, vm.ErrExecutionReverted) || len(output) == 0 {
+		return
+	}
+	f.Output = output
+	if len(output) < 4 {
+		return
+	}
+	frame := callFrame{Type: f.Type}
+	frame.processOutput(output, err)
+	f.RevertReason = frame.Revertal
+}
+
+type erc7562TracerConfig struct {
+	IgnoredOpcodes []hexutil.Uint64 `json:"ignoredOpcodes"` // Opcodes which are not reported in usedOpcodes
+	WithLog        bool             `json:"withLog"`        // If true, erc7562 tracer will collect event logs
+}
+
+// erc7562Tracer collects the storage, opcode and entity access data needed
+// by ERC-4337 bundlers to check the ERC-7562 validation rules of a UserOperation.
+type erc7562Tracer struct {
+	noopTracer
+	env               *vm.EVM
+	config            erc7562TracerConfig
+	ignoredOpcodes    map[vm.OpCode]struct{}
+	activePrecompiles []libcommon.Address
+	callstack         []callFrameWithOpcodes
+	keccakPreimages   map[string]struct{}
+	gasLimit          uint64
+	logIndex          uint64
+
+	lastOp     vm.OpCode
+	lastOpAddr libcommon.Address // address argument of the previous opcode, if it was an EXTCODE* one
+	hasLastOp  bool
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// defaultIgnoredOpcodes returns the opcodes which are not relevant for the validation
+// rules and therefore are not reported in usedOpcodes
+func defaultIgnoredOpcodes() []hexutil.Uint64 {
+	ignored := make([]hexutil.Uint64, 0, 128)
+	// PUSHx, DUPx and SWAPx opcodes have sequential codes
+	for op := vm.PUSH0; op <= vm.SWAP16; op++ {
+		ignored = append(ignored, hexutil.Uint64(op))
+	}
+	for _, op := range []vm.OpCode{
+		vm.POP, vm.ADD, vm.SUB, vm.MUL,
+		vm.DIV, vm.EQ, vm.LT, vm.GT,
+		vm.SLT, vm.SGT, vm.SHL, vm.SHR,
+		vm.AND, vm.OR, vm.NOT, vm.ISZERO,
+	} {
+		ignored = append(ignored, hexutil.Uint64(op))
+	}
+	return ignored
+}
+
+// newErc7562Tracer returns a native go tracer which collects
+// ERC-7562 validation data of a tx, and implements vm.EVMLogger.
+func newErc7562Tracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	config := erc7562TracerConfig{IgnoredOpcodes: defaultIgnoredOpcodes()}
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	ignoredOpcodes := make(map[vm.OpCode]struct{}, len(config.IgnoredOpcodes))
+	for _, op := range config.IgnoredOpcodes {
+		ignoredOpcodes[vm.OpCode(op)] = struct{}{}
+	}
+	return &erc7562Tracer{
+		config:          config,
+		ignoredOpcodes:  ignoredOpcodes,
+		keccakPreimages: make(map[string]struct{}),
+	}, nil
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *erc7562Tracer) CaptureStart(env *vm.EVM, from libcommon.Address, to libcommon.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.env = env
+	t.activePrecompiles = vm.ActivePrecompiles(env.ChainRules())
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.callstack = []callFrameWithOpcodes{newCallFrameWithOpcodes(typ, from, to, input, t.gasLimit, value)}
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *erc7562Tracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if len(t.callstack) == 0 {
+		return
+	}
+	t.callstack[0].processOutput(output, err)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *erc7562Tracer) CaptureEnter(typ vm.OpCode, from libcommon.Address, to libcommon.Address, precompile, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.callstack = append(t.callstack, newCallFrameWithOpcodes(typ, from, to, input, gas, value))
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *erc7562Tracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	size := len(t.callstack)
+	if size <= 1 {
+		return
+	}
+	call := t.callstack[size-1]
+	t.callstack = t.callstack[:size-1]
+	call.GasUsed = hexutil.Uint64(gasUsed)
+	call.processOutput(output, err)
+	// Logs of a failed frame are reverted together with it
+	if err != nil {
+		clearFrameLogs(&call)
+	}
+	t.callstack[size-2].Calls = append(t.callstack[size-2].Calls, call)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *erc7562Tracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if err != nil || len(t.callstack) == 0 {
+		return
+	}
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		return
+	}
+	frame := &t.callstack[len(t.callstack)-1]
+	stack := scope.Stack
+
+	if t.hasLastOp {
+		t.handleLastOp(op, frame)
+	}
+	if op == vm.REVERT || op == vm.RETURN {
+		// the frame is finished, the next opcode belongs to the parent
+		t.hasLastOp = false
+	} else {
+		t.lastOp, t.hasLastOp = op, true
+	}
+
+	if op != vm.GAS && !t.isIgnoredOpcode(op) {
+		frame.UsedOpcodes[hexutil.Uint64(op)]++
+	}
+
+	switch op {
+	case vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH:
+		addr := libcommon.Address(stack.Back(0).Bytes20())
+		t.lastOpAddr = addr
+		t.storeContractSize(op, addr, frame)
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		if len(stack.Data) > 1 {
+			t.storeContractSize(op, libcommon.Address(stack.Back(1).Bytes20()), frame)
+		}
+	case vm.SLOAD:
+		slot := libcommon.Hash(stack.Back(0).Bytes32())
+		key := slot.Hex()
+		_, read := frame.Accessed.Reads[key]
+		_, written := frame.Accessed.Writes[key]
+		if !read && !written {
+			var value uint256.Int
+			if err := t.env.IntraBlockState().GetState(scope.Contract.Address(), &slot, &value); err != nil {
+				log.Warn("erc7562Tracer: failed to read state", "err", err)
+				return
+			}
+			frame.Accessed.Reads[key] = append(frame.Accessed.Reads[key], libcommon.Hash(value.Bytes32()).Hex())
+		}
+	case vm.SSTORE:
+		frame.Accessed.Writes[libcommon.Hash(stack.Back(0).Bytes32()).Hex()]++
+	case vm.TLOAD:
+		frame.Accessed.TransientReads[libcommon.Hash(stack.Back(0).Bytes32()).Hex()]++
+	case vm.TSTORE:
+		frame.Accessed.TransientWrites[libcommon.Hash(stack.Back(0).Bytes32()).Hex()]++
+	case vm.KECCAK256:
+		offset, size := stack.Back(0), stack.Back(1)
+		if !offset.IsUint64() || !size.IsUint64() || size.Uint64() > maxKeccakPreimageSize {
+			return
+		}
+		preimage := make([]byte, size.Uint64())
+		copy(preimage, scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64())))
+		t.keccakPreimages[string(preimage)] = struct{}{}
+	case vm.LOG0, vm.LOG1, vm.LOG2, vm.LOG3, vm.LOG4:
+		if !t.config.WithLog {
+			return
+		}
+		topicsCount := int(op - vm.LOG0)
+		if len(stack.Data) < 2+topicsCount {
+			return
+		}
+		mStart, mSize := stack.Back(0), stack.Back(1)
+		topics := make([]libcommon.Hash, topicsCount)
+		for i := 0; i < topicsCount; i++ {
+			topics[i] = libcommon.Hash(stack.Back(2 + i).Bytes32())
+		}
+		data := scope.Memory.GetCopy(int64(mStart.Uint64()), int64(mSize.Uint64()))
+		frame.Logs = append(frame.Logs, callLog{Address: scope.Contract.Address(), Topics: topics, Data: data, Index: t.logIndex})
+		t.logIndex++
+	}
+}
+
+// handleLastOp processes the opcodes whose usage depends on the following opcode
+func (t *erc7562Tracer) handleLastOp(op vm.OpCode, frame *callFrameWithOpcodes) {
+	switch t.lastOp {
+	case vm.GAS:
+		// [OP-012] GAS is allowed only when followed immediately by a call
+		if !isCallOpcode(op) {
+			frame.UsedOpcodes[hexutil.Uint64(vm.GAS)]++
+		}
+	case vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH:
+		// [OP-051] EXTCODESIZE followed by ISZERO is an allowed existence check
+		if !(t.lastOp == vm.EXTCODESIZE && op == vm.ISZERO) {
+			frame.ExtCodeInfo = append(frame.ExtCodeInfo, t.lastOpAddr)
+		}
+	}
+}
+
+// storeContractSize records the code size of an accessed contract, used to check [OP-041]
+func (t *erc7562Tracer) storeContractSize(op vm.OpCode, addr libcommon.Address, frame *callFrameWithOpcodes) {
+	if _, ok := frame.ContractSize[addr]; ok || t.isPrecompiled(addr) {
+		return
+	}
+	size, err := t.env.IntraBlockState().GetCodeSize(addr)
+	if err != nil {
+		log.Warn("erc7562Tracer: failed to read code size", "addr", addr, "err", err)
+		return
+	}
+	frame.ContractSize[addr] = &contractSizeWithOpcode{ContractSize: size, Opcode: op}
+}
+
+func (t *erc7562Tracer) isIgnoredOpcode(op vm.OpCode) bool {
+	_, ok := t.ignoredOpcodes[op]
+	return ok
+}
+
+func (t *erc7562Tracer) isPrecompiled(addr libcommon.Address) bool {
+	for _, p := range t.activePrecompiles {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+func isCallOpcode(op vm.OpCode) bool {
+	return op == vm.CALL || op == vm.CALLCODE || op == vm.DELEGATECALL || op == vm.STATICCALL
+}
+
+func (t *erc7562Tracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+func (t *erc7562Tracer) CaptureTxEnd(restGas uint64) {
+	if len(t.callstack) == 0 {
+		return
+	}
+	t.callstack[0].GasUsed = hexutil.Uint64(t.gasLimit - restGas)
+	if t.callstack[0].Error != "" {
+		clearFrameLogs(&t.callstack[0])
+	}
+}
+
+// clearFrameLogs removes the logs of a failed frame and all its children
+func clearFrameLogs(f *callFrameWithOpcodes) {
+	f.Logs = nil
+	for i := range f.Calls {
+		clearFrameLogs(&f.Calls[i])
+	}
+}
+
+// GetResult returns the json-encoded nested list of call frames, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *erc7562Tracer) GetResult() (json.RawMessage, error) {
+	if len(t.callstack) != 1 {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	top := t.callstack[0]
+	keccak := make([]string, 0, len(t.keccakPreimages))
+	for k := range t.keccakPreimages {
+		keccak = append(keccak, k)
+	}
+	sort.Strings(keccak)
+	for _, k := range keccak {
+		top.Keccak = append(top.Keccak, hexutility.Bytes(k))
+	}
+	res, err := json.Marshal(top)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *erc7562Tracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
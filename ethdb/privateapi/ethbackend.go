@@ -19,6 +19,7 @@ package privateapi
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"math"
 
@@ -47,7 +48,8 @@ import (
 // 3.1.0 - add Subscribe to logs
 // 3.2.0 - add EngineGetBlobsBundleV1
 // 3.3.0 - merge EngineGetBlobsBundleV1 into EngineGetPayload
-var EthBackendAPIVersion = &types2.VersionReply{Major: 3, Minor: 3, Patch: 0}
+// 3.4.0 - send pending block and pending logs events in Subscribe
+var EthBackendAPIVersion = &types2.VersionReply{Major: 3, Minor: 4, Patch: 0}
 
 type EthBackendServer struct {
 	remote.UnimplementedETHBACKENDServer // must be embedded to have forward compatible implementations.
@@ -226,6 +228,10 @@ func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer
 	defer clean()
 	newSnCh, newSnClean := s.notifications.Events.AddNewSnapshotSubscription()
 	defer newSnClean()
+	pendingBlockCh, pendingBlockClean := s.notifications.Events.AddPendingBlockSubscription()
+	defer pendingBlockClean()
+	pendingLogsCh, pendingLogsClean := s.notifications.Events.AddPendingLogsSubscription()
+	defer pendingLogsClean()
 	s.logger.Info("new subscription to newHeaders established")
 	defer func() {
 		if err != nil {
@@ -256,6 +262,23 @@ func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer
 			if err = subscribeServer.Send(&remote.SubscribeReply{Type: remote.Event_NEW_SNAPSHOT}); err != nil {
 				return err
 			}
+		case block := <-pendingBlockCh:
+			blockRlp, err := rlp.EncodeToBytes(block)
+			if err != nil {
+				return err
+			}
+			if err = subscribeServer.Send(&remote.SubscribeReply{Type: remote.Event_PENDING_BLOCK, Data: blockRlp}); err != nil {
+				return err
+			}
+		case logs := <-pendingLogsCh:
+			// JSON keeps the txn hash, indices and block number of the logs, RLP drops them
+			logsJson, err := json.Marshal(logs)
+			if err != nil {
+				return err
+			}
+			if err = subscribeServer.Send(&remote.SubscribeReply{Type: remote.Event_PENDING_LOGS, Data: logsJson}); err != nil {
+				return err
+			}
 		}
 	}
 }
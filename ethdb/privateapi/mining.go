@@ -113,13 +113,14 @@ func (s *MiningServer) OnPendingLogs(req *proto_txpool.OnPendingLogsRequest, rep
 	return reply.Context().Err()
 }
 
+// BroadcastPendingLogs sends all the logs of the pending block, RLP encoded
 func (s *MiningServer) BroadcastPendingLogs(l types.Logs) error {
 	b, err := rlp.EncodeToBytes(l)
 	if err != nil {
 		return err
 	}
-	reply := &proto_txpool.OnPendingBlockReply{RplBlock: b}
-	s.pendingBlockStreams.Broadcast(reply, s.logger)
+	reply := &proto_txpool.OnPendingLogsReply{RplLogs: b}
+	s.pendingLogsStreams.Broadcast(reply, s.logger)
 	return nil
 }
 
@@ -37,4 +37,7 @@ type MiningConfig struct {
 	GasLimit   uint64            // Target gas limit for mined blocks.
 	GasPrice   *big.Int          // Minimum gas price for mining a transaction
 	Recommit   time.Duration     // The time interval for miner to re-create mining work.
+
+	TxnOrdering     string              // Ordering policy of the transactions of built blocks (see txnprovider.NewOrderingPolicy)
+	PrioritySenders []libcommon.Address `toml:",omitempty"` // Senders whose transactions go first with the priority-lanes ordering policy
 }
@@ -50,7 +50,7 @@ func TraceBorStateSyncTxnDebugAPI(
 	msgs []*types.Message,
 ) (usedGas uint64, err error) {
 	txCtx := initStateSyncTxContext(blockNum, blockHash)
-	tracer, streaming, cancel, err := transactions.AssembleTracer(ctx, traceConfig, txCtx.TxHash, stream, callTimeout)
+	tracer, streaming, cancel, err := transactions.AssembleTracer(ctx, traceConfig, txCtx.TxHash, blockHash, 0, stream, callTimeout)
 	if err != nil {
 		stream.WriteNil()
 		return usedGas, err
@@ -81,6 +81,7 @@ type Client struct {
 	isHTTP          bool
 	services        *serviceRegistry
 	methodAllowList AllowList
+	rateLimiter     *RateLimiter
 
 	idCounter uint32
 
@@ -118,6 +119,7 @@ func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.WithValue(context.Background(), clientContextKey{}, c)
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, conn.peerInfo())
 	handler := newHandler(ctx, conn, c.idgen, c.services, c.methodAllowList, 50, false /* traceRequests */, c.logger, 0)
+	handler.rateLimiter = c.rateLimiter
 	return &clientConn{conn, handler}
 }
 
@@ -184,6 +186,8 @@ func DialContext(ctx context.Context, rawurl string, logger log.Logger) (*Client
 		return DialWebsocket(ctx, rawurl, "", logger)
 	case "stdio":
 		return DialStdIO(ctx, logger)
+	case "":
+		return DialIPC(ctx, rawurl, logger)
 	default:
 		return nil, fmt.Errorf("no known transport for URL scheme %q", u.Scheme)
 	}
@@ -202,17 +206,18 @@ func newClient(initctx context.Context, connect reconnectFunc, logger log.Logger
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), &serviceRegistry{logger: logger}, logger)
+	c := initClient(conn, randomIDGenerator(), &serviceRegistry{logger: logger}, nil /* rateLimiter */, logger)
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, logger log.Logger) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, rateLimiter *RateLimiter, logger log.Logger) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
 		idgen:       idgen,
 		isHTTP:      isHTTP,
 		services:    services,
+		rateLimiter: rateLimiter,
 		writeConn:   conn,
 		close:       make(chan struct{}),
 		closing:     make(chan struct{}),
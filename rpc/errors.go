@@ -19,7 +19,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 var (
 	_ Error = new(methodNotFoundError)
@@ -29,6 +32,7 @@ var (
 	_ Error = new(invalidMessageError)
 	_ Error = new(InvalidParamsError)
 	_ Error = new(CustomError)
+	_ Error = new(RateLimitedError)
 )
 
 const defaultErrorCode = -32000
@@ -92,3 +96,24 @@ type CustomError struct {
 func (e *CustomError) ErrorCode() int { return e.Code }
 
 func (e *CustomError) Error() string { return e.Message }
+
+// RateLimitedError is returned when the client of a call exhausted its request budget
+type RateLimitedError struct {
+	Method     string
+	Cost       int
+	Burst      int
+	RetryAfter time.Duration // zero when the call costs more than the client can ever afford
+}
+
+func (e *RateLimitedError) ErrorCode() int { return -32005 }
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter == 0 {
+		return fmt.Sprintf("request cost %d of %s exceeds the rate limit burst %d", e.Cost, e.Method, e.Burst)
+	}
+	return fmt.Sprintf("rate limit exceeded for %s, retry in %s", e.Method, e.RetryAfter.Round(time.Millisecond))
+}
+
+func (e *RateLimitedError) ErrorData() interface{} {
+	return map[string]interface{}{"cost": e.Cost, "retryAfterMs": e.RetryAfter.Milliseconds()}
+}
@@ -71,6 +71,7 @@ type handler struct {
 
 	allowList     AllowList // a list of explicitly allowed methods, if empty -- everything is allowed
 	forbiddenList ForbiddenList
+	rateLimiter   *RateLimiter // nil if calls are not rate limited
 
 	subLock             sync.Mutex
 	serverSubs          map[ID]*Subscription
@@ -476,6 +477,11 @@ func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter
 	if callb == nil {
 		return msg.errorResponse(&methodNotFoundError{method: msg.Method})
 	}
+	if h.rateLimiter != nil && callb != h.unsubscribeCb {
+		if err := h.rateLimiter.Allow(cp.ctx, msg.Method, msg.Params); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	args, err := parsePositionalArguments(msg.Params, callb.argTypes)
 	if err != nil {
 		return msg.errorResponse(&InvalidParamsError{err.Error()})
@@ -511,6 +517,11 @@ func (h *handler) handleSubscribe(cp *callProc, msg *jsonrpcMessage, stream *jso
 	if callb == nil {
 		return msg.errorResponse(&subscriptionNotFoundError{namespace, name})
 	}
+	if h.rateLimiter != nil {
+		if err := h.rateLimiter.Allow(cp.ctx, msg.Method, msg.Params); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 
 	// Parse subscription name arg too, but remove it before calling the callback.
 	argTypes := append([]reflect.Type{stringType}, callb.argTypes...)
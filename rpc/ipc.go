@@ -20,6 +20,7 @@
 package rpc
 
 import (
+	"context"
 	"net"
 
 	"github.com/erigontech/erigon-lib/log/v3"
@@ -40,3 +41,18 @@ func (s *Server) ServeListener(l net.Listener) error {
 		go s.ServeCodec(NewCodec(conn), 0)
 	}
 }
+
+// DialIPC creates a new IPC client that connects to the given endpoint, the path
+// of a unix domain socket.
+//
+// The context is used for the initial connection establishment. It does not
+// affect subsequent interactions with the client.
+func DialIPC(ctx context.Context, endpoint string, logger log.Logger) (*Client, error) {
+	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
+		conn, err := new(net.Dialer).DialContext(ctx, "unix", endpoint)
+		if err != nil {
+			return nil, err
+		}
+		return NewCodec(conn), nil
+	}, logger)
+}
@@ -0,0 +1,306 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/time/rate"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/metrics"
+)
+
+const (
+	RateLimitKeyByIP     = "ip"
+	RateLimitKeyByJWT    = "jwt"
+	RateLimitKeyByHeader = "header:"
+
+	defaultRateLimitMaxClients = 10_000
+)
+
+// RateLimitConfig describes the cost of the methods and the budget of every client.
+//
+// Every call costs MethodCosts[method] (DefaultCost if absent) plus, for the methods of BlockRangeCosts,
+// PerBlock units for every block of the range given by the fromBlock/toBlock fields of its first param.
+// Every client has a token bucket refilled at Rate units per second, holding at most Burst units.
+type RateLimitConfig struct {
+	Rate            float64                   `json:"rate"`
+	Burst           int                       `json:"burst"`
+	DefaultCost     int                       `json:"defaultCost"`
+	MethodCosts     map[string]int            `json:"methodCosts"`
+	BlockRangeCosts map[string]BlockRangeCost `json:"blockRangeCosts"`
+	// KeyBy selects how clients are told apart: "ip" (default), "jwt" (subject of the bearer token, whose
+	// signature is checked against JWTSecretFile) or "header:<Name>" (e.g. an API key header, only trusted on
+	// requests coming from TrustedProxies). Clients without a valid token or trusted header fall back to their IP.
+	KeyBy string `json:"keyBy"`
+	// JWTSecretFile is the hex encoded HS256 secret of the tokens, required by "jwt"
+	JWTSecretFile string `json:"jwtSecretFile"`
+	// TrustedProxies are the IPs or CIDRs of the proxies allowed to set the header of "header:<Name>", required by it
+	TrustedProxies []string `json:"trustedProxies"`
+	// Clients overrides the budget of individual clients, by key
+	Clients map[string]ClientRateLimit `json:"clients"`
+	// MaxClients bounds the number of tracked buckets, the least recently seen ones are dropped
+	MaxClients int `json:"maxClients"`
+}
+
+type BlockRangeCost struct {
+	PerBlock float64 `json:"perBlock"`
+	// MaxBlocks caps the accounted range, and is used as the range when it is open-ended (e.g. up to "latest")
+	MaxBlocks uint64 `json:"maxBlocks"`
+}
+
+type ClientRateLimit struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// RateLimiter accounts the cost of calls and rejects the ones of clients who exhausted their budget
+type RateLimiter struct {
+	cfg            RateLimitConfig
+	header         string
+	jwtSecret      []byte
+	trustedProxies []*net.IPNet
+	buckets        *lru.Cache[string, *rate.Limiter]
+}
+
+func NewRateLimiter(cfg RateLimitConfig) (*RateLimiter, error) {
+	if cfg.Rate <= 0 || cfg.Burst <= 0 {
+		return nil, fmt.Errorf("rate limit: rate and burst must be positive, got %v and %d", cfg.Rate, cfg.Burst)
+	}
+	if cfg.DefaultCost <= 0 {
+		cfg.DefaultCost = 1
+	}
+	if cfg.MaxClients <= 0 {
+		cfg.MaxClients = defaultRateLimitMaxClients
+	}
+	l := &RateLimiter{cfg: cfg}
+	switch {
+	case cfg.KeyBy == "" || cfg.KeyBy == RateLimitKeyByIP:
+	case cfg.KeyBy == RateLimitKeyByJWT:
+		if cfg.JWTSecretFile == "" {
+			return nil, fmt.Errorf("rate limit: keyBy %q requires jwtSecretFile", cfg.KeyBy)
+		}
+		data, err := os.ReadFile(cfg.JWTSecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("rate limit: %w", err)
+		}
+		if l.jwtSecret = common.FromHex(strings.TrimSpace(string(data))); len(l.jwtSecret) != 32 {
+			return nil, fmt.Errorf("rate limit: invalid JWT secret in %s, expected 32 hex encoded bytes", cfg.JWTSecretFile)
+		}
+	case strings.HasPrefix(cfg.KeyBy, RateLimitKeyByHeader) && len(cfg.KeyBy) > len(RateLimitKeyByHeader):
+		if len(cfg.TrustedProxies) == 0 {
+			return nil, fmt.Errorf("rate limit: keyBy %q requires trustedProxies", cfg.KeyBy)
+		}
+		l.header = cfg.KeyBy[len(RateLimitKeyByHeader):]
+		for _, proxy := range cfg.TrustedProxies {
+			if !strings.Contains(proxy, "/") {
+				if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+					proxy += "/32"
+				} else {
+					proxy += "/128"
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("rate limit: trusted proxy: %w", err)
+			}
+			l.trustedProxies = append(l.trustedProxies, ipNet)
+		}
+	default:
+		return nil, fmt.Errorf("rate limit: unknown keyBy %q, expected %q, %q or %q<Name>", cfg.KeyBy, RateLimitKeyByIP, RateLimitKeyByJWT, RateLimitKeyByHeader)
+	}
+	for key, c := range cfg.Clients {
+		if c.Rate <= 0 || c.Burst <= 0 {
+			return nil, fmt.Errorf("rate limit: rate and burst of client %q must be positive", key)
+		}
+	}
+	var err error
+	if l.buckets, err = lru.New[string, *rate.Limiter](cfg.MaxClients); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Cost returns the cost of a call to method with the given params
+func (l *RateLimiter) Cost(method string, params json.RawMessage) int {
+	cost, ok := l.cfg.MethodCosts[method]
+	if !ok {
+		cost = l.cfg.DefaultCost
+	}
+	if rc, ok := l.cfg.BlockRangeCosts[method]; ok {
+		cost += int(math.Ceil(rc.PerBlock * float64(blockRangeLen(params, rc.MaxBlocks))))
+	}
+	return cost
+}
+
+// blockRangeLen returns the number of blocks between the fromBlock and toBlock fields of the first param
+func blockRangeLen(params json.RawMessage, maxBlocks uint64) uint64 {
+	var args []json.RawMessage
+	if err := json.Unmarshal(params, &args); err != nil || len(args) == 0 {
+		return 1
+	}
+	var crit struct {
+		FromBlock *BlockNumber `json:"fromBlock"`
+		ToBlock   *BlockNumber `json:"toBlock"`
+		BlockHash *string      `json:"blockHash"`
+	}
+	if err := json.Unmarshal(args[0], &crit); err != nil || crit.BlockHash != nil {
+		return 1
+	}
+	from, to := LatestBlockNumber, LatestBlockNumber
+	if crit.FromBlock != nil {
+		from = *crit.FromBlock
+	}
+	if crit.ToBlock != nil {
+		to = *crit.ToBlock
+	}
+
+	var n uint64
+	switch {
+	case from < 0 && from == to: // e.g. latest..latest
+		n = 1
+	case from < 0 || to < 0: // open-ended, the head is not known here
+		if maxBlocks == 0 {
+			return 1
+		}
+		n = maxBlocks
+	case to < from:
+		n = 1
+	default:
+		n = uint64(to-from) + 1
+	}
+	if maxBlocks > 0 && n > maxBlocks {
+		n = maxBlocks
+	}
+	return n
+}
+
+// ClientKey identifies the client of the connection: by IP, unless it presents a token signed with the JWT
+// secret, or comes from a trusted proxy which set the key header
+func (l *RateLimiter) ClientKey(info PeerInfo) string {
+	host, _, err := net.SplitHostPort(info.RemoteAddr)
+	if err != nil {
+		if info.Transport == "ipc" { // unix socket, all the local clients share the budget
+			return "ipc"
+		}
+		host = info.RemoteAddr
+	}
+	switch {
+	case l.header != "":
+		if info.HTTP.Header != nil && l.isTrustedProxy(host) {
+			if v := info.HTTP.Header.Get(l.header); v != "" {
+				return "header:" + v
+			}
+		}
+	case l.jwtSecret != nil:
+		if info.HTTP.Header != nil {
+			if sub := l.jwtSubject(info.HTTP.Header.Get("Authorization")); sub != "" {
+				return "jwt:" + sub
+			}
+		}
+	}
+	return "ip:" + host
+}
+
+func (l *RateLimiter) isTrustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range l.trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// jwtSubject returns the subject of a bearer token signed with the JWT secret, and not expired
+func (l *RateLimiter) jwtSubject(authorization string) string {
+	tokenStr, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return ""
+	}
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		return l.jwtSecret, nil
+	}
+	claims := jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, &claims, keyFunc,
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithoutClaimsValidation())
+	if err != nil || !token.Valid || !claims.VerifyExpiresAt(time.Now(), false) {
+		return ""
+	}
+	return claims.Subject
+}
+
+func (l *RateLimiter) bucket(key string) *rate.Limiter {
+	if b, ok := l.buckets.Get(key); ok {
+		return b
+	}
+	r, burst := l.cfg.Rate, l.cfg.Burst
+	// overrides are given by IP, JWT subject or header value, without the kind prefix
+	if _, id, _ := strings.Cut(key, ":"); id != "" {
+		if c, ok := l.cfg.Clients[id]; ok {
+			r, burst = c.Rate, c.Burst
+		}
+	}
+	b := rate.NewLimiter(rate.Limit(r), burst)
+	// concurrent misses may both create a bucket, keep the first one
+	if prev, ok, _ := l.buckets.PeekOrAdd(key, b); ok {
+		return prev
+	}
+	return b
+}
+
+// Allow charges the cost of the call to the client of the connection, or returns a *RateLimitedError
+// if the client can't afford it yet
+func (l *RateLimiter) Allow(ctx context.Context, method string, params json.RawMessage) error {
+	cost := l.Cost(method, params)
+	b := l.bucket(l.ClientKey(PeerInfoFromContext(ctx)))
+	if cost > b.Burst() {
+		rateLimitRejectedCounter(method).Inc()
+		return &RateLimitedError{Method: method, Cost: cost, Burst: b.Burst()}
+	}
+	now := time.Now()
+	r := b.ReserveN(now, cost)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		rateLimitRejectedCounter(method).Inc()
+		return &RateLimitedError{Method: method, Cost: cost, Burst: b.Burst(), RetryAfter: delay}
+	}
+	rateLimitCostCounter(method).AddInt(cost)
+	return nil
+}
+
+func rateLimitCostCounter(method string) metrics.Counter {
+	return metrics.GetOrCreateCounter(fmt.Sprintf(`rpc_cost_total{method="%s"}`, method))
+}
+
+func rateLimitRejectedCounter(method string) metrics.Counter {
+	return metrics.GetOrCreateCounter(fmt.Sprintf(`rpc_rate_limited_total{method="%s"}`, method))
+}
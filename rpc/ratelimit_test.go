@@ -0,0 +1,187 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/log/v3"
+)
+
+func TestRateLimitCost(t *testing.T) {
+	l, err := NewRateLimiter(RateLimitConfig{
+		Rate:            1,
+		Burst:           10,
+		MethodCosts:     map[string]int{"trace_filter": 10},
+		BlockRangeCosts: map[string]BlockRangeCost{"eth_getLogs": {PerBlock: 0.5, MaxBlocks: 1000}},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 1, l.Cost("eth_blockNumber", nil))
+	require.Equal(t, 10, l.Cost("trace_filter", []byte(`[{"fromBlock":"0x1","toBlock":"0x100"}]`)))
+
+	for _, tc := range []struct {
+		params string
+		cost   int
+	}{
+		{`[{"fromBlock":"0x1","toBlock":"0x4"}]`, 1 + 2},
+		{`[{"fromBlock":"0x1","toBlock":"0x5"}]`, 1 + 3},
+		{`[{"fromBlock":"latest","toBlock":"latest"}]`, 1 + 1},
+		{`[{}]`, 1 + 1},
+		{`[{"fromBlock":"0x1"}]`, 1 + 500},
+		{`[{"fromBlock":"0x0","toBlock":"0xffffff"}]`, 1 + 500},
+		{`[{"blockHash":"0x01","fromBlock":"0x1"}]`, 1 + 1},
+		{`[{"fromBlock":"0x10","toBlock":"0x1"}]`, 1 + 1},
+		{`"garbage"`, 1 + 1},
+	} {
+		require.Equal(t, tc.cost, l.Cost("eth_getLogs", []byte(tc.params)), tc.params)
+	}
+}
+
+func TestRateLimitClientKey(t *testing.T) {
+	secret := make([]byte, 32)
+	secret[0] = 1
+	secretFile := filepath.Join(t.TempDir(), "jwt.hex")
+	require.NoError(t, os.WriteFile(secretFile, []byte(hexutility.Encode(secret)), 0600))
+
+	byIP, err := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1})
+	require.NoError(t, err)
+	byHeader, err := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, KeyBy: "header:X-Api-Key", TrustedProxies: []string{"10.0.0.1", "192.168.0.0/16"}})
+	require.NoError(t, err)
+	byJWT, err := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, KeyBy: "jwt", JWTSecretFile: secretFile})
+	require.NoError(t, err)
+	_, err = NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, KeyBy: "cookie"})
+	require.Error(t, err)
+	_, err = NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, KeyBy: "header:X-Api-Key"})
+	require.Error(t, err)
+	_, err = NewRateLimiter(RateLimitConfig{Rate: 1, Burst: 1, KeyBy: "jwt"})
+	require.Error(t, err)
+
+	sign := func(key []byte, claims jwt.RegisteredClaims) string {
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		require.NoError(t, err)
+		return "Bearer " + token
+	}
+	token := sign(secret, jwt.RegisteredClaims{Subject: "alice"})
+
+	info := PeerInfo{Transport: "http", RemoteAddr: "10.0.0.1:1234"}
+	info.HTTP.Header = http.Header{}
+	info.HTTP.Header.Set("X-Api-Key", "key1")
+	info.HTTP.Header.Set("Authorization", token)
+
+	require.Equal(t, "ip:10.0.0.1", byIP.ClientKey(info))
+	require.Equal(t, "header:key1", byHeader.ClientKey(info))
+	require.Equal(t, "jwt:alice", byJWT.ClientKey(info))
+	require.Equal(t, "ipc", byIP.ClientKey(PeerInfo{Transport: "ipc"}))
+
+	// the header is only trusted from the proxies
+	info.RemoteAddr = "192.168.1.1:1234"
+	require.Equal(t, "header:key1", byHeader.ClientKey(info))
+	info.RemoteAddr = "10.0.0.2:1234"
+	require.Equal(t, "ip:10.0.0.2", byHeader.ClientKey(info))
+
+	// the token must be signed with the secret, and not expired
+	info.HTTP.Header.Set("Authorization", sign([]byte("not the secret"), jwt.RegisteredClaims{Subject: "alice"}))
+	require.Equal(t, "ip:10.0.0.2", byJWT.ClientKey(info))
+	info.HTTP.Header.Set("Authorization", sign(secret, jwt.RegisteredClaims{Subject: "alice", ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute))}))
+	require.Equal(t, "ip:10.0.0.2", byJWT.ClientKey(info))
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.RegisteredClaims{Subject: "alice"}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	info.HTTP.Header.Set("Authorization", "Bearer "+unsigned)
+	require.Equal(t, "ip:10.0.0.2", byJWT.ClientKey(info))
+
+	info.HTTP.Header = nil
+	require.Equal(t, "ip:10.0.0.2", byHeader.ClientKey(info))
+	require.Equal(t, "ip:10.0.0.2", byJWT.ClientKey(info))
+}
+
+func TestRateLimitHTTP(t *testing.T) {
+	logger := log.New()
+	server := newTestServer(logger)
+	defer server.Stop()
+	l, err := NewRateLimiter(RateLimitConfig{
+		Rate:           0.001,
+		Burst:          3,
+		KeyBy:          "header:X-Api-Key",
+		TrustedProxies: []string{"127.0.0.1", "::1"},
+		MethodCosts:    map[string]int{"test_sleep": 5},
+		Clients:        map[string]ClientRateLimit{"vip": {Rate: 0.001, Burst: 100}},
+	})
+	require.NoError(t, err)
+	server.SetRateLimiter(l)
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	dial := func(key string) *Client {
+		c, err := DialHTTP(ts.URL, logger)
+		require.NoError(t, err)
+		c.SetHeader("X-Api-Key", key)
+		return c
+	}
+	a, b, vip := dial("a"), dial("b"), dial("vip")
+	defer a.Close()
+	defer b.Close()
+	defer vip.Close()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, a.Call(nil, "test_noArgsRets"))
+	}
+	err = a.Call(nil, "test_noArgsRets")
+	var rpcErr Error
+	require.True(t, errors.As(err, &rpcErr), err)
+	require.Equal(t, -32005, rpcErr.ErrorCode())
+
+	// other clients have their own budget
+	require.NoError(t, b.Call(nil, "test_noArgsRets"))
+	// costlier than the burst
+	err = b.Call(nil, "test_sleep", 0)
+	require.True(t, errors.As(err, &rpcErr), err)
+	require.Equal(t, -32005, rpcErr.ErrorCode())
+	require.NoError(t, vip.Call(nil, "test_sleep", 0))
+}
+
+func TestRateLimitServeCodec(t *testing.T) {
+	logger := log.New()
+	server := newTestServer(logger)
+	defer server.Stop()
+	l, err := NewRateLimiter(RateLimitConfig{Rate: 0.001, Burst: 2})
+	require.NoError(t, err)
+	server.SetRateLimiter(l)
+	client := DialInProc(server, logger)
+	defer client.Close()
+
+	require.NoError(t, client.Call(nil, "test_noArgsRets"))
+	sub, err := client.Subscribe(context.Background(), "nftest", make(chan int), "someSubscription", 1, 1)
+	require.NoError(t, err)
+	sub.Unsubscribe()
+
+	err = client.Call(nil, "test_noArgsRets")
+	var rpcErr Error
+	require.True(t, errors.As(err, &rpcErr), err)
+	require.Equal(t, -32005, rpcErr.ErrorCode())
+}
@@ -23,6 +23,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
 	"sync/atomic"
 	"time"
 
@@ -60,6 +61,7 @@ type Server struct {
 	traceRequests       bool // Whether to print requests at INFO level
 	debugSingleRequest  bool // Whether to print requests at INFO level
 	batchLimit          int  // Maximum number of requests in a batch
+	rateLimiter         *RateLimiter
 	logger              log.Logger
 	rpcSlowLogThreshold time.Duration
 }
@@ -85,6 +87,11 @@ func (s *Server) SetBatchLimit(limit int) {
 	s.batchLimit = limit
 }
 
+// SetRateLimiter sets the cost accounting and per-client rate limiting of calls, nil disables it
+func (s *Server) SetRateLimiter(l *RateLimiter) {
+	s.rateLimiter = l
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -110,7 +117,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services, s.logger)
+	c := initClient(codec, s.idgen, &s.services, s.rateLimiter, s.logger)
 	<-codec.closed()
 	c.Close()
 }
@@ -126,6 +133,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stre
 
 	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests, s.logger, s.rpcSlowLogThreshold)
 	h.allowSubscribe = false
+	h.rateLimiter = s.rateLimiter
 	defer h.close(io.EOF, nil)
 
 	reqs, batch, err := codec.ReadBatch()
@@ -198,6 +206,8 @@ type PeerInfo struct {
 		UserAgent string
 		Origin    string
 		Host      string
+		// All the header values sent by the client, e.g. to identify it by API key.
+		Header http.Header
 	}
 }
 
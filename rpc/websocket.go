@@ -269,6 +269,7 @@ func NewWebsocketCodec(conn *websocket.Conn, host string, req http.Header) Serve
 	if req != nil {
 		wc.info.HTTP.Origin = req.Get("Origin")
 		wc.info.HTTP.UserAgent = req.Get("User-Agent")
+		wc.info.HTTP.Header = req
 	}
 	// Start pinger.
 	wc.wg.Add(1)
@@ -0,0 +1,553 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/erigontech/erigon-lib/chain"
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/temporal"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/cmd/hack/tool/fromdb"
+	"github.com/erigontech/erigon/cmd/utils"
+	"github.com/erigontech/erigon/core/rawdb"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/ethconfig"
+	"github.com/erigontech/erigon/eth/ethconsensusconfig"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/debug"
+	"github.com/erigontech/erigon/turbo/jsonrpc/receipts"
+	"github.com/erigontech/erigon/turbo/services"
+	"github.com/erigontech/erigon/turbo/snapshotsync/era1"
+)
+
+const era1ChecksumsFile = "checksums.txt"
+
+var (
+	Era1FromFlag = cli.Uint64Flag{
+		Name:  "era1.from",
+		Usage: "First epoch (of 8192 blocks) to export",
+		Value: 0,
+	}
+	Era1CountFlag = cli.Uint64Flag{
+		Name:  "era1.count",
+		Usage: "Number of epochs to export. Zero - means all the pre-merge epochs.",
+		Value: 0,
+	}
+	Era1ChecksumsFlag = cli.StringFlag{
+		Name:  "era1.checksums",
+		Usage: "File with the expected sha256 checksums of the era1 files, one `<checksum> <file name>` per line",
+	}
+	Era1RootsFlag = cli.StringFlag{
+		Name:  "era1.roots",
+		Usage: "File with the accumulator roots of the epochs, one per line from the epoch 0, instead of the embedded ones of the chain",
+	}
+)
+
+var exportEra1Command = cli.Command{
+	Action:    MigrateFlags(exportEra1),
+	Name:      "export-era1",
+	Usage:     "Export the pre-merge blocks to era1 files",
+	ArgsUsage: "<dir>",
+	Flags: []cli.Flag{
+		&utils.DataDirFlag,
+		&Era1FromFlag,
+		&Era1CountFlag,
+		&Era1ChecksumsFlag,
+	},
+	Description: `
+The export-era1 command writes the pre-merge blocks of the block snapshots to era1 files, one file per
+epoch of 8192 blocks, and appends their sha256 checksums to checksums.txt. The receipts are regenerated
+from the state history, so the node must keep the history of the exported blocks.
+
+With --era1.checksums the exported files are compared with the published checksums, which verifies the
+frozen block files of the node.`,
+}
+
+var importEra1Command = cli.Command{
+	Action:    MigrateFlags(importEra1),
+	Name:      "import-era1",
+	Usage:     "Import the pre-merge blocks from era1 files",
+	ArgsUsage: "<dir>",
+	Flags: []cli.Flag{
+		&utils.DataDirFlag,
+		&Era1ChecksumsFlag,
+		&Era1RootsFlag,
+	},
+	Description: `
+The import-era1 command imports the era1 files of the chain of an initialized datadir, in the epoch order,
+and moves the imported blocks to the block snapshots. Every file is verified before its blocks are written:
+the bodies and the receipts against the headers, the chain of the headers and the total difficulties,
+and the accumulator root against the file, its name and the known root of the epoch: the ones embedded
+for the chain, or --era1.roots. The files are also checked against --era1.checksums if set. Blocks which
+are already in the chain are checked against it. The blocks are executed by the next run of erigon.`,
+}
+
+func exportEra1(cliCtx *cli.Context) error {
+	if cliCtx.NArg() < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	logger, _, _, err := debug.Setup(cliCtx, true /* rootLogger */)
+	if err != nil {
+		return err
+	}
+	ctx := cliCtx.Context
+	outDir := cliCtx.Args().First()
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return err
+	}
+	var expected map[string]string
+	if path := cliCtx.String(Era1ChecksumsFlag.Name); path != "" {
+		if expected, err = readEra1Checksums(path); err != nil {
+			return err
+		}
+	}
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	chainDB := dbCfg(kv.ChainDB, dirs.Chaindata).MustOpen()
+	defer chainDB.Close()
+	chainConfig := fromdb.ChainConfig(chainDB)
+	cfg := ethconfig.NewSnapCfg(false, true, true, chainConfig.ChainName)
+	_, _, _, br, agg, clean, err := openSnaps(ctx, cfg, dirs, 0, chainDB, logger)
+	if err != nil {
+		return err
+	}
+	defer clean()
+	db, err := temporal.New(chainDB, agg)
+	if err != nil {
+		return err
+	}
+	blockReader, _ := br.IO()
+	receiptsGenerator := receipts.NewGenerator(blockReader, ethconsensusconfig.CreateConsensusEngineBareBones(ctx, chainConfig, logger))
+
+	checksums, err := os.OpenFile(filepath.Join(outDir, era1ChecksumsFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer checksums.Close()
+
+	from, count := cliCtx.Uint64(Era1FromFlag.Name), cliCtx.Uint64(Era1CountFlag.Name)
+	to := uint64(math.MaxUint64)
+	if count > 0 {
+		to = from + count
+	}
+	var mismatches []string
+	for epoch := from; epoch < to; epoch++ {
+		name, checksum, ok, err := exportEra1Epoch(ctx, db, blockReader, receiptsGenerator, chainConfig, outDir, epoch, logger)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if _, err := fmt.Fprintf(checksums, "%s %s\n", checksum, name); err != nil {
+			return err
+		}
+		if expected == nil {
+			continue
+		}
+		if want, ok := expected[name]; !ok {
+			logger.Warn("[era1] No checksum to compare with", "file", name)
+		} else if want != checksum {
+			logger.Error("[era1] Checksum mismatch", "file", name, "checksum", checksum, "expected", want)
+			mismatches = append(mismatches, name)
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("checksums of %d era1 files don't match: %s", len(mismatches), strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// exportEra1Epoch writes the era1 file of the epoch. Returns false if there are no pre-merge blocks to export:
+// the epoch is past the merge or is not complete yet.
+func exportEra1Epoch(ctx context.Context, db kv.TemporalRoDB, blockReader services.FullBlockReader, receiptsGenerator *receipts.Generator, chainConfig *chain.Config, dir string, epoch uint64, logger log.Logger) (name, checksum string, ok bool, err error) {
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return "", "", false, err
+	}
+	defer tx.Rollback()
+
+	tmpPath := filepath.Join(dir, fmt.Sprintf("%s-%05d%s.tmp", chainConfig.ChainName, epoch, era1.Extension))
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return "", "", false, err
+	}
+	defer func() {
+		if f != nil {
+			f.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+	w := bufio.NewWriterSize(f, 4*1024*1024)
+	hasher := sha256.New()
+	builder := era1.NewBuilder(io.MultiWriter(w, hasher))
+
+	logEvery := time.NewTicker(30 * time.Second)
+	defer logEvery.Stop()
+	var count uint64
+	for num := epoch * era1.MaxEra1Size; num < (epoch+1)*era1.MaxEra1Size; num++ {
+		block, err := blockReader.BlockByNumber(ctx, tx, num)
+		if err != nil {
+			return "", "", false, err
+		}
+		if block == nil {
+			logger.Info("[era1] Stopping at the incomplete epoch", "epoch", epoch, "missing block", num)
+			return "", "", false, nil
+		}
+		if block.Difficulty().Sign() == 0 {
+			break // the merge: the last era1 file has less blocks
+		}
+		td, err := rawdb.ReadTd(tx, block.Hash(), num)
+		if err != nil {
+			return "", "", false, err
+		}
+		if td == nil {
+			return "", "", false, fmt.Errorf("no total difficulty of block %d", num)
+		}
+		var blockReceipts types.Receipts
+		if len(block.Transactions()) > 0 {
+			if blockReceipts, err = receiptsGenerator.GetReceipts(ctx, chainConfig, tx, block); err != nil {
+				return "", "", false, fmt.Errorf("receipts of block %d: %w", num, err)
+			}
+		}
+		if root := types.DeriveSha(blockReceipts); root != block.ReceiptHash() {
+			return "", "", false, fmt.Errorf("block %d: receipts root %x, header %x", num, root, block.ReceiptHash())
+		}
+		if err := builder.Add(block, blockReceipts, td); err != nil {
+			return "", "", false, err
+		}
+		count++
+
+		select {
+		case <-ctx.Done():
+			return "", "", false, ctx.Err()
+		case <-logEvery.C:
+			logger.Info("[era1] Exporting", "epoch", epoch, "block", num)
+		default:
+		}
+	}
+	if count == 0 {
+		return "", "", false, nil
+	}
+
+	root, err := builder.Finalize()
+	if err != nil {
+		return "", "", false, err
+	}
+	if err := w.Flush(); err != nil {
+		return "", "", false, err
+	}
+	if err := f.Sync(); err != nil {
+		return "", "", false, err
+	}
+	if err := f.Close(); err != nil {
+		return "", "", false, err
+	}
+	f = nil
+	name = era1.Filename(chainConfig.ChainName, epoch, root)
+	if err := os.Rename(tmpPath, filepath.Join(dir, name)); err != nil {
+		return "", "", false, err
+	}
+	checksum = hex.EncodeToString(hasher.Sum(nil))
+	logger.Info("[era1] Exported", "file", name, "blocks", count, "accumulator", root, "sha256", checksum)
+	return name, checksum, true, nil
+}
+
+func importEra1(cliCtx *cli.Context) error {
+	if cliCtx.NArg() < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+	logger, _, _, err := debug.Setup(cliCtx, true /* rootLogger */)
+	if err != nil {
+		return err
+	}
+	ctx := cliCtx.Context
+	var checksums map[string]string
+	if path := cliCtx.String(Era1ChecksumsFlag.Name); path != "" {
+		if checksums, err = readEra1Checksums(path); err != nil {
+			return err
+		}
+	}
+
+	dirs := datadir.New(cliCtx.String(utils.DataDirFlag.Name))
+	db := dbCfg(kv.ChainDB, dirs.Chaindata).MustOpen()
+	defer db.Close()
+	chainConfig := fromdb.ChainConfig(db)
+	var roots []common.Hash
+	if path := cliCtx.String(Era1RootsFlag.Name); path != "" {
+		if roots, err = readEra1Roots(path); err != nil {
+			return err
+		}
+	} else if roots, err = era1.KnownRoots(chainConfig.ChainName); err != nil {
+		return err
+	}
+	if len(roots) == 0 {
+		return fmt.Errorf("no known accumulator roots of the %s chain: set --era1.roots", chainConfig.ChainName)
+	}
+	files, err := era1.Files(cliCtx.Args().First(), chainConfig.ChainName)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no %s era1 files in %s", chainConfig.ChainName, cliCtx.Args().First())
+	}
+	cfg := ethconfig.NewSnapCfg(false, true, true, chainConfig.ChainName)
+	_, _, _, br, _, clean, err := openSnaps(ctx, cfg, dirs, 0, db, logger)
+	if err != nil {
+		return err
+	}
+	defer clean()
+	blockReader, _ := br.IO()
+
+	var imported, last uint64
+	for _, path := range files {
+		if checksums != nil {
+			if err := checkEra1Checksum(path, checksums); err != nil {
+				return err
+			}
+		}
+		n, lastInFile, err := importEra1File(ctx, db, blockReader, path, roots, logger)
+		if err != nil {
+			return fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		if n > 0 {
+			imported, last = imported+n, lastInFile
+		}
+	}
+	if imported == 0 {
+		logger.Info("[era1] All the blocks are in the chain already")
+		return nil
+	}
+
+	logger.Info("[era1] Moving the imported blocks to the snapshots", "blocks", imported, "last", last)
+	if err := br.RetireBlocks(ctx, 0, last, log.LvlInfo, nil, nil, nil); err != nil {
+		return err
+	}
+	for deleted := math.MaxInt; deleted > 0; { // prune happens by small steps
+		if err := db.UpdateNosync(ctx, func(tx kv.RwTx) error {
+			deleted, err = br.PruneAncientBlocks(tx, 100)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importEra1File writes the blocks of the era1 file which are not in the chain yet, and checks the others
+// against the chain. The accumulator root of the file must be the known root of its epoch in roots.
+// Returns the number of imported blocks and the last one.
+func importEra1File(ctx context.Context, db kv.RwDB, blockReader services.FullBlockReader, path string, roots []common.Hash, logger log.Logger) (imported, last uint64, err error) {
+	_, epoch, shortRoot, err := era1.ParseFilename(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	e, err := era1.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer e.Close()
+	if e.Start() != epoch*era1.MaxEra1Size {
+		return 0, 0, fmt.Errorf("the first block %d is not the first block of the epoch %d", e.Start(), epoch)
+	}
+
+	tx, err := db.BeginRw(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+	head, err := stages.GetStageProgress(tx, stages.Headers)
+	if err != nil {
+		return 0, 0, err
+	}
+	bodies, err := stages.GetStageProgress(tx, stages.Bodies)
+	if err != nil {
+		return 0, 0, err
+	}
+	if bodies != head {
+		return 0, 0, fmt.Errorf("the chain has headers up to %d, bodies up to %d: finish the sync of the bodies first", head, bodies)
+	}
+
+	var parentHash common.Hash
+	var parentTd *big.Int
+	if e.Start() > 0 {
+		var ok bool
+		if parentHash, ok, err = blockReader.CanonicalHash(ctx, tx, e.Start()-1); err != nil {
+			return 0, 0, err
+		}
+		if !ok {
+			return 0, 0, fmt.Errorf("block %d is not in the chain: import the previous epochs first", e.Start()-1)
+		}
+		if parentTd, err = rawdb.ReadTd(tx, parentHash, e.Start()-1); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	var first uint64
+	var lastHash common.Hash
+	root, err := e.Verify(parentTd, func(t *era1.BlockTuple) error {
+		num, hash := t.Header.Number.Uint64(), t.Header.Hash()
+		if num == e.Start() && num > 0 && t.Header.ParentHash != parentHash {
+			return fmt.Errorf("block %d: parent hash %x, the chain has %x", num, t.Header.ParentHash, parentHash)
+		}
+		if num <= head {
+			canonical, ok, err := blockReader.CanonicalHash(ctx, tx, num)
+			if err != nil {
+				return err
+			}
+			if !ok || canonical != hash {
+				return fmt.Errorf("block %d: %x, the chain has %x", num, hash, canonical)
+			}
+			return nil
+		}
+		if num != head+1 {
+			return fmt.Errorf("block %d: the chain ends at %d", num, head)
+		}
+
+		if err := rawdb.WriteHeader(tx, t.Header); err != nil {
+			return err
+		}
+		if err := rawdb.WriteTd(tx, hash, num, t.TotalDifficulty); err != nil {
+			return err
+		}
+		if err := rawdb.WriteCanonicalHash(tx, hash, num); err != nil {
+			return err
+		}
+		if _, err := rawdb.WriteRawBodyIfNotExists(tx, hash, num, types.NewBlockFromNetwork(t.Header, t.Body).RawBody()); err != nil {
+			return err
+		}
+		if imported == 0 {
+			first = num
+		}
+		imported++
+		head, lastHash = num, hash
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	if !bytes.Equal(root[:len(shortRoot)], shortRoot) {
+		return 0, 0, fmt.Errorf("accumulator root %x doesn't match the file name", root)
+	}
+	if epoch >= uint64(len(roots)) {
+		return 0, 0, fmt.Errorf("no known accumulator root of epoch %d", epoch)
+	}
+	if roots[epoch] != root {
+		return 0, 0, fmt.Errorf("accumulator root %x, the known root is %x", root, roots[epoch])
+	}
+
+	if imported == 0 {
+		logger.Info("[era1] Verified", "epoch", epoch, "blocks", e.Count(), "accumulator", root)
+		return 0, 0, nil
+	}
+	if err := rawdb.AppendCanonicalTxNums(tx, first); err != nil {
+		return 0, 0, err
+	}
+	if err := rawdb.WriteHeadHeaderHash(tx, lastHash); err != nil {
+		return 0, 0, err
+	}
+	for _, stage := range []stages.SyncStage{stages.Headers, stages.BlockHashes, stages.Bodies} {
+		if err := stages.SaveStageProgress(tx, stage, head); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+	logger.Info("[era1] Imported", "epoch", epoch, "blocks", imported, "last", head, "accumulator", root)
+	return imported, head, nil
+}
+
+// readEra1Checksums reads the sha256 checksums of the era1 files: `<checksum> <file name>` per line, as written
+// by export-era1 and sha256sum
+func readEra1Checksums(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	checksums := map[string]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected `<checksum> <file name>`", path, i+1)
+		}
+		checksum := strings.ToLower(strings.TrimPrefix(fields[0], "0x"))
+		if b, err := hex.DecodeString(checksum); err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%s:%d: malformed checksum %q", path, i+1, fields[0])
+		}
+		checksums[filepath.Base(strings.TrimPrefix(fields[1], "*"))] = checksum
+	}
+	return checksums, nil
+}
+
+func checkEra1Checksum(path string, checksums map[string]string) error {
+	want, ok := checksums[filepath.Base(path)]
+	if !ok {
+		return fmt.Errorf("no checksum of %s", filepath.Base(path))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, f); err != nil {
+		return err
+	}
+	if checksum := hex.EncodeToString(hasher.Sum(nil)); checksum != want {
+		return fmt.Errorf("%s: checksum %s, expected %s", filepath.Base(path), checksum, want)
+	}
+	return nil
+}
+
+// readEra1Roots reads the accumulator roots of the epochs, in the format of era1.ParseRoots
+func readEra1Roots(path string) ([]common.Hash, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	roots, err := era1.ParseRoots(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(roots) == 0 {
+		return nil, errors.New(path + ": no accumulator roots")
+	}
+	return roots, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package app
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/rawdb"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/snapshotsync/era1"
+	"github.com/erigontech/erigon/turbo/stages/mock"
+)
+
+func TestImportEra1File(t *testing.T) {
+	m := mock.Mock(t)
+	signer := types.LatestSigner(m.ChainConfig)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 3, func(i int, b *core.BlockGen) {
+		txn, err := types.SignTx(types.NewTransaction(b.TxNonce(m.Address), common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1_000_000_000), nil), *signer, m.Key)
+		require.NoError(t, err)
+		b.AddTx(txn)
+	})
+	require.NoError(t, err)
+
+	// the epoch 0 starts with the genesis, which is in the chain already
+	blocks := append([]*types.Block{m.Genesis}, chain.Blocks...)
+	receipts := append([]types.Receipts{nil}, chain.Receipts...)
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "building"))
+	require.NoError(t, err)
+	builder := era1.NewBuilder(f)
+	td := new(big.Int)
+	for i, block := range blocks {
+		td = new(big.Int).Add(td, block.Difficulty())
+		require.NoError(t, builder.Add(block, receipts[i], td))
+	}
+	root, err := builder.Finalize()
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	path := filepath.Join(dir, era1.Filename("mainnet", 0, root))
+	require.NoError(t, os.Rename(filepath.Join(dir, "building"), path))
+
+	// an unknown epoch or a root other than the known one import nothing
+	_, _, err = importEra1File(m.Ctx, m.DB, m.BlockReader, path, []common.Hash{}, log.New())
+	require.ErrorContains(t, err, "no known accumulator root of epoch 0")
+	_, _, err = importEra1File(m.Ctx, m.DB, m.BlockReader, path, []common.Hash{{1}}, log.New())
+	require.ErrorContains(t, err, "the known root is")
+
+	imported, last, err := importEra1File(m.Ctx, m.DB, m.BlockReader, path, []common.Hash{root}, log.New())
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), imported)
+	require.Equal(t, uint64(3), last)
+
+	tx, err := m.DB.BeginRo(m.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	for _, block := range chain.Blocks {
+		hash, ok, err := m.BlockReader.CanonicalHash(m.Ctx, tx, block.NumberU64())
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, block.Hash(), hash)
+		body, err := m.BlockReader.BodyWithTransactions(m.Ctx, tx, hash, block.NumberU64())
+		require.NoError(t, err)
+		require.Len(t, body.Transactions, 1)
+		require.Equal(t, block.Transactions()[0].Hash(), body.Transactions[0].Hash())
+	}
+	storedTd, err := rawdb.ReadTd(tx, blocks[3].Hash(), 3)
+	require.NoError(t, err)
+	require.Equal(t, td, storedTd)
+	progress, err := stages.GetStageProgress(tx, stages.Bodies)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), progress)
+	tx.Rollback()
+
+	// a second import only checks the blocks against the chain
+	imported, _, err = importEra1File(m.Ctx, m.DB, m.BlockReader, path, []common.Hash{root}, log.New())
+	require.NoError(t, err)
+	require.Zero(t, imported)
+}
@@ -80,6 +80,8 @@ func MakeApp(name string, action cli.ActionFunc, cliFlags []cli.Flag) *cli.App {
 	app.Commands = []*cli.Command{
 		&initCommand,
 		&importCommand,
+		&exportEra1Command,
+		&importEra1Command,
 		&snapshotCommand,
 		&supportCommand,
 		//&backupCommand,
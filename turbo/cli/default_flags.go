@@ -41,6 +41,8 @@ var DefaultFlags = []cli.Flag{
 	&utils.TxPoolAccountQueueFlag,
 	&utils.TxPoolGlobalQueueFlag,
 	&utils.TxPoolLifetimeFlag,
+	&utils.TxPoolPrivateTxnLifetimeFlag,
+	&utils.TxPoolBlobStoreSizeFlag,
 	&utils.TxPoolTraceSendersFlag,
 	&utils.TxPoolCommitEveryFlag,
 	&PruneDistanceFlag,
@@ -83,12 +85,17 @@ var DefaultFlags = []cli.Flag{
 	&utils.RpcStreamingDisableFlag,
 	&utils.DBReadConcurrencyFlag,
 	&utils.RpcAccessListFlag,
+	&utils.RpcRateLimitFlag,
 	&utils.RpcTraceCompatFlag,
 	&utils.RpcGasCapFlag,
 	&utils.RpcBatchLimit,
 	&utils.RpcReturnDataLimit,
 	&utils.AllowUnprotectedTxs,
 	&utils.RpcMaxGetProofRewindBlockCount,
+	&utils.RpcKeystoreFlag,
+	&utils.RpcKeystorePasswordFileFlag,
+	&utils.RpcAllowInsecureUnlockFlag,
+	&utils.RpcExternalSignerFlag,
 	&utils.RPCGlobalTxFeeCapFlag,
 	&utils.TxpoolApiAddrFlag,
 	&utils.TraceMaxtracesFlag,
@@ -111,6 +118,8 @@ var DefaultFlags = []cli.Flag{
 	&utils.SnapKeepBlocksFlag,
 	&utils.SnapStopFlag,
 	&utils.SnapStateStopFlag,
+	&utils.SnapScrubIntervalFlag,
+	&utils.SnapScrubRateFlag,
 	&utils.DbPageSizeFlag,
 	&utils.DbSizeLimitFlag,
 	&utils.DbWriteMapFlag,
@@ -153,6 +162,8 @@ var DefaultFlags = []cli.Flag{
 	&utils.ProposingDisableFlag,
 	&utils.MinerNotifyFlag,
 	&utils.MinerGasLimitFlag,
+	&utils.MinerTxnOrderingFlag,
+	&utils.MinerPrioritySendersFlag,
 	&utils.MinerEtherbaseFlag,
 	&utils.MinerExtraDataFlag,
 	&utils.MinerNoVerfiyFlag,
@@ -176,6 +187,7 @@ var DefaultFlags = []cli.Flag{
 	&utils.PolygonSyncFlag,
 	&utils.PolygonSyncStageFlag,
 	&utils.EthStatsURLFlag,
+	&utils.LogExporterConfigFlag,
 	&utils.OverridePragueFlag,
 
 	&utils.CaplinDiscoveryAddrFlag,
@@ -219,6 +231,16 @@ var DefaultFlags = []cli.Flag{
 
 	&utils.CaplinBackfillingFlag,
 	&utils.CaplinBlobBackfillingFlag,
+	&utils.CaplinEraDirFlag,
+	&utils.CaplinValidatorFlag,
+	&utils.CaplinValidatorKeystoresFlag,
+	&utils.CaplinValidatorSecretsFlag,
+	&utils.CaplinValidatorFeeRecipientFlag,
+	&utils.CaplinValidatorGraffitiFlag,
+	&utils.CaplinValidatorRemoteSignerFlag,
+	&utils.CaplinKeymanagerAddrFlag,
+	&utils.CaplinKeymanagerPortFlag,
+	&utils.CaplinKeymanagerTokenFileFlag,
 	&utils.CaplinDisableBlobPruningFlag,
 	&utils.CaplinDisableCheckpointSyncFlag,
 	&utils.CaplinArchiveFlag,
@@ -526,6 +526,7 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config, logger log.Logg
 		RpcStreamingDisable:               ctx.Bool(utils.RpcStreamingDisableFlag.Name),
 		DBReadConcurrency:                 ctx.Int(utils.DBReadConcurrencyFlag.Name),
 		RpcAllowListFilePath:              ctx.String(utils.RpcAccessListFlag.Name),
+		RpcRateLimitFilePath:              ctx.String(utils.RpcRateLimitFlag.Name),
 		RpcFiltersConfig: rpchelper.FiltersConfig{
 			RpcSubscriptionFiltersMaxLogs:      ctx.Int(RpcSubscriptionFiltersMaxLogsFlag.Name),
 			RpcSubscriptionFiltersMaxHeaders:   ctx.Int(RpcSubscriptionFiltersMaxHeadersFlag.Name),
@@ -541,6 +542,10 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config, logger log.Logg
 		ReturnDataLimit:             ctx.Int(utils.RpcReturnDataLimit.Name),
 		AllowUnprotectedTxs:         ctx.Bool(utils.AllowUnprotectedTxs.Name),
 		MaxGetProofRewindBlockCount: ctx.Int(utils.RpcMaxGetProofRewindBlockCount.Name),
+		KeystoreDir:                 ctx.String(utils.RpcKeystoreFlag.Name),
+		KeystorePasswordFile:        ctx.String(utils.RpcKeystorePasswordFileFlag.Name),
+		ExternalSigner:              ctx.String(utils.RpcExternalSignerFlag.Name),
+		AllowInsecureUnlock:         ctx.Bool(utils.RpcAllowInsecureUnlockFlag.Name),
 
 		OtsMaxPageSize: ctx.Uint64(utils.OtsSearchMaxCapFlag.Name),
 
@@ -28,6 +28,7 @@ var ourCapabilities = []string{
 	"engine_getPayloadBodiesByHashV1",
 	"engine_getPayloadBodiesByRangeV1",
 	"engine_getClientVersionV1",
+	"engine_getBlobsV1",
 }
 
 // Returns the most recent version of the payload(for the payloadID) at the time of receiving the call
@@ -155,6 +156,13 @@ func (e *EngineServer) GetClientVersionV1(ctx context.Context, callerVersion *en
 	return result, nil
 }
 
+// Returns the blobs and proofs for the given versioned hashes from the txpool, null for the ones not found
+// See https://github.com/ethereum/execution-apis/blob/main/src/engine/cancun.md#engine_getblobsv1
+func (e *EngineServer) GetBlobsV1(ctx context.Context, blobHashes []libcommon.Hash) ([]*engine_types.BlobAndProofV1, error) {
+	e.logger.Debug("[GetBlobsV1] Received Request", "hashes", len(blobHashes))
+	return e.getBlobs(ctx, blobHashes)
+}
+
 func (e *EngineServer) ExchangeCapabilities(fromCl []string) []string {
 	e.engineLogSpamer.RecordRequest()
 	missingOurs := compareCapabilities(fromCl, ourCapabilities)
@@ -32,6 +32,7 @@ import (
 	"github.com/erigontech/erigon-lib/gointerfaces"
 	execution "github.com/erigontech/erigon-lib/gointerfaces/executionproto"
 	txpool "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
+	"github.com/erigontech/erigon-lib/gointerfaces/typesproto"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/kvcache"
 	"github.com/erigontech/erigon-lib/log/v3"
@@ -66,6 +67,7 @@ type EngineServer struct {
 	test             bool
 	caplin           bool // we need to send errors for caplin.
 	executionService execution.ExecutionClient
+	txpool           txpool.TxpoolClient // needed for getBlobs
 
 	chainRW eth1_chain_reader.ChainReaderWriterEth1
 	lock    sync.Mutex
@@ -108,6 +110,7 @@ func (e *EngineServer) Start(
 	if !e.caplin {
 		e.engineLogSpamer.Start(ctx)
 	}
+	e.txpool = txPool
 	base := jsonrpc.NewBaseApi(filters, stateCache, blockReader, httpConfig.WithDatadir, httpConfig.EvmCallTimeout, engineReader, httpConfig.Dirs, nil)
 
 	ethImpl := jsonrpc.NewEthAPI(base, db, eth, txPool, mining, httpConfig.Gascap, httpConfig.Feecap, httpConfig.ReturnDataLimit, httpConfig.AllowUnprotectedTxs, httpConfig.MaxGetProofRewindBlockCount, httpConfig.WebsocketSubscribeLogsChannelSize, e.logger)
@@ -687,6 +690,35 @@ func (s *EngineServer) getPayloadBodiesByRange(ctx context.Context, start, count
 	return resp, nil
 }
 
+func (s *EngineServer) getBlobs(ctx context.Context, blobHashes []libcommon.Hash) ([]*engine_types.BlobAndProofV1, error) {
+	if len(blobHashes) > 128 {
+		return nil, &engine_helpers.TooLargeRequestErr
+	}
+	if s.txpool == nil {
+		return nil, errors.New("txpool is not available")
+	}
+	req := &txpool.GetBlobsRequest{BlobHashes: make([]*typesproto.H256, len(blobHashes))}
+	for i := range blobHashes {
+		req.BlobHashes[i] = gointerfaces.ConvertHashToH256(blobHashes[i])
+	}
+	res, err := s.txpool.GetBlobs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*engine_types.BlobAndProofV1, len(blobHashes))
+	for i, bwp := range res.BlobsAndProofs {
+		if i >= len(ret) {
+			break
+		}
+		if bwp == nil || len(bwp.Blob) == 0 {
+			continue // null for blobs not in the pool
+		}
+		ret[i] = &engine_types.BlobAndProofV1{Blob: bwp.Blob, Proof: bwp.Proof}
+	}
+	return ret, nil
+}
+
 func compareCapabilities(from []string, to []string) []string {
 	result := make([]string, 0)
 	for _, f := range from {
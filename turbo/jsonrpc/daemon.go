@@ -17,10 +17,18 @@
 package jsonrpc
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
 	txpool "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/kvcache"
 	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/accounts"
+	"github.com/erigontech/erigon/accounts/external"
+	"github.com/erigontech/erigon/accounts/keystore"
 	"github.com/erigontech/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/erigontech/erigon/consensus"
 	"github.com/erigontech/erigon/consensus/clique"
@@ -35,13 +43,17 @@ func APIList(db kv.TemporalRoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolC
 	filters *rpchelper.Filters, stateCache kvcache.Cache,
 	blockReader services.FullBlockReader, cfg *httpcfg.HttpCfg, engine consensus.EngineReader,
 	logger log.Logger, bridgeReader bridgeReader, spanProducersReader spanProducersReader,
-) (list []rpc.API) {
+) (list []rpc.API, err error) {
 	base := NewBaseApi(filters, stateCache, blockReader, cfg.WithDatadir, cfg.EvmCallTimeout, engine, cfg.Dirs, bridgeReader)
 	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.Feecap, cfg.ReturnDataLimit, cfg.AllowUnprotectedTxs, cfg.MaxGetProofRewindBlockCount, cfg.WebsocketSubscribeLogsChannelSize, logger)
+	if ethImpl.AccountSigner, err = openAccountSigner(cfg, logger); err != nil {
+		return nil, fmt.Errorf("cannot open account signer: %w", err)
+	}
 	erigonImpl := NewErigonAPI(base, db, eth)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
 	netImpl := NewNetAPIImpl(eth)
 	debugImpl := NewPrivateDebugAPI(base, db, cfg.Gascap)
+	debugImpl.MaxGetProofRewindBlockCount = cfg.MaxGetProofRewindBlockCount
 	traceImpl := NewTraceAPI(base, db, cfg)
 	web3Impl := NewWeb3APIImpl(eth)
 	dbImpl := NewDBAPIImpl() /* deprecated */
@@ -177,5 +189,47 @@ func APIList(db kv.TemporalRoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolC
 		}
 	}
 
-	return list
+	return list, nil
+}
+
+// openAccountSigner opens the keystore or external signer configured in cfg; it returns nil if none is.
+func openAccountSigner(cfg *httpcfg.HttpCfg, logger log.Logger) (accounts.Signer, error) {
+	switch {
+	case cfg.KeystoreDir != "" && cfg.ExternalSigner != "":
+		return nil, errors.New("--rpc.keystore and --rpc.signer are mutually exclusive")
+	case cfg.ExternalSigner != "":
+		signer, err := external.Dial(context.Background(), cfg.ExternalSigner, logger)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Using external account signer", "endpoint", cfg.ExternalSigner)
+		return signer, nil
+	case cfg.KeystoreDir != "":
+		if cfg.HttpServerEnabled && !isLoopback(cfg.HttpListenAddress) && !cfg.AllowInsecureUnlock {
+			return nil, fmt.Errorf("refusing to unlock --rpc.keystore while the http server listens on %s, which is not a loopback address, see --rpc.allow-insecure-unlock", cfg.HttpListenAddress)
+		}
+		var passwords []string
+		if cfg.KeystorePasswordFile != "" {
+			var err error
+			if passwords, err = keystore.ReadPasswordFile(cfg.KeystorePasswordFile); err != nil {
+				return nil, err
+			}
+		}
+		ks, err := keystore.Open(cfg.KeystoreDir, passwords)
+		if err != nil {
+			return nil, err
+		}
+		addrs, _ := ks.Accounts(context.Background())
+		logger.Info("Using keystore account signer", "dir", cfg.KeystoreDir, "accounts", len(addrs))
+		return ks, nil
+	}
+	return nil, nil
+}
+
+func isLoopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
 }
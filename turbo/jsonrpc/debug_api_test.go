@@ -34,8 +34,12 @@ import (
 	"github.com/erigontech/erigon-lib/kv/order"
 	"github.com/erigontech/erigon-lib/kv/rawdbv3"
 	"github.com/erigontech/erigon-lib/kv/stream"
+	"github.com/erigontech/erigon-lib/rlp"
 	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/erigontech/erigon/core/rawdb"
+	"github.com/erigontech/erigon/core/stateless"
 	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
 	tracersConfig "github.com/erigontech/erigon/eth/tracers/config"
 	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/rpc/rpccfg"
@@ -518,3 +522,103 @@ func TestAccountAt(t *testing.T) {
 		require.Equal(0, int(results.Nonce))
 	})
 }
+
+func TestTraceBlockRlpAndBadBlock(t *testing.T) {
+	m, _, orphans := rpcdaemontest.CreateTestSentry(t)
+	api := NewPrivateDebugAPI(newBaseApiForTest(m), m.DB, 0)
+	trace := func(f func(stream *jsoniter.Stream) error) (string, error) {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		err := f(stream)
+		require.NoError(t, stream.Flush())
+		return buf.String(), err
+	}
+
+	var head uint64
+	require.NoError(t, m.DB.View(m.Ctx, func(tx kv.Tx) (err error) {
+		head, err = stages.GetStageProgress(tx, stages.Execution)
+		return err
+	}))
+	var expected []string
+	for n := uint64(1); n <= head; n++ {
+		blockRlp, err := api.GetRawBlock(m.Ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(n)))
+		require.NoError(t, err)
+		want, err := trace(func(stream *jsoniter.Stream) error {
+			return api.TraceBlockByNumber(m.Ctx, rpc.BlockNumber(n), &tracersConfig.TraceConfig{}, stream)
+		})
+		require.NoError(t, err)
+		got, err := trace(func(stream *jsoniter.Stream) error {
+			return api.TraceBlock(m.Ctx, blockRlp, &tracersConfig.TraceConfig{}, stream)
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, want, got, "block %d", n)
+		expected = append(expected, want)
+	}
+
+	// the parent of an orphaned block isn't canonical
+	orphanRlp, err := rlp.EncodeToBytes(orphans[0].Blocks[2])
+	require.NoError(t, err)
+	_, err = trace(func(stream *jsoniter.Stream) error {
+		return api.TraceBlock(m.Ctx, orphanRlp, &tracersConfig.TraceConfig{}, stream)
+	})
+	require.ErrorContains(t, err, "not canonical")
+
+	// mark the head as bad: it is still traced on top of its canonical parent
+	var headHash common.Hash
+	require.NoError(t, m.DB.Update(m.Ctx, func(tx kv.RwTx) (err error) {
+		if headHash, err = rawdb.ReadCanonicalHash(tx, head); err != nil {
+			return err
+		}
+		if err = rawdb.TruncateCanonicalHash(tx, head, true); err != nil {
+			return err
+		}
+		return rawdb.ResetBadBlockCache(tx, 100)
+	}))
+	// the cache of bad blocks is global, leave it empty for the other tests
+	t.Cleanup(func() {
+		require.NoError(t, m.DB.Update(m.Ctx, func(tx kv.RwTx) error {
+			if err := tx.Delete(kv.BadHeaderNumber, headHash[:]); err != nil {
+				return err
+			}
+			return rawdb.ResetBadBlockCache(tx, 100)
+		}))
+	})
+	got, err := trace(func(stream *jsoniter.Stream) error {
+		return api.TraceBadBlock(m.Ctx, headHash, &tracersConfig.TraceConfig{}, stream)
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, expected[len(expected)-1], got)
+
+	_, err = trace(func(stream *jsoniter.Stream) error {
+		return api.TraceBadBlock(m.Ctx, common.Hash{1}, &tracersConfig.TraceConfig{}, stream)
+	})
+	require.ErrorContains(t, err, "not found")
+}
+
+func TestExecutionWitnessVerifiesStatelessly(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	api := NewPrivateDebugAPI(newBaseApiForTest(m), m.DB, 0)
+	api.MaxGetProofRewindBlockCount = 100_000
+	// transfers, a contract deployment, token mints and transfers, a self-destruct and an empty block
+	for blockNum := uint64(1); blockNum <= 11; blockNum++ {
+		witness, err := api.ExecutionWitness(m.Ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum)))
+		require.NoError(t, err, blockNum)
+
+		// go through json, as a stateless client would get it
+		enc, err := json.Marshal(witness)
+		require.NoError(t, err)
+		var dec stateless.ExecutionWitness
+		require.NoError(t, json.Unmarshal(enc, &dec))
+
+		tx, err := m.DB.BeginRo(m.Ctx)
+		require.NoError(t, err)
+		block, err := m.BlockReader.BlockByNumber(m.Ctx, tx, blockNum)
+		tx.Rollback()
+		require.NoError(t, err)
+		require.NotNil(t, block)
+
+		root, err := stateless.VerifyBlock(m.ChainConfig, m.Engine, block, &dec, log.New())
+		require.NoError(t, err, blockNum)
+		require.Equal(t, block.Root(), root)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/kv/membatchwithdb"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/core/stateless"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+)
+
+// ExecutionWitness implements debug_executionWitness. Returns the geth-compatible witness of a block: the trie nodes,
+// codes and ancestor headers needed to execute the block without the state database (see core/stateless).
+func (api *PrivateDebugAPIImpl) ExecutionWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*stateless.ExecutionWitness, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNr, hash, _, err := rpchelper.GetCanonicalBlockNumber(ctx, blockNrOrHash, tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if blockNr == 0 {
+		return nil, errors.New("genesis block has no execution witness")
+	}
+	block, err := api.blockWithSenders(ctx, tx, hash, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d not found", blockNr)
+	}
+
+	batchTx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer batchTx.Rollback()
+	logger := log.New()
+	txBatch := membatchwithdb.NewMemoryBatch(batchTx, "", logger)
+	defer txBatch.Rollback()
+
+	gen, err := api.generateWitness(ctx, tx, txBatch, block, api.MaxGetProofRewindBlockCount, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, err := gen.witnessTrie.EncodedNodes()
+	if err != nil {
+		return nil, err
+	}
+	witness := &stateless.ExecutionWitness{
+		Headers: []*types.Header{gen.prevHeader},
+		Codes:   make([]hexutility.Bytes, 0, len(gen.codeReads)),
+		State:   make([]hexutility.Bytes, 0, len(nodes)),
+		Keys:    make([]hexutility.Bytes, 0, len(gen.touchedPlainKeys)),
+	}
+	for _, node := range nodes {
+		witness.State = append(witness.State, node)
+	}
+	for _, code := range gen.codeReads {
+		if len(code.Code) > 0 {
+			witness.Codes = append(witness.Codes, code.Code)
+		}
+	}
+
+	// touched keys are either addresses or address+slot composites, the preimages of the trie keys are the
+	// addresses and the slots on their own
+	seenAddresses := make(map[string]struct{}, len(gen.touchedPlainKeys))
+	for _, key := range gen.touchedPlainKeys {
+		addr := key[:length.Addr]
+		if _, ok := seenAddresses[string(addr)]; !ok {
+			seenAddresses[string(addr)] = struct{}{}
+			witness.Keys = append(witness.Keys, hexutility.Bytes(addr))
+		}
+		if len(key) > length.Addr {
+			witness.Keys = append(witness.Keys, hexutility.Bytes(key[length.Addr:]))
+		}
+	}
+
+	// ancestors needed by BLOCKHASH, from the grandparent down to the oldest one read
+	for header := gen.prevHeader; header.Number.Uint64() > gen.lowestBlockHashRead; {
+		header, err = api._blockReader.Header(ctx, tx, header.ParentHash, header.Number.Uint64()-1)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			return nil, errors.New("ancestor header not found")
+		}
+		witness.Headers = append(witness.Headers, header)
+	}
+	return witness, nil
+}
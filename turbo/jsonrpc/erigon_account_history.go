@@ -0,0 +1,236 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+const (
+	stateHistoryDefaultPageSize = 100
+	stateHistoryMaxPageSize     = 10_000
+)
+
+// AccountChange is a change of an account made by a txn. From is nil for created accounts and To is nil for deleted
+// ones.
+type AccountChange struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	// TransactionIndex is nil for the changes made outside of txns: block rewards, withdrawals, system calls
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
+	From             *AccountState   `json:"from"`
+	To               *AccountState   `json:"to"`
+}
+
+// AccountHistoryPage is a page of erigon_getAccountHistory results
+type AccountHistoryPage struct {
+	Changes []*AccountChange `json:"changes"`
+	// NextCursor is passed to get the next page, nil on the last page
+	NextCursor *hexutility.Bytes `json:"nextCursor"`
+}
+
+// StorageChange is a change of a storage slot made by a txn
+type StorageChange struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	// TransactionIndex is nil for the changes made outside of txns: system calls
+	TransactionIndex *hexutil.Uint64 `json:"transactionIndex"`
+	From             common.Hash     `json:"from"`
+	To               common.Hash     `json:"to"`
+}
+
+// StorageHistoryPage is a page of erigon_getStorageHistory results
+type StorageHistoryPage struct {
+	Changes []*StorageChange `json:"changes"`
+	// NextCursor is passed to get the next page, nil on the last page
+	NextCursor *hexutility.Bytes `json:"nextCursor"`
+}
+
+// GetAccountHistory implements erigon_getAccountHistory. Returns the changes of the account made in the blocks
+// [fromBlock, toBlock], oldest first, in pages of pageSize changes: the cursor of the next page is returned with
+// every page.
+func (api *ErigonImpl) GetAccountHistory(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, cursor *hexutility.Bytes, pageSize *hexutil.Uint64) (*AccountHistoryPage, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	page := &AccountHistoryPage{Changes: []*AccountChange{}}
+	page.NextCursor, err = api.walkStateHistory(ctx, tx, kv.AccountsHistoryIdx, kv.AccountsDomain, address[:], fromBlock, toBlock, cursor, pageSize, func(blockNum uint64, txIndex *hexutil.Uint64, from, to []byte) error {
+		change := &AccountChange{BlockNumber: hexutil.Uint64(blockNum), TransactionIndex: txIndex}
+		var err error
+		if change.From, err = decodeAccountState(from); err != nil {
+			return err
+		}
+		if change.To, err = decodeAccountState(to); err != nil {
+			return err
+		}
+		page.Changes = append(page.Changes, change)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// GetStorageHistory implements erigon_getStorageHistory. Returns the changes of the storage slot made in the blocks
+// [fromBlock, toBlock], oldest first, in pages of pageSize changes: the cursor of the next page is returned with
+// every page.
+func (api *ErigonImpl) GetStorageHistory(ctx context.Context, address common.Address, slot common.Hash, fromBlock, toBlock rpc.BlockNumber, cursor *hexutility.Bytes, pageSize *hexutil.Uint64) (*StorageHistoryPage, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	key := make([]byte, 0, len(address)+len(slot))
+	key = append(append(key, address[:]...), slot[:]...)
+	page := &StorageHistoryPage{Changes: []*StorageChange{}}
+	page.NextCursor, err = api.walkStateHistory(ctx, tx, kv.StorageHistoryIdx, kv.StorageDomain, key, fromBlock, toBlock, cursor, pageSize, func(blockNum uint64, txIndex *hexutil.Uint64, from, to []byte) error {
+		page.Changes = append(page.Changes, &StorageChange{
+			BlockNumber:      hexutil.Uint64(blockNum),
+			TransactionIndex: txIndex,
+			From:             common.BytesToHash(from),
+			To:               common.BytesToHash(to),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// walkStateHistory calls f with the changes of the key of the domain in the blocks [fromBlock, toBlock], read from
+// the inverted index of the history: the value before every change is the one of the history at its txNum, the
+// value after is the one as of the next txNum. Writes of the previous value are skipped. Returns the cursor of the
+// next page, nil if there are no more changes.
+func (api *ErigonImpl) walkStateHistory(ctx context.Context, tx kv.TemporalTx, idx kv.InvertedIdx, domain kv.Domain, key []byte, fromBlock, toBlock rpc.BlockNumber, cursor *hexutility.Bytes, pageSize *hexutil.Uint64, f func(blockNum uint64, txIndex *hexutil.Uint64, from, to []byte) error) (*hexutility.Bytes, error) {
+	limit := uint64(stateHistoryDefaultPageSize)
+	if pageSize != nil {
+		limit = min(max(uint64(*pageSize), 1), stateHistoryMaxPageSize)
+	}
+	from, _, _, err := rpchelper.GetBlockNumber(ctx, rpc.BlockNumberOrHashWithNumber(fromBlock), tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	to, _, _, err := rpchelper.GetBlockNumber(ctx, rpc.BlockNumberOrHashWithNumber(toBlock), tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, errors.New("invalid parameters: fromBlock cannot be greater than toBlock")
+	}
+
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, api._blockReader))
+	fromTxNum, err := txNumsReader.Min(tx, from)
+	if err != nil {
+		return nil, err
+	}
+	toTxNum, err := txNumsReader.Max(tx, to)
+	if err != nil {
+		return nil, err
+	}
+	toTxNum++
+	if cursor != nil {
+		if len(*cursor) != 8 {
+			return nil, fmt.Errorf("invalid parameters: malformed cursor %x", []byte(*cursor))
+		}
+		start := binary.BigEndian.Uint64(*cursor)
+		if start < fromTxNum || start >= toTxNum {
+			return nil, errors.New("invalid parameters: cursor out of the block range")
+		}
+		fromTxNum = start
+	}
+
+	it, err := tx.IndexRange(idx, key, int(fromTxNum), int(toTxNum), order.Asc, kv.Unlim)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var blockNum, blockMinTxNum, blockMaxTxNum uint64
+	var found bool
+	for n := uint64(0); it.HasNext(); {
+		txNum, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		if n == limit {
+			next := make(hexutility.Bytes, 8)
+			binary.BigEndian.PutUint64(next, txNum)
+			return &next, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		before, ok, err := tx.HistorySeek(domain, key, txNum)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("no history of %x at txNum %d", key, txNum)
+		}
+		after, _, err := tx.GetAsOf(domain, key, txNum+1)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(before, after) {
+			continue
+		}
+
+		if !found || txNum > blockMaxTxNum {
+			if found, blockNum, err = txNumsReader.FindBlockNum(tx, txNum); err != nil {
+				return nil, err
+			}
+			if !found {
+				return nil, fmt.Errorf("block not found by txNum %d", txNum)
+			}
+			if blockMinTxNum, err = txNumsReader.Min(tx, blockNum); err != nil {
+				return nil, err
+			}
+			if blockMaxTxNum, err = txNumsReader.Max(tx, blockNum); err != nil {
+				return nil, err
+			}
+		}
+		var txIndex *hexutil.Uint64
+		if txNum > blockMinTxNum && txNum < blockMaxTxNum { // the first and the last txNums of a block are system txns
+			i := hexutil.Uint64(txNum - blockMinTxNum - 1)
+			txIndex = &i
+		}
+		if err := f(blockNum, txIndex, before, after); err != nil {
+			return nil, err
+		}
+		n++
+	}
+	return nil, nil
+}
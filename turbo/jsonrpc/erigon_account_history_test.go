@@ -0,0 +1,129 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+func TestGetAccountAndStorageHistory(t *testing.T) {
+	m, chain, _ := rpcdaemontest.CreateTestSentry(t)
+	api := NewErigonAPI(newBaseApiForTest(m), m.DB, nil)
+	ctx := context.Background()
+
+	// the per-block state diffs are the reference: the changes of a block add up to its diff
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, m.BlockReader))
+	accountDiffs := map[common.Address]map[uint64]*AccountDiff{}
+	var storageDiff *StorageDiff
+	var storageDiffBlock uint64
+	for _, block := range chain.Blocks {
+		diff, err := readBlockStateDiff(ctx, tx, m.BlockReader, txNumsReader, block.NumberU64())
+		require.NoError(t, err)
+		for _, acc := range diff.Accounts {
+			if accountDiffs[acc.Address] == nil {
+				accountDiffs[acc.Address] = map[uint64]*AccountDiff{}
+			}
+			accountDiffs[acc.Address][block.NumberU64()] = acc
+		}
+		if storageDiff == nil && len(diff.Storage) > 0 {
+			storageDiff, storageDiffBlock = diff.Storage[0], block.NumberU64()
+		}
+	}
+	tx.Rollback()
+
+	// the most active account
+	var address common.Address
+	for addr, diffs := range accountDiffs {
+		if len(diffs) > len(accountDiffs[address]) {
+			address = addr
+		}
+	}
+	latest := rpc.BlockNumber(chain.Blocks[len(chain.Blocks)-1].NumberU64())
+	page, err := api.GetAccountHistory(ctx, address, 1, latest, nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, page.NextCursor)
+	require.GreaterOrEqual(t, len(page.Changes), len(accountDiffs[address]))
+
+	blocks := map[uint64]struct{}{}
+	for i, change := range page.Changes {
+		num := uint64(change.BlockNumber)
+		diff, ok := accountDiffs[address][num]
+		require.True(t, ok, "block %d", num)
+		if _, seen := blocks[num]; !seen {
+			require.Equal(t, diff.From, change.From)
+		}
+		if i == len(page.Changes)-1 || uint64(page.Changes[i+1].BlockNumber) != num {
+			require.Equal(t, diff.To, change.To)
+		}
+		if i > 0 {
+			require.Equal(t, page.Changes[i-1].To, change.From)
+		}
+		blocks[num] = struct{}{}
+	}
+	require.Len(t, blocks, len(accountDiffs[address]))
+
+	// pagination returns the same changes
+	var paged []*AccountChange
+	var cursor *hexutility.Bytes
+	pageSize := hexutil.Uint64(2)
+	for {
+		page, err := api.GetAccountHistory(ctx, address, 1, latest, cursor, &pageSize)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page.Changes), int(pageSize))
+		paged = append(paged, page.Changes...)
+		if page.NextCursor == nil {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	require.Equal(t, page.Changes, paged)
+
+	// a narrower block range
+	page, err = api.GetAccountHistory(ctx, address, 2, 2, nil, nil)
+	require.NoError(t, err)
+	for _, change := range page.Changes {
+		require.Equal(t, hexutil.Uint64(2), change.BlockNumber)
+	}
+	_, err = api.GetAccountHistory(ctx, address, 2, 1, nil, nil)
+	require.Error(t, err)
+
+	require.NotNil(t, storageDiff) // the test chain deploys contracts
+	storagePage, err := api.GetStorageHistory(ctx, storageDiff.Address, storageDiff.Slot, 1, latest, nil, nil)
+	require.NoError(t, err)
+	var found bool
+	for _, change := range storagePage.Changes {
+		if uint64(change.BlockNumber) == storageDiffBlock {
+			require.NotNil(t, change.TransactionIndex)
+			found = true
+		}
+	}
+	require.True(t, found)
+}
@@ -21,9 +21,11 @@ import (
 
 	"github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/eth/filters"
+	"github.com/erigontech/erigon/eth/tracers/config"
 	"github.com/erigontech/erigon/p2p"
 	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/turbo/rpchelper"
@@ -41,6 +43,10 @@ type ErigonAPI interface {
 	GetBlockByTimestamp(ctx context.Context, timeStamp rpc.Timestamp, fullTx bool) (map[string]interface{}, error)
 	GetBalanceChangesInBlock(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (map[common.Address]*hexutil.Big, error)
 
+	// History related (see ./erigon_account_history.go)
+	GetAccountHistory(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, cursor *hexutility.Bytes, pageSize *hexutil.Uint64) (*AccountHistoryPage, error)
+	GetStorageHistory(ctx context.Context, address common.Address, slot common.Hash, fromBlock, toBlock rpc.BlockNumber, cursor *hexutility.Bytes, pageSize *hexutil.Uint64) (*StorageHistoryPage, error)
+
 	// Receipt related (see ./erigon_receipts.go)
 	GetLogsByHash(ctx context.Context, hash common.Hash) ([][]*types.Log, error)
 	//GetLogsByNumber(ctx context.Context, number rpc.BlockNumber) ([][]*types.Log, error)
@@ -49,6 +55,9 @@ type ErigonAPI interface {
 	// Gets cannonical block receipt through hash. If the block is not cannonical returns error
 	GetBlockReceiptsByBlockHash(ctx context.Context, cannonicalBlockHash common.Hash) ([]map[string]interface{}, error)
 
+	// Trace related (see ./erigon_trace_filter.go)
+	TraceFilterV2(ctx context.Context, req TraceFilterRequest, cursor *hexutility.Bytes, traceConfig *config.TraceConfig) (*TraceFilterPage, error)
+
 	// NodeInfo returns a collection of metadata known about the host.
 	NodeInfo(ctx context.Context) ([]p2p.NodeInfo, error)
 }
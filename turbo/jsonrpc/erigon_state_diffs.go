@@ -0,0 +1,399 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/debug"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
+	"github.com/erigontech/erigon-lib/kv/rawdbv3"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/types/accounts"
+
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/turbo/services"
+	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
+)
+
+const (
+	// stateDiffsBatch is how many blocks are read in one db transaction while catching up
+	stateDiffsBatch = 256
+	// stateDiffsReorgDepth is how many emitted blocks are kept to notify their removal on reorg
+	stateDiffsReorgDepth = 128
+)
+
+// StateDiffsArgs are the arguments of erigon_subscribe("stateDiffs")
+type StateDiffsArgs struct {
+	// FromBlock is the first block to send the diff of, from history. If not set, only new blocks are sent.
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+}
+
+// BlockStateDiff is the change of state made by a block. When Removed is set, the block was reorged out and the
+// diff must be undone (From and To swapped).
+type BlockStateDiff struct {
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	ParentHash  common.Hash    `json:"parentHash"`
+	Removed     bool           `json:"removed"`
+	Accounts    []*AccountDiff `json:"accounts"`
+	Storage     []*StorageDiff `json:"storage"`
+	Codes       []*CodeDiff    `json:"codes"`
+}
+
+// StateDiffsResync is the last notification of a subscription when the chain reorged deeper than the blocks it
+// can undo: the diffs sent up to BlockNumber may be for blocks which are not canonical anymore. The subscriber has
+// to drop them and subscribe again from a block it knows to be canonical.
+type StateDiffsResync struct {
+	Resync      bool           `json:"resync"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+}
+
+// stateDiffsReorgTooDeepError is returned by the streamer when a block older than the emitted ones it keeps
+// left the canonical chain
+type stateDiffsReorgTooDeepError struct {
+	blockNum uint64
+}
+
+func (e *stateDiffsReorgTooDeepError) Error() string {
+	return fmt.Sprintf("reorg deeper than the %d blocks kept to undo: block %d is not canonical anymore", stateDiffsReorgDepth, e.blockNum)
+}
+
+// AccountDiff is the change of an account, From is nil for created accounts and To is nil for deleted ones
+type AccountDiff struct {
+	Address common.Address `json:"address"`
+	From    *AccountState  `json:"from"`
+	To      *AccountState  `json:"to"`
+}
+
+type AccountState struct {
+	Nonce    hexutil.Uint64 `json:"nonce"`
+	Balance  *hexutil.Big   `json:"balance"`
+	CodeHash common.Hash    `json:"codeHash"`
+}
+
+type StorageDiff struct {
+	Address common.Address `json:"address"`
+	Slot    common.Hash    `json:"slot"`
+	From    common.Hash    `json:"from"`
+	To      common.Hash    `json:"to"`
+}
+
+type CodeDiff struct {
+	Address common.Address   `json:"address"`
+	From    hexutility.Bytes `json:"from"`
+	To      hexutility.Bytes `json:"to"`
+}
+
+// StateDiffs implements erigon_subscribe("stateDiffs", {fromBlock}). Sends the account, storage and code changes of
+// every block from fromBlock on, read from history, then of every new block. On reorg, the diffs of the blocks
+// which left the canonical chain are sent again with removed=true before the ones of the new blocks. A reorg deeper
+// than the blocks kept to undo ends the subscription with a StateDiffsResync notification.
+func (api *ErigonImpl) StateDiffs(ctx context.Context, args StateDiffsArgs) (*rpc.Subscription, error) {
+	if api.filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	streamer, err := api.newStateDiffsStreamer(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		// subscribe before catching up, to not miss the blocks executed meanwhile
+		headers, id := api.filters.SubscribeNewHeads(32)
+		defer api.filters.UnsubscribeHeads(id)
+
+		subCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go func() {
+			select {
+			case <-rpcSub.Err():
+				cancel()
+			case <-subCtx.Done():
+			}
+		}()
+
+		notify := func(diff *BlockStateDiff) error {
+			return notifier.Notify(rpcSub.ID, diff)
+		}
+		for {
+			if err := streamer.catchUp(subCtx, api.db, notify); err != nil {
+				var tooDeep *stateDiffsReorgTooDeepError
+				if errors.As(err, &tooDeep) {
+					log.Debug("[rpc] stateDiffs subscription", "err", err)
+					if err := notifier.Notify(rpcSub.ID, &StateDiffsResync{Resync: true, BlockNumber: hexutil.Uint64(tooDeep.blockNum)}); err != nil {
+						log.Warn("[rpc] stateDiffs subscription", "err", err)
+					}
+					return
+				}
+				if subCtx.Err() == nil {
+					log.Warn("[rpc] stateDiffs subscription", "err", err)
+				}
+				return
+			}
+			select {
+			case _, ok := <-headers:
+				if !ok {
+					log.Warn("[rpc] new heads channel was closed")
+					return
+				}
+			case <-subCtx.Done():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+type emittedStateDiff struct {
+	num  uint64
+	hash common.Hash
+	diff *BlockStateDiff
+}
+
+// stateDiffsStreamer tracks which blocks were sent to a subscriber, to send the next ones and undo the reorged ones
+type stateDiffsStreamer struct {
+	blockReader services.FullBlockReader
+	nextNum     uint64
+	emitted     []emittedStateDiff
+	evicted     *emittedStateDiff // the latest block dropped from emitted, to detect the reorgs deeper than emitted
+}
+
+func (api *ErigonImpl) newStateDiffsStreamer(ctx context.Context, args StateDiffsArgs) (*stateDiffsStreamer, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	latest, err := rpchelper.GetLatestExecutedBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	s := &stateDiffsStreamer{blockReader: api._blockReader, nextNum: latest + 1}
+	if args.FromBlock != nil {
+		fromBlock, _, _, err := rpchelper.GetBlockNumber(ctx, rpc.BlockNumberOrHashWithNumber(*args.FromBlock), tx, api._blockReader, api.filters)
+		if err != nil {
+			return nil, err
+		}
+		if fromBlock > latest {
+			return nil, fmt.Errorf("fromBlock (%d) is later than the latest executed block (%d)", fromBlock, latest)
+		}
+		s.nextNum = fromBlock
+	}
+	return s, nil
+}
+
+// catchUp undoes the emitted blocks which are not canonical anymore, then sends the diffs of the blocks up to the
+// latest executed one
+func (s *stateDiffsStreamer) catchUp(ctx context.Context, db kv.TemporalRoDB, notify func(*BlockStateDiff) error) error {
+	for {
+		done, err := s.catchUpBatch(ctx, db, notify)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+func (s *stateDiffsStreamer) catchUpBatch(ctx context.Context, db kv.TemporalRoDB, notify func(*BlockStateDiff) error) (done bool, err error) {
+	tx, err := db.BeginTemporalRo(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	for len(s.emitted) > 0 {
+		last := s.emitted[len(s.emitted)-1]
+		canonicalHash, ok, err := s.blockReader.CanonicalHash(ctx, tx, last.num)
+		if err != nil {
+			return false, err
+		}
+		if ok && canonicalHash == last.hash {
+			break
+		}
+		removed := *last.diff
+		removed.Removed = true
+		if err := notify(&removed); err != nil {
+			return false, err
+		}
+		s.emitted = s.emitted[:len(s.emitted)-1]
+		s.nextNum = last.num
+	}
+	if len(s.emitted) == 0 && s.evicted != nil {
+		canonicalHash, ok, err := s.blockReader.CanonicalHash(ctx, tx, s.evicted.num)
+		if err != nil {
+			return false, err
+		}
+		if !ok || canonicalHash != s.evicted.hash {
+			return false, &stateDiffsReorgTooDeepError{blockNum: s.evicted.num}
+		}
+	}
+
+	latest, err := rpchelper.GetLatestExecutedBlockNumber(tx)
+	if err != nil {
+		return false, err
+	}
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, s.blockReader))
+	for i := 0; i < stateDiffsBatch && s.nextNum <= latest; i++ {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		diff, err := readBlockStateDiff(ctx, tx, s.blockReader, txNumsReader, s.nextNum)
+		if err != nil {
+			return false, err
+		}
+		if err := notify(diff); err != nil {
+			return false, err
+		}
+		s.emitted = append(s.emitted, emittedStateDiff{num: s.nextNum, hash: diff.BlockHash, diff: diff})
+		if len(s.emitted) > stateDiffsReorgDepth {
+			evicted := s.emitted[len(s.emitted)-stateDiffsReorgDepth-1]
+			evicted.diff = nil
+			s.evicted = &evicted
+			s.emitted = s.emitted[len(s.emitted)-stateDiffsReorgDepth:]
+		}
+		s.nextNum++
+	}
+	return s.nextNum > latest, nil
+}
+
+// readBlockStateDiff reads the state changed by the canonical block with the given number from history: the values
+// before the block are the ones of the history, the values after are the ones as of the next block
+func readBlockStateDiff(ctx context.Context, tx kv.TemporalTx, blockReader services.FullBlockReader, txNumsReader rawdbv3.TxNumsReader, blockNum uint64) (*BlockStateDiff, error) {
+	header, err := blockReader.HeaderByNumber(ctx, tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", blockNum)
+	}
+	fromTxNum, err := txNumsReader.Min(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	toTxNum, err := txNumsReader.Max(tx, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	toTxNum++
+
+	diff := &BlockStateDiff{
+		BlockNumber: hexutil.Uint64(blockNum),
+		BlockHash:   header.Hash(),
+		ParentHash:  header.ParentHash,
+		Accounts:    []*AccountDiff{},
+		Storage:     []*StorageDiff{},
+		Codes:       []*CodeDiff{},
+	}
+	err = forEachHistoryChange(tx, kv.AccountsDomain, fromTxNum, toTxNum, func(k, from, to []byte) error {
+		accDiff := &AccountDiff{Address: common.BytesToAddress(k)}
+		var err error
+		if accDiff.From, err = decodeAccountState(from); err != nil {
+			return err
+		}
+		if accDiff.To, err = decodeAccountState(to); err != nil {
+			return err
+		}
+		diff.Accounts = append(diff.Accounts, accDiff)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = forEachHistoryChange(tx, kv.StorageDomain, fromTxNum, toTxNum, func(k, from, to []byte) error {
+		if len(k) != length.Addr+length.Hash {
+			return fmt.Errorf("unexpected storage key length %d", len(k))
+		}
+		diff.Storage = append(diff.Storage, &StorageDiff{
+			Address: common.BytesToAddress(k[:length.Addr]),
+			Slot:    common.BytesToHash(k[length.Addr:]),
+			From:    common.BytesToHash(from),
+			To:      common.BytesToHash(to),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = forEachHistoryChange(tx, kv.CodeDomain, fromTxNum, toTxNum, func(k, from, to []byte) error {
+		diff.Codes = append(diff.Codes, &CodeDiff{Address: common.BytesToAddress(k), From: common.Copy(from), To: common.Copy(to)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// forEachHistoryChange calls f with the keys of the domain changed in [fromTxNum, toTxNum), with their values before
+// and after the range. Keys written with their previous value are skipped.
+func forEachHistoryChange(tx kv.TemporalTx, domain kv.Domain, fromTxNum, toTxNum uint64, f func(k, from, to []byte) error) error {
+	it, err := tx.HistoryRange(domain, int(fromTxNum), int(toTxNum), order.Asc, kv.Unlim)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		k, from, err := it.Next()
+		if err != nil {
+			return err
+		}
+		to, _, err := tx.GetAsOf(domain, k, toTxNum)
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(from, to) {
+			continue
+		}
+		if err := f(k, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeAccountState(enc []byte) (*AccountState, error) {
+	if len(enc) == 0 {
+		return nil, nil
+	}
+	var acc accounts.Account
+	if err := accounts.DeserialiseV3(&acc, enc); err != nil {
+		return nil, err
+	}
+	return &AccountState{
+		Nonce:    hexutil.Uint64(acc.Nonce),
+		Balance:  (*hexutil.Big)(acc.Balance.ToBig()),
+		CodeHash: acc.CodeHash,
+	}, nil
+}
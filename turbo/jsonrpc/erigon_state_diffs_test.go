@@ -0,0 +1,117 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/erigontech/erigon/rpc"
+)
+
+func TestStateDiffsStreamer(t *testing.T) {
+	m, chain, orphanedChains := rpcdaemontest.CreateTestSentry(t)
+	api := NewErigonAPI(newBaseApiForTest(m), m.DB, nil)
+	ctx := context.Background()
+
+	fromBlock := rpc.BlockNumber(1)
+	streamer, err := api.newStateDiffsStreamer(ctx, StateDiffsArgs{FromBlock: &fromBlock})
+	require.NoError(t, err)
+
+	var diffs []*BlockStateDiff
+	notify := func(diff *BlockStateDiff) error {
+		diffs = append(diffs, diff)
+		return nil
+	}
+	require.NoError(t, streamer.catchUp(ctx, m.DB, notify))
+	require.Len(t, diffs, len(chain.Blocks))
+	for i, diff := range diffs {
+		block := chain.Blocks[i]
+		require.Equal(t, block.NumberU64(), uint64(diff.BlockNumber))
+		require.Equal(t, block.Hash(), diff.BlockHash)
+		require.False(t, diff.Removed)
+		// every block pays the coinbase
+		require.NotEmpty(t, diff.Accounts)
+
+		// the new balances match erigon_getBalanceChangesInBlock
+		balances, err := api.GetBalanceChangesInBlock(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(block.NumberU64())))
+		require.NoError(t, err)
+		for _, acc := range diff.Accounts {
+			if balance, ok := balances[acc.Address]; ok {
+				require.NotNil(t, acc.To)
+				require.Equal(t, balance.String(), acc.To.Balance.String())
+			}
+		}
+	}
+	var codes int
+	for _, diff := range diffs {
+		for _, code := range diff.Codes {
+			require.Empty(t, code.From)
+			require.NotEmpty(t, code.To)
+			codes++
+		}
+	}
+	require.NotZero(t, codes) // the test chain deploys contracts
+
+	// nothing new
+	diffs = nil
+	require.NoError(t, streamer.catchUp(ctx, m.DB, notify))
+	require.Empty(t, diffs)
+
+	// pretend the orphaned blocks were sent, they get removed before the canonical ones are sent again
+	streamer.emitted = nil
+	for _, block := range orphanedChains[0].Blocks {
+		streamer.emitted = append(streamer.emitted, emittedStateDiff{num: block.NumberU64(), hash: block.Hash(), diff: &BlockStateDiff{BlockHash: block.Hash()}})
+	}
+	streamer.nextNum = orphanedChains[0].Blocks[len(orphanedChains[0].Blocks)-1].NumberU64() + 1
+	require.NoError(t, streamer.catchUp(ctx, m.DB, notify))
+	orphans := orphanedChains[0].Blocks
+	require.Len(t, diffs, len(orphans)+len(chain.Blocks))
+	for i := range orphans {
+		require.True(t, diffs[i].Removed)
+		require.Equal(t, orphans[len(orphans)-1-i].Hash(), diffs[i].BlockHash)
+	}
+	require.Equal(t, chain.Blocks[0].Hash(), diffs[len(orphans)].BlockHash)
+
+	// the first orphan is older than the kept blocks: the reorg can't be undone
+	diffs = nil
+	streamer.emitted = nil
+	for _, block := range orphans[1:] {
+		streamer.emitted = append(streamer.emitted, emittedStateDiff{num: block.NumberU64(), hash: block.Hash(), diff: &BlockStateDiff{BlockHash: block.Hash()}})
+	}
+	streamer.evicted = &emittedStateDiff{num: orphans[0].NumberU64(), hash: orphans[0].Hash()}
+	streamer.nextNum = orphans[len(orphans)-1].NumberU64() + 1
+	err = streamer.catchUp(ctx, m.DB, notify)
+	var tooDeep *stateDiffsReorgTooDeepError
+	require.ErrorAs(t, err, &tooDeep)
+	require.Equal(t, orphans[0].NumberU64(), tooDeep.blockNum)
+	require.Len(t, diffs, len(orphans)-1)
+	for _, diff := range diffs {
+		require.True(t, diff.Removed)
+	}
+
+	// a canonical evicted block doesn't stop the catch up
+	diffs = nil
+	streamer.emitted = nil
+	streamer.evicted = &emittedStateDiff{num: chain.Blocks[0].NumberU64(), hash: chain.Blocks[0].Hash()}
+	streamer.nextNum = chain.Blocks[1].NumberU64()
+	require.NoError(t, streamer.catchUp(ctx, m.DB, notify))
+	require.Len(t, diffs, len(chain.Blocks)-1)
+}
@@ -0,0 +1,132 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon/core/rawdb"
+	"github.com/erigontech/erigon/eth/tracers/config"
+)
+
+const (
+	traceFilterV2DefaultPageSize = 100
+	traceFilterV2MaxPageSize     = 10_000
+)
+
+// TraceFilterPage is a page of erigon_traceFilterV2 results
+type TraceFilterPage struct {
+	Traces []*ParityTrace `json:"traces"`
+	// NextCursor is passed to get the next page, nil on the last page
+	NextCursor *hexutility.Bytes `json:"nextCursor"`
+}
+
+// traceFilterCursor is the position of a trace: the txn it belongs to and its position among the traces of the txn
+type traceFilterCursor struct {
+	txNum uint64
+	pos   uint32
+}
+
+func (c traceFilterCursor) encode() *hexutility.Bytes {
+	b := make(hexutility.Bytes, 12)
+	binary.BigEndian.PutUint64(b, c.txNum)
+	binary.BigEndian.PutUint32(b[8:], c.pos)
+	return &b
+}
+
+func decodeTraceFilterCursor(b hexutility.Bytes) (traceFilterCursor, error) {
+	if len(b) != 12 {
+		return traceFilterCursor{}, fmt.Errorf("invalid parameters: malformed cursor %x", []byte(b))
+	}
+	return traceFilterCursor{txNum: binary.BigEndian.Uint64(b), pos: binary.BigEndian.Uint32(b[8:])}, nil
+}
+
+var errTraceFilterPageFull = errors.New("page full")
+
+// TraceFilterV2 implements erigon_traceFilterV2: trace_filter returning stable pages. Count is the page size, and
+// the cursor of the next page is returned with every page: unlike after/count, it stays valid while the chain grows.
+func (api *ErigonImpl) TraceFilterV2(ctx context.Context, req TraceFilterRequest, cursor *hexutility.Bytes, traceConfig *config.TraceConfig) (*TraceFilterPage, error) {
+	if req.After != nil {
+		return nil, errors.New("invalid parameters: after is not supported, use the cursor")
+	}
+	pageSize := uint64(traceFilterV2DefaultPageSize)
+	if req.Count != nil {
+		pageSize = min(max(*req.Count, 1), traceFilterV2MaxPageSize)
+	}
+
+	dbtx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	var fromBlock, toBlock uint64
+	if req.FromBlock != nil {
+		fromBlock = uint64(*req.FromBlock)
+	}
+	if req.ToBlock == nil {
+		headNumber, err := api._blockReader.HeaderNumber(ctx, dbtx, rawdb.ReadHeadHeaderHash(dbtx))
+		if err != nil {
+			return nil, err
+		}
+		if headNumber == nil {
+			return nil, errors.New("head header not found")
+		}
+		toBlock = *headNumber
+	} else {
+		toBlock = uint64(*req.ToBlock)
+	}
+	if fromBlock > toBlock {
+		return nil, errors.New("invalid parameters: fromBlock cannot be greater than toBlock")
+	}
+	fromTxNum, toTxNum, err := traceFilterTxNums(ctx, dbtx, api._blockReader, fromBlock, toBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	var start traceFilterCursor
+	if cursor != nil {
+		if start, err = decodeTraceFilterCursor(*cursor); err != nil {
+			return nil, err
+		}
+		if start.txNum < fromTxNum || start.txNum >= toTxNum {
+			return nil, errors.New("invalid parameters: cursor out of the block range")
+		}
+		fromTxNum = start.txNum
+	}
+
+	page := &TraceFilterPage{Traces: []*ParityTrace{}}
+	err = api.walkFilteredTraces(ctx, dbtx, fromTxNum, toTxNum, req, false /* compat */, false /* gasBailOut */, traceConfig, func(txNum uint64, pos int, pt *ParityTrace) error {
+		if txNum == start.txNum && uint32(pos) < start.pos {
+			return nil
+		}
+		if uint64(len(page.Traces)) == pageSize {
+			page.NextCursor = traceFilterCursor{txNum: txNum, pos: uint32(pos)}.encode()
+			return errTraceFilterPageFull
+		}
+		page.Traces = append(page.Traces, pt)
+		return nil
+	}, func(err error) error { return err })
+	if err != nil && !errors.Is(err, errTraceFilterPageFull) {
+		return nil, err
+	}
+	return page, nil
+}
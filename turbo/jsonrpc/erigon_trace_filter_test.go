@@ -0,0 +1,188 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/stream"
+	"github.com/erigontech/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
+)
+
+type filteredTrace struct {
+	Action struct {
+		CallType string           `json:"callType"`
+		Input    hexutility.Bytes `json:"input"`
+	} `json:"action"`
+	Error string `json:"error"`
+	Raw   json.RawMessage
+}
+
+func traceFilterAll(t *testing.T, api *TraceAPIImpl, req TraceFilterRequest) []filteredTrace {
+	t.Helper()
+	stream := jsoniter.ConfigDefault.BorrowStream(nil)
+	defer jsoniter.ConfigDefault.ReturnStream(stream)
+	require.NoError(t, api.Filter(context.Background(), req, new(bool), nil, stream))
+	var raws []json.RawMessage
+	require.NoError(t, json.Unmarshal(stream.Buffer(), &raws))
+	res := make([]filteredTrace, len(raws))
+	for i, raw := range raws {
+		require.NoError(t, json.Unmarshal(raw, &res[i]))
+		res[i].Raw = raw
+	}
+	return res
+}
+
+func traceFilterV2All(t *testing.T, api *ErigonImpl, req TraceFilterRequest) ([]json.RawMessage, int) {
+	t.Helper()
+	var res []json.RawMessage
+	var cursor *hexutility.Bytes
+	pages := 0
+	for {
+		page, err := api.TraceFilterV2(context.Background(), req, cursor, nil)
+		require.NoError(t, err)
+		pages++
+		for _, pt := range page.Traces {
+			b, err := json.Marshal(pt)
+			require.NoError(t, err)
+			res = append(res, b)
+		}
+		if page.NextCursor == nil {
+			return res, pages
+		}
+		require.Len(t, page.Traces, int(*req.Count))
+		cursor = page.NextCursor
+	}
+}
+
+func TestTraceFilterV2(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	traceAPI := NewTraceAPI(newBaseApiForTest(m), m.DB, &httpcfg.HttpCfg{})
+	erigonAPI := NewErigonAPI(newBaseApiForTest(m), m.DB, nil)
+
+	from, count := hexutil.Uint64(0), uint64(3)
+	req := TraceFilterRequest{FromBlock: &from, Count: &count}
+
+	all := traceFilterAll(t, traceAPI, TraceFilterRequest{FromBlock: &from})
+	require.NotEmpty(t, all)
+
+	t.Run("pages", func(t *testing.T) {
+		got, pages := traceFilterV2All(t, erigonAPI, req)
+		require.Greater(t, pages, 1)
+		require.Len(t, got, len(all))
+		for i := range all {
+			require.JSONEq(t, string(all[i].Raw), string(got[i]))
+		}
+	})
+
+	t.Run("selector", func(t *testing.T) {
+		var selector hexutility.Bytes
+		for _, tr := range all {
+			if tr.Action.CallType == "call" && len(tr.Action.Input) >= 4 {
+				selector = tr.Action.Input[:4]
+				break
+			}
+		}
+		require.NotNil(t, selector)
+		var expected []json.RawMessage
+		for _, tr := range all {
+			if len(tr.Action.Input) >= 4 && string(tr.Action.Input[:4]) == string(selector) {
+				expected = append(expected, tr.Raw)
+			}
+		}
+		req := req
+		req.Selector = []hexutility.Bytes{selector}
+		got, _ := traceFilterV2All(t, erigonAPI, req)
+		require.Len(t, got, len(expected))
+		for i := range expected {
+			require.JSONEq(t, string(expected[i]), string(got[i]))
+		}
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		var expected []json.RawMessage
+		for _, tr := range all {
+			if tr.Error != "" {
+				expected = append(expected, tr.Raw)
+			}
+		}
+		failed := true
+		req := req
+		req.Failed = &failed
+		got, _ := traceFilterV2All(t, erigonAPI, req)
+		require.Len(t, got, len(expected))
+		for i := range expected {
+			require.JSONEq(t, string(expected[i]), string(got[i]))
+		}
+	})
+
+	t.Run("bad params", func(t *testing.T) {
+		cursor := hexutility.Bytes{1, 2, 3}
+		_, err := erigonAPI.TraceFilterV2(context.Background(), req, &cursor, nil)
+		require.Error(t, err)
+		req := req
+		req.Selector = []hexutility.Bytes{{1, 2}}
+		_, err = erigonAPI.TraceFilterV2(context.Background(), req, nil, nil)
+		require.Error(t, err)
+	})
+}
+
+func TestTraceFilterBitmapsUnindexed(t *testing.T) {
+	m, chain, _ := rpcdaemontest.CreateTestSentry(t)
+	ctx := context.Background()
+
+	// the txns of a node synced from files predating the trace frame indices are missing from them
+	require.NoError(t, m.DB.Update(ctx, func(tx kv.RwTx) error {
+		for _, table := range []string{kv.TblTracesSelectorKeys, kv.TblTracesSelectorIdx, kv.TblTracesFailedKeys, kv.TblTracesFailedIdx} {
+			if err := tx.ClearBucket(table); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	fromTxNum, toTxNum, err := traceFilterTxNums(ctx, tx, m.BlockReader, 0, uint64(chain.Length()))
+	require.NoError(t, err)
+	require.Greater(t, toTxNum, fromTxNum+2)
+
+	failed := true
+	txNums := func(req TraceFilterRequest, indexedFrom uint64) []uint64 {
+		_, _, it, err := traceFilterBitmapsV3(tx, req, fromTxNum, toTxNum, indexedFrom)
+		require.NoError(t, err)
+		res, err := stream.ToArrayU64(it)
+		require.NoError(t, err)
+		return res
+	}
+	for _, req := range []TraceFilterRequest{{Failed: &failed}, {Selector: []hexutility.Bytes{{1, 2, 3, 4}}}} {
+		require.Empty(t, txNums(req, fromTxNum))
+		require.Len(t, txNums(req, toTxNum), int(toTxNum-fromTxNum))
+		mid := (fromTxNum + toTxNum) / 2
+		require.Equal(t, txNums(TraceFilterRequest{}, 0)[:mid-fromTxNum], txNums(req, mid))
+	}
+}
@@ -24,3 +24,6 @@ const NotAvailableChainData = "the function %s is not available, please use --pr
 
 // NotAvailableDeprecated x
 const NotAvailableDeprecated = "the method has been deprecated: %s"
+
+// NoAccountSigner is returned by the account and signing methods when neither a keystore nor an external signer is configured
+const NoAccountSigner = "the method %s requires an account signer, see --rpc.keystore and --rpc.signer"
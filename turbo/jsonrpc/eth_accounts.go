@@ -35,6 +35,14 @@ import (
 	"github.com/erigontech/erigon/rpc"
 )
 
+// Accounts implements eth_accounts. Returns a list of addresses owned by the client.
+func (api *APIImpl) Accounts(ctx context.Context) ([]libcommon.Address, error) {
+	if api.AccountSigner == nil {
+		return []libcommon.Address{}, fmt.Errorf(NoAccountSigner, "eth_accounts")
+	}
+	return api.AccountSigner.Accounts(ctx)
+}
+
 // GetBalance implements eth_getBalance. Returns the balance of an account for a given address.
 func (api *APIImpl) GetBalance(ctx context.Context, address libcommon.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
 	tx, err1 := api.db.BeginTemporalRo(ctx)
@@ -40,6 +40,7 @@ import (
 	"github.com/erigontech/erigon-lib/kv/kvcache"
 	"github.com/erigontech/erigon-lib/log/v3"
 	"github.com/erigontech/erigon-lib/types/accounts"
+	ethaccounts "github.com/erigontech/erigon/accounts"
 	"github.com/erigontech/erigon/consensus"
 	"github.com/erigontech/erigon/consensus/misc"
 	"github.com/erigontech/erigon/core"
@@ -110,12 +111,21 @@ type EthAPI interface {
 	Call(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides) (hexutility.Bytes, error)
 	EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, overrides *ethapi2.StateOverrides) (hexutil.Uint64, error)
 	SendRawTransaction(ctx context.Context, encodedTx hexutility.Bytes) (common.Hash, error)
-	SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
-	Sign(ctx context.Context, _ common.Address, _ hexutility.Bytes) (hexutility.Bytes, error)
-	SignTransaction(_ context.Context, txObject interface{}) (common.Hash, error)
+	SendRawTransactionConditional(ctx context.Context, encodedTx hexutility.Bytes, options SendRawTransactionConditionalOptions) (common.Hash, error)
+	SendPrivateRawTransaction(ctx context.Context, encodedTx hexutility.Bytes, options *SendPrivateRawTransactionOptions) (common.Hash, error)
+	SendTransaction(ctx context.Context, args ethapi2.CallArgs) (common.Hash, error)
+	Sign(ctx context.Context, address common.Address, data hexutility.Bytes) (hexutility.Bytes, error)
+	SignTransaction(ctx context.Context, args ethapi2.CallArgs) (*SignTransactionResult, error)
 	GetProof(ctx context.Context, address common.Address, storageKeys []common.Hash, blockNr rpc.BlockNumberOrHash) (*accounts.AccProofResult, error)
 	CreateAccessList(ctx context.Context, args ethapi2.CallArgs, blockNrOrHash *rpc.BlockNumberOrHash, optimizeGas *bool) (*accessListResult, error)
 
+	// Bundle related (see ./eth_bundle.go)
+	SendBundle(ctx context.Context, args SendBundleArgs) (*SendBundleResult, error)
+	CancelBundle(ctx context.Context, args CancelBundleArgs) (bool, error)
+
+	// Simulation related (see ./eth_simulation.go)
+	SimulateV1(ctx context.Context, req SimulationRequest, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error)
+
 	// Mining related (see ./eth_mining.go)
 	Coinbase(ctx context.Context) (common.Address, error)
 	Hashrate(ctx context.Context) (uint64, error)
@@ -386,6 +396,7 @@ type APIImpl struct {
 	AllowUnprotectedTxs         bool
 	MaxGetProofRewindBlockCount int
 	SubscribeLogsChannelSize    int
+	AccountSigner               ethaccounts.Signer // backs eth_accounts and the signing methods, nil when disabled
 	logger                      log.Logger
 }
 
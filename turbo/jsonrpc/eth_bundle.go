@@ -0,0 +1,94 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/gointerfaces"
+	txPoolProto "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
+	"github.com/erigontech/erigon-lib/gointerfaces/typesproto"
+)
+
+// SendBundleArgs are the arguments of eth_sendBundle
+type SendBundleArgs struct {
+	Txs               []hexutility.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64     `json:"blockNumber"`
+	MinTimestamp      *uint64            `json:"minTimestamp,omitempty"`
+	MaxTimestamp      *uint64            `json:"maxTimestamp,omitempty"`
+	RevertingTxHashes []common.Hash      `json:"revertingTxHashes,omitempty"`
+	ReplacementUuid   string             `json:"replacementUuid,omitempty"`
+}
+
+// SendBundleResult is the result of eth_sendBundle
+type SendBundleResult struct {
+	BundleHash common.Hash `json:"bundleHash"`
+}
+
+// CancelBundleArgs are the arguments of eth_cancelBundle
+type CancelBundleArgs struct {
+	ReplacementUuid string `json:"replacementUuid"`
+}
+
+// SendBundle implements eth_sendBundle. Submits a list of signed transactions to be included atomically, in order,
+// in the block with the given number. Transactions not listed in revertingTxHashes must not revert.
+func (api *APIImpl) SendBundle(ctx context.Context, args SendBundleArgs) (*SendBundleResult, error) {
+	if len(args.Txs) == 0 {
+		return nil, errors.New("bundle has no transactions")
+	}
+	req := &txPoolProto.SendBundleRequest{
+		RlpTxs:          make([][]byte, len(args.Txs)),
+		BlockNumber:     uint64(args.BlockNumber),
+		ReplacementUuid: args.ReplacementUuid,
+	}
+	for i, txn := range args.Txs {
+		req.RlpTxs[i] = txn
+	}
+	if args.MinTimestamp != nil {
+		req.MinTimestamp = *args.MinTimestamp
+	}
+	if args.MaxTimestamp != nil {
+		req.MaxTimestamp = *args.MaxTimestamp
+	}
+	req.RevertingTxHashes = make([]*typesproto.H256, len(args.RevertingTxHashes))
+	for i, hash := range args.RevertingTxHashes {
+		req.RevertingTxHashes[i] = gointerfaces.ConvertHashToH256(hash)
+	}
+
+	reply, err := api.txPool.SendBundle(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &SendBundleResult{BundleHash: gointerfaces.ConvertH256ToHash(reply.BundleHash)}, nil
+}
+
+// CancelBundle implements eth_cancelBundle. Removes the pending bundle with the given replacement uuid, returns false
+// if there was none.
+func (api *APIImpl) CancelBundle(ctx context.Context, args CancelBundleArgs) (bool, error) {
+	if args.ReplacementUuid == "" {
+		return false, errors.New("replacementUuid is required")
+	}
+	reply, err := api.txPool.CancelBundle(ctx, &txPoolProto.CancelBundleRequest{ReplacementUuid: args.ReplacementUuid})
+	if err != nil {
+		return false, err
+	}
+	return reply.Cancelled, nil
+}
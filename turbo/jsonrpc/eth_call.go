@@ -25,6 +25,7 @@ import (
 
 	"github.com/erigontech/erigon-lib/kv/dbutils"
 	"github.com/erigontech/erigon-lib/trie"
+	witnesstypes "github.com/erigontech/erigon-lib/types/witness"
 
 	"github.com/erigontech/erigon-lib/commitment"
 	libstate "github.com/erigontech/erigon-lib/state"
@@ -36,10 +37,12 @@ import (
 	libcommon "github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/hexutil"
 	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/common/length"
 	"github.com/erigontech/erigon-lib/crypto"
 	"github.com/erigontech/erigon-lib/gointerfaces"
 	txpool_proto "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
 	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/order"
 	"github.com/erigontech/erigon-lib/kv/rawdbv3"
 	"github.com/erigontech/erigon-lib/log/v3"
 	"github.com/erigontech/erigon-lib/types/accounts"
@@ -55,6 +58,7 @@ import (
 	"github.com/erigontech/erigon/rpc"
 	ethapi2 "github.com/erigontech/erigon/turbo/adapter/ethapi"
 	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/turbo/services"
 	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
 	"github.com/erigontech/erigon/turbo/transactions"
 )
@@ -335,99 +339,180 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi2.CallArgs
 }
 
 // maxGetProofRewindBlockCount limits the number of blocks into the past that
-// GetProof will allow computing proofs.  Because we must rewind the hash state
-// and re-compute the state trie, the further back in time the request, the more
-// computationally intensive the operation becomes.  The staged sync code
-// assumes that if more than 100_000 blocks are skipped, that the entire trie
-// should be re-computed. Re-computing the entire trie will currently take ~15
-// minutes on mainnet.  The current limit has been chosen arbitrarily as
-// 'useful' without likely being overly computationally intense.
-
-// GetProof is partially implemented; no Storage proofs, and proofs must be for
-// blocks within maxGetProofRewindBlockCount blocks of the head.
+// GetProof will allow computing proofs.  The commitment domain only holds the
+// trie of the latest state: for older blocks the branches changed since have to
+// be re-computed from the state history, the further back in time the request,
+// the more computationally intensive the operation becomes.  The current limit
+// has been chosen arbitrarily as 'useful' without likely being overly
+// computationally intense.
+
+// GetProof implements eth_getProof (EIP-1186): the account and storage proofs are built from the commitment
+// domain, for blocks within maxGetProofRewindBlockCount blocks of the head.
 func (api *APIImpl) GetProof(ctx context.Context, address libcommon.Address, storageKeys []libcommon.Hash, blockNrOrHash rpc.BlockNumberOrHash) (*accounts.AccProofResult, error) {
-	return nil, errors.New("not supported by Erigon3")
-	/*
-		tx, err := api.db.BeginTemporalRo(ctx)
-		if err != nil {
-			return nil, err
-		}
-		defer tx.Rollback()
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-		blockNr, _, _, err := rpchelper.GetBlockNumber(blockNrOrHash, tx, api.filters)
-		if err != nil {
-			return nil, err
-		}
+	blockNr, _, _, err := rpchelper.GetCanonicalBlockNumber(ctx, blockNrOrHash, tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d not found", blockNr)
+	}
 
-		header, err := api._blockReader.HeaderByNumber(ctx, tx, blockNr)
-		if err != nil {
-			return nil, err
-		}
+	domains, err := api.domainsAt(ctx, tx, header)
+	if err != nil {
+		return nil, err
+	}
+	defer domains.Close()
+	sdCtx := domains.GetCommitmentContext()
 
-		latestBlock, err := rpchelper.GetLatestBlockNumber(tx)
+	addrHash := crypto.Keccak256(address[:])
+	sdCtx.TouchKey(kv.AccountsDomain, string(address[:]), nil)
+	for _, key := range storageKeys {
+		sdCtx.TouchKey(kv.StorageDomain, string(append(address[:], key[:]...)), nil)
+	}
+	proofTrie, rootHash, err := sdCtx.Witness(ctx, header.Root[:], "eth_getProof")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(rootHash, header.Root[:]) {
+		return nil, fmt.Errorf("mismatch in expected state root computed %x vs %x indicates bug in proof implementation", rootHash, header.Root)
+	}
+
+	accountProof, err := proofTrie.Prove(addrHash, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	proof := &accounts.AccProofResult{
+		Address:      address,
+		AccountProof: toHexutilityBytes(accountProof),
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		StorageProof: make([]accounts.StorProofResult, len(storageKeys)),
+	}
+	if acc, _ := proofTrie.GetAccount(addrHash); acc != nil {
+		proof.Balance = (*hexutil.Big)(acc.Balance.ToBig())
+		proof.Nonce = hexutil.Uint64(acc.Nonce)
+		proof.CodeHash = acc.CodeHash
+		proof.StorageHash = acc.Root
+	}
+
+	for i, key := range storageKeys {
+		storageKey := append(libcommon.Copy(addrHash), crypto.Keccak256(key[:])...)
+		storageProof, err := proofTrie.Prove(storageKey, 2*length.Hash, true)
 		if err != nil {
 			return nil, err
 		}
-
-		if latestBlock < blockNr {
-			// shouldn't happen, but check anyway
-			return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNr)
+		v, _ := proofTrie.Get(storageKey)
+		proof.StorageProof[i] = accounts.StorProofResult{
+			Key:   key,
+			Value: (*hexutil.Big)(new(big.Int).SetBytes(v)),
+			Proof: toHexutilityBytes(storageProof),
 		}
+	}
 
-		rl := trie.NewRetainList(0)
-		var loader *trie.FlatDBTrieLoader
-		if blockNr < latestBlock {
-			if latestBlock-blockNr > uint64(api.MaxGetProofRewindBlockCount) {
-				return nil, fmt.Errorf("requested block is too old, block must be within %d blocks of the head block number (currently %d)", uint64(api.MaxGetProofRewindBlockCount), latestBlock)
-			}
-			batch := membatchwithdb.NewMemoryBatch(tx, api.dirs.Tmp, api.logger)
-			defer batch.Rollback()
-
-			unwindState := &stagedsync.UnwindState{UnwindPoint: blockNr}
-			stageState := &stagedsync.StageState{BlockNumber: latestBlock}
-
-			hashStageCfg := stagedsync.StageHashStateCfg(nil, api.dirs, api.historyV3(batch))
-			if err := stagedsync.UnwindHashStateStage(unwindState, stageState, batch, hashStageCfg, ctx, api.logger); err != nil {
-				return nil, err
-			}
-
-			interHashStageCfg := stagedsync.StageTrieCfg(nil, false, false, false, api.dirs.Tmp, api._blockReader, nil, api.historyV3(batch), api._agg)
-			loader, err = stagedsync.UnwindIntermediateHashesForTrieLoader("eth_getProof", rl, unwindState, stageState, batch, interHashStageCfg, nil, nil, ctx.Done(), api.logger)
-			if err != nil {
-				return nil, err
-			}
-			tx = batch
-		} else {
-			loader = trie.NewFlatDBTrieLoader("eth_getProof", rl, nil, nil, false)
+	if err := trie.VerifyAccountProof(header.Root, proof); err != nil {
+		return nil, fmt.Errorf("invalid account proof: %w", err)
+	}
+	for _, storageProof := range proof.StorageProof {
+		if err := trie.VerifyStorageProof(proof.StorageHash, storageProof); err != nil {
+			return nil, fmt.Errorf("invalid storage proof of %x: %w", storageProof.Key, err)
 		}
+	}
+	return proof, nil
+}
+
+// domainsAt opens shared domains holding the trie of the state after the block of header. For a block behind the
+// latest state, the changes made since are rewound: it has to be within maxGetProofRewindBlockCount blocks of the head.
+func (api *APIImpl) domainsAt(ctx context.Context, tx kv.TemporalTx, header *types.Header) (*libstate.SharedDomains, error) {
+	blockNr := header.Number.Uint64()
+	latestBlock, err := rpchelper.GetLatestBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	if latestBlock < blockNr {
+		// shouldn't happen, but check anyway
+		return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNr)
+	}
+	if latestBlock-blockNr > uint64(api.MaxGetProofRewindBlockCount) {
+		return nil, fmt.Errorf("requested block is too old, block must be within %d blocks of the head block number (currently %d)", uint64(api.MaxGetProofRewindBlockCount), latestBlock)
+	}
+	return rewoundDomains(ctx, tx, api._blockReader, header)
+}
 
-		reader, err := rpchelper.CreateStateReader(ctx, tx, blockNrOrHash, 0, api.filters, api.stateCache, "")
+// rewoundDomains opens domains with the state trie of the given block, rewound from the latest one
+func rewoundDomains(ctx context.Context, tx kv.TemporalTx, blockReader services.FullBlockReader, header *types.Header) (*libstate.SharedDomains, error) {
+	blockNr := header.Number.Uint64()
+	domains, err := libstate.NewSharedDomains(tx, log.New())
+	if err != nil {
+		return nil, err
+	}
+	if blockNr < domains.BlockNum() {
+		txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, blockReader))
+		fromTxNum, err := txNumsReader.Min(tx, blockNr+1)
 		if err != nil {
+			domains.Close()
 			return nil, err
 		}
-		a, err := reader.ReadAccountData(address)
-		if err != nil {
+		if err := rewindCommitment(ctx, tx, domains, fromTxNum, header); err != nil {
+			domains.Close()
 			return nil, err
 		}
-		if a == nil {
-			a = &accounts.Account{}
+	}
+	return domains, nil
+}
+
+var errTrieNotRebuilt = errors.New("can't rebuild the state trie")
+
+// rewindCommitment brings the trie of domains back to the state before fromTxNum: the keys changed since are
+// touched and the branches re-computed with their historical values. The branches are kept in memory.
+func rewindCommitment(ctx context.Context, tx kv.TemporalTx, domains *libstate.SharedDomains, fromTxNum uint64, header *types.Header) error {
+	sdCtx := domains.GetCommitmentContext()
+	sdCtx.SetHistoryStateReader(fromTxNum)
+	for _, domain := range []kv.Domain{kv.AccountsDomain, kv.StorageDomain, kv.CodeDomain} {
+		touchDomain := domain
+		if domain == kv.CodeDomain {
+			// the code hash is a part of the account leaf
+			touchDomain = kv.AccountsDomain
 		}
-		pr, err := trie.NewProofRetainer(address, a, storageKeys, rl)
+		it, err := tx.HistoryRange(domain, int(fromTxNum), -1, order.Asc, kv.Unlim)
 		if err != nil {
-			return nil, err
+			return err
 		}
-
-		loader.SetProofRetainer(pr)
-		root, err := loader.CalcTrieRoot(tx, nil)
-		if err != nil {
-			return nil, err
+		for it.HasNext() {
+			k, _, err := it.Next()
+			if err != nil {
+				it.Close()
+				return err
+			}
+			sdCtx.TouchKey(touchDomain, string(k), nil)
 		}
+		it.Close()
+	}
+	rootHash, err := domains.ComputeCommitment(ctx, false, header.Number.Uint64(), "eth_getProof")
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(rootHash, header.Root[:]) {
+		// e.g. the history of the state was pruned
+		return fmt.Errorf("%w of block %d: computed root %x, expected %x", errTrieNotRebuilt, header.Number.Uint64(), rootHash, header.Root)
+	}
+	return nil
+}
 
-		if root != header.Root {
-			return nil, fmt.Errorf("mismatch in expected state root computed %v vs %v indicates bug in proof implementation", root, header.Root)
-		}
-		return pr.ProofResult()
-	*/
+func toHexutilityBytes(b [][]byte) []hexutility.Bytes {
+	res := make([]hexutility.Bytes, len(b))
+	for i := range b {
+		res[i] = b[i]
+	}
+	return res
 }
 
 func (api *APIImpl) GetWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (hexutility.Bytes, error) {
@@ -503,6 +588,84 @@ func (api *BaseAPI) getWitness(ctx context.Context, db kv.RoDB, blockNrOrHash rp
 		return nil, fmt.Errorf("transaction index out of bounds: %d", txIndex)
 	}
 
+	roTx2, err := db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer roTx2.Rollback()
+	txBatch2 := membatchwithdb.NewMemoryBatch(roTx2, "", logger)
+	defer txBatch2.Rollback()
+
+	gen, err := api.generateWitness(ctx, roTx, txBatch2, block, maxGetProofRewindBlockCount, logger)
+	if err != nil {
+		return nil, err
+	}
+	store, prevHeader, witnessTrie, touchedHashedKeys, codeReads := gen.store, gen.prevHeader, gen.witnessTrie, gen.touchedHashedKeys, gen.codeReads
+
+	// retain list is need for the serialization of the trie.Trie into a witness
+	retainListBuilder := trie.NewRetainListBuilder()
+	for _, key := range touchedHashedKeys {
+		if len(key) == 32 {
+			retainListBuilder.AddTouch(key)
+		} else {
+			addr, _, hash := dbutils.ParseCompositeStorageKey(key)
+			storageTouch := dbutils.GenerateCompositeTrieKey(addr, hash)
+			retainListBuilder.AddStorageTouch(storageTouch)
+		}
+	}
+
+	for _, codeWithHash := range codeReads {
+		retainListBuilder.ReadCode(codeWithHash.CodeHash, codeWithHash.Code)
+	}
+
+	retainList := retainListBuilder.Build(false)
+
+	// serialize witness trie
+	witness, err := witnessTrie.ExtractWitness(true, retainList)
+	if err != nil {
+		return nil, err
+	}
+
+	var witnessBuffer bytes.Buffer
+	_, err = witness.WriteInto(&witnessBuffer)
+	if err != nil {
+		return nil, err
+	}
+
+	// this is a verification step: we execute block #blockNr statelessly using the witness, and we expect to get the same state root as in the header
+	// otherwise something went wrong
+	store.Tds.SetTrie(witnessTrie)
+	newStateRoot, err := stagedsync.ExecuteBlockStatelessly(block, prevHeader, store.ChainReader, store.Tds, &gen.cfg, &witnessBuffer, store.GetHashFn, logger)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(newStateRoot.Bytes(), block.Root().Bytes()) {
+		return nil, fmt.Errorf("state root mismatch after stateless execution actual(%x) != expected(%x)", newStateRoot.Bytes(), block.Root().Bytes())
+	}
+	witnessBufBytes := witnessBuffer.Bytes()
+	witnessBufBytesCopy := make([]byte, len(witnessBufBytes))
+	copy(witnessBufBytesCopy, witnessBufBytes)
+	return witnessBufBytesCopy, nil
+}
+
+// witnessGeneration is the outcome of the ephemeral execution of a block on top of a rewound state, with the merkle
+// paths to all the touched keys loaded into witnessTrie (at the state of the parent block)
+type witnessGeneration struct {
+	cfg               stagedsync.WitnessCfg
+	store             *stagedsync.WitnessStore
+	prevHeader        *types.Header
+	witnessTrie       *trie.Trie
+	touchedPlainKeys  [][]byte
+	touchedHashedKeys [][]byte
+	codeReads         map[libcommon.Hash]witnesstypes.CodeWithHash
+	// lowestBlockHashRead is the lowest block number read by the BLOCKHASH opcode, equal to the block number if none
+	lowestBlockHashRead uint64
+}
+
+// generateWitness rewinds txBatch to the state before the block, executes the block ephemerally to record the touched
+// accounts, storage slots and codes, and generates the witness trie for them
+func (api *BaseAPI) generateWitness(ctx context.Context, roTx kv.Tx, txBatch *membatchwithdb.MemoryMutation, block *types.Block, maxGetProofRewindBlockCount int, logger log.Logger) (*witnessGeneration, error) {
+	blockNr := block.NumberU64()
 	latestBlock, err := rpchelper.GetLatestBlockNumber(roTx)
 	if err != nil {
 		return nil, err
@@ -529,33 +692,25 @@ func (api *BaseAPI) getWitness(ctx context.Context, db kv.RoDB, blockNrOrHash rp
 		return nil, errors.New("engine is not consensus.Engine")
 	}
 
-	roTx2, err := db.BeginRo(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer roTx2.Rollback()
-	txBatch2 := membatchwithdb.NewMemoryBatch(roTx2, "", logger)
-	defer txBatch2.Rollback()
-
 	// Prepare witness config
-	chainConfig, err := api.chainConfig(ctx, roTx2)
+	chainConfig, err := api.chainConfig(ctx, txBatch)
 	if err != nil {
 		return nil, fmt.Errorf("error loading chain config: %v", err)
 	}
 
 	// Unwind to blockNr
 	cfg := stagedsync.StageWitnessCfg(true, 0, chainConfig, engine, api._blockReader, api.dirs)
-	err = stagedsync.RewindStagesForWitness(txBatch2, blockNr, latestBlock, &cfg, regenerateHash, ctx, logger)
+	err = stagedsync.RewindStagesForWitness(txBatch, blockNr, latestBlock, &cfg, regenerateHash, ctx, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	store, err := stagedsync.PrepareForWitness(txBatch2, block, prevHeader.Root, &cfg, ctx, logger)
+	store, err := stagedsync.PrepareForWitness(txBatch, block, prevHeader.Root, &cfg, ctx, logger)
 	if err != nil {
 		return nil, err
 	}
 
-	domains, err := libstate.NewSharedDomains(txBatch2, log.New())
+	domains, err := libstate.NewSharedDomains(txBatch, log.New())
 	if err != nil {
 		return nil, err
 	}
@@ -567,7 +722,12 @@ func (api *BaseAPI) getWitness(ctx context.Context, db kv.RoDB, blockNrOrHash rp
 	}
 
 	// execute block #blockNr ephemerally. This will use TrieStateWriter to record touches of accounts and storage keys.
-	_, err = core.ExecuteBlockEphemerally(chainConfig, &vm.Config{}, store.GetHashFn, engine, block, store.Tds, store.TrieStateWriter, store.ChainReader, nil, logger)
+	lowestBlockHashRead := blockNr
+	getHashFn := func(n uint64) libcommon.Hash {
+		lowestBlockHashRead = min(lowestBlockHashRead, n)
+		return store.GetHashFn(n)
+	}
+	_, err = core.ExecuteBlockEphemerally(chainConfig, &vm.Config{}, getHashFn, engine, block, store.Tds, store.TrieStateWriter, store.ChainReader, nil, logger)
 	if err != nil {
 		return nil, err
 	}
@@ -595,50 +755,16 @@ func (api *BaseAPI) getWitness(ctx context.Context, db kv.RoDB, blockNrOrHash rp
 		return nil, fmt.Errorf("witness root hash mismatch actual(%x)!=expected(%x)", witnessRootHash, prevHeader.Root[:])
 	}
 
-	// retain list is need for the serialization of the trie.Trie into a witness
-	retainListBuilder := trie.NewRetainListBuilder()
-	for _, key := range touchedHashedKeys {
-		if len(key) == 32 {
-			retainListBuilder.AddTouch(key)
-		} else {
-			addr, _, hash := dbutils.ParseCompositeStorageKey(key)
-			storageTouch := dbutils.GenerateCompositeTrieKey(addr, hash)
-			retainListBuilder.AddStorageTouch(storageTouch)
-		}
-	}
-
-	for _, codeWithHash := range codeReads {
-		retainListBuilder.ReadCode(codeWithHash.CodeHash, codeWithHash.Code)
-	}
-
-	retainList := retainListBuilder.Build(false)
-
-	// serialize witness trie
-	witness, err := witnessTrie.ExtractWitness(true, retainList)
-	if err != nil {
-		return nil, err
-	}
-
-	var witnessBuffer bytes.Buffer
-	_, err = witness.WriteInto(&witnessBuffer)
-	if err != nil {
-		return nil, err
-	}
-
-	// this is a verification step: we execute block #blockNr statelessly using the witness, and we expect to get the same state root as in the header
-	// otherwise something went wrong
-	store.Tds.SetTrie(witnessTrie)
-	newStateRoot, err := stagedsync.ExecuteBlockStatelessly(block, prevHeader, store.ChainReader, store.Tds, &cfg, &witnessBuffer, store.GetHashFn, logger)
-	if err != nil {
-		return nil, err
-	}
-	if !bytes.Equal(newStateRoot.Bytes(), block.Root().Bytes()) {
-		fmt.Printf("state root mismatch after stateless execution actual(%x) != expected(%x)\n", newStateRoot.Bytes(), block.Root().Bytes())
-	}
-	witnessBufBytes := witnessBuffer.Bytes()
-	witnessBufBytesCopy := make([]byte, len(witnessBufBytes))
-	copy(witnessBufBytesCopy, witnessBufBytes)
-	return witnessBufBytesCopy, nil
+	return &witnessGeneration{
+		cfg:                 cfg,
+		store:               store,
+		prevHeader:          prevHeader,
+		witnessTrie:         witnessTrie,
+		touchedPlainKeys:    touchedPlainKeys,
+		touchedHashedKeys:   touchedHashedKeys,
+		codeReads:           codeReads,
+		lowestBlockHashRead: lowestBlockHashRead,
+	}, nil
 }
 
 func (api *APIImpl) tryBlockFromLru(hash libcommon.Hash) *types.Block {
@@ -39,6 +39,7 @@ import (
 	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/turbo/adapter/ethapi"
 	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/turbo/transactions"
 )
 
 type BlockOverrides struct {
@@ -198,13 +199,6 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 	// this makes sure resources are cleaned up.
 	defer cancel()
 
-	// Wait for the context to be done and cancel the evm. Even if the
-	// EVM has finished, cancelling may be done (repeatedly)
-	go func() {
-		<-ctx.Done()
-		evm.Cancel()
-	}()
-
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64).AddBlobGas(math.MaxUint64)
@@ -217,17 +211,11 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 		txCtx = core.NewEVMTxContext(msg)
 		evm = vm.NewEVM(blockCtx, txCtx, evm.IntraBlockState(), chainConfig, vm.Config{Debug: false})
 		// Execute the transaction message
-		_, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
-		if err != nil {
+		if _, err = transactions.ApplyCall(ctx, evm, msg, gp, timeout); err != nil {
 			return nil, err
 		}
 
 		_ = st.FinalizeTx(rules, state.NewNoopWriter())
-
-		// If the timer caused an abort, return an appropriate error message
-		if evm.Cancelled() {
-			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
-		}
 	}
 
 	// after replaying the txns, we want to overload the state
@@ -277,17 +265,12 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 			}
 			txCtx = core.NewEVMTxContext(msg)
 			evm = vm.NewEVM(blockCtx, txCtx, evm.IntraBlockState(), chainConfig, vm.Config{Debug: false})
-			result, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
+			result, err := transactions.ApplyCall(ctx, evm, msg, gp, timeout)
 			if err != nil {
 				return nil, err
 			}
 
 			_ = st.FinalizeTx(rules, state.NewNoopWriter())
-
-			// If the timer caused an abort, return an appropriate error message
-			if evm.Cancelled() {
-				return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
-			}
 			jsonResult := make(map[string]interface{})
 			if result.Err != nil {
 				if len(result.Revert()) > 0 {
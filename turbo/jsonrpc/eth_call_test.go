@@ -18,6 +18,7 @@ package jsonrpc
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"math/big"
 	"testing"
@@ -110,9 +111,6 @@ func TestGetProof(t *testing.T) {
 	var maxGetProofRewindBlockCount = 1 // Note, this is unsafe for parallel tests, but, this test is the only consumer for now
 
 	m, bankAddr, contractAddr := chainWithDeployedContract(t)
-	if m.HistoryV3 {
-		t.Skip("not supported by Erigon3")
-	}
 	api := NewEthAPI(newBaseApiForTest(m), m.DB, nil, nil, nil, 5000000, 1e18, 100_000, false, maxGetProofRewindBlockCount, 128, log.New())
 
 	key := func(b byte) libcommon.Hash {
@@ -231,6 +229,38 @@ func TestGetProof(t *testing.T) {
 	}
 }
 
+func TestGetProofHistory(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	ctx := context.Background()
+	tx, err := m.DB.BeginTemporalRo(ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	head, err := rpchelper.GetLatestBlockNumber(tx)
+	require.NoError(t, err)
+	api := NewEthAPI(newBaseApiForTest(m), m.DB, nil, nil, nil, 5000000, 1e18, 100_000, false, int(head), 128, log.New())
+
+	var toAddr libcommon.Address
+	binary.BigEndian.PutUint64(toAddr[:], 4)
+	addrs := []libcommon.Address{{1}, toAddr, m.Address}
+
+	// every block is proved against its own state root, rebuilding the trie from the history for the past ones
+	for blockNum := uint64(0); blockNum <= head; blockNum++ {
+		header, err := m.BlockReader.HeaderByNumber(ctx, tx, blockNum)
+		require.NoError(t, err)
+		for _, addr := range addrs {
+			blockNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum))
+			proof, err := api.GetProof(ctx, addr, []libcommon.Hash{{}}, blockNrOrHash)
+			require.NoError(t, err, "block %d addr %x", blockNum, addr)
+			require.NoError(t, trie.VerifyAccountProof(header.Root, proof), "block %d addr %x", blockNum, addr)
+			require.NoError(t, trie.VerifyStorageProof(proof.StorageHash, proof.StorageProof[0]))
+
+			balance, err := api.GetBalance(ctx, addr, blockNrOrHash)
+			require.NoError(t, err)
+			require.Equal(t, balance.ToInt(), proof.Balance.ToInt(), "block %d addr %x", blockNum, addr)
+		}
+	}
+}
+
 func TestGetBlockByTimestampLatestTime(t *testing.T) {
 	ctx := context.Background()
 	m, _, _ := rpcdaemontest.CreateTestSentry(t)
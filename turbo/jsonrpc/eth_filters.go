@@ -18,6 +18,7 @@ package jsonrpc
 
 import (
 	"context"
+	"math/big"
 	"strings"
 
 	"github.com/erigontech/erigon-lib/log/v3"
@@ -266,6 +267,9 @@ func (api *APIImpl) NewPendingTransactionsWithBody(ctx context.Context) (*rpc.Su
 }
 
 // Logs send a notification each time a new log appears.
+// With toBlock "pending" the logs of the pending block are sent too, and with fromBlock "pending" only them: every
+// time the pending block is rebuilt, the logs which are no longer pending are sent with removed set, followed by
+// the new ones.
 func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
 	if api.filters == nil {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -275,12 +279,25 @@ func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
 
+	isPending := func(n *big.Int) bool { return n != nil && n.IsInt64() && n.Int64() == rpc.PendingBlockNumber.Int64() }
+	minedLogs, pendingLogs := !isPending(crit.FromBlock), isPending(crit.FromBlock) || isPending(crit.ToBlock)
+
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
 		defer debug.LogPanic()
-		logs, id := api.filters.SubscribeLogs(api.SubscribeLogsChannelSize, crit)
-		defer api.filters.UnsubscribeLogs(id)
+		var logs <-chan *types.Log
+		if minedLogs {
+			var id rpchelper.LogsSubID
+			logs, id = api.filters.SubscribeLogs(api.SubscribeLogsChannelSize, crit)
+			defer api.filters.UnsubscribeLogs(id)
+		}
+		var pending <-chan types.Logs
+		if pendingLogs {
+			var id rpchelper.PendingLogsSubID
+			pending, id = api.filters.SubscribePendingLogs(api.SubscribeLogsChannelSize)
+			defer api.filters.UnsubscribePendingLogs(id)
+		}
 
 		for {
 			select {
@@ -295,6 +312,17 @@ func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc
 					log.Warn("[rpc] log channel was closed")
 					return
 				}
+			case pl, ok := <-pending:
+				for _, l := range filterLogs(pl, crit.Addresses, crit.Topics) {
+					err := notifier.Notify(rpcSub.ID, l)
+					if err != nil {
+						log.Warn("[rpc] error while notifying subscription", "err", err)
+					}
+				}
+				if !ok {
+					log.Warn("[rpc] pending log channel was closed")
+					return
+				}
 			case <-rpcSub.Err():
 				return
 			}
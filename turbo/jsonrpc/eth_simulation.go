@@ -0,0 +1,674 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/holiman/uint256"
+
+	"github.com/erigontech/erigon-lib/chain"
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+	libstate "github.com/erigontech/erigon-lib/state"
+
+	"github.com/erigontech/erigon/consensus/misc"
+	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/state"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/core/vm"
+	"github.com/erigontech/erigon/core/vm/evmtypes"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/turbo/adapter/ethapi"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/turbo/transactions"
+)
+
+const (
+	// maxSimulateBlocks is the maximum number of blocks (including the empty
+	// ones filling gaps between requested block numbers) in one eth_simulateV1 request
+	maxSimulateBlocks = 256
+	// maxSimulateRootRewindBlocks is the maximum distance of the base block from the latest state for which the
+	// state roots of the simulated blocks are computed
+	maxSimulateRootRewindBlocks = 128
+	// simulateTimestampIncrement is the default time distance between two simulated blocks
+	simulateTimestampIncrement = 12
+)
+
+var (
+	// transferAddress is the ERC-7528 pseudo-address used as emitter of the ETH transfer logs
+	transferAddress = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+	// transferTopic is keccak256("Transfer(address,address,uint256)")
+	transferTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+)
+
+// Error codes of eth_simulateV1, as defined by the execution-apis specification
+const (
+	simulateErrCodeReverted         = 3
+	simulateErrCodeVMError          = -32015
+	simulateErrCodeBlockGasLimit    = -38015
+	simulateErrCodeInvalidBlockNum  = -38020
+	simulateErrCodeInvalidTimestamp = -38021
+	simulateErrCodeClientLimit      = -38026
+)
+
+// SimulationRequest is the payload of eth_simulateV1
+type SimulationRequest struct {
+	BlockStateCalls        []SimulatedBlock `json:"blockStateCalls"`
+	TraceTransfers         bool             `json:"traceTransfers"`
+	Validation             bool             `json:"validation"`
+	ReturnFullTransactions bool             `json:"returnFullTransactions"`
+}
+
+// SimulatedBlock is a single block of eth_simulateV1: block header overrides, state overrides applied
+// before the block is executed and calls executed in order as the block transactions
+type SimulatedBlock struct {
+	BlockOverrides *SimulatedBlockOverrides `json:"blockOverrides"`
+	StateOverrides *ethapi.StateOverrides   `json:"stateOverrides"`
+	Calls          []ethapi.CallArgs        `json:"calls"`
+}
+
+// SimulatedBlockOverrides contains the header fields that can be overridden in eth_simulateV1
+type SimulatedBlockOverrides struct {
+	Number        *hexutil.Big    `json:"number"`
+	Time          *hexutil.Uint64 `json:"time"`
+	GasLimit      *hexutil.Uint64 `json:"gasLimit"`
+	FeeRecipient  *common.Address `json:"feeRecipient"`
+	PrevRandao    *common.Hash    `json:"prevRandao"`
+	BaseFeePerGas *hexutil.Big    `json:"baseFeePerGas"`
+	BlobBaseFee   *hexutil.Big    `json:"blobBaseFee"`
+}
+
+// SimulatedCallResult is the outcome of a single call inside a simulated block
+type SimulatedCallResult struct {
+	ReturnData hexutility.Bytes    `json:"returnData"`
+	Logs       []*types.Log        `json:"logs"`
+	GasUsed    hexutil.Uint64      `json:"gasUsed"`
+	Status     hexutil.Uint64      `json:"status"`
+	Error      *SimulatedCallError `json:"error,omitempty"`
+}
+
+// SimulatedCallError describes why a simulated call failed
+type SimulatedCallError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+// simulationError is returned for requests which are invalid as a whole (e.g. non-increasing block numbers)
+type simulationError struct {
+	code    int
+	message string
+}
+
+func (e *simulationError) Error() string  { return e.message }
+func (e *simulationError) ErrorCode() int { return e.code }
+
+// SimulateV1 implements eth_simulateV1. Executes a chain of simulated blocks on top of the given block
+// and returns the resulting blocks, with the receipts-like per-call results attached as "calls".
+func (api *APIImpl) SimulateV1(ctx context.Context, req SimulationRequest, blockNrOrHash *rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
+	if len(req.BlockStateCalls) == 0 {
+		return nil, errors.New("empty input")
+	}
+	if len(req.BlockStateCalls) > maxSimulateBlocks {
+		return nil, &simulationError{code: simulateErrCodeClientLimit, message: "too many blocks"}
+	}
+
+	bNrOrHash := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	if blockNrOrHash != nil {
+		bNrOrHash = *blockNrOrHash
+	}
+
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	chainConfig, err := api.chainConfig(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(start time.Time) { log.Trace("Executing EVM simulateV1 finished", "runtime", time.Since(start)) }(time.Now())
+
+	blockNum, hash, _, err := rpchelper.GetBlockNumber(ctx, bNrOrHash, tx, api._blockReader, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	base, err := api._blockReader.Header(ctx, tx, hash, blockNum)
+	if err != nil {
+		return nil, err
+	}
+	if base == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNum, hash)
+	}
+
+	stateReader, err := rpchelper.CreateStateReader(ctx, tx, api._blockReader, rpc.BlockNumberOrHashWithHash(hash, false), 0, api.filters, api.stateCache, chainConfig.ChainName)
+	if err != nil {
+		return nil, err
+	}
+	domains, err := api.simulationDomainsAt(ctx, tx, base)
+	if err != nil {
+		return nil, err
+	}
+	stateWriter := state.StateWriter(state.NewNoopWriter())
+	if domains != nil {
+		defer domains.Close()
+		stateWriter = state.NewWriterV4(domains)
+	}
+
+	blocks, err := sanitizeSimulatedBlocks(base, req.BlockStateCalls)
+	if err != nil {
+		return nil, err
+	}
+
+	var cancel context.CancelFunc
+	if api.evmCallTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, api.evmCallTimeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	sim := &simulator{
+		api:         api,
+		tx:          tx,
+		chainConfig: chainConfig,
+		base:        base,
+		ibs:         state.New(stateReader),
+		domains:     domains,
+		stateWriter: stateWriter,
+		req:         req,
+		hashes:      make(map[uint64]common.Hash, len(blocks)),
+	}
+
+	results := make([]map[string]interface{}, 0, len(blocks))
+	parent := base
+	for i := range blocks {
+		block, callResults, err := sim.simulateBlock(ctx, &blocks[i], parent)
+		if err != nil {
+			return nil, err
+		}
+		fields, err := ethapi.RPCMarshalBlock(block, true, req.ReturnFullTransactions, map[string]interface{}{"calls": callResults})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, fields)
+		parent = block.Header()
+	}
+	return results, nil
+}
+
+// simulationDomainsAt opens the in-memory domains the state changes of the simulated blocks are written to, on top
+// of the state of the base block, for their state roots to be computed. It returns nil if the trie of the base block
+// can't be rebuilt: it is more than maxSimulateRootRewindBlocks blocks behind the latest state, or its history was pruned.
+func (api *APIImpl) simulationDomainsAt(ctx context.Context, tx kv.TemporalTx, base *types.Header) (*libstate.SharedDomains, error) {
+	latestBlock, err := rpchelper.GetLatestBlockNumber(tx)
+	if err != nil {
+		return nil, err
+	}
+	if blockNr := base.Number.Uint64(); blockNr > latestBlock || latestBlock-blockNr > maxSimulateRootRewindBlocks {
+		return nil, nil
+	}
+	domains, err := rewoundDomains(ctx, tx, api._blockReader, base)
+	if errors.Is(err, errTrieNotRebuilt) {
+		log.Debug("[eth_simulateV1] state roots not computed", "err", err)
+		return nil, nil
+	}
+	return domains, err
+}
+
+// sanitizeSimulatedBlocks checks that block numbers and timestamps are strictly increasing, fills the missing
+// ones with defaults and inserts empty blocks where the requested block numbers leave gaps
+func sanitizeSimulatedBlocks(base *types.Header, blocks []SimulatedBlock) ([]SimulatedBlock, error) {
+	res := make([]SimulatedBlock, 0, len(blocks))
+	prevNumber := base.Number.Uint64()
+	prevTime := base.Time
+	for _, block := range blocks {
+		// the overrides are filled in, on a copy not to change the request
+		var overrides SimulatedBlockOverrides
+		if block.BlockOverrides != nil {
+			overrides = *block.BlockOverrides
+		}
+		block.BlockOverrides = &overrides
+		if block.BlockOverrides.Number == nil {
+			n := new(big.Int).SetUint64(prevNumber + 1)
+			block.BlockOverrides.Number = (*hexutil.Big)(n)
+		}
+		number := block.BlockOverrides.Number.ToInt()
+		if !number.IsUint64() || number.Uint64() <= prevNumber {
+			return nil, &simulationError{code: simulateErrCodeInvalidBlockNum, message: fmt.Sprintf("block numbers must be in order: %s <= %d", number, prevNumber)}
+		}
+		if gap := number.Uint64() - prevNumber; gap > 1 {
+			if uint64(len(res))+gap > maxSimulateBlocks {
+				return nil, &simulationError{code: simulateErrCodeClientLimit, message: "too many blocks"}
+			}
+			for n := prevNumber + 1; n < number.Uint64(); n++ {
+				prevTime += simulateTimestampIncrement
+				t := hexutil.Uint64(prevTime)
+				res = append(res, SimulatedBlock{BlockOverrides: &SimulatedBlockOverrides{
+					Number: (*hexutil.Big)(new(big.Int).SetUint64(n)),
+					Time:   &t,
+				}})
+			}
+		}
+		prevNumber = number.Uint64()
+
+		if block.BlockOverrides.Time == nil {
+			t := hexutil.Uint64(prevTime + simulateTimestampIncrement)
+			block.BlockOverrides.Time = &t
+		} else if uint64(*block.BlockOverrides.Time) <= prevTime {
+			return nil, &simulationError{code: simulateErrCodeInvalidTimestamp, message: fmt.Sprintf("block timestamps must be in order: %d <= %d", *block.BlockOverrides.Time, prevTime)}
+		}
+		prevTime = uint64(*block.BlockOverrides.Time)
+		res = append(res, block)
+	}
+	if len(res) > maxSimulateBlocks {
+		return nil, &simulationError{code: simulateErrCodeClientLimit, message: "too many blocks"}
+	}
+	return res, nil
+}
+
+// simulator holds the state shared between the simulated blocks of a single eth_simulateV1 request
+type simulator struct {
+	api         *APIImpl
+	tx          kv.TemporalTx
+	chainConfig *chain.Config
+	base        *types.Header
+	ibs         *state.IntraBlockState
+	domains     *libstate.SharedDomains // state of ibs, as of the end of the last simulated txn; nil if not available
+	stateWriter state.StateWriter       // writes to domains
+	req         SimulationRequest
+
+	hashes  map[uint64]common.Hash // hashes of already simulated blocks
+	txIndex int                    // monotonic transaction index in ibs, across all simulated blocks
+}
+
+func (s *simulator) makeHeader(parent *types.Header, overrides *SimulatedBlockOverrides) (*types.Header, error) {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		UncleHash:  types.EmptyUncleHash,
+		Coinbase:   parent.Coinbase,
+		Difficulty: new(big.Int).Set(parent.Difficulty),
+		Number:     new(big.Int).Set(overrides.Number.ToInt()),
+		GasLimit:   parent.GasLimit,
+		Time:       uint64(*overrides.Time),
+		MixDigest:  parent.MixDigest,
+	}
+	if overrides.FeeRecipient != nil {
+		header.Coinbase = *overrides.FeeRecipient
+	}
+	if overrides.GasLimit != nil {
+		header.GasLimit = uint64(*overrides.GasLimit)
+	}
+	if overrides.PrevRandao != nil {
+		header.MixDigest = *overrides.PrevRandao
+	}
+	number := header.Number.Uint64()
+	if s.chainConfig.IsLondon(number) {
+		switch {
+		case overrides.BaseFeePerGas != nil:
+			header.BaseFee = new(big.Int).Set(overrides.BaseFeePerGas.ToInt())
+		case s.req.Validation:
+			header.BaseFee = misc.CalcBaseFee(s.chainConfig, parent)
+		default:
+			header.BaseFee = new(big.Int)
+		}
+	}
+	if s.chainConfig.IsShanghai(header.Time) {
+		header.WithdrawalsHash = &types.EmptyRootHash
+	}
+	if s.chainConfig.IsCancun(header.Time) {
+		var excessBlobGas uint64
+		if parent.ExcessBlobGas != nil {
+			excessBlobGas = misc.CalcExcessBlobGas(s.chainConfig, parent)
+		}
+		header.ExcessBlobGas = &excessBlobGas
+		header.BlobGasUsed = new(uint64)
+		header.ParentBeaconBlockRoot = &common.Hash{}
+	}
+	return header, nil
+}
+
+func (s *simulator) getHash(n uint64) common.Hash {
+	if h, ok := s.hashes[n]; ok {
+		return h
+	}
+	if n > s.base.Number.Uint64() {
+		return common.Hash{}
+	}
+	h, ok, err := s.api._blockReader.CanonicalHash(context.Background(), s.tx, n)
+	if err != nil || !ok {
+		log.Debug("Can't get block hash by number", "number", n, "only-canonical", true, "err", err, "ok", ok)
+	}
+	return h
+}
+
+func (s *simulator) simulateBlock(ctx context.Context, block *SimulatedBlock, parent *types.Header) (*types.Block, []SimulatedCallResult, error) {
+	header, err := s.makeHeader(parent, block.BlockOverrides)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if block.StateOverrides != nil {
+		if err := block.StateOverrides.Override(s.ibs); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	blockCtx := core.NewEVMBlockContext(header, s.getHash, s.api.engine(), &header.Coinbase, s.chainConfig)
+	if block.BlockOverrides.BlobBaseFee != nil {
+		blobBaseFee, overflow := uint256.FromBig(block.BlockOverrides.BlobBaseFee.ToInt())
+		if overflow {
+			return nil, nil, errors.New("blobBaseFee higher than 2^256-1")
+		}
+		blockCtx.BlobBaseFee = blobBaseFee
+	}
+	rules := s.chainConfig.Rules(header.Number.Uint64(), header.Time)
+
+	var (
+		txs      = make(types.Transactions, 0, len(block.Calls))
+		receipts = make(types.Receipts, 0, len(block.Calls))
+		results  = make([]SimulatedCallResult, 0, len(block.Calls))
+		gp       = new(core.GasPool).AddGas(header.GasLimit).AddBlobGas(s.chainConfig.GetMaxBlobGasPerBlock())
+		gasUsed  uint64
+		blobGas  uint64
+	)
+	for i := range block.Calls {
+		args := block.Calls[i]
+		if args.Gas == nil {
+			remaining := hexutil.Uint64(gp.Gas())
+			args.Gas = &remaining
+		}
+		if uint64(*args.Gas) > gp.Gas() {
+			return nil, nil, &simulationError{code: simulateErrCodeBlockGasLimit, message: fmt.Sprintf("block gas limit reached: %d >= %d", gasUsed, header.GasLimit)}
+		}
+		if args.Nonce == nil {
+			var from common.Address
+			if args.From != nil {
+				from = *args.From
+			}
+			nonce, err := s.ibs.GetNonce(from)
+			if err != nil {
+				return nil, nil, err
+			}
+			args.Nonce = (*hexutil.Uint64)(&nonce)
+		}
+		var baseFee *uint256.Int
+		if header.BaseFee != nil {
+			baseFee, _ = uint256.FromBig(header.BaseFee)
+		}
+		msg, err := args.ToMessage(s.api.GasCap, baseFee)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg = types.NewMessage(msg.From(), msg.To(), uint64(*args.Nonce), msg.Value(), msg.Gas(), msg.GasPrice(), msg.FeeCap(), msg.Tip(),
+			msg.Data(), msg.AccessList(), s.req.Validation /* checkNonce */, false /* isFree */, msg.MaxFeePerBlobGas())
+
+		txn := simulatedTransaction(&args, &msg, s.chainConfig.ChainID)
+		txHash := txn.Hash()
+
+		var tracer *transferTracer
+		vmConfig := vm.Config{NoBaseFee: !s.req.Validation}
+		if s.req.TraceTransfers {
+			tracer = newTransferTracer(s.ibs, s.txIndex)
+			vmConfig.Debug = true
+			vmConfig.Tracer = tracer
+		}
+
+		s.ibs.SetTxContext(s.txIndex)
+		txCtx := core.NewEVMTxContext(msg)
+		txCtx.TxHash = txHash
+		evm := vm.NewEVM(blockCtx, txCtx, s.ibs, s.chainConfig, vmConfig)
+		result, err := transactions.ApplyCall(ctx, evm, msg, gp, s.api.evmCallTimeout)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = s.ibs.FinalizeTx(rules, s.stateWriter); err != nil {
+			return nil, nil, err
+		}
+
+		gasUsed += result.UsedGas
+		blobGas += msg.BlobGas()
+
+		logs := s.ibs.GetRawLogs(s.txIndex)
+		if tracer != nil {
+			logs = tracer.mergeLogs(logs)
+		}
+		receipt := &types.Receipt{
+			Type:              txn.Type(),
+			CumulativeGasUsed: gasUsed,
+			TxHash:            txHash,
+			GasUsed:           result.UsedGas,
+			Logs:              logs,
+			TransactionIndex:  uint(i),
+		}
+		if result.Failed() {
+			receipt.Status = types.ReceiptStatusFailed
+		} else {
+			receipt.Status = types.ReceiptStatusSuccessful
+		}
+		if msg.To() == nil {
+			receipt.ContractAddress = crypto.CreateAddress(msg.From(), msg.Nonce())
+		}
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+
+		callResult := SimulatedCallResult{
+			ReturnData: result.Return(),
+			Logs:       logs,
+			GasUsed:    hexutil.Uint64(result.UsedGas),
+			Status:     hexutil.Uint64(receipt.Status),
+		}
+		if result.Err != nil {
+			callResult.Error = simulatedCallError(result)
+		}
+
+		txs = append(txs, txn)
+		receipts = append(receipts, receipt)
+		results = append(results, callResult)
+		s.txIndex++
+	}
+
+	header.GasUsed = gasUsed
+	if header.BlobGasUsed != nil {
+		header.BlobGasUsed = &blobGas
+	}
+	var withdrawals []*types.Withdrawal
+	if header.WithdrawalsHash != nil {
+		withdrawals = []*types.Withdrawal{}
+	}
+	// state overrides of a block without calls are still pending
+	if err := s.ibs.FinalizeTx(rules, s.stateWriter); err != nil {
+		return nil, nil, err
+	}
+	if s.domains != nil {
+		root, err := s.domains.ComputeCommitment(ctx, false /* saveStateAfter */, header.Number.Uint64(), "eth_simulateV1")
+		if err != nil {
+			return nil, nil, err
+		}
+		header.Root = common.BytesToHash(root)
+	} else {
+		// the state trie of the base block is not available: the simulated blocks carry its state root
+		header.Root = s.base.Root
+	}
+	b := types.NewBlock(header, txs, nil, receipts, withdrawals)
+
+	blockHash := b.Hash()
+	s.hashes[b.NumberU64()] = blockHash
+	logIndex := uint(0)
+	for i, receipt := range receipts {
+		receipt.BlockHash = blockHash
+		receipt.BlockNumber = new(big.Int).Set(header.Number)
+		for _, l := range receipt.Logs {
+			l.TxHash = receipt.TxHash
+			l.TxIndex = uint(i)
+			l.BlockHash = blockHash
+			l.BlockNumber = header.Number.Uint64()
+			l.Index = logIndex
+			logIndex++
+		}
+		if results[i].Logs == nil {
+			results[i].Logs = []*types.Log{}
+		}
+	}
+	return b, results, nil
+}
+
+// simulatedTransaction builds the unsigned transaction representing a simulated call in the resulting block
+func simulatedTransaction(args *ethapi.CallArgs, msg *types.Message, chainID *big.Int) types.Transaction {
+	var txn types.Transaction
+	chainId, _ := uint256.FromBig(chainID)
+	if args.GasPrice != nil || (args.MaxFeePerGas == nil && args.MaxPriorityFeePerGas == nil && args.AccessList == nil) {
+		txn = &types.LegacyTx{
+			CommonTx: types.CommonTx{
+				Nonce: msg.Nonce(),
+				Gas:   msg.Gas(),
+				To:    msg.To(),
+				Value: msg.Value(),
+				Data:  msg.Data(),
+			},
+			GasPrice: msg.GasPrice(),
+		}
+	} else {
+		txn = &types.DynamicFeeTransaction{
+			CommonTx: types.CommonTx{
+				Nonce: msg.Nonce(),
+				Gas:   msg.Gas(),
+				To:    msg.To(),
+				Value: msg.Value(),
+				Data:  msg.Data(),
+			},
+			ChainID:    chainId,
+			Tip:        msg.Tip(),
+			FeeCap:     msg.FeeCap(),
+			AccessList: msg.AccessList(),
+		}
+	}
+	txn.SetSender(msg.From())
+	return txn
+}
+
+func simulatedCallError(result *evmtypes.ExecutionResult) *SimulatedCallError {
+	if errors.Is(result.Err, vm.ErrExecutionReverted) {
+		revertErr := ethapi.NewRevertError(result)
+		callErr := &SimulatedCallError{Code: simulateErrCodeReverted, Message: revertErr.Error()}
+		if data, ok := revertErr.ErrorData().(string); ok {
+			callErr.Data = data
+		}
+		return callErr
+	}
+	return &SimulatedCallError{Code: simulateErrCodeVMError, Message: result.Err.Error()}
+}
+
+// transferTracer records the ETH value transfers of a call as ERC-7528 style Transfer logs.
+// Transfers of reverted frames are dropped together with the frame.
+type transferTracer struct {
+	ibs     *state.IntraBlockState
+	txIndex int
+	frames  [][]transferLog // logs of the currently open call frames
+	logs    []transferLog
+}
+
+// transferLog is a Transfer log, along with the number of logs the EVM emitted before it
+type transferLog struct {
+	log      *types.Log
+	position int
+}
+
+func newTransferTracer(ibs *state.IntraBlockState, txIndex int) *transferTracer {
+	return &transferTracer{ibs: ibs, txIndex: txIndex}
+}
+
+func (t *transferTracer) enter(from, to common.Address, value *uint256.Int) {
+	var logs []transferLog
+	if value != nil && !value.IsZero() {
+		data := value.Bytes32()
+		logs = append(logs, transferLog{
+			log: &types.Log{
+				Address: transferAddress,
+				Topics:  []common.Hash{transferTopic, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())},
+				Data:    data[:],
+			},
+			// reverting a frame only drops the logs emitted since its start, so the position stays right
+			position: len(t.ibs.GetRawLogs(t.txIndex)),
+		})
+	}
+	t.frames = append(t.frames, logs)
+}
+
+func (t *transferTracer) exit(err error) {
+	last := len(t.frames) - 1
+	logs := t.frames[last]
+	t.frames = t.frames[:last]
+	if err != nil {
+		return
+	}
+	if last == 0 {
+		t.logs = append(t.logs, logs...)
+		return
+	}
+	t.frames[last-1] = append(t.frames[last-1], logs...)
+}
+
+// mergeLogs interleaves the transfer logs with the logs emitted by the EVM, in the order they happened.
+// A transfer happens at the start of the call frame performing it.
+func (t *transferTracer) mergeLogs(logs types.Logs) types.Logs {
+	if len(t.logs) == 0 {
+		return logs
+	}
+	res := make(types.Logs, 0, len(t.logs)+len(logs))
+	emitted := 0
+	for _, l := range t.logs {
+		position := min(l.position, len(logs))
+		res = append(res, logs[emitted:position]...)
+		emitted = position
+		res = append(res, l.log)
+	}
+	return append(res, logs[emitted:]...)
+}
+
+func (t *transferTracer) CaptureTxStart(gasLimit uint64) {}
+func (t *transferTracer) CaptureTxEnd(restGas uint64)    {}
+func (t *transferTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	t.enter(from, to, value)
+}
+func (t *transferTracer) CaptureEnd(output []byte, usedGas uint64, err error) {
+	t.exit(err)
+}
+func (t *transferTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, precompile bool, create bool, input []byte, gas uint64, value *uint256.Int, code []byte) {
+	if typ == vm.DELEGATECALL {
+		// value of a delegate call is inherited from the parent frame and is not transferred again
+		value = nil
+	}
+	t.enter(from, to, value)
+}
+func (t *transferTracer) CaptureExit(output []byte, usedGas uint64, err error) {
+	t.exit(err)
+}
+func (t *transferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+func (t *transferTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
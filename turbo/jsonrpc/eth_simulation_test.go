@@ -0,0 +1,232 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package jsonrpc
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/kv/kvcache"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/accounts/abi/bind/backends"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/rpc/rpccfg"
+	"github.com/erigontech/erigon/turbo/adapter/ethapi"
+)
+
+var simulationTestKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+// newSimulationTestAPI returns the eth API of a simulated backend with one mined block, funding the address of
+// simulationTestKey in genesis
+func newSimulationTestAPI(t *testing.T) (*APIImpl, *backends.SimulatedBackend) {
+	t.Helper()
+	alloc := types.GenesisAlloc{crypto.PubkeyToAddress(simulationTestKey.PublicKey): {Balance: big.NewInt(9000000000000000000)}}
+	contractBackend := backends.NewTestSimulatedBackendWithConfig(t, alloc, params.TestChainConfig, 10000000)
+	contractBackend.Commit()
+
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, contractBackend.BlockReader(), false, rpccfg.DefaultEvmCallTimeout, contractBackend.Engine(), datadir.New(t.TempDir()), nil), contractBackend.DB(), nil, nil, nil, 5000000, 1e18, 100_000, false, 100_000, 128, log.New())
+	return api, contractBackend
+}
+
+func TestSimulateV1(t *testing.T) {
+	var (
+		address  = crypto.PubkeyToAddress(simulationTestKey.PublicKey)
+		receiver = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		ctx      = context.Background()
+	)
+	api, contractBackend := newSimulationTestAPI(t)
+
+	value := (*hexutil.Big)(big.NewInt(1000))
+	transfer := ethapi.CallArgs{From: &address, To: &receiver, Value: value}
+	newBalance := hexutil.Big(*big.NewInt(5))
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	overrides := &SimulatedBlockOverrides{Number: (*hexutil.Big)(big.NewInt(5))}
+	res, err := api.SimulateV1(ctx, SimulationRequest{
+		TraceTransfers: true,
+		BlockStateCalls: []SimulatedBlock{
+			{Calls: []ethapi.CallArgs{transfer}},
+			{
+				BlockOverrides: overrides,
+				StateOverrides: &ethapi.StateOverrides{receiver: ethapi.Account{Balance: func() **hexutil.Big { b := &newBalance; return &b }()}},
+				Calls:          []ethapi.CallArgs{transfer, transfer},
+			},
+		},
+	}, &latest)
+	require.NoError(t, err)
+	// block 2 is simulated, 3 and 4 are empty blocks filling the gap up to the requested block 5
+	require.Len(t, res, 4)
+	// the request is left as it is
+	require.Nil(t, overrides.Time)
+
+	for i, block := range res {
+		require.Equal(t, uint64(i+2), block["number"].(*hexutil.Big).ToInt().Uint64())
+	}
+	require.Equal(t, res[0]["hash"], res[1]["parentHash"])
+
+	calls := res[0]["calls"].([]SimulatedCallResult)
+	require.Len(t, calls, 1)
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), calls[0].Status)
+	require.Equal(t, hexutil.Uint64(21000), calls[0].GasUsed)
+	require.Len(t, calls[0].Logs, 1)
+	require.Equal(t, transferAddress, calls[0].Logs[0].Address)
+	require.Equal(t, common.BytesToHash(receiver.Bytes()), calls[0].Logs[0].Topics[2])
+
+	require.Empty(t, res[1]["calls"])
+	require.Empty(t, res[2]["calls"])
+	// the state changes of each block make it to its state root
+	base, err := contractBackend.BlockByNumber(ctx, big.NewInt(1))
+	require.NoError(t, err)
+	require.NotEqual(t, base.Root(), res[0]["stateRoot"])
+	require.Equal(t, res[0]["stateRoot"], res[1]["stateRoot"])
+	require.Equal(t, res[1]["stateRoot"], res[2]["stateRoot"])
+	require.NotEqual(t, res[2]["stateRoot"], res[3]["stateRoot"])
+
+	calls = res[3]["calls"].([]SimulatedCallResult)
+	require.Len(t, calls, 2)
+	require.Equal(t, uint(1), calls[1].Logs[0].Index)
+	require.Equal(t, hexutil.Uint64(42000), res[3]["gasUsed"])
+}
+
+func TestSimulateV1StateRoot(t *testing.T) {
+	var (
+		address  = crypto.PubkeyToAddress(simulationTestKey.PublicKey)
+		receiver = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		ctx      = context.Background()
+	)
+	api, contractBackend := newSimulationTestAPI(t)
+
+	// the transfer simulated on top of block 1 is then mined in block 2
+	gasPrice := big.NewInt(2 * params.GWei)
+	value := big.NewInt(1000)
+	transfer := ethapi.CallArgs{From: &address, To: &receiver, Value: (*hexutil.Big)(value), GasPrice: (*hexutil.Big)(gasPrice)}
+	// the zero coinbase holds the reward of block 1. The engine credits the one of block 2 after its txns, the state
+	// override credits it upfront.
+	reward := hexutil.Big(*new(big.Int).Mul(big.NewInt(4), big.NewInt(params.Ether)))
+	req := SimulationRequest{
+		Validation: true,
+		BlockStateCalls: []SimulatedBlock{{
+			StateOverrides: &ethapi.StateOverrides{common.Address{}: ethapi.Account{Balance: func() **hexutil.Big { b := &reward; return &b }()}},
+			Calls:          []ethapi.CallArgs{transfer},
+		}},
+	}
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	res, err := api.SimulateV1(ctx, req, &latest)
+	require.NoError(t, err)
+	require.Len(t, res, 1)
+
+	signer := types.LatestSignerForChainID(params.TestChainConfig.ChainID)
+	txn, err := types.SignTx(types.NewTransaction(0, receiver, uint256.MustFromBig(value), params.TxGas, uint256.MustFromBig(gasPrice), nil), *signer, simulationTestKey)
+	require.NoError(t, err)
+	require.NoError(t, contractBackend.SendTransaction(ctx, txn))
+	contractBackend.Commit()
+	block, err := contractBackend.BlockByNumber(ctx, big.NewInt(2))
+	require.NoError(t, err)
+	require.Equal(t, block.Root(), res[0]["stateRoot"])
+
+	// block 1 is now behind the latest state, which is rewound
+	past := rpc.BlockNumberOrHashWithNumber(1)
+	res, err = api.SimulateV1(ctx, req, &past)
+	require.NoError(t, err)
+	require.Equal(t, block.Root(), res[0]["stateRoot"])
+
+	// the trie of a block too far behind is not rebuilt: the simulated block carries its state root
+	for i := 0; i < maxSimulateRootRewindBlocks; i++ {
+		contractBackend.Commit()
+	}
+	res, err = api.SimulateV1(ctx, req, &past)
+	require.NoError(t, err)
+	base, err := contractBackend.BlockByNumber(ctx, big.NewInt(1))
+	require.NoError(t, err)
+	require.Equal(t, base.Root(), res[0]["stateRoot"])
+	require.Len(t, res[0]["calls"], 1)
+}
+
+func TestSimulateV1TransferLogs(t *testing.T) {
+	var (
+		address  = crypto.PubkeyToAddress(simulationTestKey.PublicKey)
+		contract = common.HexToAddress("0x2000000000000000000000000000000000000002")
+		receiver = common.HexToAddress("0x1000000000000000000000000000000000000001")
+		ctx      = context.Background()
+	)
+	api, _ := newSimulationTestAPI(t)
+
+	// LOG0, then CALL(gas, receiver, 1, 0, 0, 0, 0)
+	code := hexutility.Bytes(append(append(common.FromHex("0x60006000a06000600060006000600173"), receiver.Bytes()...), common.FromHex("0x5af100")...))
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	res, err := api.SimulateV1(ctx, SimulationRequest{
+		TraceTransfers: true,
+		BlockStateCalls: []SimulatedBlock{{
+			StateOverrides: &ethapi.StateOverrides{contract: ethapi.Account{Code: &code}},
+			Calls:          []ethapi.CallArgs{{From: &address, To: &contract, Value: (*hexutil.Big)(big.NewInt(1000))}},
+		}},
+	}, &latest)
+	require.NoError(t, err)
+
+	calls := res[0]["calls"].([]SimulatedCallResult)
+	require.Len(t, calls, 1)
+	require.Equal(t, hexutil.Uint64(types.ReceiptStatusSuccessful), calls[0].Status)
+	// the transfer logs are interleaved with the logs of the contract, in call order
+	logs := calls[0].Logs
+	require.Len(t, logs, 3)
+	require.Equal(t, transferAddress, logs[0].Address)
+	require.Equal(t, common.BytesToHash(contract.Bytes()), logs[0].Topics[2])
+	require.Equal(t, contract, logs[1].Address)
+	require.Equal(t, transferAddress, logs[2].Address)
+	require.Equal(t, common.BytesToHash(contract.Bytes()), logs[2].Topics[1])
+	require.Equal(t, common.BytesToHash(receiver.Bytes()), logs[2].Topics[2])
+	for i, l := range logs {
+		require.Equal(t, uint(i), l.Index)
+	}
+}
+
+func TestSimulateV1InvalidBlockOrder(t *testing.T) {
+	base := &types.Header{Number: big.NewInt(10), Time: 100}
+
+	_, err := sanitizeSimulatedBlocks(base, []SimulatedBlock{{BlockOverrides: &SimulatedBlockOverrides{Number: (*hexutil.Big)(big.NewInt(10))}}})
+	var simErr *simulationError
+	require.ErrorAs(t, err, &simErr)
+	require.Equal(t, simulateErrCodeInvalidBlockNum, simErr.ErrorCode())
+
+	ts := hexutil.Uint64(100)
+	_, err = sanitizeSimulatedBlocks(base, []SimulatedBlock{{BlockOverrides: &SimulatedBlockOverrides{Time: &ts}}})
+	require.ErrorAs(t, err, &simErr)
+	require.Equal(t, simulateErrCodeInvalidTimestamp, simErr.ErrorCode())
+
+	_, err = sanitizeSimulatedBlocks(base, []SimulatedBlock{{BlockOverrides: &SimulatedBlockOverrides{Number: (*hexutil.Big)(big.NewInt(10 + maxSimulateBlocks + 1))}}})
+	require.ErrorAs(t, err, &simErr)
+	require.Equal(t, simulateErrCodeClientLimit, simErr.ErrorCode())
+
+	blocks, err := sanitizeSimulatedBlocks(base, []SimulatedBlock{{}, {}})
+	require.NoError(t, err)
+	require.Len(t, blocks, 2)
+	require.Equal(t, hexutil.Uint64(112), *blocks[0].BlockOverrides.Time)
+	require.Equal(t, hexutil.Uint64(124), *blocks[1].BlockOverrides.Time)
+}
@@ -1,73 +1,473 @@
 package jsonrpc
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 
+	"github.com/holiman/uint256"
+
 	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
 	"github.com/erigontech/erigon-lib/common/hexutility"
 	txPoolProto "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
 
+	"github.com/erigontech/erigon/core/rawdb"
 	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/params"
+	"github.com/erigontech/erigon/rpc"
+	ethapi2 "github.com/erigontech/erigon/turbo/adapter/ethapi"
+	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/txnprovider/txpool"
+	"github.com/erigontech/erigon/txnprovider/txpool/txpoolcfg"
 )
 
 // SendRawTransaction implements eth_sendRawTransaction. Creates new message call transaction or a contract creation for previously-signed transactions.
 func (api *APIImpl) SendRawTransaction(ctx context.Context, encodedTx hexutility.Bytes) (common.Hash, error) {
-	txn, err := types.DecodeWrappedTransaction(encodedTx)
+	txn, err := api.checkRawTransaction(ctx, encodedTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	hash := txn.Hash()
+	res, err := api.txPool.Add(ctx, &txPoolProto.AddRequest{RlpTxs: [][]byte{encodedTx}})
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if res.Imported[0] != txPoolProto.ImportResult_SUCCESS {
+		return hash, fmt.Errorf("%s: %s", txPoolProto.ImportResult_name[int32(res.Imported[0])], res.Errors[0])
+	}
+
+	return txn.Hash(), nil
+}
+
+// SendPrivateRawTransactionOptions are the options of eth_sendPrivateRawTransaction
+type SendPrivateRawTransactionOptions struct {
+	MaxBlockNumber *hexutil.Uint64 `json:"maxBlockNumber,omitempty"` // the transaction expires after this block, capped by --txpool.private.lifetime
+	Fallback       bool            `json:"fallback,omitempty"`       // propagate the transaction to peers once it expires, instead of dropping it
+}
+
+// SendPrivateRawTransaction implements eth_sendPrivateRawTransaction. Like eth_sendRawTransaction, but the transaction
+// is only used by locally built blocks and never propagated to peers, until it expires.
+func (api *APIImpl) SendPrivateRawTransaction(ctx context.Context, encodedTx hexutility.Bytes, options *SendPrivateRawTransactionOptions) (common.Hash, error) {
+	txn, err := api.checkRawTransaction(ctx, encodedTx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	req := &txPoolProto.AddRequest{RlpTxs: [][]byte{encodedTx}, Private: true}
+	if options != nil {
+		if options.MaxBlockNumber != nil {
+			req.PrivateMaxBlockNumber = uint64(*options.MaxBlockNumber)
+		}
+		req.PrivateFallback = options.Fallback
+	}
+
+	hash := txn.Hash()
+	res, err := api.txPool.Add(ctx, req)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if res.Imported[0] != txPoolProto.ImportResult_SUCCESS {
+		return hash, fmt.Errorf("%s: %s", txPoolProto.ImportResult_name[int32(res.Imported[0])], res.Errors[0])
+	}
+
+	return hash, nil
+}
+
+// errCodeConditionsNotMet is returned by eth_sendRawTransactionConditional when the conditions don't hold
+const errCodeConditionsNotMet = -32003
+
+// KnownAccountConditions are the conditions on the storage of an account: either its storage root,
+// given as a hash, or the values of some of its slots, given as a slot => value object.
+type KnownAccountConditions struct {
+	StorageRoot *common.Hash
+	Slots       map[common.Hash]common.Hash
+}
+
+func (c *KnownAccountConditions) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '{' {
+		return json.Unmarshal(data, &c.Slots)
+	}
+	c.StorageRoot = new(common.Hash)
+	return json.Unmarshal(data, c.StorageRoot)
+}
+
+func (c KnownAccountConditions) MarshalJSON() ([]byte, error) {
+	if c.StorageRoot != nil {
+		return json.Marshal(c.StorageRoot)
+	}
+	return json.Marshal(c.Slots)
+}
+
+// SendRawTransactionConditionalOptions are the conditions of eth_sendRawTransactionConditional
+type SendRawTransactionConditionalOptions struct {
+	KnownAccounts  map[common.Address]KnownAccountConditions `json:"knownAccounts,omitempty"`
+	BlockNumberMin *hexutil.Uint64                           `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Uint64                           `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64                           `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64                           `json:"timestampMax,omitempty"`
+}
+
+// SendRawTransactionConditional implements eth_sendRawTransactionConditional, used by ERC-4337 bundlers. Like
+// eth_sendRawTransaction, but the transaction is only included while the given storage and block number/timestamp
+// conditions hold: it is dropped from the pool as soon as they can't hold anymore and is never propagated to peers.
+func (api *APIImpl) SendRawTransactionConditional(ctx context.Context, encodedTx hexutility.Bytes, options SendRawTransactionConditionalOptions) (common.Hash, error) {
+	txn, err := api.checkRawTransaction(ctx, encodedTx)
 	if err != nil {
 		return common.Hash{}, err
 	}
 
+	conditions := &txpool.TxnConditions{}
+	if options.BlockNumberMin != nil {
+		conditions.BlockNumberMin = uint64(*options.BlockNumberMin)
+	}
+	if options.BlockNumberMax != nil {
+		conditions.BlockNumberMax = uint64(*options.BlockNumberMax)
+	}
+	if options.TimestampMin != nil {
+		conditions.TimestampMin = uint64(*options.TimestampMin)
+	}
+	if options.TimestampMax != nil {
+		conditions.TimestampMax = uint64(*options.TimestampMax)
+	}
+	for addr, account := range options.KnownAccounts {
+		if account.StorageRoot != nil {
+			if conditions.StorageRoots == nil {
+				conditions.StorageRoots = map[common.Address]common.Hash{}
+			}
+			conditions.StorageRoots[addr] = *account.StorageRoot
+			continue
+		}
+		if conditions.KnownAccounts == nil {
+			conditions.KnownAccounts = map[common.Address]map[common.Hash]common.Hash{}
+		}
+		conditions.KnownAccounts[addr] = account.Slots
+	}
+	if err := conditions.Validate(); err != nil {
+		return common.Hash{}, &rpc.InvalidParamsError{Message: err.Error()}
+	}
+
+	// The txpool can't compute storage roots: they are checked here against a single block, the pool drops the
+	// transaction as soon as a later block changes the storage of one of these accounts. The pool rejects roots
+	// checked against an older block than the last one it has processed, then they are checked again.
+	hash := txn.Hash()
+	for attempt := 1; ; attempt++ {
+		if len(conditions.StorageRoots) > 0 {
+			if conditions.StorageRootsBlock, err = api.checkStorageRoots(ctx, conditions.StorageRoots); err != nil {
+				return common.Hash{}, err
+			}
+		}
+
+		res, err := api.txPool.AddConditional(ctx, &txPoolProto.AddConditionalRequest{RlpTx: encodedTx, Conditions: txpool.ConditionsToProto(conditions)})
+		if err != nil {
+			return common.Hash{}, err
+		}
+
+		if res.Imported[0] == txPoolProto.ImportResult_SUCCESS {
+			return hash, nil
+		}
+		switch res.Errors[0] {
+		case txpoolcfg.ConditionsOutdated.String():
+			if attempt < maxStorageRootsChecks {
+				continue
+			}
+		case txpoolcfg.ConditionsNotMet.String():
+			return hash, &rpc.CustomError{Code: errCodeConditionsNotMet, Message: res.Errors[0]}
+		}
+		return hash, fmt.Errorf("%s: %s", txPoolProto.ImportResult_name[int32(res.Imported[0])], res.Errors[0])
+	}
+}
+
+// maxStorageRootsChecks bounds the storage roots checks of eth_sendRawTransactionConditional racing new blocks
+const maxStorageRootsChecks = 3
+
+// checkStorageRoots checks the storage roots of a conditional transaction against the latest block and returns its number
+func (api *APIImpl) checkStorageRoots(ctx context.Context, roots map[common.Address]common.Hash) (uint64, error) {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	blockNum, err := rpchelper.GetLatestBlockNumber(tx)
+	tx.Rollback()
+	if err != nil {
+		return 0, err
+	}
+	for addr, root := range roots {
+		proof, err := api.GetProof(ctx, addr, nil, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(blockNum)))
+		if err != nil {
+			return 0, err
+		}
+		if proof.StorageHash != root {
+			return 0, &rpc.CustomError{Code: errCodeConditionsNotMet, Message: fmt.Sprintf("storage root of %x is %x, not %x", addr, proof.StorageHash, root)}
+		}
+	}
+	return blockNum, nil
+}
+
+// checkRawTransaction decodes a transaction submitted over rpc and checks its fee cap, replay protection and chain id.
+func (api *APIImpl) checkRawTransaction(ctx context.Context, encodedTx hexutility.Bytes) (types.Transaction, error) {
+	txn, err := types.DecodeWrappedTransaction(encodedTx)
+	if err != nil {
+		return nil, err
+	}
+
 	// If the transaction fee cap is already specified, ensure the
 	// fee of the given transaction is _reasonable_.
 	if err := checkTxFee(txn.GetPrice().ToBig(), txn.GetGas(), api.FeeCap); err != nil {
-		return common.Hash{}, err
+		return nil, err
 	}
 	if !txn.Protected() && !api.AllowUnprotectedTxs {
-		return common.Hash{}, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
+		return nil, errors.New("only replay-protected (EIP-155) transactions allowed over RPC")
 	}
 
-	// this has been moved to prior to adding of transactions to capture the
-	// pre state of the db - which is used for logging in the messages below
 	tx, err := api.db.BeginTemporalRo(ctx)
 	if err != nil {
-		return common.Hash{}, err
+		return nil, err
 	}
-
 	defer tx.Rollback()
 
 	cc, err := api.chainConfig(ctx, tx)
 	if err != nil {
-		return common.Hash{}, err
+		return nil, err
 	}
 
 	if txn.Protected() {
 		txnChainId := txn.GetChainID()
 		chainId := cc.ChainID
 		if chainId.Cmp(txnChainId.ToBig()) != 0 {
-			return common.Hash{}, fmt.Errorf("invalid chain id, expected: %d got: %d", chainId, *txnChainId)
+			return nil, fmt.Errorf("invalid chain id, expected: %d got: %d", chainId, *txnChainId)
 		}
 	}
+	return txn, nil
+}
 
-	hash := txn.Hash()
-	res, err := api.txPool.Add(ctx, &txPoolProto.AddRequest{RlpTxs: [][]byte{encodedTx}})
+// SendTransaction implements eth_sendTransaction. Creates new message call transaction or a contract creation if the data field contains code.
+// The transaction is signed by the configured account signer on behalf of args.From and submitted like eth_sendRawTransaction.
+func (api *APIImpl) SendTransaction(ctx context.Context, args ethapi2.CallArgs) (common.Hash, error) {
+	if api.AccountSigner == nil {
+		return common.Hash{}, fmt.Errorf(NoAccountSigner, "eth_sendTransaction")
+	}
+	txn, err := api.signTransaction(ctx, args)
 	if err != nil {
 		return common.Hash{}, err
 	}
+	var buf bytes.Buffer
+	if err := txn.MarshalBinary(&buf); err != nil {
+		return common.Hash{}, err
+	}
+	return api.SendRawTransaction(ctx, buf.Bytes())
+}
 
-	if res.Imported[0] != txPoolProto.ImportResult_SUCCESS {
-		return hash, fmt.Errorf("%s: %s", txPoolProto.ImportResult_name[int32(res.Imported[0])], res.Errors[0])
+// SignTransactionResult is the result of eth_signTransaction: the encoded transaction and its decoded form.
+type SignTransactionResult struct {
+	Raw hexutility.Bytes `json:"raw"`
+	Tx  *RPCTransaction  `json:"tx"`
+}
+
+// SignTransaction implements eth_signTransaction. Fills in the missing fields of args like eth_sendTransaction
+// and signs the transaction on behalf of args.From, without submitting it.
+func (api *APIImpl) SignTransaction(ctx context.Context, args ethapi2.CallArgs) (*SignTransactionResult, error) {
+	if api.AccountSigner == nil {
+		return nil, fmt.Errorf(NoAccountSigner, "eth_signTransaction")
+	}
+	if args.Gas == nil {
+		return nil, errors.New("gas not specified")
+	}
+	if args.Nonce == nil {
+		return nil, errors.New("nonce not specified")
 	}
+	txn, err := api.signTransaction(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := txn.MarshalBinary(&buf); err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{Raw: buf.Bytes(), Tx: NewRPCTransaction(txn, common.Hash{}, 0, 0, nil)}, nil
+}
 
-	return txn.Hash(), nil
+// Sign implements eth_sign. Calculates an Ethereum specific signature with: sign(keccak256('\\x19Ethereum Signed Message:\\n' + len(message) + message))).
+func (api *APIImpl) Sign(ctx context.Context, address common.Address, data hexutility.Bytes) (hexutility.Bytes, error) {
+	if api.AccountSigner == nil {
+		return nil, fmt.Errorf(NoAccountSigner, "eth_sign")
+	}
+	return api.AccountSigner.SignText(ctx, address, data)
 }
 
-// SendTransaction implements eth_sendTransaction. Creates new message call transaction or a contract creation if the data field contains code.
-func (api *APIImpl) SendTransaction(_ context.Context, txObject interface{}) (common.Hash, error) {
-	return common.Hash{0}, fmt.Errorf(NotImplemented, "eth_sendTransaction")
+// signTransaction builds the transaction described by args, filling in the nonce, fees and gas limit
+// when they are missing, and signs it with the account signer.
+func (api *APIImpl) signTransaction(ctx context.Context, args ethapi2.CallArgs) (types.Transaction, error) {
+	if args.From == nil {
+		return nil, errors.New("from address not specified")
+	}
+	if args.GasPrice != nil && (args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil) {
+		return nil, errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+	}
+	if args.Data != nil && args.Input != nil && !bytes.Equal(*args.Data, *args.Input) {
+		return nil, errors.New(`both "data" and "input" are set and not equal. Please use "input" to pass transaction call data`)
+	}
+	if args.Input == nil {
+		args.Input = args.Data
+	}
+	args.Data = nil
+	if args.To == nil && (args.Input == nil || len(*args.Input) == 0) {
+		return nil, errors.New("contract creation without any data provided")
+	}
+
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	cc, err := api.chainConfig(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	head := rawdb.ReadCurrentHeader(tx)
+	if head == nil {
+		return nil, errors.New("current header not found")
+	}
+	tx.Rollback()
+
+	if args.ChainID != nil && args.ChainID.ToInt().Cmp(cc.ChainID) != 0 {
+		return nil, fmt.Errorf("invalid chain id, expected: %d got: %d", cc.ChainID, args.ChainID.ToInt())
+	}
+	args.ChainID = (*hexutil.Big)(cc.ChainID)
+
+	if args.Nonce == nil {
+		nonce, err := api.GetTransactionCount(ctx, *args.From, rpc.BlockNumberOrHashWithNumber(rpc.PendingBlockNumber))
+		if err != nil {
+			return nil, err
+		}
+		args.Nonce = nonce
+	}
+	if err := api.setFeeDefaults(ctx, &args, head); err != nil {
+		return nil, err
+	}
+	if args.Gas == nil {
+		gas, err := api.EstimateGas(ctx, &args, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+		args.Gas = &gas
+	}
+
+	var value uint256.Int
+	if args.Value != nil {
+		if value.SetFromBig(args.Value.ToInt()) {
+			return nil, errors.New("value overflows uint256")
+		}
+	}
+	var input []byte
+	if args.Input != nil {
+		input = *args.Input
+	}
+	chainID := uint256.MustFromBig(cc.ChainID)
+
+	var txn types.Transaction
+	var commonTx *types.CommonTx
+	switch {
+	case args.MaxFeePerGas != nil:
+		tip, err := feeFromBig("maxPriorityFeePerGas", args.MaxPriorityFeePerGas)
+		if err != nil {
+			return nil, err
+		}
+		feeCap, err := feeFromBig("maxFeePerGas", args.MaxFeePerGas)
+		if err != nil {
+			return nil, err
+		}
+		dynamicTx := &types.DynamicFeeTransaction{
+			ChainID: chainID,
+			Tip:     tip,
+			FeeCap:  feeCap,
+		}
+		if args.AccessList != nil {
+			dynamicTx.AccessList = *args.AccessList
+		}
+		txn, commonTx = dynamicTx, &dynamicTx.CommonTx
+	default:
+		gasPrice, err := feeFromBig("gasPrice", args.GasPrice)
+		if err != nil {
+			return nil, err
+		}
+		if args.AccessList != nil {
+			accessListTx := &types.AccessListTx{
+				LegacyTx:   types.LegacyTx{GasPrice: gasPrice},
+				ChainID:    chainID,
+				AccessList: *args.AccessList,
+			}
+			txn, commonTx = accessListTx, &accessListTx.CommonTx
+		} else {
+			legacyTx := &types.LegacyTx{GasPrice: gasPrice}
+			txn, commonTx = legacyTx, &legacyTx.CommonTx
+		}
+	}
+	commonTx.Nonce = uint64(*args.Nonce)
+	commonTx.Gas = uint64(*args.Gas)
+	commonTx.To = args.To
+	commonTx.Value = &value
+	commonTx.Data = input
+	return api.AccountSigner.SignTx(ctx, *args.From, txn, cc.ChainID)
+}
+
+// feeFromBig converts a fee field of the call args, which may be set to anything by the caller
+func feeFromBig(name string, fee *hexutil.Big) (*uint256.Int, error) {
+	if fee == nil {
+		return nil, fmt.Errorf("%s not specified", name)
+	}
+	if fee.ToInt().Sign() < 0 {
+		return nil, fmt.Errorf("%s is negative", name)
+	}
+	var res uint256.Int
+	if res.SetFromBig(fee.ToInt()) {
+		return nil, fmt.Errorf("%s overflows uint256", name)
+	}
+	return &res, nil
+}
+
+// setFeeDefaults fills in the fee fields of args: a dynamic fee transaction is built once London is active on head,
+// unless a legacy gasPrice was given.
+func (api *APIImpl) setFeeDefaults(ctx context.Context, args *ethapi2.CallArgs, head *types.Header) error {
+	if args.GasPrice != nil {
+		if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+			return errors.New("both gasPrice and (maxFeePerGas or maxPriorityFeePerGas) specified")
+		}
+		return nil
+	}
+	if head.BaseFee == nil {
+		if args.MaxFeePerGas != nil || args.MaxPriorityFeePerGas != nil {
+			return errors.New("maxFeePerGas or maxPriorityFeePerGas specified but london is not active yet")
+		}
+		price, err := api.GasPrice(ctx)
+		if err != nil {
+			return err
+		}
+		args.GasPrice = price
+		return nil
+	}
+	if args.MaxPriorityFeePerGas == nil {
+		tip, err := api.MaxPriorityFeePerGas(ctx)
+		if err != nil {
+			return err
+		}
+		args.MaxPriorityFeePerGas = tip
+	}
+	if args.MaxFeePerGas == nil {
+		// twice the current base fee leaves room for six consecutive full blocks
+		feeCap := new(big.Int).Add(args.MaxPriorityFeePerGas.ToInt(), new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+		args.MaxFeePerGas = (*hexutil.Big)(feeCap)
+	}
+	if args.MaxFeePerGas.ToInt().Cmp(args.MaxPriorityFeePerGas.ToInt()) < 0 {
+		return fmt.Errorf("maxFeePerGas (%v) < maxPriorityFeePerGas (%v)", args.MaxFeePerGas, args.MaxPriorityFeePerGas)
+	}
+	return nil
 }
 
 // checkTxFee is an internal function used to check whether the fee of
@@ -28,7 +28,9 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
 	"github.com/erigontech/erigon-lib/common/u256"
+	"github.com/erigontech/erigon-lib/crypto"
 	sentry "github.com/erigontech/erigon-lib/gointerfaces/sentryproto"
 	txpool "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
 	txpool_proto "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
@@ -36,12 +38,15 @@ import (
 	"github.com/erigontech/erigon-lib/log/v3"
 	"github.com/erigontech/erigon-lib/rlp"
 	"github.com/erigontech/erigon-lib/wrap"
+	"github.com/erigontech/erigon/accounts"
+	"github.com/erigontech/erigon/accounts/keystore"
 	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/erigontech/erigon/core"
 	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/eth/protocols/eth"
 	"github.com/erigontech/erigon/params"
 	"github.com/erigontech/erigon/rpc/rpccfg"
+	"github.com/erigontech/erigon/turbo/adapter/ethapi"
 	"github.com/erigontech/erigon/turbo/jsonrpc"
 	"github.com/erigontech/erigon/turbo/rpchelper"
 	"github.com/erigontech/erigon/turbo/stages"
@@ -181,6 +186,78 @@ func TestSendRawTransactionUnprotected(t *testing.T) {
 	}
 }
 
+func TestSendTransactionWithKeystore(t *testing.T) {
+	mockSentry, require := mock.MockWithTxPool(t), require.New(t)
+	logger := log.New()
+
+	oneBlockStep(mockSentry, require, t)
+
+	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, mockSentry)
+	txPool := txpool.NewTxpoolClient(conn)
+	ff := rpchelper.New(ctx, rpchelper.DefaultFiltersConfig, nil, txPool, txpool.NewMiningClient(conn), func() {}, mockSentry.Log)
+	base := jsonrpc.NewBaseApi(ff, kvcache.New(kvcache.DefaultCoherentConfig), mockSentry.BlockReader, false, rpccfg.DefaultEvmCallTimeout, mockSentry.Engine, mockSentry.Dirs, nil)
+	api := jsonrpc.NewEthAPI(base, mockSentry.DB, nil, txPool, nil, 5000000, 1e18, 100_000, false, 100_000, 128, logger)
+
+	// without a signer the account methods stay disabled
+	_, err := api.Accounts(ctx)
+	require.ErrorContains(err, "requires an account signer")
+	_, err = api.SendTransaction(ctx, ethapi.CallArgs{From: &mockSentry.Address})
+	require.ErrorContains(err, "requires an account signer")
+
+	dir := t.TempDir()
+	_, err = keystore.StoreKey(dir, mockSentry.Key, "secret", keystore.LightScryptN, keystore.LightScryptP)
+	require.NoError(err)
+	ks, err := keystore.Open(dir, []string{"secret"})
+	require.NoError(err)
+	api.AccountSigner = ks
+
+	addrs, err := api.Accounts(ctx)
+	require.NoError(err)
+	require.Equal([]common.Address{mockSentry.Address}, addrs)
+
+	sig, err := api.Sign(ctx, mockSentry.Address, []byte("hello"))
+	require.NoError(err)
+	sig[crypto.RecoveryIDOffset] -= 27
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte("hello")), sig)
+	require.NoError(err)
+	require.Equal(mockSentry.Address, crypto.PubkeyToAddress(*pub))
+
+	to := common.Address{1}
+	gas, nonce := hexutil.Uint64(params.TxGas), hexutil.Uint64(0)
+	signed, err := api.SignTransaction(ctx, ethapi.CallArgs{From: &mockSentry.Address, To: &to, Gas: &gas, Nonce: &nonce, Value: (*hexutil.Big)(big.NewInt(1))})
+	require.NoError(err)
+	require.Equal(mockSentry.Address, signed.Tx.From)
+	decoded, err := types.DecodeTransaction(signed.Raw)
+	require.NoError(err)
+	require.Equal(signed.Tx.Hash, decoded.Hash())
+	_, err = api.SignTransaction(ctx, ethapi.CallArgs{From: &mockSentry.Address, To: &to, Nonce: &nonce})
+	require.ErrorContains(err, "gas not specified")
+	_, err = api.SignTransaction(ctx, ethapi.CallArgs{From: &common.Address{2}, To: &to, Gas: &gas, Nonce: &nonce})
+	require.ErrorIs(err, accounts.ErrUnknownAccount)
+	hugePrice := (*hexutil.Big)(new(big.Int).Lsh(big.NewInt(1), 256))
+	_, err = api.SignTransaction(ctx, ethapi.CallArgs{From: &mockSentry.Address, To: &to, Gas: &gas, Nonce: &nonce, GasPrice: hugePrice})
+	require.ErrorContains(err, "gasPrice overflows uint256")
+
+	txsCh, id := ff.SubscribePendingTxs(1)
+	defer ff.UnsubscribePendingTxs(id)
+
+	expectedValue := uint64(5678)
+	txHash, err := api.SendTransaction(ctx, ethapi.CallArgs{From: &mockSentry.Address, To: &to, Value: (*hexutil.Big)(new(big.Int).SetUint64(expectedValue))})
+	require.NoError(err)
+
+	select {
+	case got := <-txsCh:
+		require.Equal(txHash, got[0].Hash())
+		require.Equal(expectedValue, got[0].GetValue().Uint64())
+		require.Equal(params.TxGas, got[0].GetGas())
+	case <-time.After(20 * time.Second): // Sometimes the channel times out on github actions
+		t.Log("Timeout waiting for txn from channel")
+		jsonTx, err := api.GetTransactionByHash(ctx, txHash)
+		require.NoError(err)
+		require.Equal(expectedValue, jsonTx.Value.Uint64())
+	}
+}
+
 func transaction(nonce uint64, gaslimit uint64, key *ecdsa.PrivateKey) types.Transaction {
 	return pricedTransaction(nonce, gaslimit, u256.Num1, key)
 }
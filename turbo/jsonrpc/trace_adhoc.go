@@ -27,6 +27,7 @@ import (
 
 	"github.com/holiman/uint256"
 
+	"github.com/erigontech/erigon-lib/chain"
 	libcommon "github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/hexutil"
 	"github.com/erigontech/erigon-lib/common/hexutility"
@@ -985,6 +986,45 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 	}
 	defer tx.Rollback()
 
+	return api.traceMessage(ctx, tx, blockNrOrHash, traceTypes, traceConfig, true /* gasBailout */, func(chainConfig *chain.Config, header *types.Header) (types.Message, error) {
+		// Get a new instance of the EVM.
+		var baseFee *uint256.Int
+		if header != nil && header.BaseFee != nil {
+			var overflow bool
+			baseFee, overflow = uint256.FromBig(header.BaseFee)
+			if overflow {
+				return types.Message{}, errors.New("header.BaseFee uint256 overflow")
+			}
+		}
+		return args.ToMessage(api.gasCap, baseFee)
+	})
+}
+
+// RawTransaction implements trace_rawTransaction: traces a signed transaction, which doesn't have to be known by
+// the node, on top of the state of the given block (latest by default). Unlike trace_call, the nonce and the
+// balance of the sender are checked.
+func (api *TraceAPIImpl) RawTransaction(ctx context.Context, encodedTx hexutility.Bytes, traceTypes []string, blockNrOrHash *rpc.BlockNumberOrHash, traceConfig *config.TraceConfig) (*TraceCallResult, error) {
+	txn, err := types.DecodeWrappedTransaction(encodedTx)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transaction: %w", err)
+	}
+
+	tx, err := api.kv.BeginTemporalRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return api.traceMessage(ctx, tx, blockNrOrHash, traceTypes, traceConfig, false /* gasBailout */, func(chainConfig *chain.Config, header *types.Header) (types.Message, error) {
+		signer := types.MakeSigner(chainConfig, header.Number.Uint64(), header.Time)
+		return txn.AsMessage(*signer, header.BaseFee, chainConfig.Rules(header.Number.Uint64(), header.Time))
+	})
+}
+
+// traceMessage traces the message returned by toMessage, executed on top of the state of the given block (latest
+// by default), in its context
+func (api *TraceAPIImpl) traceMessage(ctx context.Context, tx kv.TemporalTx, blockNrOrHash *rpc.BlockNumberOrHash, traceTypes []string, traceConfig *config.TraceConfig, gasBailout bool,
+	toMessage func(chainConfig *chain.Config, header *types.Header) (types.Message, error)) (*TraceCallResult, error) {
 	chainConfig, err := api.chainConfig(ctx, tx)
 	if err != nil {
 		return nil, err
@@ -1058,16 +1098,7 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 		ot.traceAddr = []int{}
 	}
 
-	// Get a new instance of the EVM.
-	var baseFee *uint256.Int
-	if header != nil && header.BaseFee != nil {
-		var overflow bool
-		baseFee, overflow = uint256.FromBig(header.BaseFee)
-		if overflow {
-			return nil, errors.New("header.BaseFee uint256 overflow")
-		}
-	}
-	msg, err := args.ToMessage(api.gasCap, baseFee)
+	msg, err := toMessage(chainConfig, header)
 	if err != nil {
 		return nil, err
 	}
@@ -1090,7 +1121,7 @@ func (api *TraceAPIImpl) Call(ctx context.Context, args TraceCallParam, traceTyp
 	gp := new(core.GasPool).AddGas(msg.Gas()).AddBlobGas(msg.BlobGas())
 	var execResult *evmtypes.ExecutionResult
 	ibs.SetTxContext(0)
-	execResult, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, true /* gasBailout */)
+	execResult, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, gasBailout)
 	if err != nil {
 		return nil, err
 	}
@@ -1416,9 +1447,3 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, stateReader
 
 	return results, nil
 }
-
-// RawTransaction implements trace_rawTransaction.
-func (api *TraceAPIImpl) RawTransaction(ctx context.Context, txHash libcommon.Hash, traceTypes []string) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_rawTransaction")
-}
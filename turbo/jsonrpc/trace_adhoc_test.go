@@ -17,6 +17,7 @@
 package jsonrpc
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"math/big"
@@ -33,6 +34,7 @@ import (
 	"github.com/erigontech/erigon-lib/common/hexutil"
 	"github.com/erigontech/erigon-lib/common/math"
 	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
 
 	"github.com/erigontech/erigon/cmd/rpcdaemon/cli/httpcfg"
 	"github.com/erigontech/erigon/cmd/rpcdaemon/rpcdaemontest"
@@ -42,6 +44,7 @@ import (
 	"github.com/erigontech/erigon/core/vm"
 	"github.com/erigontech/erigon/core/vm/evmtypes"
 	"github.com/erigontech/erigon/eth/tracers/config"
+	"github.com/erigontech/erigon/params"
 	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/tests"
 	"github.com/erigontech/erigon/turbo/stages/mock"
@@ -453,3 +456,38 @@ func TestOeTracer(t *testing.T) {
 		})
 	}
 }
+
+func TestRawTransaction(t *testing.T) {
+	m, _, _ := rpcdaemontest.CreateTestSentry(t)
+	api := NewTraceAPI(newBaseApiForTest(m), m.DB, &httpcfg.HttpCfg{})
+	ethApi := NewEthAPI(newBaseApiForTest(m), m.DB, nil, nil, nil, 5000000, 1e18, 100_000, false, 100_000, 128, log.New())
+	ctx := context.Background()
+
+	nonce, err := ethApi.GetTransactionCount(ctx, m.Address, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber))
+	require.NoError(t, err)
+	gasPrice := uint256.NewInt(100 * params.GWei)
+
+	to := libcommon.Address{0xee}
+	encode := func(nonce uint64) []byte {
+		txn := types.MustSignNewTx(m.Key, *types.LatestSigner(m.ChainConfig), types.NewTransaction(nonce, to, uint256.NewInt(7), 21000, gasPrice, nil))
+		var buf bytes.Buffer
+		require.NoError(t, txn.MarshalBinary(&buf))
+		return buf.Bytes()
+	}
+
+	res, err := api.RawTransaction(ctx, encode(uint64(*nonce)), []string{TraceTypeTrace, TraceTypeStateDiff}, nil, nil)
+	require.NoError(t, err)
+	require.Len(t, res.Trace, 1)
+	action := res.Trace[0].Action.(*CallTraceAction)
+	require.Equal(t, m.Address, action.From)
+	require.Equal(t, to, action.To)
+	require.Equal(t, uint64(7), action.Value.ToInt().Uint64())
+	require.Contains(t, res.StateDiff, to)
+	require.Contains(t, res.StateDiff, m.Address)
+
+	// the nonce is checked, unlike in trace_call
+	_, err = api.RawTransaction(ctx, encode(uint64(*nonce)+1), []string{TraceTypeTrace}, nil, nil)
+	require.Error(t, err)
+	_, err = api.RawTransaction(ctx, []byte{1, 2, 3}, []string{TraceTypeTrace}, nil, nil)
+	require.Error(t, err)
+}
@@ -20,6 +20,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"math/big"
 
 	jsoniter "github.com/json-iterator/go"
 
@@ -28,11 +30,13 @@ import (
 	"github.com/erigontech/erigon-lib/chain"
 	"github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/order"
 	"github.com/erigontech/erigon-lib/kv/rawdbv3"
 	"github.com/erigontech/erigon-lib/kv/stream"
 	"github.com/erigontech/erigon-lib/log/v3"
+	libstate "github.com/erigontech/erigon-lib/state"
 	"github.com/erigontech/erigon/consensus"
 	"github.com/erigontech/erigon/consensus/ethash"
 	"github.com/erigontech/erigon/core"
@@ -47,6 +51,7 @@ import (
 	bortypes "github.com/erigontech/erigon/polygon/bor/types"
 	"github.com/erigontech/erigon/rpc"
 	"github.com/erigontech/erigon/turbo/rpchelper"
+	"github.com/erigontech/erigon/turbo/services"
 	"github.com/erigontech/erigon/turbo/snapshotsync/freezeblocks"
 	"github.com/erigontech/erigon/turbo/transactions"
 )
@@ -256,7 +261,7 @@ func (api *TraceAPIImpl) Block(ctx context.Context, blockNr rpc.BlockNumber, gas
 	return out, err
 }
 
-func traceFilterBitmapsV3(tx kv.TemporalTx, req TraceFilterRequest, from, to uint64) (fromAddresses, toAddresses map[common.Address]struct{}, allBlocks stream.U64, err error) {
+func traceFilterBitmapsV3(tx kv.TemporalTx, req TraceFilterRequest, from, to, indexedFrom uint64) (fromAddresses, toAddresses map[common.Address]struct{}, allBlocks stream.U64, err error) {
 	fromAddresses = make(map[common.Address]struct{}, len(req.FromAddress))
 	toAddresses = make(map[common.Address]struct{}, len(req.ToAddress))
 	var blocksTo stream.U64
@@ -294,15 +299,68 @@ func traceFilterBitmapsV3(tx kv.TemporalTx, req TraceFilterRequest, from, to uin
 
 	// Special case - if no addresses specified, take all traces
 	if len(req.FromAddress) == 0 && len(req.ToAddress) == 0 {
-		allBlocks = stream.Range[uint64](from, to)
+		allBlocks = nil
 		//} else {
 		//allBlocks.RemoveRange(0, from)
 		//allBlocks.RemoveRange(to, uint64(0x100000000))
 	}
 
+	// the indices of the call frames narrow the txns down further, from indexedFrom on: the txns below it are kept,
+	// the criteria are checked on their traces anyway
+	if len(req.Selector) > 0 || (req.Failed != nil && *req.Failed) {
+		indexedFrom = min(max(indexedFrom, from), to)
+		var byFrames stream.U64
+		if len(req.Selector) > 0 && indexedFrom < to {
+			for _, sel := range req.Selector {
+				it, err := tx.IndexRange(kv.TracesSelectorIdx, sel, int(indexedFrom), int(to), order.Asc, kv.Unlim)
+				if err != nil {
+					return nil, nil, nil, err
+				}
+				byFrames = stream.Union[uint64](byFrames, it, order.Asc, -1)
+			}
+		}
+		if req.Failed != nil && *req.Failed && indexedFrom < to {
+			it, err := tx.IndexRange(kv.TracesFailedIdx, kv.TracesFailedAnyKey, int(indexedFrom), int(to), order.Asc, kv.Unlim)
+			if err != nil {
+				return nil, nil, nil, err
+			}
+			byFrames = intersectTxNums(byFrames, it)
+		}
+		if indexedFrom > from {
+			byFrames = stream.Union[uint64](stream.Range[uint64](from, indexedFrom), byFrames, order.Asc, -1)
+		}
+		allBlocks = intersectTxNums(allBlocks, byFrames)
+	}
+	if allBlocks == nil {
+		allBlocks = stream.Range[uint64](from, to)
+	}
+
 	return fromAddresses, toAddresses, allBlocks, nil
 }
 
+// tracesIndexedFrom returns the txNum from which TracesSelectorIdx and TracesFailedIdx have the entries of all the
+// txns: they are written by the execution and backfilled by the custom trace stage. Nothing is assumed indexed if
+// the files can't be inspected through tx.
+func tracesIndexedFrom(tx kv.TemporalTx) uint64 {
+	casted, ok := tx.(libstate.HasAggTx)
+	if !ok {
+		return math.MaxUint64
+	}
+	ac, ok := casted.AggTx().(*libstate.AggregatorRoTx)
+	if !ok {
+		return math.MaxUint64
+	}
+	return max(ac.IIFirstCompleteTxNum(kv.TracesSelectorIdxPos), ac.IIFirstCompleteTxNum(kv.TracesFailedIdxPos))
+}
+
+// intersectTxNums intersects two streams of txNums, nil meaning no constraint
+func intersectTxNums(x, y stream.U64) stream.U64 {
+	if x == nil {
+		return y
+	}
+	return stream.Intersect[uint64](x, y, -1)
+}
+
 // Filter implements trace_filter
 // NOTE: We do not store full traces - we just store index for each address
 // Pull blocks which have txs with matching address
@@ -342,22 +400,91 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, gas
 }
 
 func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromBlock, toBlock uint64, req TraceFilterRequest, stream *jsoniter.Stream, gasBailOut bool, traceConfig *config.TraceConfig) error {
-	var fromTxNum, toTxNum uint64
-	var err error
-	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, api._blockReader))
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	stream.WriteArrayStart()
+	first := true
+	writeErr := func(err error) error {
+		if first {
+			first = false
+		} else {
+			stream.WriteMore()
+		}
+		stream.WriteObjectStart()
+		rpc.HandleError(err, stream)
+		stream.WriteObjectEnd()
+		return nil
+	}
+
+	count := uint64(^uint(0)) // this just makes it easier to use below
+	if req.Count != nil {
+		count = *req.Count
+	}
+	after := uint64(0) // this just makes it easier to use below
+	if req.After != nil {
+		after = *req.After
+	}
+	nSeen := uint64(0)
+	nExported := uint64(0)
+
+	fromTxNum, toTxNum, err := traceFilterTxNums(ctx, dbtx, api._blockReader, fromBlock, toBlock)
+	if err != nil {
+		return err
+	}
+	if err := api.walkFilteredTraces(ctx, dbtx, fromTxNum, toTxNum, req, api.compatibility, gasBailOut, traceConfig, func(_ uint64, _ int, pt *ParityTrace) error {
+		nSeen++
+		b, err := json.Marshal(pt)
+		if err != nil {
+			return writeErr(err)
+		}
+		if nSeen > after && nExported < count {
+			if first {
+				first = false
+			} else {
+				stream.WriteMore()
+			}
+			if _, err := stream.Write(b); err != nil {
+				return err
+			}
+			nExported++
+		}
+		return nil
+	}, writeErr); err != nil {
+		return err
+	}
+	stream.WriteArrayEnd()
+	return stream.Flush()
+}
 
+// traceFilterTxNums converts the inclusive block range of a trace filter to the [from, to) txNums range
+func traceFilterTxNums(ctx context.Context, dbtx kv.TemporalTx, blockReader services.FullBlockReader, fromBlock, toBlock uint64) (fromTxNum, toTxNum uint64, err error) {
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, blockReader))
 	if fromBlock > 0 {
 		fromTxNum, err = txNumsReader.Min(dbtx, fromBlock)
 		if err != nil {
-			return err
+			return 0, 0, err
 		}
 	}
 	toTxNum, err = txNumsReader.Max(dbtx, toBlock) // toBlock is an inclusive bound
 	if err != nil {
-		return err
+		return 0, 0, err
 	}
 	toTxNum++ //+1 because internally Erigon using semantic [from, to), but some RPC have different semantic
-	fromAddresses, toAddresses, allTxs, err := traceFilterBitmapsV3(dbtx, req, fromTxNum, toTxNum)
+	return fromTxNum, toTxNum, nil
+}
+
+// traceFilterVisitor receives the traces matching a trace filter. pos is the position of the trace among all the
+// traces of its txn (or among the rewards of the block, for the final system txn), so (txNum, pos) is stable
+type traceFilterVisitor func(txNum uint64, pos int, pt *ParityTrace) error
+
+// walkFilteredTraces re-executes the txns of [fromTxNum, toTxNum) picked by the trace indices, and visits the traces
+// matching the filter in order. Per-txn errors are passed to onErr, which aborts the walk if it returns an error.
+func (api *BaseAPI) walkFilteredTraces(ctx context.Context, dbtx kv.TemporalTx, fromTxNum, toTxNum uint64, req TraceFilterRequest, compat, gasBailOut bool, traceConfig *config.TraceConfig, visit traceFilterVisitor, onErr func(error) error) error {
+	criteria, err := newTraceFilterCriteria(req)
+	if err != nil {
+		return err
+	}
+	txNumsReader := rawdbv3.TxNums.WithCustomReadTxNumFunc(freezeblocks.ReadTxNumFuncFromBlockReader(ctx, api._blockReader))
+	fromAddresses, toAddresses, allTxs, err := traceFilterBitmapsV3(dbtx, req, fromTxNum, toTxNum, tracesIndexedFrom(dbtx))
 	if err != nil {
 		return err
 	}
@@ -370,23 +497,10 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 	}
 	engine := api.engine()
 
-	var json = jsoniter.ConfigCompatibleWithStandardLibrary
-	stream.WriteArrayStart()
-	first := true
 	// Execute all transactions in picked blocks
-
-	count := uint64(^uint(0)) // this just makes it easier to use below
-	if req.Count != nil {
-		count = *req.Count
-	}
-	after := uint64(0) // this just makes it easier to use below
-	if req.After != nil {
-		after = *req.After
-	}
 	vmConfig := vm.Config{}
-	nSeen := uint64(0)
-	nExported := uint64(0)
 	includeAll := len(fromAddresses) == 0 && len(toAddresses) == 0
+	isIntersectionMode := req.Mode == TraceFilterModeIntersection
 
 	var lastBlockHash common.Hash
 	var lastHeader *types.Header
@@ -400,38 +514,23 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 	for it.HasNext() {
 		txNum, blockNum, txIndex, isFnalTxn, blockNumChanged, err := it.Next()
 		if err != nil {
-			if first {
-				first = false
-			} else {
-				stream.WriteMore()
+			if err := onErr(err); err != nil {
+				return err
 			}
-			stream.WriteObjectStart()
-			rpc.HandleError(err, stream)
-			stream.WriteObjectEnd()
 			continue
 		}
 
 		if blockNumChanged {
 			if lastHeader, err = api._blockReader.HeaderByNumber(ctx, dbtx, blockNum); err != nil {
-				if first {
-					first = false
-				} else {
-					stream.WriteMore()
+				if err := onErr(err); err != nil {
+					return err
 				}
-				stream.WriteObjectStart()
-				rpc.HandleError(err, stream)
-				stream.WriteObjectEnd()
 				continue
 			}
 			if lastHeader == nil {
-				if first {
-					first = false
-				} else {
-					stream.WriteMore()
+				if err := onErr(fmt.Errorf("header not found: %d", blockNum)); err != nil {
+					return err
 				}
-				stream.WriteObjectStart()
-				rpc.HandleError(fmt.Errorf("header not found: %d", blockNum), stream)
-				stream.WriteObjectEnd()
 				continue
 			}
 
@@ -455,20 +554,14 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 
 			body, _, err := api._blockReader.Body(ctx, dbtx, lastBlockHash, blockNum)
 			if err != nil {
-				if first {
-					first = false
-				} else {
-					stream.WriteMore()
+				if err := onErr(err); err != nil {
+					return err
 				}
-				stream.WriteObjectStart()
-				rpc.HandleError(err, stream)
-				stream.WriteObjectEnd()
 				continue
 			}
 			// Block reward section, handle specially
 			minerReward, uncleRewards := ethash.AccumulateRewards(chainConfig, lastHeader, body.Uncles)
 			if _, ok := toAddresses[lastHeader.Coinbase]; ok || includeAll {
-				nSeen++
 				var tr ParityTrace
 				var rewardAction = &RewardTraceAction{}
 				rewardAction.Author = lastHeader.Coinbase
@@ -481,34 +574,15 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 				*tr.BlockNumber = blockNum
 				tr.Type = "reward" // nolint: goconst
 				tr.TraceAddress = []int{}
-				b, err := json.Marshal(tr)
-				if err != nil {
-					if first {
-						first = false
-					} else {
-						stream.WriteMore()
-					}
-					stream.WriteObjectStart()
-					rpc.HandleError(err, stream)
-					stream.WriteObjectEnd()
-					continue
-				}
-				if nSeen > after && nExported < count {
-					if first {
-						first = false
-					} else {
-						stream.WriteMore()
-					}
-					if _, err := stream.Write(b); err != nil {
+				if criteria.match(&tr) {
+					if err := visit(txNum, 0, &tr); err != nil {
 						return err
 					}
-					nExported++
 				}
 			}
 			for i, uncle := range body.Uncles {
 				if _, ok := toAddresses[uncle.Coinbase]; ok || includeAll {
 					if i < len(uncleRewards) {
-						nSeen++
 						var tr ParityTrace
 						rewardAction := &RewardTraceAction{}
 						rewardAction.Author = uncle.Coinbase
@@ -521,28 +595,10 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 						*tr.BlockNumber = blockNum
 						tr.Type = "reward" // nolint: goconst
 						tr.TraceAddress = []int{}
-						b, err := json.Marshal(tr)
-						if err != nil {
-							if first {
-								first = false
-							} else {
-								stream.WriteMore()
-							}
-							stream.WriteObjectStart()
-							rpc.HandleError(err, stream)
-							stream.WriteObjectEnd()
-							continue
-						}
-						if nSeen > after && nExported < count {
-							if first {
-								first = false
-							} else {
-								stream.WriteMore()
-							}
-							if _, err := stream.Write(b); err != nil {
+						if criteria.match(&tr) {
+							if err := visit(txNum, 1+i, &tr); err != nil {
 								return err
 							}
-							nExported++
 						}
 					}
 				}
@@ -556,14 +612,9 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 		//fmt.Printf("txNum=%d, blockNum=%d, txIndex=%d\n", txNum, blockNum, txIndex)
 		txn, err := api._txnReader.TxnByIdxInBlock(ctx, dbtx, blockNum, txIndex)
 		if err != nil {
-			if first {
-				first = false
-			} else {
-				stream.WriteMore()
+			if err := onErr(err); err != nil {
+				return err
 			}
-			stream.WriteObjectStart()
-			rpc.HandleError(err, stream)
-			stream.WriteObjectEnd()
 			continue
 		}
 		if txn == nil {
@@ -572,14 +623,9 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 		txHash := txn.Hash()
 		msg, err := txn.AsMessage(*lastSigner, lastHeader.BaseFee, lastRules)
 		if err != nil {
-			if first {
-				first = false
-			} else {
-				stream.WriteMore()
+			if err := onErr(err); err != nil {
+				return err
 			}
-			stream.WriteObjectStart()
-			rpc.HandleError(err, stream)
-			stream.WriteObjectEnd()
 			continue
 		}
 
@@ -597,7 +643,7 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 		if err != nil {
 			return err
 		}
-		ot.compat = api.compatibility
+		ot.compat = compat
 		ot.r = traceResult
 		ot.idx = []string{fmt.Sprintf("%d-", txIndex)}
 		ot.traceAddr = []int{}
@@ -614,75 +660,38 @@ func (api *TraceAPIImpl) filterV3(ctx context.Context, dbtx kv.TemporalTx, fromB
 		var execResult *evmtypes.ExecutionResult
 		execResult, err = core.ApplyMessage(evm, msg, gp, true /* refunds */, gasBailOut)
 		if err != nil {
-			if first {
-				first = false
-			} else {
-				stream.WriteMore()
+			if err := onErr(err); err != nil {
+				return err
 			}
-			stream.WriteObjectStart()
-			rpc.HandleError(err, stream)
-			stream.WriteObjectEnd()
 			continue
 		}
 		traceResult.Output = common.Copy(execResult.ReturnData)
 		if err = ibs.FinalizeTx(evm.ChainRules(), noop); err != nil {
-			if first {
-				first = false
-			} else {
-				stream.WriteMore()
+			if err := onErr(err); err != nil {
+				return err
 			}
-			stream.WriteObjectStart()
-			rpc.HandleError(err, stream)
-			stream.WriteObjectEnd()
 			continue
 		}
 		if err = ibs.CommitBlock(evm.ChainRules(), cachedWriter); err != nil {
-			if first {
-				first = false
-			} else {
-				stream.WriteMore()
+			if err := onErr(err); err != nil {
+				return err
 			}
-			stream.WriteObjectStart()
-			rpc.HandleError(err, stream)
-			stream.WriteObjectEnd()
 			continue
 		}
-		isIntersectionMode := req.Mode == TraceFilterModeIntersection
-		for _, pt := range traceResult.Trace {
-			if includeAll || filterTrace(pt, fromAddresses, toAddresses, isIntersectionMode) {
-				nSeen++
-				pt.BlockHash = &lastBlockHash
+		blockHash := lastBlockHash // the visitor may keep the traces
+		for pos, pt := range traceResult.Trace {
+			if (includeAll || filterTrace(pt, fromAddresses, toAddresses, isIntersectionMode)) && criteria.match(pt) {
+				pt.BlockHash = &blockHash
 				pt.BlockNumber = &blockNum
 				pt.TransactionHash = &txHash
 				pt.TransactionPosition = &txIndexU64
-				b, err := json.Marshal(pt)
-				if err != nil {
-					if first {
-						first = false
-					} else {
-						stream.WriteMore()
-					}
-					stream.WriteObjectStart()
-					rpc.HandleError(err, stream)
-					stream.WriteObjectEnd()
-					continue
-				}
-				if nSeen > after && nExported < count {
-					if first {
-						first = false
-					} else {
-						stream.WriteMore()
-					}
-					if _, err := stream.Write(b); err != nil {
-						return err
-					}
-					nExported++
+				if err := visit(txNum, pos, pt); err != nil {
+					return err
 				}
 			}
 		}
 	}
-	stream.WriteArrayEnd()
-	return stream.Flush()
+	return nil
 }
 
 func filterTrace(pt *ParityTrace, fromAddresses map[common.Address]struct{}, toAddresses map[common.Address]struct{}, isIntersectionMode bool) bool {
@@ -845,6 +854,87 @@ type TraceFilterRequest struct {
 	Mode        TraceFilterMode   `json:"mode"`
 	After       *uint64           `json:"after"`
 	Count       *uint64           `json:"count"`
+
+	// Extensions, narrowing the traces matched by the addresses down
+
+	// CallType matches the trace type ("call", "create", "suicide", "reward") or the call type of calls
+	// ("call", "delegatecall", "staticcall", "callcode")
+	CallType []string `json:"callType"`
+	// Selector matches the calls by the 4-byte function selector of their input
+	Selector []hexutility.Bytes `json:"selector"`
+	// MinValue matches the traces transferring at least this value
+	MinValue *hexutil.Big `json:"minValue"`
+	// Failed matches the failed (true) or the successful (false) traces
+	Failed *bool `json:"failed"`
+}
+
+// traceFilterCriteria are the extended criteria of a TraceFilterRequest, checked on every trace
+type traceFilterCriteria struct {
+	callTypes map[string]struct{}
+	selectors map[[4]byte]struct{}
+	minValue  *big.Int
+	failed    *bool
+}
+
+func newTraceFilterCriteria(req TraceFilterRequest) (*traceFilterCriteria, error) {
+	c := &traceFilterCriteria{failed: req.Failed}
+	if len(req.CallType) > 0 {
+		c.callTypes = make(map[string]struct{}, len(req.CallType))
+		for _, t := range req.CallType {
+			c.callTypes[t] = struct{}{}
+		}
+	}
+	if len(req.Selector) > 0 {
+		c.selectors = make(map[[4]byte]struct{}, len(req.Selector))
+		for _, sel := range req.Selector {
+			if len(sel) != 4 {
+				return nil, fmt.Errorf("invalid parameters: selector must be 4 bytes, got %d", len(sel))
+			}
+			c.selectors[[4]byte(sel)] = struct{}{}
+		}
+	}
+	if req.MinValue != nil {
+		c.minValue = req.MinValue.ToInt()
+	}
+	return c, nil
+}
+
+func (c *traceFilterCriteria) match(pt *ParityTrace) bool {
+	if c.failed != nil && *c.failed != (pt.Error != "") {
+		return false
+	}
+	var callType string
+	var input []byte
+	var value *big.Int
+	switch action := pt.Action.(type) {
+	case *CallTraceAction:
+		callType, input, value = action.CallType, action.Input, action.Value.ToInt()
+	case *CreateTraceAction:
+		value = action.Value.ToInt()
+	case *SuicideTraceAction:
+		value = action.Balance.ToInt()
+	case *RewardTraceAction:
+		value = action.Value.ToInt()
+	}
+	if c.callTypes != nil {
+		_, okType := c.callTypes[pt.Type]
+		_, okCallType := c.callTypes[callType]
+		if !okType && !okCallType {
+			return false
+		}
+	}
+	if c.selectors != nil {
+		if len(input) < 4 {
+			return false
+		}
+		if _, ok := c.selectors[[4]byte(input[:4])]; !ok {
+			return false
+		}
+	}
+	if c.minValue != nil && (value == nil || value.Cmp(c.minValue) < 0) {
+		return false
+	}
+	return true
 }
 
 type TraceFilterMode string
@@ -29,10 +29,14 @@ import (
 
 	"github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/common/hexutility"
+	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/rawdbv3"
 	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon-lib/rlp"
 
 	"github.com/erigontech/erigon/core"
+	"github.com/erigontech/erigon/core/rawdb"
 	"github.com/erigontech/erigon/core/state"
 	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/core/vm"
@@ -80,12 +84,91 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		return fmt.Errorf("invalid arguments; block with hash %x not found", hash)
 	}
 
+	usedGas, err := api.traceBlockTxns(ctx, tx, block, config, stream)
+	if err != nil {
+		return err
+	}
+
+	if dbg.AssertEnabled {
+		if block.GasUsed() != usedGas {
+			panic(fmt.Errorf("assert: block.GasUsed() %d != usedGas %d. blockNum=%d", block.GasUsed(), usedGas, blockNumber))
+		}
+	}
+	return nil
+}
+
+// TraceBlock implements debug_traceBlock. Returns Geth style traces of an RLP encoded block, which doesn't have to
+// be known by the node, executed on top of the state of its parent. The parent must be canonical.
+func (api *PrivateDebugAPIImpl) TraceBlock(ctx context.Context, blockRlp hexutility.Bytes, config *tracersConfig.TraceConfig, stream *jsoniter.Stream) error {
+	block := &types.Block{}
+	if err := rlp.DecodeBytes(blockRlp, block); err != nil {
+		stream.WriteNil()
+		return fmt.Errorf("could not decode block: %w", err)
+	}
+	return api.traceOffchainBlock(ctx, block, config, stream)
+}
+
+// TraceBadBlock implements debug_traceBadBlock. Returns Geth style traces of a block which failed validation, see
+// eth_getBadBlocks, executed on top of the state of its parent. The parent must be canonical.
+func (api *PrivateDebugAPIImpl) TraceBadBlock(ctx context.Context, hash common.Hash, config *tracersConfig.TraceConfig, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	blocks, err := rawdb.GetLatestBadBlocks(tx)
+	tx.Rollback()
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	for _, block := range blocks {
+		if block != nil && block.Hash() == hash {
+			return api.traceOffchainBlock(ctx, block, config, stream)
+		}
+	}
+	stream.WriteNil()
+	return fmt.Errorf("bad block %#x not found", hash)
+}
+
+// traceOffchainBlock traces a block which isn't part of the canonical chain, on top of its canonical parent
+func (api *PrivateDebugAPIImpl) traceOffchainBlock(ctx context.Context, block *types.Block, config *tracersConfig.TraceConfig, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginTemporalRo(ctx)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	defer tx.Rollback()
+
+	if block.NumberU64() == 0 {
+		stream.WriteNil()
+		return errors.New("genesis is not traceable")
+	}
+	parentHash, ok, err := api._blockReader.CanonicalHash(ctx, tx, block.NumberU64()-1)
+	if err != nil {
+		stream.WriteNil()
+		return err
+	}
+	if !ok || parentHash != block.ParentHash() {
+		stream.WriteNil()
+		return fmt.Errorf("parent %#x of block %d is not canonical", block.ParentHash(), block.NumberU64())
+	}
+
+	_, err = api.traceBlockTxns(ctx, tx, block, config, stream)
+	return err
+}
+
+// traceBlockTxns streams the traces of the txns of the block, executed on top of the state of the canonical
+// block before it, and returns the gas they used
+func (api *PrivateDebugAPIImpl) traceBlockTxns(ctx context.Context, tx kv.TemporalTx, block *types.Block, config *tracersConfig.TraceConfig, stream *jsoniter.Stream) (uint64, error) {
+	blockNumber := block.NumberU64()
+
 	// if we've pruned this history away for this block then just return early
 	// to save any red herring errors
-	err = api.BaseAPI.checkPruneHistory(ctx, tx, block.NumberU64())
+	err := api.BaseAPI.checkPruneHistory(ctx, tx, blockNumber)
 	if err != nil {
 		stream.WriteNil()
-		return err
+		return 0, err
 	}
 
 	if config == nil {
@@ -100,7 +183,7 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 	chainConfig, err := api.chainConfig(ctx, tx)
 	if err != nil {
 		stream.WriteNil()
-		return err
+		return 0, err
 	}
 	engine := api.engine()
 
@@ -108,7 +191,7 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 	ibs, blockCtx, _, rules, signer, err := transactions.ComputeBlockContext(ctx, engine, block.HeaderNoCopy(), chainConfig, api._blockReader, txNumsReader, tx, 0)
 	if err != nil {
 		stream.WriteNil()
-		return err
+		return 0, err
 	}
 
 	stream.WriteArrayStart()
@@ -128,7 +211,7 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		}
 		if err != nil {
 			stream.WriteArrayEnd()
-			return err
+			return 0, err
 		}
 		if ok {
 			borStateSyncTxn = bortypes.NewBorTransaction()
@@ -157,7 +240,7 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 			stream.WriteNil()
 			stream.WriteObjectEnd()
 			stream.WriteArrayEnd()
-			return ctx.Err()
+			return 0, ctx.Err()
 		}
 		ibs.SetTxContext(idx)
 		msg, _ := txn.AsMessage(*signer, block.BaseFee(), rules)
@@ -180,7 +263,7 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 			var stateSyncEvents []*types.Message
 			stateSyncEvents, err = api.stateSyncEvents(ctx, tx, block.Hash(), blockNumber, chainConfig)
 			if err != nil {
-				return err
+				return 0, err
 			}
 
 			var _usedGas uint64
@@ -200,7 +283,7 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 			usedGas += _usedGas
 		} else {
 			var _usedGas uint64
-			_usedGas, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
+			_usedGas, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, block.Hash(), idx, ibs, config, chainConfig, stream, api.evmCallTimeout)
 			usedGas += _usedGas
 		}
 		if err == nil {
@@ -219,22 +302,16 @@ func (api *PrivateDebugAPIImpl) traceBlock(ctx context.Context, blockNrOrHash rp
 		}
 
 		if err := stream.Flush(); err != nil {
-			return err
-		}
-	}
-
-	if dbg.AssertEnabled {
-		if block.GasUsed() != usedGas {
-			panic(fmt.Errorf("assert: block.GasUsed() %d != usedGas %d. blockNum=%d", block.GasUsed(), usedGas, blockNumber))
+			return 0, err
 		}
 	}
 
 	stream.WriteArrayEnd()
 	if err := stream.Flush(); err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return usedGas, nil
 }
 
 // TraceTransaction implements debug_traceTransaction. Returns Geth style transaction traces.
@@ -358,7 +435,7 @@ func (api *PrivateDebugAPIImpl) TraceTransaction(ctx context.Context, hash commo
 	}
 
 	// Trace the transaction and return
-	_, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
+	_, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, block.Hash(), txnIndex, ibs, config, chainConfig, stream, api.evmCallTimeout)
 	return err
 }
 
@@ -427,7 +504,7 @@ func (api *PrivateDebugAPIImpl) TraceCall(ctx context.Context, args ethapi.CallA
 	blockCtx := transactions.NewEVMBlockContext(engine, header, blockNrOrHash.RequireCanonical, dbtx, api._blockReader, chainConfig)
 	txCtx := core.NewEVMTxContext(msg)
 	// Trace the transaction and return
-	_, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, ibs, config, chainConfig, stream, api.evmCallTimeout)
+	_, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, common.Hash{}, 0, ibs, config, chainConfig, stream, api.evmCallTimeout)
 	return err
 }
 
@@ -565,7 +642,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 			}
 			txCtx = core.NewEVMTxContext(msg)
 			ibs.SetTxContext(txnIndex)
-			_, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, evm.IntraBlockState(), config, chainConfig, stream, api.evmCallTimeout)
+			_, err = transactions.TraceTx(ctx, msg, blockCtx, txCtx, common.Hash{}, txnIndex, evm.IntraBlockState(), config, chainConfig, stream, api.evmCallTimeout)
 			if err != nil {
 				stream.WriteArrayEnd()
 				stream.WriteArrayEnd()
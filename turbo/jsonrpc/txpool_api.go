@@ -20,22 +20,29 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/erigontech/erigon-lib/common/hexutil"
 
 	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/debug"
 	"github.com/erigontech/erigon-lib/gointerfaces"
 	proto_txpool "github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
 	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
 
 	"github.com/erigontech/erigon/core/rawdb"
 	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/rpc"
+	"github.com/erigontech/erigon/txnprovider/txpool/txpoolcfg"
 )
 
 // TxPoolAPI the interface for the txpool_ RPC commands
 type TxPoolAPI interface {
 	Content(ctx context.Context) (map[string]map[string]map[string]*RPCTransaction, error)
 	ContentFrom(ctx context.Context, addr libcommon.Address) (map[string]map[string]*RPCTransaction, error)
+	Events(ctx context.Context) (*rpc.Subscription, error)
+	GetTransactionStatus(ctx context.Context, hash libcommon.Hash) (*TxnStatus, error)
 }
 
 // TxPoolAPIImpl data structure to store things needed for net_ commands
@@ -64,11 +71,13 @@ func (api *TxPoolAPIImpl) Content(ctx context.Context) (map[string]map[string]ma
 		"pending": make(map[string]map[string]*RPCTransaction),
 		"baseFee": make(map[string]map[string]*RPCTransaction),
 		"queued":  make(map[string]map[string]*RPCTransaction),
+		"private": make(map[string]map[string]*RPCTransaction),
 	}
 
 	pending := make(map[libcommon.Address][]types.Transaction, 8)
 	baseFee := make(map[libcommon.Address][]types.Transaction, 8)
 	queued := make(map[libcommon.Address][]types.Transaction, 8)
+	private := make(map[libcommon.Address][]types.Transaction, 8) // also listed in their sub-pool
 	for i := range reply.Txs {
 		txn, err := types.DecodeWrappedTransaction(reply.Txs[i].RlpTx)
 		if err != nil {
@@ -92,6 +101,9 @@ func (api *TxPoolAPIImpl) Content(ctx context.Context) (map[string]map[string]ma
 			}
 			queued[addr] = append(queued[addr], txn)
 		}
+		if reply.Txs[i].IsPrivate {
+			private[addr] = append(private[addr], txn)
+		}
 	}
 
 	tx, err := api.db.BeginTemporalRo(ctx)
@@ -132,6 +144,14 @@ func (api *TxPoolAPIImpl) Content(ctx context.Context) (map[string]map[string]ma
 		}
 		content["queued"][account.Hex()] = dump
 	}
+	// Flatten the private transactions
+	for account, txs := range private {
+		dump := make(map[string]*RPCTransaction)
+		for _, txn := range txs {
+			dump[strconv.FormatUint(txn.GetNonce(), 10)] = newRPCPendingTransaction(txn, curHeader, cc)
+		}
+		content["private"][account.Hex()] = dump
+	}
 	return content, nil
 }
 
@@ -145,11 +165,13 @@ func (api *TxPoolAPIImpl) ContentFrom(ctx context.Context, addr libcommon.Addres
 		"pending": make(map[string]*RPCTransaction),
 		"baseFee": make(map[string]*RPCTransaction),
 		"queued":  make(map[string]*RPCTransaction),
+		"private": make(map[string]*RPCTransaction),
 	}
 
 	pending := make([]types.Transaction, 0, 4)
 	baseFee := make([]types.Transaction, 0, 4)
 	queued := make([]types.Transaction, 0, 4)
+	private := make([]types.Transaction, 0, 4) // also listed in their sub-pool
 	for i := range reply.Txs {
 		txn, err := types.DecodeWrappedTransaction(reply.Txs[i].RlpTx)
 		if err != nil {
@@ -168,6 +190,9 @@ func (api *TxPoolAPIImpl) ContentFrom(ctx context.Context, addr libcommon.Addres
 		case proto_txpool.AllReply_QUEUED:
 			queued = append(queued, txn)
 		}
+		if reply.Txs[i].IsPrivate {
+			private = append(private, txn)
+		}
 	}
 
 	tx, err := api.db.BeginTemporalRo(ctx)
@@ -202,6 +227,12 @@ func (api *TxPoolAPIImpl) ContentFrom(ctx context.Context, addr libcommon.Addres
 		dump[strconv.FormatUint(txn.GetNonce(), 10)] = newRPCPendingTransaction(txn, curHeader, cc)
 	}
 	content["queued"] = dump
+	// Flatten the private transactions
+	dump = make(map[string]*RPCTransaction)
+	for _, txn := range private {
+		dump[strconv.FormatUint(txn.GetNonce(), 10)] = newRPCPendingTransaction(txn, curHeader, cc)
+	}
+	content["private"] = dump
 	return content, nil
 }
 
@@ -255,3 +286,113 @@ func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
 	return content
 }
 */
+
+// TxnEvent is a step of a transaction lifecycle in the pool
+type TxnEvent struct {
+	Type          string          `json:"type"`
+	Hash          libcommon.Hash  `json:"hash"`
+	SubPool       string          `json:"subPool,omitempty"`       // for added, promoted and demoted
+	ReplacedBy    *libcommon.Hash `json:"replacedBy,omitempty"`    // for replaced
+	DiscardReason string          `json:"discardReason,omitempty"` // for discarded
+	BlockNumber   hexutil.Uint64  `json:"blockNumber"`
+	Timestamp     hexutil.Uint64  `json:"timestamp"`
+}
+
+func newTxnEvent(e *proto_txpool.TxnEvent) *TxnEvent {
+	event := &TxnEvent{
+		Type:        strings.ToLower(e.Type.String()),
+		Hash:        gointerfaces.ConvertH256ToHash(e.Hash),
+		BlockNumber: hexutil.Uint64(e.BlockNumber),
+		Timestamp:   hexutil.Uint64(e.Timestamp),
+	}
+	switch e.Type {
+	case proto_txpool.TxnEventType_ADDED, proto_txpool.TxnEventType_PROMOTED, proto_txpool.TxnEventType_DEMOTED:
+		switch e.SubPool {
+		case proto_txpool.AllReply_PENDING:
+			event.SubPool = "pending"
+		case proto_txpool.AllReply_BASE_FEE:
+			event.SubPool = "baseFee"
+		case proto_txpool.AllReply_QUEUED:
+			event.SubPool = "queued"
+		}
+	case proto_txpool.TxnEventType_REPLACED:
+		replacedBy := libcommon.Hash(gointerfaces.ConvertH256ToHash(e.ReplacedBy))
+		event.ReplacedBy = &replacedBy
+	case proto_txpool.TxnEventType_DISCARDED:
+		event.DiscardReason = txpoolcfg.DiscardReason(e.DiscardReason).String()
+	}
+	return event
+}
+
+// Events sends a notification for each transaction lifecycle event in the pool: added, promoted, demoted,
+// replaced, discarded and mined. Subscribe with txpool_subscribe("events").
+func (api *TxPoolAPIImpl) Events(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	subCtx, cancel := context.WithCancel(context.Background())
+	stream, err := api.pool.OnTxnEvent(subCtx, &proto_txpool.OnTxnEventRequest{})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		defer cancel()
+		go func() {
+			select {
+			case <-rpcSub.Err():
+				cancel()
+			case <-subCtx.Done():
+			}
+		}()
+
+		for {
+			e, err := stream.Recv()
+			if err != nil {
+				if subCtx.Err() == nil {
+					log.Warn("[rpc] txpool events subscription", "err", err)
+				}
+				return
+			}
+			if err := notifier.Notify(rpcSub.ID, newTxnEvent(e)); err != nil {
+				log.Warn("[rpc] error while notifying subscription", "err", err)
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// TxnStatus is the current status of a transaction, with the pool events it went through
+type TxnStatus struct {
+	Status string      `json:"status"` // pending, baseFee, queued, replaced, discarded or mined
+	Events []*TxnEvent `json:"events"`
+}
+
+// GetTransactionStatus returns the status of a transaction recently seen by the pool, or nil if the pool doesn't
+// remember it. Only the latest events of the latest transactions are kept.
+func (api *TxPoolAPIImpl) GetTransactionStatus(ctx context.Context, hash libcommon.Hash) (*TxnStatus, error) {
+	reply, err := api.pool.TransactionStatus(ctx, &proto_txpool.TransactionStatusRequest{Hash: gointerfaces.ConvertHashToH256(hash)})
+	if err != nil {
+		return nil, err
+	}
+	if !reply.Known || len(reply.Events) == 0 {
+		return nil, nil
+	}
+	status := &TxnStatus{Events: make([]*TxnEvent, len(reply.Events))}
+	for i := range reply.Events {
+		status.Events[i] = newTxnEvent(reply.Events[i])
+	}
+	last := status.Events[len(status.Events)-1]
+	if last.SubPool != "" {
+		status.Status = last.SubPool
+	} else {
+		status.Status = last.Type
+	}
+	return status, nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package logexporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/erigontech/erigon-lib/common"
+)
+
+// Config is the content of the --logexporter.config file, e.g.
+//
+//	{"exporters": [{
+//		"name": "indexer",
+//		"sink": "http://localhost:8080/events",
+//		"addresses": ["0xdac17f958d2ee523a2206206994597c13d831ec7"],
+//		"topics": [["0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"]],
+//		"receipts": true
+//	}]}
+type Config struct {
+	Exporters []ExporterConfig `json:"exporters"`
+}
+
+// ExporterConfig configures a single exporter. Addresses and Topics select the logs the same way as
+// eth_getLogs and rpchelper.LogsFilter: empty addresses match any address, an empty (null) topic position
+// matches any topic.
+type ExporterConfig struct {
+	Name string `json:"name"` // identifies the cursor of the exporter, renaming it restarts the export
+	// Sink is where the events are delivered:
+	//   - http(s)://host/path - webhook, every batch is POSTed as NDJSON, any 2xx status acknowledges it
+	//   - file:///path/to/events.ndjson - append-only NDJSON file, synced after every batch
+	//   - unix:///path/to/socket - NDJSON stream over a local Unix socket
+	Sink      string           `json:"sink"`
+	Addresses []common.Address `json:"addresses,omitempty"`
+	Topics    [][]common.Hash  `json:"topics,omitempty"`
+	Receipts  bool             `json:"receipts,omitempty"`  // attach the receipts of the txns with matching logs
+	Headers   bool             `json:"headers,omitempty"`   // attach the block headers
+	FromBlock *uint64          `json:"fromBlock,omitempty"` // first block to export, by default the export starts at the head
+}
+
+var exporterNameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+func ReadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("log exporter config: %w", err)
+	}
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("log exporter config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("log exporter config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (cfg *Config) Validate() error {
+	names := map[string]struct{}{}
+	for _, e := range cfg.Exporters {
+		if !exporterNameRe.MatchString(e.Name) {
+			return fmt.Errorf("invalid exporter name %q, expected letters, digits, '_', '.' or '-'", e.Name)
+		}
+		if _, ok := names[e.Name]; ok {
+			return fmt.Errorf("duplicate exporter name %q", e.Name)
+		}
+		names[e.Name] = struct{}{}
+		if e.Sink == "" {
+			return fmt.Errorf("exporter %q: no sink", e.Name)
+		}
+		if len(e.Topics) > 4 {
+			return fmt.Errorf("exporter %q: at most 4 topic positions, got %d", e.Name, len(e.Topics))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,421 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package logexporter pushes the logs, receipts and headers of the executed canonical blocks to external
+// sinks, in order and at least once. Every exporter persists its cursor in the chaindata together with a
+// journal of the recently delivered blocks: when an unwind of the execution - a reorg - drops some of them
+// from the canonical chain, explicit revert events are delivered for them, the most recent first, before
+// the blocks of the new chain.
+package logexporter
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/erigontech/erigon-lib/chain"
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/hexutil"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/consensus"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/eth/stagedsync/stages"
+	"github.com/erigontech/erigon/turbo/jsonrpc/receipts"
+	"github.com/erigontech/erigon/turbo/services"
+)
+
+const (
+	// journalLimit is the number of the delivered blocks remembered per exporter, deeper reorgs can't be reverted
+	journalLimit = 1024
+	// maxBatchBlocks is the max number of blocks, or reverted blocks, delivered in a single batch
+	maxBatchBlocks = 64
+	// pollInterval is how often the exporters check for new blocks without head notifications
+	pollInterval  = 12 * time.Second
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+)
+
+type EventType string
+
+const (
+	BlockEvent  EventType = "block"  // a new canonical block, with the matching logs
+	RevertEvent EventType = "revert" // a previously delivered block which isn't canonical anymore
+)
+
+// Event is a line of the NDJSON delivered to the sinks
+type Event struct {
+	Type        EventType      `json:"type"`
+	Exporter    string         `json:"exporter"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	ParentHash  *common.Hash   `json:"parentHash,omitempty"`
+	Header      *types.Header  `json:"header,omitempty"`
+	Logs        types.Logs     `json:"logs,omitempty"`
+	Receipts    types.Receipts `json:"receipts,omitempty"`
+}
+
+// Chain is the view of the executed canonical chain the exporters read
+type Chain interface {
+	// Head returns the number of the last executed block
+	Head(ctx context.Context, tx kv.TemporalTx) (uint64, error)
+	CanonicalHash(ctx context.Context, tx kv.TemporalTx, blockNum uint64) (common.Hash, bool, error)
+	BlockWithReceipts(ctx context.Context, tx kv.TemporalTx, blockNum uint64, hash common.Hash) (*types.Block, types.Receipts, error)
+}
+
+type executedChain struct {
+	blockReader services.FullBlockReader
+	receipts    *receipts.Generator
+	chainConfig *chain.Config
+}
+
+func NewChain(blockReader services.FullBlockReader, chainConfig *chain.Config, engine consensus.EngineReader) Chain {
+	return &executedChain{blockReader: blockReader, receipts: receipts.NewGenerator(blockReader, engine), chainConfig: chainConfig}
+}
+
+func (c *executedChain) Head(_ context.Context, tx kv.TemporalTx) (uint64, error) {
+	return stages.GetStageProgress(tx, stages.Execution)
+}
+
+func (c *executedChain) CanonicalHash(ctx context.Context, tx kv.TemporalTx, blockNum uint64) (common.Hash, bool, error) {
+	return c.blockReader.CanonicalHash(ctx, tx, blockNum)
+}
+
+func (c *executedChain) BlockWithReceipts(ctx context.Context, tx kv.TemporalTx, blockNum uint64, hash common.Hash) (*types.Block, types.Receipts, error) {
+	block, _, err := c.blockReader.BlockWithSenders(ctx, tx, hash, blockNum)
+	if err != nil {
+		return nil, nil, err
+	}
+	if block == nil {
+		return nil, nil, fmt.Errorf("block %d %x not found", blockNum, hash)
+	}
+	blockReceipts, err := c.receipts.GetReceipts(ctx, c.chainConfig, tx, block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, blockReceipts, nil
+}
+
+type blockRef struct {
+	num  uint64
+	hash common.Hash
+}
+
+type exporter struct {
+	cfg   ExporterConfig
+	sink  Sink
+	addrs map[common.Address]struct{}
+
+	started   bool       // the cursor is persisted
+	next      uint64     // first block not delivered yet
+	delivered []blockRef // recently delivered blocks, oldest first, all below next
+}
+
+// Service runs the configured exporters, each one in its own goroutine
+type Service struct {
+	db        kv.TemporalRwDB
+	chain     Chain
+	exporters []*exporter
+	logger    log.Logger
+}
+
+func New(ctx context.Context, cfg *Config, db kv.TemporalRwDB, chain Chain, logger log.Logger) (*Service, error) {
+	s := &Service{db: db, chain: chain, logger: logger}
+	for _, ec := range cfg.Exporters {
+		sink, err := NewSink(ec.Sink)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("exporter %q: %w", ec.Name, err)
+		}
+		e := &exporter{cfg: ec, sink: sink, addrs: make(map[common.Address]struct{}, len(ec.Addresses))}
+		for _, addr := range ec.Addresses {
+			e.addrs[addr] = struct{}{}
+		}
+		s.exporters = append(s.exporters, e)
+	}
+	if err := db.View(ctx, func(tx kv.Tx) (err error) {
+		for _, e := range s.exporters {
+			if e.next, e.started, e.delivered, err = readCursor(tx, e.cfg.Name); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Run exports until ctx is cancelled. Every notification of newHeads wakes the exporters up.
+func (s *Service) Run(ctx context.Context, newHeads <-chan [][]byte) {
+	defer s.Close()
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	wakeups := make([]chan struct{}, len(s.exporters))
+	for i, e := range s.exporters {
+		wakeups[i] = make(chan struct{}, 1)
+		wg.Add(1)
+		go func(e *exporter, wakeup <-chan struct{}) {
+			defer wg.Done()
+			s.runExporter(ctx, e, wakeup)
+		}(e, wakeups[i])
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-newHeads:
+			if !ok {
+				newHeads = nil // unsubscribed, the exporters keep polling
+				continue
+			}
+			for _, wakeup := range wakeups {
+				select {
+				case wakeup <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}
+}
+
+func (s *Service) runExporter(ctx context.Context, e *exporter, wakeup <-chan struct{}) {
+	s.logger.Info("[logexporter] started", "exporter", e.cfg.Name, "sink", e.cfg.Sink, "next", e.next)
+	wait, retryDelay := time.Duration(0), minRetryDelay
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-wakeup:
+		case <-timer.C:
+		}
+		timer.Stop()
+
+		if err := s.sync(ctx, e); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			s.logger.Warn("[logexporter] export failed, will retry", "exporter", e.cfg.Name, "next", e.next, "in", retryDelay, "err", err)
+			wait, retryDelay = retryDelay, min(2*retryDelay, maxRetryDelay)
+			continue
+		}
+		wait, retryDelay = pollInterval, minRetryDelay
+	}
+}
+
+// sync delivers the pending reverts and then the new blocks, batch by batch, moving the cursor after every
+// acknowledged batch
+func (s *Service) sync(ctx context.Context, e *exporter) error {
+	for {
+		b, err := s.nextBatch(ctx, e)
+		if err != nil {
+			return err
+		}
+		if len(b.events) == 0 && e.started && b.next == e.next {
+			return nil
+		}
+		if len(b.events) > 0 {
+			var data []byte
+			for _, ev := range b.events {
+				line, err := json.Marshal(ev)
+				if err != nil {
+					return err
+				}
+				data = append(append(data, line...), '\n')
+			}
+			if err := e.sink.Deliver(ctx, data); err != nil {
+				return fmt.Errorf("deliver blocks %d-%d: %w", b.events[0].BlockNumber, b.events[len(b.events)-1].BlockNumber, err)
+			}
+		}
+		if err := s.db.Update(ctx, func(tx kv.RwTx) error {
+			return writeCursor(tx, e.cfg.Name, b.next, e.delivered, b.delivered)
+		}); err != nil {
+			return err
+		}
+		e.started, e.next, e.delivered = true, b.next, b.delivered
+		if len(b.events) == 0 {
+			return nil
+		}
+	}
+}
+
+type batch struct {
+	events    []*Event
+	next      uint64
+	delivered []blockRef
+}
+
+func (s *Service) nextBatch(ctx context.Context, e *exporter) (b batch, err error) {
+	tx, err := s.db.BeginTemporalRo(ctx)
+	if err != nil {
+		return b, err
+	}
+	defer tx.Rollback()
+	head, err := s.chain.Head(ctx, tx)
+	if err != nil {
+		return b, err
+	}
+	b.next, b.delivered = e.next, slices.Clone(e.delivered)
+	if !e.started {
+		b.next = head + 1
+		if e.cfg.FromBlock != nil {
+			b.next = *e.cfg.FromBlock
+		}
+	}
+
+	// the delivered blocks unwound from the canonical chain are reverted first, the most recent first
+	for len(b.delivered) > 0 && len(b.events) < maxBatchBlocks {
+		last := b.delivered[len(b.delivered)-1]
+		if last.num <= head {
+			hash, ok, err := s.chain.CanonicalHash(ctx, tx, last.num)
+			if err != nil {
+				return b, err
+			}
+			if ok && hash == last.hash {
+				break
+			}
+		}
+		b.events = append(b.events, &Event{Type: RevertEvent, Exporter: e.cfg.Name, BlockNumber: hexutil.Uint64(last.num), BlockHash: last.hash})
+		b.delivered, b.next = b.delivered[:len(b.delivered)-1], last.num
+		if len(b.delivered) == 0 {
+			s.logger.Warn("[logexporter] reorg deeper than the journal of delivered blocks", "exporter", e.cfg.Name, "block", last.num)
+		}
+	}
+	if len(b.events) > 0 {
+		return b, nil
+	}
+
+	for ; b.next <= head && len(b.events) < maxBatchBlocks; b.next++ {
+		hash, ok, err := s.chain.CanonicalHash(ctx, tx, b.next)
+		if err != nil {
+			return b, err
+		}
+		if !ok {
+			break
+		}
+		block, blockReceipts, err := s.chain.BlockWithReceipts(ctx, tx, b.next, hash)
+		if err != nil {
+			return b, err
+		}
+		b.events = append(b.events, e.blockEvent(block, blockReceipts))
+		b.delivered = append(b.delivered, blockRef{num: b.next, hash: hash})
+	}
+	if len(b.delivered) > journalLimit {
+		b.delivered = b.delivered[len(b.delivered)-journalLimit:]
+	}
+	return b, nil
+}
+
+func (e *exporter) blockEvent(block *types.Block, blockReceipts types.Receipts) *Event {
+	parentHash := block.ParentHash()
+	ev := &Event{Type: BlockEvent, Exporter: e.cfg.Name, BlockNumber: hexutil.Uint64(block.NumberU64()), BlockHash: block.Hash(), ParentHash: &parentHash}
+	if e.cfg.Headers {
+		ev.Header = block.Header()
+	}
+	matchAll := len(e.addrs) == 0 && len(e.cfg.Topics) == 0
+	for _, receipt := range blockReceipts {
+		if receipt == nil {
+			continue
+		}
+		logs := receipt.Logs
+		if !matchAll {
+			logs = logs.Filter(e.addrs, e.cfg.Topics, 0)
+		}
+		ev.Logs = append(ev.Logs, logs...)
+		if e.cfg.Receipts && (matchAll || len(logs) > 0) {
+			ev.Receipts = append(ev.Receipts, receipt)
+		}
+	}
+	return ev
+}
+
+func (s *Service) Close() {
+	for _, e := range s.exporters {
+		if err := e.sink.Close(); err != nil {
+			s.logger.Warn("[logexporter] close sink", "exporter", e.cfg.Name, "err", err)
+		}
+	}
+}
+
+func journalKey(name string, blockNum uint64) []byte {
+	k := make([]byte, len(name)+1+8)
+	copy(k, name)
+	binary.BigEndian.PutUint64(k[len(name)+1:], blockNum)
+	return k
+}
+
+func readCursor(tx kv.Tx, name string) (next uint64, ok bool, delivered []blockRef, err error) {
+	v, err := tx.GetOne(kv.LogExporterCursor, []byte(name))
+	if err != nil || v == nil {
+		return 0, false, nil, err
+	}
+	if len(v) != 8 {
+		return 0, false, nil, fmt.Errorf("log exporter %q: invalid cursor %x", name, v)
+	}
+	next = binary.BigEndian.Uint64(v)
+
+	it, err := tx.Prefix(kv.LogExporterCursor, append([]byte(name), 0))
+	if err != nil {
+		return 0, false, nil, err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		k, v, err := it.Next()
+		if err != nil {
+			return 0, false, nil, err
+		}
+		if len(k) != len(name)+1+8 || len(v) != len(common.Hash{}) {
+			return 0, false, nil, errors.New("log exporter: invalid journal entry")
+		}
+		delivered = append(delivered, blockRef{num: binary.BigEndian.Uint64(k[len(name)+1:]), hash: common.BytesToHash(v)})
+	}
+	return next, true, delivered, nil
+}
+
+func writeCursor(tx kv.RwTx, name string, next uint64, prev, delivered []blockRef) error {
+	hashes, prevHashes := make(map[uint64]common.Hash, len(delivered)), make(map[uint64]common.Hash, len(prev))
+	for _, r := range delivered {
+		hashes[r.num] = r.hash
+	}
+	for _, r := range prev {
+		prevHashes[r.num] = r.hash
+		if _, ok := hashes[r.num]; ok {
+			continue
+		}
+		if err := tx.Delete(kv.LogExporterCursor, journalKey(name, r.num)); err != nil {
+			return err
+		}
+	}
+	for _, r := range delivered {
+		if h, ok := prevHashes[r.num]; ok && h == r.hash {
+			continue
+		}
+		if err := tx.Put(kv.LogExporterCursor, journalKey(name, r.num), r.hash[:]); err != nil {
+			return err
+		}
+	}
+	var v [8]byte
+	binary.BigEndian.PutUint64(v[:], next)
+	return tx.Put(kv.LogExporterCursor, []byte(name), v[:])
+}
@@ -0,0 +1,262 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package logexporter
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/datadir"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/kv/temporal/temporaltest"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/core/types"
+)
+
+var (
+	addrA  = common.Address{0xa}
+	addrB  = common.Address{0xb}
+	topicT = common.Hash{0x1}
+)
+
+type testChain struct {
+	head      uint64
+	canonical map[uint64]*types.Block
+	receipts  map[common.Hash]types.Receipts
+}
+
+func newTestChain() *testChain {
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})
+	return &testChain{canonical: map[uint64]*types.Block{0: genesis}, receipts: map[common.Hash]types.Receipts{}}
+}
+
+// build makes count blocks on top of the canonical block parentNum the new canonical chain
+func (c *testChain) build(parentNum uint64, count int, fork byte) {
+	for i := parentNum + 1; i <= c.head; i++ {
+		delete(c.canonical, i)
+	}
+	parent := c.canonical[parentNum]
+	for i := 0; i < count; i++ {
+		block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).Add(parent.Number(), common.Big1), ParentHash: parent.Hash(), Extra: []byte{fork}})
+		c.receipts[block.Hash()] = types.Receipts{{
+			Status:      types.ReceiptStatusSuccessful,
+			TxHash:      common.Hash{fork, byte(block.NumberU64())},
+			BlockNumber: block.Number(),
+			Logs: types.Logs{
+				{Address: addrA, Topics: []common.Hash{topicT}, BlockNumber: block.NumberU64(), BlockHash: block.Hash()},
+				{Address: addrB, Topics: []common.Hash{topicT}, BlockNumber: block.NumberU64(), BlockHash: block.Hash(), Index: 1},
+			},
+		}}
+		c.canonical[block.NumberU64()] = block
+		parent = block
+	}
+	c.head = parent.NumberU64()
+}
+
+func (c *testChain) Head(context.Context, kv.TemporalTx) (uint64, error) { return c.head, nil }
+
+func (c *testChain) CanonicalHash(_ context.Context, _ kv.TemporalTx, blockNum uint64) (common.Hash, bool, error) {
+	block, ok := c.canonical[blockNum]
+	if !ok {
+		return common.Hash{}, false, nil
+	}
+	return block.Hash(), true, nil
+}
+
+func (c *testChain) BlockWithReceipts(_ context.Context, _ kv.TemporalTx, blockNum uint64, hash common.Hash) (*types.Block, types.Receipts, error) {
+	return c.canonical[blockNum], c.receipts[hash], nil
+}
+
+// testWebhook collects the delivered events, or fails the deliveries
+type testWebhook struct {
+	lock   sync.Mutex
+	events []Event
+	fail   bool
+}
+
+func (h *testWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var ev Event
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		h.events = append(h.events, ev)
+	}
+}
+
+func (h *testWebhook) take() []Event {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	events := h.events
+	h.events = nil
+	return events
+}
+
+func requireEvents(t *testing.T, c *testChain, events []Event, expected ...any) {
+	t.Helper()
+	require.Len(t, events, len(expected)/2)
+	for i, ev := range events {
+		typ, num := expected[2*i].(EventType), expected[2*i+1].(int)
+		require.Equal(t, typ, ev.Type, "event %d", i)
+		require.Equal(t, uint64(num), uint64(ev.BlockNumber), "event %d", i)
+		if typ == BlockEvent {
+			require.Equal(t, c.canonical[uint64(num)].Hash(), ev.BlockHash, "event %d", i)
+		}
+	}
+}
+
+func TestExporter(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New()
+	db, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	hook := &testWebhook{}
+	srv := httptest.NewServer(hook)
+	defer srv.Close()
+
+	c := newTestChain()
+	c.build(0, 5, 0)
+	fromBlock := uint64(1)
+	cfg := &Config{Exporters: []ExporterConfig{{Name: "indexer", Sink: srv.URL, Addresses: []common.Address{addrA}, Receipts: true, FromBlock: &fromBlock}}}
+	s, err := New(ctx, cfg, db, c, logger)
+	require.NoError(t, err)
+	e := s.exporters[0]
+
+	require.NoError(t, s.sync(ctx, e))
+	events := hook.take()
+	requireEvents(t, c, events, BlockEvent, 1, BlockEvent, 2, BlockEvent, 3, BlockEvent, 4, BlockEvent, 5)
+	for _, ev := range events {
+		require.Len(t, ev.Logs, 1)
+		require.Equal(t, addrA, ev.Logs[0].Address)
+		require.Len(t, ev.Receipts, 1)
+		require.Equal(t, c.canonical[uint64(ev.BlockNumber)].ParentHash(), *ev.ParentHash)
+	}
+	reorged := []common.Hash{c.canonical[4].Hash(), c.canonical[5].Hash()}
+
+	// reorg: the unwound blocks are reverted, the most recent first, then the new chain is delivered
+	c.build(3, 3, 1)
+	require.NoError(t, s.sync(ctx, e))
+	events = hook.take()
+	requireEvents(t, c, events, RevertEvent, 5, RevertEvent, 4, BlockEvent, 4, BlockEvent, 5, BlockEvent, 6)
+	require.Equal(t, reorged[1], events[0].BlockHash)
+	require.Equal(t, reorged[0], events[1].BlockHash)
+
+	// a failed delivery doesn't move the cursor
+	c.build(6, 1, 1)
+	hook.fail = true
+	require.Error(t, s.sync(ctx, e))
+	hook.fail = false
+	require.NoError(t, s.sync(ctx, e))
+	requireEvents(t, c, hook.take(), BlockEvent, 7)
+
+	// the cursor survives restarts
+	s, err = New(ctx, cfg, db, c, logger)
+	require.NoError(t, err)
+	e = s.exporters[0]
+	require.NoError(t, s.sync(ctx, e))
+	require.Empty(t, hook.take())
+	c.build(7, 1, 1)
+	require.NoError(t, s.sync(ctx, e))
+	requireEvents(t, c, hook.take(), BlockEvent, 8)
+
+	// unwind of the execution without new blocks
+	c.head = 6
+	delete(c.canonical, 7)
+	delete(c.canonical, 8)
+	require.NoError(t, s.sync(ctx, e))
+	requireEvents(t, c, hook.take(), RevertEvent, 8, RevertEvent, 7)
+	require.Equal(t, uint64(7), e.next)
+}
+
+func TestExporterStartsAtHead(t *testing.T) {
+	ctx := context.Background()
+	db, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	sinkPath := filepath.Join(t.TempDir(), "events.ndjson")
+
+	c := newTestChain()
+	c.build(0, 3, 0)
+	cfg := &Config{Exporters: []ExporterConfig{{Name: "all", Sink: "file://" + sinkPath, Headers: true}}}
+	s, err := New(ctx, cfg, db, c, log.New())
+	require.NoError(t, err)
+	defer s.Close()
+	e := s.exporters[0]
+
+	require.NoError(t, s.sync(ctx, e))
+	require.Equal(t, uint64(4), e.next)
+	c.build(3, 2, 0)
+	require.NoError(t, s.sync(ctx, e))
+
+	f, err := os.Open(sinkPath)
+	require.NoError(t, err)
+	defer f.Close()
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var ev Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &ev))
+		events = append(events, ev)
+	}
+	requireEvents(t, c, events, BlockEvent, 4, BlockEvent, 5)
+	require.Len(t, events[0].Logs, 2)
+	require.Empty(t, events[0].Receipts)
+	require.Equal(t, c.canonical[4].Hash(), events[0].Header.Hash())
+}
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(content string) string {
+		path := filepath.Join(dir, "exporters.json")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return path
+	}
+
+	cfg, err := ReadConfig(write(`{"exporters": [{"name": "indexer", "sink": "unix:///tmp/erigon.sock", "topics": [null, ["0x0000000000000000000000000000000000000000000000000000000000000001"]], "fromBlock": 10}]}`))
+	require.NoError(t, err)
+	require.Len(t, cfg.Exporters, 1)
+	require.Len(t, cfg.Exporters[0].Topics, 2)
+	require.Empty(t, cfg.Exporters[0].Topics[0])
+	require.Equal(t, uint64(10), *cfg.Exporters[0].FromBlock)
+
+	_, err = ReadConfig(write(`{"exporters": [{"name": "a", "sink": "file:///tmp/a"}, {"name": "a", "sink": "file:///tmp/b"}]}`))
+	require.ErrorContains(t, err, "duplicate")
+	_, err = ReadConfig(write(`{"exporters": [{"name": "a/b", "sink": "file:///tmp/a"}]}`))
+	require.ErrorContains(t, err, "invalid exporter name")
+	_, err = ReadConfig(write(`{"exporters": [{"name": "a", "sink": "file:///tmp/a", "adresses": []}]}`))
+	require.ErrorContains(t, err, "unknown field")
+
+	_, err = NewSink("ftp://host/path")
+	require.ErrorContains(t, err, "unsupported scheme")
+}
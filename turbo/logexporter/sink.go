@@ -0,0 +1,169 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package logexporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const sinkTimeout = 30 * time.Second
+
+// Sink delivers batches of events, encoded as NDJSON. The batch is acknowledged - and the cursor of the
+// exporter moves past it - only when Deliver returns nil, otherwise it's delivered again later.
+type Sink interface {
+	Deliver(ctx context.Context, batch []byte) error
+	Close() error
+}
+
+// NewSink creates the sink for the target of ExporterConfig.Sink
+func NewSink(target string) (Sink, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("sink %q: %w", target, err)
+	}
+	path := u.Path
+	if path == "" {
+		path = u.Opaque // file:events.ndjson
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &webhookSink{url: target, client: &http.Client{Timeout: sinkTimeout}}, nil
+	case "file":
+		if path == "" {
+			return nil, fmt.Errorf("sink %q: no file path", target)
+		}
+		return &fileSink{path: path}, nil
+	case "unix":
+		if path == "" {
+			return nil, fmt.Errorf("sink %q: no socket path", target)
+		}
+		return &unixSink{path: path}, nil
+	default:
+		return nil, fmt.Errorf("sink %q: unsupported scheme %q, expected http, https, file or unix", target, u.Scheme)
+	}
+}
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func (s *webhookSink) Deliver(ctx context.Context, batch []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(batch))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook %s: %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+type fileSink struct {
+	path string
+	lock sync.Mutex
+	f    *os.File
+}
+
+func (s *fileSink) Deliver(_ context.Context, batch []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.f == nil {
+		f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		s.f = f
+	}
+	if _, err := s.f.Write(batch); err != nil {
+		return err
+	}
+	return s.f.Sync()
+}
+
+func (s *fileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// unixSink streams the events to a local Unix socket, reconnecting after failures. A batch written just
+// before a failure may be written again after the reconnect.
+type unixSink struct {
+	path string
+	lock sync.Mutex
+	conn net.Conn
+}
+
+func (s *unixSink) Deliver(ctx context.Context, batch []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "unix", s.path)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	if err := s.conn.SetWriteDeadline(time.Now().Add(sinkTimeout)); err != nil {
+		return err
+	}
+	if _, err := s.conn.Write(batch); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (s *unixSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -20,10 +20,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"reflect"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -34,6 +34,7 @@ import (
 
 	libcommon "github.com/erigontech/erigon-lib/common"
 	"github.com/erigontech/erigon-lib/common/concurrent"
+	"github.com/erigontech/erigon-lib/common/length"
 	"github.com/erigontech/erigon-lib/gointerfaces"
 	"github.com/erigontech/erigon-lib/gointerfaces/grpcutil"
 	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
@@ -53,6 +54,7 @@ type Filters struct {
 	mu sync.RWMutex
 
 	pendingBlock *types.Block
+	pendingLogs  types.Logs
 
 	headsSubs        *concurrent.SyncMap[HeadsSubID, Sub[*types.Header]]
 	pendingLogsSubs  *concurrent.SyncMap[PendingLogsSubID, Sub[types.Logs]]
@@ -72,8 +74,9 @@ type Filters struct {
 
 // New creates a new Filters instance, initializes it, and starts subscription goroutines for Ethereum events.
 // It requires a context, Ethereum backend, transaction pool client, mining client, snapshot callback function,
-// and a logger for logging events.
-func New(ctx context.Context, config FiltersConfig, ethBackend ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient, onNewSnapshot func(), logger log.Logger) *Filters {
+// and a logger for logging events. The pending block and pending logs come with the Ethereum backend events,
+// the mining client streams aren't subscribed to: they carry the same pending block.
+func New(ctx context.Context, config FiltersConfig, ethBackend ApiBackend, txPool txpool.TxpoolClient, _ txpool.MiningClient, onNewSnapshot func(), logger log.Logger) *Filters {
 	logger.Info("rpc filters: subscribing to Erigon events")
 
 	ff := &Filters{
@@ -172,57 +175,6 @@ func New(ctx context.Context, config FiltersConfig, ethBackend ApiBackend, txPoo
 				}
 			}
 		}()
-
-		if !reflect.ValueOf(mining).IsNil() { //https://groups.google.com/g/golang-nuts/c/wnH302gBa4I
-			go func() {
-				activeSubscriptionsLogsClientGauge.With(prometheus.Labels{clientLabelName: "txPool_PendingBlock"}).Inc()
-				for {
-					select {
-					case <-ctx.Done():
-						activeSubscriptionsLogsClientGauge.With(prometheus.Labels{clientLabelName: "txPool_PendingBlock"}).Dec()
-						return
-					default:
-					}
-					if err := ff.subscribeToPendingBlocks(ctx, mining); err != nil {
-						select {
-						case <-ctx.Done():
-							activeSubscriptionsLogsClientGauge.With(prometheus.Labels{clientLabelName: "txPool_PendingBlock"}).Dec()
-							return
-						default:
-						}
-						if grpcutil.IsEndOfStream(err) || grpcutil.IsRetryLater(err) {
-							time.Sleep(3 * time.Second)
-							continue
-						}
-						logger.Warn("rpc filters: error subscribing to pending blocks", "err", err)
-					}
-				}
-			}()
-			go func() {
-				activeSubscriptionsLogsClientGauge.With(prometheus.Labels{clientLabelName: "txPool_PendingLogs"}).Inc()
-				for {
-					select {
-					case <-ctx.Done():
-						activeSubscriptionsLogsClientGauge.With(prometheus.Labels{clientLabelName: "txPool_PendingLogs"}).Dec()
-						return
-					default:
-					}
-					if err := ff.subscribeToPendingLogs(ctx, mining); err != nil {
-						select {
-						case <-ctx.Done():
-							activeSubscriptionsLogsClientGauge.With(prometheus.Labels{clientLabelName: "txPool_PendingLogs"}).Dec()
-							return
-						default:
-						}
-						if grpcutil.IsEndOfStream(err) || grpcutil.IsRetryLater(err) {
-							time.Sleep(3 * time.Second)
-							continue
-						}
-						logger.Warn("rpc filters: error subscribing to pending logs", "err", err)
-					}
-				}
-			}()
-		}
 	}
 
 	return ff
@@ -257,94 +209,110 @@ func (ff *Filters) subscribeToPendingTransactions(ctx context.Context, txPool tx
 	return nil
 }
 
-// subscribeToPendingBlocks subscribes to pending blocks using the given mining client.
-// It listens for new pending blocks and processes them as they arrive.
-func (ff *Filters) subscribeToPendingBlocks(ctx context.Context, mining txpool.MiningClient) error {
-	subscription, err := mining.OnPendingBlock(ctx, &txpool.OnPendingBlockRequest{}, grpc.WaitForReady(true))
-	if err != nil {
-		return err
-	}
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
-
-		event, err := subscription.Recv()
-		if errors.Is(err, io.EOF) {
-			ff.logger.Debug("rpcdaemon: the subscription to pending blocks channel was closed")
-			break
-		}
-		if err != nil {
-			return err
-		}
-
-		ff.HandlePendingBlock(event)
-	}
-	return nil
-}
-
 // HandlePendingBlock handles a new pending block received from the mining client.
 // It updates the internal state and notifies subscribers about the new block.
 func (ff *Filters) HandlePendingBlock(reply *txpool.OnPendingBlockReply) {
-	b := &types.Block{}
 	if reply == nil || len(reply.RplBlock) == 0 {
 		return
 	}
-	if err := rlp.Decode(bytes.NewReader(reply.RplBlock), b); err != nil {
+	if err := ff.onPendingBlockRlp(reply.RplBlock); err != nil {
 		ff.logger.Warn("OnNewPendingBlock rpc filters, unprocessable payload", "err", err)
 	}
+}
+
+// onPendingBlockRlp replaces the pending block with the given one and notifies subscribers about it.
+func (ff *Filters) onPendingBlockRlp(payload []byte) error {
+	b := &types.Block{}
+	if err := rlp.Decode(bytes.NewReader(payload), b); err != nil {
+		return fmt.Errorf("unprocessable payload: %w", err)
+	}
 
 	ff.mu.Lock()
 	defer ff.mu.Unlock()
 	ff.pendingBlock = b
 
-	ff.pendingBlockSubs.Range(func(k PendingBlockSubID, v Sub[*types.Block]) error {
+	return ff.pendingBlockSubs.Range(func(k PendingBlockSubID, v Sub[*types.Block]) error {
 		v.Send(b)
 		return nil
 	})
 }
 
-// subscribeToPendingLogs subscribes to pending logs using the given mining client.
-// It listens for new pending logs and processes them as they arrive.
-func (ff *Filters) subscribeToPendingLogs(ctx context.Context, mining txpool.MiningClient) error {
-	subscription, err := mining.OnPendingLogs(ctx, &txpool.OnPendingLogsRequest{}, grpc.WaitForReady(true))
-	if err != nil {
-		return err
+// HandlePendingLogs handles new pending logs received from the mining client, RLP encoded.
+// It updates the internal state and notifies subscribers about the new logs.
+func (ff *Filters) HandlePendingLogs(reply *txpool.OnPendingLogsReply) {
+	if reply == nil || len(reply.RplLogs) == 0 {
+		return
 	}
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-		default:
-		}
-		event, err := subscription.Recv()
-		if errors.Is(err, io.EOF) {
-			ff.logger.Debug("rpcdaemon: the subscription to pending logs channel was closed")
-			break
-		}
-		if err != nil {
-			return err
-		}
+	logs := types.Logs{}
+	if err := rlp.Decode(bytes.NewReader(reply.RplLogs), &logs); err != nil {
+		ff.logger.Warn("OnNewPendingLogs rpc filters, unprocessable payload", "err", err)
+		return
+	}
+	ff.replacePendingLogs(logs)
+}
 
-		ff.HandlePendingLogs(event)
+// onPendingLogsJson handles all the logs of a rebuilt pending block, JSON encoded.
+func (ff *Filters) onPendingLogsJson(payload []byte) error {
+	var logs types.Logs
+	if err := json.Unmarshal(payload, &logs); err != nil {
+		return fmt.Errorf("unprocessable payload: %w", err)
 	}
+	ff.replacePendingLogs(logs)
 	return nil
 }
 
-// HandlePendingLogs handles new pending logs received from the mining client.
-// It updates the internal state and notifies subscribers about the new logs.
-func (ff *Filters) HandlePendingLogs(reply *txpool.OnPendingLogsReply) {
-	if len(reply.RplLogs) == 0 {
-		return
+// pendingLogKey identifies a pending log across rebuilds of the pending block
+type pendingLogKey struct {
+	blockNumber uint64
+	txHash      libcommon.Hash
+	index       uint
+	address     libcommon.Address
+	topics      string
+	data        string
+}
+
+func newPendingLogKey(l *types.Log) pendingLogKey {
+	topics := make([]byte, 0, len(l.Topics)*length.Hash)
+	for _, t := range l.Topics {
+		topics = append(topics, t[:]...)
 	}
-	l := []*types.Log{}
-	if err := rlp.Decode(bytes.NewReader(reply.RplLogs), &l); err != nil {
-		ff.logger.Warn("OnNewPendingLogs rpc filters, unprocessable payload", "err", err)
+	return pendingLogKey{blockNumber: l.BlockNumber, txHash: l.TxHash, index: l.Index, address: l.Address, topics: string(topics), data: string(l.Data)}
+}
+
+// replacePendingLogs replaces the logs of the pending block with the logs of its latest rebuild. Subscribers
+// get the difference: the logs which are no longer pending, with Removed set, followed by the new ones.
+// The logs which are still pending aren't sent again.
+func (ff *Filters) replacePendingLogs(logs types.Logs) {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+
+	current := make(map[pendingLogKey]struct{}, len(logs))
+	for _, l := range logs {
+		current[newPendingLogKey(l)] = struct{}{}
+	}
+	previous := make(map[pendingLogKey]struct{}, len(ff.pendingLogs))
+	var diff types.Logs
+	for _, l := range ff.pendingLogs {
+		k := newPendingLogKey(l)
+		previous[k] = struct{}{}
+		if _, ok := current[k]; !ok {
+			removed := *l
+			removed.Removed = true
+			diff = append(diff, &removed)
+		}
+	}
+	for _, l := range logs {
+		if _, ok := previous[newPendingLogKey(l)]; !ok {
+			diff = append(diff, l)
+		}
+	}
+	ff.pendingLogs = logs
+
+	if len(diff) == 0 {
+		return
 	}
 	ff.pendingLogsSubs.Range(func(k PendingLogsSubID, v Sub[types.Logs]) error {
-		v.Send(l)
+		v.Send(diff)
 		return nil
 	})
 }
@@ -578,38 +546,17 @@ func (ff *Filters) onNewEvent(event *remote.SubscribeReply) error {
 	}
 }
 
-// TODO: implement?
-// onPendingLog handles a new pending log event from the remote.
+// onPendingLog handles a new pending logs event from the remote: all the logs of the rebuilt pending block.
 func (ff *Filters) onPendingLog(event *remote.SubscribeReply) error {
-	//	payload := event.Data
-	//	var logs types.Logs
-	//	err := rlp.Decode(bytes.NewReader(payload), &logs)
-	//	if err != nil {
-	//		// ignoring what we can't unmarshal
-	//		log.Warn("OnNewEvent rpc filters (pending logs), unprocessable payload", "err", err)
-	//	} else {
-	//		for _, v := range ff.pendingLogsSubs {
-	//			v <- logs
-	//		}
-	//	}
-	return nil
+	return ff.onPendingLogsJson(event.Data)
 }
 
-// TODO: implement?
 // onPendingBlock handles a new pending block event from the remote.
 func (ff *Filters) onPendingBlock(event *remote.SubscribeReply) error {
-	//	payload := event.Data
-	//	var block types.Block
-	//	err := rlp.Decode(bytes.NewReader(payload), &block)
-	//	if err != nil {
-	//		// ignoring what we can't unmarshal
-	//		log.Warn("OnNewEvent rpc filters (pending txs), unprocessable payload", "err", err)
-	//	} else {
-	//		for _, v := range ff.pendingBlockSubs {
-	//			v <- &block
-	//		}
-	//	}
-	return nil
+	if len(event.Data) == 0 {
+		return nil
+	}
+	return ff.onPendingBlockRlp(event.Data)
 }
 
 // onNewHeader handles a new block header event from the remote and updates the internal state.
@@ -18,9 +18,13 @@ package rpchelper
 
 import (
 	"context"
+	"encoding/json"
+	"math/big"
 	"testing"
+	"time"
 
 	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
 
 	"github.com/erigontech/erigon/core/types"
 
@@ -29,6 +33,7 @@ import (
 	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
 
 	types2 "github.com/erigontech/erigon-lib/gointerfaces/typesproto"
+	"github.com/erigontech/erigon-lib/rlp"
 
 	"github.com/erigontech/erigon-lib/log/v3"
 	"github.com/erigontech/erigon/eth/filters"
@@ -507,3 +512,62 @@ func TestFilters_AddPendingTxs(t *testing.T) {
 		})
 	}
 }
+
+func TestFilters_OnPendingEvents(t *testing.T) {
+	f := New(context.TODO(), DefaultFiltersConfig, nil, nil, nil, func() {}, log.New())
+	blocks, blockID := f.SubscribePendingBlock(4)
+	defer f.UnsubscribePendingBlock(blockID)
+	logs, logsID := f.SubscribePendingLogs(4)
+	defer f.UnsubscribePendingLogs(logsID)
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(10)})
+	blockRlp, err := rlp.EncodeToBytes(block)
+	require.NoError(t, err)
+	f.OnNewEvent(&remote.SubscribeReply{Type: remote.Event_PENDING_BLOCK, Data: blockRlp})
+	require.Equal(t, block.Hash(), (<-blocks).Hash())
+	require.Equal(t, block.Hash(), f.LastPendingBlock().Hash())
+
+	txn1, txn2, txn3 := libcommon.Hash{1}, libcommon.Hash{2}, libcommon.Hash{3}
+	sendLogs := func(l types.Logs) {
+		data, err := json.Marshal(l)
+		require.NoError(t, err)
+		f.OnNewEvent(&remote.SubscribeReply{Type: remote.Event_PENDING_LOGS, Data: data})
+	}
+	type sent struct {
+		txHash  libcommon.Hash
+		removed bool
+	}
+	received := func() []sent {
+		var res []sent
+		select {
+		case l := <-logs:
+			for _, lg := range l {
+				res = append(res, sent{lg.TxHash, lg.Removed})
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for pending logs")
+		}
+		return res
+	}
+	newLog := func(txHash libcommon.Hash, index uint) *types.Log {
+		return &types.Log{Address: address1, Topics: []libcommon.Hash{topic1}, Data: []byte{1}, BlockNumber: 10, TxHash: txHash, Index: index}
+	}
+
+	sendLogs(types.Logs{newLog(txn1, 0), newLog(txn2, 1)})
+	require.Equal(t, []sent{{txn1, false}, {txn2, false}}, received())
+
+	// rebuilt with the same logs: nothing to send
+	sendLogs(types.Logs{newLog(txn1, 0), newLog(txn2, 1)})
+	// rebuilt without txn2 and with txn3
+	sendLogs(types.Logs{newLog(txn1, 0), newLog(txn3, 1)})
+	require.Equal(t, []sent{{txn2, true}, {txn3, false}}, received())
+
+	// emptied, e.g. after its txns were included in a block
+	sendLogs(types.Logs{})
+	require.Equal(t, []sent{{txn1, true}, {txn3, true}}, received())
+	select {
+	case l := <-logs:
+		t.Fatalf("unexpected pending logs %v", l)
+	default:
+	}
+}
@@ -31,8 +31,6 @@ type RpcEventType uint64
 
 type NewSnapshotSubscription func() error
 type HeaderSubscription func(headerRLP []byte) error
-type PendingLogsSubscription func(types.Logs) error
-type PendingBlockSubscription func(*types.Block) error
 type PendingTxsSubscription func([]types.Transaction) error
 type LogsSubscription func([]*remote.SubscribeLogsReply) error
 
@@ -41,8 +39,8 @@ type Events struct {
 	id                        int
 	headerSubscriptions       map[int]chan [][]byte
 	newSnapshotSubscription   map[int]chan struct{}
-	pendingLogsSubscriptions  map[int]PendingLogsSubscription
-	pendingBlockSubscriptions map[int]PendingBlockSubscription
+	pendingLogsSubscriptions  map[int]chan types.Logs
+	pendingBlockSubscriptions map[int]chan *types.Block
 	pendingTxsSubscriptions   map[int]PendingTxsSubscription
 	logsSubscriptions         map[int]chan []*remote.SubscribeLogsReply
 	hasLogSubscriptions       bool
@@ -52,8 +50,8 @@ type Events struct {
 func NewEvents() *Events {
 	return &Events{
 		headerSubscriptions:       map[int]chan [][]byte{},
-		pendingLogsSubscriptions:  map[int]PendingLogsSubscription{},
-		pendingBlockSubscriptions: map[int]PendingBlockSubscription{},
+		pendingLogsSubscriptions:  map[int]chan types.Logs{},
+		pendingBlockSubscriptions: map[int]chan *types.Block{},
 		pendingTxsSubscriptions:   map[int]PendingTxsSubscription{},
 		logsSubscriptions:         map[int]chan []*remote.SubscribeLogsReply{},
 		newSnapshotSubscription:   map[int]chan struct{}{},
@@ -111,16 +109,37 @@ func (e *Events) HasLogSubsriptions() bool {
 	return e.hasLogSubscriptions
 }
 
-func (e *Events) AddPendingLogsSubscription(s PendingLogsSubscription) {
+// AddPendingLogsSubscription subscribes to the logs of the pending block: every notification holds all the
+// logs of the latest rebuild of the pending block, replacing the previous ones
+func (e *Events) AddPendingLogsSubscription() (chan types.Logs, func()) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
-	e.pendingLogsSubscriptions[len(e.pendingLogsSubscriptions)] = s
+	ch := make(chan types.Logs, 8)
+	e.id++
+	id := e.id
+	e.pendingLogsSubscriptions[id] = ch
+	return ch, func() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		delete(e.pendingLogsSubscriptions, id)
+		close(ch)
+	}
 }
 
-func (e *Events) AddPendingBlockSubscription(s PendingBlockSubscription) {
+// AddPendingBlockSubscription subscribes to the pending block, notified every time it is rebuilt
+func (e *Events) AddPendingBlockSubscription() (chan *types.Block, func()) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
-	e.pendingBlockSubscriptions[len(e.pendingBlockSubscriptions)] = s
+	ch := make(chan *types.Block, 8)
+	e.id++
+	id := e.id
+	e.pendingBlockSubscriptions[id] = ch
+	return ch, func() {
+		e.lock.Lock()
+		defer e.lock.Unlock()
+		delete(e.pendingBlockSubscriptions, id)
+		close(ch)
+	}
 }
 
 func (e *Events) OnNewSnapshot() {
@@ -142,10 +161,16 @@ func (e *Events) OnNewHeader(newHeadersRlp [][]byte) {
 func (e *Events) OnNewPendingLogs(logs types.Logs) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
-	for i, sub := range e.pendingLogsSubscriptions {
-		if err := sub(logs); err != nil {
-			delete(e.pendingLogsSubscriptions, i)
-		}
+	for _, ch := range e.pendingLogsSubscriptions {
+		common.PrioritizedSend(ch, logs)
+	}
+}
+
+func (e *Events) OnNewPendingBlock(block *types.Block) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, ch := range e.pendingBlockSubscriptions {
+		common.PrioritizedSend(ch, block)
 	}
 }
 
@@ -0,0 +1,121 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package e2store implements the e2store framing of the .era and .era1 archives: a file is a sequence of
+// type-length-value entries with an 8 bytes header - 2 bytes of type, 4 bytes of little-endian length and
+// 2 reserved zero bytes.
+//
+// See https://github.com/status-im/nimbus-eth2/blob/stable/docs/e2store.md
+package e2store
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const HeaderSize = 8
+
+// Types shared by all e2store files
+const (
+	TypeVersion uint16 = 0x3265 // "e2"
+	TypeEmpty   uint16 = 0x0000
+)
+
+// ValueSizeLimit protects the readers from allocating for corrupted lengths
+var ValueSizeLimit uint32 = 1 << 30
+
+type Entry struct {
+	Type  uint16
+	Value []byte
+}
+
+// Writer appends entries to the underlying writer
+type Writer struct {
+	w io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes the entry and returns the number of written bytes, header included
+func (w *Writer) Write(typ uint16, value []byte) (int, error) {
+	var header [HeaderSize]byte
+	binary.LittleEndian.PutUint16(header[:2], typ)
+	binary.LittleEndian.PutUint32(header[2:6], uint32(len(value)))
+	n, err := w.w.Write(header[:])
+	if err != nil {
+		return n, err
+	}
+	m, err := w.w.Write(value)
+	return n + m, err
+}
+
+// Reader reads the entries at the given offsets
+type Reader struct {
+	r io.ReaderAt
+}
+
+func NewReader(r io.ReaderAt) *Reader {
+	return &Reader{r: r}
+}
+
+// ReadHeaderAt reads the type and the value length of the entry at off
+func (r *Reader) ReadHeaderAt(off int64) (typ uint16, length uint32, err error) {
+	var header [HeaderSize]byte
+	if _, err := r.r.ReadAt(header[:], off); err != nil {
+		return 0, 0, err
+	}
+	if header[6] != 0 || header[7] != 0 {
+		return 0, 0, fmt.Errorf("e2store: reserved bytes are not zero at offset %d", off)
+	}
+	return binary.LittleEndian.Uint16(header[:2]), binary.LittleEndian.Uint32(header[2:6]), nil
+}
+
+// ReadAt reads the entry at off and returns it with its size, header included
+func (r *Reader) ReadAt(off int64) (*Entry, int64, error) {
+	typ, length, err := r.ReadHeaderAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if length > ValueSizeLimit {
+		return nil, 0, fmt.Errorf("e2store: entry of %d bytes at offset %d exceeds the limit", length, off)
+	}
+	e := &Entry{Type: typ, Value: make([]byte, length)}
+	if length > 0 {
+		if _, err := r.r.ReadAt(e.Value, off+HeaderSize); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, 0, err
+		}
+	}
+	return e, HeaderSize + int64(length), nil
+}
+
+// ReadValueAt reads the entry at off and fails if it's not of the expected type
+func (r *Reader) ReadValueAt(off int64, typ uint16) ([]byte, error) {
+	e, _, err := r.ReadAt(off)
+	if err != nil {
+		return nil, err
+	}
+	if e.Type != typ {
+		return nil, fmt.Errorf("e2store: expected entry type %#04x at offset %d, got %#04x", typ, off, e.Type)
+	}
+	return e.Value, nil
+}
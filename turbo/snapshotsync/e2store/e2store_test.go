@@ -0,0 +1,61 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package e2store
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWrite(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	n, err := w.Write(TypeVersion, nil)
+	require.NoError(t, err)
+	require.Equal(t, HeaderSize, n)
+	n, err = w.Write(0x03, []byte("value"))
+	require.NoError(t, err)
+	require.Equal(t, HeaderSize+5, n)
+	require.Equal(t, []byte{0x65, 0x32, 0, 0, 0, 0, 0, 0}, buf.Bytes()[:HeaderSize])
+
+	r := NewReader(bytes.NewReader(buf.Bytes()))
+	e, size, err := r.ReadAt(0)
+	require.NoError(t, err)
+	require.Equal(t, TypeVersion, e.Type)
+	require.Empty(t, e.Value)
+	require.Equal(t, int64(HeaderSize), size)
+	v, err := r.ReadValueAt(size, 0x03)
+	require.NoError(t, err)
+	require.Equal(t, []byte("value"), v)
+	_, err = r.ReadValueAt(size, 0x04)
+	require.ErrorContains(t, err, "expected entry type")
+	_, _, err = r.ReadAt(int64(buf.Len()))
+	require.ErrorIs(t, err, io.EOF)
+
+	// truncated value
+	_, _, err = NewReader(bytes.NewReader(buf.Bytes()[:buf.Len()-1])).ReadAt(size)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	// reserved bytes
+	corrupted := bytes.Clone(buf.Bytes())
+	corrupted[7] = 1
+	_, _, err = NewReader(bytes.NewReader(corrupted)).ReadAt(0)
+	require.ErrorContains(t, err, "reserved")
+}
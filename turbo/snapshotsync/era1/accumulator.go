@@ -0,0 +1,76 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package era1
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon/cl/merkle_tree"
+)
+
+// ComputeAccumulator returns the root of the epoch accumulator of the blocks: the SSZ hash tree root of
+// List[HeaderRecord, MaxEra1Size], where HeaderRecord is {block_hash: Bytes32, total_difficulty: uint256}.
+func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, error) {
+	if len(hashes) != len(tds) {
+		return common.Hash{}, fmt.Errorf("accumulator: %d hashes, %d total difficulties", len(hashes), len(tds))
+	}
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, fmt.Errorf("accumulator: %d blocks exceed the limit %d", len(hashes), MaxEra1Size)
+	}
+	leaves := make([][32]byte, len(hashes), MaxEra1Size)
+	var record [64]byte
+	for i, hash := range hashes {
+		tdBytes, err := tdLittleEndian(tds[i])
+		if err != nil {
+			return common.Hash{}, err
+		}
+		copy(record[:32], hash[:])
+		copy(record[32:], tdBytes[:])
+		leaves[i] = sha256.Sum256(record[:])
+	}
+	root, err := merkle_tree.MerkleizeVector(leaves, MaxEra1Size)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	// mix in the length of the list
+	copy(record[:32], root[:])
+	clear(record[32:])
+	binary.LittleEndian.PutUint64(record[32:], uint64(len(hashes)))
+	return sha256.Sum256(record[:]), nil
+}
+
+// tdLittleEndian encodes the total difficulty as the 32 bytes little-endian uint256 of SSZ and era1
+func tdLittleEndian(td *big.Int) ([32]byte, error) {
+	var b [32]byte
+	if td.Sign() < 0 || td.BitLen() > 256 {
+		return b, fmt.Errorf("total difficulty %d out of the uint256 range", td)
+	}
+	td.FillBytes(b[:])
+	slices.Reverse(b[:])
+	return b, nil
+}
+
+func tdFromLittleEndian(b []byte) *big.Int {
+	be := slices.Clone(b)
+	slices.Reverse(be)
+	return new(big.Int).SetBytes(be)
+}
@@ -0,0 +1,449 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+// Package era1 reads and writes the .era1 archives of the pre-merge execution history. An era1 file holds
+// up to 8192 consecutive blocks:
+//
+//	era1 := Version | block-tuple* | Accumulator | BlockIndex
+//	block-tuple := CompressedHeader | CompressedBody | CompressedReceipts | TotalDifficulty
+//
+// Headers, bodies and receipts are snappy-framed RLP, the accumulator is the SSZ root of the block hashes and
+// total difficulties, the block index holds the offsets of the block tuples.
+//
+// See https://github.com/eth-clients/e2store-format-specs/blob/main/formats/era1.md
+package era1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/snappy"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/rlp"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/turbo/snapshotsync/e2store"
+)
+
+const (
+	TypeCompressedHeader   uint16 = 0x03
+	TypeCompressedBody     uint16 = 0x04
+	TypeCompressedReceipts uint16 = 0x05
+	TypeTotalDifficulty    uint16 = 0x06
+	TypeAccumulator        uint16 = 0x07
+	TypeBlockIndex         uint16 = 0x3266 // "f2"
+
+	// MaxEra1Size is the number of blocks of an era1 file, only the last pre-merge one may have less
+	MaxEra1Size = 8192
+
+	Extension = ".era1"
+)
+
+// Filename returns the standard name of the era1 file: <network>-<epoch>-<short accumulator root>.era1
+func Filename(network string, epoch uint64, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s%s", network, epoch, hex.EncodeToString(root[:4]), Extension)
+}
+
+// ParseFilename is the reverse of Filename
+func ParseFilename(name string) (network string, epoch uint64, shortRoot []byte, err error) {
+	base := strings.TrimSuffix(filepath.Base(name), Extension)
+	parts := strings.Split(base, "-")
+	if len(parts) < 3 || base == filepath.Base(name) {
+		return "", 0, nil, fmt.Errorf("malformed era1 file name %q", name)
+	}
+	// the network name may contain dashes
+	network = strings.Join(parts[:len(parts)-2], "-")
+	if epoch, err = strconv.ParseUint(parts[len(parts)-2], 10, 64); err != nil {
+		return "", 0, nil, fmt.Errorf("malformed era1 file name %q: %w", name, err)
+	}
+	if shortRoot, err = hex.DecodeString(parts[len(parts)-1]); err != nil || len(shortRoot) != 4 {
+		return "", 0, nil, fmt.Errorf("malformed era1 file name %q: bad accumulator root", name)
+	}
+	return network, epoch, shortRoot, nil
+}
+
+// Files returns the era1 files of the network in the dir, ordered by epoch
+func Files(dir, network string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type file struct {
+		path  string
+		epoch uint64
+	}
+	var files []file
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), Extension) {
+			continue
+		}
+		net, epoch, _, err := ParseFilename(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if net != network {
+			continue
+		}
+		files = append(files, file{path: filepath.Join(dir, entry.Name()), epoch: epoch})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].epoch < files[j].epoch })
+	paths := make([]string, len(files))
+	for i, f := range files {
+		if i > 0 && f.epoch == files[i-1].epoch {
+			return nil, fmt.Errorf("two era1 files of epoch %d: %s, %s", f.epoch, files[i-1].path, f.path)
+		}
+		paths[i] = f.path
+	}
+	return paths, nil
+}
+
+// Builder writes an era1 file, block by block
+type Builder struct {
+	w       *e2store.Writer
+	written uint64
+
+	startNum uint64
+	offsets  []uint64
+	hashes   []common.Hash
+	tds      []*big.Int
+
+	buf    bytes.Buffer
+	snappy *snappy.Writer
+}
+
+func NewBuilder(w io.Writer) *Builder {
+	return &Builder{w: e2store.NewWriter(w)}
+}
+
+// Add appends the block, its receipts and its total difficulty. Blocks must be consecutive.
+func (b *Builder) Add(block *types.Block, receipts types.Receipts, td *big.Int) error {
+	header, err := rlp.EncodeToBytes(block.HeaderNoCopy())
+	if err != nil {
+		return err
+	}
+	// pre-merge bodies have no withdrawals
+	body, err := rlp.EncodeToBytes(&types.Body{Transactions: block.Transactions(), Uncles: block.Uncles()})
+	if err != nil {
+		return err
+	}
+	encodedReceipts, err := rlp.EncodeToBytes(receipts)
+	if err != nil {
+		return err
+	}
+	return b.AddRLP(header, body, encodedReceipts, block.NumberU64(), block.Hash(), td)
+}
+
+// AddRLP is Add of the encoded header, body and receipts
+func (b *Builder) AddRLP(header, body, receipts []byte, num uint64, hash common.Hash, td *big.Int) error {
+	if len(b.offsets) == 0 {
+		if err := b.write(e2store.TypeVersion, nil); err != nil {
+			return err
+		}
+		b.startNum = num
+	} else if expected := b.startNum + uint64(len(b.offsets)); num != expected {
+		return fmt.Errorf("era1: expected block %d, got %d", expected, num)
+	}
+	if len(b.offsets) == MaxEra1Size {
+		return fmt.Errorf("era1: more than %d blocks", MaxEra1Size)
+	}
+	tdBytes, err := tdLittleEndian(td)
+	if err != nil {
+		return err
+	}
+
+	b.offsets = append(b.offsets, b.written)
+	b.hashes = append(b.hashes, hash)
+	b.tds = append(b.tds, new(big.Int).Set(td))
+	for _, entry := range []struct {
+		typ  uint16
+		data []byte
+	}{{TypeCompressedHeader, header}, {TypeCompressedBody, body}, {TypeCompressedReceipts, receipts}} {
+		if err := b.writeCompressed(entry.typ, entry.data); err != nil {
+			return err
+		}
+	}
+	return b.write(TypeTotalDifficulty, tdBytes[:])
+}
+
+// Finalize writes the accumulator and the block index, and returns the accumulator root
+func (b *Builder) Finalize() (common.Hash, error) {
+	if len(b.offsets) == 0 {
+		return common.Hash{}, errors.New("era1: no blocks")
+	}
+	root, err := ComputeAccumulator(b.hashes, b.tds)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if err := b.write(TypeAccumulator, root[:]); err != nil {
+		return common.Hash{}, err
+	}
+
+	// the offsets are relative to the start of the block index
+	base := b.written
+	index := make([]byte, 16+8*len(b.offsets))
+	binary.LittleEndian.PutUint64(index, b.startNum)
+	for i, offset := range b.offsets {
+		binary.LittleEndian.PutUint64(index[8+8*i:], uint64(int64(offset)-int64(base)))
+	}
+	binary.LittleEndian.PutUint64(index[8+8*len(b.offsets):], uint64(len(b.offsets)))
+	if err := b.write(TypeBlockIndex, index); err != nil {
+		return common.Hash{}, err
+	}
+	return root, nil
+}
+
+func (b *Builder) write(typ uint16, value []byte) error {
+	n, err := b.w.Write(typ, value)
+	b.written += uint64(n)
+	return err
+}
+
+func (b *Builder) writeCompressed(typ uint16, data []byte) error {
+	b.buf.Reset()
+	if b.snappy == nil {
+		b.snappy = snappy.NewBufferedWriter(&b.buf)
+	} else {
+		b.snappy.Reset(&b.buf)
+	}
+	if _, err := b.snappy.Write(data); err != nil {
+		return err
+	}
+	if err := b.snappy.Close(); err != nil {
+		return err
+	}
+	return b.write(typ, b.buf.Bytes())
+}
+
+// Era is an open era1 file
+type Era struct {
+	f       *os.File
+	r       *e2store.Reader
+	start   uint64
+	offsets []int64 // absolute offsets of the block tuples
+	indexAt int64
+}
+
+// Open opens the era1 file and reads its block index
+func Open(path string) (*Era, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	e, err := newEra(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return e, nil
+}
+
+func newEra(f *os.File) (*Era, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	e := &Era{f: f, r: e2store.NewReader(f)}
+	version, _, err := e.r.ReadAt(0)
+	if err != nil {
+		return nil, err
+	}
+	if version.Type != e2store.TypeVersion || len(version.Value) != 0 {
+		return nil, errors.New("era1: no version entry")
+	}
+
+	var countBuf [8]byte
+	if size < e2store.HeaderSize+16 {
+		return nil, errors.New("era1: file too short")
+	}
+	if _, err := f.ReadAt(countBuf[:], size-8); err != nil {
+		return nil, err
+	}
+	count := binary.LittleEndian.Uint64(countBuf[:])
+	if count == 0 || count > MaxEra1Size {
+		return nil, fmt.Errorf("era1: bad block count %d", count)
+	}
+	e.indexAt = size - e2store.HeaderSize - 16 - 8*int64(count)
+	index, err := e.r.ReadValueAt(e.indexAt, TypeBlockIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(index) != 16+8*int(count) {
+		return nil, errors.New("era1: malformed block index")
+	}
+	e.start = binary.LittleEndian.Uint64(index)
+	e.offsets = make([]int64, count)
+	for i := range e.offsets {
+		e.offsets[i] = e.indexAt + int64(binary.LittleEndian.Uint64(index[8+8*i:]))
+		if e.offsets[i] < e2store.HeaderSize || e.offsets[i] >= e.indexAt {
+			return nil, fmt.Errorf("era1: block %d offset out of the file", e.start+uint64(i))
+		}
+	}
+	return e, nil
+}
+
+func (e *Era) Close() error { return e.f.Close() }
+
+// Start is the number of the first block
+func (e *Era) Start() uint64 { return e.start }
+
+// Count is the number of the blocks
+func (e *Era) Count() uint64 { return uint64(len(e.offsets)) }
+
+// Accumulator returns the accumulator root stored in the file
+func (e *Era) Accumulator() (common.Hash, error) {
+	// the accumulator is right before the block index
+	v, err := e.r.ReadValueAt(e.indexAt-e2store.HeaderSize-32, TypeAccumulator)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(v), nil
+}
+
+// BlockTuple is a block of an era1 file
+type BlockTuple struct {
+	Header          *types.Header
+	Body            *types.Body
+	Receipts        types.Receipts
+	TotalDifficulty *big.Int
+}
+
+// Block reads the block tuple of the block num
+func (e *Era) Block(num uint64) (*BlockTuple, error) {
+	if num < e.start || num >= e.start+e.Count() {
+		return nil, fmt.Errorf("era1: block %d is out of the range [%d, %d)", num, e.start, e.start+e.Count())
+	}
+	off := e.offsets[num-e.start]
+	t := &BlockTuple{Header: &types.Header{}, Body: &types.Body{}}
+	for _, entry := range []struct {
+		typ uint16
+		dst any
+	}{{TypeCompressedHeader, t.Header}, {TypeCompressedBody, t.Body}, {TypeCompressedReceipts, &t.Receipts}} {
+		value, size, err := e.readEntry(off, entry.typ)
+		if err != nil {
+			return nil, fmt.Errorf("block %d: %w", num, err)
+		}
+		data, err := io.ReadAll(snappy.NewReader(bytes.NewReader(value)))
+		if err != nil {
+			return nil, fmt.Errorf("block %d: entry %#04x: %w", num, entry.typ, err)
+		}
+		if err := rlp.DecodeBytes(data, entry.dst); err != nil {
+			return nil, fmt.Errorf("block %d: entry %#04x: %w", num, entry.typ, err)
+		}
+		off += size
+	}
+	value, _, err := e.readEntry(off, TypeTotalDifficulty)
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %w", num, err)
+	}
+	if len(value) != 32 {
+		return nil, fmt.Errorf("block %d: malformed total difficulty", num)
+	}
+	t.TotalDifficulty = tdFromLittleEndian(value)
+	if t.Header.Number.Uint64() != num {
+		return nil, fmt.Errorf("block %d: the header is of block %d", num, t.Header.Number.Uint64())
+	}
+	return t, nil
+}
+
+func (e *Era) readEntry(off int64, typ uint16) ([]byte, int64, error) {
+	entry, size, err := e.r.ReadAt(off)
+	if err != nil {
+		return nil, 0, err
+	}
+	if entry.Type != typ {
+		return nil, 0, fmt.Errorf("era1: expected entry type %#04x at offset %d, got %#04x", typ, off, entry.Type)
+	}
+	return entry.Value, size, nil
+}
+
+// Check verifies the body and the receipts against the roots of the header
+func (t *BlockTuple) Check() error {
+	num := t.Header.Number.Uint64()
+	if hash := types.DeriveSha(types.Transactions(t.Body.Transactions)); hash != t.Header.TxHash {
+		return fmt.Errorf("block %d: transactions root %x, header %x", num, hash, t.Header.TxHash)
+	}
+	if hash := types.CalcUncleHash(t.Body.Uncles); hash != t.Header.UncleHash {
+		return fmt.Errorf("block %d: uncles hash %x, header %x", num, hash, t.Header.UncleHash)
+	}
+	if len(t.Receipts) != len(t.Body.Transactions) {
+		return fmt.Errorf("block %d: %d receipts for %d transactions", num, len(t.Receipts), len(t.Body.Transactions))
+	}
+	if hash := types.DeriveSha(t.Receipts); hash != t.Header.ReceiptHash {
+		return fmt.Errorf("block %d: receipts root %x, header %x", num, hash, t.Header.ReceiptHash)
+	}
+	return nil
+}
+
+// Verify reads all the blocks of the file and checks them: the bodies and the receipts against the headers,
+// the chain of the headers, the total difficulties and the accumulator. Returns the accumulator root.
+// parentTd is the total difficulty of the parent of the first block, nil if unknown.
+func (e *Era) Verify(parentTd *big.Int, f func(*BlockTuple) error) (common.Hash, error) {
+	hashes := make([]common.Hash, 0, e.Count())
+	tds := make([]*big.Int, 0, e.Count())
+	var parentHash common.Hash
+	for num := e.start; num < e.start+e.Count(); num++ {
+		t, err := e.Block(num)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		if err := t.Check(); err != nil {
+			return common.Hash{}, err
+		}
+		hash := t.Header.Hash()
+		if num > e.start && t.Header.ParentHash != parentHash {
+			return common.Hash{}, fmt.Errorf("block %d: parent hash %x, expected %x", num, t.Header.ParentHash, parentHash)
+		}
+		if parentTd != nil {
+			if expected := new(big.Int).Add(parentTd, t.Header.Difficulty); expected.Cmp(t.TotalDifficulty) != 0 {
+				return common.Hash{}, fmt.Errorf("block %d: total difficulty %d, expected %d", num, t.TotalDifficulty, expected)
+			}
+		} else if num == 0 && t.TotalDifficulty.Cmp(t.Header.Difficulty) != 0 {
+			return common.Hash{}, fmt.Errorf("block 0: total difficulty %d, expected %d", t.TotalDifficulty, t.Header.Difficulty)
+		}
+		if f != nil {
+			if err := f(t); err != nil {
+				return common.Hash{}, err
+			}
+		}
+		hashes = append(hashes, hash)
+		tds = append(tds, t.TotalDifficulty)
+		parentHash, parentTd = hash, t.TotalDifficulty
+	}
+
+	root, err := ComputeAccumulator(hashes, tds)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	stored, err := e.Accumulator()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if root != stored {
+		return common.Hash{}, fmt.Errorf("accumulator root %x, the file has %x", root, stored)
+	}
+	return root, nil
+}
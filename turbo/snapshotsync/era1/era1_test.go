@@ -0,0 +1,227 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package era1
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon/core/types"
+)
+
+// testChain makes count blocks from the genesis, each with a legacy and a dynamic fee txn
+func testChain(t *testing.T, count int) ([]*types.Block, []types.Receipts, []*big.Int) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+		tds      []*big.Int
+		parent   *types.Block
+		td       = new(big.Int)
+	)
+	for i := 0; i < count; i++ {
+		header := &types.Header{Number: big.NewInt(int64(i)), Difficulty: big.NewInt(int64(1000 + i)), GasLimit: 1_000_000, Extra: []byte("era1")}
+		var txs types.Transactions
+		var blockReceipts types.Receipts
+		if parent != nil {
+			header.ParentHash = parent.Hash()
+			legacy, err := types.SignTx(types.NewTransaction(uint64(2*i), common.Address{1}, uint256.NewInt(1), 21000, uint256.NewInt(1), nil), *signer, key)
+			require.NoError(t, err)
+			dynamic, err := types.SignTx(types.NewEIP1559Transaction(*uint256.NewInt(1), uint64(2*i+1), common.Address{2}, uint256.NewInt(1), 21000, nil, uint256.NewInt(1), uint256.NewInt(2), nil), *signer, key)
+			require.NoError(t, err)
+			txs = types.Transactions{legacy, dynamic}
+			logs := []*types.Log{{Address: common.Address{2}, Topics: []common.Hash{{byte(i)}}, Data: []byte{1}}}
+			blockReceipts = types.Receipts{
+				{Type: types.LegacyTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*types.Log{}},
+				{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, CumulativeGasUsed: 42000, Logs: logs, Bloom: types.CreateBloom(types.Receipts{{Logs: logs}})},
+			}
+		}
+		var uncles []*types.Header
+		if i == 2 {
+			uncles = []*types.Header{{Number: big.NewInt(1), Difficulty: big.NewInt(1), Extra: []byte("uncle")}}
+		}
+		block := types.NewBlock(header, txs, uncles, blockReceipts, nil)
+		td = new(big.Int).Add(td, header.Difficulty)
+		blocks = append(blocks, block)
+		receipts = append(receipts, blockReceipts)
+		tds = append(tds, td)
+		parent = block
+	}
+	return blocks, receipts, tds
+}
+
+func writeEra1(t *testing.T, dir string, blocks []*types.Block, receipts []types.Receipts, tds []*big.Int) (string, common.Hash) {
+	tmp := filepath.Join(dir, "building")
+	f, err := os.Create(tmp)
+	require.NoError(t, err)
+	b := NewBuilder(f)
+	for i, block := range blocks {
+		require.NoError(t, b.Add(block, receipts[i], tds[i]))
+	}
+	root, err := b.Finalize()
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	path := filepath.Join(dir, Filename("mainnet", blocks[0].NumberU64()/MaxEra1Size, root))
+	require.NoError(t, os.Rename(tmp, path))
+	return path, root
+}
+
+func TestReadWrite(t *testing.T) {
+	dir := t.TempDir()
+	blocks, receipts, tds := testChain(t, 5)
+	path, root := writeEra1(t, dir, blocks, receipts, tds)
+
+	e, err := Open(path)
+	require.NoError(t, err)
+	defer e.Close()
+	require.Equal(t, uint64(0), e.Start())
+	require.Equal(t, uint64(5), e.Count())
+	stored, err := e.Accumulator()
+	require.NoError(t, err)
+	require.Equal(t, root, stored)
+
+	tuple, err := e.Block(3)
+	require.NoError(t, err)
+	require.Equal(t, blocks[3].Hash(), tuple.Header.Hash())
+	require.Len(t, tuple.Body.Transactions, 2)
+	require.Equal(t, blocks[3].Transactions()[1].Hash(), tuple.Body.Transactions[1].Hash())
+	require.Equal(t, uint8(types.DynamicFeeTxType), tuple.Receipts[1].Type)
+	require.Equal(t, tds[3], tuple.TotalDifficulty)
+	_, err = e.Block(5)
+	require.Error(t, err)
+
+	var visited []common.Hash
+	verified, err := e.Verify(nil, func(t *BlockTuple) error {
+		visited = append(visited, t.Header.Hash())
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, root, verified)
+	require.Len(t, visited, 5)
+	require.Equal(t, blocks[2].Hash(), visited[2])
+
+	// the parent total difficulty of the first block is checked
+	_, err = e.Verify(big.NewInt(1), nil)
+	require.ErrorContains(t, err, "total difficulty")
+
+	network, epoch, shortRoot, err := ParseFilename(path)
+	require.NoError(t, err)
+	require.Equal(t, "mainnet", network)
+	require.Equal(t, uint64(0), epoch)
+	require.Equal(t, root[:4], shortRoot)
+	files, err := Files(dir, "mainnet")
+	require.NoError(t, err)
+	require.Equal(t, []string{path}, files)
+	files, err = Files(dir, "sepolia")
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
+
+func TestVerifyDetectsCorruption(t *testing.T) {
+	dir := t.TempDir()
+	blocks, receipts, tds := testChain(t, 3)
+
+	// receipts of another block
+	corruptReceipts := append([]types.Receipts{}, receipts...)
+	corruptReceipts[2] = receipts[1][:1]
+	path, _ := writeEra1(t, dir, blocks, corruptReceipts, tds)
+	e, err := Open(path)
+	require.NoError(t, err)
+	_, err = e.Verify(nil, nil)
+	require.ErrorContains(t, err, "block 2: 1 receipts for 2 transactions")
+	require.NoError(t, e.Close())
+
+	// a total difficulty out of the chain
+	corruptTds := append([]*big.Int{}, tds...)
+	corruptTds[1] = new(big.Int).Add(tds[1], common.Big1)
+	path, _ = writeEra1(t, t.TempDir(), blocks, receipts, corruptTds)
+	e, err = Open(path)
+	require.NoError(t, err)
+	_, err = e.Verify(nil, nil)
+	require.ErrorContains(t, err, "block 1: total difficulty")
+	require.NoError(t, e.Close())
+
+	// a block out of the sequence
+	b := NewBuilder(&discard{})
+	require.NoError(t, b.Add(blocks[0], receipts[0], tds[0]))
+	require.ErrorContains(t, b.Add(blocks[2], receipts[2], tds[2]), "expected block 1")
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestAccumulator(t *testing.T) {
+	hashes := []common.Hash{{1}, {2}, {3}}
+	tds := []*big.Int{big.NewInt(1), big.NewInt(3), new(big.Int).Lsh(common.Big1, 200)}
+	root, err := ComputeAccumulator(hashes, tds)
+	require.NoError(t, err)
+
+	// plain merkleization of the SSZ list
+	layer := make([][32]byte, MaxEra1Size)
+	for i := range hashes {
+		var record [64]byte
+		copy(record[:], hashes[i][:])
+		tdBytes, err := tdLittleEndian(tds[i])
+		require.NoError(t, err)
+		copy(record[32:], tdBytes[:])
+		layer[i] = sha256.Sum256(record[:])
+	}
+	for len(layer) > 1 {
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = sha256.Sum256(append(layer[2*i][:], layer[2*i+1][:]...))
+		}
+		layer = next
+	}
+	var mixIn [64]byte
+	copy(mixIn[:], layer[0][:])
+	binary.LittleEndian.PutUint64(mixIn[32:], uint64(len(hashes)))
+	require.Equal(t, common.Hash(sha256.Sum256(mixIn[:])), root)
+
+	require.Equal(t, tds[2], tdFromLittleEndian(func() []byte { b, _ := tdLittleEndian(tds[2]); return b[:] }()))
+	_, err = ComputeAccumulator(hashes, tds[:2])
+	require.Error(t, err)
+}
+
+func TestRoots(t *testing.T) {
+	roots, err := ParseRoots([]byte("# comment\n0x01" + common.Hash{}.Hex()[4:] + "\n\n" + common.Hash{2}.Hex()[2:] + "\n"))
+	require.NoError(t, err)
+	require.Equal(t, []common.Hash{{1}, {2}}, roots)
+	_, err = ParseRoots([]byte("0x0102\n"))
+	require.ErrorContains(t, err, "line 1: malformed accumulator root")
+
+	// the embedded roots parse
+	for _, network := range []string{"mainnet", "sepolia"} {
+		_, err = KnownRoots(network)
+		require.NoError(t, err, network)
+	}
+	roots, err = KnownRoots("dev")
+	require.NoError(t, err)
+	require.Empty(t, roots)
+}
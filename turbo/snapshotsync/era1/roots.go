@@ -0,0 +1,64 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package era1
+
+import (
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/erigontech/erigon-lib/common"
+)
+
+// roots holds the known accumulator roots of the era1 epochs of the public networks, roots/<network>.txt
+//
+//go:embed roots/*.txt
+var roots embed.FS
+
+// KnownRoots returns the embedded accumulator roots of the network, indexed by epoch. Returns no roots for
+// a network without era1 files.
+func KnownRoots(network string) ([]common.Hash, error) {
+	data, err := roots.ReadFile("roots/" + network + ".txt")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseRoots(data)
+}
+
+// ParseRoots parses accumulator roots, one hex root per line: the N-th root is the one of the epoch N.
+// Empty lines and lines starting with # are skipped.
+func ParseRoots(data []byte) ([]common.Hash, error) {
+	var parsed []common.Hash
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b, err := hex.DecodeString(strings.TrimPrefix(line, "0x"))
+		if err != nil || len(b) != len(common.Hash{}) {
+			return nil, fmt.Errorf("line %d: malformed accumulator root %q", i+1, line)
+		}
+		parsed = append(parsed, common.BytesToHash(b))
+	}
+	return parsed, nil
+}
@@ -364,7 +364,10 @@ func MockWithEverything(tb testing.TB, gspec *types.Genesis, key *ecdsa.PrivateK
 		mock.StreamWg.Add(1)
 		mock.TxPoolFetch.ConnectSentries()
 		mock.StreamWg.Wait()
-		mock.TxnProvider = txnprovider.NewOrderedTxnPoolProvider(mock.TxPool)
+		mock.TxnProvider = txnprovider.NewCompositeTxnProvider(
+			txnprovider.NewBundleTxnProvider(mock.TxPoolGrpcServer.Bundles),
+			txnprovider.NewOrderedTxnPoolProvider(mock.TxPool),
+		)
 
 		go txpool.MainLoop(mock.Ctx, mock.TxPool, newTxs, mock.TxPoolSend, mock.TxPoolGrpcServer.NewSlotsStreams, func() {})
 	}
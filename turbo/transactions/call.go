@@ -102,14 +102,17 @@ func DoCall(
 
 	evm := vm.NewEVM(blockCtx, txCtx, state, chainConfig, vm.Config{NoBaseFee: true})
 
-	// Wait for the context to be done and cancel the evm. Even if the
-	// EVM has finished, cancelling may be done (repeatedly)
-	go func() {
-		<-ctx.Done()
-		evm.Cancel()
-	}()
-
 	gp := new(core.GasPool).AddGas(msg.Gas()).AddBlobGas(msg.BlobGas())
+	return ApplyCall(ctx, evm, msg, gp, callTimeout)
+}
+
+// ApplyCall applies msg on evm the way calls are: with refunds and without gas bailout. The execution is aborted
+// once ctx is done, callTimeout being reported as the reason.
+func ApplyCall(ctx context.Context, evm *vm.EVM, msg core.Message, gp *core.GasPool, callTimeout time.Duration) (*evmtypes.ExecutionResult, error) {
+	// Cancel the evm once the context is done. Even if the EVM has finished, cancelling may be done (repeatedly)
+	stop := context.AfterFunc(ctx, evm.Cancel)
+	defer stop()
+
 	result, err := core.ApplyMessage(evm, msg, gp, true /* refunds */, false /* gasBailout */)
 	if err != nil {
 		return nil, err
@@ -102,13 +102,15 @@ func TraceTx(
 	message core.Message,
 	blockCtx evmtypes.BlockContext,
 	txCtx evmtypes.TxContext,
+	blockHash libcommon.Hash,
+	txnIndex int,
 	ibs evmtypes.IntraBlockState,
 	config *tracersConfig.TraceConfig,
 	chainConfig *chain.Config,
 	stream *jsoniter.Stream,
 	callTimeout time.Duration,
 ) (usedGas uint64, err error) {
-	tracer, streaming, cancel, err := AssembleTracer(ctx, config, txCtx.TxHash, stream, callTimeout)
+	tracer, streaming, cancel, err := AssembleTracer(ctx, config, txCtx.TxHash, blockHash, txnIndex, stream, callTimeout)
 	if err != nil {
 		stream.WriteNil()
 		return 0, err
@@ -134,6 +136,8 @@ func AssembleTracer(
 	ctx context.Context,
 	config *tracersConfig.TraceConfig,
 	txHash libcommon.Hash,
+	blockHash libcommon.Hash,
+	txnIndex int,
 	stream *jsoniter.Stream,
 	callTimeout time.Duration,
 ) (vm.EVMLogger, bool, context.CancelFunc, error) {
@@ -155,7 +159,7 @@ func AssembleTracer(
 		if config != nil && config.TracerConfig != nil {
 			cfg = *config.TracerConfig
 		}
-		tracer, err := tracers.New(*config.Tracer, &tracers.Context{TxHash: txHash}, cfg)
+		tracer, err := tracers.New(*config.Tracer, &tracers.Context{TxHash: txHash, BlockHash: blockHash, TxIndex: txnIndex}, cfg)
 		if err != nil {
 			return nil, false, func() {}, err
 		}
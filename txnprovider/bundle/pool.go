@@ -0,0 +1,241 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/crypto"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/core/types"
+)
+
+const (
+	// MaxBundles is the maximum number of bundles kept in the pool
+	MaxBundles = 1024
+	// MaxBundleTxns is the maximum number of transactions in a bundle
+	MaxBundleTxns = 64
+)
+
+var (
+	ErrEmptyBundle     = errors.New("bundle has no transactions")
+	ErrTooManyTxns     = fmt.Errorf("bundle has more than %d transactions", MaxBundleTxns)
+	ErrNoBlockNumber   = errors.New("bundle has no target block number")
+	ErrBlockInThePast  = errors.New("bundle target block is in the past")
+	ErrBadTimestamps   = errors.New("bundle min timestamp is after its max timestamp")
+	ErrPoolFull        = fmt.Errorf("bundle pool is full (%d bundles)", MaxBundles)
+	ErrMissingSender   = errors.New("bundle transaction has no sender")
+	ErrDuplicateBundle = errors.New("bundle already known")
+)
+
+// Bundle is a list of transactions to be included atomically and in order, at the top of a given block
+type Bundle struct {
+	Txns         []types.Transaction
+	BlockNumber  uint64
+	MinTimestamp uint64 // 0 means no lower bound
+	MaxTimestamp uint64 // 0 means no upper bound
+	// RevertingTxnHashes are the transactions of the bundle which are allowed to revert, any other reverting
+	// transaction makes the whole bundle being dropped
+	RevertingTxnHashes map[common.Hash]struct{}
+	// ReplacementUuid identifies the bundle for replacement and cancellation, optional
+	ReplacementUuid string
+
+	hash    common.Hash
+	arrival uint64
+}
+
+// Hash is the keccak of the concatenated hashes of the bundle transactions
+func (b *Bundle) Hash() common.Hash {
+	return b.hash
+}
+
+// Arrival is the order in which the bundle was added to the pool, lower is earlier
+func (b *Bundle) Arrival() uint64 {
+	return b.arrival
+}
+
+func (b *Bundle) CanRevert(txnHash common.Hash) bool {
+	_, ok := b.RevertingTxnHashes[txnHash]
+	return ok
+}
+
+// Eligible tells whether the bundle can be included in the block with the given number and timestamp
+func (b *Bundle) Eligible(blockNum uint64, blockTime uint64) bool {
+	if b.BlockNumber != blockNum {
+		return false
+	}
+	if blockTime == 0 {
+		return true
+	}
+	return (b.MinTimestamp == 0 || blockTime >= b.MinTimestamp) && (b.MaxTimestamp == 0 || blockTime <= b.MaxTimestamp)
+}
+
+func (b *Bundle) computeHash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txns)*length.Hash)
+	for _, txn := range b.Txns {
+		txnHash := txn.Hash()
+		hashes = append(hashes, txnHash[:]...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// Pool keeps the bundles submitted via eth_sendBundle until their target block has passed
+type Pool struct {
+	lock          sync.Mutex
+	byHash        map[common.Hash]*Bundle
+	byUuid        map[string]*Bundle
+	byTxnHash     map[common.Hash]*Bundle
+	arrivals      uint64
+	lastBlockSeen uint64
+	logger        log.Logger
+}
+
+func NewPool(logger log.Logger) *Pool {
+	return &Pool{
+		byHash:    map[common.Hash]*Bundle{},
+		byUuid:    map[string]*Bundle{},
+		byTxnHash: map[common.Hash]*Bundle{},
+		logger:    logger,
+	}
+}
+
+// Add validates and stores a bundle, replacing the previous one with the same replacement uuid, if any.
+// The transactions must have their senders set.
+func (p *Pool) Add(b *Bundle) (common.Hash, error) {
+	if len(b.Txns) == 0 {
+		return common.Hash{}, ErrEmptyBundle
+	}
+	if len(b.Txns) > MaxBundleTxns {
+		return common.Hash{}, ErrTooManyTxns
+	}
+	if b.BlockNumber == 0 {
+		return common.Hash{}, ErrNoBlockNumber
+	}
+	if b.MinTimestamp != 0 && b.MaxTimestamp != 0 && b.MinTimestamp > b.MaxTimestamp {
+		return common.Hash{}, ErrBadTimestamps
+	}
+	for _, txn := range b.Txns {
+		if _, ok := txn.GetSender(); !ok {
+			return common.Hash{}, ErrMissingSender
+		}
+	}
+	b.hash = b.computeHash()
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if b.BlockNumber <= p.lastBlockSeen {
+		return common.Hash{}, ErrBlockInThePast
+	}
+	if _, ok := p.byHash[b.hash]; ok {
+		return common.Hash{}, ErrDuplicateBundle
+	}
+	if b.ReplacementUuid != "" {
+		if old, ok := p.byUuid[b.ReplacementUuid]; ok {
+			p.removeLocked(old)
+		}
+	}
+	if len(p.byHash) >= MaxBundles {
+		return common.Hash{}, ErrPoolFull
+	}
+	p.arrivals++
+	b.arrival = p.arrivals
+	p.byHash[b.hash] = b
+	if b.ReplacementUuid != "" {
+		p.byUuid[b.ReplacementUuid] = b
+	}
+	for _, txn := range b.Txns {
+		p.byTxnHash[txn.Hash()] = b
+	}
+	p.logger.Debug("[bundles] Added bundle", "hash", b.hash, "txns", len(b.Txns), "block", b.BlockNumber)
+	return b.hash, nil
+}
+
+// Cancel removes the bundle with the given replacement uuid, it returns false if there was none
+func (p *Pool) Cancel(replacementUuid string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b, ok := p.byUuid[replacementUuid]
+	if !ok {
+		return false
+	}
+	p.removeLocked(b)
+	return true
+}
+
+// Drop removes the bundle with the given hash, e.g. because it reverted during block building
+func (p *Pool) Drop(bundleHash common.Hash, reason error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if b, ok := p.byHash[bundleHash]; ok {
+		p.logger.Debug("[bundles] Dropping bundle", "hash", bundleHash, "reason", reason)
+		p.removeLocked(b)
+	}
+}
+
+// BundleOf returns the bundle which the transaction with the given hash belongs to
+func (p *Pool) BundleOf(txnHash common.Hash) (*Bundle, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	b, ok := p.byTxnHash[txnHash]
+	return b, ok
+}
+
+// Eligible returns the bundles which can be included in the block with the given number and timestamp (if not 0),
+// in arrival order. Bundles targeting earlier blocks are pruned.
+func (p *Pool) Eligible(blockNum uint64, blockTime uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if blockNum > 0 && blockNum-1 > p.lastBlockSeen {
+		p.lastBlockSeen = blockNum - 1
+	}
+	var eligible []*Bundle
+	for _, b := range p.byHash {
+		if b.BlockNumber <= p.lastBlockSeen {
+			p.removeLocked(b)
+			continue
+		}
+		if b.Eligible(blockNum, blockTime) {
+			eligible = append(eligible, b)
+		}
+	}
+	sort.Slice(eligible, func(i, j int) bool { return eligible[i].arrival < eligible[j].arrival })
+	return eligible
+}
+
+func (p *Pool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return len(p.byHash)
+}
+
+func (p *Pool) removeLocked(b *Bundle) {
+	delete(p.byHash, b.hash)
+	if b.ReplacementUuid != "" && p.byUuid[b.ReplacementUuid] == b {
+		delete(p.byUuid, b.ReplacementUuid)
+	}
+	for _, txn := range b.Txns {
+		if p.byTxnHash[txn.Hash()] == b {
+			delete(p.byTxnHash, txn.Hash())
+		}
+	}
+}
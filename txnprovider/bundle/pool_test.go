@@ -0,0 +1,110 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package bundle
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/log/v3"
+
+	"github.com/erigontech/erigon/core/types"
+)
+
+func newTxn(nonce uint64) types.Transaction {
+	to := common.Address{0xff}
+	txn := &types.LegacyTx{
+		CommonTx: types.CommonTx{
+			Nonce: nonce,
+			Gas:   21_000,
+			To:    &to,
+			Value: uint256.NewInt(0),
+		},
+		GasPrice: uint256.NewInt(1),
+	}
+	txn.SetSender(common.Address{0xa})
+	return txn
+}
+
+func TestPoolAdd(t *testing.T) {
+	pool := NewPool(log.New())
+
+	_, err := pool.Add(&Bundle{BlockNumber: 10})
+	require.ErrorIs(t, err, ErrEmptyBundle)
+	_, err = pool.Add(&Bundle{Txns: []types.Transaction{newTxn(0)}})
+	require.ErrorIs(t, err, ErrNoBlockNumber)
+	_, err = pool.Add(&Bundle{Txns: []types.Transaction{newTxn(0)}, BlockNumber: 10, MinTimestamp: 2, MaxTimestamp: 1})
+	require.ErrorIs(t, err, ErrBadTimestamps)
+
+	b := &Bundle{Txns: []types.Transaction{newTxn(0), newTxn(1)}, BlockNumber: 10}
+	hash, err := pool.Add(b)
+	require.NoError(t, err)
+	require.Equal(t, b.Hash(), hash)
+	_, err = pool.Add(&Bundle{Txns: []types.Transaction{newTxn(0), newTxn(1)}, BlockNumber: 10})
+	require.ErrorIs(t, err, ErrDuplicateBundle)
+
+	found, ok := pool.BundleOf(newTxn(1).Hash())
+	require.True(t, ok)
+	require.Same(t, b, found)
+
+	pool.Drop(hash, nil)
+	require.Zero(t, pool.Len())
+	_, ok = pool.BundleOf(newTxn(1).Hash())
+	require.False(t, ok)
+}
+
+func TestPoolReplaceAndCancel(t *testing.T) {
+	pool := NewPool(log.New())
+
+	_, err := pool.Add(&Bundle{Txns: []types.Transaction{newTxn(0)}, BlockNumber: 10, ReplacementUuid: "a"})
+	require.NoError(t, err)
+	replacement := &Bundle{Txns: []types.Transaction{newTxn(1)}, BlockNumber: 10, ReplacementUuid: "a"}
+	_, err = pool.Add(replacement)
+	require.NoError(t, err)
+	require.Equal(t, 1, pool.Len())
+	_, ok := pool.BundleOf(newTxn(0).Hash())
+	require.False(t, ok)
+
+	require.False(t, pool.Cancel("b"))
+	require.True(t, pool.Cancel("a"))
+	require.Zero(t, pool.Len())
+}
+
+func TestPoolEligible(t *testing.T) {
+	pool := NewPool(log.New())
+
+	late := &Bundle{Txns: []types.Transaction{newTxn(0)}, BlockNumber: 11}
+	timed := &Bundle{Txns: []types.Transaction{newTxn(1)}, BlockNumber: 10, MinTimestamp: 100, MaxTimestamp: 200}
+	untimed := &Bundle{Txns: []types.Transaction{newTxn(2)}, BlockNumber: 10}
+	for _, b := range []*Bundle{late, timed, untimed} {
+		_, err := pool.Add(b)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []*Bundle{timed, untimed}, pool.Eligible(10, 150))
+	require.Equal(t, []*Bundle{untimed}, pool.Eligible(10, 250))
+	require.Equal(t, []*Bundle{timed, untimed}, pool.Eligible(10, 0))
+
+	// building block 11 prunes the bundles for block 10
+	require.Equal(t, []*Bundle{late}, pool.Eligible(11, 0))
+	require.Equal(t, 1, pool.Len())
+	_, err := pool.Add(&Bundle{Txns: []types.Transaction{newTxn(3)}, BlockNumber: 10})
+	require.ErrorIs(t, err, ErrBlockInThePast)
+}
@@ -0,0 +1,268 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txnprovider
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/holiman/uint256"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/txnprovider/txpool/txpoolcfg"
+)
+
+const (
+	DefaultOrderingPolicyName        = "default"
+	FifoOrderingPolicyName           = "fifo"
+	SenderFairnessOrderingPolicyName = "sender-fairness"
+	PriorityLanesOrderingPolicyName  = "priority-lanes"
+	MaxProfitOrderingPolicyName      = "max-profit"
+)
+
+var OrderingPolicyNames = []string{
+	DefaultOrderingPolicyName,
+	FifoOrderingPolicyName,
+	SenderFairnessOrderingPolicyName,
+	PriorityLanesOrderingPolicyName,
+	MaxProfitOrderingPolicyName,
+}
+
+// OrderingCandidate is a unit of block inclusion: either a single transaction or a bundle of transactions
+// which have to be included atomically and in the given order.
+type OrderingCandidate struct {
+	Txns []types.Transaction
+	// Arrival is the order in which the candidate was received, lower is earlier
+	Arrival uint64
+}
+
+func (c *OrderingCandidate) sender() libcommon.Address {
+	sender, _ := c.Txns[0].GetSender()
+	return sender
+}
+
+func (c *OrderingCandidate) isBundle() bool {
+	return len(c.Txns) > 1
+}
+
+// gas is what the candidate takes from the gas targets, see intrinsicGas
+func (c *OrderingCandidate) gas() (gas uint64, blobGas uint64) {
+	for _, txn := range c.Txns {
+		gas += intrinsicGas(txn)
+		blobGas += txn.GetBlobGas()
+	}
+	return gas, blobGas
+}
+
+// intrinsicGas is the gas a txn is accounted for against the gas target, the same as the txn pool does when
+// yielding its best txns: the gas actually used is only known once executed
+func intrinsicGas(txn types.Transaction) uint64 {
+	data := txn.GetData()
+	var dataNonZeroLen uint64
+	for _, b := range data {
+		if b != 0 {
+			dataNonZeroLen++
+		}
+	}
+	var authorizationsLen uint64
+	if setCodeTxn, ok := txn.Unwrap().(*types.SetCodeTransaction); ok {
+		authorizationsLen = uint64(len(setCodeTxn.GetAuthorizations()))
+	}
+	gas, _ := txpoolcfg.CalcIntrinsicGas(uint64(len(data)), dataNonZeroLen, authorizationsLen, nil, txn.GetTo() == nil, true, true, true)
+	return gas
+}
+
+// profitPerGas is the effective tip paid to the block producer per unit of gas, averaged over a bundle
+func (c *OrderingCandidate) profitPerGas(baseFee *uint256.Int) *uint256.Int {
+	var profit, gas uint256.Int
+	for _, txn := range c.Txns {
+		var txnProfit uint256.Int
+		txnProfit.Mul(txn.GetEffectiveGasTip(baseFee), uint256.NewInt(txn.GetGas()))
+		profit.Add(&profit, &txnProfit)
+		gas.AddUint64(&gas, txn.GetGas())
+	}
+	if gas.IsZero() {
+		return &gas
+	}
+	return profit.Div(&profit, &gas)
+}
+
+// OrderingPolicy decides the order in which candidates are offered for inclusion in a block being built.
+// Policies never reorder the transactions of a single sender (they stay in nonce order) nor split bundles.
+type OrderingPolicy interface {
+	Name() string
+	Order(candidates []*OrderingCandidate, baseFee *uint256.Int) []*OrderingCandidate
+}
+
+// NewOrderingPolicy returns the policy with the given name, nil for the default one (i.e. the order of the txn provider).
+// prioritySenders is used by the priority-lanes policy.
+func NewOrderingPolicy(name string, prioritySenders []libcommon.Address) (OrderingPolicy, error) {
+	switch name {
+	case "", DefaultOrderingPolicyName:
+		return nil, nil
+	case FifoOrderingPolicyName:
+		return FifoOrderingPolicy{}, nil
+	case SenderFairnessOrderingPolicyName:
+		return SenderFairnessOrderingPolicy{}, nil
+	case PriorityLanesOrderingPolicyName:
+		if len(prioritySenders) == 0 {
+			return nil, fmt.Errorf("%s txn ordering policy requires priority senders", PriorityLanesOrderingPolicyName)
+		}
+		return NewPriorityLanesOrderingPolicy(prioritySenders), nil
+	case MaxProfitOrderingPolicyName:
+		return MaxProfitOrderingPolicy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown txn ordering policy %q, expected one of: %s", name, strings.Join(OrderingPolicyNames, ", "))
+	}
+}
+
+// FifoOrderingPolicy orders candidates strictly by arrival time. Its candidates are the earliest arrivals of the
+// txn providers rather than their best ones (see WithArrivalOrder).
+type FifoOrderingPolicy struct{}
+
+func (FifoOrderingPolicy) Name() string { return FifoOrderingPolicyName }
+
+func (FifoOrderingPolicy) byArrival() {}
+
+func (FifoOrderingPolicy) Order(candidates []*OrderingCandidate, _ *uint256.Int) []*OrderingCandidate {
+	return orderByHeads(candidates, func(a, b *OrderingCandidate, _ map[libcommon.Address]int) bool {
+		return a.Arrival < b.Arrival
+	})
+}
+
+// SenderFairnessOrderingPolicy takes candidates from each sender in turn (round-robin), so that a single sender
+// can't fill up a block while others are waiting. Senders at the same turn are ordered by arrival time.
+type SenderFairnessOrderingPolicy struct{}
+
+func (SenderFairnessOrderingPolicy) Name() string { return SenderFairnessOrderingPolicyName }
+
+func (SenderFairnessOrderingPolicy) Order(candidates []*OrderingCandidate, _ *uint256.Int) []*OrderingCandidate {
+	return orderByHeads(candidates, func(a, b *OrderingCandidate, picked map[libcommon.Address]int) bool {
+		pickedA, pickedB := picked[a.sender()], picked[b.sender()]
+		if pickedA != pickedB {
+			return pickedA < pickedB
+		}
+		return a.Arrival < b.Arrival
+	})
+}
+
+// MaxProfitOrderingPolicy orders candidates by the effective tip per gas they pay to the block producer,
+// bundles being valued as a whole
+type MaxProfitOrderingPolicy struct{}
+
+func (MaxProfitOrderingPolicy) Name() string { return MaxProfitOrderingPolicyName }
+
+func (MaxProfitOrderingPolicy) Order(candidates []*OrderingCandidate, baseFee *uint256.Int) []*OrderingCandidate {
+	profits := candidateProfits(candidates, baseFee)
+	return orderByHeads(candidates, func(a, b *OrderingCandidate, _ map[libcommon.Address]int) bool {
+		if c := profits[a].Cmp(profits[b]); c != 0 {
+			return c > 0
+		}
+		return a.Arrival < b.Arrival
+	})
+}
+
+// PriorityLanesOrderingPolicy puts the candidates of allow-listed senders first, then everything else.
+// Within a lane, candidates are ordered by profit.
+type PriorityLanesOrderingPolicy struct {
+	prioritySenders map[libcommon.Address]struct{}
+}
+
+func NewPriorityLanesOrderingPolicy(prioritySenders []libcommon.Address) PriorityLanesOrderingPolicy {
+	p := PriorityLanesOrderingPolicy{prioritySenders: make(map[libcommon.Address]struct{}, len(prioritySenders))}
+	for _, sender := range prioritySenders {
+		p.prioritySenders[sender] = struct{}{}
+	}
+	return p
+}
+
+func (PriorityLanesOrderingPolicy) Name() string { return PriorityLanesOrderingPolicyName }
+
+func (p PriorityLanesOrderingPolicy) Order(candidates []*OrderingCandidate, baseFee *uint256.Int) []*OrderingCandidate {
+	profits := candidateProfits(candidates, baseFee)
+	return orderByHeads(candidates, func(a, b *OrderingCandidate, _ map[libcommon.Address]int) bool {
+		_, priorityA := p.prioritySenders[a.sender()]
+		_, priorityB := p.prioritySenders[b.sender()]
+		if priorityA != priorityB {
+			return priorityA
+		}
+		if c := profits[a].Cmp(profits[b]); c != 0 {
+			return c > 0
+		}
+		return a.Arrival < b.Arrival
+	})
+}
+
+func candidateProfits(candidates []*OrderingCandidate, baseFee *uint256.Int) map[*OrderingCandidate]*uint256.Int {
+	profits := make(map[*OrderingCandidate]*uint256.Int, len(candidates))
+	for _, c := range candidates {
+		profits[c] = c.profitPerGas(baseFee)
+	}
+	return profits
+}
+
+// candidateBetter reports whether a should come before b. picked is the number of candidates already ordered per sender.
+type candidateBetter func(a, b *OrderingCandidate, picked map[libcommon.Address]int) bool
+
+// orderByHeads merges the per-sender queues of candidates (kept in nonce order), repeatedly taking the best
+// queue head according to better. Bundles are queues on their own.
+func orderByHeads(candidates []*OrderingCandidate, better candidateBetter) []*OrderingCandidate {
+	var queues [][]*OrderingCandidate
+	bySender := make(map[libcommon.Address]int)
+	for _, c := range candidates {
+		if len(c.Txns) == 0 {
+			continue
+		}
+		if c.isBundle() {
+			queues = append(queues, []*OrderingCandidate{c})
+			continue
+		}
+		sender := c.sender()
+		i, ok := bySender[sender]
+		if !ok {
+			i = len(queues)
+			bySender[sender] = i
+			queues = append(queues, nil)
+		}
+		queues[i] = append(queues[i], c)
+	}
+	for _, q := range queues {
+		sort.SliceStable(q, func(i, j int) bool { return q[i].Txns[0].GetNonce() < q[j].Txns[0].GetNonce() })
+	}
+
+	// a linear scan over the queue heads rather than a heap, as fairness changes the rank of all the heads of a sender
+	ordered := make([]*OrderingCandidate, 0, len(candidates))
+	picked := make(map[libcommon.Address]int)
+	for len(queues) > 0 {
+		best := 0
+		for i := 1; i < len(queues); i++ {
+			if better(queues[i][0], queues[best][0], picked) {
+				best = i
+			}
+		}
+		c := queues[best][0]
+		ordered = append(ordered, c)
+		picked[c.sender()]++
+		if queues[best] = queues[best][1:]; len(queues[best]) == 0 {
+			queues = append(queues[:best], queues[best+1:]...)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,238 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txnprovider
+
+import (
+	"context"
+	"testing"
+
+	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+
+	"github.com/erigontech/erigon/core/types"
+)
+
+var (
+	senderA = libcommon.Address{0xa}
+	senderB = libcommon.Address{0xb}
+	senderC = libcommon.Address{0xc}
+)
+
+// fakePool yields its candidates in the order they were added, honouring the amount and the filter
+type fakePool struct {
+	candidates   []*OrderingCandidate
+	arrivalOrder bool // of the last yield
+}
+
+func (p *fakePool) add(arrival uint64, txns ...types.Transaction) *fakePool {
+	p.candidates = append(p.candidates, &OrderingCandidate{Txns: txns, Arrival: arrival})
+	return p
+}
+
+func (p *fakePool) Priority() uint64 { return 0 }
+
+func (p *fakePool) Yield(ctx context.Context, opts ...YieldOption) ([]types.Transaction, error) {
+	params := yieldParamsFromOptions(opts...)
+	candidates, err := p.YieldCandidates(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var txns []types.Transaction
+	for _, c := range candidates {
+		for _, txn := range c.Txns {
+			params.TxnIdsFilter.Add(txn.Hash())
+		}
+		txns = append(txns, c.Txns...)
+	}
+	return txns, nil
+}
+
+func (p *fakePool) YieldCandidates(_ context.Context, opts ...YieldOption) ([]*OrderingCandidate, error) {
+	params := yieldParamsFromOptions(opts...)
+	p.arrivalOrder = params.ArrivalOrder
+	var candidates []*OrderingCandidate
+	for _, c := range p.candidates {
+		if len(candidates) >= params.Amount {
+			break
+		}
+		if !c.filtered(params) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates, nil
+}
+
+// txnOnlyProvider hides the candidates of the fake pool, as a provider which doesn't know about arrival times
+type txnOnlyProvider struct {
+	pool *fakePool
+}
+
+func (p txnOnlyProvider) Priority() uint64 { return 0 }
+
+func (p txnOnlyProvider) Yield(ctx context.Context, opts ...YieldOption) ([]types.Transaction, error) {
+	return p.pool.Yield(ctx, opts...)
+}
+
+func newTxn(sender libcommon.Address, nonce uint64, tip uint64, gas uint64) types.Transaction {
+	to := libcommon.Address{0xff}
+	txn := &types.DynamicFeeTransaction{
+		CommonTx: types.CommonTx{
+			Nonce: nonce,
+			Gas:   gas,
+			To:    &to,
+			Value: uint256.NewInt(0),
+			// the sender is not part of the hash of unsigned txns, so make it part of the data
+			Data: sender.Bytes(),
+		},
+		ChainID: uint256.NewInt(1),
+		Tip:     uint256.NewInt(tip),
+		FeeCap:  uint256.NewInt(tip + 100),
+	}
+	txn.SetSender(sender)
+	return txn
+}
+
+// withCalldata appends n zero bytes to the data of txn, for its intrinsic gas to grow by 4 gas per byte
+func withCalldata(txn types.Transaction, n int) types.Transaction {
+	dynamicFeeTxn := txn.(*types.DynamicFeeTransaction)
+	dynamicFeeTxn.Data = append(dynamicFeeTxn.Data, make([]byte, n)...)
+	return dynamicFeeTxn
+}
+
+func yieldedNonces(t *testing.T, txns []types.Transaction) []string {
+	t.Helper()
+	res := make([]string, len(txns))
+	for i, txn := range txns {
+		sender, ok := txn.GetSender()
+		require.True(t, ok)
+		res[i] = string(rune('A'+sender[0]-0xa)) + string(rune('0'+txn.GetNonce()))
+	}
+	return res
+}
+
+func yieldAll(t *testing.T, provider TxnProvider, policy OrderingPolicy, opts ...YieldOption) []string {
+	t.Helper()
+	txns, err := YieldWithPolicy(context.Background(), provider, policy, opts...)
+	require.NoError(t, err)
+	return yieldedNonces(t, txns)
+}
+
+func TestFifoOrderingPolicy(t *testing.T) {
+	pool := (&fakePool{}).
+		add(5, newTxn(senderA, 0, 10, 21_000)).
+		add(1, newTxn(senderA, 1, 10, 21_000)).
+		add(2, newTxn(senderB, 0, 1000, 21_000)).
+		add(3, newTxn(senderC, 0, 1, 21_000))
+
+	// A1 arrived first, but has to wait for A0
+	require.Equal(t, []string{"B0", "C0", "A0", "A1"}, yieldAll(t, pool, FifoOrderingPolicy{}, WithTxnIdsFilter(mapset.NewSet[[32]byte]())))
+	// the candidates are the earliest arrivals of the pool, not its best txns
+	require.True(t, pool.arrivalOrder)
+	yieldAll(t, pool, MaxProfitOrderingPolicy{}, WithTxnIdsFilter(mapset.NewSet[[32]byte]()))
+	require.False(t, pool.arrivalOrder)
+	// without arrival times, the provider order is the arrival order
+	require.Equal(t, []string{"A0", "A1", "B0", "C0"}, yieldAll(t, txnOnlyProvider{pool}, FifoOrderingPolicy{}, WithTxnIdsFilter(mapset.NewSet[[32]byte]())))
+}
+
+func TestSenderFairnessOrderingPolicy(t *testing.T) {
+	pool := (&fakePool{}).
+		add(0, newTxn(senderA, 0, 10, 21_000)).
+		add(1, newTxn(senderA, 1, 10, 21_000)).
+		add(2, newTxn(senderA, 2, 10, 21_000)).
+		add(3, newTxn(senderB, 0, 10, 21_000)).
+		add(4, newTxn(senderC, 0, 10, 21_000)).
+		add(5, newTxn(senderB, 1, 10, 21_000))
+
+	require.Equal(t, []string{"A0", "B0", "C0", "A1", "B1", "A2"}, yieldAll(t, pool, SenderFairnessOrderingPolicy{}, WithTxnIdsFilter(mapset.NewSet[[32]byte]())))
+}
+
+func TestPriorityLanesOrderingPolicy(t *testing.T) {
+	pool := (&fakePool{}).
+		add(0, newTxn(senderA, 0, 1000, 21_000)).
+		add(1, newTxn(senderB, 0, 1, 21_000)).
+		add(2, newTxn(senderC, 0, 500, 21_000)).
+		add(3, newTxn(senderB, 1, 2000, 21_000))
+
+	policy, err := NewOrderingPolicy(PriorityLanesOrderingPolicyName, []libcommon.Address{senderB})
+	require.NoError(t, err)
+	require.Equal(t, []string{"B0", "B1", "A0", "C0"}, yieldAll(t, pool, policy, WithTxnIdsFilter(mapset.NewSet[[32]byte]())))
+
+	_, err = NewOrderingPolicy(PriorityLanesOrderingPolicyName, nil)
+	require.Error(t, err)
+}
+
+func TestMaxProfitOrderingPolicyBundles(t *testing.T) {
+	pool := (&fakePool{}).
+		add(0, newTxn(senderA, 0, 100, 21_000)).
+		// bundle paying 150/gas on average
+		add(1, newTxn(senderB, 0, 1, 21_000), newTxn(senderC, 0, 299, 21_000)).
+		add(2, newTxn(senderA, 1, 200, 21_000)).
+		// the most profitable bundle doesn't fit in the gas target, and must not be split
+		add(3, newTxn(senderB, 1, 5000, 21_000), withCalldata(newTxn(senderC, 1, 5000, 1_000_000), 120_000))
+
+	filter := mapset.NewSet[[32]byte]()
+	yielded := yieldAll(t, pool, MaxProfitOrderingPolicy{}, WithGasTarget(500_000), WithTxnIdsFilter(filter))
+	require.Equal(t, []string{"B0", "C0", "A0", "A1"}, yielded)
+	require.Equal(t, 4, filter.Cardinality())
+
+	// next round only has the dropped bundle left
+	require.Empty(t, yieldAll(t, pool, MaxProfitOrderingPolicy{}, WithGasTarget(500_000), WithTxnIdsFilter(filter)))
+	require.Equal(t, []string{"B1", "C1"}, yieldAll(t, pool, MaxProfitOrderingPolicy{}, WithTxnIdsFilter(filter)))
+}
+
+func TestOrderingPolicyAmountAndGasTarget(t *testing.T) {
+	pool := (&fakePool{}).
+		add(0, newTxn(senderA, 0, 10, 21_000)).
+		add(1, newTxn(senderA, 1, 10, 21_000)).
+		add(2, withCalldata(newTxn(senderB, 0, 10, 100_000), 10_000)).
+		add(3, newTxn(senderB, 1, 10, 21_000)).
+		add(4, newTxn(senderC, 0, 10, 21_000))
+
+	filter := mapset.NewSet[[32]byte]()
+	require.Equal(t, []string{"A0", "A1"}, yieldAll(t, pool, FifoOrderingPolicy{}, WithAmount(2), WithTxnIdsFilter(filter)))
+	// B0 doesn't fit, so B1 is skipped as well
+	require.Equal(t, []string{"C0"}, yieldAll(t, pool, FifoOrderingPolicy{}, WithGasTarget(50_000), WithTxnIdsFilter(filter)))
+	require.Equal(t, []string{"B0", "B1"}, yieldAll(t, pool, FifoOrderingPolicy{}, WithTxnIdsFilter(filter)))
+}
+
+func TestOrderingPolicyIntrinsicGas(t *testing.T) {
+	// the gas targets are accounted with the intrinsic gas of the txns, as the txn pool does, not with their gas limit
+	pool := (&fakePool{}).
+		add(0, newTxn(senderA, 0, 10, 30_000_000)).
+		add(1, newTxn(senderB, 0, 10, 30_000_000))
+
+	require.Equal(t, []string{"A0", "B0"}, yieldAll(t, pool, FifoOrderingPolicy{}, WithGasTarget(50_000), WithTxnIdsFilter(mapset.NewSet[[32]byte]())))
+	require.Equal(t, []string{"A0"}, yieldAll(t, pool, FifoOrderingPolicy{}, WithGasTarget(40_000), WithTxnIdsFilter(mapset.NewSet[[32]byte]())))
+}
+
+func TestNewOrderingPolicy(t *testing.T) {
+	for _, name := range []string{"", DefaultOrderingPolicyName} {
+		policy, err := NewOrderingPolicy(name, nil)
+		require.NoError(t, err)
+		require.Nil(t, policy)
+	}
+	for _, name := range []string{FifoOrderingPolicyName, SenderFairnessOrderingPolicyName, MaxProfitOrderingPolicyName} {
+		policy, err := NewOrderingPolicy(name, nil)
+		require.NoError(t, err)
+		require.Equal(t, name, policy.Name())
+	}
+	_, err := NewOrderingPolicy("lifo", nil)
+	require.Error(t, err)
+}
@@ -21,6 +21,7 @@ import (
 	"math"
 
 	mapset "github.com/deckarep/golang-set/v2"
+	"github.com/holiman/uint256"
 
 	"github.com/erigontech/erigon/core/types"
 )
@@ -62,12 +63,36 @@ func WithTxnIdsFilter(txnIdsFilter mapset.Set[[32]byte]) YieldOption {
 	}
 }
 
+func WithBaseFee(baseFee *uint256.Int) YieldOption {
+	return func(opt *yieldParams) {
+		opt.BaseFee = baseFee
+	}
+}
+
+// WithBlockTime is the timestamp of the block being built, used by providers with time constraints
+func WithBlockTime(blockTime uint64) YieldOption {
+	return func(opt *yieldParams) {
+		opt.BlockTime = blockTime
+	}
+}
+
+// WithArrivalOrder asks for the earliest arrived transactions rather than the best ones, to the providers which
+// keep track of arrivals
+func WithArrivalOrder() YieldOption {
+	return func(opt *yieldParams) {
+		opt.ArrivalOrder = true
+	}
+}
+
 type yieldParams struct {
 	ParentBlockNum uint64
 	Amount         int
 	GasTarget      uint64
 	BlobGasTarget  uint64
 	TxnIdsFilter   mapset.Set[[32]byte]
+	BaseFee        *uint256.Int
+	BlockTime      uint64
+	ArrivalOrder   bool
 }
 
 func yieldParamsFromOptions(opts ...YieldOption) yieldParams {
@@ -84,4 +109,7 @@ var defaultYieldParams = yieldParams{
 	GasTarget:      math.MaxUint64,            // all transactions by default
 	BlobGasTarget:  math.MaxUint64,            // all transactions by default
 	TxnIdsFilter:   mapset.NewSet[[32]byte](), // no filter by default
+	BaseFee:        nil,                       // pre-London by default
+	BlockTime:      0,                         // no time constraints by default
+	ArrivalOrder:   false,                     // best transactions by default
 }
@@ -0,0 +1,87 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txnprovider
+
+import (
+	"context"
+
+	"github.com/erigontech/erigon-lib/common"
+
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/txnprovider/bundle"
+)
+
+// bundles go ahead of the public mempool
+const bundleProviderPriority = 120
+
+// BundleProvider is a TxnProvider whose transactions may belong to bundles, which block builders have to include
+// atomically: either all the transactions of a bundle are included without reverting (unless allowed to), or none is.
+type BundleProvider interface {
+	TxnProvider
+	BundleOf(txnHash common.Hash) (*bundle.Bundle, bool)
+	DropBundle(bundleHash common.Hash, reason error)
+}
+
+var _ BundleProvider = BundleTxnProvider{}
+var _ CandidateProvider = BundleTxnProvider{}
+
+// BundleTxnProvider yields the bundles submitted via eth_sendBundle which target the block being built
+type BundleTxnProvider struct {
+	bundlePool *bundle.Pool
+}
+
+func NewBundleTxnProvider(bundlePool *bundle.Pool) BundleTxnProvider {
+	return BundleTxnProvider{bundlePool: bundlePool}
+}
+
+func (p BundleTxnProvider) Priority() uint64 {
+	return bundleProviderPriority
+}
+
+func (p BundleTxnProvider) Yield(ctx context.Context, opts ...YieldOption) ([]types.Transaction, error) {
+	params := yieldParamsFromOptions(opts...)
+	candidates, err := p.YieldCandidates(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	txns, _ := selectCandidates(candidates, params)
+	for _, txn := range txns {
+		params.TxnIdsFilter.Add(txn.Hash())
+	}
+	return txns, nil
+}
+
+func (p BundleTxnProvider) YieldCandidates(_ context.Context, opts ...YieldOption) ([]*OrderingCandidate, error) {
+	params := yieldParamsFromOptions(opts...)
+	var candidates []*OrderingCandidate
+	for _, b := range p.bundlePool.Eligible(params.ParentBlockNum+1, params.BlockTime) {
+		candidate := &OrderingCandidate{Txns: b.Txns, Arrival: b.Arrival()}
+		if candidate.filtered(params) {
+			continue
+		}
+		candidates = append(candidates, candidate)
+	}
+	return candidates, nil
+}
+
+func (p BundleTxnProvider) BundleOf(txnHash common.Hash) (*bundle.Bundle, bool) {
+	return p.bundlePool.BundleOf(txnHash)
+}
+
+func (p BundleTxnProvider) DropBundle(bundleHash common.Hash, reason error) {
+	p.bundlePool.Drop(bundleHash, reason)
+}
@@ -18,26 +18,117 @@ package txnprovider
 
 import (
 	"context"
+	"sort"
+
+	"github.com/erigontech/erigon-lib/common"
 
 	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/txnprovider/bundle"
 )
 
-var _ TxnProvider = CompositeTxnProvider{}
+var _ CandidateProvider = CompositeTxnProvider{}
+var _ BundleProvider = CompositeTxnProvider{}
 
-// CompositeTxnProvider provides the ability to fetch transactions from multiple transaction providers. It uses the
-// priority of each provider to impose an ordering of which provider to use first. Once a provider is exhausted (i.e.
-// it has no more transactions to yield) then the composite provider moves on to the next lower priority provider
-// and continues doing this until all providers are exhausted or the relevant amount of transactions, gas target,
-// blob gas target, etc. have been reached.
+// CompositeTxnProvider yields the transactions of its providers, in the order of their priority (highest first),
+// sharing the amount and gas targets between them
 type CompositeTxnProvider struct {
+	providers []TxnProvider
+}
+
+func NewCompositeTxnProvider(providers ...TxnProvider) CompositeTxnProvider {
+	sorted := make([]TxnProvider, len(providers))
+	copy(sorted, providers)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Priority() > sorted[j].Priority() })
+	return CompositeTxnProvider{providers: sorted}
 }
 
 func (c CompositeTxnProvider) Priority() uint64 {
-	//TODO implement me
-	panic("implement me")
+	if len(c.providers) == 0 {
+		return 0
+	}
+	return c.providers[0].Priority()
+}
+
+func (c CompositeTxnProvider) Yield(ctx context.Context, opts ...YieldOption) ([]types.Transaction, error) {
+	params := yieldParamsFromOptions(opts...)
+	var txns []types.Transaction
+	for _, provider := range c.providers {
+		if len(txns) >= params.Amount {
+			break
+		}
+		providerTxns, err := provider.Yield(ctx, c.remainingOpts(opts, params, txns)...)
+		if err != nil {
+			return nil, err
+		}
+		txns = append(txns, providerTxns...)
+	}
+	return txns, nil
+}
+
+func (c CompositeTxnProvider) YieldCandidates(ctx context.Context, opts ...YieldOption) ([]*OrderingCandidate, error) {
+	params := yieldParamsFromOptions(opts...)
+	// candidates are not marked as yielded in the caller's filter, but they must not be yielded twice either
+	filter := params.TxnIdsFilter.Clone()
+	var candidates []*OrderingCandidate
+	var txns []types.Transaction
+	for _, provider := range c.providers {
+		if len(txns) >= params.Amount {
+			break
+		}
+		providerOpts := append(c.remainingOpts(opts, params, txns), WithTxnIdsFilter(filter))
+		var providerCandidates []*OrderingCandidate
+		if cp, ok := provider.(CandidateProvider); ok {
+			var err error
+			if providerCandidates, err = cp.YieldCandidates(ctx, providerOpts...); err != nil {
+				return nil, err
+			}
+		} else {
+			providerTxns, err := provider.Yield(ctx, providerOpts...)
+			if err != nil {
+				return nil, err
+			}
+			for i, txn := range providerTxns {
+				providerCandidates = append(providerCandidates, &OrderingCandidate{Txns: []types.Transaction{txn}, Arrival: uint64(i)})
+			}
+		}
+		for _, candidate := range providerCandidates {
+			for _, txn := range candidate.Txns {
+				filter.Add(txn.Hash())
+			}
+			txns = append(txns, candidate.Txns...)
+		}
+		candidates = append(candidates, providerCandidates...)
+	}
+	return candidates, nil
+}
+
+func (c CompositeTxnProvider) BundleOf(txnHash common.Hash) (*bundle.Bundle, bool) {
+	for _, provider := range c.providers {
+		if bp, ok := provider.(BundleProvider); ok {
+			if b, ok := bp.BundleOf(txnHash); ok {
+				return b, true
+			}
+		}
+	}
+	return nil, false
+}
+
+func (c CompositeTxnProvider) DropBundle(bundleHash common.Hash, reason error) {
+	for _, provider := range c.providers {
+		if bp, ok := provider.(BundleProvider); ok {
+			bp.DropBundle(bundleHash, reason)
+		}
+	}
 }
 
-func (c CompositeTxnProvider) Yield(_ context.Context, _ ...YieldOption) ([]types.Transaction, error) {
-	//TODO implement me
-	panic("implement me")
+// remainingOpts are the options for the next provider, given what was yielded by the previous ones
+func (c CompositeTxnProvider) remainingOpts(opts []YieldOption, params yieldParams, yielded []types.Transaction) []YieldOption {
+	gasTarget, blobGasTarget := params.GasTarget, params.BlobGasTarget
+	for _, txn := range yielded {
+		gasTarget -= min(gasTarget, intrinsicGas(txn))
+		blobGasTarget -= min(blobGasTarget, txn.GetBlobGas())
+	}
+	remaining := make([]YieldOption, 0, len(opts)+3)
+	remaining = append(remaining, opts...)
+	return append(remaining, WithAmount(params.Amount-len(yielded)), WithGasTarget(gasTarget), WithBlobGasTarget(blobGasTarget))
 }
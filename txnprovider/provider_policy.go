@@ -0,0 +1,134 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txnprovider
+
+import (
+	"context"
+	"math"
+	"time"
+
+	libcommon "github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/metrics"
+
+	"github.com/erigontech/erigon/core/types"
+)
+
+// policyCandidatesWindow is how many more candidates than requested are fetched from the provider,
+// to give the ordering policy something to choose from
+const policyCandidatesWindow = 4
+
+// CandidateProvider is a TxnProvider which can also yield candidates with the metadata ordering policies need
+// (arrival order, bundles). The options have the same meaning as for Yield, except that the TxnIdsFilter is only
+// read: marking the yielded transactions is left to the caller.
+type CandidateProvider interface {
+	TxnProvider
+	YieldCandidates(ctx context.Context, opts ...YieldOption) ([]*OrderingCandidate, error)
+}
+
+// arrivalOrderingPolicy is an OrderingPolicy which looks at arrivals only, so which needs the earliest candidates
+// of the provider rather than its best ones
+type arrivalOrderingPolicy interface {
+	OrderingPolicy
+	byArrival()
+}
+
+// YieldWithPolicy yields transactions from the provider in the order decided by the policy, within the amount and
+// gas targets of the options. Bundles are either yielded whole or not at all. If the provider can't yield
+// candidates, its transactions are ordered as if they had arrived in the order it yields them.
+func YieldWithPolicy(ctx context.Context, provider TxnProvider, policy OrderingPolicy, opts ...YieldOption) ([]types.Transaction, error) {
+	params := yieldParamsFromOptions(opts...)
+	start := time.Now()
+
+	window := params.Amount
+	if window <= math.MaxInt/policyCandidatesWindow {
+		window *= policyCandidatesWindow
+	}
+	windowOpts := make([]YieldOption, 0, len(opts)+2)
+	windowOpts = append(windowOpts, opts...)
+	windowOpts = append(windowOpts, WithAmount(window))
+	if _, ok := policy.(arrivalOrderingPolicy); ok {
+		windowOpts = append(windowOpts, WithArrivalOrder())
+	}
+
+	var candidates []*OrderingCandidate
+	if cp, ok := provider.(CandidateProvider); ok {
+		var err error
+		if candidates, err = cp.YieldCandidates(ctx, windowOpts...); err != nil {
+			return nil, err
+		}
+	} else {
+		// the filter is marked by Yield, so it has to be copied to not lose the candidates the policy leaves out
+		filter := params.TxnIdsFilter.Clone()
+		txns, err := provider.Yield(ctx, append(windowOpts, WithTxnIdsFilter(filter))...)
+		if err != nil {
+			return nil, err
+		}
+		candidates = make([]*OrderingCandidate, len(txns))
+		for i, txn := range txns {
+			candidates[i] = &OrderingCandidate{Txns: []types.Transaction{txn}, Arrival: uint64(i)}
+		}
+	}
+
+	ordered := policy.Order(candidates, params.BaseFee)
+	txns, droppedBundles := selectCandidates(ordered, params)
+	for _, txn := range txns {
+		params.TxnIdsFilter.Add(txn.Hash())
+	}
+	metrics.UpdateBlockProducerTxnOrdering(policy.Name(), start, len(candidates), len(txns), droppedBundles)
+	return txns, nil
+}
+
+// selectCandidates takes the ordered candidates while they fit in the amount and gas targets. Once a candidate of
+// a sender is left out, so are the later ones of the same sender, which would have a nonce gap.
+func selectCandidates(ordered []*OrderingCandidate, params yieldParams) (txns []types.Transaction, droppedBundles int) {
+	gasLeft, blobGasLeft := params.GasTarget, params.BlobGasTarget
+	skippedSenders := make(map[libcommon.Address]struct{})
+	for _, c := range ordered {
+		if len(txns) >= params.Amount {
+			break
+		}
+		sender := c.sender()
+		if _, skipped := skippedSenders[sender]; skipped && !c.isBundle() {
+			continue
+		}
+		if c.filtered(params) {
+			continue
+		}
+		gas, blobGas := c.gas()
+		if gas > gasLeft || blobGas > blobGasLeft || len(txns)+len(c.Txns) > params.Amount {
+			if c.isBundle() {
+				droppedBundles++
+			} else {
+				skippedSenders[sender] = struct{}{}
+			}
+			continue
+		}
+		gasLeft -= gas
+		blobGasLeft -= blobGas
+		txns = append(txns, c.Txns...)
+	}
+	return txns, droppedBundles
+}
+
+func (c *OrderingCandidate) filtered(params yieldParams) bool {
+	for _, txn := range c.Txns {
+		if params.TxnIdsFilter.Contains(txn.Hash()) {
+			return true
+		}
+	}
+	return false
+}
@@ -19,13 +19,17 @@ package txnprovider
 import (
 	"context"
 
+	mapset "github.com/deckarep/golang-set/v2"
+
+	"github.com/erigontech/erigon-lib/common"
+
 	"github.com/erigontech/erigon/core/types"
 	"github.com/erigontech/erigon/txnprovider/txpool"
 )
 
 const orderedTxnPoolProviderPriority = 100
 
-var _ TxnProvider = OrderedTxnPoolProvider{}
+var _ CandidateProvider = OrderedTxnPoolProvider{}
 
 // OrderedTxnPoolProvider provides transactions from the devp2p transaction pool by following the ordering function
 // described at: https://github.com/erigontech/erigon/tree/main/txnprovider/txpool#ordering-function
@@ -45,12 +49,41 @@ func (p OrderedTxnPoolProvider) Priority() uint64 {
 
 func (p OrderedTxnPoolProvider) Yield(ctx context.Context, opts ...YieldOption) ([]types.Transaction, error) {
 	params := yieldParamsFromOptions(opts...)
-	return p.txnPool.YieldBestTxns(
+	return p.yield(ctx, params, params.TxnIdsFilter)
+}
+
+func (p OrderedTxnPoolProvider) YieldCandidates(ctx context.Context, opts ...YieldOption) ([]*OrderingCandidate, error) {
+	params := yieldParamsFromOptions(opts...)
+	// the pool marks what it yields, but the caller decides what is actually used
+	txns, err := p.yield(ctx, params, params.TxnIdsFilter.Clone())
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]common.Hash, len(txns))
+	for i, txn := range txns {
+		hashes[i] = txn.Hash()
+	}
+	arrivals := p.txnPool.TxnArrivals(hashes)
+	candidates := make([]*OrderingCandidate, len(txns))
+	for i, txn := range txns {
+		candidates[i] = &OrderingCandidate{Txns: []types.Transaction{txn}, Arrival: arrivals[i]}
+	}
+	return candidates, nil
+}
+
+func (p OrderedTxnPoolProvider) yield(ctx context.Context, params yieldParams, txnIdsFilter mapset.Set[[32]byte]) ([]types.Transaction, error) {
+	yield := p.txnPool.YieldBestTxns
+	if params.ArrivalOrder {
+		yield = p.txnPool.YieldEarliestTxns
+	}
+	return yield(
 		ctx,
 		params.Amount,
 		params.ParentBlockNum,
 		params.GasTarget,
 		params.BlobGasTarget,
-		params.TxnIdsFilter,
+		params.BlockTime,
+		txnIdsFilter,
 	)
 }
@@ -0,0 +1,265 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/dir"
+	"github.com/erigontech/erigon-lib/rlp"
+)
+
+const blobStoreFileExt = ".rlp"
+
+// BlobStore keeps blob txns in their network form - with blobs, commitments and proofs - on disk, one file
+// per txn, so that the pool keeps only their metadata in memory. It also keeps the txns of the recently mined
+// blocks, to re-add them with their sidecars on reorg.
+// With an empty dir the txns are kept in memory.
+//
+// Put and Delete don't touch the disk: the txns put are kept in memory until the next flush, which writes and
+// fsyncs their files and removes the files of the deleted txns. The pool flushes the store along with its db,
+// with the file I/O out of its lock (see beginFlush).
+//
+// BlobStore is not thread-safe: the pool uses it under its lock, only the files are read out of it (see lookup).
+type BlobStore struct {
+	dir     string
+	sizes   map[string]uint64 // txn_hash => size of the txn rlp
+	size    uint64
+	unsaved map[string][]byte   // txn_hash => txn rlp : not written to dir yet, or dir is empty
+	saving  map[string][]byte   // txn_hash => txn rlp : being written by a flush
+	removed map[string]struct{} // txn_hash : deleted txns whose file is still to be removed
+}
+
+func OpenBlobStore(dirPath string) (*BlobStore, error) {
+	s := &BlobStore{dir: dirPath, sizes: map[string]uint64{}, unsaved: map[string][]byte{}, removed: map[string]struct{}{}}
+	if dirPath == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dirPath, 0o755); err != nil {
+		return nil, err
+	}
+	entries, err := dir.ReadDir(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		hash, err := hex.DecodeString(strings.TrimSuffix(name, blobStoreFileExt))
+		if e.IsDir() || !strings.HasSuffix(name, blobStoreFileExt) || err != nil || len(hash) != 32 {
+			// leftover of an interrupted flush
+			if err := os.RemoveAll(filepath.Join(dirPath, name)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		s.sizes[string(hash)] = uint64(info.Size())
+		s.size += uint64(info.Size())
+	}
+	return s, nil
+}
+
+func blobStorePath(dirPath string, hash []byte) string {
+	return filepath.Join(dirPath, hex.EncodeToString(hash)+blobStoreFileExt)
+}
+
+func (s *BlobStore) Has(hash []byte) bool {
+	_, ok := s.sizes[string(hash)]
+	return ok
+}
+
+// Get returns the rlp of the txn, or nil if it's not in the store
+func (s *BlobStore) Get(hash []byte) ([]byte, error) {
+	return s.lookup(hash).read()
+}
+
+// blobStoreRead is a txn looked up in the blob store, whose file is read by read. The lookup is done under the
+// lock of the store's user and read can run without it, for the file I/O not to stall the pool.
+type blobStoreRead struct {
+	rlp  []byte // the txn is in memory
+	path string // the txn is in this file
+}
+
+func (s *BlobStore) lookup(hash []byte) blobStoreRead {
+	if !s.Has(hash) {
+		return blobStoreRead{}
+	}
+	if txnRlp, ok := s.unsaved[string(hash)]; ok {
+		return blobStoreRead{rlp: txnRlp}
+	}
+	if txnRlp, ok := s.saving[string(hash)]; ok {
+		return blobStoreRead{rlp: txnRlp}
+	}
+	return blobStoreRead{path: blobStorePath(s.dir, hash)}
+}
+
+// read returns the rlp of the txn looked up, or nil if it was not in the store or has been removed since
+func (r blobStoreRead) read() ([]byte, error) {
+	if r.path == "" {
+		return r.rlp, nil
+	}
+	txnRlp, err := os.ReadFile(r.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("blob store: %w", err)
+	}
+	return txnRlp, nil
+}
+
+func (s *BlobStore) Put(hash []byte, txnRlp []byte) {
+	if s.Has(hash) {
+		return
+	}
+	s.unsaved[string(hash)] = common.Copy(txnRlp)
+	delete(s.removed, string(hash))
+	s.sizes[string(hash)] = uint64(len(txnRlp))
+	s.size += uint64(len(txnRlp))
+}
+
+func (s *BlobStore) Delete(hash []byte) {
+	size, ok := s.sizes[string(hash)]
+	if !ok {
+		return
+	}
+	delete(s.unsaved, string(hash))
+	if s.dir != "" {
+		s.removed[string(hash)] = struct{}{}
+	}
+	delete(s.sizes, string(hash))
+	s.size -= size
+}
+
+// Prune deletes the txns for which keep returns false
+func (s *BlobStore) Prune(keep func(hash []byte) bool) {
+	for hash := range s.sizes {
+		if !keep([]byte(hash)) {
+			s.Delete([]byte(hash))
+		}
+	}
+}
+
+// Size is the total size of the stored txns, in bytes
+func (s *BlobStore) Size() uint64 { return s.size }
+
+func (s *BlobStore) Len() int { return len(s.sizes) }
+
+// Flush writes the txns put since the last flush to their files and removes the files of the deleted txns
+func (s *BlobStore) Flush() error {
+	f := s.beginFlush()
+	err := f.write()
+	s.endFlush(f, err)
+	return err
+}
+
+// blobStoreFlush are the changes of the blob store taken by beginFlush, to be written to disk
+type blobStoreFlush struct {
+	dir     string
+	writes  map[string][]byte
+	removes map[string]struct{}
+}
+
+// beginFlush takes the changes since the last flush. Their file I/O is done by write, which doesn't touch the
+// store and can run without the lock of the store's user. The txns being written are still read from memory
+// until endFlush. Flushes must not overlap.
+func (s *BlobStore) beginFlush() *blobStoreFlush {
+	if s.dir == "" {
+		return &blobStoreFlush{}
+	}
+	f := &blobStoreFlush{dir: s.dir, writes: s.unsaved, removes: s.removed}
+	s.saving, s.unsaved, s.removed = s.unsaved, map[string][]byte{}, map[string]struct{}{}
+	return f
+}
+
+// write writes and fsyncs the files of the txns put, and removes the files of the deleted ones
+func (f *blobStoreFlush) write() error {
+	if len(f.writes) == 0 && len(f.removes) == 0 {
+		return nil
+	}
+	for hash, txnRlp := range f.writes {
+		// write and rename, to never leave a partially written txn behind
+		path := blobStorePath(f.dir, []byte(hash))
+		if err := dir.WriteFileWithFsync(path+".tmp", txnRlp, 0o644); err != nil {
+			return fmt.Errorf("blob store: %w", err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return fmt.Errorf("blob store: %w", err)
+		}
+	}
+	for hash := range f.removes {
+		if err := os.Remove(blobStorePath(f.dir, []byte(hash))); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("blob store: %w", err)
+		}
+	}
+	// make the renames and the removals durable
+	d, err := os.Open(f.dir)
+	if err != nil {
+		return fmt.Errorf("blob store: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("blob store: %w", err)
+	}
+	return nil
+}
+
+// endFlush completes the flush f, with the outcome err of its write: the txns written are read from their
+// files from now on. On error, the changes which are still relevant are kept for the next flush.
+func (s *BlobStore) endFlush(f *blobStoreFlush, err error) {
+	s.saving = nil
+	if err == nil {
+		return
+	}
+	for hash, txnRlp := range f.writes {
+		if _, ok := s.unsaved[hash]; !ok && s.Has([]byte(hash)) {
+			s.unsaved[hash] = txnRlp
+		}
+	}
+	for hash := range f.removes {
+		if !s.Has([]byte(hash)) {
+			s.removed[hash] = struct{}{}
+		}
+	}
+}
+
+// unwrapBlobTxnRlp strips the blobs, commitments and proofs off the network form of a blob txn:
+// type || rlp([txn_payload_body, blobs, commitments, proofs]) => type || rlp(txn_payload_body)
+func unwrapBlobTxnRlp(wrapped []byte) ([]byte, error) {
+	if len(wrapped) == 0 || wrapped[0] != BlobTxnType {
+		return nil, fmt.Errorf("%w: not a blob txn", ErrParseTxn)
+	}
+	wrapperPos, _, err := rlp.ParseList(wrapped, 1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrapped blob tx: %s", ErrParseTxn, err) //nolint
+	}
+	bodyPos, bodyLen, err := rlp.ParseList(wrapped, wrapperPos)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrapped blob tx body: %s", ErrParseTxn, err) //nolint
+	}
+	unwrapped := make([]byte, 0, 1+bodyPos+bodyLen-wrapperPos)
+	unwrapped = append(unwrapped, BlobTxnType)
+	return append(unwrapped, wrapped[wrapperPos:bodyPos+bodyLen]...), nil
+}
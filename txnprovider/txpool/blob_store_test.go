@@ -0,0 +1,135 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/erigontech/erigon/core/types/typestest"
+)
+
+func TestBlobStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenBlobStore(dir)
+	require.NoError(t, err)
+
+	h1, h2 := make([]byte, 32), make([]byte, 32)
+	h1[0], h2[0] = 1, 2
+	s.Put(h1, []byte{1, 2, 3})
+	s.Put(h2, []byte{4, 5})
+	s.Put(h2, []byte{4, 5})
+	require.Equal(t, 2, s.Len())
+	require.Equal(t, uint64(5), s.Size())
+
+	// the txns put are served from memory until flushed
+	v, err := s.Get(h1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, v)
+	_, err = os.Stat(blobStorePath(dir, h1))
+	require.True(t, os.IsNotExist(err))
+	require.NoError(t, s.Flush())
+	_, err = os.Stat(blobStorePath(dir, h1))
+	require.NoError(t, err)
+
+	// leftovers of an interrupted flush are removed on open
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "junk.rlp.tmp"), []byte{1}, 0o644))
+	s, err = OpenBlobStore(dir)
+	require.NoError(t, err)
+	require.Equal(t, 2, s.Len())
+	require.Equal(t, uint64(5), s.Size())
+	v, err = s.Get(h1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, v)
+	_, err = os.Stat(filepath.Join(dir, "junk.rlp.tmp"))
+	require.True(t, os.IsNotExist(err))
+
+	s.Prune(func(hash []byte) bool { return hash[0] == 2 })
+	require.False(t, s.Has(h1))
+	v, err = s.Get(h1)
+	require.NoError(t, err)
+	require.Nil(t, v)
+	require.Equal(t, uint64(2), s.Size())
+
+	// a lookup is read after the txn left the store, as when the file is read out of the pool lock
+	r := s.lookup(h2)
+	s.Delete(h2)
+	s.Delete(h2)
+	require.Zero(t, s.Len())
+	require.Zero(t, s.Size())
+	v, err = r.read()
+	require.NoError(t, err)
+	require.Equal(t, []byte{4, 5}, v)
+
+	// the files of the deleted txns are removed by the flush
+	_, err = os.Stat(blobStorePath(dir, h2))
+	require.NoError(t, err)
+	require.NoError(t, s.Flush())
+	_, err = os.Stat(blobStorePath(dir, h2))
+	require.True(t, os.IsNotExist(err))
+	v, err = r.read()
+	require.NoError(t, err)
+	require.Nil(t, v)
+	s, err = OpenBlobStore(dir)
+	require.NoError(t, err)
+	require.Zero(t, s.Len())
+}
+
+func TestBlobStoreFlushFailure(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenBlobStore(dir)
+	require.NoError(t, err)
+
+	h1, h2 := make([]byte, 32), make([]byte, 32)
+	h1[0], h2[0] = 1, 2
+	s.Put(h1, []byte{1, 2, 3})
+	s.Put(h2, []byte{4, 5})
+	f := s.beginFlush()
+	// changes made during the flush are kept for the next one
+	s.Delete(h2)
+	s.endFlush(f, os.ErrPermission)
+	v, err := s.Get(h1)
+	require.NoError(t, err)
+	require.Equal(t, []byte{1, 2, 3}, v)
+	require.False(t, s.Has(h2))
+
+	require.NoError(t, s.Flush())
+	s, err = OpenBlobStore(dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, s.Len())
+	require.True(t, s.Has(h1))
+}
+
+func TestUnwrapBlobTxnRlp(t *testing.T) {
+	wrapped, _ := typestest.MakeBlobTxnRlp()
+	unwrapped, err := unwrapBlobTxnRlp(wrapped)
+	require.NoError(t, err)
+
+	parseCtx := NewTxnParseContext(*uint256.NewInt(5))
+	parseCtx.WithSender(false)
+	var wrappedTxn, unwrappedTxn TxnSlot
+	_, err = parseCtx.ParseTransaction(wrapped, 0, &wrappedTxn, nil, false, true, nil)
+	require.NoError(t, err)
+	_, err = parseCtx.ParseTransaction(unwrapped, 0, &unwrappedTxn, nil, false, false, nil)
+	require.NoError(t, err)
+	require.Equal(t, wrappedTxn.IDHash, unwrappedTxn.IDHash)
+	require.Empty(t, unwrappedTxn.Blobs)
+}
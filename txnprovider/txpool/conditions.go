@@ -0,0 +1,215 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/common/length"
+	"github.com/erigontech/erigon-lib/gointerfaces"
+	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
+	"github.com/erigontech/erigon-lib/kv"
+)
+
+// MaxConditionSlots limits the number of storage slots a conditional transaction may depend on,
+// as each of them is re-read on every new block
+const MaxConditionSlots = 1000
+
+var (
+	ErrConditionsBounds   = errors.New("conditional transaction: min bound is above max bound")
+	ErrConditionsTooLarge = fmt.Errorf("conditional transaction: more than %d storage slots", MaxConditionSlots)
+)
+
+// TxnConditions are the preconditions of a transaction submitted with eth_sendRawTransactionConditional.
+// The transaction is only yielded for a block while they hold and is dropped once they can't hold anymore.
+// Zero block number and timestamp bounds are unset.
+//
+// The pool can't compute storage roots: StorageRoots are checked by the caller against block StorageRootsBlock,
+// the pool then drops the txn as soon as a later block changes the storage of one of these accounts.
+type TxnConditions struct {
+	KnownAccounts     map[common.Address]map[common.Hash]common.Hash // account => storage slot => expected value
+	StorageRoots      map[common.Address]common.Hash                 // account => expected storage root
+	StorageRootsBlock uint64                                         // block StorageRoots were checked against
+	BlockNumberMin    uint64
+	BlockNumberMax    uint64
+	TimestampMin      uint64
+	TimestampMax      uint64
+}
+
+func (c *TxnConditions) Validate() error {
+	if c.BlockNumberMax != 0 && c.BlockNumberMin > c.BlockNumberMax {
+		return ErrConditionsBounds
+	}
+	if c.TimestampMax != 0 && c.TimestampMin > c.TimestampMax {
+		return ErrConditionsBounds
+	}
+	slots := len(c.StorageRoots)
+	for _, accountSlots := range c.KnownAccounts {
+		slots += len(accountSlots)
+	}
+	if slots > MaxConditionSlots {
+		return ErrConditionsTooLarge
+	}
+	return nil
+}
+
+// expired returns true when the bounds exclude blockNum and every later block.
+// blockTime == 0 skips the timestamp check.
+func (c *TxnConditions) expired(blockNum, blockTime uint64) bool {
+	if c.BlockNumberMax != 0 && blockNum > c.BlockNumberMax {
+		return true
+	}
+	return blockTime != 0 && c.TimestampMax != 0 && blockTime > c.TimestampMax
+}
+
+// includable returns true when the bounds allow the txn into block blockNum.
+// blockTime == 0 skips the timestamp check.
+func (c *TxnConditions) includable(blockNum, blockTime uint64) bool {
+	if c.expired(blockNum, blockTime) || blockNum < c.BlockNumberMin {
+		return false
+	}
+	return blockTime == 0 || blockTime >= c.TimestampMin
+}
+
+// storageMatches compares the known accounts slots with the latest state. Storage is read from
+// the chain db directly: the state cache of the pool doesn't follow storage changes.
+func (c *TxnConditions) storageMatches(tx kv.Tx) (bool, error) {
+	if len(c.KnownAccounts) == 0 {
+		return true, nil
+	}
+	ttx, ok := tx.(kv.TemporalTx)
+	if !ok {
+		return false, errors.New("conditional transaction: storage conditions need a temporal chain db")
+	}
+	key := make([]byte, length.Addr+length.Hash)
+	for addr, slots := range c.KnownAccounts {
+		copy(key, addr[:])
+		for slot, expected := range slots {
+			copy(key[length.Addr:], slot[:])
+			v, _, err := ttx.GetLatest(kv.StorageDomain, key)
+			if err != nil {
+				return false, err
+			}
+			if common.BytesToHash(v) != expected {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
+
+// storageRootsChanged returns true if stateChanges touch the storage of an account with a storage root condition
+// after StorageRootsBlock, or unwind below it. nil stateChanges stand for unknown changes, e.g. the blocks processed
+// while the pool was down.
+func (c *TxnConditions) storageRootsChanged(stateChanges *remote.StateChangeBatch) bool {
+	if len(c.StorageRoots) == 0 {
+		return false
+	}
+	if stateChanges == nil {
+		return true
+	}
+	for _, diff := range stateChanges.ChangeBatch {
+		if diff.Direction == remote.Direction_FORWARD && diff.BlockHeight <= c.StorageRootsBlock {
+			continue // already seen by the caller which checked the roots
+		}
+		for _, change := range diff.Changes {
+			if len(change.StorageChanges) == 0 && change.Action != remote.Action_REMOVE {
+				continue
+			}
+			if _, ok := c.StorageRoots[gointerfaces.ConvertH160toAddress(change.Address)]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// encode serializes the conditions for kv.PoolTxnConditions as
+// min/max block, min/max timestamp, roots count+(address+root)..., then address+slots count+(slot+value)... per account
+func (c *TxnConditions) encode() []byte {
+	size := 4*8 + 4 + len(c.StorageRoots)*(length.Addr+length.Hash)
+	for _, slots := range c.KnownAccounts {
+		size += length.Addr + 4 + len(slots)*2*length.Hash
+	}
+	v := make([]byte, 0, size)
+	v = binary.BigEndian.AppendUint64(v, c.BlockNumberMin)
+	v = binary.BigEndian.AppendUint64(v, c.BlockNumberMax)
+	v = binary.BigEndian.AppendUint64(v, c.TimestampMin)
+	v = binary.BigEndian.AppendUint64(v, c.TimestampMax)
+	v = binary.BigEndian.AppendUint32(v, uint32(len(c.StorageRoots)))
+	for addr, root := range c.StorageRoots {
+		v = append(v, addr[:]...)
+		v = append(v, root[:]...)
+	}
+	for addr, slots := range c.KnownAccounts {
+		v = append(v, addr[:]...)
+		v = binary.BigEndian.AppendUint32(v, uint32(len(slots)))
+		for slot, value := range slots {
+			v = append(v, slot[:]...)
+			v = append(v, value[:]...)
+		}
+	}
+	return v
+}
+
+func decodeTxnConditions(v []byte) (*TxnConditions, error) {
+	if len(v) < 4*8+4 {
+		return nil, fmt.Errorf("conditional transaction: encoded conditions too short: %d", len(v))
+	}
+	c := &TxnConditions{
+		BlockNumberMin: binary.BigEndian.Uint64(v[0:]),
+		BlockNumberMax: binary.BigEndian.Uint64(v[8:]),
+		TimestampMin:   binary.BigEndian.Uint64(v[16:]),
+		TimestampMax:   binary.BigEndian.Uint64(v[24:]),
+	}
+	roots := int(binary.BigEndian.Uint32(v[32:]))
+	v = v[36:]
+	if len(v) < roots*(length.Addr+length.Hash) {
+		return nil, errors.New("conditional transaction: truncated storage roots")
+	}
+	if roots > 0 {
+		c.StorageRoots = make(map[common.Address]common.Hash, roots)
+	}
+	for i := 0; i < roots; i++ {
+		c.StorageRoots[common.BytesToAddress(v[:length.Addr])] = common.BytesToHash(v[length.Addr : length.Addr+length.Hash])
+		v = v[length.Addr+length.Hash:]
+	}
+	for len(v) > 0 {
+		if len(v) < length.Addr+4 {
+			return nil, errors.New("conditional transaction: truncated known account")
+		}
+		addr := common.BytesToAddress(v[:length.Addr])
+		n := int(binary.BigEndian.Uint32(v[length.Addr:]))
+		v = v[length.Addr+4:]
+		if len(v) < n*2*length.Hash {
+			return nil, errors.New("conditional transaction: truncated known account slots")
+		}
+		if c.KnownAccounts == nil {
+			c.KnownAccounts = map[common.Address]map[common.Hash]common.Hash{}
+		}
+		slots := make(map[common.Hash]common.Hash, n)
+		for i := 0; i < n; i++ {
+			slots[common.BytesToHash(v[:length.Hash])] = common.BytesToHash(v[length.Hash : 2*length.Hash])
+			v = v[2*length.Hash:]
+		}
+		c.KnownAccounts[addr] = slots
+	}
+	return c, nil
+}
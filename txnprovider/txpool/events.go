@@ -0,0 +1,222 @@
+// Copyright 2024 The Erigon Authors
+// This file is part of Erigon.
+//
+// Erigon is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// Erigon is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with Erigon. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/erigontech/erigon-lib/common"
+	"github.com/erigontech/erigon-lib/kv"
+	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/txnprovider/txpool/txpoolcfg"
+)
+
+const (
+	// TxnStatusHistoryLimit is the number of txns whose lifecycle events are kept for txpool_getTransactionStatus
+	TxnStatusHistoryLimit = 10_000
+	// MaxTxnStatusEvents is the number of the latest events kept per txn
+	MaxTxnStatusEvents = 16
+	// txnEventsSubscriptionBuffer is the number of events buffered per subscriber, slower subscribers miss events
+	txnEventsSubscriptionBuffer = 4096
+)
+
+type TxnEventType uint8
+
+const (
+	TxnAdded     TxnEventType = 0 // entered the pool, always to the queued sub-pool first
+	TxnPromoted  TxnEventType = 1 // moved to a better sub-pool
+	TxnDemoted   TxnEventType = 2 // moved to a worse sub-pool
+	TxnReplaced  TxnEventType = 3 // replaced by a txn with the same sender and nonce and a higher tip
+	TxnDiscarded TxnEventType = 4 // dropped from the pool, see DiscardReason
+	TxnMined     TxnEventType = 5 // included in a block
+)
+
+func (t TxnEventType) String() string {
+	switch t {
+	case TxnAdded:
+		return "added"
+	case TxnPromoted:
+		return "promoted"
+	case TxnDemoted:
+		return "demoted"
+	case TxnReplaced:
+		return "replaced"
+	case TxnDiscarded:
+		return "discarded"
+	case TxnMined:
+		return "mined"
+	}
+	return fmt.Sprintf("unknown:%d", t)
+}
+
+// TxnEvent is a step of a txn lifecycle in the pool
+type TxnEvent struct {
+	Type          TxnEventType
+	Hash          common.Hash
+	SubPool       SubPoolType             // sub-pool the txn is in after TxnAdded, TxnPromoted and TxnDemoted
+	ReplacedBy    common.Hash             // hash of the replacing txn for TxnReplaced
+	DiscardReason txpoolcfg.DiscardReason // set for TxnDiscarded
+	BlockNumber   uint64                  // block being applied by the pool when the event happened, or the last seen one
+	Timestamp     uint64                  // unix seconds
+}
+
+const txnEventLen = 1 + 1 + 1 + 8 + 8 + 32 + 32
+
+func encodeTxnEvents(events []TxnEvent) []byte {
+	v := make([]byte, len(events)*txnEventLen)
+	for i := range events {
+		e, b := &events[i], v[i*txnEventLen:]
+		b[0], b[1], b[2] = byte(e.Type), byte(e.SubPool), byte(e.DiscardReason)
+		binary.BigEndian.PutUint64(b[3:], e.BlockNumber)
+		binary.BigEndian.PutUint64(b[11:], e.Timestamp)
+		copy(b[19:51], e.Hash[:])
+		copy(b[51:83], e.ReplacedBy[:])
+	}
+	return v
+}
+
+func decodeTxnEvents(v []byte) ([]TxnEvent, error) {
+	if len(v)%txnEventLen != 0 {
+		return nil, fmt.Errorf("invalid txn events length: %d", len(v))
+	}
+	events := make([]TxnEvent, len(v)/txnEventLen)
+	for i := range events {
+		e, b := &events[i], v[i*txnEventLen:]
+		e.Type, e.SubPool, e.DiscardReason = TxnEventType(b[0]), SubPoolType(b[1]), txpoolcfg.DiscardReason(b[2])
+		e.BlockNumber = binary.BigEndian.Uint64(b[3:])
+		e.Timestamp = binary.BigEndian.Uint64(b[11:])
+		copy(e.Hash[:], b[19:51])
+		copy(e.ReplacedBy[:], b[51:83])
+	}
+	return events, nil
+}
+
+// TxnEventsFeed fans txn events out to subscribers without ever blocking the pool
+type TxnEventsFeed struct {
+	mu      sync.Mutex
+	id      uint
+	subs    map[uint]chan TxnEvent
+	dropped uint64
+}
+
+func (f *TxnEventsFeed) Subscribe() (events <-chan TxnEvent, unsubscribe func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.subs == nil {
+		f.subs = map[uint]chan TxnEvent{}
+	}
+	f.id++
+	id := f.id
+	ch := make(chan TxnEvent, txnEventsSubscriptionBuffer)
+	f.subs[id] = ch
+	return ch, func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subs, id) // double-unsubscribe support
+	}
+}
+
+func (f *TxnEventsFeed) send(e TxnEvent, logger log.Logger) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ch := range f.subs {
+		select {
+		case ch <- e:
+		default:
+			f.dropped++
+			if f.dropped%txnEventsSubscriptionBuffer == 1 {
+				logger.Debug("[txpool] slow txn events subscriber, dropping events", "dropped", f.dropped)
+			}
+		}
+	}
+}
+
+// SubscribeTxnEvents subscribes to the lifecycle events of all txns in the pool
+func (p *TxPool) SubscribeTxnEvents() (events <-chan TxnEvent, unsubscribe func()) {
+	return p.txnEvents.Subscribe()
+}
+
+// TxnStatus returns the recorded lifecycle events of the txn, oldest first. Only the last
+// TxnStatusHistoryLimit txns are remembered
+func (p *TxPool) TxnStatus(hash common.Hash) ([]TxnEvent, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	events, ok := p.txnStatusLRU.Peek(string(hash[:]))
+	if !ok {
+		return nil, false
+	}
+	return append([]TxnEvent(nil), events...), true
+}
+
+func (p *TxPool) emitLocked(e TxnEvent) {
+	e.BlockNumber = p.lastSeenBlock.Load()
+	if p.newBlockInProgress != 0 {
+		e.BlockNumber = p.newBlockInProgress
+	}
+	e.Timestamp = uint64(time.Now().Unix())
+
+	hashStr := string(e.Hash[:])
+	events, _ := p.txnStatusLRU.Get(hashStr)
+	if len(events) >= MaxTxnStatusEvents {
+		events = append(events[:0:0], events[len(events)-MaxTxnStatusEvents+1:]...)
+	}
+	p.txnStatusLRU.Add(hashStr, append(events, e))
+	p.txnStatusDirty[hashStr] = struct{}{}
+
+	p.txnEvents.send(e, p.logger)
+}
+
+func (p *TxPool) emitMovedLocked(mt *metaTxn, from SubPoolType) {
+	e := TxnEvent{Type: TxnPromoted, Hash: mt.TxnSlot.IDHash, SubPool: mt.currentSubPool}
+	if mt.currentSubPool > from { // sub-pool types are ordered from the best to the worst
+		e.Type = TxnDemoted
+	}
+	p.emitLocked(e)
+}
+
+// flushTxnStatusLocked writes the histories changed since the last flush, and deletes the evicted ones
+func (p *TxPool) flushTxnStatusLocked(tx kv.RwTx) error {
+	for hashStr := range p.txnStatusDirty {
+		events, ok := p.txnStatusLRU.Peek(hashStr)
+		if !ok {
+			if err := tx.Delete(kv.PoolTxnStatus, []byte(hashStr)); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Put(kv.PoolTxnStatus, []byte(hashStr), encodeTxnEvents(events)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *TxPool) txnStatusFromDB(tx kv.Tx) error {
+	return tx.ForEach(kv.PoolTxnStatus, nil, func(k, v []byte) error {
+		events, err := decodeTxnEvents(v)
+		if err != nil {
+			p.logger.Warn("[txpool] fromDB: decodeTxnEvents", "err", err)
+			p.txnStatusDirty[string(k)] = struct{}{} // not in the LRU, so deleted on the next flush
+			return nil
+		}
+		p.txnStatusLRU.Add(string(k), events)
+		return nil
+	})
+}
@@ -36,9 +36,18 @@ type metaTxn struct {
 	bestIndex                 int
 	worstIndex                int
 	timestamp                 uint64 // when it was added to pool
+	arrival                   uint64 // sequence number in the order of addition to the pool
 	subPool                   SubPoolMarker
 	currentSubPool            SubPoolType
 	minedBlockNum             uint64
+	conditions                *TxnConditions // set for txns added with eth_sendRawTransactionConditional, never gossiped
+	privateUntil              uint64         // set for private txns: never gossiped, they expire after this block
+	privateFallback           bool           // expired private txn goes public instead of being dropped
+}
+
+// gossipable returns false for txns which must stay on this node
+func (mt *metaTxn) gossipable() bool {
+	return mt.conditions == nil && mt.privateUntil == 0
 }
 
 // Returns true if the txn "mt" is better than the parameter txn "than"
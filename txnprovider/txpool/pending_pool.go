@@ -17,8 +17,10 @@
 package txpool
 
 import (
+	"cmp"
 	"container/heap"
 	"fmt"
+	"slices"
 	"sort"
 
 	"github.com/erigontech/erigon-lib/log/v3"
@@ -67,6 +69,30 @@ func (p *PendingPool) PopWorst() *metaTxn { //nolint
 	return i
 }
 
+// byArrival returns the pending txns in the order they arrived. A txn never comes before the lower nonces of its
+// sender though: it's available from the latest arrival among them.
+func (p *PendingPool) byArrival() []*metaTxn {
+	ms := slices.Clone(p.best.ms)
+	slices.SortFunc(ms, func(a, b *metaTxn) int {
+		if c := cmp.Compare(a.TxnSlot.SenderID, b.TxnSlot.SenderID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.TxnSlot.Nonce, b.TxnSlot.Nonce)
+	})
+	available := make(map[*metaTxn]uint64, len(ms))
+	for i, mt := range ms {
+		available[mt] = mt.arrival
+		if i > 0 && ms[i-1].TxnSlot.SenderID == mt.TxnSlot.SenderID {
+			available[mt] = max(mt.arrival, available[ms[i-1]])
+		}
+	}
+	// stable, for the txns of a sender available at once to stay in nonce order
+	slices.SortStableFunc(ms, func(a, b *metaTxn) int {
+		return cmp.Compare(available[a], available[b])
+	})
+	return ms
+}
+
 func (p *PendingPool) Updated(mt *metaTxn) {
 	heap.Fix(p.worst, mt.worstIndex)
 }
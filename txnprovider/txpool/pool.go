@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"math"
 	"math/big"
+	"path/filepath"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -115,13 +116,23 @@ type TxPool struct {
 	pending                 *PendingPool
 	baseFee                 *SubPool
 	queued                  *SubPool
-	minedBlobTxnsByBlock    map[uint64][]*metaTxn            // (blockNum => slice): cache of recently mined blobs
-	minedBlobTxnsByHash     map[string]*metaTxn              // (hash => mt): map of recently mined blobs
-	isLocalLRU              *simplelru.LRU[string, struct{}] // txn_hash => is_local : to restore isLocal flag of unwinded transactions
-	newPendingTxns          chan Announcements               // notifications about new txns in Pending sub-pool
-	all                     *BySenderAndNonce                // senderID => (sorted map of txn nonce => *metaTxn)
-	deletedTxns             []*metaTxn                       // list of discarded txns since last db commit
+	minedBlobTxnsByBlock    map[uint64][]*metaTxn              // (blockNum => slice): cache of recently mined blobs
+	minedBlobTxnsByHash     map[string]*metaTxn                // (hash => mt): map of recently mined blobs
+	blobHashToTxn           map[common.Hash]blobTxnRef         // (versioned hash => txn): blobs available in the pool
+	blobEvictions           *blobEvictionQueue                 // pooled blob txns kept in the blob store, in eviction order
+	blobStore               *BlobStore                         // txn_hash => blob txn with its sidecar : on disk, pooled blob txns keep only metadata in memory
+	conditionalTxns         map[string]*metaTxn                // txn_hash => txn : txns with conditions, re-validated on every new block
+	privateTxns             map[string]*metaTxn                // txn_hash => txn : private txns, kept for local block building only until they expire
+	arrivals                uint64                             // counter of the transactions added to the pool, for metaTxn.arrival
+	isLocalLRU              *simplelru.LRU[string, struct{}]   // txn_hash => is_local : to restore isLocal flag of unwinded transactions
+	txnStatusLRU            *simplelru.LRU[string, []TxnEvent] // txn_hash => lifecycle events : for txpool_getTransactionStatus
+	txnStatusDirty          map[string]struct{}                // txn_hash : histories changed or evicted since last db commit
+	txnEvents               TxnEventsFeed                      // subscribers of txn lifecycle events
+	newPendingTxns          chan Announcements                 // notifications about new txns in Pending sub-pool
+	all                     *BySenderAndNonce                  // senderID => (sorted map of txn nonce => *metaTxn)
+	deletedTxns             []*metaTxn                         // list of discarded txns since last db commit
 	promoted                Announcements
+	newBlockInProgress      uint64 // block being applied by OnNewBlock, events are attributed to it
 	cfg                     txpoolcfg.Config
 	chainID                 uint256.Int
 	lastSeenBlock           atomic.Uint64
@@ -208,6 +219,11 @@ func New(
 		unprocessedRemoteByHash: map[string]int{},
 		minedBlobTxnsByBlock:    map[uint64][]*metaTxn{},
 		minedBlobTxnsByHash:     map[string]*metaTxn{},
+		blobHashToTxn:           map[common.Hash]blobTxnRef{},
+		blobEvictions:           newBlobEvictionQueue(),
+		conditionalTxns:         map[string]*metaTxn{},
+		privateTxns:             map[string]*metaTxn{},
+		txnStatusDirty:          map[string]struct{}{},
 		maxBlobsPerBlock:        maxBlobsPerBlock,
 		feeCalculator:           feeCalculator,
 		logger:                  logger,
@@ -242,6 +258,21 @@ func New(
 		res.pragueTime = &pragueTimeU64
 	}
 
+	blobStoreDir := ""
+	if cfg.DBDir != "" {
+		blobStoreDir = filepath.Join(cfg.DBDir, "blobs")
+	}
+	if res.blobStore, err = OpenBlobStore(blobStoreDir); err != nil {
+		return nil, err
+	}
+
+	res.txnStatusLRU, err = simplelru.NewLRU[string, []TxnEvent](TxnStatusHistoryLimit, func(hashStr string, _ []TxnEvent) {
+		res.txnStatusDirty[hashStr] = struct{}{}
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	return res, nil
 }
 
@@ -301,12 +332,14 @@ func (p *TxPool) OnNewBlock(ctx context.Context, stateChanges *remote.StateChang
 	}
 
 	p.lock.Lock()
+	p.newBlockInProgress = block
 	defer func() {
 		if err == nil {
 			p.lastSeenBlock.Store(block)
 			p.lastSeenCond.Broadcast()
 		}
 
+		p.newBlockInProgress = 0
 		p.lock.Unlock()
 	}()
 
@@ -403,6 +436,8 @@ func (p *TxPool) OnNewBlock(ctx context.Context, stateChanges *remote.StateChang
 		return err
 	}
 
+	p.removeConditionsNotMet(coreTx, stateChanges, block+1, uint64(time.Now().Unix()))
+
 	var announcements Announcements
 
 	announcements, err = p.addTxnsOnNewBlock(block, cacheView, stateChanges, p.senders, unwindTxns, /* newTxns */
@@ -411,6 +446,8 @@ func (p *TxPool) OnNewBlock(ctx context.Context, stateChanges *remote.StateChang
 		return err
 	}
 
+	p.expirePrivateTxns(block, &announcements)
+
 	p.pending.EnforceWorstInvariants()
 	p.baseFee.EnforceInvariants()
 	p.queued.EnforceInvariants()
@@ -495,6 +532,16 @@ func (p *TxPool) processRemoteTxns(ctx context.Context) (err error) {
 
 func (p *TxPool) getRlpLocked(tx kv.Tx, hash []byte) (rlpTxn []byte, sender common.Address, isLocal bool, err error) {
 	txn, ok := p.byHash[string(hash)]
+	if ok && txn.TxnSlot.Type == BlobTxnType {
+		// pooled blob txns keep their sidecars in the blob store only
+		rlpTxn, err := p.blobStore.Get(hash)
+		if err != nil {
+			return nil, common.Address{}, false, err
+		}
+		if rlpTxn != nil {
+			return rlpTxn, p.senders.senderID2Addr[txn.TxnSlot.SenderID], txn.subPool&IsLocal > 0, nil
+		}
+	}
 	if ok && txn.TxnSlot.Rlp != nil {
 		return txn.TxnSlot.Rlp, p.senders.senderID2Addr[txn.TxnSlot.SenderID], txn.subPool&IsLocal > 0, nil
 	}
@@ -508,8 +555,14 @@ func (p *TxPool) getRlpLocked(tx kv.Tx, hash []byte) (rlpTxn []byte, sender comm
 	return v[20:], *(*[20]byte)(v[:20]), txn != nil && txn.subPool&IsLocal > 0, nil
 }
 
+// GetRlp returns the rlp of a pooled txn. Blob txns are read from the blob store out of the pool lock.
 func (p *TxPool) GetRlp(tx kv.Tx, hash []byte) ([]byte, error) {
 	p.lock.Lock()
+	if txn, ok := p.byHash[string(hash)]; ok && txn.TxnSlot.Type == BlobTxnType && p.blobStore.Has(hash) {
+		stored := p.blobStore.lookup(hash)
+		p.lock.Unlock()
+		return stored.read()
+	}
 	defer p.lock.Unlock()
 	rlpTx, _, _, err := p.getRlpLocked(tx, hash)
 	return common.Copy(rlpTx), err
@@ -519,7 +572,7 @@ func (p *TxPool) AppendLocalAnnouncements(types []byte, sizes []uint32, hashes [
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	for hash, txn := range p.byHash {
-		if txn.subPool&IsLocal == 0 {
+		if txn.subPool&IsLocal == 0 || !txn.gossipable() {
 			continue
 		}
 		types = append(types, txn.TxnSlot.Type)
@@ -534,7 +587,7 @@ func (p *TxPool) AppendRemoteAnnouncements(types []byte, sizes []uint32, hashes
 	defer p.lock.Unlock()
 
 	for hash, txn := range p.byHash {
-		if txn.subPool&IsLocal != 0 {
+		if txn.subPool&IsLocal != 0 || !txn.gossipable() {
 			continue
 		}
 		types = append(types, txn.TxnSlot.Type)
@@ -603,12 +656,20 @@ func (p *TxPool) getUnprocessedTxn(hashS string) (*TxnSlot, bool) {
 
 func (p *TxPool) getCachedBlobTxnLocked(tx kv.Tx, hash []byte) (*metaTxn, error) {
 	hashS := string(hash)
-	if mt, ok := p.minedBlobTxnsByHash[hashS]; ok {
-		return mt, nil
-	}
 	if txn, ok := p.getUnprocessedTxn(hashS); ok {
 		return newMetaTxn(txn, false, 0), nil
 	}
+	// the blob store keeps the sidecars of the pooled and of the recently mined blob txns
+	txnRlp, err := p.blobStore.Get(hash)
+	if err != nil {
+		return nil, fmt.Errorf("TxPool.getCachedBlobTxnLocked: %w", err)
+	}
+	if txnRlp != nil {
+		return p.parseWrappedBlobTxn(txnRlp)
+	}
+	if mt, ok := p.minedBlobTxnsByHash[hashS]; ok {
+		return mt, nil
+	}
 	if mt, ok := p.byHash[hashS]; ok {
 		return mt, nil
 	}
@@ -619,11 +680,18 @@ func (p *TxPool) getCachedBlobTxnLocked(tx kv.Tx, hash []byte) (*metaTxn, error)
 	if len(v) == 0 {
 		return nil, nil
 	}
-	txnRlp := common.Copy(v[20:])
+	return p.parseWrappedBlobTxn(common.Copy(v[20:]))
+}
+
+// parseWrappedBlobTxn returns nil for the txns stored without their sidecars
+func (p *TxPool) parseWrappedBlobTxn(txnRlp []byte) (*metaTxn, error) {
 	parseCtx := NewTxnParseContext(p.chainID)
 	parseCtx.WithSender(false)
 	txnSlot := &TxnSlot{}
-	parseCtx.ParseTransaction(txnRlp, 0, txnSlot, nil, false, true, nil)
+	if _, err := parseCtx.ParseTransaction(txnRlp, 0, txnSlot, nil, false, true, nil); err != nil {
+		p.logger.Debug("[txpool] parse wrapped blob txn", "err", err)
+		return nil, nil
+	}
 	return newMetaTxn(txnSlot, false, 0), nil
 }
 
@@ -634,6 +702,15 @@ func (p *TxPool) IsLocal(idHash []byte) bool {
 	return p.isLocalLRU.Contains(hashS)
 }
 
+// IsGossipable returns false for txns which must not leave the node: private txns, and conditional txns
+// as peers can't check their conditions and would include them regardless
+func (p *TxPool) IsGossipable(idHash []byte) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	mt, ok := p.byHash[string(idHash)]
+	return !ok || mt.gossipable()
+}
+
 func (p *TxPool) AddNewGoodPeer(peerID PeerID) {
 	p.recentlyConnectedPeers.AddPeer(peerID)
 }
@@ -642,7 +719,7 @@ func (p *TxPool) Started() bool {
 	return p.started.Load()
 }
 
-func (p *TxPool) best(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availableGas, availableBlobGas uint64, yielded mapset.Set[[32]byte]) (bool, int, error) {
+func (p *TxPool) best(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availableGas, availableBlobGas, blockTime uint64, yielded mapset.Set[[32]byte], byArrival bool) (bool, int, error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
@@ -651,17 +728,24 @@ func (p *TxPool) best(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availa
 		p.lastSeenCond.Wait()
 	}
 
-	best := p.pending.best
+	best := p.pending.best.ms
+	if byArrival {
+		best = p.pending.byArrival()
+	}
+	blockNum := onTopOf + 1
+	if onTopOf == 0 {
+		blockNum = p.lastSeenBlock.Load() + 1
+	}
 
 	isShanghai := p.isShanghai() || p.isAgra()
 
-	txns.Resize(uint(min(n, len(best.ms))))
+	txns.Resize(uint(min(n, len(best))))
 	var toRemove []*metaTxn
 	count := 0
 	i := 0
 
 	defer func() {
-		p.logger.Debug("[txpool] Processing best request", "last", onTopOf, "txRequested", n, "txAvailable", len(best.ms), "txProcessed", i, "txReturned", count)
+		p.logger.Debug("[txpool] Processing best request", "last", onTopOf, "txRequested", n, "txAvailable", len(best), "txProcessed", i, "txReturned", count)
 	}()
 
 	tx, err := p.poolDB.BeginRo(ctx)
@@ -670,13 +754,13 @@ func (p *TxPool) best(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availa
 	}
 
 	defer tx.Rollback()
-	for ; count < n && i < len(best.ms); i++ {
+	for ; count < n && i < len(best); i++ {
 		// if we wouldn't have enough gas for a standard transaction then quit out early
 		if availableGas < fixedgas.TxGas {
 			break
 		}
 
-		mt := best.ms[i]
+		mt := best[i]
 
 		if yielded.Contains(mt.TxnSlot.IDHash) {
 			continue
@@ -687,6 +771,11 @@ func (p *TxPool) best(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availa
 			continue
 		}
 
+		// Storage conditions are re-checked on every new block, only the bounds depend on the block being built
+		if mt.conditions != nil && !mt.conditions.includable(blockNum, blockTime) {
+			continue
+		}
+
 		rlpTxn, sender, isLocal, err := p.getRlpLocked(tx, mt.TxnSlot.IDHash[:])
 		if err != nil {
 			return false, count, err
@@ -736,14 +825,37 @@ func (p *TxPool) YieldBestTxns(
 	parentBlockNum uint64,
 	gasTarget uint64,
 	blobGasTarget uint64,
+	blockTime uint64,
 	txnIdsFilter mapset.Set[[32]byte],
 ) ([]types.Transaction, error) {
 	var txnsRlp TxnsRlp
-	_, _, err := p.YieldBest(ctx, amount, &txnsRlp, parentBlockNum, gasTarget, blobGasTarget, txnIdsFilter)
+	_, _, err := p.YieldBest(ctx, amount, &txnsRlp, parentBlockNum, gasTarget, blobGasTarget, blockTime, txnIdsFilter)
 	if err != nil {
 		return nil, err
 	}
+	return decodeYieldedTxns(txnsRlp)
+}
 
+// YieldEarliestTxns is like YieldBestTxns, but yields the pending txns in the order they arrived in the pool.
+// A txn still never comes before the lower nonces of its sender.
+func (p *TxPool) YieldEarliestTxns(
+	ctx context.Context,
+	amount int,
+	parentBlockNum uint64,
+	gasTarget uint64,
+	blobGasTarget uint64,
+	blockTime uint64,
+	txnIdsFilter mapset.Set[[32]byte],
+) ([]types.Transaction, error) {
+	var txnsRlp TxnsRlp
+	_, _, err := p.best(ctx, amount, &txnsRlp, parentBlockNum, gasTarget, blobGasTarget, blockTime, txnIdsFilter, true /* byArrival */)
+	if err != nil {
+		return nil, err
+	}
+	return decodeYieldedTxns(txnsRlp)
+}
+
+func decodeYieldedTxns(txnsRlp TxnsRlp) ([]types.Transaction, error) {
 	txns := make([]types.Transaction, 0, len(txnsRlp.Txns))
 	for i := range txnsRlp.Txns {
 		txn, err := types.DecodeWrappedTransaction(txnsRlp.Txns[i])
@@ -760,13 +872,15 @@ func (p *TxPool) YieldBestTxns(
 	return txns, nil
 }
 
-func (p *TxPool) YieldBest(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availableGas, availableBlobGas uint64, toSkip mapset.Set[[32]byte]) (bool, int, error) {
-	return p.best(ctx, n, txns, onTopOf, availableGas, availableBlobGas, toSkip)
+// YieldBest yields the best txns for a block on top of onTopOf, with the given timestamp.
+// blockTime == 0 means the timestamp is not known yet: timestamp conditions are not checked.
+func (p *TxPool) YieldBest(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availableGas, availableBlobGas, blockTime uint64, toSkip mapset.Set[[32]byte]) (bool, int, error) {
+	return p.best(ctx, n, txns, onTopOf, availableGas, availableBlobGas, blockTime, toSkip, false /* byArrival */)
 }
 
 func (p *TxPool) PeekBest(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availableGas, availableBlobGas uint64) (bool, error) {
 	set := mapset.NewThreadUnsafeSet[[32]byte]()
-	onTime, _, err := p.YieldBest(ctx, n, txns, onTopOf, availableGas, availableBlobGas, set)
+	onTime, _, err := p.YieldBest(ctx, n, txns, onTopOf, availableGas, availableBlobGas, 0 /* blockTime */, set)
 	return onTime, err
 }
 
@@ -1141,7 +1255,39 @@ func fillDiscardReasons(reasons []txpoolcfg.DiscardReason, newTxns TxnSlots, dis
 	return reasons
 }
 
+// localTxnOpts are the submission options of local txns which restrict where they go
+type localTxnOpts struct {
+	conditions      *TxnConditions
+	privateUntil    uint64
+	privateFallback bool
+}
+
 func (p *TxPool) AddLocalTxns(ctx context.Context, newTxns TxnSlots) ([]txpoolcfg.DiscardReason, error) {
+	return p.addLocalTxns(ctx, newTxns, localTxnOpts{})
+}
+
+// AddLocalConditionalTxns adds local txns which are only included while conditions hold.
+// They are rejected with txpoolcfg.ConditionsNotMet if the conditions already fail, are dropped
+// as soon as a new block breaks them and are never propagated to peers.
+func (p *TxPool) AddLocalConditionalTxns(ctx context.Context, newTxns TxnSlots, conditions *TxnConditions) ([]txpoolcfg.DiscardReason, error) {
+	if err := conditions.Validate(); err != nil {
+		return nil, err
+	}
+	return p.addLocalTxns(ctx, newTxns, localTxnOpts{conditions: conditions})
+}
+
+// AddLocalPrivateTxns adds local txns which are kept for local block building only and never propagated to peers.
+// They expire after block maxBlockNumber, or after cfg.PrivateTxnLifetime blocks if it comes first (or maxBlockNumber is 0):
+// expired txns are dropped with txpoolcfg.PrivateTxnExpired, or announced to peers as regular txns if fallback is set.
+func (p *TxPool) AddLocalPrivateTxns(ctx context.Context, newTxns TxnSlots, maxBlockNumber uint64, fallback bool) ([]txpoolcfg.DiscardReason, error) {
+	privateUntil := p.lastSeenBlock.Load() + p.cfg.PrivateTxnLifetime
+	if maxBlockNumber != 0 && maxBlockNumber < privateUntil {
+		privateUntil = maxBlockNumber
+	}
+	return p.addLocalTxns(ctx, newTxns, localTxnOpts{privateUntil: privateUntil, privateFallback: fallback})
+}
+
+func (p *TxPool) addLocalTxns(ctx context.Context, newTxns TxnSlots, opts localTxnOpts) ([]txpoolcfg.DiscardReason, error) {
 	coreDb, cache := p.coreDBWithCache()
 	coreTx, err := coreDb.BeginRo(ctx)
 	if err != nil {
@@ -1157,6 +1303,28 @@ func (p *TxPool) AddLocalTxns(ctx context.Context, newTxns TxnSlots) ([]txpoolcf
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
+	if opts.privateUntil != 0 && opts.privateUntil <= p.lastSeenBlock.Load() && !opts.privateFallback {
+		reasons := make([]txpoolcfg.DiscardReason, len(newTxns.Txns))
+		for i := range reasons {
+			reasons[i] = txpoolcfg.PrivateTxnExpired
+		}
+		return reasons, nil
+	}
+
+	if opts.conditions != nil {
+		reason, err := p.checkNewConditionsLocked(ctx, coreDb, opts.conditions)
+		if err != nil {
+			return nil, err
+		}
+		if reason != txpoolcfg.NotSet {
+			reasons := make([]txpoolcfg.DiscardReason, len(newTxns.Txns))
+			for i := range reasons {
+				reasons[i] = reason
+			}
+			return reasons, nil
+		}
+	}
+
 	if err = p.senders.registerNewSenders(&newTxns, p.logger); err != nil {
 		return nil, err
 	}
@@ -1184,6 +1352,7 @@ func (p *TxPool) AddLocalTxns(ctx context.Context, newTxns TxnSlots) ([]txpoolcf
 	for i, reason := range reasons {
 		if reason == txpoolcfg.Success {
 			txn := newTxns.Txns[i]
+			p.setLocalTxnOptsLocked(string(txn.IDHash[:]), opts)
 			if txn.Traced {
 				p.logger.Info(fmt.Sprintf("TX TRACING: AddLocalTxns promotes idHash=%x, senderId=%d", txn.IDHash, txn.SenderID))
 			}
@@ -1393,6 +1562,7 @@ func (p *TxPool) addLocked(mt *metaTxn, announcements *Announcements) txpoolcfg.
 			//already removed
 		}
 
+		p.emitLocked(TxnEvent{Type: TxnReplaced, Hash: found.TxnSlot.IDHash, ReplacedBy: mt.TxnSlot.IDHash})
 		p.discardLocked(found, txpoolcfg.ReplacedByHigherTip)
 	}
 
@@ -1400,9 +1570,16 @@ func (p *TxPool) addLocked(mt *metaTxn, announcements *Announcements) txpoolcfg.
 	if mt.TxnSlot.Type == BlobTxnType && mt.TxnSlot.BlobFeeCap.LtUint64(p.pendingBlobFee.Load()) {
 		return txpoolcfg.FeeTooLow
 	}
+	if mt.TxnSlot.Type == BlobTxnType {
+		if reason := p.storeBlobTxnLocked(mt); reason != txpoolcfg.NotSet {
+			return reason
+		}
+	}
 
 	hashStr := string(mt.TxnSlot.IDHash[:])
 	p.byHash[hashStr] = mt
+	p.arrivals++
+	mt.arrival = p.arrivals
 
 	if replaced := p.all.replaceOrInsert(mt, p.logger); replaced != nil {
 		if assert.Enable {
@@ -1415,9 +1592,16 @@ func (p *TxPool) addLocked(mt *metaTxn, announcements *Announcements) txpoolcfg.
 	}
 	// All transactions are first added to the queued pool and then immediately promoted from there if required
 	p.queued.Add(mt, "addLocked", p.logger)
+	p.emitLocked(TxnEvent{Type: TxnAdded, Hash: mt.TxnSlot.IDHash, SubPool: mt.currentSubPool})
 	if mt.TxnSlot.Type == BlobTxnType {
 		t := p.totalBlobsInPool.Load()
 		p.totalBlobsInPool.Store(t + (uint64(len(mt.TxnSlot.BlobHashes))))
+		for i, blobHash := range mt.TxnSlot.BlobHashes {
+			p.blobHashToTxn[blobHash] = blobTxnRef{mt: mt, index: i}
+		}
+		if p.blobStore.Has(mt.TxnSlot.IDHash[:]) {
+			p.blobEvictions.add(mt)
+		}
 	}
 
 	// Remove from mined cache as we are now "resurrecting" it to a sub-pool
@@ -1430,12 +1614,33 @@ func (p *TxPool) addLocked(mt *metaTxn, announcements *Announcements) txpoolcfg.
 func (p *TxPool) discardLocked(mt *metaTxn, reason txpoolcfg.DiscardReason) {
 	hashStr := string(mt.TxnSlot.IDHash[:])
 	delete(p.byHash, hashStr)
+	delete(p.conditionalTxns, hashStr)
+	delete(p.privateTxns, hashStr)
 	p.deletedTxns = append(p.deletedTxns, mt)
 	p.all.delete(mt, reason, p.logger)
 	p.discardReasonsLRU.Add(hashStr, reason)
+	switch reason {
+	case txpoolcfg.Mined:
+		p.emitLocked(TxnEvent{Type: TxnMined, Hash: mt.TxnSlot.IDHash})
+	case txpoolcfg.ReplacedByHigherTip:
+		// reported by addLocked, along with the replacing txn
+	default:
+		p.emitLocked(TxnEvent{Type: TxnDiscarded, Hash: mt.TxnSlot.IDHash, DiscardReason: reason})
+	}
 	if mt.TxnSlot.Type == BlobTxnType {
+		// mined txns are kept in the blob store until finalized, to be re-added with their sidecars on reorg
+		if reason != txpoolcfg.Mined {
+			p.blobStore.Delete(mt.TxnSlot.IDHash[:])
+		}
+		p.blobEvictions.remove(mt)
 		t := p.totalBlobsInPool.Load()
 		p.totalBlobsInPool.Store(t - uint64(len(mt.TxnSlot.BlobHashes)))
+		for _, blobHash := range mt.TxnSlot.BlobHashes {
+			// the same blob may be carried by another (e.g. replacement) txn, keep its entry
+			if ref, ok := p.blobHashToTxn[blobHash]; ok && ref.mt == mt {
+				delete(p.blobHashToTxn, blobHash)
+			}
+		}
 	}
 }
 
@@ -1447,6 +1652,9 @@ func (p *TxPool) processMinedFinalizedBlobs(minedTxns []*TxnSlot, finalizedBlock
 		// delete individual hashes
 		for _, mt := range p.minedBlobTxnsByBlock[finalizedBlock] {
 			delete(p.minedBlobTxnsByHash, string(mt.TxnSlot.IDHash[:]))
+			if _, ok := p.byHash[string(mt.TxnSlot.IDHash[:])]; !ok {
+				p.blobStore.Delete(mt.TxnSlot.IDHash[:])
+			}
 		}
 		// delete the map entry for this block num
 		delete(p.minedBlobTxnsByBlock, finalizedBlock)
@@ -1482,6 +1690,142 @@ func (p *TxPool) deleteMinedBlobTxn(hash string) {
 	delete(p.minedBlobTxnsByHash, hash)
 }
 
+// storeBlobTxnLocked moves the sidecar of a blob txn to the blob store, evicting the cheapest blob txns if it's
+// over its budget. Txns without a sidecar, e.g. unwound ones with an unknown sidecar, are kept as they are.
+func (p *TxPool) storeBlobTxnLocked(mt *metaTxn) txpoolcfg.DiscardReason {
+	if len(mt.TxnSlot.Blobs) == 0 {
+		return txpoolcfg.NotSet
+	}
+	hash := mt.TxnSlot.IDHash[:]
+	if !p.blobStore.Has(hash) {
+		if mt.TxnSlot.Rlp == nil {
+			return txpoolcfg.NotSet
+		}
+		if reason := p.makeRoomInBlobStoreLocked(mt, uint64(len(mt.TxnSlot.Rlp))); reason != txpoolcfg.NotSet {
+			return reason
+		}
+		p.blobStore.Put(hash, mt.TxnSlot.Rlp)
+	}
+	if mt.TxnSlot.Rlp != nil {
+		unwrapped, err := unwrapBlobTxnRlp(mt.TxnSlot.Rlp)
+		if err != nil {
+			p.logger.Warn("[txpool] store blob txn, keeping its sidecar in memory", "err", err)
+			return txpoolcfg.NotSet
+		}
+		mt.TxnSlot.Rlp = unwrapped
+	}
+	mt.TxnSlot.Blobs, mt.TxnSlot.Commitments, mt.TxnSlot.Proofs = nil, nil, nil
+	return txpoolcfg.NotSet
+}
+
+// makeRoomInBlobStoreLocked discards the stored blob txns worth less than mt until size more bytes fit in the blob
+// store budget. The senders worth the least go first, each from its highest nonce down (see blobEvictionQueue).
+func (p *TxPool) makeRoomInBlobStoreLocked(mt *metaTxn, size uint64) txpoolcfg.DiscardReason {
+	budget := p.cfg.BlobStoreSize.Bytes()
+	for budget > 0 && p.blobStore.Size()+size > budget {
+		evict, cheapest := p.blobEvictions.next()
+		if evict == nil || !blobTxnCheaper(cheapest, mt) {
+			return txpoolcfg.BlobStoreOverflow
+		}
+		// mt would need its own lower nonces, which aren't worth more than it
+		if evict.TxnSlot.SenderID == mt.TxnSlot.SenderID && evict.TxnSlot.Nonce < mt.TxnSlot.Nonce {
+			return txpoolcfg.BlobStoreOverflow
+		}
+		switch evict.currentSubPool {
+		case PendingSubPool:
+			p.pending.Remove(evict, "blob-store-overflow", p.logger)
+		case BaseFeeSubPool:
+			p.baseFee.Remove(evict, "blob-store-overflow", p.logger)
+		case QueuedSubPool:
+			p.queued.Remove(evict, "blob-store-overflow", p.logger)
+		}
+		p.discardLocked(evict, txpoolcfg.BlobStoreOverflow)
+	}
+	return txpoolcfg.NotSet
+}
+
+func blobTxnCheaper(a, b *metaTxn) bool {
+	if c := a.TxnSlot.BlobFeeCap.Cmp(&b.TxnSlot.BlobFeeCap); c != 0 {
+		return c < 0
+	}
+	return a.TxnSlot.Tip.Lt(&b.TxnSlot.Tip)
+}
+
+// blobTxnRef points to a blob carried by a pooled blob txn
+type blobTxnRef struct {
+	mt    *metaTxn
+	index int // index of the blob within the txn
+}
+
+// GetBlobs returns blobs and their KZG proofs for the given versioned hashes, preserving the order of the request.
+// If a blob is not in the pool, nil is returned in its slot. The txns kept in the blob store are read after
+// releasing the pool lock.
+func (p *TxPool) GetBlobs(blobHashes []common.Hash) (blobs [][]byte, proofs [][]byte) {
+	blobs = make([][]byte, len(blobHashes))
+	proofs = make([][]byte, len(blobHashes))
+	stored := map[*metaTxn]blobStoreRead{} // txns to read from the blob store
+	storedRefs := map[int]blobTxnRef{}     // request index => blob of a txn to read from the blob store
+	p.lock.Lock()
+	for i, blobHash := range blobHashes {
+		ref, ok := p.blobHashToTxn[blobHash]
+		if !ok {
+			continue
+		}
+		txn := ref.mt.TxnSlot
+		if len(txn.Blobs) == 0 {
+			if _, ok := stored[ref.mt]; !ok {
+				stored[ref.mt] = p.blobStore.lookup(txn.IDHash[:])
+			}
+			storedRefs[i] = ref
+			continue
+		}
+		if ref.index < len(txn.Blobs) && ref.index < len(txn.Proofs) {
+			blobs[i] = txn.Blobs[ref.index]
+			proofs[i] = txn.Proofs[ref.index][:]
+		}
+	}
+	p.lock.Unlock()
+
+	sidecars := make(map[*metaTxn]*TxnSlot, len(stored))
+	for mt, r := range stored {
+		txnRlp, err := r.read()
+		if err != nil {
+			p.logger.Warn("[txpool] GetBlobs", "err", err)
+		}
+		if txnRlp == nil {
+			continue
+		}
+		if parsed, _ := p.parseWrappedBlobTxn(txnRlp); parsed != nil {
+			sidecars[mt] = parsed.TxnSlot
+		}
+	}
+	for i, ref := range storedRefs {
+		txn, ok := sidecars[ref.mt]
+		if !ok || ref.index >= len(txn.Blobs) || ref.index >= len(txn.Proofs) {
+			// txn was stored without its sidecar, or has left the store meanwhile
+			continue
+		}
+		blobs[i] = txn.Blobs[ref.index]
+		proofs[i] = txn.Proofs[ref.index][:]
+	}
+	return blobs, proofs
+}
+
+// TxnArrivals returns the order in which the given transactions were added to the pool, lower is earlier.
+// Transactions which are not in the pool get math.MaxUint64.
+func (p *TxPool) TxnArrivals(txnHashes []common.Hash) []uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	arrivals := make([]uint64, len(txnHashes))
+	for i, txnHash := range txnHashes {
+		arrivals[i] = math.MaxUint64
+		if mt, ok := p.byHash[string(txnHash[:])]; ok {
+			arrivals[i] = mt.arrival
+		}
+	}
+	return arrivals
+}
+
 func (p *TxPool) NonceFromAddress(addr [20]byte) (nonce uint64, inPool bool) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
@@ -1562,6 +1906,133 @@ func (p *TxPool) removeMined(byNonce *BySenderAndNonce, minedTxns []*TxnSlot) er
 	return nil
 }
 
+func (p *TxPool) setLocalTxnOptsLocked(hashStr string, opts localTxnOpts) {
+	mt, ok := p.byHash[hashStr]
+	if !ok {
+		return
+	}
+	if opts.conditions != nil {
+		mt.conditions = opts.conditions
+		p.conditionalTxns[hashStr] = mt
+	}
+	if opts.privateUntil != 0 {
+		mt.privateUntil = opts.privateUntil
+		mt.privateFallback = opts.privateFallback
+		p.privateTxns[hashStr] = mt
+	}
+}
+
+// expirePrivateTxns handles the private txns which weren't included up to block blockNum:
+// they are dropped, or announced as regular txns if they were submitted with fallback.
+func (p *TxPool) expirePrivateTxns(blockNum uint64, announcements *Announcements) {
+	var toDel []*metaTxn
+	for hashStr, mt := range p.privateTxns {
+		if mt.privateUntil > blockNum {
+			continue
+		}
+		if !mt.privateFallback {
+			toDel = append(toDel, mt)
+			continue
+		}
+		if mt.TxnSlot.Traced {
+			p.logger.Info("TX TRACING: private txn expired, going public", "idHash", fmt.Sprintf("%x", mt.TxnSlot.IDHash), "senderId", mt.TxnSlot.SenderID, "nonce", mt.TxnSlot.Nonce)
+		}
+		mt.privateUntil = 0
+		delete(p.privateTxns, hashStr)
+		if mt.gossipable() && mt.currentSubPool == PendingSubPool {
+			announcements.Append(mt.TxnSlot.Type, mt.TxnSlot.Size, mt.TxnSlot.IDHash[:])
+		}
+	}
+	for _, mt := range toDel {
+		if mt.TxnSlot.Traced {
+			p.logger.Info("TX TRACING: private txn expired", "idHash", fmt.Sprintf("%x", mt.TxnSlot.IDHash), "senderId", mt.TxnSlot.SenderID, "nonce", mt.TxnSlot.Nonce, "currentSubPool", mt.currentSubPool)
+		}
+		switch mt.currentSubPool {
+		case PendingSubPool:
+			p.pending.Remove(mt, "private-txn-expired", p.logger)
+		case BaseFeeSubPool:
+			p.baseFee.Remove(mt, "private-txn-expired", p.logger)
+		case QueuedSubPool:
+			p.queued.Remove(mt, "private-txn-expired", p.logger)
+		default:
+			//already removed
+		}
+		p.discardLocked(mt, txpoolcfg.PrivateTxnExpired)
+	}
+	if len(toDel) > 0 {
+		p.logger.Debug("[txpool] Discarded expired private transactions", "count", len(toDel), "block", blockNum)
+	}
+}
+
+// conditionsHold returns false if conditions fail for block blockNum and can't hold in any later block
+func (p *TxPool) conditionsHold(coreTx kv.Tx, conditions *TxnConditions, blockNum, now uint64) (bool, error) {
+	if conditions.expired(blockNum, now) {
+		return false, nil
+	}
+	return conditions.storageMatches(coreTx)
+}
+
+// checkNewConditionsLocked checks the conditions of a new txn against the view of the pool rather than the
+// one of the caller: storage is read by a tx begun under the pool lock, so it can't miss a block the pool
+// has already processed, and storage roots must have been checked against the last block seen by the pool
+// or a later one, as the pool only follows the storage changes of the blocks it processes.
+func (p *TxPool) checkNewConditionsLocked(ctx context.Context, coreDb kv.RoDB, conditions *TxnConditions) (txpoolcfg.DiscardReason, error) {
+	lastSeenBlock := p.lastSeenBlock.Load()
+	if len(conditions.StorageRoots) > 0 && conditions.StorageRootsBlock < lastSeenBlock {
+		return txpoolcfg.ConditionsOutdated, nil
+	}
+	coreTx, err := coreDb.BeginRo(ctx)
+	if err != nil {
+		return txpoolcfg.NotSet, err
+	}
+	defer coreTx.Rollback()
+	hold, err := p.conditionsHold(coreTx, conditions, lastSeenBlock+1, uint64(time.Now().Unix()))
+	if err != nil {
+		return txpoolcfg.NotSet, err
+	}
+	if !hold {
+		return txpoolcfg.ConditionsNotMet, nil
+	}
+	return txpoolcfg.NotSet, nil
+}
+
+// removeConditionsNotMet drops the conditional txns broken by the latest block, with state changes
+// stateChanges (nil if unknown). Txns which are just too early for blockNum stay in the pool,
+// best() skips them until their bounds allow them. Txns whose storage can't be read are dropped too:
+// a conditional txn must not outlive conditions the pool can't check.
+func (p *TxPool) removeConditionsNotMet(coreTx kv.Tx, stateChanges *remote.StateChangeBatch, blockNum, now uint64) {
+	var toDel []*metaTxn
+	for _, mt := range p.conditionalTxns {
+		hold, err := p.conditionsHold(coreTx, mt.conditions, blockNum, now)
+		if err != nil {
+			p.logger.Warn("[txpool] Cannot check transaction conditions, dropping it", "idHash", fmt.Sprintf("%x", mt.TxnSlot.IDHash), "err", err)
+			hold = false
+		}
+		if !hold || mt.conditions.storageRootsChanged(stateChanges) {
+			toDel = append(toDel, mt)
+		}
+	}
+	for _, mt := range toDel {
+		if mt.TxnSlot.Traced {
+			p.logger.Info("TX TRACING: removeConditionsNotMet", "idHash", fmt.Sprintf("%x", mt.TxnSlot.IDHash), "senderId", mt.TxnSlot.SenderID, "nonce", mt.TxnSlot.Nonce, "currentSubPool", mt.currentSubPool)
+		}
+		switch mt.currentSubPool {
+		case PendingSubPool:
+			p.pending.Remove(mt, "conditions-not-met", p.logger)
+		case BaseFeeSubPool:
+			p.baseFee.Remove(mt, "conditions-not-met", p.logger)
+		case QueuedSubPool:
+			p.queued.Remove(mt, "conditions-not-met", p.logger)
+		default:
+			//already removed
+		}
+		p.discardLocked(mt, txpoolcfg.ConditionsNotMet)
+	}
+	if len(toDel) > 0 {
+		p.logger.Debug("[txpool] Discarded conditional transactions", "count", len(toDel), "block", blockNum)
+	}
+}
+
 // onSenderStateChange is the function that recalculates ephemeral fields of transactions and determines
 // which sub pool they will need to go to. Since this depends on other transactions from the same sender by with lower
 // nonces, and also affect other transactions from the same sender with higher nonce, it loops through all transactions
@@ -1676,13 +2147,14 @@ func (p *TxPool) onSenderStateChange(senderID uint64, senderNonce uint64, sender
 func (p *TxPool) promote(pendingBaseFee uint64, pendingBlobFee uint64, announcements *Announcements, logger log.Logger) {
 	// Demote worst transactions that do not qualify for pending sub pool anymore, to other sub pools, or discard
 	for worst := p.pending.Worst(); p.pending.Len() > 0 && (worst.subPool < BaseFeePoolBits || worst.minFeeCap.LtUint64(pendingBaseFee) || (worst.TxnSlot.Type == BlobTxnType && worst.TxnSlot.BlobFeeCap.LtUint64(pendingBlobFee))); worst = p.pending.Worst() {
+		tx := p.pending.PopWorst()
 		if worst.subPool >= BaseFeePoolBits {
-			tx := p.pending.PopWorst()
 			announcements.Append(tx.TxnSlot.Type, tx.TxnSlot.Size, tx.TxnSlot.IDHash[:])
 			p.baseFee.Add(tx, "demote-pending", logger)
 		} else {
-			p.queued.Add(p.pending.PopWorst(), "demote-pending", logger)
+			p.queued.Add(tx, "demote-pending", logger)
 		}
+		p.emitMovedLocked(tx, PendingSubPool)
 	}
 
 	// Promote best transactions from base fee pool to pending pool while they qualify
@@ -1690,22 +2162,26 @@ func (p *TxPool) promote(pendingBaseFee uint64, pendingBlobFee uint64, announcem
 		tx := p.baseFee.PopBest()
 		announcements.Append(tx.TxnSlot.Type, tx.TxnSlot.Size, tx.TxnSlot.IDHash[:])
 		p.pending.Add(tx, logger)
+		p.emitMovedLocked(tx, BaseFeeSubPool)
 	}
 
 	// Demote worst transactions that do not qualify for base fee pool anymore, to queued sub pool, or discard
 	for worst := p.baseFee.Worst(); p.baseFee.Len() > 0 && worst.subPool < BaseFeePoolBits; worst = p.baseFee.Worst() {
-		p.queued.Add(p.baseFee.PopWorst(), "demote-base", logger)
+		tx := p.baseFee.PopWorst()
+		p.queued.Add(tx, "demote-base", logger)
+		p.emitMovedLocked(tx, BaseFeeSubPool)
 	}
 
 	// Promote best transactions from the queued pool to either pending or base fee pool, while they qualify
 	for best := p.queued.Best(); p.queued.Len() > 0 && best.subPool >= BaseFeePoolBits; best = p.queued.Best() {
+		tx := p.queued.PopBest()
 		if best.minFeeCap.Cmp(uint256.NewInt(pendingBaseFee)) >= 0 {
-			tx := p.queued.PopBest()
 			announcements.Append(tx.TxnSlot.Type, tx.TxnSlot.Size, tx.TxnSlot.IDHash[:])
 			p.pending.Add(tx, logger)
 		} else {
-			p.baseFee.Add(p.queued.PopBest(), "promote-queued", logger)
+			p.baseFee.Add(tx, "promote-queued", logger)
 		}
+		p.emitMovedLocked(tx, QueuedSubPool)
 	}
 
 	// Discard worst transactions from the queued sub pool if they do not qualify
@@ -1836,6 +2312,10 @@ func MainLoop(ctx context.Context, p *TxPool, newTxns chan Announcements, send *
 							continue
 						}
 
+						// Private and conditional transactions are neither gossiped nor published to the subscribers
+						if !p.IsGossipable(hash) {
+							continue
+						}
 						// Empty rlp can happen if a transaction we want to broadcast has just been mined, for example
 						slotsRlp = append(slotsRlp, slotRlp)
 						if p.IsLocal(hash) {
@@ -1906,9 +2386,10 @@ func MainLoop(ctx context.Context, p *TxPool, newTxns chan Announcements, send *
 	}
 }
 
-func (p *TxPool) flushNoFsync(ctx context.Context) (written uint64, err error) {
+func (p *TxPool) flushNoFsync(ctx context.Context) (written uint64, blobs *blobStoreFlush, err error) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	blobs = p.blobStore.beginFlush()
 	//it's important that write db txn is done inside lock, to make last writes visible for all read operations
 	if err := p.poolDB.UpdateNosync(ctx, func(tx kv.RwTx) error {
 		err = p.flushLocked(tx)
@@ -1921,16 +2402,24 @@ func (p *TxPool) flushNoFsync(ctx context.Context) (written uint64, err error) {
 		}
 		return nil
 	}); err != nil {
-		return 0, err
+		p.blobStore.endFlush(blobs, err)
+		return 0, nil, err
 	}
-	return written, nil
+	return written, blobs, nil
 }
 
 func (p *TxPool) flush(ctx context.Context) (written uint64, err error) {
 	defer writeToDBTimer.ObserveDuration(time.Now())
 	// 1. get global lock on txpool and flush it to db, without fsync (to release lock asap)
-	// 2. then fsync db without txpool lock
-	written, err = p.flushNoFsync(ctx)
+	// 2. then write and fsync the blob store files, and fsync db, without txpool lock
+	written, blobs, err := p.flushNoFsync(ctx)
+	if err != nil {
+		return 0, err
+	}
+	err = blobs.write()
+	p.lock.Lock()
+	p.blobStore.endFlush(blobs, err)
+	p.lock.Unlock()
 	if err != nil {
 		return 0, err
 	}
@@ -1977,9 +2466,28 @@ func (p *TxPool) flushLocked(tx kv.RwTx) (err error) {
 				return err
 			}
 		}
+		if mt.conditions != nil {
+			if err := tx.Delete(kv.PoolTxnConditions, idHash); err != nil {
+				return err
+			}
+		}
 		p.deletedTxns[i] = nil // for gc
 	}
 
+	// private txns are few and may go public at any block: rewrite them all
+	if err := tx.ClearBucket(kv.PoolPrivateTransaction); err != nil {
+		return err
+	}
+	for txHash, mt := range p.privateTxns {
+		if err := PutPrivateTxn(tx, []byte(txHash), mt.privateUntil, mt.privateFallback); err != nil {
+			return err
+		}
+	}
+
+	if err := p.flushTxnStatusLocked(tx); err != nil {
+		return err
+	}
+
 	txHashes := p.isLocalLRU.Keys()
 	encID := make([]byte, 8)
 	if err := tx.ClearBucket(kv.RecentLocalTransaction); err != nil {
@@ -2016,6 +2524,11 @@ func (p *TxPool) flushLocked(tx kv.RwTx) (err error) {
 			if err := tx.Put(kv.PoolTransaction, []byte(txHash), v); err != nil {
 				return err
 			}
+			if metaTx.conditions != nil {
+				if err := tx.Put(kv.PoolTxnConditions, []byte(txHash), metaTx.conditions.encode()); err != nil {
+					return err
+				}
+			}
 		}
 		metaTx.TxnSlot.Rlp = nil
 	}
@@ -2036,6 +2549,7 @@ func (p *TxPool) flushLocked(tx kv.RwTx) (err error) {
 	// DB will stay consistent but some in-memory structures may be already cleaned, and retry will not work
 	// failed write transaction must not create side-effects
 	p.deletedTxns = p.deletedTxns[:0]
+	clear(p.txnStatusDirty)
 	return nil
 }
 
@@ -2081,6 +2595,36 @@ func (p *TxPool) fromDB(ctx context.Context, tx kv.Tx, coreTx kv.Tx) error {
 		p.isLocalLRU.Add(string(v), struct{}{})
 	}
 
+	if err := p.txnStatusFromDB(tx); err != nil {
+		return err
+	}
+
+	conditions := map[string]*TxnConditions{}
+	it, err = tx.Range(kv.PoolTxnConditions, nil, nil, order.Asc, kv.Unlim)
+	if err != nil {
+		return err
+	}
+	for it.HasNext() {
+		k, v, err := it.Next()
+		if err != nil {
+			return err
+		}
+		c, err := decodeTxnConditions(v)
+		if err != nil {
+			p.logger.Warn("[txpool] fromDB: decodeTxnConditions", "err", err)
+			continue
+		}
+		conditions[string(k)] = c
+	}
+
+	private := map[string]localTxnOpts{}
+	if err := ForEachPrivateTxn(tx, func(idHash []byte, until uint64, fallback bool) error {
+		private[string(idHash)] = localTxnOpts{privateUntil: until, privateFallback: fallback}
+		return nil
+	}); err != nil {
+		return err
+	}
+
 	txns := TxnSlots{}
 	parseCtx := NewTxnParseContext(p.chainID)
 	parseCtx.WithSender(false)
@@ -2096,6 +2640,14 @@ func (p *TxPool) fromDB(ctx context.Context, tx kv.Tx, coreTx kv.Tx) error {
 			return err
 		}
 		addr, txnRlp := *(*[20]byte)(v[:20]), v[20:]
+		// blob txns are stored without their sidecars, which are in the blob store
+		storedRlp, err := p.blobStore.Get(k)
+		if err != nil {
+			return err
+		}
+		if storedRlp != nil {
+			txnRlp = storedRlp
+		}
 		txn := &TxnSlot{}
 
 		// TODO(eip-4844) ensure wrappedWithBlobs when transactions are saved to the DB
@@ -2105,7 +2657,11 @@ func (p *TxPool) fromDB(ctx context.Context, tx kv.Tx, coreTx kv.Tx) error {
 			p.logger.Warn("[txpool] fromDB: parseTransaction", "err", err)
 			continue
 		}
-		txn.Rlp = nil // means that we don't need store it in db anymore
+		if txn.Type == BlobTxnType && storedRlp == nil {
+			txn.Rlp = common.Copy(txn.Rlp) // stored with its sidecar before the blob store, move it there
+		} else {
+			txn.Rlp = nil // means that we don't need store it in db anymore
+		}
 
 		txn.SenderID, txn.Traced = p.senders.getOrCreateID(addr, p.logger)
 		isLocalTx := p.isLocalLRU.Contains(string(k))
@@ -2167,6 +2723,20 @@ func (p *TxPool) fromDB(ctx context.Context, tx kv.Tx, coreTx kv.Tx) error {
 		pendingBaseFee, pendingBlobFee, blockGasLimit, false, p.logger); err != nil {
 		return err
 	}
+	// mined txns aren't tracked across restarts, and an unclean shutdown may leave discarded txns behind
+	p.blobStore.Prune(func(hash []byte) bool {
+		_, ok := p.byHash[string(hash)]
+		return ok
+	})
+	for hashStr, c := range conditions {
+		p.setLocalTxnOptsLocked(hashStr, localTxnOpts{conditions: c})
+	}
+	for hashStr, opts := range private {
+		p.setLocalTxnOptsLocked(hashStr, opts)
+	}
+	// txns going public are announced to peers by the regular sync with new peers
+	p.expirePrivateTxns(p.lastSeenBlock.Load(), &Announcements{})
+	p.removeConditionsNotMet(coreTx, nil /* stateChanges */, p.lastSeenBlock.Load()+1, uint64(time.Now().Unix()))
 	p.pendingBaseFee.Store(pendingBaseFee)
 	p.pendingBlobFee.Store(pendingBlobFee)
 	p.blockGasLimit.Store(blockGasLimit)
@@ -2218,7 +2788,7 @@ func (p *TxPool) logStats() {
 }
 
 // Deprecated need switch to streaming-like
-func (p *TxPool) deprecatedForEach(_ context.Context, f func(rlp []byte, sender common.Address, t SubPoolType), tx kv.Tx) {
+func (p *TxPool) deprecatedForEach(_ context.Context, f func(rlp []byte, sender common.Address, t SubPoolType, private bool), tx kv.Tx) {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 	p.all.ascendAll(func(mt *metaTxn) bool {
@@ -2237,7 +2807,7 @@ func (p *TxPool) deprecatedForEach(_ context.Context, f func(rlp []byte, sender
 			slotRlp = v[20:]
 		}
 		if sender, found := p.senders.senderID2Addr[slot.SenderID]; found {
-			f(slotRlp, sender, mt.currentSubPool)
+			f(slotRlp, sender, mt.currentSubPool, mt.privateUntil != 0)
 		}
 		return true
 	})
@@ -96,3 +96,24 @@ func PutChainConfig(tx kv.Putter, cc *chain.Config, buf []byte) error {
 	}
 	return nil
 }
+
+// PutPrivateTxn persists the private flag of a txn: the block after which it expires, and whether it's
+// gossiped instead of dropped at expiry
+func PutPrivateTxn(tx kv.Putter, idHash []byte, until uint64, fallback bool) error {
+	v := make([]byte, 9)
+	binary.BigEndian.PutUint64(v, until)
+	if fallback {
+		v[8] = 1
+	}
+	return tx.Put(kv.PoolPrivateTransaction, idHash, v)
+}
+
+// ForEachPrivateTxn walks over the txns persisted with PutPrivateTxn
+func ForEachPrivateTxn(tx kv.Tx, f func(idHash []byte, until uint64, fallback bool) error) error {
+	return tx.ForEach(kv.PoolPrivateTransaction, nil, func(k, v []byte) error {
+		if len(v) != 9 {
+			return fmt.Errorf("invalid private txn record of %x: %x", k, v)
+		}
+		return f(k, binary.BigEndian.Uint64(v), v[8] == 1)
+	})
+}
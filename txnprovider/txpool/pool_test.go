@@ -23,10 +23,13 @@ import (
 	"math"
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/c2h5oh/datasize"
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 
 	"github.com/erigontech/erigon-lib/common/datadir"
 	"github.com/erigontech/erigon-lib/kv/temporal/temporaltest"
@@ -39,6 +42,7 @@ import (
 	"github.com/erigontech/erigon-lib/crypto/kzg"
 	"github.com/erigontech/erigon-lib/gointerfaces"
 	remote "github.com/erigontech/erigon-lib/gointerfaces/remoteproto"
+	"github.com/erigontech/erigon-lib/gointerfaces/txpoolproto"
 	"github.com/erigontech/erigon-lib/kv"
 	"github.com/erigontech/erigon-lib/kv/kvcache"
 	"github.com/erigontech/erigon-lib/kv/memdb"
@@ -1231,3 +1235,673 @@ type sender struct {
 func newSender(nonce uint64, balance uint256.Int) *sender {
 	return &sender{nonce: nonce, balance: balance}
 }
+
+func TestGetBlobs(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+	ch := make(chan Announcements, 5)
+	coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	db := memdb.NewTestPoolDB(t)
+
+	cfg := txpoolcfg.DefaultConfig
+	sendersCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	// chain id of makeBlobTxn, for the sidecars to be parsed back from the blob store
+	pool, err := New(ch, db, coreDB, cfg, sendersCache, *uint256.NewInt(5), common.Big0, nil, common.Big0, nil, fixedgas.DefaultMaxBlobsPerBlock, nil, log.New())
+	assert.NoError(err)
+	require.True(pool != nil)
+	ctx := context.Background()
+	var stateVersionID uint64 = 0
+
+	h1 := gointerfaces.ConvertHashToH256([32]byte{})
+	change := &remote.StateChangeBatch{
+		StateVersionId:       stateVersionID,
+		PendingBlockBaseFee:  200_000,
+		BlockGasLimit:        1000000,
+		PendingBlobFeePerGas: 100_000,
+		ChangeBatch: []*remote.StateChange{
+			{BlockHeight: 0, BlockHash: h1},
+		},
+	}
+	var addr [20]byte
+	addr[0] = 1
+
+	// Add 1 eth to the user account, as a part of change
+	v := types2.EncodeAccountBytesV3(2, uint256.NewInt(1*common.Ether), make([]byte, 32), 1)
+
+	change.ChangeBatch[0].Changes = append(change.ChangeBatch[0].Changes, &remote.AccountChange{
+		Action:  remote.Action_UPSERT,
+		Address: gointerfaces.ConvertAddressToH160(addr),
+		Data:    v,
+	})
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+	assert.NoError(err)
+
+	blobTxn := makeBlobTxn()
+	blobTxn.IDHash[0] = 0x01
+	blobTxn.Nonce = 0x2
+	// the pool moves the sidecar of the added txn to the blob store
+	wantBlobs, wantProofs := blobTxn.Blobs, blobTxn.Proofs
+	txnSlots := TxnSlots{}
+	txnSlots.Append(&blobTxn, addr[:], true)
+	reasons, err := pool.AddLocalTxns(ctx, txnSlots)
+	assert.NoError(err)
+	for _, reason := range reasons {
+		assert.Equal(txpoolcfg.Success, reason, reason.String())
+	}
+	assert.Nil(blobTxn.Blobs)
+	assert.Equal(1, pool.blobStore.Len())
+
+	unknown := common.Hash{0x42}
+	blobs, proofs := pool.GetBlobs([]common.Hash{blobTxn.BlobHashes[1], unknown, blobTxn.BlobHashes[0]})
+	require.Len(blobs, 3)
+	require.Len(proofs, 3)
+	assert.Equal(wantBlobs[1], blobs[0])
+	assert.Equal(wantProofs[1][:], proofs[0])
+	assert.Nil(blobs[1])
+	assert.Nil(proofs[1])
+	assert.Equal(wantBlobs[0], blobs[2])
+	assert.Equal(wantProofs[0][:], proofs[2])
+
+	// blobs are no longer served once the txn leaves the pool
+	pool.lock.Lock()
+	pool.discardLocked(pool.blobHashToTxn[blobTxn.BlobHashes[0]].mt, txpoolcfg.Mined)
+	pool.lock.Unlock()
+	blobs, _ = pool.GetBlobs(blobTxn.BlobHashes)
+	assert.Nil(blobs[0])
+	assert.Nil(blobs[1])
+}
+
+func TestBlobStoreOverflow(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+	ch := make(chan Announcements, 5)
+	coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	db := memdb.NewTestPoolDB(t)
+
+	blobTxnSize := len(makeBlobTxn().Rlp)
+	cfg := txpoolcfg.DefaultConfig
+	cfg.BlobStoreSize = datasize.ByteSize(blobTxnSize * 5 / 2) // room for two txns
+	sendersCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	// chain id of makeBlobTxn, for the sidecars to be parsed back from the blob store
+	pool, err := New(ch, db, coreDB, cfg, sendersCache, *uint256.NewInt(5), common.Big0, nil, common.Big0, nil, fixedgas.DefaultMaxBlobsPerBlock, nil, log.New())
+	assert.NoError(err)
+	require.True(pool != nil)
+	ctx := context.Background()
+
+	h1 := gointerfaces.ConvertHashToH256([32]byte{})
+	change := &remote.StateChangeBatch{
+		StateVersionId:       0,
+		PendingBlockBaseFee:  200_000,
+		BlockGasLimit:        1000000,
+		PendingBlobFeePerGas: 100_000,
+		ChangeBatch: []*remote.StateChange{
+			{BlockHeight: 0, BlockHash: h1},
+		},
+	}
+	addrs := make([][20]byte, 3)
+	for i := range addrs {
+		addrs[i][0] = byte(i + 1)
+		v := types2.EncodeAccountBytesV3(0, uint256.NewInt(1*common.Ether), make([]byte, 32), 1)
+		change.ChangeBatch[0].Changes = append(change.ChangeBatch[0].Changes, &remote.AccountChange{
+			Action:  remote.Action_UPSERT,
+			Address: gointerfaces.ConvertAddressToH160(addrs[i]),
+			Data:    v,
+		})
+	}
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+	assert.NoError(err)
+
+	addBlobTxn := func(id, sender int, nonce, blobFeeCap uint64) (TxnSlot, txpoolcfg.DiscardReason) {
+		blobTxn := makeBlobTxn()
+		blobTxn.IDHash[0] = byte(id)
+		blobTxn.Nonce = nonce
+		blobTxn.BlobFeeCap = *uint256.NewInt(blobFeeCap)
+		txnSlots := TxnSlots{}
+		txnSlots.Append(&blobTxn, addrs[sender][:], true)
+		reasons, err := pool.AddLocalTxns(ctx, txnSlots)
+		assert.NoError(err)
+		return blobTxn, reasons[0]
+	}
+	pooled := func(txn TxnSlot) bool {
+		pool.lock.Lock()
+		defer pool.lock.Unlock()
+		_, ok := pool.byHash[string(txn.IDHash[:])]
+		return ok
+	}
+
+	cheap, reason := addBlobTxn(1, 0, 0, 200_000)
+	assert.Equal(txpoolcfg.Success, reason, reason.String())
+	assert.True(pool.blobStore.Has(cheap.IDHash[:]))
+	cheapNext, reason := addBlobTxn(2, 0, 1, 400_000)
+	assert.Equal(txpoolcfg.Success, reason, reason.String())
+	assert.True(pool.blobStore.Has(cheapNext.IDHash[:]))
+
+	// a better paying txn evicts the sender worth the least, from its highest nonce down: its next txn can't be
+	// mined before its cheap one, however much it pays
+	expensive, reason := addBlobTxn(3, 1, 0, 300_000)
+	assert.Equal(txpoolcfg.Success, reason, reason.String())
+	assert.True(pool.blobStore.Has(expensive.IDHash[:]))
+	assert.False(pool.blobStore.Has(cheapNext.IDHash[:]))
+	assert.False(pooled(cheapNext))
+	assert.True(pool.blobStore.Has(cheap.IDHash[:]))
+	assert.True(pooled(cheap))
+
+	// a txn doesn't evict the lower nonces of its own sender
+	_, reason = addBlobTxn(4, 0, 1, 500_000)
+	assert.Equal(txpoolcfg.BlobStoreOverflow, reason, reason.String())
+	assert.True(pooled(cheap))
+
+	// a better paying txn evicts the cheap one
+	expensive2, reason := addBlobTxn(5, 2, 0, 250_000)
+	assert.Equal(txpoolcfg.Success, reason, reason.String())
+	assert.True(pool.blobStore.Has(expensive2.IDHash[:]))
+	assert.False(pool.blobStore.Has(cheap.IDHash[:]))
+	assert.False(pooled(cheap))
+
+	// and a worse paying one doesn't get in
+	_, reason = addBlobTxn(6, 0, 0, 150_000)
+	assert.Equal(txpoolcfg.BlobStoreOverflow, reason, reason.String())
+	assert.Equal(2, pool.blobStore.Len())
+	assert.Equal(uint64(2*blobTxnSize), pool.blobStore.Size())
+
+	// the stored txn is served in its network form, from memory and after a flush
+	pool.lock.Lock()
+	rlpTxn, _, _, err := pool.getRlpLocked(nil, expensive.IDHash[:])
+	pool.lock.Unlock()
+	require.NoError(err)
+	assert.Len(rlpTxn, blobTxnSize)
+	_, err = pool.flush(ctx)
+	require.NoError(err)
+	pool.lock.Lock()
+	rlpTxn, _, _, err = pool.getRlpLocked(nil, expensive.IDHash[:])
+	pool.lock.Unlock()
+	require.NoError(err)
+	assert.Len(rlpTxn, blobTxnSize)
+}
+
+func TestConditionalTxns(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+	ch := make(chan Announcements, 100)
+
+	coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	db := memdb.NewTestPoolDB(t)
+	cfg := txpoolcfg.DefaultConfig
+	sendersCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	pool, err := New(ch, db, coreDB, cfg, sendersCache, *u256.N1, nil, nil, nil, nil, fixedgas.DefaultMaxBlobsPerBlock, nil, log.New())
+	assert.NoError(err)
+	require.True(pool != nil)
+	ctx := context.Background()
+
+	h1 := gointerfaces.ConvertHashToH256([32]byte{})
+	var addr, watched [20]byte
+	addr[0], watched[0] = 1, 2
+	v := types2.EncodeAccountBytesV3(0, uint256.NewInt(1*common.Ether), make([]byte, 32), 1)
+	change := &remote.StateChangeBatch{
+		PendingBlockBaseFee: 200_000,
+		BlockGasLimit:       1_000_000,
+		ChangeBatch: []*remote.StateChange{
+			{BlockHeight: 0, BlockHash: h1},
+		},
+	}
+	change.ChangeBatch[0].Changes = append(change.ChangeBatch[0].Changes, &remote.AccountChange{
+		Action:  remote.Action_UPSERT,
+		Address: gointerfaces.ConvertAddressToH160(addr),
+		Data:    v,
+	})
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+	assert.NoError(err)
+
+	newTxn := func(nonce uint64, id byte) TxnSlots {
+		var txnSlots TxnSlots
+		txnSlot := &TxnSlot{
+			Tip:    *uint256.NewInt(300_000),
+			FeeCap: *uint256.NewInt(300_000),
+			Gas:    100_000,
+			Nonce:  nonce,
+		}
+		txnSlot.IDHash[0] = id
+		txnSlots.Append(txnSlot, addr[:], true)
+		return txnSlots
+	}
+
+	// bounds which can't hold anymore are rejected
+	reasons, err := pool.AddLocalConditionalTxns(ctx, newTxn(0, 1), &TxnConditions{TimestampMax: 1})
+	require.NoError(err)
+	require.Len(reasons, 1)
+	assert.Equal(txpoolcfg.ConditionsNotMet, reasons[0], reasons[0].String())
+
+	_, err = pool.AddLocalConditionalTxns(ctx, newTxn(0, 1), &TxnConditions{BlockNumberMin: 3, BlockNumberMax: 2})
+	assert.ErrorIs(err, ErrConditionsBounds)
+
+	// unset slots are zero
+	conditions := &TxnConditions{
+		KnownAccounts:  map[common.Address]map[common.Hash]common.Hash{watched: {{0x01}: {}}},
+		StorageRoots:   map[common.Address]common.Hash{watched: {0x02}},
+		BlockNumberMax: 5,
+	}
+	txnSlots := newTxn(0, 2)
+	reasons, err = pool.AddLocalConditionalTxns(ctx, txnSlots, conditions)
+	require.NoError(err)
+	for _, reason := range reasons {
+		assert.Equal(txpoolcfg.Success, reason, reason.String())
+	}
+	mt, ok := pool.byHash[string(txnSlots.Txns[0].IDHash[:])]
+	require.True(ok)
+	assert.Equal(PendingSubPool, mt.currentSubPool)
+	assert.False(pool.IsGossipable(mt.TxnSlot.IDHash[:]))
+
+	// conditional txns are never announced to peers
+	announcedTypes, _, _ := pool.AppendLocalAnnouncements(nil, nil, nil)
+	assert.Empty(announcedTypes)
+
+	// a block not touching the watched account keeps the txn
+	change.ChangeBatch[0] = &remote.StateChange{BlockHeight: 1, BlockHash: h1}
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+	assert.NoError(err)
+	_, ok = pool.byHash[string(mt.TxnSlot.IDHash[:])]
+	assert.True(ok)
+
+	// storage roots must be checked against the last block seen by the pool or a later one
+	reasons, err = pool.AddLocalConditionalTxns(ctx, newTxn(1, 3), &TxnConditions{StorageRoots: map[common.Address]common.Hash{watched: {0x02}}})
+	require.NoError(err)
+	require.Len(reasons, 1)
+	assert.Equal(txpoolcfg.ConditionsOutdated, reasons[0], reasons[0].String())
+	checkedLater := &TxnConditions{
+		KnownAccounts:     map[common.Address]map[common.Hash]common.Hash{watched: {{0x01}: {}}},
+		StorageRoots:      map[common.Address]common.Hash{watched: {0x02}},
+		StorageRootsBlock: 2,
+	}
+	laterSlots := newTxn(1, 4)
+	reasons, err = pool.AddLocalConditionalTxns(ctx, laterSlots, checkedLater)
+	require.NoError(err)
+	for _, reason := range reasons {
+		assert.Equal(txpoolcfg.Success, reason, reason.String())
+	}
+
+	// changing the storage of the watched account breaks its storage root condition
+	change.ChangeBatch[0] = &remote.StateChange{BlockHeight: 2, BlockHash: h1, Changes: []*remote.AccountChange{{
+		Action:         remote.Action_STORAGE,
+		Address:        gointerfaces.ConvertAddressToH160(watched),
+		StorageChanges: []*remote.StorageChange{{Location: gointerfaces.ConvertHashToH256(common.Hash{0x03})}},
+	}}}
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+	assert.NoError(err)
+	_, ok = pool.byHash[string(mt.TxnSlot.IDHash[:])]
+	assert.False(ok)
+	reason, ok := pool.discardReasonsLRU.Get(string(mt.TxnSlot.IDHash[:]))
+	assert.True(ok)
+	assert.Equal(txpoolcfg.ConditionsNotMet, reason)
+	// unless its roots were checked against that block already
+	laterIdHash := string(laterSlots.Txns[0].IDHash[:])
+	_, ok = pool.byHash[laterIdHash]
+	assert.True(ok)
+	assert.Len(pool.conditionalTxns, 1)
+
+	// storage which can't be read drops the txn instead of failing the block
+	poolTx, err := db.BeginRo(ctx)
+	require.NoError(err)
+	defer poolTx.Rollback()
+	pool.lock.Lock()
+	pool.removeConditionsNotMet(poolTx, &remote.StateChangeBatch{}, 3, 0)
+	pool.lock.Unlock()
+	_, ok = pool.byHash[laterIdHash]
+	assert.False(ok)
+	assert.Empty(pool.conditionalTxns)
+	reason, ok = pool.discardReasonsLRU.Get(laterIdHash)
+	assert.True(ok)
+	assert.Equal(txpoolcfg.ConditionsNotMet, reason)
+}
+
+func TestTxnConditionsBounds(t *testing.T) {
+	c := &TxnConditions{BlockNumberMin: 10, BlockNumberMax: 20, TimestampMin: 1000, TimestampMax: 2000}
+	assert.False(t, c.includable(9, 1500))
+	assert.True(t, c.includable(10, 1500))
+	assert.True(t, c.includable(20, 0)) // unknown timestamp
+	assert.False(t, c.includable(15, 999))
+	assert.False(t, c.expired(15, 999))
+	assert.True(t, c.expired(21, 1500))
+	assert.True(t, c.expired(15, 2001))
+
+	unbounded := &TxnConditions{}
+	assert.True(t, unbounded.includable(1, 1))
+	assert.False(t, unbounded.expired(math.MaxUint64, math.MaxUint64))
+}
+
+func TestTxnConditionsEncoding(t *testing.T) {
+	c := &TxnConditions{
+		KnownAccounts: map[common.Address]map[common.Hash]common.Hash{
+			{0x01}: {{0x01}: {0x02}, {0x03}: {0x04}},
+			{0x02}: {{0x05}: {0x06}},
+		},
+		StorageRoots:   map[common.Address]common.Hash{{0x03}: {0x07}},
+		BlockNumberMin: 1,
+		BlockNumberMax: 2,
+		TimestampMin:   3,
+		TimestampMax:   4,
+	}
+	decoded, err := decodeTxnConditions(c.encode())
+	require.NoError(t, err)
+	assert.Equal(t, c, decoded)
+
+	decoded, err = decodeTxnConditions((&TxnConditions{BlockNumberMax: 7}).encode())
+	require.NoError(t, err)
+	assert.Equal(t, &TxnConditions{BlockNumberMax: 7}, decoded)
+
+	_, err = decodeTxnConditions(c.encode()[:40])
+	assert.Error(t, err)
+}
+
+func TestPrivateTxns(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+	ch := make(chan Announcements, 100)
+
+	coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	db := memdb.NewTestPoolDB(t)
+	cfg := txpoolcfg.DefaultConfig
+	cfg.PrivateTxnLifetime = 5
+	sendersCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	pool, err := New(ch, db, coreDB, cfg, sendersCache, *u256.N1, nil, nil, nil, nil, fixedgas.DefaultMaxBlobsPerBlock, nil, log.New())
+	assert.NoError(err)
+	require.True(pool != nil)
+	ctx := context.Background()
+
+	h1 := gointerfaces.ConvertHashToH256([32]byte{})
+	var addr [20]byte
+	addr[0] = 1
+	v := types2.EncodeAccountBytesV3(0, uint256.NewInt(1*common.Ether), make([]byte, 32), 1)
+	change := &remote.StateChangeBatch{
+		PendingBlockBaseFee: 200_000,
+		BlockGasLimit:       1_000_000,
+		ChangeBatch: []*remote.StateChange{
+			{BlockHeight: 0, BlockHash: h1},
+		},
+	}
+	change.ChangeBatch[0].Changes = append(change.ChangeBatch[0].Changes, &remote.AccountChange{
+		Action:  remote.Action_UPSERT,
+		Address: gointerfaces.ConvertAddressToH160(addr),
+		Data:    v,
+	})
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+	assert.NoError(err)
+	newBlock := func(n uint64) {
+		change.ChangeBatch[0] = &remote.StateChange{BlockHeight: n, BlockHash: h1}
+		err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+		assert.NoError(err)
+	}
+
+	var txnSlots TxnSlots
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		txnSlot := &TxnSlot{
+			Tip:    *uint256.NewInt(300_000),
+			FeeCap: *uint256.NewInt(300_000),
+			Gas:    100_000,
+			Nonce:  nonce,
+			Rlp:    []byte{byte(nonce)},
+		}
+		txnSlot.IDHash[0] = byte(nonce + 1)
+		txnSlots.Append(txnSlot, addr[:], true)
+	}
+	dropped, public := txnSlots.Txns[0].IDHash, txnSlots.Txns[1].IDHash
+
+	reasons, err := pool.AddLocalPrivateTxns(ctx, TxnSlots{Txns: txnSlots.Txns[:1], Senders: txnSlots.Senders.At(0), IsLocal: txnSlots.IsLocal[:1]}, 3, false)
+	require.NoError(err)
+	assert.Equal([]txpoolcfg.DiscardReason{txpoolcfg.Success}, reasons)
+	// the lifetime comes first
+	reasons, err = pool.AddLocalPrivateTxns(ctx, TxnSlots{Txns: txnSlots.Txns[1:], Senders: txnSlots.Senders.At(1), IsLocal: txnSlots.IsLocal[1:]}, 100, true)
+	require.NoError(err)
+	assert.Equal([]txpoolcfg.DiscardReason{txpoolcfg.Success}, reasons)
+	assert.Equal(uint64(5), pool.byHash[string(public[:])].privateUntil)
+
+	// private txns are never announced to peers
+	announcedTypes, _, _ := pool.AppendLocalAnnouncements(nil, nil, nil)
+	assert.Empty(announcedTypes)
+	assert.False(pool.IsGossipable(dropped[:]))
+
+	var contentPrivate int
+	pool.deprecatedForEach(ctx, func(rlp []byte, sender common.Address, t SubPoolType, private bool) {
+		if private {
+			contentPrivate++
+		}
+	}, nil)
+	assert.Equal(2, contentPrivate)
+
+	// the private flag persists across restarts
+	tx, err := db.BeginRw(ctx)
+	require.NoError(err)
+	defer tx.Rollback()
+	pool.lock.Lock()
+	require.NoError(pool.flushLocked(tx))
+	pool.lock.Unlock()
+	persisted := map[[32]byte]uint64{}
+	require.NoError(ForEachPrivateTxn(tx, func(idHash []byte, until uint64, fallback bool) error {
+		persisted[[32]byte(idHash)] = until
+		return nil
+	}))
+	assert.Equal(map[[32]byte]uint64{dropped: 3, public: 5}, persisted)
+	tx.Rollback()
+
+	newBlock(2)
+	_, ok := pool.byHash[string(dropped[:])]
+	assert.True(ok)
+
+	newBlock(3)
+	_, ok = pool.byHash[string(dropped[:])]
+	assert.False(ok)
+	reason, ok := pool.discardReasonsLRU.Get(string(dropped[:]))
+	assert.True(ok)
+	assert.Equal(txpoolcfg.PrivateTxnExpired, reason)
+
+	// falls back to public gossip
+	newBlock(5)
+	mt, ok := pool.byHash[string(public[:])]
+	require.True(ok)
+	assert.True(mt.gossipable())
+	assert.True(pool.IsGossipable(public[:]))
+	assert.Empty(pool.privateTxns)
+}
+
+// onAddStream collects what is published to the newPendingTransactions subscribers
+type onAddStream struct {
+	grpc.ServerStream
+	replies chan *txpoolproto.OnAddReply
+}
+
+func (s *onAddStream) Send(reply *txpoolproto.OnAddReply) error {
+	s.replies <- reply
+	return nil
+}
+
+func TestPrivateTxnsAreNotPublished(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+	ch := make(chan Announcements, 100)
+
+	coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	db := memdb.NewTestPoolDB(t)
+	cfg := txpoolcfg.DefaultConfig
+	sendersCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	logger := log.New()
+	pool, err := New(ch, db, coreDB, cfg, sendersCache, *u256.N1, nil, nil, nil, nil, fixedgas.DefaultMaxBlobsPerBlock, nil, logger)
+	require.NoError(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	require.NoError(pool.Start(ctx))
+
+	h1 := gointerfaces.ConvertHashToH256([32]byte{})
+	var addr [20]byte
+	addr[0] = 1
+	v := types2.EncodeAccountBytesV3(0, uint256.NewInt(1*common.Ether), make([]byte, 32), 1)
+	change := &remote.StateChangeBatch{
+		PendingBlockBaseFee: 200_000,
+		BlockGasLimit:       1_000_000,
+		ChangeBatch: []*remote.StateChange{
+			{BlockHeight: 0, BlockHash: h1},
+		},
+	}
+	change.ChangeBatch[0].Changes = append(change.ChangeBatch[0].Changes, &remote.AccountChange{
+		Action:  remote.Action_UPSERT,
+		Address: gointerfaces.ConvertAddressToH160(addr),
+		Data:    v,
+	})
+	require.NoError(pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{}))
+
+	var txnSlots TxnSlots
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		txnSlot := &TxnSlot{
+			Tip:    *uint256.NewInt(300_000),
+			FeeCap: *uint256.NewInt(300_000),
+			Gas:    100_000,
+			Nonce:  nonce,
+			Rlp:    []byte{0xc0 + byte(nonce)},
+		}
+		txnSlot.IDHash[0] = byte(nonce + 1)
+		txnSlots.Append(txnSlot, addr[:], true)
+	}
+	private, public := txnSlots.Txns[0].Rlp, txnSlots.Txns[1].Rlp
+
+	stream := &onAddStream{replies: make(chan *txpoolproto.OnAddReply, 10)}
+	var newSlotsStreams NewSlotsStreams
+	newSlotsStreams.Add(stream)
+	go MainLoop(ctx, pool, ch, NewSend(ctx, nil, pool, logger), &newSlotsStreams, func() {})
+	nextReply := func() *txpoolproto.OnAddReply {
+		select {
+		case reply := <-stream.replies:
+			return reply
+		case <-time.After(5 * time.Second):
+			t.Fatal("no txns were published")
+			return nil
+		}
+	}
+
+	// the announcement of the private txn is processed, without publishing it
+	reasons, err := pool.AddLocalPrivateTxns(ctx, TxnSlots{Txns: txnSlots.Txns[:1], Senders: txnSlots.Senders.At(0), IsLocal: txnSlots.IsLocal[:1]}, 100, false)
+	require.NoError(err)
+	assert.Equal([]txpoolcfg.DiscardReason{txpoolcfg.Success}, reasons)
+	assert.NotContains(nextReply().RplTxs, private)
+
+	// whereas the public txn is
+	reasons, err = pool.AddLocalTxns(ctx, TxnSlots{Txns: txnSlots.Txns[1:], Senders: txnSlots.Senders.At(1), IsLocal: txnSlots.IsLocal[1:]})
+	require.NoError(err)
+	assert.Equal([]txpoolcfg.DiscardReason{txpoolcfg.Success}, reasons)
+	assert.Equal([][]byte{public}, nextReply().RplTxs)
+}
+
+func TestTxnEvents(t *testing.T) {
+	assert, require := assert.New(t), require.New(t)
+	ch := make(chan Announcements, 100)
+
+	coreDB, _ := temporaltest.NewTestDB(t, datadir.New(t.TempDir()))
+	db := memdb.NewTestPoolDB(t)
+	cfg := txpoolcfg.DefaultConfig
+	sendersCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	pool, err := New(ch, db, coreDB, cfg, sendersCache, *u256.N1, nil, nil, nil, nil, fixedgas.DefaultMaxBlobsPerBlock, nil, log.New())
+	assert.NoError(err)
+	require.True(pool != nil)
+	ctx := context.Background()
+
+	h1 := gointerfaces.ConvertHashToH256([32]byte{})
+	var addr [20]byte
+	addr[0] = 1
+	v := types2.EncodeAccountBytesV3(0, uint256.NewInt(1*common.Ether), make([]byte, 32), 1)
+	change := &remote.StateChangeBatch{
+		PendingBlockBaseFee: 200_000,
+		BlockGasLimit:       1_000_000,
+		ChangeBatch: []*remote.StateChange{
+			{BlockHeight: 0, BlockHash: h1},
+		},
+	}
+	change.ChangeBatch[0].Changes = append(change.ChangeBatch[0].Changes, &remote.AccountChange{
+		Action:  remote.Action_UPSERT,
+		Address: gointerfaces.ConvertAddressToH160(addr),
+		Data:    v,
+	})
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, TxnSlots{})
+	assert.NoError(err)
+
+	events, unsubscribe := pool.SubscribeTxnEvents()
+	defer unsubscribe()
+	add := func(tip uint64, hashByte byte) common.Hash {
+		var txnSlots TxnSlots
+		txnSlot := &TxnSlot{
+			Tip:    *uint256.NewInt(tip),
+			FeeCap: *uint256.NewInt(tip),
+			Gas:    100_000,
+			Rlp:    []byte{hashByte},
+		}
+		txnSlot.IDHash[0] = hashByte
+		txnSlots.Append(txnSlot, addr[:], true)
+		reasons, err := pool.AddLocalTxns(ctx, txnSlots)
+		require.NoError(err)
+		assert.Equal([]txpoolcfg.DiscardReason{txpoolcfg.Success}, reasons)
+		return txnSlot.IDHash
+	}
+	received := func(n int) []TxnEvent {
+		var res []TxnEvent
+		for i := 0; i < n; i++ {
+			e := <-events
+			e.BlockNumber, e.Timestamp = 0, 0
+			res = append(res, e)
+		}
+		assert.Empty(events)
+		return res
+	}
+
+	replaced := add(300_000, 1)
+	assert.Equal([]TxnEvent{
+		{Type: TxnAdded, Hash: replaced, SubPool: QueuedSubPool},
+		{Type: TxnPromoted, Hash: replaced, SubPool: PendingSubPool},
+	}, received(2))
+
+	mined := add(400_000, 2)
+	assert.Equal([]TxnEvent{
+		{Type: TxnReplaced, Hash: replaced, ReplacedBy: mined},
+		{Type: TxnAdded, Hash: mined, SubPool: QueuedSubPool},
+		{Type: TxnPromoted, Hash: mined, SubPool: PendingSubPool},
+	}, received(3))
+
+	var minedTxns TxnSlots
+	minedTxns.Append(pool.byHash[string(mined[:])].TxnSlot, addr[:], false)
+	change.ChangeBatch[0] = &remote.StateChange{BlockHeight: 1, BlockHash: h1}
+	err = pool.OnNewBlock(ctx, change, TxnSlots{}, TxnSlots{}, minedTxns)
+	assert.NoError(err)
+	assert.Equal([]TxnEvent{{Type: TxnMined, Hash: mined}}, received(1))
+
+	history, ok := pool.TxnStatus(replaced)
+	require.True(ok)
+	require.Len(history, 3)
+	assert.Equal(TxnReplaced, history[2].Type)
+	history, ok = pool.TxnStatus(mined)
+	require.True(ok)
+	require.Len(history, 3)
+	assert.Equal(TxnMined, history[2].Type)
+	assert.Equal(uint64(1), history[2].BlockNumber)
+
+	// the history persists across restarts
+	tx, err := db.BeginRw(ctx)
+	require.NoError(err)
+	defer tx.Rollback()
+	pool.lock.Lock()
+	require.NoError(pool.flushLocked(tx))
+	pool.lock.Unlock()
+	v, err = tx.GetOne(kv.PoolTxnStatus, mined[:])
+	require.NoError(err)
+	persisted, err := decodeTxnEvents(v)
+	require.NoError(err)
+	assert.Equal(history, persisted)
+}
+
+func TestPendingByArrival(t *testing.T) {
+	pending := NewPendingSubPool(PendingSubPool, 10)
+	add := func(sender, nonce, arrival uint64) *metaTxn {
+		mt := &metaTxn{TxnSlot: &TxnSlot{SenderID: sender, Nonce: nonce}, arrival: arrival}
+		pending.Add(mt, log.New())
+		return mt
+	}
+	a1 := add(1, 1, 1)
+	b0 := add(2, 0, 2)
+	a0 := add(1, 0, 5)
+	c0 := add(3, 0, 3)
+	a2 := add(1, 2, 4)
+
+	// the txns of sender 1 have to wait for its nonce 0
+	require.Equal(t, []*metaTxn{b0, c0, a0, a1, a2}, pending.byArrival())
+	// on a copy, the best slice is left as it is
+	require.Equal(t, []*metaTxn{a1, b0, a0, c0, a2}, pending.best.ms)
+}
@@ -16,7 +16,13 @@
 
 package txpool
 
-import "github.com/holiman/uint256"
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+
+	"github.com/holiman/uint256"
+)
 
 // bestSlice - is similar to best queue, but uses a linear structure with O(n log n) sort complexity and
 // it maintains element.bestIndex field
@@ -123,3 +129,111 @@ func (p *WorstQueue) Pop() interface{} {
 	p.ms = old[0 : n-1]
 	return item
 }
+
+// blobEvictionSender are the pooled blob txns of a sender kept in the blob store, by nonce
+type blobEvictionSender struct {
+	txns     []*metaTxn
+	cheapest *metaTxn // the cheapest of txns
+	index    int      // in blobEvictionQueue
+}
+
+// blobEvictionQueue orders the senders of the pooled blob txns kept in the blob store by their cheapest txn,
+// the cheapest one first. A txn can't be mined before the lower nonces of its sender, so it's worth no more
+// than the cheapest of them: the txns of a sender are evicted from its highest nonce down, which never leaves
+// a nonce gap behind.
+type blobEvictionQueue struct {
+	senders  []*blobEvictionSender
+	bySender map[uint64]*blobEvictionSender
+}
+
+func newBlobEvictionQueue() *blobEvictionQueue {
+	return &blobEvictionQueue{bySender: map[uint64]*blobEvictionSender{}}
+}
+
+func (q *blobEvictionQueue) Len() int {
+	return len(q.senders)
+}
+
+func (q *blobEvictionQueue) Less(i, j int) bool {
+	return blobTxnCheaper(q.senders[i].cheapest, q.senders[j].cheapest)
+}
+
+func (q *blobEvictionQueue) Swap(i, j int) {
+	q.senders[i], q.senders[j] = q.senders[j], q.senders[i]
+	q.senders[i].index = i
+	q.senders[j].index = j
+}
+
+func (q *blobEvictionQueue) Push(x interface{}) {
+	item := x.(*blobEvictionSender)
+	item.index = len(q.senders)
+	q.senders = append(q.senders, item)
+}
+
+func (q *blobEvictionQueue) Pop() interface{} {
+	old := q.senders
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil // avoid memory leak
+	item.index = -1
+	q.senders = old[0 : n-1]
+	return item
+}
+
+// add tracks mt, a pooled blob txn kept in the blob store
+func (q *blobEvictionQueue) add(mt *metaTxn) {
+	s, ok := q.bySender[mt.TxnSlot.SenderID]
+	if !ok {
+		s = &blobEvictionSender{txns: []*metaTxn{mt}, cheapest: mt}
+		q.bySender[mt.TxnSlot.SenderID] = s
+		heap.Push(q, s)
+		return
+	}
+	i, _ := slices.BinarySearchFunc(s.txns, mt.TxnSlot.Nonce, func(other *metaTxn, nonce uint64) int {
+		return cmp.Compare(other.TxnSlot.Nonce, nonce)
+	})
+	if i < len(s.txns) && s.txns[i] == mt {
+		return
+	}
+	s.txns = slices.Insert(s.txns, i, mt)
+	if blobTxnCheaper(mt, s.cheapest) {
+		s.cheapest = mt
+		heap.Fix(q, s.index)
+	}
+}
+
+// remove stops tracking mt, if it is tracked
+func (q *blobEvictionQueue) remove(mt *metaTxn) {
+	s, ok := q.bySender[mt.TxnSlot.SenderID]
+	if !ok {
+		return
+	}
+	i := slices.Index(s.txns, mt)
+	if i < 0 {
+		return
+	}
+	s.txns = slices.Delete(s.txns, i, i+1)
+	if len(s.txns) == 0 {
+		heap.Remove(q, s.index)
+		delete(q.bySender, mt.TxnSlot.SenderID)
+		return
+	}
+	if s.cheapest == mt {
+		s.cheapest = s.txns[0]
+		for _, other := range s.txns[1:] {
+			if blobTxnCheaper(other, s.cheapest) {
+				s.cheapest = other
+			}
+		}
+		heap.Fix(q, s.index)
+	}
+}
+
+// next returns the txn to evict first, and the cheapest txn of its sender which bounds its worth
+func (q *blobEvictionQueue) next() (evict, cheapest *metaTxn) {
+	if len(q.senders) == 0 {
+		return nil, nil
+	}
+	s := q.senders[0]
+	return s.txns[len(s.txns)-1], s.cheapest
+}
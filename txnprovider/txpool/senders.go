@@ -138,9 +138,9 @@ func (b *BySenderAndNonce) delete(mt *metaTxn, reason txpoolcfg.DiscardReason, l
 			delete(b.senderIDTxnCount, senderID)
 		}
 
-		if mt.TxnSlot.Type == BlobTxnType && mt.TxnSlot.Blobs != nil {
+		if mt.TxnSlot.Type == BlobTxnType {
 			accBlobCount := b.senderIDBlobCount[senderID]
-			txnBlobCount := len(mt.TxnSlot.Blobs)
+			txnBlobCount := len(mt.TxnSlot.BlobHashes)
 			if txnBlobCount > 1 {
 				b.senderIDBlobCount[senderID] = accBlobCount - uint64(txnBlobCount)
 			} else {
@@ -165,8 +165,8 @@ func (b *BySenderAndNonce) replaceOrInsert(mt *metaTxn, logger log.Logger) *meta
 	}
 
 	b.senderIDTxnCount[mt.TxnSlot.SenderID]++
-	if mt.TxnSlot.Type == BlobTxnType && mt.TxnSlot.Blobs != nil {
-		b.senderIDBlobCount[mt.TxnSlot.SenderID] += uint64(len(mt.TxnSlot.Blobs))
+	if mt.TxnSlot.Type == BlobTxnType {
+		b.senderIDBlobCount[mt.TxnSlot.SenderID] += uint64(len(mt.TxnSlot.BlobHashes))
 	}
 	return nil
 }
@@ -37,6 +37,8 @@ import (
 	"google.golang.org/protobuf/types/known/emptypb"
 
 	"github.com/erigontech/erigon-lib/log/v3"
+	"github.com/erigontech/erigon/core/types"
+	"github.com/erigontech/erigon/txnprovider/bundle"
 	"github.com/erigontech/erigon/txnprovider/txpool/txpoolcfg"
 
 	"github.com/erigontech/erigon-lib/common"
@@ -55,10 +57,15 @@ type txPool interface {
 	PeekBest(ctx context.Context, n int, txns *TxnsRlp, onTopOf, availableGas, availableBlobGas uint64) (bool, error)
 	GetRlp(tx kv.Tx, hash []byte) ([]byte, error)
 	AddLocalTxns(ctx context.Context, newTxns TxnSlots) ([]txpoolcfg.DiscardReason, error)
-	deprecatedForEach(_ context.Context, f func(rlp []byte, sender common.Address, t SubPoolType), tx kv.Tx)
+	AddLocalConditionalTxns(ctx context.Context, newTxns TxnSlots, conditions *TxnConditions) ([]txpoolcfg.DiscardReason, error)
+	AddLocalPrivateTxns(ctx context.Context, newTxns TxnSlots, maxBlockNumber uint64, fallback bool) ([]txpoolcfg.DiscardReason, error)
+	deprecatedForEach(_ context.Context, f func(rlp []byte, sender common.Address, t SubPoolType, private bool), tx kv.Tx)
 	CountContent() (int, int, int)
 	IdHashKnown(tx kv.Tx, hash []byte) (bool, error)
 	NonceFromAddress(addr [20]byte) (nonce uint64, inPool bool)
+	GetBlobs(blobHashes []common.Hash) (blobs [][]byte, proofs [][]byte)
+	SubscribeTxnEvents() (events <-chan TxnEvent, unsubscribe func())
+	TxnStatus(hash common.Hash) ([]TxnEvent, bool)
 }
 
 var _ txpool_proto.TxpoolServer = (*GrpcServer)(nil)   // compile-time interface check
@@ -97,6 +104,24 @@ func (*GrpcDisabled) Status(ctx context.Context, request *txpool_proto.StatusReq
 func (*GrpcDisabled) Nonce(ctx context.Context, request *txpool_proto.NonceRequest) (*txpool_proto.NonceReply, error) {
 	return nil, ErrPoolDisabled
 }
+func (*GrpcDisabled) GetBlobs(ctx context.Context, request *txpool_proto.GetBlobsRequest) (*txpool_proto.GetBlobsReply, error) {
+	return nil, ErrPoolDisabled
+}
+func (*GrpcDisabled) SendBundle(ctx context.Context, request *txpool_proto.SendBundleRequest) (*txpool_proto.SendBundleReply, error) {
+	return nil, ErrPoolDisabled
+}
+func (*GrpcDisabled) CancelBundle(ctx context.Context, request *txpool_proto.CancelBundleRequest) (*txpool_proto.CancelBundleReply, error) {
+	return nil, ErrPoolDisabled
+}
+func (*GrpcDisabled) AddConditional(ctx context.Context, request *txpool_proto.AddConditionalRequest) (*txpool_proto.AddReply, error) {
+	return nil, ErrPoolDisabled
+}
+func (*GrpcDisabled) OnTxnEvent(request *txpool_proto.OnTxnEventRequest, server txpool_proto.Txpool_OnTxnEventServer) error {
+	return ErrPoolDisabled
+}
+func (*GrpcDisabled) TransactionStatus(ctx context.Context, request *txpool_proto.TransactionStatusRequest) (*txpool_proto.TransactionStatusReply, error) {
+	return nil, ErrPoolDisabled
+}
 
 type GrpcServer struct {
 	txpool_proto.UnimplementedTxpoolServer
@@ -104,13 +129,14 @@ type GrpcServer struct {
 	txPool          txPool
 	db              kv.RoDB
 	NewSlotsStreams *NewSlotsStreams
+	Bundles         *bundle.Pool // bundles submitted via SendBundle, for the block builder
 
 	chainID uint256.Int
 	logger  log.Logger
 }
 
 func NewGrpcServer(ctx context.Context, txPool txPool, db kv.RoDB, chainID uint256.Int, logger log.Logger) *GrpcServer {
-	return &GrpcServer{ctx: ctx, txPool: txPool, db: db, NewSlotsStreams: &NewSlotsStreams{}, chainID: chainID, logger: logger}
+	return &GrpcServer{ctx: ctx, txPool: txPool, db: db, NewSlotsStreams: &NewSlotsStreams{}, Bundles: bundle.NewPool(logger), chainID: chainID, logger: logger}
 }
 
 func (s *GrpcServer) Version(context.Context, *emptypb.Empty) (*typesproto.VersionReply, error) {
@@ -136,11 +162,12 @@ func (s *GrpcServer) All(ctx context.Context, _ *txpool_proto.AllRequest) (*txpo
 	defer tx.Rollback()
 	reply := &txpool_proto.AllReply{}
 	reply.Txs = make([]*txpool_proto.AllReply_Tx, 0, 32)
-	s.txPool.deprecatedForEach(ctx, func(rlp []byte, sender common.Address, t SubPoolType) {
+	s.txPool.deprecatedForEach(ctx, func(rlp []byte, sender common.Address, t SubPoolType, private bool) {
 		reply.Txs = append(reply.Txs, &txpool_proto.AllReply_Tx{
-			Sender:  gointerfaces.ConvertAddressToH160(sender),
-			TxnType: convertSubPoolType(t),
-			RlpTx:   common.Copy(rlp),
+			Sender:    gointerfaces.ConvertAddressToH160(sender),
+			TxnType:   convertSubPoolType(t),
+			RlpTx:     common.Copy(rlp),
+			IsPrivate: private,
 		})
 	}, tx)
 	return reply, nil
@@ -170,6 +197,86 @@ func (s *GrpcServer) FindUnknown(ctx context.Context, in *txpool_proto.TxHashes)
 }
 
 func (s *GrpcServer) Add(ctx context.Context, in *txpool_proto.AddRequest) (*txpool_proto.AddReply, error) {
+	if in.Private {
+		return s.addLocal(ctx, in.RlpTxs, func(slots TxnSlots) ([]txpoolcfg.DiscardReason, error) {
+			return s.txPool.AddLocalPrivateTxns(ctx, slots, in.PrivateMaxBlockNumber, in.PrivateFallback)
+		})
+	}
+	return s.addLocal(ctx, in.RlpTxs, func(slots TxnSlots) ([]txpoolcfg.DiscardReason, error) {
+		return s.txPool.AddLocalTxns(ctx, slots)
+	})
+}
+
+func (s *GrpcServer) AddConditional(ctx context.Context, in *txpool_proto.AddConditionalRequest) (*txpool_proto.AddReply, error) {
+	conditions := ConditionsFromProto(in.Conditions)
+	if err := conditions.Validate(); err != nil {
+		return nil, err
+	}
+	return s.addLocal(ctx, [][]byte{in.RlpTx}, func(slots TxnSlots) ([]txpoolcfg.DiscardReason, error) {
+		return s.txPool.AddLocalConditionalTxns(ctx, slots, conditions)
+	})
+}
+
+// ConditionsFromProto converts the conditions of an AddConditionalRequest
+func ConditionsFromProto(in *txpool_proto.TransactionConditions) *TxnConditions {
+	if in == nil {
+		return &TxnConditions{}
+	}
+	c := &TxnConditions{
+		BlockNumberMin:    in.BlockNumberMin,
+		BlockNumberMax:    in.BlockNumberMax,
+		TimestampMin:      in.TimestampMin,
+		TimestampMax:      in.TimestampMax,
+		StorageRootsBlock: in.StorageRootsBlock,
+	}
+	if len(in.KnownAccounts) > 0 {
+		c.KnownAccounts = make(map[common.Address]map[common.Hash]common.Hash, len(in.KnownAccounts))
+	}
+	for _, account := range in.KnownAccounts {
+		addr := gointerfaces.ConvertH160toAddress(account.Address)
+		if account.StorageRoot != nil {
+			if c.StorageRoots == nil {
+				c.StorageRoots = map[common.Address]common.Hash{}
+			}
+			c.StorageRoots[addr] = gointerfaces.ConvertH256ToHash(account.StorageRoot)
+			continue
+		}
+		slots, ok := c.KnownAccounts[addr]
+		if !ok {
+			slots = make(map[common.Hash]common.Hash, len(account.Slots))
+			c.KnownAccounts[addr] = slots
+		}
+		for _, slot := range account.Slots {
+			slots[gointerfaces.ConvertH256ToHash(slot.Key)] = gointerfaces.ConvertH256ToHash(slot.Value)
+		}
+	}
+	return c
+}
+
+// ConditionsToProto converts conditions for an AddConditionalRequest
+func ConditionsToProto(c *TxnConditions) *txpool_proto.TransactionConditions {
+	out := &txpool_proto.TransactionConditions{
+		BlockNumberMin:    c.BlockNumberMin,
+		BlockNumberMax:    c.BlockNumberMax,
+		TimestampMin:      c.TimestampMin,
+		TimestampMax:      c.TimestampMax,
+		StorageRootsBlock: c.StorageRootsBlock,
+	}
+	for addr, slots := range c.KnownAccounts {
+		account := &txpool_proto.KnownAccount{Address: gointerfaces.ConvertAddressToH160(addr)}
+		for key, value := range slots {
+			account.Slots = append(account.Slots, &txpool_proto.StorageSlot{Key: gointerfaces.ConvertHashToH256(key), Value: gointerfaces.ConvertHashToH256(value)})
+		}
+		out.KnownAccounts = append(out.KnownAccounts, account)
+	}
+	for addr, root := range c.StorageRoots {
+		out.KnownAccounts = append(out.KnownAccounts, &txpool_proto.KnownAccount{Address: gointerfaces.ConvertAddressToH160(addr), StorageRoot: gointerfaces.ConvertHashToH256(root)})
+	}
+	return out
+}
+
+// addLocal parses rlpTxs and adds the well-formed ones with add, preserving incoming order and amount in the reply
+func (s *GrpcServer) addLocal(ctx context.Context, rlpTxs [][]byte, add func(slots TxnSlots) ([]txpoolcfg.DiscardReason, error)) (*txpool_proto.AddReply, error) {
 	tx, err := s.db.BeginRo(ctx)
 	if err != nil {
 		return nil, err
@@ -180,14 +287,14 @@ func (s *GrpcServer) Add(ctx context.Context, in *txpool_proto.AddRequest) (*txp
 	parseCtx := NewTxnParseContext(s.chainID).ChainIDRequired()
 	parseCtx.ValidateRLP(s.txPool.ValidateSerializedTxn)
 
-	reply := &txpool_proto.AddReply{Imported: make([]txpool_proto.ImportResult, len(in.RlpTxs)), Errors: make([]string, len(in.RlpTxs))}
+	reply := &txpool_proto.AddReply{Imported: make([]txpool_proto.ImportResult, len(rlpTxs)), Errors: make([]string, len(rlpTxs))}
 
-	for i := 0; i < len(in.RlpTxs); i++ {
+	for i := 0; i < len(rlpTxs); i++ {
 		j := len(slots.Txns) // some incoming txns may be rejected, so - need second index
 		slots.Resize(uint(j + 1))
 		slots.Txns[j] = &TxnSlot{}
 		slots.IsLocal[j] = true
-		if _, err := parseCtx.ParseTransaction(in.RlpTxs[i], 0, slots.Txns[j], slots.Senders.At(j), false /* hasEnvelope */, true /* wrappedWithBlobs */, func(hash []byte) error {
+		if _, err := parseCtx.ParseTransaction(rlpTxs[i], 0, slots.Txns[j], slots.Senders.At(j), false /* hasEnvelope */, true /* wrappedWithBlobs */, func(hash []byte) error {
 			if known, _ := s.txPool.IdHashKnown(tx, hash); known {
 				return ErrAlreadyKnown
 			}
@@ -207,7 +314,7 @@ func (s *GrpcServer) Add(ctx context.Context, in *txpool_proto.AddRequest) (*txp
 		}
 	}
 
-	discardReasons, err := s.txPool.AddLocalTxns(ctx, slots)
+	discardReasons, err := add(slots)
 	if err != nil {
 		return nil, err
 	}
@@ -232,12 +339,12 @@ func mapDiscardReasonToProto(reason txpoolcfg.DiscardReason) txpool_proto.Import
 		return txpool_proto.ImportResult_SUCCESS
 	case txpoolcfg.AlreadyKnown:
 		return txpool_proto.ImportResult_ALREADY_EXISTS
-	case txpoolcfg.UnderPriced, txpoolcfg.ReplaceUnderpriced, txpoolcfg.FeeTooLow:
+	case txpoolcfg.UnderPriced, txpoolcfg.ReplaceUnderpriced, txpoolcfg.FeeTooLow, txpoolcfg.BlobStoreOverflow:
 		return txpool_proto.ImportResult_FEE_TOO_LOW
 	case txpoolcfg.InvalidSender, txpoolcfg.NegativeValue, txpoolcfg.OversizedData, txpoolcfg.InitCodeTooLarge,
 		txpoolcfg.RLPTooLong, txpoolcfg.InvalidCreateTxn, txpoolcfg.NoBlobs, txpoolcfg.TooManyBlobs,
 		txpoolcfg.TypeNotActivated, txpoolcfg.UnequalBlobTxExt, txpoolcfg.BlobHashCheckFail,
-		txpoolcfg.UnmatchedBlobTxExt, txpoolcfg.NoAuthorizations:
+		txpoolcfg.UnmatchedBlobTxExt, txpoolcfg.NoAuthorizations, txpoolcfg.ConditionsNotMet, txpoolcfg.PrivateTxnExpired:
 		// TODO(EIP-7702) TypeNotActivated may be transient (e.g. a set code transaction is submitted 1 sec prior to the Pectra activation)
 		return txpool_proto.ImportResult_INVALID
 	default:
@@ -258,6 +365,49 @@ func (s *GrpcServer) OnAdd(req *txpool_proto.OnAddRequest, stream txpool_proto.T
 	}
 }
 
+func (s *GrpcServer) OnTxnEvent(req *txpool_proto.OnTxnEventRequest, stream txpool_proto.Txpool_OnTxnEventServer) error {
+	events, unsubscribe := s.txPool.SubscribeTxnEvents()
+	defer unsubscribe()
+	for {
+		select {
+		case e := <-events:
+			if err := stream.Send(TxnEventToProto(&e)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		}
+	}
+}
+
+func (s *GrpcServer) TransactionStatus(ctx context.Context, in *txpool_proto.TransactionStatusRequest) (*txpool_proto.TransactionStatusReply, error) {
+	events, ok := s.txPool.TxnStatus(gointerfaces.ConvertH256ToHash(in.Hash))
+	reply := &txpool_proto.TransactionStatusReply{Known: ok, Events: make([]*txpool_proto.TxnEvent, len(events))}
+	for i := range events {
+		reply.Events[i] = TxnEventToProto(&events[i])
+	}
+	return reply, nil
+}
+
+func TxnEventToProto(e *TxnEvent) *txpool_proto.TxnEvent {
+	reply := &txpool_proto.TxnEvent{
+		Type:          txpool_proto.TxnEventType(e.Type),
+		Hash:          gointerfaces.ConvertHashToH256(e.Hash),
+		DiscardReason: uint32(e.DiscardReason),
+		BlockNumber:   e.BlockNumber,
+		Timestamp:     e.Timestamp,
+	}
+	if e.SubPool != 0 {
+		reply.SubPool = convertSubPoolType(e.SubPool)
+	}
+	if e.Type == TxnReplaced {
+		reply.ReplacedBy = gointerfaces.ConvertHashToH256(e.ReplacedBy)
+	}
+	return reply
+}
+
 func (s *GrpcServer) Transactions(ctx context.Context, in *txpool_proto.TransactionsRequest) (*txpool_proto.TransactionsReply, error) {
 	tx, err := s.db.BeginRo(ctx)
 	if err != nil {
@@ -301,6 +451,59 @@ func (s *GrpcServer) Nonce(ctx context.Context, in *txpool_proto.NonceRequest) (
 	}, nil
 }
 
+// returns blobs and proofs for the given versioned hashes, unknown hashes get empty entries
+func (s *GrpcServer) GetBlobs(ctx context.Context, in *txpool_proto.GetBlobsRequest) (*txpool_proto.GetBlobsReply, error) {
+	hashes := make([]common.Hash, len(in.BlobHashes))
+	for i := range in.BlobHashes {
+		hashes[i] = gointerfaces.ConvertH256ToHash(in.BlobHashes[i])
+	}
+	blobs, proofs := s.txPool.GetBlobs(hashes)
+	reply := &txpool_proto.GetBlobsReply{BlobsAndProofs: make([]*txpool_proto.BlobAndProofV1, len(hashes))}
+	for i := range blobs {
+		reply.BlobsAndProofs[i] = &txpool_proto.BlobAndProofV1{Blob: blobs[i], Proof: proofs[i]}
+	}
+	return reply, nil
+}
+
+func (s *GrpcServer) SendBundle(ctx context.Context, in *txpool_proto.SendBundleRequest) (*txpool_proto.SendBundleReply, error) {
+	signer := types.LatestSignerForChainID(s.chainID.ToBig())
+	b := &bundle.Bundle{
+		Txns:               make([]types.Transaction, len(in.RlpTxs)),
+		BlockNumber:        in.BlockNumber,
+		MinTimestamp:       in.MinTimestamp,
+		MaxTimestamp:       in.MaxTimestamp,
+		RevertingTxnHashes: make(map[common.Hash]struct{}, len(in.RevertingTxHashes)),
+		ReplacementUuid:    in.ReplacementUuid,
+	}
+	for i, rlpTxn := range in.RlpTxs {
+		txn, err := types.DecodeWrappedTransaction(rlpTxn)
+		if err != nil {
+			return nil, fmt.Errorf("bundle transaction %d: %w", i, err)
+		}
+		sender, err := txn.Sender(*signer)
+		if err != nil {
+			return nil, fmt.Errorf("bundle transaction %d: %w", i, err)
+		}
+		txn.SetSender(sender)
+		b.Txns[i] = txn
+	}
+	for _, h := range in.RevertingTxHashes {
+		b.RevertingTxnHashes[gointerfaces.ConvertH256ToHash(h)] = struct{}{}
+	}
+	bundleHash, err := s.Bundles.Add(b)
+	if err != nil {
+		return nil, err
+	}
+	return &txpool_proto.SendBundleReply{BundleHash: gointerfaces.ConvertHashToH256(bundleHash)}, nil
+}
+
+func (s *GrpcServer) CancelBundle(ctx context.Context, in *txpool_proto.CancelBundleRequest) (*txpool_proto.CancelBundleReply, error) {
+	if in.ReplacementUuid == "" {
+		return nil, errors.New("replacement uuid is required to cancel a bundle")
+	}
+	return &txpool_proto.CancelBundleReply{Cancelled: s.Bundles.Cancel(in.ReplacementUuid)}, nil
+}
+
 // NewSlotsStreams - it's safe to use this class as non-pointer
 type NewSlotsStreams struct {
 	chans map[uint]txpool_proto.Txpool_OnAddServer
@@ -60,6 +60,10 @@ type Config struct {
 	MdbxWriteMap    bool
 
 	NoGossip bool // this mode doesn't broadcast any txns, and if receive remote-txn - skip it
+
+	PrivateTxnLifetime uint64 // Number of blocks private txns are kept for local block building before they expire
+
+	BlobStoreSize datasize.ByteSize // Byte budget of the on-disk store of blob txns with their sidecars, the cheapest blob txns are evicted above it
 }
 
 var DefaultConfig = Config{
@@ -79,6 +83,10 @@ var DefaultConfig = Config{
 	PriceBump:          10,  // Price bump percentage to replace an already existing transaction
 	BlobPriceBump:      100,
 
+	PrivateTxnLifetime: 25,
+
+	BlobStoreSize: 2560 * datasize.MB,
+
 	NoGossip:     false,
 	MdbxWriteMap: false,
 }
@@ -119,6 +127,10 @@ const (
 	BlobTxReplace       DiscardReason = 30 // Cannot replace type-3 blob txn with another type of txn
 	BlobPoolOverflow    DiscardReason = 31 // The total number of blobs (through blob txns) in the pool has reached its limit
 	NoAuthorizations    DiscardReason = 32 // EIP-7702 transactions with an empty authorization list are invalid
+	ConditionsNotMet    DiscardReason = 33 // The preconditions of a conditional transaction don't hold anymore
+	PrivateTxnExpired   DiscardReason = 34 // Private transaction wasn't included before its expiry block
+	BlobStoreOverflow   DiscardReason = 35 // The blob store reached its byte budget and the blob txn is among the cheapest ones
+	ConditionsOutdated  DiscardReason = 36 // The storage roots of a conditional transaction were checked against an older block than the pool's
 )
 
 func (r DiscardReason) String() string {
@@ -183,6 +195,14 @@ func (r DiscardReason) String() string {
 		return "blobs limit in txpool is full"
 	case NoAuthorizations:
 		return "EIP-7702 transactions with an empty authorization list are invalid"
+	case ConditionsNotMet:
+		return "transaction conditions are not met"
+	case PrivateTxnExpired:
+		return "private transaction expired"
+	case BlobStoreOverflow:
+		return "blob store overflow"
+	case ConditionsOutdated:
+		return "transaction conditions were checked against an outdated block"
 	default:
 		panic(fmt.Sprintf("discard reason: %d", r))
 	}